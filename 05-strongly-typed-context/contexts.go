@@ -10,7 +10,6 @@ type RequestContext interface {
 type DatabaseInterface interface {
 	Read(
 		ctx interface{
-			context.Context
 			SecretsContext
 			LoggerContext
 		},
@@ -9,43 +9,23 @@ import (
 // Some mock implementations to support doing the thing
 // ================================
 func GetContextWithAllTheMocks() MockContext {
-	return MockContext{
-		Context:    context.Background(),
-		request:    &Request{key: "mockUser"},
-		database:   &Database{},
-		httpClient: &HttpClient{},
-		secrets:    &Secrets{},
-		logger:     &Logger{},
-	}
+	return ComposeMockContext(
+		context.Background(),
+		&Request{key: "mockUser"},
+		&Database{},
+		&HttpClient{},
+		&Secrets{},
+		&Logger{},
+	)
 }
 
-type MockContext struct {
-	context.Context
-	request    *Request
-	database   *Database
-	httpClient *HttpClient
-	secrets    *Secrets
-	logger     *Logger
-}
-
-func (c MockContext) Request() *Request {
-	return c.request
-}
-
-func (c MockContext) Database() DatabaseInterface {
-	return c.database
-}
-
-func (c MockContext) HttpClient() *HttpClient {
-	return c.httpClient
-}
-
-func (c MockContext) Secrets() *Secrets {
-	return c.secrets
-}
-
-func (c MockContext) Logger() *Logger {
-	return c.logger
+//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=MockContext
+type MockContext interface {
+	RequestContext
+	DatabaseContext
+	HttpClientContext
+	SecretsContext
+	LoggerContext
 }
 
 type Request struct {
@@ -80,7 +60,6 @@ type Database struct{}
 
 func (*Database) Read(
 	ctx interface {
-		context.Context
 		SecretsContext
 		LoggerContext
 	},
@@ -100,7 +79,6 @@ type HttpClient struct{}
 
 func (*HttpClient) Post(
 	ctx interface {
-		context.Context
 		RequestContext
 	},
 	url string,
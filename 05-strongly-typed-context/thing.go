@@ -1,12 +1,7 @@
 package main
 
-import (
-	"context"
-)
-
 func DoTheThing(
 	ctx interface {
-		context.Context
 		RequestContext
 		DatabaseContext
 		HttpClientContext
@@ -9,41 +9,22 @@ import (
 // Some mock implementations to support doing the thing
 // ================================
 func GetServerWithAllTheMocks() MockServer {
-	return MockServer{
-		request:    &Request{key: "mockUser"},
-		database:   &Database{},
-		httpClient: &HttpClient{},
-		secrets:    &Secrets{},
-		logger:     &Logger{},
-	}
+	return ComposeMockServer(
+		&Request{key: "mockUser"},
+		&Database{},
+		&HttpClient{},
+		&Secrets{},
+		&Logger{},
+	)
 }
 
-type MockServer struct {
-	request    *Request
-	database   *Database
-	httpClient *HttpClient
-	secrets    *Secrets
-	logger     *Logger
-}
-
-func (c MockServer) Request() *Request {
-	return c.request
-}
-
-func (c MockServer) Database() DatabaseInterface {
-	return c.database
-}
-
-func (c MockServer) HttpClient() *HttpClient {
-	return c.httpClient
-}
-
-func (c MockServer) Secrets() *Secrets {
-	return c.secrets
-}
-
-func (c MockServer) Logger() *Logger {
-	return c.logger
+//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=MockServer
+type MockServer interface {
+	RequestServer
+	DatabaseServer
+	HttpClientServer
+	SecretsServer
+	LoggerServer
 }
 
 type Request struct {
@@ -85,6 +66,9 @@ func (*Database) Read(
 	key DatabaseKey,
 ) (*User, error) {
 	fmt.Printf("Database Reading %v\n", string(key))
+	// Mark as used so the linter (with -serverinterfaces) doesn't complain
+	_ = server.Secrets()
+	_ = server.Logger()
 	return &User{name: string(key)}, nil
 }
 
@@ -101,6 +85,8 @@ func (*HttpClient) Post(
 	param string,
 ) error {
 	fmt.Printf("HTTP Posting %v?%v\n", url, param)
+	// Mark as used so the linter (with -serverinterfaces) doesn't complain
+	_ = server.Request()
 	return nil
 }
 
@@ -0,0 +1,20 @@
+"""Macros for running the typed context analyzers under Bazel."""
+
+load("@io_bazel_rules_go//go:def.bzl", "nogo")
+load(":analyzers.bzl", "TYPED_CONTEXT_ANALYZERS")
+
+def typed_context_nogo(name, deps = [], **kwargs):
+    """Declares a nogo target running the typed context analyzers.
+
+    See the documentation of package bazel for how to use it.
+
+    Args:
+      name: the name of the target.
+      deps: other analyzers to run too, like those of go vet.
+      **kwargs: passed on to nogo, like config and visibility.
+    """
+    nogo(
+        name = name,
+        deps = TYPED_CONTEXT_ANALYZERS + deps,
+        **kwargs
+    )
@@ -0,0 +1,34 @@
+// Package bazel exposes the typed context analyzers to Bazel, through
+// rules_go's nogo, which runs analyzers as part of every Go build.  nogo
+// wants each analyzer in a go_library of its own, exporting it as Analyzer;
+// those are generated, from linter.Analyzers, into analyzers/, along with
+// analyzers.bzl, which lists them.  Regenerate them (with go generate) after
+// adding an analyzer.
+//
+// To use them, declare a nogo target with the typed_context_nogo macro in
+// defs.bzl, which takes the same arguments as nogo:
+//
+//	load("@com_github_khan_typed_context//bazel:defs.bzl", "typed_context_nogo")
+//
+//	typed_context_nogo(
+//		name = "nogo",
+//		config = "nogo_config.json",
+//		visibility = ["//visibility:public"],
+//	)
+//
+// and register it with go_sdk.nogo (or go_register_toolchains(nogo = ...),
+// without Bzlmod).  Other analyzers, like those of go vet, can be added to
+// its deps.
+//
+// Analyzer flags go in the nogo config, under the analyzer's name, like
+//
+//	{
+//		"typedcontextfixture": {"analyzer_flags": {"enable": "true"}},
+//		"typedcontextsize": {"analyzer_flags": {"max": "6"}}
+//	}
+//
+// .typedcontext.yaml files aren't inputs to the build actions nogo runs in,
+// so aren't read under Bazel; use analyzer flags instead.
+package bazel
+
+//go:generate go run ./gen
@@ -0,0 +1,209 @@
+// Command gen generates the nogo packages for the typed context analyzers:
+// for each analyzer in linter.Analyzers, a go_library in
+// analyzers/<name> exporting it as Analyzer, and analyzers.bzl, listing
+// them.  It's run by go generate in package bazel:
+//
+//	go generate ./bazel
+//
+// With -check, it writes nothing, but exits with status 1 if any of the
+// files are out of date, for CI.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+var (
+	dir   = flag.String("dir", ".", "the bazel directory, into which to generate")
+	check = flag.Bool("check", false, "report files which are out of date, rather than writing them")
+)
+
+// _importPath is the import path of the bazel directory.
+const _importPath = "github.com/khan/typed-context/bazel"
+
+// _header marks the files we generate.
+const _header = "Code generated by bazel/gen; DO NOT EDIT."
+
+var _analyzerTemplate = template.Must(template.New("analyzer").Parse(`// ` + _header + `
+
+// Package {{.Name}} exposes the {{.Name}} analyzer to nogo.
+package {{.Name}}
+
+import contextLinter "github.com/khan/typed-context/linter"
+
+// Analyzer {{.Doc}}.
+var Analyzer = contextLinter.{{.Var}}
+`))
+
+var _buildTemplate = template.Must(template.New("build").Parse(`# ` + _header + `
+
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "{{.Name}}",
+    srcs = ["analyzer.go"],
+    importpath = "` + _importPath + `/analyzers/{{.Name}}",
+    visibility = ["//visibility:public"],
+    deps = ["//linter"],
+)
+`))
+
+var _bzlTemplate = template.Must(template.New("bzl").Parse(`# ` + _header + `
+
+"""The typed context analyzers, as deps for nogo."""
+
+TYPED_CONTEXT_ANALYZERS = [
+{{- range .}}
+    Label("//bazel/analyzers/{{.Name}}"),
+{{- end}}
+]
+`))
+
+// analyzer is what the templates need to know about an analyzer.
+type analyzer struct {
+	// Name is the analyzer's name, like typedcontextinterface, which is also
+	// the name of its package.
+	Name string
+	// Var is the name of the variable holding it in package linter, like
+	// TypedContextInterfaceAnalyzer.
+	Var string
+	// Doc is the first line of its documentation.
+	Doc string
+}
+
+// _varName returns the name of the variable in package linter holding the
+// analyzer of the given name: typedcontextinterface is
+// TypedContextInterfaceAnalyzer.  (If that's ever wrong, the generated code
+// won't compile.)
+func _varName(name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, "typedcontext")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("analyzer %s isn't named typedcontext<something>", name)
+	}
+	return "TypedContext" + strings.ToUpper(rest[:1]) + rest[1:] + "Analyzer", nil
+}
+
+// _render executes the given template, and formats the result if it's Go.
+func _render(tmpl *template.Template, data any, isGo bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if !isGo {
+		return buf.Bytes(), nil
+	}
+	return format.Source(buf.Bytes())
+}
+
+// generate returns the files to generate, by path relative to the bazel
+// directory.
+func generate() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	var analyzers []analyzer
+	for _, a := range contextLinter.Analyzers {
+		varName, err := _varName(a.Name)
+		if err != nil {
+			return nil, err
+		}
+		doc, _, _ := strings.Cut(a.Doc, "\n")
+		data := analyzer{Name: a.Name, Var: varName, Doc: doc}
+		analyzers = append(analyzers, data)
+
+		source, err := _render(_analyzerTemplate, data, true)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Join("analyzers", a.Name, "analyzer.go")] = source
+		build, err := _render(_buildTemplate, data, false)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Join("analyzers", a.Name, "BUILD.bazel")] = build
+	}
+	bzl, err := _render(_bzlTemplate, analyzers, false)
+	if err != nil {
+		return nil, err
+	}
+	files["analyzers.bzl"] = bzl
+	return files, nil
+}
+
+// stale returns the directories under analyzers/ which we generated, but
+// which are no longer for any analyzer.
+func stale(files map[string][]byte) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(*dir, "analyzers"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		rel := filepath.Join("analyzers", entry.Name())
+		if !entry.IsDir() || files[filepath.Join(rel, "analyzer.go")] != nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(*dir, rel, "analyzer.go"))
+		if err == nil && bytes.Contains(content, []byte(_header)) {
+			dirs = append(dirs, rel)
+		}
+	}
+	return dirs, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("bazel/gen: ")
+	flag.Parse()
+
+	files, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	staleDirs, err := stale(files)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outOfDate := false
+	for _, rel := range staleDirs {
+		if *check {
+			fmt.Fprintf(os.Stderr, "%s: no longer an analyzer\n", rel)
+			outOfDate = true
+		} else if err := os.RemoveAll(filepath.Join(*dir, rel)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for rel, content := range files {
+		path := filepath.Join(*dir, rel)
+		existing, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		if *check {
+			fmt.Fprintf(os.Stderr, "%s: out of date\n", rel)
+			outOfDate = true
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if outOfDate {
+		fmt.Fprintf(os.Stderr, "run go generate ./bazel\n")
+		os.Exit(1)
+	}
+}
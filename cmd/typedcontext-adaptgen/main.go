@@ -0,0 +1,79 @@
+// Command typedcontext-adaptgen generates adapters between the typed-context
+// and server-interface styles, for code migrating from one to the other.
+// It's designed to be used with go:generate, like
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-adaptgen -type=Server
+//	type Server struct {
+//		Request *Request
+//		Logger  *Logger
+//	}
+//
+// which generates the server interfaces RequestServer, LoggerServer and
+// ServerInterface, the typed contexts RequestContext, LoggerContext and
+// ServerContext, constructors wrapping a *Server as either, and conversions
+// between the two.  See gen.Generator.Adapt for details.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khan/typed-context/typedcontext/gen"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct names; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_adapt.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-adaptgen -type=T[,T...] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-adaptgen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeNames, ",")
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_adapt.go")
+	}
+
+	pkg, err := gen.LoadPackage(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := gen.NewGenerator(pkg, "typedcontext-adaptgen")
+	for _, name := range names {
+		provider, err := gen.LookupProvider(pkg, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.Adapt(provider)
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
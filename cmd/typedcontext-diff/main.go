@@ -0,0 +1,169 @@
+// Command typedcontext-diff reports how a change affects what the exported
+// functions of a module require of their callers' contexts, for pasting into
+// a code review.  For example,
+//
+//	typedcontext-diff -base=origin/main ./...
+//
+// compares the packages matching the patterns in the working tree to those
+// at origin/main, and prints a markdown list of the exported functions and
+// methods whose typed context parameters now request interfaces they didn't
+// (like "this change makes GetUser require SecretsContext"), or no longer
+// request ones they did.  With -head=REV, it compares REV rather than the
+// working tree.  Revisions other than the working tree are checked out in
+// temporary git worktrees.
+//
+// It accepts the same flags as the typedcontextinterface analyzer (like
+// -contextroots), and reads the same configuration files.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+var (
+	jsonOutput = flag.Bool("json", false, "emit JSON output, including positions")
+	base       = flag.String("base", "origin/main", "the git revision to compare against")
+	head       = flag.String("head", "", "the git revision to compare (default the working tree)")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-diff [flags] [packages]\n")
+	flag.PrintDefaults()
+}
+
+// git runs git with the given arguments, and returns its output, trimmed.
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// capabilities returns the capabilities of the packages matching the
+// patterns at the given revision (or in the working tree, if rev is ""),
+// with positions relative to the current directory's counterpart there.
+func capabilities(rev string, patterns []string) ([]contextLinter.FunctionCapabilities, error) {
+	dir := ""
+	if rev != "" {
+		prefix, err := git("rev-parse", "--show-prefix")
+		if err != nil {
+			return nil, err
+		}
+		worktree, err := os.MkdirTemp("", "typedcontext-diff-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(worktree)
+		if _, err := git("worktree", "add", "--detach", worktree, rev); err != nil {
+			return nil, err
+		}
+		defer git("worktree", "remove", "--force", worktree)
+		dir = filepath.Join(worktree, filepath.FromSlash(prefix))
+	}
+
+	result, err := contextLinter.Capabilities(dir, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("at %s: %v", revName(rev), err)
+	}
+	root := dir
+	if root == "" {
+		if root, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	for i, function := range result {
+		if rel, err := filepath.Rel(root, function.Position); err == nil && !strings.HasPrefix(rel, "..") {
+			result[i].Position = rel
+		}
+	}
+	return result, nil
+}
+
+// revName returns how we describe the given revision.
+func revName(rev string) string {
+	if rev == "" {
+		return "the working tree"
+	}
+	return rev
+}
+
+// shortName returns the given "import/path.Name" without the directories of
+// the import path, for brevity.
+func shortName(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// codeList returns the given names, shortened, as a list of code spans.
+func codeList(names []string) string {
+	spans := make([]string, len(names))
+	for i, name := range names {
+		spans[i] = "`" + shortName(name) + "`"
+	}
+	return strings.Join(spans, ", ")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-diff: ")
+	contextLinter.TypedContextInterfaceAnalyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	flag.Usage = usage
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	before, err := capabilities(*base, patterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	after, err := capabilities(*head, patterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes := contextLinter.DiffCapabilities(before, after)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(changes); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("#### Typed context changes since `%s`\n\n", *base)
+	if len(changes) == 0 {
+		fmt.Println("No exported function's context requirements changed.")
+		return
+	}
+	for _, change := range changes {
+		var parts []string
+		if len(change.Added) > 0 {
+			parts = append(parts, "now requires "+codeList(change.Added))
+		}
+		if len(change.Removed) > 0 {
+			parts = append(parts, "no longer requires "+codeList(change.Removed))
+		}
+		name := "`" + shortName(change.Function) + "`"
+		if change.New {
+			name += " (new)"
+		}
+		fmt.Printf("- %s %s\n", name, strings.Join(parts, "; "))
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+// This file collects the analyzers' fixes, reconciles them, and applies
+// them.
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// edit is a single text edit, by byte offsets into a file.
+type edit struct {
+	filename   string
+	start, end int
+	newText    string
+}
+
+// overlaps returns true if the two edits, which must be in the same file,
+// can't both be applied: if their ranges overlap, or they insert at the same
+// place (in which case which goes first is ambiguous).
+func (e edit) overlaps(other edit) bool {
+	if e.start == other.start {
+		return true
+	}
+	return e.start < other.end && other.start < e.end
+}
+
+// fix is a suggested fix: a set of edits to be applied together.
+type fix struct {
+	// message is the fix's message, or the diagnostic's if it has none.
+	message string
+	// posn is the position of the diagnostic.
+	posn  token.Position
+	edits []edit
+}
+
+// key identifies the fix by its edits, so that the same fix reported twice
+// (say, for a package and its test variant) is applied once.
+func (f fix) key() string {
+	var key strings.Builder
+	for _, e := range f.edits {
+		fmt.Fprintf(&key, "%s:%d:%d:%q\n", e.filename, e.start, e.end, e.newText)
+	}
+	return key.String()
+}
+
+// collectFixes runs the analyzers over the packages matching the given
+// patterns, and returns their suggested fixes, without duplicates, in order
+// of the position of their diagnostics.
+func collectFixes(patterns []string, tests bool) ([]fix, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: tests}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(contextLinter.Analyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	var fixes []fix
+	seen := map[string]bool{}
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %v", act.Analyzer.Name, act.Err)
+		}
+		fset := act.Package.Fset
+		for _, diagnostic := range act.Diagnostics {
+			for _, suggested := range diagnostic.SuggestedFixes {
+				f := fix{message: suggested.Message, posn: fset.Position(diagnostic.Pos)}
+				if f.message == "" {
+					f.message = diagnostic.Message
+				}
+				for _, textEdit := range suggested.TextEdits {
+					start := fset.Position(textEdit.Pos)
+					end := start
+					if textEdit.End.IsValid() {
+						end = fset.Position(textEdit.End)
+					}
+					f.edits = append(f.edits, edit{start.Filename, start.Offset, end.Offset, string(textEdit.NewText)})
+				}
+				sort.Slice(f.edits, func(i, j int) bool {
+					if f.edits[i].filename != f.edits[j].filename {
+						return f.edits[i].filename < f.edits[j].filename
+					}
+					return f.edits[i].start < f.edits[j].start
+				})
+				if key := f.key(); !seen[key] {
+					seen[key] = true
+					fixes = append(fixes, f)
+				}
+			}
+		}
+	}
+	sort.SliceStable(fixes, func(i, j int) bool {
+		if fixes[i].posn.Filename != fixes[j].posn.Filename {
+			return fixes[i].posn.Filename < fixes[j].posn.Filename
+		}
+		return fixes[i].posn.Offset < fixes[j].posn.Offset
+	})
+	return fixes, nil
+}
+
+// conflict is a fix we didn't apply, since some of its edits overlap those
+// of an accepted fix.
+type conflict struct {
+	fix, with fix
+}
+
+// reconcile returns the fixes, in the given order, which can be applied
+// together: each fix is accepted unless one of its edits overlaps an edit of
+// a fix accepted before it, other than an identical edit, which is merged.
+// It also returns the conflicts which kept the others out.
+func reconcile(fixes []fix) ([]fix, []conflict) {
+	var accepted []fix
+	var conflicts []conflict
+	// acceptedEdits are the edits of the accepted fixes, by file, and which
+	// fix each came from.
+	type acceptedEdit struct {
+		edit
+		from fix
+	}
+	acceptedEdits := map[string][]acceptedEdit{}
+
+	for _, f := range fixes {
+		var with *fix
+		var fresh []edit // edits not already accepted as part of another fix
+	edits:
+		for _, e := range f.edits {
+			for _, other := range acceptedEdits[e.filename] {
+				if other.edit == e {
+					continue edits
+				}
+				if other.overlaps(e) {
+					with = &other.from
+					break edits
+				}
+			}
+			fresh = append(fresh, e)
+		}
+		if with != nil {
+			conflicts = append(conflicts, conflict{f, *with})
+			continue
+		}
+		accepted = append(accepted, f)
+		for _, e := range fresh {
+			acceptedEdits[e.filename] = append(acceptedEdits[e.filename], acceptedEdit{e, f})
+		}
+	}
+	return accepted, conflicts
+}
+
+// apply applies the given fixes, which must not conflict, and gofmts the
+// changed files.  It writes nothing unless every changed file can be
+// formatted.
+func apply(fixes []fix) error {
+	byFile := map[string][]edit{}
+	for _, f := range fixes {
+		for _, e := range f.edits {
+			duplicate := false
+			for _, other := range byFile[e.filename] {
+				duplicate = duplicate || other == e
+			}
+			if !duplicate {
+				byFile[e.filename] = append(byFile[e.filename], e)
+			}
+		}
+	}
+
+	contents := map[string][]byte{}
+	for filename, edits := range byFile {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+		var out bytes.Buffer
+		last := 0
+		for _, e := range edits {
+			if e.start < last || e.end > len(src) {
+				return fmt.Errorf("%s: edits out of range; has it changed since it was analyzed?", filename)
+			}
+			out.Write(src[last:e.start])
+			out.WriteString(e.newText)
+			last = e.end
+		}
+		out.Write(src[last:])
+		formatted, err := format.Source(out.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s: fixed file doesn't format, so not applying any fixes: %v", filename, err)
+		}
+		contents[filename] = formatted
+	}
+
+	// Write each file to a temporary file next to it first, so a failure
+	// partway leaves no file half-written, and as few as possible changed.
+	temps := map[string]string{}
+	defer func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}()
+	for filename, content := range contents {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		temp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".fix*")
+		if err != nil {
+			return err
+		}
+		temps[filename] = temp.Name()
+		_, err = temp.Write(content)
+		if closeErr := temp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(temp.Name(), info.Mode())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for filename, temp := range temps {
+		if err := os.Rename(temp, filename); err != nil {
+			return err
+		}
+		delete(temps, filename)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Command typedcontext-fix applies the suggested fixes of the typed context
+// analyzers across a whole module at once:
+//
+//	typedcontext-fix ./...
+//
+// The drivers' -fix flag applies fixes one package at a time, so when the
+// findings of several packages call for edits to the same interface
+// declaration, the edits can clash, or be applied against a file another
+// package's fixes have already changed.  typedcontext-fix instead collects
+// every fix first, merges identical edits (the same fix reported for a
+// package and its test variant, or by several packages), and accepts each
+// fix only if none of its edits overlap an edit already accepted.  Then it
+// gofmts every changed file, and writes them only if all of them format
+// successfully; so either all the accepted fixes are applied, or none are.
+//
+// Fixes which conflict with an accepted one are listed, and the command
+// exits with status 3; running it again after the first round often
+// resolves them, since the analyzers see the fixed code.  With -n, it lists
+// the fixes it would apply, without changing anything.
+//
+// Analyzer flags are accepted as for the linter, prefixed by the analyzer
+// name, e.g. -typedcontextinterface.runners=example.com/pool.Submit, and
+// configuration files are read as usual.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+var (
+	dryRun = flag.Bool("n", false, "list the fixes which would be applied, but don't apply them")
+	tests  = flag.Bool("test", true, "also analyze test packages")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-fix [flags] [packages]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-fix: ")
+	for _, analyzer := range contextLinter.Analyzers {
+		prefix := analyzer.Name + "."
+		analyzer.Flags.VisitAll(func(f *flag.Flag) {
+			flag.Var(f.Value, prefix+f.Name, f.Usage)
+		})
+	}
+	flag.Usage = usage
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	fixes, err := collectFixes(patterns, *tests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	accepted, conflicts := reconcile(fixes)
+
+	for _, fix := range accepted {
+		verb := "fixed"
+		if *dryRun {
+			verb = "would fix"
+		}
+		fmt.Printf("%s: %s: %s\n", fix.posn, verb, fix.message)
+	}
+	if !*dryRun {
+		if err := apply(accepted); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, conflict := range conflicts {
+		fmt.Printf("%s: not fixed, since it conflicts with the fix at %s: %s\n",
+			conflict.fix.posn, conflict.with.posn, conflict.fix.message)
+	}
+	if len(conflicts) > 0 {
+		os.Exit(3) // like the linter, when problems remain
+	}
+}
@@ -0,0 +1,153 @@
+// Command typedcontext-gen generates code for composite typed-context
+// interfaces.  It's designed to be used with go:generate, like
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=AppContext
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		LoggerContext
+//	}
+//
+// which generates a constructor ComposeAppContext taking a ctx and one
+// argument per provider.  With -lazy, it also generates ComposeLazyAppContext,
+// taking a function per provider, each called on first use of its accessor.
+// See package typedcontext for details.
+//
+// Alternatively, with -schema=FILE, it generates the interfaces themselves,
+// from a YAML file listing the package's providers and the capability
+// groups built from them (see gen.Schema), along with the constructors for
+// the capability groups, their test doubles (like typedcontext-mockgen's),
+// and the registration of providers propagated across process boundaries,
+// all in one file (by default, <dir>/<schema>_typedcontext.go):
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -schema=contexts.yaml
+//
+// Adding a provider is then a matter of adding it to the schema, and
+// including it in the capability groups which need it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khan/typed-context/typedcontext/gen"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of interface names; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_typedcontext.go")
+	lazy      = flag.Bool("lazy", false, "also generate ComposeLazyT constructors, whose providers are built on first use")
+	schema    = flag.String("schema", "", "YAML file describing the providers and capability groups to generate interfaces for, instead of -type")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-gen -type=T[,T...] [directory]\n")
+	fmt.Fprintf(os.Stderr, "       typedcontext-gen -schema=FILE [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-gen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if (*typeNames == "") == (*schema == "") || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if *schema != "" {
+		generateSchema(dir)
+		return
+	}
+	names := strings.Split(*typeNames, ",")
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_typedcontext.go")
+	}
+
+	pkg, err := gen.LoadPackage(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := gen.NewGenerator(pkg, "typedcontext-gen")
+	for _, name := range names {
+		composite, err := gen.LookupComposite(pkg, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.Compose(composite)
+		if *lazy {
+			g.ComposeLazy(composite)
+		}
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateSchema generates the code for -schema, in the package in dir.
+func generateSchema(dir string) {
+	outputName := *output
+	if outputName == "" {
+		base := strings.TrimSuffix(filepath.Base(*schema), filepath.Ext(*schema))
+		outputName = filepath.Join(dir, strings.ToLower(base)+"_typedcontext.go")
+	}
+	schemaFile, err := gen.ReadSchema(*schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// First, generate the interfaces; then, load the package with them, so
+	// we can generate the rest from their types.
+	pkg, err := gen.LoadPackage(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := gen.NewGenerator(pkg, "typedcontext-gen")
+	g.Declare(schemaFile)
+	declarations, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pkg, err = gen.LoadPackageWith(dir, outputName, declarations)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, capability := range schemaFile.Capabilities {
+		composite, err := gen.LookupComposite(pkg, capability.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.Compose(composite)
+		if capability.Lazy || *lazy {
+			g.ComposeLazy(composite)
+		}
+		if capability.Mock {
+			g.Mock(composite, "Mock"+capability.Name)
+		}
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,49 @@
+// Command typedcontext-lsp is a small language server which runs the typed
+// context analyzers whenever a Go file is opened or saved, publishes their
+// diagnostics, and offers their suggested fixes (like removing a redundant
+// embed) as code actions.  It's meant to run alongside gopls, so that editors
+// show typed context problems as you work rather than in CI.
+//
+// It speaks LSP over stdin and stdout.  Analyzer flags are accepted as for
+// the linter, prefixed by the analyzer name, e.g.
+//
+//	typedcontext-lsp -typedcontextinterface.runners=example.com/pool.Submit
+//
+// and configuration files are read as usual.  Files are analyzed as saved on
+// disk, so diagnostics update on save, not on every keystroke.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-lsp [flags]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Our stdout is the protocol stream; logs go to stderr, which editors
+	// usually show in the server's output panel.
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-lsp: ")
+	for _, analyzer := range contextLinter.Analyzers {
+		prefix := analyzer.Name + "."
+		analyzer.Flags.VisitAll(func(f *flag.Flag) {
+			flag.Var(f.Value, prefix+f.Name, f.Usage)
+		})
+	}
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	os.Exit(newServer(newConn(os.Stdin, os.Stdout)).serve())
+}
@@ -0,0 +1,184 @@
+package main
+
+// This file defines the small subset of the Language Server Protocol we
+// speak, and its JSON-RPC framing: each message is a JSON object preceded by
+// a Content-Length header.  See
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// message is an incoming request or notification.  (We never send requests
+// to the client, so we never receive responses.)
+type message struct {
+	// ID is unset for notifications.
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// responseError is the error of a failed request.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type position struct {
+	Line      int `json:"line"`      // zero-based
+	Character int `json:"character"` // zero-based, in UTF-16 code units
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type diagnosticRelatedInformation struct {
+	Location location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+// The LSP DiagnosticSeverities we report with: advice (see
+// contextLinter.AdvicePrefix) is information, and everything else a warning.
+const (
+	severityWarning     = 2
+	severityInformation = 3
+)
+
+type diagnostic struct {
+	Range              lspRange                       `json:"range"`
+	Severity           int                            `json:"severity"`
+	Code               string                         `json:"code,omitempty"`
+	Source             string                         `json:"source"`
+	Message            string                         `json:"message"`
+	RelatedInformation []diagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type workspaceEdit struct {
+	Changes map[string][]textEdit `json:"changes"`
+}
+
+type codeAction struct {
+	Title       string        `json:"title"`
+	Kind        string        `json:"kind"`
+	Diagnostics []diagnostic  `json:"diagnostics,omitempty"`
+	Edit        workspaceEdit `json:"edit"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// textDocumentParams are the params of didOpen, didSave and didClose, as far
+// as we care.
+type textDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        lspRange               `json:"range"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// before returns true if p comes strictly before other.
+func (p position) before(other position) bool {
+	return p.Line < other.Line || p.Line == other.Line && p.Character < other.Character
+}
+
+// overlaps returns true if the two ranges overlap, or touch: a cursor at the
+// end of a diagnostic's range still gets its code actions.
+func (r lspRange) overlaps(other lspRange) bool {
+	return !r.End.before(other.Start) && !other.End.before(r.Start)
+}
+
+// conn reads and writes LSP messages.  Writes may come from several
+// goroutines.
+type conn struct {
+	reader *textproto.Reader
+
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{reader: textproto.NewReader(bufio.NewReader(r)), writer: w}
+}
+
+// read returns the next message.
+func (c *conn) read() (*message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// write sends the given JSON-RPC message, adding its version.
+func (c *conn) write(msg map[string]interface{}) error {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// reply responds to the request with the given ID; if err is set, it's
+// an error response, and result is ignored.
+func (c *conn) reply(id json.RawMessage, result interface{}, err *responseError) error {
+	if err != nil {
+		return c.write(map[string]interface{}{"id": id, "error": err})
+	}
+	return c.write(map[string]interface{}{"id": id, "result": result})
+}
+
+// notify sends the client a notification.
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(map[string]interface{}{"method": method, "params": params})
+}
@@ -0,0 +1,384 @@
+package main
+
+// This file defines the server proper: it analyzes the package of each file
+// the editor opens or saves, publishes the diagnostics, and remembers their
+// suggested fixes so it can offer them as code actions.
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// fix is a suggested fix, ready to offer as a code action for the given
+// diagnostic.
+type fix struct {
+	diagnostic diagnostic
+	action     codeAction
+}
+
+// server holds the state of the language server.
+type server struct {
+	conn *conn
+
+	// analyzing serializes analyses: the linter's settings and type caches
+	// are global, and each analysis resets them when it starts.
+	analyzing sync.Mutex
+	// running counts the analyses analyzeAsync has started and which
+	// haven't finished, so tests can wait for them.
+	running sync.WaitGroup
+
+	mu sync.Mutex
+	// generations counts, by directory, the analyses requested, so that one
+	// superseded by a later request for the same package is dropped rather
+	// than publishing stale diagnostics.
+	generations map[string]int
+	// published lists, by directory, the URIs we've published diagnostics
+	// for, so we can clear them when they're fixed.
+	published map[string][]string
+	// fixes are the fixes of the diagnostics we've published, by URI.
+	fixes map[string][]fix
+	// shutdown is set once the client has asked us to shut down.
+	shutdown bool
+}
+
+func newServer(conn *conn) *server {
+	return &server{
+		conn:        conn,
+		generations: map[string]int{},
+		published:   map[string][]string{},
+		fixes:       map[string][]fix{},
+	}
+}
+
+// uriToPath returns the filename of the given file: URI.
+func uriToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI %s", uri)
+	}
+	return filepath.FromSlash(parsed.Path), nil
+}
+
+// pathToURI returns the file: URI of the given filename.
+func pathToURI(filename string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+}
+
+// positions converts token.Pos values to LSP positions, reading (and
+// caching) files as needed to count columns in UTF-16 code units.
+type positions struct {
+	fset  *token.FileSet
+	files map[string][]byte
+}
+
+// position returns the URI and position of pos.
+func (p *positions) position(pos token.Pos) (string, position) {
+	posn := p.fset.Position(pos)
+	content, ok := p.files[posn.Filename]
+	if !ok {
+		content, _ = os.ReadFile(posn.Filename)
+		p.files[posn.Filename] = content
+	}
+	lineStart := posn.Offset - (posn.Column - 1)
+	if lineStart < 0 || posn.Offset > len(content) {
+		// We can't see the line; count bytes, which is right for ASCII.
+		return pathToURI(posn.Filename), position{posn.Line - 1, posn.Column - 1}
+	}
+	character := 0
+	for _, r := range string(content[lineStart:posn.Offset]) {
+		character += utf16.RuneLen(r)
+	}
+	return pathToURI(posn.Filename), position{posn.Line - 1, character}
+}
+
+// location returns the location of the range [start, end), where end may be
+// token.NoPos for an empty range.
+func (p *positions) location(start, end token.Pos) location {
+	uri, startPosition := p.position(start)
+	endPosition := startPosition
+	if end.IsValid() {
+		_, endPosition = p.position(end)
+	}
+	return location{uri, lspRange{startPosition, endPosition}}
+}
+
+// convert returns the LSP form of the given analysis diagnostic, and its
+// fixes.
+func (p *positions) convert(d analysis.Diagnostic) (string, diagnostic, []codeAction) {
+	loc := p.location(d.Pos, d.End)
+	severity := severityWarning
+	if strings.HasPrefix(d.Message, contextLinter.AdvicePrefix) {
+		severity = severityInformation
+	}
+	result := diagnostic{
+		Range:    loc.Range,
+		Severity: severity,
+		Code:     d.Category,
+		Source:   "typedcontext",
+		Message:  d.Message,
+	}
+	for _, related := range d.Related {
+		result.RelatedInformation = append(result.RelatedInformation,
+			diagnosticRelatedInformation{p.location(related.Pos, related.End), related.Message})
+	}
+
+	var actions []codeAction
+	for _, suggested := range d.SuggestedFixes {
+		action := codeAction{
+			Title:       suggested.Message,
+			Kind:        "quickfix",
+			Diagnostics: []diagnostic{result},
+			Edit:        workspaceEdit{Changes: map[string][]textEdit{}},
+		}
+		for _, edit := range suggested.TextEdits {
+			editLoc := p.location(edit.Pos, edit.End)
+			action.Edit.Changes[editLoc.URI] = append(action.Edit.Changes[editLoc.URI],
+				textEdit{editLoc.Range, string(edit.NewText)})
+		}
+		actions = append(actions, action)
+	}
+	return loc.URI, result, actions
+}
+
+// _current returns true if the given analysis of the given directory is the
+// latest requested.  It must be called with s.mu held.
+func (s *server) _current(dir string, generation int) bool {
+	return s.generations[dir] == generation
+}
+
+// analyze runs the analyzers over the packages containing the given file,
+// as saved on disk, and publishes the diagnostics for all their files,
+// unless a later analysis of the same directory (the given generation of it
+// being this one) has been requested meanwhile.
+func (s *server) analyze(filename string, generation int) error {
+	dir := filepath.Dir(filename)
+
+	s.analyzing.Lock()
+	defer s.analyzing.Unlock()
+	s.mu.Lock()
+	current := s._current(dir, generation)
+	s.mu.Unlock()
+	if !current {
+		return nil // the later one will publish
+	}
+	// Forget the settings and types of the last run, so we see any
+	// configuration changed since, and don't keep every version of every
+	// type we've ever loaded.
+	contextLinter.ResetSettings()
+
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Dir: dir, Tests: true}
+	pkgs, err := packages.Load(config, "file="+filename)
+	if err != nil {
+		return err
+	}
+	var loaded []*packages.Package
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) == 0 {
+			loaded = append(loaded, pkg)
+		}
+	}
+	if len(loaded) == 0 {
+		// Probably a syntax or type error; the editor's other tools will
+		// say so.  Keep the old diagnostics until it's fixed.
+		return nil
+	}
+
+	graph, err := checker.Analyze(contextLinter.Analyzers, loaded, nil)
+	if err != nil {
+		return err
+	}
+
+	// Start with an empty list for each file, so fixed diagnostics go away.
+	diagnostics := map[string][]diagnostic{}
+	fixes := map[string][]fix{}
+	for _, pkg := range loaded {
+		for _, filename := range pkg.CompiledGoFiles {
+			diagnostics[pathToURI(filename)] = []diagnostic{}
+		}
+	}
+	seen := map[string]bool{}
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return fmt.Errorf("%s: %v", act.Analyzer.Name, act.Err)
+		}
+		p := &positions{fset: act.Package.Fset, files: map[string][]byte{}}
+		for _, d := range act.Diagnostics {
+			// Files in both p and p [p.test] are reported twice.
+			key := act.Package.Fset.Position(d.Pos).String() + ": " + d.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			uri, result, actions := p.convert(d)
+			diagnostics[uri] = append(diagnostics[uri], result)
+			for _, action := range actions {
+				fixes[uri] = append(fixes[uri], fix{result, action})
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if !s._current(dir, generation) {
+		s.mu.Unlock()
+		return nil
+	}
+	// Clear any files we published for before but not this time (e.g.
+	// because they were removed from the package).
+	for _, uri := range s.published[dir] {
+		if _, ok := diagnostics[uri]; !ok {
+			diagnostics[uri] = []diagnostic{}
+		}
+	}
+	uris := make([]string, 0, len(diagnostics))
+	for uri := range diagnostics {
+		uris = append(uris, uri)
+		s.fixes[uri] = fixes[uri]
+	}
+	sort.Strings(uris)
+	s.published[dir] = uris
+	s.mu.Unlock()
+
+	for _, uri := range uris {
+		err := s.conn.notify("textDocument/publishDiagnostics",
+			publishDiagnosticsParams{uri, diagnostics[uri]})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// analyzeAsync analyzes the given document in the background, logging any
+// errors.  Analyses run one at a time; any still waiting for the same
+// directory when a new one is requested are dropped.
+func (s *server) analyzeAsync(params json.RawMessage) {
+	var doc textDocumentParams
+	if err := json.Unmarshal(params, &doc); err != nil {
+		log.Print(err)
+		return
+	}
+	filename, err := uriToPath(doc.TextDocument.URI)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if filepath.Ext(filename) != ".go" {
+		return
+	}
+	dir := filepath.Dir(filename)
+	s.mu.Lock()
+	s.generations[dir]++
+	generation := s.generations[dir]
+	s.mu.Unlock()
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		if err := s.analyze(filename, generation); err != nil {
+			log.Printf("%s: %v", filename, err)
+		}
+	}()
+}
+
+// codeActions returns the fixes for the diagnostics in the given range.
+func (s *server) codeActions(params codeActionParams) []codeAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := []codeAction{}
+	uri := params.TextDocument.URI
+	if filename, err := uriToPath(uri); err == nil {
+		uri = pathToURI(filename) // normalize, to match what we published
+	}
+	for _, fix := range s.fixes[uri] {
+		if fix.diagnostic.Range.overlaps(params.Range) {
+			actions = append(actions, fix.action)
+		}
+	}
+	return actions
+}
+
+// handle handles a single message, and returns true if the server should
+// exit (with the given status).
+func (s *server) handle(msg *message) (exit bool, status int, err error) {
+	switch msg.Method {
+	case "initialize":
+		return false, 0, s.conn.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    0, // none: we analyze what's on disk
+					"save":      map[string]interface{}{"includeText": false},
+				},
+				"codeActionProvider": map[string]interface{}{
+					"codeActionKinds": []string{"quickfix"},
+				},
+			},
+			"serverInfo": map[string]interface{}{"name": "typedcontext-lsp"},
+		}, nil)
+	case "textDocument/didOpen", "textDocument/didSave":
+		s.analyzeAsync(msg.Params)
+		return false, 0, nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return false, 0, s.conn.reply(msg.ID, nil,
+				&responseError{codeInvalidParams, err.Error()})
+		}
+		return false, 0, s.conn.reply(msg.ID, s.codeActions(params), nil)
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return false, 0, s.conn.reply(msg.ID, nil, nil)
+	case "exit":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.shutdown {
+			return true, 0, nil
+		}
+		return true, 1, nil
+	}
+
+	if msg.ID != nil {
+		return false, 0, s.conn.reply(msg.ID, nil,
+			&responseError{codeMethodNotFound, "unsupported method " + msg.Method})
+	}
+	return false, 0, nil // a notification we don't care about, like didClose
+}
+
+// serve handles messages until the client tells us to exit, and returns the
+// exit status.
+func (s *server) serve() int {
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		exit, status, err := s.handle(msg)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if exit {
+			return status
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// pointer takes a pointer to its context, so the analyzers offer a fix
+// passing the interface itself.
+const pointer = `package p
+
+import "context"
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+func log(ctx *LoggerContext) {
+	(*ctx).Logger()
+}
+`
+
+// fixed is pointer, fixed.
+const fixed = `package p
+
+import "context"
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+func log(ctx LoggerContext) {
+	ctx.Logger()
+}
+`
+
+// buffer is a bytes.Buffer safe for the server's goroutines to write to.
+type buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// published returns the diagnostics the server has published so far for
+// uri, in order, and forgets them.
+func (b *buffer) published(t *testing.T, uri string) [][]diagnostic {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	reader := textproto.NewReader(bufio.NewReader(&b.buf))
+	var published [][]diagnostic
+	for {
+		header, err := reader.ReadMIMEHeader()
+		if err == io.EOF {
+			return published
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader.R, body); err != nil {
+			t.Fatal(err)
+		}
+		var msg struct {
+			Method string                   `json:"method"`
+			Params publishDiagnosticsParams `json:"params"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Method == "textDocument/publishDiagnostics" && msg.Params.URI == uri {
+			published = append(published, msg.Params.Diagnostics)
+		}
+	}
+}
+
+// save writes source to filename, and tells the server, twice over, before
+// waiting for the analyses, so they overlap.
+func save(t *testing.T, s *server, filename, source string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	params, err := json.Marshal(textDocumentParams{textDocumentIdentifier{pathToURI(filename)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, _, err := s.handle(&message{Method: "textDocument/didSave", Params: params}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.running.Wait()
+}
+
+func TestOverlappingAnalyses(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/p\n\ngo 1.25\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOFLAGS", "")
+	filename := filepath.Join(dir, "p.go")
+	uri := pathToURI(filename)
+	out := &buffer{}
+	s := newServer(newConn(&bytes.Buffer{}, out))
+	everywhere := codeActionParams{textDocumentIdentifier{uri}, lspRange{End: position{Line: 100}}}
+
+	// Of the two analyses, only the later publishes, and its fixes are
+	// offered once.
+	save(t, s, filename, pointer)
+	published := out.published(t, uri)
+	if len(published) != 1 {
+		t.Fatalf("got %d publications of %s, want 1: %v", len(published), uri, published)
+	}
+	if len(published[0]) != 1 {
+		t.Fatalf("got diagnostics %v, want one", published[0])
+	}
+	actions := s.codeActions(everywhere)
+	if len(actions) != 1 {
+		t.Fatalf("got code actions %v, want one", actions)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) == 0 || actions[0].Diagnostics[0].Message != published[0][0].Message {
+		t.Errorf("got code action %+v, want one fixing %q", actions[0], published[0][0].Message)
+	}
+
+	// Once it's fixed, both the diagnostic and its fix go away.
+	save(t, s, filename, fixed)
+	published = out.published(t, uri)
+	if len(published) != 1 || len(published[0]) != 0 {
+		t.Errorf("got publications %v, want just one, of no diagnostics", published)
+	}
+	if actions := s.codeActions(everywhere); len(actions) != 0 {
+		t.Errorf("got code actions %v after the fix, want none", actions)
+	}
+}
@@ -0,0 +1,79 @@
+// Command typedcontext-mockgen generates test doubles for composite
+// typed-context interfaces.  It's designed to be used with go:generate, like
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-mockgen -type=AppContext
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		LoggerContext
+//	}
+//
+// which generates a struct MockAppContext with settable fields
+// RequestProvider and LoggerProvider, implementing AppContext (and thus
+// RequestContext and LoggerContext), which records the accessors called.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khan/typed-context/typedcontext/gen"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of interface names; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_mock.go")
+	prefix    = flag.String("prefix", "Mock", "prefix for the names of the generated test doubles")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-mockgen -type=T[,T...] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-mockgen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeNames, ",")
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_mock.go")
+	}
+
+	pkg, err := gen.LoadPackage(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := gen.NewGenerator(pkg, "typedcontext-mockgen")
+	for _, name := range names {
+		composite, err := gen.LookupComposite(pkg, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.Mock(composite, *prefix+name)
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+// Command typedcontext-query prints the typed context interfaces a function
+// needs, so you can see what its signature should request while you design
+// it, rather than waiting for the linter to complain.  For example,
+//
+//	typedcontext-query ./05-strongly-typed-context.DoTheThing
+//
+// prints, for each context parameter of DoTheThing, the smallest set of
+// interfaces it could request.  Methods are named like
+// import/path.Type.Method or (*import/path.Type).Method.
+//
+// It accepts the same flags as the typedcontextinterface analyzer (like
+// -runners and -sinks), and reads the same configuration files.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+var jsonOutput = flag.Bool("json", false, "emit JSON output")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-query [flags] import/path.Func\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-query: ")
+	contextLinter.TypedContextInterfaceAnalyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	requirements, err := contextLinter.Requirements(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(requirements); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(requirements) == 0 {
+		fmt.Printf("%s takes no context parameters\n", flag.Arg(0))
+	}
+	for _, requirement := range requirements {
+		needs := "nothing"
+		if len(requirement.Needs) > 0 {
+			needs = strings.Join(requirement.Needs, ", ")
+		}
+		fmt.Printf("%s (declared %s) needs: %s\n",
+			requirement.Param, requirement.Declared, needs)
+	}
+}
@@ -0,0 +1,81 @@
+// Command typedcontext-stats ranks the named typed context interfaces of a
+// module by how many functions request them, for deciding which to split or
+// merge.  For example,
+//
+//	typedcontext-stats ./...
+//
+// prints, for each interface defined in the packages matching the patterns,
+// how many functions request it (directly or via embeds), how many of those
+// use it, and how many packages they're in, most requested first.  An
+// interface many functions request but few use should likely be split, and
+// one few functions request merged into another.  Run it over the whole
+// module: functions in packages not matched aren't counted.
+//
+// It accepts the same flags as the typedcontextinterface analyzer (like
+// -runners and -sinks), and reads the same configuration files.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+var (
+	jsonOutput = flag.Bool("json", false, "emit JSON output, including the packages involved")
+	top        = flag.Int("n", 0, "print only the first n interfaces (0 for all)")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-stats [flags] [packages]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-stats: ")
+	contextLinter.TypedContextInterfaceAnalyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	flag.Usage = usage
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	stats, err := contextLinter.InterfaceStats(patterns...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *top > 0 && len(stats) > *top {
+		stats = stats[:*top]
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(stats); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "requesters\tusers\tused%\tpackages\t\tinterface")
+	for _, stat := range stats {
+		percent := "-"
+		if stat.Requesters > 0 {
+			percent = fmt.Sprintf("%d%%", 100*stat.Users/stat.Requesters)
+		}
+		fmt.Fprintf(writer, "%d\t%d\t%s\t%d\t\t%s\n",
+			stat.Requesters, stat.Users, percent, len(stat.Packages), stat.Interface)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+// Command typedcontext-testgen generates conformance tests for the
+// implementations of composite typed-context interfaces.  It's designed to
+// be used with go:generate, like
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-testgen -type=AppContext -build=newAppContext
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		LoggerContext
+//	}
+//
+//	func newAppContext(ctx context.Context) *appContext { ... }
+//
+// which generates a test TestAppContextConformance, in
+// appcontext_conformance_test.go, checking with
+// typedcontexttest.Conformance that the context newAppContext builds
+// returns a non-nil provider from Request() and Logger(), and delegates the
+// methods of context.Context to ctx.  Regenerate it when AppContext changes.
+//
+// The function passed to -build takes a context.Context (or, for a server
+// interface, which doesn't embed one, optionally nothing), and returns the
+// implementation; it must be declared in a non-test file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khan/typed-context/typedcontext/gen"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of interface names; must be set")
+	builds    = flag.String("build", "", "comma-separated list of functions building an implementation of each interface; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_conformance_test.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-testgen -type=T[,T...] -build=F[,F...] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-testgen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || *builds == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeNames, ",")
+	buildNames := strings.Split(*builds, ",")
+	if len(names) != len(buildNames) {
+		log.Fatalf("-type lists %d interfaces, but -build lists %d functions", len(names), len(buildNames))
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_conformance_test.go")
+	}
+
+	// Loaded from source, since the functions passed to -build are likely
+	// unexported.
+	loaded, _, err := gen.LoadPackages(dir, outputName, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pkg := loaded.Types
+
+	g := gen.NewGenerator(pkg, "typedcontext-testgen")
+	for i, name := range names {
+		composite, err := gen.LookupComposite(pkg, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		build, err := gen.LookupBuilder(pkg, buildNames[i], composite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.Conformance(composite, build)
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Command typedcontext-wrapgen generates wrappers for the combinations of
+// typed-context interfaces that code requests without naming them, like
+//
+//	func charge(ctx interface {
+//		DatabaseContext
+//		LoggerContext
+//	}, amount int) error
+//
+// It's designed to be used with go:generate, in the package declaring the
+// interfaces, like
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-wrapgen -patterns=example.com/app/...
+//
+// which finds the combinations used in the packages matching -patterns (the
+// same way the linter's typedcontextshapes analyzer does), and generates,
+// for each, a struct WrappedDatabaseLogger implementing it, built by
+// WrapDatabaseLogger(ctx, database, logger).  Middleware building a context
+// for a new combination can then do so without allocating, and without
+// writing (or forgetting an accessor of) a struct by hand.  See
+// gen.Generator.Wrap for details.
+//
+// Combinations including an interface which the package can't refer to (an
+// unexported one elsewhere, one declared in a test, or one in a package
+// which imports this one) are skipped.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+	"github.com/khan/typed-context/typedcontext/gen"
+)
+
+var (
+	patterns = flag.String("patterns", "./...", "comma-separated list of package patterns whose combinations to wrap, relative to the directory")
+	output   = flag.String("output", "", "output file name; default <dir>/wrappers_typedcontext.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: typedcontext-wrapgen [-patterns=P[,P...]] [directory]\n")
+	flag.PrintDefaults()
+}
+
+// importer reports which packages import which, among those loaded.
+type importer struct {
+	pkgs map[string]*packages.Package
+	// imports caches the answers of imports, by "importer importee".
+	imports map[string]bool
+}
+
+// importsPath returns true if the package with path from imports (directly or
+// indirectly) the package with path to.
+func (imp *importer) importsPath(from, to string) bool {
+	key := from + " " + to
+	if result, ok := imp.imports[key]; ok {
+		return result
+	}
+	imp.imports[key] = false // in case of cycles
+	result := false
+	if pkg, ok := imp.pkgs[from]; ok {
+		for path := range pkg.Imports {
+			if path == to || imp.importsPath(path, to) {
+				result = true
+				break
+			}
+		}
+	}
+	imp.imports[key] = result
+	return result
+}
+
+// referable returns true if code in the package target can refer to the
+// given interface.
+func referable(typ types.Type, target *packages.Package, imp *importer) bool {
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	obj := named.Obj()
+	if strings.HasSuffix(target.Fset.Position(obj.Pos()).Filename, "_test.go") {
+		return false
+	}
+	path := obj.Pkg().Path()
+	if path == target.PkgPath {
+		return true
+	}
+	return obj.Exported() && obj.Pkg().Name() != "main" && !imp.importsPath(path, target.PkgPath)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("typedcontext-wrapgen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, "wrappers_typedcontext.go")
+	}
+
+	target, pkgs, err := gen.LoadPackages(dir, outputName, strings.Split(*patterns, ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+	imp := &importer{pkgs: map[string]*packages.Package{}, imports: map[string]bool{}}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.ID == pkg.PkgPath {
+			imp.pkgs[pkg.PkgPath] = pkg
+		}
+	})
+
+	g := gen.NewGenerator(target.Types, "typedcontext-wrapgen")
+	seen := map[string]bool{}
+	usedNames := map[string]bool{}
+shapes:
+	for _, shape := range contextLinter.ShapesIn(pkgs) {
+		key := strings.Join(shape.Leaves, ", ")
+		if seen[key] {
+			continue // used in several packages
+		}
+		seen[key] = true
+
+		var names []string
+		for _, leaf := range shape.Types {
+			if !referable(leaf, target, imp) {
+				continue shapes
+			}
+			names = append(names, types.Unalias(leaf).(*types.Named).Obj().Name())
+		}
+		name := gen.CombinationName(names)
+		for i := 2; usedNames[name]; i++ {
+			name = gen.CombinationName(names) + strconv.Itoa(i)
+		}
+		usedNames[name] = true
+
+		combination, err := gen.NewCombination(name, shape.Types)
+		if err != nil {
+			log.Printf("skipping interface{ %s }: %v", key, err)
+			continue
+		}
+		g.Wrap(combination)
+	}
+
+	source, err := g.Source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputName, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package gqlgenplugin makes gqlgen resolvers take typed contexts.
+//
+// gqlgen generates resolver interfaces whose methods take a context.Context,
+// which is why lintutil.IsResolverFunc exempts resolvers from our linters.
+// This plugin rewrites the resolver implementations gqlgen generates (e.g.
+// schema.resolvers.go) so that each method takes a typed composite context
+// instead, and generates a shim for each resolver type which implements
+// gqlgen's interface by narrowing the context.Context it's given, using a
+// function you provide, and calling the typed method.  For example,
+//
+//	func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error)
+//
+// becomes
+//
+//	func (r *queryResolver) User(ctx app.Context, id string) (*model.User, error)
+//
+// plus, in typedcontext_resolvers.go,
+//
+//	type typedQueryResolver struct{ *queryResolver }
+//
+//	func (r typedQueryResolver) User(ctx context.Context, id string) (ret0 *model.User, err error) {
+//		typedCtx, err := app.Narrow(ctx)
+//		if err != nil {
+//			return ret0, err
+//		}
+//		return r.queryResolver.User(typedCtx, id)
+//	}
+//
+// and the root resolver's `Query()` method returns a typedQueryResolver.  The
+// resolvers themselves can then use typed accessors without any assertion
+// boilerplate, and are linted like any other function.
+//
+// gqlgen regenerates the resolver signatures (but keeps their bodies) each
+// time it runs, so the rewrite must be run after every generation.  Since the
+// plugin rewrites files gqlgen has already written, it's used from a custom
+// gqlgen entrypoint:
+//
+//	plugin := gqlgenplugin.New("github.com/acme/app.Context", "github.com/acme/app.Narrow")
+//	if err := api.Generate(cfg, api.AddPlugin(plugin)); err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := plugin.RewriteResolvers(cfg.Resolver.Dir()); err != nil {
+//		log.Fatal(err)
+//	}
+package gqlgenplugin
+
+import (
+	"strings"
+)
+
+// shimFilename is the file, in the resolver directory, to which we write the
+// shims.
+const shimFilename = "typedcontext_resolvers.go"
+
+// Plugin rewrites gqlgen resolvers to take a typed context.
+type Plugin struct {
+	// contextType is the typed context the resolvers should take.
+	contextType qualifiedName
+	// narrow is a function `func(context.Context) (T, error)` which returns
+	// the typed context for a context.Context, where T is contextType.
+	narrow qualifiedName
+}
+
+// New returns a plugin which rewrites resolvers to take the given typed
+// context, narrowed with the given function.
+//
+// Both are given as "import/path.Name", or just "Name" if they're in the
+// resolver package.  narrow must have the signature
+// `func(context.Context) (T, error)` where T is the context type; typically it
+// will type-assert its argument, or use one of the typedcontext adapters.
+func New(contextType, narrow string) *Plugin {
+	return &Plugin{
+		contextType: parseQualifiedName(contextType),
+		narrow:      parseQualifiedName(narrow),
+	}
+}
+
+// Name returns the name of the plugin, as gqlgen's plugin.Plugin requires.
+func (p *Plugin) Name() string {
+	return "typedcontext"
+}
+
+// qualifiedName is a name, possibly in another package.
+type qualifiedName struct {
+	// path is the import path of the package, or "" for the resolver
+	// package.
+	path string
+	name string
+	// pkg is the name of the package, once loaded; see
+	// Plugin._loadPackageNames.
+	pkg string
+}
+
+func parseQualifiedName(name string) qualifiedName {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return qualifiedName{name: name}
+	}
+	return qualifiedName{path: name[:i], name: name[i+1:]}
+}
+
+// expr returns the name as a Go expression in the resolver package.
+func (name qualifiedName) expr() string {
+	if name.path == "" {
+		return name.name
+	}
+	return name.pkg + "." + name.name
+}
@@ -0,0 +1,389 @@
+package gqlgenplugin
+
+// This file does the actual rewriting of the resolvers, and generates the
+// shims.
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
+)
+
+// _resolverMethod is a resolver method we've rewritten, and need a shim for.
+type _resolverMethod struct {
+	decl *ast.FuncDecl
+	file *ast.File
+	// recvName is the name of the resolver type, e.g. "queryResolver".
+	recvName string
+}
+
+// _isContextContext returns true if expr is `context.Context`.  (We work
+// without type information, since the resolver package generally won't
+// compile until we're done.)
+func _isContextContext(expr ast.Expr, file *ast.File) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"context"` {
+			return imp.Name == nil && pkg.Name == "context" ||
+				imp.Name != nil && imp.Name.Name == pkg.Name
+		}
+	}
+	return false
+}
+
+// _recvTypeName returns the name of the receiver type of the given method,
+// e.g. "queryResolver" for `func (r *queryResolver) ...`.
+func _recvTypeName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	typ := decl.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	return ident.Name
+}
+
+// _importName returns the name with which to import the package of the
+// given name: none if its package name matches the last element of its
+// path, or else its package name, so that readers (and goimports) needn't
+// guess.
+func _importName(name qualifiedName) string {
+	if name.pkg == name.path[strings.LastIndex(name.path, "/")+1:] {
+		return ""
+	}
+	return name.pkg
+}
+
+// _shimName returns the name of the shim type for the given resolver type.
+func _shimName(recvName string) string {
+	return "typed" + strings.ToUpper(recvName[:1]) + recvName[1:]
+}
+
+// _loadPackageNames fills in the package names of the context type and the
+// narrowing function, as loaded from the given directory.  (The last element
+// of the import path is only a guess: it's wrong for paths like
+// "example.com/app/v2" or "gopkg.in/yaml.v3", and packages may be named
+// anything.)
+func (p *Plugin) _loadPackageNames(dir string) error {
+	names := []*qualifiedName{&p.contextType, &p.narrow}
+	var paths []string
+	for _, name := range names {
+		if name.path != "" && name.pkg == "" {
+			paths = append(paths, name.path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName, Dir: dir}, paths...)
+	if err != nil {
+		return err
+	}
+	pkgNames := map[string]string{}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return pkg.Errors[0]
+		}
+		pkgNames[pkg.PkgPath] = pkg.Name
+	}
+	for _, name := range names {
+		if name.path == "" {
+			continue
+		}
+		if name.pkg = pkgNames[name.path]; name.pkg == "" {
+			return fmt.Errorf("couldn't load package %s", name.path)
+		}
+	}
+	return nil
+}
+
+// RewriteResolvers rewrites the resolvers in the given directory to take the
+// typed context, and (re)generates the shims.
+//
+// It's safe to run on resolvers which were already rewritten.
+func (p *Plugin) RewriteResolvers(dir string) error {
+	if err := p._loadPackageNames(dir); err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	var methods []_resolverMethod
+	files := map[string]*ast.File{}
+	changed := map[string]bool{}
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") || filepath.Base(filename) == shimFilename {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		files[filename] = file
+
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || !funcDecl.Name.IsExported() {
+				continue
+			}
+			recvName := _recvTypeName(funcDecl)
+			if !strings.HasSuffix(recvName, "Resolver") {
+				continue
+			}
+			params := funcDecl.Type.Params.List
+			if len(params) == 0 || len(params[0].Names) != 1 {
+				continue
+			}
+			if _isContextContext(params[0].Type, file) {
+				// Fresh from gqlgen: rewrite it.
+				params[0].Type = ast.NewIdent(p.contextType.expr())
+				changed[filename] = true
+			} else if _exprString(params[0].Type) != p.contextType.expr() {
+				continue // not a resolver we're interested in
+			}
+			methods = append(methods, _resolverMethod{funcDecl, file, recvName})
+		}
+	}
+
+	// Make the root resolvers return the shims.
+	recvNames := map[string]bool{}
+	for _, method := range methods {
+		recvNames[method.recvName] = true
+	}
+	for filename, file := range files {
+		if p._wrapRootResolvers(file, recvNames) {
+			changed[filename] = true
+		}
+	}
+
+	for filename := range changed {
+		file := files[filename]
+		if p.contextType.path != "" {
+			astutil.AddNamedImport(fset, file, _importName(p.contextType), p.contextType.path)
+		}
+		if err := _writeFile(fset, filename, file); err != nil {
+			return err
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil
+	}
+	return p._writeShims(filepath.Join(dir, shimFilename), methods)
+}
+
+// _wrapRootResolvers rewrites root-resolver methods like
+//	func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+// to return the shim for the resolver type, if we have one.  It returns true
+// if it changed anything.
+func (p *Plugin) _wrapRootResolvers(file *ast.File, recvNames map[string]bool) bool {
+	changed := false
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv == nil || funcDecl.Body == nil ||
+			len(funcDecl.Body.List) != 1 {
+			continue
+		}
+		ret, ok := funcDecl.Body.List[0].(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			continue
+		}
+		unary, ok := ret.Results[0].(*ast.UnaryExpr)
+		if !ok || unary.Op != token.AND {
+			continue
+		}
+		lit, ok := unary.X.(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+		typeName, ok := lit.Type.(*ast.Ident)
+		if !ok || !recvNames[typeName.Name] {
+			continue
+		}
+		ret.Results[0] = &ast.CompositeLit{
+			Type: ast.NewIdent(_shimName(typeName.Name)),
+			Elts: []ast.Expr{unary},
+		}
+		changed = true
+	}
+	return changed
+}
+
+// _exprString formats the given expression as Go source.
+func _exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// _writeFile formats the given file and writes it to filename.
+//
+// We also clean up the imports: the rewritten file may well no longer need
+// "context".
+func _writeFile(fset *token.FileSet, filename string, file *ast.File) error {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return err
+	}
+	source, err := imports.Process(filename, buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, source, 0o644)
+}
+
+// _writeShims generates the shims for the given methods to filename.
+func (p *Plugin) _writeShims(filename string, methods []_resolverMethod) error {
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].recvName != methods[j].recvName {
+			return methods[i].recvName < methods[j].recvName
+		}
+		return methods[i].decl.Name.Name < methods[j].decl.Name.Name
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gqlgenplugin; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", methods[0].file.Name.Name)
+
+	// We import everything any of the resolver files import, and let
+	// imports.Process remove what we don't need.
+	importSpecs := map[string]bool{`"context"`: true}
+	for _, name := range []qualifiedName{p.contextType, p.narrow} {
+		if name.path != "" {
+			importSpecs[strings.TrimSpace(_importName(name)+" "+fmt.Sprintf("%q", name.path))] = true
+		}
+	}
+	for _, method := range methods {
+		for _, imp := range method.file.Imports {
+			spec := imp.Path.Value
+			if imp.Name != nil {
+				spec = imp.Name.Name + " " + spec
+			}
+			importSpecs[spec] = true
+		}
+	}
+	specs := make([]string, 0, len(importSpecs))
+	for spec := range importSpecs {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	fmt.Fprintf(&buf, "import (\n\t%s\n)\n\n", strings.Join(specs, "\n\t"))
+
+	for i, method := range methods {
+		if i == 0 || methods[i-1].recvName != method.recvName {
+			fmt.Fprintf(&buf, "// %s implements gqlgen's interface for %s, by\n",
+				_shimName(method.recvName), method.recvName)
+			fmt.Fprintf(&buf, "// narrowing the context and calling the typed resolver.\n")
+			fmt.Fprintf(&buf, "type %s struct{ *%s }\n\n",
+				_shimName(method.recvName), method.recvName)
+		}
+		p._writeShimMethod(&buf, method)
+	}
+
+	source, err := imports.Process(filename, buf.Bytes(), nil)
+	if err != nil {
+		return fmt.Errorf("generated invalid shims: %w", err)
+	}
+	return os.WriteFile(filename, source, 0o644)
+}
+
+// _writeShimMethod writes the shim for a single resolver method to buf.
+func (p *Plugin) _writeShimMethod(buf *bytes.Buffer, method _resolverMethod) {
+	funcType := method.decl.Type
+
+	// Params: ctx becomes a context.Context, and we name any unnamed params
+	// so we can forward them.
+	ctxName := funcType.Params.List[0].Names[0].Name
+	var params, args []string
+	for i, field := range funcType.Params.List {
+		typ := _exprString(field.Type)
+		if i == 0 {
+			typ = "context.Context"
+		}
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		if len(names) == 0 || names[0] == "_" {
+			names = []string{fmt.Sprintf("arg%d", len(args))}
+		}
+		for j, name := range names {
+			if i == 0 {
+				name = "typedCtx"
+			} else if _, variadic := field.Type.(*ast.Ellipsis); variadic && j == len(names)-1 {
+				name += "..."
+			}
+			args = append(args, name)
+		}
+		params = append(params, strings.Join(names, ", ")+" "+typ)
+	}
+
+	// Results: we name them, so we can return zero values on error.
+	var results []string
+	returnsError := false
+	if funcType.Results != nil {
+		for i, field := range funcType.Results.List {
+			typ := _exprString(field.Type)
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for j := 0; j < n; j++ {
+				name := fmt.Sprintf("ret%d", len(results))
+				if i == len(funcType.Results.List)-1 && j == n-1 && typ == "error" {
+					name = "err"
+					returnsError = true
+				}
+				results = append(results, name+" "+typ)
+			}
+		}
+	}
+	zeros := make([]string, 0, len(results))
+	for _, result := range results {
+		zeros = append(zeros, strings.Fields(result)[0])
+	}
+
+	fmt.Fprintf(buf, "func (r %s) %s(%s) (%s) {\n",
+		_shimName(method.recvName), method.decl.Name.Name,
+		strings.Join(params, ", "), strings.Join(results, ", "))
+	if returnsError {
+		fmt.Fprintf(buf, "\ttypedCtx, err := %s(%s)\n", p.narrow.expr(), ctxName)
+		fmt.Fprintf(buf, "\tif err != nil {\n\t\treturn %s\n\t}\n", strings.Join(zeros, ", "))
+	} else {
+		fmt.Fprintf(buf, "\ttypedCtx, narrowErr := %s(%s)\n", p.narrow.expr(), ctxName)
+		fmt.Fprintf(buf, "\tif narrowErr != nil {\n\t\tpanic(narrowErr)\n\t}\n")
+	}
+	call := fmt.Sprintf("r.%s.%s(%s)", method.recvName, method.decl.Name.Name, strings.Join(args, ", "))
+	if len(results) == 0 {
+		fmt.Fprintf(buf, "\t%s\n\treturn\n}\n\n", call)
+	} else {
+		fmt.Fprintf(buf, "\treturn %s\n}\n\n", call)
+	}
+}
@@ -0,0 +1,102 @@
+package gqlgenplugin_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/khan/typed-context/gqlgenplugin"
+)
+
+// The resolvers in testdata/src/graph are as gqlgen generates them; each
+// file the plugin writes should match the corresponding .golden file.  The
+// typed context is in testdata/src/app/v2, which is package app.  We load it
+// in GOPATH mode, from a copy of testdata, since the plugin writes to the
+// resolvers in place.
+
+// copyTestdata copies testdata to a temporary directory, and sets up the
+// environment to load its packages by import path, until the test ends; it
+// returns the copy's resolver directory.
+func copyTestdata(t *testing.T) string {
+	dir := t.TempDir()
+	err := filepath.WalkDir("testdata", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel("testdata", path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), 0o755)
+		}
+		if strings.HasSuffix(path, ".golden") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0o644)
+	})
+	if err != nil {
+		t.Fatalf("copying testdata: %v", err)
+	}
+	t.Setenv("GOPATH", dir)
+	t.Setenv("GO111MODULE", "off")
+	t.Setenv("GOFLAGS", "")
+	return filepath.Join(dir, "src", "graph")
+}
+
+// checkGolden checks that each file in dir matches its .golden file in
+// testdata/src/graph, if it has one, and that every .golden file has a
+// counterpart.
+func checkGolden(t *testing.T, dir string) {
+	t.Helper()
+	goldens, err := filepath.Glob(filepath.Join("testdata", "src", "graph", "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goldens) == 0 {
+		t.Fatal("no .golden files")
+	}
+	for _, golden := range goldens {
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("reading rewritten %s: %v", name, err)
+			continue
+		}
+		if string(got) != string(want) {
+			t.Errorf("rewritten %s:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
+
+func TestRewriteResolvers(t *testing.T) {
+	dir := copyTestdata(t)
+	if err := gqlgenplugin.New("app/v2.Context", "app/v2.Narrow").RewriteResolvers(dir); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, dir)
+
+	// Running it again, on the rewritten resolvers, changes nothing.
+	if err := gqlgenplugin.New("app/v2.Context", "app/v2.Narrow").RewriteResolvers(dir); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, dir)
+}
+
+func TestRewriteResolversMissingPackage(t *testing.T) {
+	dir := copyTestdata(t)
+	err := gqlgenplugin.New("app/v3.Context", "app/v3.Narrow").RewriteResolvers(dir)
+	if err == nil || !strings.Contains(err.Error(), "app/v3") {
+		t.Errorf("got error %v, want one about app/v3", err)
+	}
+}
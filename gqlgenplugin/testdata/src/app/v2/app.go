@@ -0,0 +1,24 @@
+// Package app is the typed context the resolvers take.  Its name differs
+// from the last element of its path, so the rewritten files must import it
+// by name.
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+type Loaders struct{}
+
+type Context interface {
+	context.Context
+	Loaders() *Loaders
+}
+
+func Narrow(ctx context.Context) (Context, error) {
+	typed, ok := ctx.(Context)
+	if !ok {
+		return nil, errors.New("not an app.Context")
+	}
+	return typed, nil
+}
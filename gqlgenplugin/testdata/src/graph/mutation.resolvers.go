@@ -0,0 +1,16 @@
+package graph
+
+import (
+	stdcontext "context"
+)
+
+// Rename is the resolver for the rename field, whose file imports context
+// under another name.
+func (r *mutationResolver) Rename(ctx stdcontext.Context, id string, names ...string) (*User, error) {
+	return &User{ID: id}, nil
+}
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+type mutationResolver struct{ *Resolver }
@@ -0,0 +1,11 @@
+package graph
+
+type Resolver struct{}
+
+type QueryResolver interface{}
+
+type MutationResolver interface{}
+
+type User struct {
+	ID string
+}
@@ -0,0 +1,23 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+)
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*User, error) {
+	return &User{ID: id}, nil
+}
+
+// Count is the resolver for the count field.
+func (r *queryResolver) Count(ctx context.Context) int {
+	return 0
+}
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type queryResolver struct{ *Resolver }
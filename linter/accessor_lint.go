@@ -0,0 +1,90 @@
+package linter
+
+// This file defines the linter that typed context interfaces are pure
+// capability declarations: that the only methods they declare (other than
+// those of context.Context) are accessors, which take no arguments and return
+// a single provider, like
+//	type LoggerContext interface {
+//		context.Context
+//		Logger() *Logger
+//	}
+// A method like `Log(msg string)` or `LookupUser(id string) (*User, error)`
+// is business logic; it belongs on a provider (`ctx.Logger().Log(msg)`), not
+// on the context itself, where every implementation of the context would
+// have to provide it.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextAccessorAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextaccessor",
+	Doc:  "enforces that typed context interfaces declare only accessor methods",
+	Run:  _runAccessor,
+}
+
+// _checkAccessors reports any methods declared directly in the given
+// interface-type which are not accessors.
+//
+// We only look at methods declared in this interface-type, not those it
+// embeds: those are checked where they're declared (if they're in a package
+// we lint at all).
+func _checkAccessors(pass *analysis.Pass, ifaceType *ast.InterfaceType) {
+	typ := pass.TypesInfo.TypeOf(ifaceType)
+	if typ == nil || !isContextType(typ) {
+		return
+	}
+
+	for _, field := range ifaceType.Methods.List {
+		funcType, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			continue // an embed
+		}
+		sig, ok := pass.TypesInfo.TypeOf(funcType).(*types.Signature)
+		if !ok { // should never happen
+			continue
+		}
+		if sig.Params().Len() == 0 && sig.Results().Len() == 1 {
+			continue
+		}
+		for _, name := range field.Names {
+			// A hand-rolled context (see analysisengine.IsContextType)
+			// declares context.Context's own methods itself.
+			if method, ok := pass.TypesInfo.Defs[name].(*types.Func); ok &&
+				analysisengine.IsContextMethod(method) {
+				continue
+			}
+			reportf(pass, name, CodeNonAccessorMethod,
+				"typed context interface declares non-accessor method %s; "+
+					"context methods should take no arguments and return "+
+					"a single provider", name.Name)
+		}
+	}
+}
+
+// _runAccessor lints that typed context interfaces only declare accessors.
+//
+// This includes both named interfaces and inline ones, such as in the type
+// of a function parameter.
+func _runAccessor(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if ifaceType, ok := node.(*ast.InterfaceType); ok {
+				_checkAccessors(pass, ifaceType)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,169 @@
+package analysisengine
+
+// This file handles contexts captured by closures which outlive the function
+// they're declared in, like functional options:
+//	func WithContext(ctx interface {
+//		LoggerContext
+//		DatabaseContext
+//	}) Option {
+//		return func(o *options) {
+//			o.ctx = ctx
+//		}
+//	}
+// Uses of ctx within the closure are uses like any other, wherever the
+// closure ends up being called: ctx.Logger() in it uses LoggerContext.  And
+// storing ctx in a field of some typed context uses that type, as any
+// assignment does.  But if the field is a plain context.Context (or any), the
+// uses come later, in whatever applies the options, by way of a cast like
+// o.ctx.(LoggerContext), which we can't trace back to ctx.  Counting the store
+// as a use of context.Context alone would have us report that WithContext
+// uses none of what it requests.
+//
+// So when a closure returned from the function declaring a context stores
+// the context in a field whose type drops its typed interfaces, we count that
+// as a use of all of them, and record the Capture, so that analyzers can say
+// that the context's uses couldn't be checked.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Capture is a context stored by a closure returned from the function which
+// declares it, in a field whose type isn't a typed context (see the top of
+// the file).
+type Capture struct {
+	// Object is the context.
+	Object types.Object
+	// Closure is the function literal which captures it.
+	Closure *ast.FuncLit
+	// Value is the identifier stored.
+	Value *ast.Ident
+	// Type is the type of the field it's stored in, like context.Context.
+	Type types.Type
+}
+
+// _escapingClosure returns the function literal which expr returns, if it's
+// one, possibly converted to a named function type like Option.
+func (tracker *Tracker) _escapingClosure(expr ast.Expr) *ast.FuncLit {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.FuncLit:
+			return e
+		case *ast.CallExpr:
+			if len(e.Args) != 1 || !tracker.typesInfo.Types[e.Fun].IsType() {
+				return nil
+			}
+			expr = e.Args[0]
+		default:
+			return nil
+		}
+	}
+}
+
+// _dropsInterfaces returns true if storing a typed context in a field of the
+// given type loses its typed interfaces.
+func _dropsInterfaces(typ types.Type) bool {
+	if _, ok := typ.Underlying().(*types.Interface); !ok {
+		return false
+	}
+	return !IsContextType(typ) || IsContextRoot(typ)
+}
+
+// _fieldStores calls store for each value which the given node stores in a
+// struct field, by assignment or in a composite literal, with the field's
+// type.
+func (tracker *Tracker) _fieldStores(node ast.Node, store func(typ types.Type, value ast.Expr)) {
+	switch node := node.(type) {
+	case *ast.AssignStmt:
+		if node.Tok != token.ASSIGN || len(node.Lhs) != len(node.Rhs) {
+			return
+		}
+		for i, lhs := range node.Lhs {
+			selector, ok := ast.Unparen(lhs).(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			selection := tracker.typesInfo.Selections[selector]
+			if selection != nil && selection.Kind() == types.FieldVal {
+				store(selection.Type(), node.Rhs[i])
+			}
+		}
+	case *ast.KeyValueExpr:
+		key, ok := node.Key.(*ast.Ident)
+		if !ok {
+			return
+		}
+		if field, ok := tracker.typesInfo.Uses[key].(*types.Var); ok && field.IsField() {
+			store(field.Type(), node.Value)
+		}
+	}
+}
+
+// identifyCaptures finds the contexts which closures returned from the
+// functions declaring them store in fields whose types drop their typed
+// interfaces, and records them in captures.
+func (tracker *Tracker) identifyCaptures(files []*ast.File) {
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			ret, ok := node.(*ast.ReturnStmt)
+			if !ok {
+				return true
+			}
+			for _, result := range ret.Results {
+				lit := tracker._escapingClosure(result)
+				if lit == nil {
+					continue
+				}
+				ast.Inspect(lit.Body, func(node ast.Node) bool {
+					tracker._fieldStores(node, func(typ types.Type, value ast.Expr) {
+						ident, ok := ast.Unparen(value).(*ast.Ident)
+						if !ok || !_dropsInterfaces(typ) {
+							return
+						}
+						obj := tracker.typesInfo.Uses[ident]
+						if obj == nil || tracker.trackedIdents[obj] == nil ||
+							lit.Pos() <= obj.Pos() && obj.Pos() < lit.End() {
+							return // not a captured context
+						}
+						tracker.captures[ident] = &Capture{
+							Object:  obj,
+							Closure: lit,
+							Value:   ident,
+							Type:    typ,
+						}
+					})
+					return true
+				})
+			}
+			return true
+		})
+	}
+}
+
+// _markCaptureUsed marks used all the interfaces of the context to which the
+// given identifier refers, if it's stored by a closure as a Capture.
+func (tracker *Tracker) _markCaptureUsed(ident *ast.Ident) {
+	capture := tracker.captures[ident]
+	if capture == nil {
+		return
+	}
+	if info := tracker.trackedIdents[capture.Object]; info != nil {
+		info.useInterface(capture.Object.Type(), ident.Pos())
+	}
+}
+
+// Captures returns the contexts stored by closures returned from the
+// functions declaring them in fields which aren't typed contexts, whose
+// uses we therefore can't check (see the top of captures.go), in order of
+// position.
+func (tracker *Tracker) Captures() []Capture {
+	captures := make([]Capture, 0, len(tracker.captures))
+	for _, capture := range tracker.captures {
+		captures = append(captures, *capture)
+	}
+	sort.Slice(captures, func(i, j int) bool { return captures[i].Value.Pos() < captures[j].Value.Pos() })
+	return captures
+}
@@ -0,0 +1,173 @@
+package analysisengine
+
+// This file handles derivers: functions which return a context derived from
+// their context argument, like
+//	ctx, span := typedcontextotel.Start(ctx, "load")
+//	spanCtx, span := tracer.Start(ctx, "load")
+//	g, groupCtx := errgroup.WithContext(ctx)
+// The result is the same context, as far as we're concerned, carrying a span
+// or some such (or, for errgroup, cancelled when one of the group's
+// goroutines fails).  So a new variable holding it (like spanCtx) shares the
+// Usage of the argument, as the parameters of runners' function literals do
+// (see identifyRunnerCalls): its uses count toward the argument, and we
+// don't report on it separately, since its type was chosen by the deriver.
+//
+// Passing the argument to the deriver uses only what the deriver itself
+// needs: for a generic deriver like typedcontextotel.Start, the constraint of
+// its type parameter (TraceContext), not the whole type it's instantiated
+// with, which would count every interface of the context as used.
+//
+// The closures passed to the group's Go method (or a sync.WaitGroup's) need
+// no special handling: they capture ctx, or groupCtx, and their uses of it
+// are uses like any other.
+//
+// If the result is instead assigned to an existing variable, or to a new one
+// with an explicit type, the caller chose its type, so it's tracked in its
+// own right, and the argument is used as that type, as in any assignment.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// _deriverContext returns the first tracked context passed to the given
+// call, if it's a call to a deriver.
+func (tracker *Tracker) _deriverContext(call *ast.CallExpr, derivers map[string]bool) types.Object {
+	if !derivers[lintutil.NameOf(lintutil.ObjectFor(call.Fun, tracker.typesInfo))] {
+		return nil
+	}
+	for _, arg := range call.Args {
+		if ident, ok := arg.(*ast.Ident); ok {
+			obj := tracker.typesInfo.ObjectOf(ident)
+			if tracker.trackedIdents[obj] != nil {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// identifyDeriverCalls finds the calls to derivers, and makes the new
+// variables defined from their results aliases of their context arguments.
+func (tracker *Tracker) identifyDeriverCalls(files []*ast.File) {
+	derivers := map[string]bool{}
+	for _, deriver := range tracker.options.Derivers {
+		derivers[deriver] = true
+	}
+	if len(derivers) == 0 {
+		return
+	}
+
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			var lhs, rhs []ast.Expr
+			switch node := node.(type) {
+			case *ast.CallExpr:
+				if obj := tracker._deriverContext(node, derivers); obj != nil {
+					tracker.deriverCalls[node] = obj
+				}
+				return true
+			case *ast.AssignStmt:
+				if node.Tok != token.DEFINE {
+					return true
+				}
+				lhs, rhs = node.Lhs, node.Rhs
+			case *ast.ValueSpec:
+				if node.Type != nil {
+					return true
+				}
+				for _, name := range node.Names {
+					lhs = append(lhs, name)
+				}
+				rhs = node.Values
+			default:
+				return true
+			}
+
+			if len(rhs) != 1 {
+				return true
+			}
+			call, ok := ast.Unparen(rhs[0]).(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			outer := tracker._deriverContext(call, derivers)
+			if outer == nil {
+				return true
+			}
+			for _, expr := range lhs {
+				ident, ok := expr.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				// (Defs is nil for a variable which := merely reassigns.)
+				obj := tracker.typesInfo.Defs[ident]
+				if obj == nil || obj.Name() == "_" || !IsContextType(obj.Type()) {
+					continue
+				}
+				tracker.trackedIdents[obj] = tracker.trackedIdents[outer]
+				tracker.aliases[obj] = true
+			}
+			return true
+		})
+	}
+}
+
+// _markDeriverArgsUsed marks used the interfaces of the context passed to
+// the given call to a deriver which the deriver itself requires.
+func (tracker *Tracker) _markDeriverArgsUsed(call *ast.CallExpr) {
+	funcType, ok := tracker.typesInfo.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		return
+	}
+	if fn, ok := lintutil.ObjectFor(call.Fun, tracker.typesInfo).(*types.Func); ok {
+		// The uninstantiated signature, whose parameters may be type
+		// parameters.
+		funcType = fn.Type().(*types.Signature)
+	}
+	for i, arg := range call.Args {
+		info := tracker._usageOf(arg)
+		paramType := ParamTypeAt(call, funcType, i)
+		if info == nil || paramType == nil {
+			continue
+		}
+		if typeParam, ok := paramType.(*types.TypeParam); ok {
+			paramType = typeParam.Constraint()
+		}
+		info.useInterface(paramType, arg.Pos())
+	}
+}
+
+// _markDerivedAssignedUsed marks the context passed to a deriver used as the
+// types of the given targets, if values is a single call to a deriver whose
+// result is assigned to them: existing variables, or new ones with an
+// explicit type.
+func (tracker *Tracker) _markDerivedAssignedUsed(targets []ast.Expr, values []ast.Expr) {
+	if len(values) != 1 {
+		return
+	}
+	call, ok := ast.Unparen(values[0]).(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	outer := tracker.deriverCalls[call]
+	if outer == nil {
+		return
+	}
+	for _, target := range targets {
+		typ := tracker.typesInfo.TypeOf(target)
+		if typ == nil || !IsContextType(typ) {
+			continue
+		}
+		if ident, ok := target.(*ast.Ident); ok {
+			obj := tracker.typesInfo.ObjectOf(ident)
+			if obj == outer || tracker.aliases[obj] {
+				continue // ctx, span = Start(ctx, ...) converts nothing
+			}
+		}
+		tracker.trackedIdents[outer].useInterface(typ, target.Pos())
+	}
+}
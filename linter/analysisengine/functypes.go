@@ -0,0 +1,411 @@
+package analysisengine
+
+// This file handles function-typed declarations, like
+//	type Handler func(ctx BigContext) error
+//	type Server struct { OnRequest func(ctx BigContext) error }
+//	var onShutdown func(ctx BigContext)
+// whose context parameters we don't otherwise track (see trackIdents): they
+// have no body, so nothing uses them directly.  What they need is whatever
+// the functions assigned to them need.  So with Options.FunctionTypes, we
+// track each such parameter, and count the uses of the corresponding
+// parameter of each function assigned to the declaration as its uses, much
+// as identifyInterfaceMethods does for implementations of an interface.
+//
+// We only see assignments in the package being analyzed; if other packages
+// assign functions to an exported declaration, they may need more.
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// _funcTypeDecl is a function-typed declaration, and what we know about the
+// functions assigned to it.
+type _funcTypeDecl struct {
+	sig *types.Signature
+	// impls are the signatures of the functions assigned to it whose bodies
+	// we can see: function literals, and functions and methods declared in
+	// this package.
+	impls map[*types.Signature]bool
+	// opaque is set if some function assigned to it isn't one we can see,
+	// in which case we don't know what it needs, and leave it alone.
+	opaque bool
+}
+
+// _funcTypeDecls returns the function-typed declarations in the given files,
+// keyed by the object they declare: a type name, a struct field, or a
+// package-level variable.  (Fields and variables whose type is a named
+// function type are covered by the type's declaration; function-typed
+// locals are just like any other local, and the functions assigned to them
+// are checked directly.)
+func (tracker *Tracker) _funcTypeDecls(files []*ast.File) map[types.Object]*_funcTypeDecl {
+	decls := map[types.Object]*_funcTypeDecl{}
+	add := func(name *ast.Ident, funcType *ast.FuncType) {
+		obj := tracker.typesInfo.Defs[name]
+		sig, ok := tracker.typesInfo.TypeOf(funcType).(*types.Signature)
+		if obj != nil && ok {
+			decls[obj] = &_funcTypeDecl{sig: sig, impls: map[*types.Signature]bool{}}
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.TypeSpec:
+				funcType, ok := node.Type.(*ast.FuncType)
+				if ok && node.TypeParams == nil {
+					add(node.Name, funcType)
+				}
+			case *ast.StructType:
+				for _, field := range node.Fields.List {
+					if funcType, ok := field.Type.(*ast.FuncType); ok {
+						for _, name := range field.Names {
+							add(name, funcType)
+						}
+					}
+				}
+			case *ast.ValueSpec:
+				funcType, ok := node.Type.(*ast.FuncType)
+				for _, name := range node.Names {
+					obj := tracker.typesInfo.Defs[name]
+					if ok && obj != nil && obj.Parent() == tracker.pkg.Scope() {
+						add(name, funcType)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return decls
+}
+
+// _implementation returns the signature of the function given by expr, if
+// it's a function literal or refers to a function or method declared in
+// this package, or nil otherwise.  That signature's parameters are the
+// parameters of the function's declaration.
+func (tracker *Tracker) _implementation(expr ast.Expr) *types.Signature {
+	var fn *types.Func
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.FuncLit:
+		sig, _ := tracker.typesInfo.TypeOf(expr).(*types.Signature)
+		return sig
+	case *ast.Ident:
+		fn, _ = tracker.typesInfo.Uses[expr].(*types.Func)
+	case *ast.SelectorExpr:
+		// A method expression T.M takes the receiver as an extra first
+		// parameter, so its parameters don't line up; we leave those alone.
+		selection := tracker.typesInfo.Selections[expr]
+		if selection == nil || selection.Kind() == types.MethodVal {
+			fn, _ = tracker.typesInfo.Uses[expr.Sel].(*types.Func)
+		}
+	}
+	if fn == nil || fn.Pkg() != tracker.pkg {
+		return nil
+	}
+	sig, _ := fn.Type().(*types.Signature)
+	return sig
+}
+
+// _assignee returns the variable or field assigned to by the given
+// left-hand side, if any.
+func (tracker *Tracker) _assignee(lhs ast.Expr) types.Object {
+	switch lhs := ast.Unparen(lhs).(type) {
+	case *ast.Ident:
+		return tracker.typesInfo.ObjectOf(lhs)
+	case *ast.SelectorExpr:
+		return tracker.typesInfo.ObjectOf(lhs.Sel)
+	}
+	return nil
+}
+
+// _isExportedFuncDecl returns true if obj is an exported function-typed
+// declaration of some package: a named function type, or a package-level
+// variable of an unnamed one.
+func _isExportedFuncDecl(obj types.Object) bool {
+	if !obj.Exported() || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+		return false
+	}
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams() != nil {
+			return false
+		}
+		_, ok = named.Underlying().(*types.Signature)
+		return ok
+	case *types.Var:
+		_, ok := obj.Type().(*types.Signature)
+		return ok
+	}
+	return false
+}
+
+// _assignFunc records that the given expression is assigned to target (a
+// variable or field, or nil if it's something else like a parameter), which
+// has type typ, if that's one of the given declarations.  If exported is
+// set, any exported function-typed declaration (see _isExportedFuncDecl)
+// counts, and is added to decls when it's first assigned to.
+func (tracker *Tracker) _assignFunc(
+	decls map[types.Object]*_funcTypeDecl,
+	exported bool,
+	expr ast.Expr,
+	target types.Object,
+	typ types.Type,
+) {
+	key := target
+	named, isNamed := types.Unalias(typ).(*types.Named)
+	if isNamed {
+		key = named.Obj()
+	}
+	if key == nil {
+		return
+	}
+	decl := decls[key]
+	if decl == nil && exported && _isExportedFuncDecl(key) {
+		decl = &_funcTypeDecl{
+			sig:   key.Type().Underlying().(*types.Signature),
+			impls: map[*types.Signature]bool{},
+		}
+		decls[key] = decl
+	}
+	if decl == nil {
+		return
+	}
+
+	if impl := tracker._implementation(expr); impl != nil {
+		decl.impls[impl] = true
+		return
+	}
+	value := tracker.typesInfo.Types[expr]
+	switch {
+	case value.IsNil():
+		// Nothing to check.
+	case isNamed && types.Identical(value.Type, typ):
+		// A value which already had the declared type; we've seen (or will
+		// see) whatever function it holds get converted to that type.
+	case !isNamed && tracker._assignee(expr) == target:
+		// Likewise for a value from the declared field or variable itself.
+	default:
+		decl.opaque = true
+	}
+}
+
+// _enclosingSignature returns the signature of the innermost function in
+// stack, a path of nodes from the root.
+func (tracker *Tracker) _enclosingSignature(stack []ast.Node) *types.Signature {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch node := stack[i].(type) {
+		case *ast.FuncLit:
+			sig, _ := tracker.typesInfo.TypeOf(node).(*types.Signature)
+			return sig
+		case *ast.FuncDecl:
+			if fn, ok := tracker.typesInfo.Defs[node.Name].(*types.Func); ok {
+				sig, _ := fn.Type().(*types.Signature)
+				return sig
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// _findFuncAssignments finds the functions assigned to each of the given
+// declarations in the given files: by assignment, in a composite literal,
+// as a call argument or conversion, by return, or by a channel send.  If
+// exported is set, it adds any exported function-typed declaration assigned
+// to (see _assignFunc).
+func (tracker *Tracker) _findFuncAssignments(files []*ast.File, decls map[types.Object]*_funcTypeDecl, exported bool) {
+	for _, file := range files {
+		var stack []ast.Node
+		ast.Inspect(file, func(node ast.Node) bool {
+			if node == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			stack = append(stack, node)
+
+			switch node := node.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) {
+					for i, lhs := range node.Lhs {
+						tracker._assignFunc(decls, exported, node.Rhs[i],
+							tracker._assignee(lhs), tracker.typesInfo.TypeOf(lhs))
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) == len(node.Values) {
+					for i, name := range node.Names {
+						if obj := tracker.typesInfo.Defs[name]; obj != nil {
+							tracker._assignFunc(decls, exported, node.Values[i], obj, obj.Type())
+						}
+					}
+				}
+			case *ast.CompositeLit:
+				typ := tracker.typesInfo.TypeOf(node)
+				if typ == nil {
+					break
+				}
+				for i, element := range node.Elts {
+					keyValue, keyed := element.(*ast.KeyValueExpr)
+					if keyed {
+						element = keyValue.Value
+					}
+					switch underlying := typ.Underlying().(type) {
+					case *types.Struct:
+						field := underlying.Field(i)
+						if keyed {
+							if ident, ok := keyValue.Key.(*ast.Ident); ok {
+								field, _ = tracker.typesInfo.ObjectOf(ident).(*types.Var)
+							}
+						}
+						if field != nil {
+							tracker._assignFunc(decls, exported, element, field, field.Type())
+						}
+					case *types.Slice:
+						tracker._assignFunc(decls, exported, element, nil, underlying.Elem())
+					case *types.Array:
+						tracker._assignFunc(decls, exported, element, nil, underlying.Elem())
+					case *types.Map:
+						tracker._assignFunc(decls, exported, element, nil, underlying.Elem())
+					}
+				}
+			case *ast.CallExpr:
+				if tracker.typesInfo.Types[node.Fun].IsType() {
+					if len(node.Args) == 1 {
+						tracker._assignFunc(decls, exported, node.Args[0], nil, tracker.typesInfo.TypeOf(node))
+					}
+					break
+				}
+				funcType, ok := tracker.typesInfo.TypeOf(node.Fun).Underlying().(*types.Signature)
+				if !ok {
+					break
+				}
+				for i, arg := range node.Args {
+					if paramType := ParamTypeAt(node, funcType, i); paramType != nil {
+						tracker._assignFunc(decls, exported, arg, nil, paramType)
+					}
+				}
+			case *ast.ReturnStmt:
+				sig := tracker._enclosingSignature(stack)
+				if sig != nil && sig.Results().Len() == len(node.Results) {
+					for i, result := range node.Results {
+						tracker._assignFunc(decls, exported, result, nil, sig.Results().At(i).Type())
+					}
+				}
+			case *ast.SendStmt:
+				if ch, ok := tracker.typesInfo.TypeOf(node.Chan).Underlying().(*types.Chan); ok {
+					tracker._assignFunc(decls, exported, node.Value, nil, ch.Elem())
+				}
+			}
+			return true
+		})
+	}
+}
+
+// identifyFunctionTypes modifies trackedIdents so that the context parameters
+// of function-typed declarations are tracked, and the corresponding
+// parameters of the functions assigned to each declaration share its Usage.
+// Those functions' parameters are then aliases, like those of runners'
+// function literals: we report on the declaration instead.
+//
+// We leave a declaration alone if we can't see all the functions assigned to
+// it, or if one of those functions' parameters already shares its Usage
+// with something else (say, because it also implements an interface
+// method, or is assigned to another declaration).
+func (tracker *Tracker) identifyFunctionTypes(files []*ast.File) {
+	decls := tracker._funcTypeDecls(files)
+	if len(decls) == 0 {
+		return
+	}
+	tracker._findFuncAssignments(files, decls, false)
+
+	// Count the sharers of each Usage, and the declarations each parameter
+	// is assigned to.
+	sharers := map[*Usage]int{}
+	for _, usage := range tracker.trackedIdents {
+		sharers[usage]++
+	}
+	assignedTo := map[types.Object]int{}
+	for _, decl := range decls {
+		for impl := range decl.impls {
+			for i := 0; i < impl.Params().Len(); i++ {
+				assignedTo[impl.Params().At(i)]++
+			}
+		}
+	}
+
+	for _, decl := range decls {
+		if decl.opaque || len(decl.impls) == 0 {
+			continue
+		}
+		shared := false
+		for impl := range decl.impls {
+			for i := 0; i < impl.Params().Len(); i++ {
+				param := impl.Params().At(i)
+				usage := tracker.trackedIdents[param]
+				if assignedTo[param] > 1 || usage != nil && sharers[usage] > 1 {
+					shared = true
+				}
+			}
+		}
+		if shared {
+			continue
+		}
+
+		params := decl.sig.Params()
+		for i := 0; i < params.Len(); i++ {
+			param := params.At(i)
+			if !IsContextType(param.Type()) {
+				continue
+			}
+			leaves := LeafInterfaces(param.Type())
+			if len(leaves) == 1 && IsContextRoot(leaves[0]) {
+				continue // as in track
+			}
+			usage := tracker.TrackObject(param)
+			for impl := range decl.impls {
+				implParam := impl.Params().At(i)
+				if tracker.trackedIdents[implParam] != nil {
+					tracker.trackedIdents[implParam] = usage
+					tracker.aliases[implParam] = true
+				}
+			}
+		}
+	}
+}
+
+// FuncTypeImplementations are the functions assigned to an exported
+// function-typed declaration in a package.
+type FuncTypeImplementations struct {
+	// Signature is the declaration's signature.
+	Signature *types.Signature
+	// Implementations are the signatures of the functions assigned to it
+	// whose bodies the package has: function literals, and functions and
+	// methods declared there.  Their parameters are those of the functions'
+	// declarations, whose Usage Track records.
+	Implementations []*types.Signature
+	// Opaque is set if some function assigned to it isn't one of those.
+	Opaque bool
+}
+
+// ExportedFuncTypes returns the functions which the given files, which
+// should be all the files of the package, assign to exported function-typed
+// declarations -- named function types, like
+//
+//	type HandlerFunc func(ctx AppContext, r Request) error
+//
+// and package-level variables of unnamed ones -- of any package, keyed by
+// the declared type name or variable.  Unlike with Options.FunctionTypes,
+// the functions' parameters keep their own Usage.
+func (tracker *Tracker) ExportedFuncTypes(files []*ast.File) map[types.Object]FuncTypeImplementations {
+	decls := map[types.Object]*_funcTypeDecl{}
+	tracker._findFuncAssignments(files, decls, true)
+	result := make(map[types.Object]FuncTypeImplementations, len(decls))
+	for obj, decl := range decls {
+		impls := FuncTypeImplementations{Signature: decl.sig, Opaque: decl.opaque}
+		for impl := range decl.impls {
+			impls.Implementations = append(impls.Implementations, impl)
+		}
+		result[obj] = impls
+	}
+	return result
+}
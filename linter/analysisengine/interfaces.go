@@ -0,0 +1,563 @@
+// Package analysisengine implements the usage analysis behind the typed
+// context linter: for each context variable in a package, it records which
+// of its typed context interfaces are used, and where, and from that which
+// ones the variable requests but doesn't use, or uses but doesn't request.
+//
+// The typedcontextinterface analyzer reports those problems; the same data
+// also answers "what does this function need?" for cmd/typedcontext-query,
+// and can be used by code generators and editor tooling.  Typical use is
+//
+//	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+//	tracker.Track(pass.Files)
+//	for _, file := range pass.Files {
+//		tracker.MarkUses(file)
+//	}
+//	for _, obj := range tracker.Objects() {
+//		allUnused, unused, unrequested := tracker.Usage(obj).Problems()
+//		...
+//	}
+package analysisengine
+
+// This file defines the helpers that decompose a context type into the
+// interfaces it's made of.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+	"sync"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// SameUnitFunc returns true if the two packages should be treated as one
+// unit (see ExplicitInterfaces); other is the package being analyzed.  A nil
+// SameUnitFunc treats only identical packages as the same unit.
+type SameUnitFunc func(pkg, other *types.Package) bool
+
+// call calls sameUnit, or compares the packages if it's nil.
+func (sameUnit SameUnitFunc) call(pkg, other *types.Package) bool {
+	if sameUnit == nil {
+		return pkg == other
+	}
+	return sameUnit(pkg, other)
+}
+
+var (
+	_contextRootsMu sync.RWMutex
+	// _contextRoots are the interfaces, other than context.Context, which
+	// SetContextRoots says to treat as context roots, as "import/path.Name".
+	_contextRoots []string
+)
+
+// SetContextRoots sets the interfaces, given as "import/path.Name", which we
+// treat as context roots, like context.Context: every interface embedding
+// one of them is a context-type.  This is for code whose contexts are built
+// on some root interface of its own, like
+//
+//	type Base interface {
+//		Deadline() (deadline time.Time, ok bool)
+//		Done() <-chan struct{}
+//		Err() error
+//		Value(key any) any
+//	}
+//
+// which doesn't embed context.Context.  It applies to all trackers (and the
+// functions in this file), so should be called before analysis starts.
+func SetContextRoots(roots []string) {
+	_contextRootsMu.Lock()
+	defer _contextRootsMu.Unlock()
+	_contextRoots = append([]string(nil), roots...)
+}
+
+// IsContextMethod returns true if method has the name and signature of one of
+// the methods of context.Context.
+func IsContextMethod(method *types.Func) bool {
+	sig, ok := method.Type().(*types.Signature)
+	if !ok || sig.Variadic() {
+		return false
+	}
+	params, results := sig.Params(), sig.Results()
+	isError := func(typ types.Type) bool {
+		return types.Identical(typ, types.Universe.Lookup("error").Type())
+	}
+	isAny := func(typ types.Type) bool {
+		iface, ok := typ.Underlying().(*types.Interface)
+		return ok && iface.Empty()
+	}
+
+	switch method.Name() {
+	case "Deadline":
+		return params.Len() == 0 && results.Len() == 2 &&
+			lintutil.TypeIs(results.At(0).Type(), "time", "Time") &&
+			types.Identical(results.At(1).Type(), types.Typ[types.Bool])
+	case "Done":
+		if params.Len() != 0 || results.Len() != 1 {
+			return false
+		}
+		ch, ok := results.At(0).Type().(*types.Chan)
+		if !ok || ch.Dir() != types.RecvOnly {
+			return false
+		}
+		elem, ok := ch.Elem().(*types.Struct)
+		return ok && elem.NumFields() == 0
+	case "Err":
+		return params.Len() == 0 && results.Len() == 1 && isError(results.At(0).Type())
+	case "Value":
+		return params.Len() == 1 && results.Len() == 1 &&
+			isAny(params.At(0).Type()) && isAny(results.At(0).Type())
+	}
+	return false
+}
+
+// _contextMethodNames are the names of the methods of context.Context.
+var _contextMethodNames = []string{"Deadline", "Done", "Err", "Value"}
+
+// _hasContextMethods returns true if iface's method set includes all of
+// context.Context's methods, so that it's a context even if it doesn't
+// embed context.Context, like the hand-rolled
+//
+//	interface {
+//		Deadline() (time.Time, bool)
+//		Done() <-chan struct{}
+//		Err() error
+//		Value(key any) any
+//		Logger() *Logger
+//	}
+//
+// If only is set, it must have no other methods.
+func _hasContextMethods(iface *types.Interface, only bool) bool {
+	if iface.NumMethods() < len(_contextMethodNames) ||
+		only && iface.NumMethods() != len(_contextMethodNames) {
+		return false
+	}
+	for _, name := range _contextMethodNames {
+		found := false
+		for i := 0; i < iface.NumMethods(); i++ {
+			method := iface.Method(i)
+			if method.Name() == name {
+				found = IsContextMethod(method)
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// IsContextRoot returns true if typ is context.Context, an interface with
+// exactly its methods, or one of the roots set by SetContextRoots.  Every
+// context-type provides its root, so a root is never worth reporting as
+// unused or unrequested.
+func IsContextRoot(typ types.Type) bool {
+	if lintutil.TypeIs(typ, "context", "Context") {
+		return true
+	}
+	if iface, ok := typ.Underlying().(*types.Interface); ok && _hasContextMethods(iface, true) {
+		return true
+	}
+	_contextRootsMu.RLock()
+	defer _contextRootsMu.RUnlock()
+	for _, root := range _contextRoots {
+		i := strings.LastIndex(root, ".")
+		if i > 0 && lintutil.TypeIs(typ, root[:i], root[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsContextType returns true if the input is a context-type (either Go-style
+// context.Context, or another root set by SetContextRoots, or a typed-context
+// style interface embedding one).  Interfaces which have all the methods of
+// context.Context, without embedding it, count too.
+func IsContextType(typ types.Type) bool {
+	if IsContextRoot(typ) {
+		return true
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+	if _hasContextMethods(iface, false) {
+		return true
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if IsContextType(iface.EmbeddedType(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsServerInterface returns true if the input is a "server interface", as in
+// example 07: an interface, not embedding context.Context, all of whose
+// methods are accessors, like
+//
+//	interface { RequestServer; LoggerServer }
+//
+// where RequestServer has just `Request() *Request`.  Code in that style
+// passes such an interface alongside a plain context.Context.
+func IsServerInterface(typ types.Type) bool {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 || IsContextType(typ) {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		sig := iface.Method(i).Type().(*types.Signature)
+		if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// _typeKeys caches TypeKey, by type.
+var _typeKeys sync.Map
+
+// TypeKey returns a string identifying typ: two types with the same key are
+// the same type, even if they aren't the same types.Type.  That happens when
+// the same declaration is type-checked more than once -- in each build
+// configuration, say, or for a package and its test variant -- since each
+// gets its own *types.Named.  Types declared inside a function are keyed by
+// their object, since their names needn't be unique.
+func TypeKey(typ types.Type) string {
+	if key, ok := _typeKeys.Load(typ); ok {
+		return key.(string)
+	}
+	var key string
+	if named, ok := types.Unalias(typ).(*types.Named); ok &&
+		named.Obj().Pkg() != nil && named.Obj().Parent() != named.Obj().Pkg().Scope() {
+		key = fmt.Sprintf("%s@%p", named, named.Obj())
+	} else {
+		key = types.TypeString(types.Unalias(typ), nil)
+	}
+	_typeKeys.Store(typ, key)
+	return key
+}
+
+// SameType returns true if a and b are the same type: identical, or declared
+// by the same declaration (see TypeKey).  Use this, not ==, to compare
+// context interfaces: the same interface may well be reached by two embed
+// paths, or come from two type-checks, and still be the one interface.
+func SameType(a, b types.Type) bool {
+	return a == b || types.Identical(a, b) || TypeKey(a) == TypeKey(b)
+}
+
+// ExplicitInterfaces returns the Typed-Context interfaces explicitly
+// included in the given type.  (This may include the type itself.)
+//
+// Specifically, these are the interfaces that you are treated as having
+// requested if you use the given interface; and they are the interfaces that
+// you are treated as having used (and thus that you need to have requested) if
+// you call some function which wants the given interface.
+//
+// Defining that in a way that makes sense is somewhat subtle.  We use package
+// boundaries:
+//   - we do not include, and recurse on on all unnamed or unexported interfaces
+//     within the package
+//   - we include, but also recurse on, all named exported interfaces within the
+//     package
+//   - we include, and do not recurse on, all named interfaces defined in other
+//     packages
+//
+// In context, this means if you request some context from another package
+//
+//	type I interface { C }
+//
+// it's fine to use that to call some function `otherpkg.F(ctx otherpkg.I)`,
+// but you can't use `C` yourself.  But if `I` were defined in your package, it
+// would be fine to use `C` -- you are the one wrapping things up and maybe the
+// whole reason to define `I` is so your callers can use it.  (But if `C`
+// itself contains other contexts, you still can't use those.)
+//
+// "Package" here really means "unit", as decided by sameUnit: the linter's
+// -sameunit flag, for instance, treats all the packages under a given prefix
+// as a single package.
+//
+// For example, given:
+//
+//	type A interface { other.B; c; M() }
+//	type c interface { other.D }
+//	func(ctx interface { A; other.E })
+//
+// then calling ExplicitInterfaces on the type of ctx will return `A`,
+// `other.B`, `other.D`, and `other.E`, but not `c` (it's not exported),
+// `interface { A; other.F }` (it's not named), nor `M()` (it's not itself an
+// interface).
+func ExplicitInterfaces(typ types.Type, currentPackage *types.Package, sameUnit SameUnitFunc) []types.Type {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	retval := make([]types.Type, 0, iface.NumEmbeddeds())
+	named, ok := types.Unalias(typ).(*types.Named)
+	if ok && !sameUnit.call(named.Obj().Pkg(), currentPackage) {
+		return []types.Type{typ}
+	} else if ok && named.Obj().Exported() {
+		retval = append(retval, typ)
+	}
+
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		retval = append(retval, ExplicitInterfaces(iface.EmbeddedType(i), currentPackage, sameUnit)...)
+	}
+	return retval
+}
+
+// LeafInterfaces returns a list of all interfaces embedded by this
+// interface, including the interface itself, stopping at named interfaces
+// with methods.
+//
+// For example, if you do
+//
+//	type A interface { B; C }
+//	type B interface { M() }
+//	type C interface { D; N() }
+//	type D interface { O() }
+//
+// then:
+//
+//	LeafInterfaces(A) => B, C
+//	LeafInterfaces(B) => B
+//	LeafInterfaces(C) => C
+//
+// An unnamed interface which declares methods of its own, like
+// `interface { A; B; M() }`, is split into its embeds' leaves plus a
+// synthetic leaf per method, `interface { M() }`, so that uses of part of it
+// are attributed correctly: if one callee wants A and another wants
+// `interface { B; M() }`, between them they use all of A, B, and M.  (Were
+// `interface { A; B; M() }` a single leaf, neither would use it.)  Each
+// synthetic leaf also embeds any context roots the interface embeds
+// directly, and includes any methods of context.Context it declares, so
+// that `interface { context.Context; M() }` is still a single leaf, rather
+// than a context.Context its user must request, and `interface { M() }`,
+// which isn't a context at all.
+func LeafInterfaces(typ types.Type) []types.Type {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	_, named := types.Unalias(typ).(*types.Named)
+	if iface.NumExplicitMethods() > 0 && named {
+		return []types.Type{typ}
+	}
+
+	var roots []types.Type
+	retval := make([]types.Type, 0, iface.NumEmbeddeds()+iface.NumExplicitMethods())
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embed := iface.EmbeddedType(i)
+		if iface.NumExplicitMethods() > 0 && IsContextRoot(embed) {
+			roots = append(roots, embed)
+			continue
+		}
+		retval = append(retval, LeafInterfaces(embed)...)
+	}
+	return append(retval, _methodLeaves(typ, iface, roots)...)
+}
+
+// _methodLeafCache caches the synthetic leaves of _methodLeaves, by method,
+// so that each method gets the same types.Type each time (see TypeKey).
+var _methodLeafCache sync.Map
+
+// ResetCaches forgets the cached TypeKeys and synthetic leaves.  They're
+// keyed by types.Type, so a long-running driver which reloads packages would
+// otherwise keep every version of every type it has seen.  It mustn't be
+// called while analyzers are running: within a run, each type must keep its
+// key, and each method its leaf.
+func ResetCaches() {
+	_typeKeys.Clear()
+	_methodLeafCache.Clear()
+}
+
+// _methodLeaves returns the synthetic leaves (see LeafInterfaces) of the
+// unnamed interface typ, whose underlying interface is iface, and which
+// embeds the given context roots: one per explicit method, other than those
+// of context.Context.  If it has only those, typ is its own leaf.
+func _methodLeaves(typ types.Type, iface *types.Interface, roots []types.Type) []types.Type {
+	var contextMethods, accessors []*types.Func
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		method := iface.ExplicitMethod(i)
+		if IsContextMethod(method) {
+			contextMethods = append(contextMethods, method)
+		} else {
+			accessors = append(accessors, method)
+		}
+	}
+	if len(accessors) == 0 {
+		if len(contextMethods) > 0 || len(roots) > 0 {
+			return []types.Type{typ}
+		}
+		return nil
+	}
+
+	leaves := make([]types.Type, len(accessors))
+	for i, accessor := range accessors {
+		if leaf, ok := _methodLeafCache.Load(accessor); ok {
+			leaves[i] = leaf.(types.Type)
+			continue
+		}
+		methods := append(append([]*types.Func(nil), contextMethods...), accessor)
+		leaf := types.NewInterfaceType(methods, roots).Complete()
+		actual, _ := _methodLeafCache.LoadOrStore(accessor, leaf)
+		leaves[i] = actual.(types.Type)
+	}
+	return leaves
+}
+
+// EmbedsExplicitlyContaining returns the interface recursively embedded in
+// this interface(s), if any, which explicitly contains a method with the given
+// name.
+//
+// If the method is an explicit method of the interface, returns the input
+// interface.  If the method is not a method of the input interface at all,
+// returns nil.  If the method is an explicit method of several recursively
+// embedded interfaces (rare), returns all of them.
+//
+// Note the returned value contains the types as used (e.g. named types), not
+// the underlying interface types.  This is all used to calculate which
+// contexts you must explicitly request to use a method.
+func EmbedsExplicitlyContaining(typ types.Type, methodName string) []types.Type {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	// embeds is keyed by TypeKey, to uniquify, for the case of diamond-deps
+	embeds := map[string]types.Type{}
+	// If the method is an explicit method of the interface, return the
+	// interface.
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i).Name() == methodName {
+			embeds[TypeKey(typ)] = typ
+			break // early-out: interfaces can't have explicit dupe methods
+		}
+	}
+
+	// Otherwise, check the embeds.
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		for _, embed := range EmbedsExplicitlyContaining(iface.EmbeddedType(i), methodName) {
+			embeds[TypeKey(embed)] = embed
+		}
+		// (no early-out: we can have the same method via two embeds, in 1.14+)
+	}
+
+	retval := make([]types.Type, 0, len(embeds))
+	for _, embed := range embeds {
+		retval = append(retval, embed)
+	}
+	return retval
+}
+
+// EmbedChain returns the path of embeds by which typ includes target: the
+// named interfaces from typ (if it's named) down to target (inclusive).
+// For example, given
+//
+//	type A interface { c }
+//	type c interface { other.D }
+//
+// EmbedChain(interface { A; other.E }, other.D) returns A, c, other.D.  It
+// returns nil if target isn't embedded in typ at all (say, because typ just
+// happens to implement it).
+func EmbedChain(typ, target types.Type) []types.Type {
+	if SameType(typ, target) {
+		return []types.Type{typ}
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		chain := EmbedChain(iface.EmbeddedType(i), target)
+		if chain == nil {
+			continue
+		}
+		if _, ok := types.Unalias(typ).(*types.Named); ok {
+			chain = append([]types.Type{typ}, chain...)
+		}
+		return chain
+	}
+	return nil
+}
+
+// _hasExplicitMethod returns true if iface has an explicit method with the
+// given name (i.e. it's defined on that interface, not some embedded
+// interface).
+func _hasExplicitMethod(iface *types.Interface, name string) bool {
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// _embedNamed takes an interface type and returns the interface type, if any,
+// recursively embedded in it with the given name.  The names are as with
+// lintutil.TypeIs.
+//
+// This is sort of a hack to get a reference to the types.Type for
+// context.Context; we don't have a convenient way to look it up a priori, but
+// we do have a reference to kacontext.Base, so we can grab the former from the
+// latter.
+func _embedNamed(typ types.Type, pkgName, typeName string) types.Type {
+	if lintutil.TypeIs(typ, pkgName, typeName) {
+		return typ
+	}
+
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	// Check the embeds
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embed := _embedNamed(iface.EmbeddedType(i), pkgName, typeName)
+		if embed != nil {
+			return embed
+		}
+	}
+
+	return nil
+}
+
+// ParamTypeAt gets the type of the parameter to which the i'th argument
+// of call, whose function has type funcType, will be assigned.
+//
+// You might think this would be just funcType.Params().At(i).Type(), but for
+// variadic functions the extra arguments -- and the first, if it's the
+// last parameter -- are assigned to elements of the final parameter, so we
+// return its element type: in f(ctx) where f(ctxs ...LoggerContext), ctx is
+// used as a LoggerContext, not a []LoggerContext.  The exception is a call
+// with a spread argument, f(ctxs...), where the slice itself is assigned to
+// the final parameter.
+//
+// Returns nil if there is no such parameter, which can happen for the function
+// make() due to a bug: https://github.com/golang/go/issues/37349.  After
+// that's fixed, this should never return nil.
+func ParamTypeAt(call *ast.CallExpr, funcType *types.Signature, i int) types.Type {
+	params := funcType.Params()
+	nParams := params.Len()
+	switch {
+	case !funcType.Variadic() || i < nParams-1:
+		if i < nParams {
+			return params.At(i).Type()
+		}
+		// Should never happen, except see the bug in the function docstring.
+		return nil
+	case call.Ellipsis.IsValid():
+		return params.At(nParams - 1).Type()
+	default:
+		slice, ok := params.At(nParams - 1).Type().Underlying().(*types.Slice)
+		if !ok {
+			// Only for append([]byte, string...), which has a spread
+			// argument anyway.
+			return nil
+		}
+		return slice.Elem()
+	}
+}
@@ -0,0 +1,151 @@
+package analysisengine
+
+// This file defines UseMarkers, which let code outside this package teach
+// the Tracker that some calls use contexts in ways it can't see, like our
+// caching library does.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sync"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// UseMarker marks the uses of contexts that a call implies, beyond those the
+// Tracker finds itself.  This is for libraries whose calls use contexts
+// indirectly, like a caching library which calls the function it's passed
+// later, with some context of its own.
+//
+// The Tracker calls MarkUses on every call in the package, after its own
+// handling, so MarkUses should return quickly for calls it doesn't care
+// about, typically by checking the callee with CalleeName.  It records uses
+// with the Tracker's exported methods, like UseInterface and Untrack.
+type UseMarker interface {
+	MarkUses(call *ast.CallExpr, tracker *Tracker)
+}
+
+// UseMarkerFunc is a function which is a UseMarker.
+type UseMarkerFunc func(call *ast.CallExpr, tracker *Tracker)
+
+// MarkUses calls marker(call, tracker).
+func (marker UseMarkerFunc) MarkUses(call *ast.CallExpr, tracker *Tracker) {
+	marker(call, tracker)
+}
+
+var (
+	_useMarkersMu sync.RWMutex
+	// _useMarkers are the markers every Tracker calls; see
+	// RegisterUseMarker.  They start with our caching library's.
+	_useMarkers = []UseMarker{
+		UseMarkerFunc(_markCachedFunctionUsed),
+		UseMarkerFunc(_markKeyParamsFunctionUsed),
+	}
+)
+
+// RegisterUseMarker adds a marker which every Tracker calls on each call it
+// sees; see UseMarker.  It's meant for forks with special cases of their
+// own, which can register them from an init function rather than patching
+// the Tracker.  Like SetContextRoots, it applies to all trackers, so should
+// be called before analysis starts.
+func RegisterUseMarker(marker UseMarker) {
+	_useMarkersMu.Lock()
+	defer _useMarkersMu.Unlock()
+	_useMarkers = append(_useMarkers, marker)
+}
+
+// _markRegisteredUses calls each registered UseMarker on the given call.
+func (tracker *Tracker) _markRegisteredUses(call *ast.CallExpr) {
+	_useMarkersMu.RLock()
+	markers := _useMarkers
+	_useMarkersMu.RUnlock()
+	for _, marker := range markers {
+		marker.MarkUses(call, tracker)
+	}
+}
+
+// TypesInfo returns the type information of the package being tracked.
+func (tracker *Tracker) TypesInfo() *types.Info {
+	return tracker.typesInfo
+}
+
+// CalleeName returns the name of the function or method called, as returned
+// by lintutil.NameOf, like "context.WithValue", or "" if the callee isn't
+// a named function or method (e.g. it's a function-literal).
+func (tracker *Tracker) CalleeName(call *ast.CallExpr) string {
+	return lintutil.NameOf(lintutil.ObjectFor(call.Fun, tracker.typesInfo))
+}
+
+// UsageOf returns the Usage of the tracked variable to which expr refers, or
+// nil if there is none: expr may be an identifier, or a selector of a
+// tracked struct field, like s.ctx, possibly parenthesized.
+func (tracker *Tracker) UsageOf(expr ast.Expr) *Usage {
+	return tracker._usageOf(expr)
+}
+
+// UseInterface records that the tracked variable to which expr refers, if
+// any, is used as the given interface type at pos; see UsageOf.
+func (tracker *Tracker) UseInterface(expr ast.Expr, typ types.Type, pos token.Pos) {
+	if info := tracker._usageOf(expr); info != nil {
+		info.useInterface(typ, pos)
+	}
+}
+
+// UseEntirely records that the tracked variable to which expr refers, if
+// any, uses all of its interfaces at pos, as if it were passed to a context
+// sink with mode SinkAll.
+func (tracker *Tracker) UseEntirely(expr ast.Expr, pos token.Pos) {
+	if info := tracker._usageOf(expr); info != nil {
+		info.useInterface(info.obj.Type(), pos)
+	}
+}
+
+// Untrack stops tracking the given object, so that nothing reports on its
+// uses.  This is for variables whose type is dictated by something else, so
+// that they can't request any less.
+func (tracker *Tracker) Untrack(obj types.Object) {
+	delete(tracker.trackedIdents, obj)
+}
+
+// _cachedFunctionContext returns the context parameter of the function
+// passed as the first argument of a call to the given function of our
+// caching library (pkg/lib/cache), or nil if this isn't such a call.
+func _cachedFunctionContext(call *ast.CallExpr, tracker *Tracker, funcName string) types.Object {
+	if tracker.CalleeName(call) != funcName ||
+		len(call.Args) == 0 { // len == 0 never happens (cache arg is required)
+		return nil
+	}
+
+	cachedFunctionSig, ok := tracker.typesInfo.TypeOf(call.Args[0]).(*types.Signature)
+	if !ok || cachedFunctionSig.Params().Len() == 0 {
+		// should also never happen (if init-time validation passes): first arg
+		// of cache is always a function, and it must have a context arg
+		return nil
+	}
+	return cachedFunctionSig.Params().At(0)
+}
+
+// _markCachedFunctionUsed marks any context-interfaces that might be needed
+// for our caching library (pkg/lib/cache), as a special-case.  This is a case
+// it's common in our codebase, and hard to handle other ways, so we just put
+// in a special hack.
+func _markCachedFunctionUsed(call *ast.CallExpr, tracker *Tracker) {
+	ctxArg := _cachedFunctionContext(call, tracker, "github.com/Khan/webapp/pkg/lib/cache.Cache")
+	if info := tracker.trackedIdents[ctxArg]; info != nil {
+		info.isCached = true
+	}
+}
+
+// _markKeyParamsFunctionUsed marks any context-interfaces that might be needed
+// for a key-params function in our caching library (pkg/lib/cache), as a
+// special-case.  This is a case it's common in our codebase, and hard to
+// handle other ways, so we just put in a special hack.
+func _markKeyParamsFunctionUsed(call *ast.CallExpr, tracker *Tracker) {
+	// If it's used as a key-params fxn, its argument types must match exactly
+	// those of the cached function, so we just ignore it.
+	ctxArg := _cachedFunctionContext(call, tracker, "github.com/Khan/webapp/pkg/lib/cache.KeyParamsFxn")
+	if ctxArg != nil {
+		tracker.Untrack(ctxArg)
+	}
+}
@@ -0,0 +1,992 @@
+package analysisengine
+
+// This file defines the Tracker, which finds the context variables in a
+// package and records how each is used.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// SinkMode says what passing a context to a context sink means: a function
+// which takes a context but doesn't use its typed interfaces as such, like
+// fmt.Println or slog.InfoContext.
+type SinkMode string
+
+// The modes a context sink may have.
+const (
+	// SinkIgnore means passing a context to the sink isn't a use of any of
+	// its typed interfaces: only of context.Context (or another root), if
+	// the sink's parameter is one, like slog.InfoContext's, and not even
+	// that if it's any, like fmt.Println's.  This is right for things like
+	// loggers, which only care about the context.Context (if anything).
+	SinkIgnore SinkMode = "ignore"
+	// SinkAll means passing a context to the sink is a use of all of its
+	// interfaces.  This is right for things which inspect the context
+	// dynamically, and might use whatever it has.
+	SinkAll SinkMode = "all"
+)
+
+// Options configures a Tracker.  The zero value is a reasonable default.
+type Options struct {
+	// SameUnit decides which packages' interfaces are as transparent as the
+	// analyzed package's own; see ExplicitInterfaces.
+	SameUnit SameUnitFunc
+	// Runners lists functions, as returned by lintutil.NameOf, which call a
+	// function-literal argument with their context argument; see
+	// identifyRunnerCalls.
+	Runners []string
+	// Derivers lists functions, as returned by lintutil.NameOf, which
+	// return a context derived from their context argument; see
+	// identifyDeriverCalls.
+	Derivers []string
+	// Sinks lists context sinks, by name as returned by lintutil.NameOf,
+	// with their modes.
+	Sinks map[string]SinkMode
+	// ServerInterfaces says whether to track server interfaces (see
+	// IsServerInterface) passed alongside a context, as if they were
+	// contexts themselves.
+	ServerInterfaces bool
+	// FunctionTypes says whether to track the context parameters of
+	// function-typed declarations; see identifyFunctionTypes.
+	FunctionTypes bool
+	// SeparateImplementations says not to share the Usage of the
+	// implementations of an interface method (see identifyInterfaceMethods),
+	// so that each records only its own uses.  This is for checking which
+	// implementations need what; see InterfaceMethods.
+	SeparateImplementations bool
+}
+
+// Tracker is the object we use to manage our process of marking
+// which interfaces requested by an object are used.  Create one with
+// NewTracker.
+type Tracker struct {
+	// Map goes: object we want to check -> interfaces it uses -> whether we've
+	// found a use.  The types are those returned by ExplicitInterfaces.
+	trackedIdents map[types.Object]*Usage
+
+	typesInfo *types.Info
+	pkg       *types.Package
+	options   Options
+
+	// runnerCalls are calls to runners whose context arguments we've
+	// forwarded to their function-literal arguments; see
+	// identifyRunnerCalls.
+	runnerCalls map[*ast.CallExpr]bool
+	// deriverCalls are calls to derivers, with the context each is passed;
+	// see identifyDeriverCalls.
+	deriverCalls map[*ast.CallExpr]types.Object
+	// aliases are parameters of such function-literals which share the
+	// Usage of the context passed to the runner, and parameters of functions
+	// which share the Usage of a function-typed declaration's parameter (see
+	// identifyFunctionTypes); we report on the latter.
+	aliases map[types.Object]bool
+	// serverParams are the parameters with server interface types which
+	// we track as if they were contexts; see _serverInterfaceParams.
+	serverParams map[types.Object]bool
+	// captures are the contexts stored by closures which outlive them, by
+	// the identifier stored; see identifyCaptures.
+	captures map[*ast.Ident]*Capture
+	// funcSignatures maps the scope of each function in the package to its
+	// signature; see _signatureAt.  It's computed when first needed.
+	funcSignatures map[*types.Scope]*types.Signature
+}
+
+// track adds the given identifier to have its interface usage tracked.
+//
+// If the identifier is named _, or is not a context type, it is ignored.
+func (tracker *Tracker) track(ident *ast.Ident) {
+	obj := tracker.typesInfo.Defs[ident]
+	// obj is only nil in edge cases we don't care about (like struct fields)
+	if obj == nil || obj.Name() == "_" ||
+		!IsContextType(obj.Type()) && !tracker.serverParams[obj] {
+		return
+	}
+
+	// Nor do we track type parameters, like T in
+	//	func Start[T TraceContext](ctx T) (T, trace.Span)
+	// or variables of their types, like ctx: the caller chooses the type,
+	// and the function can only request it via the constraint.
+	if _, ok := obj.(*types.TypeName); ok {
+		return
+	}
+	if _, ok := obj.Type().(*types.TypeParam); ok {
+		return
+	}
+
+	ifaces := LeafInterfaces(obj.Type())
+	if len(ifaces) == 0 {
+		return // this isn't a ctx.
+	}
+
+	// If you _just_ requested context.Context, and don't use it, that's
+	// probably to match an interface or for future expansion; there's
+	// nothing to narrow, so we skip checking this case.  (The interface
+	// analyzer reports such parameters if they're never used at all, with
+	// -typedcontextinterface.unusedroots.)
+	if len(ifaces) == 1 && IsContextRoot(ifaces[0]) {
+		return
+	}
+
+	// Otherwise, get ready to track this interface.
+	tracker.TrackObject(obj)
+}
+
+// TrackObject starts tracking the uses of the given object, whatever its
+// type, and returns its (so far empty) Usage.  Track calls this for each
+// context variable it finds; it's useful by itself for checking some subset
+// of a function's uses, e.g. those in one branch of an if, or for checking a
+// struct field, whose uses are those of its selectors, like s.ctx.
+func (tracker *Tracker) TrackObject(obj types.Object) *Usage {
+	usage := &Usage{
+		obj:           obj,
+		interfaceUses: map[types.Type]token.Pos{},
+		methodUses:    map[string]token.Pos{},
+		sameUnit:      tracker.options.SameUnit,
+	}
+	tracker.trackedIdents[obj] = usage
+	return usage
+}
+
+// _usageOf returns the Usage of the tracked variable to which expr refers, if
+// any: expr may be an identifier, or a selector of a struct field, like
+// s.ctx, if the field is tracked (see TrackObject), possibly parenthesized.
+func (tracker *Tracker) _usageOf(expr ast.Expr) *Usage {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return tracker.trackedIdents[tracker.typesInfo.ObjectOf(expr)]
+	case *ast.SelectorExpr:
+		selection := tracker.typesInfo.Selections[expr]
+		if selection == nil || selection.Kind() != types.FieldVal {
+			return nil
+		}
+		return tracker.trackedIdents[selection.Obj()]
+	}
+	return nil
+}
+
+// _markArgsUsed marks used any context-interfaces which are required as
+// parameters to the given call.
+//
+// For example, if you call database.Read(ctx), this will mark the
+// database.Context interface of ctx as used.
+func (tracker *Tracker) _markArgsUsed(call *ast.CallExpr) {
+	if tracker.typesInfo.Types[call].Value != nil {
+		// A constant, like len("abc"): go/types doesn't record the
+		// builtin's signature, and it can't use a context anyway.
+		return
+	}
+	funcType, ok := tracker.typesInfo.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		panic("Bad Signature?")
+	}
+	for i := 0; i < len(call.Args); i++ {
+		info := tracker._usageOf(call.Args[i])
+		if info == nil {
+			continue
+		}
+		paramType := ParamTypeAt(call, funcType, i)
+		if paramType == nil {
+			continue
+		}
+		info.useInterface(paramType, call.Args[i].Pos())
+	}
+}
+
+// _markRootArgsUsed marks used context.Context (or another root) for any
+// contexts passed to the given call as such, like slog.InfoContext(ctx, ...),
+// but not any other interfaces.  This is for context sinks with mode
+// SinkIgnore.
+func (tracker *Tracker) _markRootArgsUsed(call *ast.CallExpr) {
+	funcType, ok := tracker.typesInfo.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		return
+	}
+	for i, arg := range call.Args {
+		info := tracker._usageOf(arg)
+		if info == nil {
+			continue
+		}
+		paramType := ParamTypeAt(call, funcType, i)
+		if paramType != nil && IsContextRoot(paramType) {
+			info.useInterface(paramType, arg.Pos())
+		}
+	}
+}
+
+// _markArgsUsedEntirely marks used all the context-interfaces of any contexts
+// passed to the given call.  This is for context sinks with mode SinkAll.
+func (tracker *Tracker) _markArgsUsedEntirely(call *ast.CallExpr) {
+	for _, arg := range call.Args {
+		info := tracker._usageOf(arg)
+		if info != nil {
+			info.useInterface(info.obj.Type(), arg.Pos())
+		}
+	}
+}
+
+// _markCastUsed marks used any context-interfaces used via a cast.
+//
+// This is sorta a hack: you're doing a cast and all bets are off!  But in
+// practice it makes sense that we mark the overlap between the type you are
+// and the type you're casting to as used.  For example, if you cast from
+// interface{ A; B } to interface{ B; C } we'll count that as a use of B.
+func (tracker *Tracker) _markCastUsed(cast *ast.TypeAssertExpr) {
+	info := tracker._usageOf(cast.X)
+	if info != nil {
+		info.useInterface(tracker.typesInfo.TypeOf(cast.Type), cast.Pos())
+	}
+}
+
+// _markConversionUsed marks used any context-interfaces used via a
+// conversion, like SmallContext(ctx), with the same rules as a cast (see
+// _markCastUsed): the interface converted to is used.  Conversions to
+// non-interface types don't use anything.
+func (tracker *Tracker) _markConversionUsed(conversion *ast.CallExpr) {
+	typ := tracker.typesInfo.TypeOf(conversion.Fun)
+	if len(conversion.Args) != 1 || typ == nil || !types.IsInterface(typ) {
+		return
+	}
+	info := tracker._usageOf(conversion.Args[0])
+	if info != nil {
+		info.useInterface(typ, conversion.Pos())
+	}
+}
+
+// _markReceiverUsed marks used any context-interfaces which are required to
+// make this receiver-method call.
+//
+// For example, if you call ctx.Datastore(), this will mark the
+// datastore.KAContext interface of ctx as used.
+func (tracker *Tracker) _markReceiverUsed(call *ast.CallExpr) {
+	// We want the case where the function is <ident>.<method>.
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	info := tracker._usageOf(selector.X)
+	if info != nil {
+		info.useMethod(selector.Sel.Name, selector.Sel.Pos())
+	}
+}
+
+// _markMethodValueUsed marks used the method of the given selector, if it's a
+// method value of a tracked variable, like ctx.Logger in
+//
+//	defer flushLogs(ctx.Logger)
+//	done := ctx.Done
+//
+// A selector which is called, like ctx.Logger(), is also a method value;
+// _markReceiverUsed handles those too, which does no harm.
+//
+// (We don't need any special handling for defer, go, or select statements
+// otherwise: MarkUses visits the calls within them like any others.)
+func (tracker *Tracker) _markMethodValueUsed(selector *ast.SelectorExpr) {
+	selection := tracker.typesInfo.Selections[selector]
+	if selection == nil || selection.Kind() != types.MethodVal {
+		return
+	}
+	info := tracker._usageOf(selector.X)
+	if info != nil {
+		info.useMethod(selector.Sel.Name, selector.Sel.Pos())
+	}
+}
+
+// _markReceiverResultsDerived handles an assignment like
+//
+//	spanCtx := ctx.WithSpan()
+//
+// where some method of a tracked context returns another context.  Calling
+// the method already counts as a use of the interface of ctx which provides
+// it (see _markReceiverUsed); here we record that spanCtx is derived from
+// ctx.  We still track spanCtx, but its type was chosen by WithSpan, not by
+// the caller, so we don't report on it.
+//
+// We only handle definitions without an explicit type: if you write
+//
+//	var spanCtx LoggerContext = ctx.WithSpan()
+//
+// you've chosen the type of spanCtx yourself.
+func (tracker *Tracker) _markReceiverResultsDerived(lhs []ast.Expr, rhs []ast.Expr) {
+	if len(rhs) != 1 {
+		return
+	}
+	call, ok := rhs[0].(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	recv, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	recvInfo := tracker.trackedIdents[tracker.typesInfo.ObjectOf(recv)]
+	if recvInfo == nil {
+		return
+	}
+
+	for _, expr := range lhs {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		info := tracker.trackedIdents[tracker.typesInfo.Defs[ident]]
+		if info != nil {
+			info.derivedFrom = recvInfo
+		}
+	}
+}
+
+// _markAssignedUsed marks used any context-interfaces which are required to
+// assign the given values to variables (or fields, or results) of the given
+// target types, like SmallContext in
+//
+//	var small SmallContext = ctx
+//	server.ctx = ctx // where the field has type SmallContext
+//	return ctx       // from a function returning SmallContext
+//
+// This is just like passing ctx to a function taking a SmallContext.  A nil
+// type, like that of _ in `_ = ctx`, isn't a use.  (For returns, see
+// _markReturnUsed.)
+//
+// (A definition like `small := ctx` converts nothing: small has the type of
+// ctx, and is tracked in its own right.)
+func (tracker *Tracker) _markAssignedUsed(targets []types.Type, values []ast.Expr) {
+	if len(targets) != len(values) {
+		return // x, y = f(), which can't be a tracked variable
+	}
+	for i, value := range values {
+		if targets[i] == nil {
+			continue
+		}
+		info := tracker._usageOf(value)
+		if info != nil {
+			info.useInterface(targets[i], ast.Unparen(value).Pos())
+		}
+	}
+}
+
+// _signatureAt returns the signature of the innermost function (declared, or
+// a literal) containing pos, or nil if there is none.
+func (tracker *Tracker) _signatureAt(pos token.Pos) *types.Signature {
+	if tracker.funcSignatures == nil {
+		tracker.funcSignatures = map[*types.Scope]*types.Signature{}
+		for _, obj := range tracker.typesInfo.Defs {
+			if fn, ok := obj.(*types.Func); ok && fn.Scope() != nil {
+				tracker.funcSignatures[fn.Scope()] = fn.Type().(*types.Signature)
+			}
+		}
+		for expr, value := range tracker.typesInfo.Types {
+			lit, ok := expr.(*ast.FuncLit)
+			if !ok {
+				continue
+			}
+			sig, ok := value.Type.(*types.Signature)
+			scope := tracker.typesInfo.Scopes[lit.Type]
+			if ok && scope != nil {
+				tracker.funcSignatures[scope] = sig
+			}
+		}
+	}
+
+	for scope := tracker.pkg.Scope().Innermost(pos); scope != nil; scope = scope.Parent() {
+		if sig := tracker.funcSignatures[scope]; sig != nil {
+			return sig
+		}
+	}
+	return nil
+}
+
+// _markReturnUsed marks used any context-interfaces which are required to
+// return the given values from the enclosing function, like LoggerContext in
+//
+//	func F(ctx AppContext) LoggerContext {
+//		return ctx
+//	}
+//
+// (see _markAssignedUsed).  A bare return, from a function with named
+// results, uses each result as its own type: the function's caller gets all
+// of it.
+func (tracker *Tracker) _markReturnUsed(ret *ast.ReturnStmt) {
+	sig := tracker._signatureAt(ret.Pos())
+	if sig == nil {
+		return
+	}
+	results := sig.Results()
+
+	if len(ret.Results) == 0 {
+		for i := 0; i < results.Len(); i++ {
+			info := tracker.trackedIdents[results.At(i)]
+			if info != nil {
+				info.useInterface(results.At(i).Type(), ret.Pos())
+			}
+		}
+		return
+	}
+
+	resultTypes := make([]types.Type, results.Len())
+	for i := range resultTypes {
+		resultTypes[i] = results.At(i).Type()
+	}
+	tracker._markAssignedUsed(resultTypes, ret.Results)
+}
+
+func (tracker *Tracker) _markSingleStructValueUsed(typ types.Type, val ast.Expr) {
+	info := tracker._usageOf(val)
+	if info != nil {
+		info.useInterface(typ, val.Pos())
+	}
+}
+
+// _markCompositeLitValuesUsed marks used any context-interfaces which are
+// required to use the context in this struct-, map-, slice-, or
+// array-literal.
+//
+// Struct-literals are the common case; slice-literals matter mostly as the
+// spread argument of a variadic function (see ParamTypeAt).
+func (tracker *Tracker) _markCompositeLitValuesUsed(compLit *ast.CompositeLit) {
+	if len(compLit.Elts) == 0 {
+		return
+	}
+
+	typ := tracker.typesInfo.TypeOf(compLit)
+	if typ == nil { // should never happen
+		return
+	}
+
+	var elem types.Type
+	switch underlying := typ.Underlying().(type) {
+	case *types.Slice:
+		elem = underlying.Elem()
+	case *types.Array:
+		elem = underlying.Elem()
+	case *types.Map:
+		for _, element := range compLit.Elts {
+			if element, ok := element.(*ast.KeyValueExpr); ok {
+				tracker._markSingleStructValueUsed(underlying.Key(), element.Key)
+				tracker._markSingleStructValueUsed(underlying.Elem(), element.Value)
+			}
+		}
+		return
+	}
+	if elem != nil {
+		// Each element (or value, if keyed by index) has the element type;
+		// this covers e.g. f([]LoggerContext{ctx}...) for variadic f.
+		for _, element := range compLit.Elts {
+			if keyValue, ok := element.(*ast.KeyValueExpr); ok {
+				element = keyValue.Value
+			}
+			tracker._markSingleStructValueUsed(elem, element)
+		}
+		return
+	}
+
+	underlying, ok := typ.Underlying().(*types.Struct)
+	if !ok { // should never happen
+		return
+	}
+
+	// It's guaranteed that either all fields are keyed, or none of them are,
+	// but we just check each, it's easier that way.
+	for i, element := range compLit.Elts {
+		switch element := element.(type) {
+		case *ast.KeyValueExpr:
+			// Keyed field; the type of the key is the type of the
+			// struct-field.
+			tracker._markSingleStructValueUsed(
+				tracker.typesInfo.TypeOf(element.Key), element.Value)
+		default:
+			// Unkeyed field; we just look at the i'th field of the struct.
+			tracker._markSingleStructValueUsed(
+				underlying.Field(i).Type(), element)
+		}
+	}
+}
+
+// MarkUses traverses marks as used all interfaces required by the code in the
+// given node and all its descendants.
+func (tracker *Tracker) MarkUses(startNode ast.Node) {
+	ast.Inspect(startNode, func(node ast.Node) bool {
+		tracker.MarkNodeUses(node)
+		return true // recurse
+	})
+}
+
+// MarkNodeUses marks as used all interfaces required by the given node itself
+// (but not its descendants).
+func (tracker *Tracker) MarkNodeUses(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Ident:
+		tracker._markCaptureUsed(node)
+	case *ast.TypeAssertExpr:
+		if node.Type != nil { // nil means a type-switch x.(type)
+			tracker._markCastUsed(node)
+		}
+	case *ast.CallExpr:
+		if tracker.typesInfo.Types[node.Fun].IsType() {
+			tracker._markConversionUsed(node)
+			break
+		}
+		sinkMode := tracker.options.Sinks[lintutil.NameOf(lintutil.ObjectFor(node.Fun, tracker.typesInfo))]
+		switch {
+		case tracker.runnerCalls[node]:
+			// We've already forwarded the context arguments to the
+			// function-literal; see identifyRunnerCalls.
+		case tracker.deriverCalls[node] != nil:
+			tracker._markDeriverArgsUsed(node)
+		case sinkMode == SinkIgnore:
+			// Passing the context to a sink isn't a use of its typed
+			// interfaces.
+			tracker._markRootArgsUsed(node)
+		case sinkMode == SinkAll:
+			tracker._markArgsUsedEntirely(node)
+		default:
+			tracker._markArgsUsed(node)
+		}
+		tracker._markReceiverUsed(node)
+		tracker._markRegisteredUses(node)
+	case *ast.SelectorExpr:
+		tracker._markMethodValueUsed(node)
+	case *ast.AssignStmt:
+		switch node.Tok {
+		case token.DEFINE:
+			tracker._markReceiverResultsDerived(node.Lhs, node.Rhs)
+			tracker._markDerivedAssignedUsed(node.Lhs, node.Rhs)
+		case token.ASSIGN:
+			lhsTypes := make([]types.Type, len(node.Lhs))
+			for i, lhs := range node.Lhs {
+				lhsTypes[i] = tracker.typesInfo.TypeOf(lhs)
+			}
+			tracker._markAssignedUsed(lhsTypes, node.Rhs)
+			tracker._markDerivedAssignedUsed(node.Lhs, node.Rhs)
+		}
+	case *ast.ValueSpec:
+		if node.Type == nil {
+			lhs := make([]ast.Expr, len(node.Names))
+			for i, name := range node.Names {
+				lhs[i] = name
+			}
+			tracker._markReceiverResultsDerived(lhs, node.Values)
+		} else {
+			lhsTypes := make([]types.Type, len(node.Names))
+			for i := range lhsTypes {
+				lhsTypes[i] = tracker.typesInfo.TypeOf(node.Type)
+			}
+			tracker._markAssignedUsed(lhsTypes, node.Values)
+			lhs := make([]ast.Expr, len(node.Names))
+			for i, name := range node.Names {
+				lhs[i] = name
+			}
+			tracker._markDerivedAssignedUsed(lhs, node.Values)
+		}
+	case *ast.ReturnStmt:
+		tracker._markReturnUsed(node)
+	case *ast.CompositeLit: // struct, map, or array
+		tracker._markCompositeLitValuesUsed(node)
+	}
+}
+
+// trackIdents registers all identifiers (function parameters, variables, etc.)
+// in the given node and all its descendants if we want to ensure they have no
+// more ka-contexts than they need.
+//
+// If includeFuncType is set, we will recurse within the first funcType we see
+// (typically the input node).  Otherwise, we don't (see comments inline).
+func (tracker *Tracker) trackIdents(node ast.Node, includeFuncType bool) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Ident:
+			tracker.track(node)
+			return false // nothing to recurse
+		case *ast.GenDecl:
+			// Don't recurse within typedefs -- we'll lint at their
+			// use-sites if relevant.
+			return node.Tok != token.TYPE
+		case *ast.FuncType:
+			// We don't look at FuncTypes unless they're a child of a
+			// FuncLit or a FuncDecl.  In those cases (immediately following)
+			// we set the flag includeFuncType to signal that; otherwise we
+			// just ignore the FuncType.  (We also set the flag back to false
+			// before recursing; we only want to handle the immediate child
+			// FuncType, not, say, a FuncType within the FuncDecl's body.)
+			//
+			// This is all because we want to analyze the named parameters in,
+			// e.g.,
+			//	helper := func(ctx ...) { ... }
+			// (which is a FuncLit) but not in
+			//	var myFunc = cache.Cache(_uncachedMyFunc).(func(ctx ...))
+			// (where the FuncType is nested within a TypeAssertExpr
+			// instead) as the latter don't really have uses as such.
+			ret := includeFuncType
+			includeFuncType = false
+			return ret
+		case *ast.FuncDecl:
+			// In this case, we want to recurse into our child FuncType.  But
+			// the normal recursion we do via `return true` won't do that,
+			// since normally FuncType's are ignored (in the case right before
+			// this one).  So we explicitly recurse on the FuncType, setting
+			// the flag such that it won't be ignored.
+			tracker.trackIdents(node.Type, true)
+			return true
+		case *ast.FuncLit:
+			// Same as FuncDecl.
+			tracker.trackIdents(node.Type, true)
+			return true
+		default:
+			return true // recurse everywhere else
+		}
+	})
+}
+
+// InterfaceMethod is a method of a named interface declared in a package,
+// with its implementations in that package.
+type InterfaceMethod struct {
+	// Interface is the interface.
+	Interface *types.TypeName
+	// Method is the method, which may be declared by an interface Interface
+	// embeds.
+	Method *types.Func
+	// Implementations are the declarations of the methods implementing it,
+	// of the types in the package whose pointers implement Interface, in
+	// order of position.
+	Implementations []*ast.FuncDecl
+}
+
+// InterfaceMethods returns the methods of the named interfaces declared in
+// the given files, which should be all the files of the package, with their
+// implementations there, in order of position of the interface, then of
+// the method.  Methods without implementations are omitted.
+func InterfaceMethods(files []*ast.File, typesInfo *types.Info) []InterfaceMethod {
+	recvs := lintutil.ReceiversByType(files, typesInfo)
+
+	// First, find all the named interfaces in the package.
+	var methods []InterfaceMethod
+	for _, def := range typesInfo.Defs {
+		typeDef, ok := def.(*types.TypeName)
+		if !ok {
+			continue // not a type-definition
+		}
+		iface, ok := typeDef.Type().Underlying().(*types.Interface)
+		if !ok {
+			continue // not an interface
+		}
+		if iface.Empty() {
+			// early-out; the rest would be a no-op anyway because the empty
+			// interface has no methods.
+			continue
+		}
+
+		// We have a (non-empty) interface; find its methods.
+		//
+		// The methods are identified by their "ID" as used by the go/types
+		// package, which is the unqualified-name for an exported method, and
+		// the package + unqualified name for unexported methods.  This matches
+		// how go does interface method name-matching.
+		implsByMethod := map[string][]*ast.FuncDecl{}
+
+		// Now, go through all the receivers for types which implement this
+		// interface, and collect their methods.
+		for recvTyp, recvDefs := range recvs {
+			// We include the methods as long as the pointer implements the
+			// interface.  (This includes the case where the value implements
+			// the interface.)
+			if !types.Implements(types.NewPointer(recvTyp), iface) {
+				continue
+			}
+
+			for _, recvDef := range recvDefs {
+				recvObj := typesInfo.Defs[recvDef.Name]
+				if recvObj == nil { // should never happen
+					continue
+				}
+				// Id() returns package + local-name if the method is
+				// unexported, or just the local-name if it's exported;
+				// this is the key on which Go matches interface
+				// method-names.
+				id := recvObj.Id()
+				implsByMethod[id] = append(implsByMethod[id], recvDef)
+			}
+		}
+
+		for i := 0; i < iface.NumMethods(); i++ {
+			method := iface.Method(i)
+			impls := implsByMethod[method.Id()]
+			if len(impls) == 0 {
+				continue // not a method of this interface, or unimplemented
+			}
+			sort.Slice(impls, func(i, j int) bool { return impls[i].Pos() < impls[j].Pos() })
+			methods = append(methods, InterfaceMethod{typeDef, method, impls})
+		}
+	}
+
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Interface != methods[j].Interface {
+			return methods[i].Interface.Pos() < methods[j].Interface.Pos()
+		}
+		return methods[i].Method.Pos() < methods[j].Method.Pos()
+	})
+	return methods
+}
+
+// ImplementationParam returns the first parameter of the given method, where
+// its context should be, or nil if it has no named parameters.
+func ImplementationParam(impl *ast.FuncDecl, typesInfo *types.Info) types.Object {
+	paramsList := impl.Type.Params.List
+	if len(paramsList) == 0 || len(paramsList[0].Names) == 0 {
+		return nil
+	}
+	return typesInfo.Defs[paramsList[0].Names[0]]
+}
+
+// identifyInterfaceMethods modifies trackedIdents so that its maps are shared
+// between implementations of the same interface method.
+//
+// If you want to implement an interface, the types of your methods must match
+// exactly; this means sometimes you have to ask for a type with more
+// typed context interfaces than you really wanted.  For example, if you have
+// several implementations T, U, and V of an interface I { M(ctx ...) }, you
+// might require that the context be some context-type K because T needs that
+// type, but U and V might need only a subset of it.  We don't want to complain
+// about that.
+//
+// So, we update tracker.trackedIdents, such that the entries corresponding to
+// the 'ctx' arguments of T, U, and V are all the same.  That way, when we mark
+// a type as used by T, we'll cover U and V as well.  In fact, we'll allow it
+// even if T, U, and V each use different subsets of K, which add up to the
+// whole thing!  (See tests for examples.)
+//
+// NOTE: We might also wish to check for the case where the interface
+// being implemented is in another package; we could look for the standard
+//
+//	var _ I = (*T)(nil) // ensure T implements I
+//
+// to avoid looking at all interfaces ever.
+//
+// NOTE: Another thing we should check with interfaces is that the
+// interface explicitly requests all the contexts that its implementations do.
+// If you use named types, that's already guaranteed -- an interface-method
+// `M(MyContext)` is only matched by an implementation-method `M(MyContext)` --
+// but if you did `M(interface { ... })` on the interface, then the
+// implementation can use any other interface with the same method-set.  We
+// should ideally to say they have to be structurally the same, or at least
+// have the same explicit members, in the sense used elsewhere in this linter.
+func (tracker *Tracker) identifyInterfaceMethods(files []*ast.File) {
+	for _, method := range InterfaceMethods(files, tracker.typesInfo) {
+		// If this is the first implementation we've found with a tracked
+		// context, save its Usage so we can use it for later ones.
+		// Otherwise, re-use that saved Usage.
+		var shared *Usage
+		for _, impl := range method.Implementations {
+			paramObj := ImplementationParam(impl, tracker.typesInfo)
+			if paramObj == nil || tracker.trackedIdents[paramObj] == nil {
+				// not a parameter we are interested in
+				continue
+			}
+			if shared == nil {
+				shared = tracker.trackedIdents[paramObj]
+			} else {
+				tracker.trackedIdents[paramObj] = shared
+			}
+		}
+	}
+}
+
+// identifyRunnerCalls handles calls to "runners" (see Options.Runners), like
+//
+//	pool.Submit(ctx, func(ctx LoggerContext) error { ... })
+//
+// which call their function-literal argument with their context argument.
+// Passing ctx to Submit doesn't tell us which of its interfaces are needed;
+// the function-literal does.
+//
+// So we treat the runner call as if it called the literal with the context
+// directly: if the literal's parameter is a typed context, the outer context
+// is used as that type, as with any other call.  If it's just a
+// context.Context (which we don't otherwise track), the parameter shares the
+// Usage of the outer context (as in identifyInterfaceMethods), so that
+// whatever the literal does with it -- casting it, passing it along, etc. --
+// counts as a use of the outer context.
+//
+// If several tracked contexts are passed to the runner, we assume the first
+// is the one passed to the literal.
+func (tracker *Tracker) identifyRunnerCalls(files []*ast.File) {
+	runners := map[string]bool{}
+	for _, runner := range tracker.options.Runners {
+		runners[runner] = true
+	}
+
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok ||
+				!runners[lintutil.NameOf(lintutil.ObjectFor(call.Fun, tracker.typesInfo))] {
+				return true
+			}
+
+			var outer *Usage
+			for _, arg := range call.Args {
+				if ident, ok := arg.(*ast.Ident); ok {
+					outer = tracker.trackedIdents[tracker.typesInfo.ObjectOf(ident)]
+					if outer != nil {
+						break
+					}
+				}
+			}
+			if outer == nil {
+				return true
+			}
+
+			for _, arg := range call.Args {
+				funcLit, ok := arg.(*ast.FuncLit)
+				if !ok {
+					continue
+				}
+				for _, field := range funcLit.Type.Params.List {
+					for _, name := range field.Names {
+						param := tracker.typesInfo.Defs[name]
+						if param == nil || !IsContextType(param.Type()) {
+							continue
+						}
+						if tracker.trackedIdents[param] != nil {
+							outer.useInterface(param.Type(), name.Pos())
+						} else if name.Name != "_" {
+							tracker.trackedIdents[param] = outer
+							tracker.aliases[param] = true
+						}
+						tracker.runnerCalls[call] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// _serverInterfaceParams returns the parameters, in the given files, whose
+// types are server interfaces (see IsServerInterface) and which are in the
+// same parameter list as a context.  With Options.ServerInterfaces, we track
+// those just like contexts.
+//
+// We require the adjacent context so we don't mistake any old interface of
+// getters for a server.
+func _serverInterfaceParams(files []*ast.File, typesInfo *types.Info) map[types.Object]bool {
+	params := map[types.Object]bool{}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			funcType, ok := node.(*ast.FuncType)
+			if !ok || funcType.Params == nil {
+				return true
+			}
+			hasContext := false
+			var servers []types.Object
+			for _, field := range funcType.Params.List {
+				typ := typesInfo.TypeOf(field.Type)
+				switch {
+				case typ == nil:
+				case IsContextType(typ):
+					hasContext = true
+				case IsServerInterface(typ):
+					for _, name := range field.Names {
+						if obj := typesInfo.Defs[name]; obj != nil {
+							servers = append(servers, obj)
+						}
+					}
+				}
+			}
+			if hasContext {
+				for _, obj := range servers {
+					params[obj] = true
+				}
+			}
+			return true
+		})
+	}
+	return params
+}
+
+// NewTracker returns a tracker for the given package, with no identifiers
+// tracked yet.
+func NewTracker(typesInfo *types.Info, pkg *types.Package, options Options) *Tracker {
+	return &Tracker{
+		trackedIdents: map[types.Object]*Usage{},
+		typesInfo:     typesInfo,
+		pkg:           pkg,
+		options:       options,
+		runnerCalls:   map[*ast.CallExpr]bool{},
+		deriverCalls:  map[*ast.CallExpr]types.Object{},
+		aliases:       map[types.Object]bool{},
+		serverParams:  map[types.Object]bool{},
+		captures:      map[*ast.Ident]*Capture{},
+	}
+}
+
+// Track finds the context variables (parameters, locals, and so on) declared
+// in the given files, which should be all the files of the package, and
+// starts tracking them.  Call MarkUses afterwards to record their uses.
+func (tracker *Tracker) Track(files []*ast.File) {
+	if tracker.options.ServerInterfaces {
+		tracker.serverParams = _serverInterfaceParams(files, tracker.typesInfo)
+	}
+
+	// First, find the identifiers we want to look at.
+	for _, file := range files {
+		tracker.trackIdents(file, false)
+	}
+
+	// For interface-methods, share the trackedIdents-maps so we can tret a
+	// use of a particular context in one implementation of the interface as a
+	// use for all the implementations.  (See callee for details.)
+	if !tracker.options.SeparateImplementations {
+		tracker.identifyInterfaceMethods(files)
+	}
+
+	// Likewise, forward contexts passed to runners to their function-literal
+	// arguments.
+	tracker.identifyRunnerCalls(files)
+
+	// And make contexts returned by derivers aliases of those passed in.
+	tracker.identifyDeriverCalls(files)
+
+	// And, if asked, share the parameters of functions assigned to
+	// function-typed declarations with those declarations.
+	if tracker.options.FunctionTypes {
+		tracker.identifyFunctionTypes(files)
+	}
+
+	// Finally, find contexts which closures store where we can't follow
+	// them.
+	tracker.identifyCaptures(files)
+}
+
+// Usage returns what we know about the uses of the given object, or nil if
+// it's not tracked.
+func (tracker *Tracker) Usage(obj types.Object) *Usage {
+	return tracker.trackedIdents[obj]
+}
+
+// Objects returns the tracked objects, in order of position.
+func (tracker *Tracker) Objects() []types.Object {
+	objs := make([]types.Object, 0, len(tracker.trackedIdents))
+	for obj := range tracker.trackedIdents {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+	return objs
+}
+
+// IsAlias returns true if the given object is the parameter of a
+// function-literal passed to a runner, which shares the Usage of the context
+// passed to the runner (see identifyRunnerCalls), or of a function assigned
+// to a function-typed declaration, which shares the Usage of the
+// declaration's parameter (see identifyFunctionTypes).  Callers reporting
+// problems should report them on the latter.
+func (tracker *Tracker) IsAlias(obj types.Object) bool {
+	return tracker.aliases[obj]
+}
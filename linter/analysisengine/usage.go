@@ -0,0 +1,297 @@
+package analysisengine
+
+// This file defines Usage, which records how a single context variable is
+// used, and decides which of its interfaces are unused or unrequested.
+
+import (
+	"go/token"
+	"go/types"
+)
+
+// Usage represents what we know about how a particular variable is used.
+type Usage struct {
+	// obj is the object representing the variable (most importantly,
+	// obj.Type() is its type)
+	obj types.Object
+	// interfaceUses contains the places where the variable is used as an
+	// interface value, most commonly by passing it to a function expecting
+	// some typed context-interface.  (Specifically it contains the interface types
+	// as which the variable is used, and the position of the first such use.)
+	interfaceUses map[types.Type]token.Pos
+	// methodUses is the places where the variable is used by calling a method
+	// with the variable as a receiver.  (Specifically it contains the method
+	// names, and the position of the first such call.)
+	methodUses map[string]token.Pos
+	// isCached is set if this variable is the argument to a cached function;
+	// see _markCachedFunctionUsed.
+	isCached bool
+	// derivedFrom is set if this variable holds a context returned by a
+	// method of another tracked context, like `spanCtx := ctx.WithSpan()`;
+	// see _markReceiverResultsDerived.
+	derivedFrom *Usage
+	// sameUnit is the tracker's Options.SameUnit.
+	sameUnit SameUnitFunc
+	// usedElsewhere are the leaf interfaces, by TypeKey, which the variable
+	// uses in code the tracker can't see; see UseElsewhere.
+	usedElsewhere map[string]bool
+}
+
+// Object returns the variable whose uses this records.
+func (info *Usage) Object() types.Object {
+	return info.obj
+}
+
+// InterfaceUses returns the interfaces as which the variable is used, most
+// commonly by passing it to a function expecting them, and the position of
+// the first such use of each.
+func (info *Usage) InterfaceUses() map[types.Type]token.Pos {
+	uses := make(map[types.Type]token.Pos, len(info.interfaceUses))
+	for typ, pos := range info.interfaceUses {
+		uses[typ] = pos
+	}
+	return uses
+}
+
+// MethodUses returns the names of the methods called on the variable, and
+// the position of the first call of each.
+func (info *Usage) MethodUses() map[string]token.Pos {
+	uses := make(map[string]token.Pos, len(info.methodUses))
+	for name, pos := range info.methodUses {
+		uses[name] = pos
+	}
+	return uses
+}
+
+// DerivedFrom returns the usage of the context from which this one was
+// derived, like ctx in `spanCtx := ctx.WithSpan()`, or nil if there is none.
+// A derived context's type was chosen by the method that returned it, not
+// the code using it, so callers generally shouldn't report on it.
+func (info *Usage) DerivedFrom() *Usage {
+	return info.derivedFrom
+}
+
+// useInterface records that the variable is used, at pos, as the given
+// interface type.
+func (info *Usage) useInterface(typ types.Type, pos token.Pos) {
+	if _, ok := info.interfaceUses[typ]; !ok {
+		info.interfaceUses[typ] = pos
+	}
+}
+
+// useMethod records that the given method is called, at pos, with the
+// variable as its receiver.
+func (info *Usage) useMethod(name string, pos token.Pos) {
+	if _, ok := info.methodUses[name]; !ok {
+		info.methodUses[name] = pos
+	}
+}
+
+// UseElsewhere records that the variable uses the given leaf interface (see
+// LeafInterfaces) in code the tracker can't see, like code built only under
+// other build tags.  It counts as used, but since we don't know how, it's
+// not checked for being requested explicitly.
+func (info *Usage) UseElsewhere(typ types.Type) {
+	if info.usedElsewhere == nil {
+		info.usedElsewhere = map[string]bool{}
+	}
+	info.usedElsewhere[TypeKey(typ)] = true
+}
+
+// InterfaceWasUsed returns true if the given interface -- a leaf-interface of
+// the variable's type (see LeafInterfaces) -- was in fact used.
+//
+// The main cases are if we passed it to a function requiring that interface,
+// or if that interface defines a method we called, but there are some others,
+// discussed inline.
+func (info *Usage) InterfaceWasUsed(typ types.Type) bool {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok { // should never happen, assume it's used
+		return true
+	}
+
+	// We used it in code we can't see (see UseElsewhere).
+	if info.usedElsewhere[TypeKey(typ)] {
+		return true
+	}
+
+	// We used the variable as this interface (or some interface which
+	// contains, i.e. implements, this one)
+	for used := range info.interfaceUses {
+		if types.Implements(used, iface) {
+			return true
+		}
+	}
+
+	// We called a method defined explicitly in this interface on the variable.
+	for methodName := range info.methodUses {
+		if _hasExplicitMethod(iface, methodName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// _interfaceWasRequested returns true if the given interface was
+// explicitly-requested in the type of the variable.
+//
+// Mainly, this means that it was one of the explicitly-requested interfaces of
+// the type of the variable.  But again, there are some other cases, discussed
+// inline.
+func (info *Usage) _interfaceWasRequested(typ types.Type) bool {
+	// If we used the given interface via a cast (see _markCastUsed), the type
+	// of the variable may not even implement it!  We shouldn't have to request
+	// it; that's the whole point of a cast.
+	iface, ok := typ.Underlying().(*types.Interface)
+	if ok && !types.Implements(info.obj.Type(), iface) {
+		return true
+	}
+
+	// Every context-type provides context.Context, and its methods (Done,
+	// Err, and so on), whichever embed they happen to come from; requesting
+	// it explicitly would be redundant (see embed_lint.go).
+	if IsContextRoot(typ) {
+		return true
+	}
+
+	// If the interface is an inline interface, but has an explicit method,
+	// things get very confusing and we just give up on this check.
+	inlineIface, ok := typ.(*types.Interface)
+	if ok && inlineIface.NumExplicitMethods() > 0 {
+		return true
+	}
+
+	// This is the main check: if we used the given type, then we have to have
+	// requested it explicitly.
+	for _, embed := range ExplicitInterfaces(info.obj.Type(), info.obj.Pkg(), info.sameUnit) {
+		if SameType(typ, embed) {
+			return true
+		}
+	}
+
+	// Alternately, it's okay if we requested all the constituent interfaces of
+	// the given type (e.g. our caller asked for `type C interface { A; B }`
+	// and we asked for `A; B`).
+	if named, ok := types.Unalias(typ).(*types.Named); ok {
+		// Note we calculate said "constitutent interfaces" with respect to the
+		// *caller*'s package; otherwise we'd likely just get C itself.
+		var typMentions []types.Type
+		for _, mention := range ExplicitInterfaces(typ, named.Obj().Pkg(), info.sameUnit) {
+			// We don't count the type itself, which we skip to avoid
+			// infinite recursion, nor context.Context, which every context
+			// provides anyway (see above).
+			if !SameType(mention, typ) && !IsContextRoot(mention) {
+				typMentions = append(typMentions, mention)
+			}
+		}
+		// It only counts if "all" was at least one!
+		if len(typMentions) > 0 {
+			for _, mention := range typMentions {
+				if !info._interfaceWasRequested(mention) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// _methodWasRequested returns true if interface that provides the given method
+// was explicitly-requested in the type of the variable.
+//
+// The nontrivial part here is finding which interface that is!
+func (info *Usage) _methodWasRequested(methodName string) bool {
+	embeds := EmbedsExplicitlyContaining(info.obj.Type(), methodName)
+	for _, embed := range embeds {
+		if info._interfaceWasRequested(embed) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnrequestedUse is a use of an interface which wasn't explicitly requested.
+type UnrequestedUse struct {
+	// Type is the interface used, as returned by ExplicitInterfaces or
+	// EmbedsExplicitlyContaining.
+	Type types.Type
+	// Pos is the position of the (first) use.
+	Pos token.Pos
+	// Method is the name of the method called, if the use was a method call
+	// rather than a use as an interface value.
+	Method string
+}
+
+// Problems computes whether there are any problems with this variable's
+// context-interfaces.  Specifically:
+//   - allUnused is true if the variable appears totally unused
+//   - unused contains any context-interfaces the variable requested in its
+//     type, but did not use
+//   - unrequested contains any context-interfaces the variable used, but did not
+//     explicitly request in its type (perhaps it requested them indirectly),
+//     along with where it used them
+func (info *Usage) Problems() (allUnused bool, unused []types.Type, unrequested []UnrequestedUse) {
+	typ := info.obj.Type()
+
+	allLeaves := LeafInterfaces(typ)
+	for _, embed := range allLeaves {
+		if !info.InterfaceWasUsed(embed) {
+			unused = append(unused, embed)
+		}
+	}
+
+	for usedInterface, pos := range info.interfaceUses {
+		for _, usedEmbed := range ExplicitInterfaces(usedInterface, info.obj.Pkg(), info.sameUnit) {
+			if !info._interfaceWasRequested(usedEmbed) {
+				unrequested = append(unrequested, UnrequestedUse{Type: usedEmbed, Pos: pos})
+			}
+		}
+	}
+
+	for usedMethod, pos := range info.methodUses {
+		if !info._methodWasRequested(usedMethod) {
+			// If there are multiple distinct types explicitly containing this
+			// method, and none are requested, we'll just mention all of them.
+			for _, embed := range EmbedsExplicitlyContaining(typ, usedMethod) {
+				unrequested = append(unrequested, UnrequestedUse{Type: embed, Pos: pos, Method: usedMethod})
+			}
+		}
+	}
+
+	// If it was used as context.Context (or another root), as by passing it
+	// to slog.InfoContext, it's not totally unused, even if it used none of
+	// its leaves.
+	allUnused = len(unused) == len(allLeaves)
+	for usedInterface := range info.interfaceUses {
+		if IsContextRoot(usedInterface) {
+			allUnused = false
+		}
+	}
+	return allUnused, unused, unrequested
+}
+
+// Requirements returns the typed context interfaces the variable needs:
+// those it's used as (as seen from its package, see ExplicitInterfaces),
+// and those explicitly defining the methods it calls.  Unlike Problems, this
+// doesn't depend on what the variable's type requests, so it can tell you
+// what to request in the first place.
+func (info *Usage) Requirements() []types.Type {
+	typ := info.obj.Type()
+	var needs []types.Type
+	for used := range info.interfaceUses {
+		for _, embed := range ExplicitInterfaces(used, info.obj.Pkg(), info.sameUnit) {
+			// Interfaces we reach via a cast (see _markCastUsed) don't
+			// need to be requested.
+			iface, ok := embed.Underlying().(*types.Interface)
+			if ok && types.Implements(typ, iface) {
+				needs = append(needs, embed)
+			}
+		}
+	}
+	for method := range info.methodUses {
+		needs = append(needs, EmbedsExplicitlyContaining(typ, method)...)
+	}
+	return needs
+}
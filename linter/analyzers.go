@@ -0,0 +1,51 @@
+package linter
+
+import "golang.org/x/tools/go/analysis"
+
+// Analyzers are all the analyzers defined in this package, in the order they
+// should be listed by drivers.  Some of them are opt-in; those do nothing
+// unless their -enable flag is set.  None of them report in generated files,
+// unless configured to; see generated.go.  All of them apply the severities
+// configured for each code; see severity.go.
+var Analyzers = []*analysis.Analyzer{
+	TypedContextInterfaceAnalyzer,
+	TypedContextCohesionAnalyzer,
+	TypedContextDetachAnalyzer,
+	TypedContextAccessorAnalyzer,
+	TypedContextRedundantAnalyzer,
+	TypedContextExposedAnalyzer,
+	TypedContextDynamicAnalyzer,
+	TypedContextEmbedAnalyzer,
+	TypedContextShadowAnalyzer,
+	TypedContextLayoutAnalyzer,
+	TypedContextValueAnalyzer,
+	TypedContextSizeAnalyzer,
+	TypedContextBackgroundAnalyzer,
+	TypedContextReturnAnalyzer,
+	TypedContextAnyAnalyzer,
+	TypedContextWrapperAnalyzer,
+	TypedContextShapesAnalyzer,
+	TypedContextGetterAnalyzer,
+	TypedContextFixtureAnalyzer,
+	TypedContextPointerAnalyzer,
+	TypedContextFieldAnalyzer,
+	TypedContextRegistryAnalyzer,
+	TypedContextCapabilityAnalyzer,
+	TypedContextCancelAnalyzer,
+	TypedContextImplAnalyzer,
+	TypedContextImplementationsAnalyzer,
+	TypedContextExtractAnalyzer,
+	TypedContextRewrapAnalyzer,
+	TypedContextLeakAnalyzer,
+	TypedContextMocksAnalyzer,
+	TypedContextOrderAnalyzer,
+	TypedContextRoundtripAnalyzer,
+	TypedContextRepeatedAnalyzer,
+}
+
+func init() {
+	for _, analyzer := range Analyzers {
+		_filterGenerated(analyzer)
+		_applySeverity(analyzer)
+	}
+}
@@ -0,0 +1,100 @@
+package linter
+
+// This file defines the linter that typed contexts aren't passed to
+// functions as untyped values, like
+//	registry.Register(ctx)
+// where Register takes an `any`.  Whatever Register (or whoever gets the
+// value back out of the registry) does with ctx, it has to type-assert it
+// first, so the capabilities it depends on appear in no signature, and the
+// interface linter can't see them.
+//
+// We report typed contexts (not plain context.Contexts) passed as arguments
+// whose parameter type is `any` (or interface{}), except to functions in the
+// packages listed in -typedcontextany.allowpkgs: by default fmt, log and
+// errors, which just format their arguments.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextAnyAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextany",
+	Doc:  "reports typed contexts passed to functions as any",
+	Run:  _runAny,
+}
+
+// _anyAllowedPackages lists package-path prefixes whose functions may take
+// typed contexts as any.
+var _anyAllowedPackages = stringList{"fmt", "log", "errors"}
+
+func init() {
+	TypedContextAnyAnalyzer.Flags.Var(&_anyAllowedPackages, "allowpkgs",
+		"comma-separated list of package-path prefixes whose functions may "+
+			"take typed contexts as any (e.g. for formatting)")
+}
+
+// _isEmptyInterface returns true if typ is any, or an equivalent interface
+// (but not a type parameter constrained by any).
+func _isEmptyInterface(typ types.Type) bool {
+	if _, ok := typ.(*types.TypeParam); ok {
+		return false
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
+
+// _checkAnyArgs reports the typed contexts passed as any in the given call.
+func _checkAnyArgs(pass *analysis.Pass, call *ast.CallExpr) {
+	sig, ok := pass.TypesInfo.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		return // a conversion
+	}
+	callee := lintutil.ObjectFor(call.Fun, pass.TypesInfo)
+	if callee != nil && callee.Pkg() != nil &&
+		hasAnyPathPrefix(callee.Pkg().Path(), _anyAllowedPackages) {
+		return
+	}
+	for i, arg := range call.Args {
+		paramType := analysisengine.ParamTypeAt(call, sig, i)
+		if paramType == nil || !_isEmptyInterface(paramType) {
+			continue
+		}
+		argType := pass.TypesInfo.TypeOf(arg)
+		if argType == nil || !isContextType(argType) || isContextRoot(argType) {
+			continue
+		}
+		calleeName := "a function"
+		if callee != nil {
+			calleeName = callee.Name()
+		}
+		reportf(pass, arg, CodeContextAsAny,
+			"typed context passed to %s as any, which hides the capabilities "+
+				"it depends on; have it request a typed context interface instead",
+			calleeName)
+	}
+}
+
+// _runAny lints that typed contexts aren't passed as any.
+func _runAny(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				_checkAnyArgs(pass, call)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,271 @@
+package linter
+
+// This file defines the linter that functions which have a context pass it
+// along, rather than starting afresh with context.Background() or
+// context.TODO(), like
+//	func F(ctx AppContext) {
+//		G(context.Background())
+//	}
+// G loses ctx's deadline, cancellation and values; and if G is ever changed
+// to want some typed context interface, F will likely build a new one from
+// scratch, rather than passing along the request's.  The interface linter
+// can't see this: ctx is simply unused there.
+//
+// We report calls in any function (or function literal) which has a
+// context-typed parameter available, either its own or one of an enclosing
+// function.  We don't report calls in main or init functions, in _test.go
+// files, or in functions in -typedcontextbackground.allow (or the
+// allowbackground key of a configuration file); the latter is for functions
+// which really do mean to start something independent of their caller.  (For
+// work which must outlive a request, typedcontext.Detach is usually better.)
+//
+// A related mistake comes with server interfaces (see example 07), which
+// carry the providers but not the context.Context:
+//	G(context.Background(), ctx)
+// where G takes a context.Context and a LoggerServer, narrows ctx to its
+// providers, and passes a context with no deadline or cancellation alongside.
+// We report any call passing a fresh context alongside a typed context, or a
+// conversion of one, as a server interface, wherever the typed context came
+// from (a parameter or not), except in test files and the functions in
+// -typedcontextbackground.allow.  That's reported instead of the above.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextBackgroundAnalyzer = &analysis.Analyzer{
+	Name:     "typedcontextbackground",
+	Doc:      "reports calls to context.Background or context.TODO in functions which already have a context",
+	Run:      _runBackground,
+	Requires: []*analysis.Analyzer{lintutil.EnclosingFuncsAnalyzer},
+}
+
+// _backgroundAllowed lists functions, as returned by lintutil.NameOf, which
+// may call context.Background() even though they have a context.
+var _backgroundAllowed stringList
+
+func init() {
+	TypedContextBackgroundAnalyzer.Flags.Var(&_backgroundAllowed, "allow",
+		"comma-separated list of functions, like example.com/jobs.Start or "+
+			"(*example.com/jobs.Runner).Start, which may call "+
+			"context.Background or context.TODO even though they have a context")
+}
+
+// _isFreshContextCall returns the name of the function, if the given call is
+// a call to context.Background or context.TODO.
+func _isFreshContextCall(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	switch lintutil.NameOf(lintutil.ObjectFor(call.Fun, pass.TypesInfo)) {
+	case "context.Background":
+		return "context.Background", true
+	case "context.TODO":
+		return "context.TODO", true
+	}
+	return "", false
+}
+
+// _contextParam returns the first context-typed parameter of the given
+// function type, if any.
+func _contextParam(pass *analysis.Pass, funcType *ast.FuncType) *types.Var {
+	for _, field := range funcType.Params.List {
+		typ := pass.TypesInfo.TypeOf(field.Type)
+		if typ == nil || !isContextType(typ) {
+			continue
+		}
+		for _, name := range field.Names {
+			if param, ok := pass.TypesInfo.Defs[name].(*types.Var); ok {
+				return param
+			}
+		}
+		// An unnamed parameter; there's still a context, we just can't
+		// suggest using it.
+		return types.NewParam(field.Pos(), pass.Pkg, "", typ)
+	}
+	return nil
+}
+
+// _narrowedContext returns the first argument of the given call which is a
+// typed context (possibly converted) passed as a server interface, if any,
+// and the parameter's type.
+func _narrowedContext(pass *analysis.Pass, call *ast.CallExpr) (ast.Expr, types.Type) {
+	funcType, ok := pass.TypesInfo.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		return nil, nil // a conversion, or a builtin
+	}
+	for i, arg := range call.Args {
+		paramType := analysisengine.ParamTypeAt(call, funcType, i)
+		if paramType == nil || !analysisengine.IsServerInterface(paramType) {
+			continue
+		}
+		expr := ast.Unparen(arg)
+		// Look through a conversion, like LoggerServer(ctx).
+		if conversion, ok := expr.(*ast.CallExpr); ok && len(conversion.Args) == 1 {
+			if tv, ok := pass.TypesInfo.Types[conversion.Fun]; ok && tv.IsType() {
+				expr = ast.Unparen(conversion.Args[0])
+			}
+		}
+		typ := pass.TypesInfo.TypeOf(expr)
+		if typ != nil && isContextType(typ) {
+			return expr, paramType
+		}
+	}
+	return nil, nil
+}
+
+// _severedFix returns a fix replacing the given call with the typed context
+// passed alongside it, if that's a plain identifier.
+func _severedFix(call *ast.CallExpr, narrowed ast.Expr) []analysis.SuggestedFix {
+	ident, ok := narrowed.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	return []analysis.SuggestedFix{{
+		Message: "Pass " + ident.Name + " instead",
+		TextEdits: []analysis.TextEdit{{
+			Pos: call.Pos(), End: call.End(), NewText: []byte(ident.Name),
+		}},
+	}}
+}
+
+// _backgroundFix returns a fix replacing the given call with a reference to
+// param, if param can be referred to by name there.
+func _backgroundFix(pass *analysis.Pass, call *ast.CallExpr, param *types.Var) []analysis.SuggestedFix {
+	if param.Name() == "" || param.Name() == "_" {
+		return nil
+	}
+	scope := pass.Pkg.Scope().Innermost(call.Pos())
+	if scope == nil {
+		return nil
+	}
+	if _, obj := scope.LookupParent(param.Name(), call.Pos()); obj != param {
+		return nil // shadowed
+	}
+	return []analysis.SuggestedFix{{
+		Message: "Pass " + param.Name() + " instead",
+		TextEdits: []analysis.TextEdit{{
+			Pos: call.Pos(), End: call.End(), NewText: []byte(param.Name()),
+		}},
+	}}
+}
+
+// _backgroundAllowedAt returns true if the position is in a function (or a
+// function literal in a function) in -typedcontextbackground.allow.
+func _backgroundAllowedAt(pass *analysis.Pass, funcs *lintutil.FuncIndex, settings *settings, pos token.Pos) bool {
+	enclosing := funcs.Enclosing(pos)
+	if len(enclosing) == 0 {
+		return false
+	}
+	funcDecl, ok := enclosing[len(enclosing)-1].(*ast.FuncDecl)
+	if !ok {
+		return false
+	}
+	funcName := lintutil.NameOf(pass.TypesInfo.Defs[funcDecl.Name])
+	return slices.Contains(settings.backgroundAllowed, funcName)
+}
+
+// _runBackground lints that functions with a context don't make a new one.
+func _runBackground(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	funcs := pass.ResultOf[lintutil.EnclosingFuncsAnalyzer].(*lintutil.FuncIndex)
+	for _, file := range pass.Files {
+		filename := pass.Fset.File(file.Pos()).Name()
+		if strings.HasSuffix(filename, "_test.go") || _skipFile(filename, pass.Pkg) {
+			continue
+		}
+		// severed are the fresh-context calls we've reported as passed
+		// alongside a narrowed typed context.
+		severed := map[*ast.CallExpr]bool{}
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || severed[call] {
+				return true
+			}
+			name, ok := _isFreshContextCall(pass, call)
+			if !ok {
+				narrowed, paramType := _narrowedContext(pass, call)
+				if narrowed == nil {
+					return true
+				}
+				for _, arg := range call.Args {
+					fresh, ok := ast.Unparen(arg).(*ast.CallExpr)
+					if !ok {
+						continue
+					}
+					name, ok := _isFreshContextCall(pass, fresh)
+					if !ok || _backgroundAllowedAt(pass, funcs, settings, fresh.Pos()) {
+						continue
+					}
+					severed[fresh] = true
+					pass.Report(analysis.Diagnostic{
+						Pos:      fresh.Pos(),
+						End:      fresh.End(),
+						Category: string(CodeSeveredContext),
+						Message: name + "() passed alongside " + types.ExprString(narrowed) +
+							", narrowed to " + _shortTypeName(paramType, pass.Pkg) +
+							"; pass " + types.ExprString(narrowed) + " as the context " +
+							"too, so its deadline and cancellation aren't lost",
+						SuggestedFixes: _severedFix(fresh, narrowed),
+					})
+				}
+				return true
+			}
+			enclosing := funcs.Enclosing(call.Pos())
+			if len(enclosing) == 0 {
+				return true // in a package-level initializer
+			}
+			funcDecl, ok := enclosing[len(enclosing)-1].(*ast.FuncDecl)
+			if !ok {
+				return true // in a function literal in an initializer
+			}
+			if funcDecl.Recv == nil && (funcDecl.Name.Name == "init" ||
+				funcDecl.Name.Name == "main" && pass.Pkg.Name() == "main") {
+				return true
+			}
+			if _backgroundAllowedAt(pass, funcs, settings, call.Pos()) {
+				return true
+			}
+
+			var param *types.Var
+			for _, fn := range enclosing {
+				switch fn := fn.(type) {
+				case *ast.FuncDecl:
+					param = _contextParam(pass, fn.Type)
+				case *ast.FuncLit:
+					param = _contextParam(pass, fn.Type)
+				}
+				if param != nil {
+					break
+				}
+			}
+			if param == nil {
+				return true
+			}
+			description := "a context parameter"
+			if param.Name() != "" && param.Name() != "_" {
+				description = param.Name()
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      call.Pos(),
+				End:      call.End(),
+				Category: string(CodeFreshContext),
+				Message: name + "() called where " + description +
+					" is available; pass it along instead, so its " +
+					"deadline, values and typed interfaces aren't lost",
+				SuggestedFixes: _backgroundFix(pass, call, param),
+			})
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,284 @@
+package linter
+
+// This file defines the opt-in linter that loops which block check for
+// cancellation.  In
+//	func sync(ctx DatabaseContext, ids []int) error {
+//		for _, id := range ids {
+//			if err := ctx.Database().Put(id); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	}
+// the request may have been cancelled (the client went away, or a deadline
+// passed) long before the loop is done, but nothing notices: every iteration
+// still does its round trip.  The loop should check ctx.Err() (or select on
+// ctx.Done()), or pass ctx to the call, which can then give up itself.
+//
+// So, in each function which has a context (as a parameter of it or of a
+// function enclosing it), we look at each loop whose body blocks: it calls a
+// function in one of the I/O packages (as found by lintutil.Effects, with
+// the packages of -typedcontextgetter.iopkgs), or a method of a provider
+// got from one of the context accessors listed in
+// -typedcontextcancel.providers, like ctx.Database().Put(id), or db.Put(id)
+// after db := ctx.Database().  We report it unless the loop checks for
+// cancellation: it calls Err or Done on a context, or passes a context to
+// some call (which we trust to check).  Each loop is judged by its own body,
+// except that blocking calls in a nested loop are the nested loop's
+// business; calls in function literals (like goroutines the loop starts)
+// don't block the loop at all.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextCancelAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextcancel",
+	Doc:  "reports loops which block without checking their context for cancellation",
+	Run:  _runCancel,
+}
+
+// _providerAccessors lists the context accessors, by method name, whose
+// providers block.
+var _providerAccessors = stringList{"Database", "HTTPClient"}
+
+func init() {
+	TypedContextCancelAnalyzer.Flags.Var(&_providerAccessors, "providers",
+		"comma-separated list of context accessors, by method name, whose "+
+			"providers' methods block, like Database for ctx.Database().Get(key)")
+	optIn(TypedContextCancelAnalyzer)
+}
+
+// _cancelChecker finds the loops of a single function which block without
+// checking for cancellation.
+type _cancelChecker struct {
+	pass *analysis.Pass
+	// providers are the variables assigned a provider, like db in
+	// db := ctx.Database().
+	providers map[types.Object]bool
+}
+
+// isContext returns true if expr is a context.
+func (checker *_cancelChecker) isContext(expr ast.Expr) bool {
+	typ := checker.pass.TypesInfo.TypeOf(expr)
+	return typ != nil && isContextType(typ)
+}
+
+// isProvider returns true if expr is a provider which blocks: a call of one
+// of the accessors on a context, or a variable assigned one.
+func (checker *_cancelChecker) isProvider(expr ast.Expr) bool {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return checker.providers[checker.pass.TypesInfo.ObjectOf(expr)]
+	case *ast.CallExpr:
+		selector, ok := ast.Unparen(expr.Fun).(*ast.SelectorExpr)
+		if !ok || len(expr.Args) != 0 || !checker.isContext(selector.X) {
+			return false
+		}
+		for _, accessor := range _providerAccessors {
+			if selector.Sel.Name == accessor {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findProviders records the variables assigned a provider in the given
+// function body.
+func (checker *_cancelChecker) findProviders(body *ast.BlockStmt) {
+	record := func(lhs []ast.Expr, rhs []ast.Expr) {
+		if len(lhs) != len(rhs) {
+			return
+		}
+		for i, value := range rhs {
+			if ident, ok := lhs[i].(*ast.Ident); ok && checker.isProvider(value) {
+				if obj := checker.pass.TypesInfo.ObjectOf(ident); obj != nil {
+					checker.providers[obj] = true
+				}
+			}
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			record(node.Lhs, node.Rhs)
+		case *ast.ValueSpec:
+			var lhs []ast.Expr
+			for _, name := range node.Names {
+				lhs = append(lhs, name)
+			}
+			record(lhs, node.Values)
+		}
+		return true
+	})
+}
+
+// _inLoop returns true if pos is in one of the given loops.
+func _inLoop(pos token.Pos, loops []ast.Stmt) bool {
+	for _, loop := range loops {
+		if loop.Pos() <= pos && pos < loop.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// _loopParts returns the body of the given loop, and its condition and
+// post statement, if any, which are run each iteration.
+func _loopParts(loop ast.Stmt) (body *ast.BlockStmt, others []ast.Node) {
+	switch loop := loop.(type) {
+	case *ast.ForStmt:
+		if loop.Cond != nil {
+			others = append(others, loop.Cond)
+		}
+		if loop.Post != nil {
+			others = append(others, loop.Post)
+		}
+		return loop.Body, others
+	case *ast.RangeStmt:
+		return loop.Body, nil
+	}
+	return nil, nil
+}
+
+// blockingCall returns a description of the first call in the given loop
+// which blocks, other than in loops nested in it, or "" if there is none.
+func (checker *_cancelChecker) blockingCall(loop ast.Stmt) string {
+	body, _ := _loopParts(loop)
+	var nested []ast.Stmt
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt, *ast.RangeStmt:
+			nested = append(nested, node.(ast.Stmt))
+			return false
+		}
+		return true
+	})
+
+	var description string
+	var pos token.Pos
+	for _, effect := range lintutil.Effects(body, checker.pass.TypesInfo, _ioPackages) {
+		if effect.Kind == lintutil.EffectIO && !_inLoop(effect.Node.Pos(), nested) {
+			description, pos = effect.Description, effect.Node.Pos()
+			break
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt, *ast.RangeStmt:
+			return false
+		case *ast.CallExpr:
+			selector, ok := ast.Unparen(node.Fun).(*ast.SelectorExpr)
+			if ok && checker.isProvider(selector.X) && (description == "" || node.Pos() < pos) {
+				description, pos = "calls "+types.ExprString(node.Fun), node.Pos()
+			}
+		}
+		return true
+	})
+	return description
+}
+
+// checksCancellation returns true if the given loop checks for
+// cancellation: it calls Err or Done on a context, or passes a context to
+// some call.
+func (checker *_cancelChecker) checksCancellation(loop ast.Stmt) bool {
+	body, others := _loopParts(loop)
+	checks := false
+	for _, part := range append(others, body) {
+		ast.Inspect(part, func(node ast.Node) bool {
+			if checks {
+				return false
+			}
+			switch node := node.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.CallExpr:
+				if selector, ok := ast.Unparen(node.Fun).(*ast.SelectorExpr); ok &&
+					(selector.Sel.Name == "Err" || selector.Sel.Name == "Done") &&
+					checker.isContext(selector.X) {
+					checks = true
+				}
+				for _, arg := range node.Args {
+					if checker.isContext(arg) {
+						checks = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return checks
+}
+
+// _contextName returns the name of the first context parameter of the given
+// function type (see _contextParam), or "" if it has none, or it's unnamed,
+// so that it can't be checked.
+func _contextName(pass *analysis.Pass, funcType *ast.FuncType) string {
+	param := _contextParam(pass, funcType)
+	if param == nil || param.Name() == "_" {
+		return ""
+	}
+	return param.Name()
+}
+
+// check reports the loops in the given function body which block without
+// checking for cancellation.  ctxName is the name of the context the body
+// has, from its function or an enclosing one, or "" if it has none.
+func (checker *_cancelChecker) check(body *ast.BlockStmt, ctxName string) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			name := _contextName(checker.pass, node.Type)
+			if name == "" {
+				name = ctxName
+			}
+			checker.check(node.Body, name)
+			return false
+		case *ast.ForStmt, *ast.RangeStmt:
+			loop := node.(ast.Stmt)
+			if ctxName == "" {
+				return true
+			}
+			if description := checker.blockingCall(loop); description != "" && !checker.checksCancellation(loop) {
+				reportf(checker.pass, loop, CodeUncancellableLoop,
+					"loop %s, but never checks whether %s is cancelled; check "+
+						"%s.Err() (or select on %s.Done()) in the loop, or pass it "+
+						"to the call", description, ctxName, ctxName, ctxName)
+			}
+		}
+		return true
+	})
+}
+
+// _runCancel lints that loops which block check for cancellation.
+func _runCancel(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+			checker := &_cancelChecker{pass: pass, providers: map[types.Object]bool{}}
+			checker.findProviders(funcDecl.Body)
+			checker.check(funcDecl.Body, _contextName(pass, funcDecl.Type))
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,212 @@
+package linter
+
+// This file computes which typed context interfaces each exported function
+// of a program requires of its callers, and how that changes between two
+// versions of the program, for review: "this change makes GetUser require
+// SecretsContext" is easy to miss in a diff of a composite interface three
+// files away.  (That's cmd/typedcontext-diff.)
+//
+// A function requires the leaf interfaces (see
+// analysisengine.LeafInterfaces) of the types of its typed context
+// parameters, other than context roots: whatever it uses, its callers must
+// provide those.  As for the other whole-program checks, the analyzer here
+// exports a fact for each package, listing its exported functions'
+// requirements, and Capabilities aggregates the facts.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// TypedContextCapabilitiesAnalyzer computes the facts used by Capabilities.
+// It reports nothing itself, so it isn't in Analyzers.
+var TypedContextCapabilitiesAnalyzer = &analysis.Analyzer{
+	Name:      "typedcontextcapabilities",
+	Doc:       "records which typed context interfaces each exported function requires",
+	Run:       _runCapabilities,
+	FactTypes: []analysis.Fact{new(_capabilitiesFact)},
+}
+
+// _capabilitiesFact is the package fact exported by
+// TypedContextCapabilitiesAnalyzer.
+type _capabilitiesFact struct {
+	// Functions maps the exported functions and methods of the package
+	// which take typed contexts, named as for FunctionCapabilities, to
+	// their requirements.
+	Functions map[string]FunctionCapabilities
+}
+
+func (*_capabilitiesFact) AFact() {}
+
+func (fact *_capabilitiesFact) String() string {
+	return fmt.Sprintf("%d exported functions take typed contexts", len(fact.Functions))
+}
+
+// FunctionCapabilities describes what an exported function requires of its
+// callers' contexts.
+type FunctionCapabilities struct {
+	// Function is the function's name, as "import/path.Func" or
+	// "import/path.Type.Method".
+	Function string `json:"function"`
+	// Position is the position of its declaration, as "file:line:col".
+	Position string `json:"position"`
+	// Requires are the leaf interfaces its typed context parameters
+	// request, other than context roots, as "import/path.Name", sorted.
+	Requires []string `json:"requires"`
+}
+
+func _runCapabilities(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	fact := &_capabilitiesFact{Functions: map[string]FunctionCapabilities{}}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || !_isExportedFunc(funcDecl) {
+				continue
+			}
+			name := pass.Pkg.Path() + "." + funcDecl.Name.Name
+			if recv := _receiverTypeName(funcDecl); recv != "" {
+				name = pass.Pkg.Path() + "." + recv + "." + funcDecl.Name.Name
+			}
+
+			requires := map[string]bool{}
+			isContext := false
+			for _, field := range funcDecl.Type.Params.List {
+				typ := pass.TypesInfo.TypeOf(field.Type)
+				if typ == nil || !isContextType(typ) {
+					continue
+				}
+				isContext = true
+				for _, leaf := range analysisengine.LeafInterfaces(typ) {
+					if !isContextRoot(leaf) {
+						requires[types.TypeString(leaf, nil)] = true
+					}
+				}
+			}
+			if !isContext {
+				continue
+			}
+
+			capabilities := FunctionCapabilities{
+				Function: name,
+				Position: pass.Fset.Position(funcDecl.Pos()).String(),
+				Requires: []string{},
+			}
+			for leaf := range requires {
+				capabilities.Requires = append(capabilities.Requires, leaf)
+			}
+			sort.Strings(capabilities.Requires)
+			fact.Functions[name] = capabilities
+		}
+	}
+	pass.ExportPackageFact(fact)
+	return nil, nil
+}
+
+// Capabilities returns the requirements of the exported functions and
+// methods taking typed contexts in the packages matching the given patterns,
+// as loaded from the given directory (or the current one, if dir is ""),
+// sorted by name.  Tests aren't included.
+func Capabilities(dir string, patterns ...string) ([]FunctionCapabilities, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Dir: dir}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(
+		[]*analysis.Analyzer{TypedContextCapabilitiesAnalyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result []FunctionCapabilities
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, act.Err
+		}
+		var fact _capabilitiesFact
+		if !act.PackageFact(act.Package.Types, &fact) {
+			continue
+		}
+		for _, capabilities := range fact.Functions {
+			result = append(result, capabilities)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Function < result[j].Function })
+	return result, nil
+}
+
+// CapabilityChange describes how an exported function's requirements
+// changed between two versions of a program.
+type CapabilityChange struct {
+	// Function is the function's name, as for FunctionCapabilities.
+	Function string `json:"function"`
+	// Position is the position of its declaration in the new version.
+	Position string `json:"position"`
+	// New is set if the function didn't take a typed context in the old
+	// version (or didn't exist).
+	New bool `json:"new,omitempty"`
+	// Added are the interfaces it newly requires, sorted.
+	Added []string `json:"added,omitempty"`
+	// Removed are the interfaces it no longer requires, sorted.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// DiffCapabilities returns the changes in requirements of a program's
+// exported functions between two versions, before and after, as returned by
+// Capabilities, sorted by name.  Functions which no longer take typed
+// contexts (or no longer exist) aren't included: their callers need provide
+// nothing.
+func DiffCapabilities(before, after []FunctionCapabilities) []CapabilityChange {
+	oldRequires := map[string]map[string]bool{}
+	for _, capabilities := range before {
+		oldRequires[capabilities.Function] = map[string]bool{}
+		for _, leaf := range capabilities.Requires {
+			oldRequires[capabilities.Function][leaf] = true
+		}
+	}
+
+	var changes []CapabilityChange
+	for _, capabilities := range after {
+		previous, ok := oldRequires[capabilities.Function]
+		change := CapabilityChange{
+			Function: capabilities.Function,
+			Position: capabilities.Position,
+			New:      !ok,
+		}
+		current := map[string]bool{}
+		for _, leaf := range capabilities.Requires {
+			current[leaf] = true
+			if !previous[leaf] {
+				change.Added = append(change.Added, leaf)
+			}
+		}
+		for leaf := range previous {
+			if !current[leaf] {
+				change.Removed = append(change.Removed, leaf)
+			}
+		}
+		sort.Strings(change.Removed)
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Function < changes[j].Function })
+	return changes
+}
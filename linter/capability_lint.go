@@ -0,0 +1,168 @@
+package linter
+
+// This file defines the linter that sensitive typed context interfaces are
+// only requested by code in allowlisted packages.  For example, with
+//	-typedcontextcapability.rules='example.com/ctx.SecretsContext=example.com/secure/...'
+// only packages under example.com/secure may request a SecretsContext: a
+// function elsewhere taking one, or a context embedding one (like an
+// AppContext which includes everything), is reported, even if it only ever
+// uses the secrets correctly.  That makes the typed context the enforcement
+// point of a capability boundary: code outside it can't even be handed the
+// capability without a diagnostic.
+//
+// Each rule is INTERFACE=PACKAGE, where INTERFACE is "import/path.Name", or
+// just "Name" to match in any package, and PACKAGE is a package-path
+// pattern, where ... matches any string (as for the go command).  An
+// interface matching several rules may be requested in a package matching
+// any of them.  With no rules, this reports nothing.
+//
+// Unlike most options, the rules can only be set by flag, not in a
+// .typedcontext.yaml (see config.go): otherwise any directory could
+// allowlist itself.  For the same reason, directories exempted by a
+// configuration file are still checked.  Tests are not, unless
+// -typedcontextinterface.checktests (or the like) says so: test fixtures
+// routinely provide every interface.
+//
+// Which variables are contexts comes from TypedContextUsageAnalyzer.  We
+// don't report contexts derived from another by a method (see
+// analysisengine.Usage.DerivedFrom), whose types aren't their code's choice.
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextCapabilityAnalyzer = &analysis.Analyzer{
+	Name:     "typedcontextcapability",
+	Doc:      "enforces that sensitive typed context interfaces are only requested in allowlisted packages",
+	Run:      _runCapability,
+	Requires: []*analysis.Analyzer{TypedContextUsageAnalyzer},
+}
+
+// _capabilityRules are the rules, as INTERFACE=PACKAGE; see the top of the
+// file.
+var _capabilityRules stringList
+
+func init() {
+	TypedContextCapabilityAnalyzer.Flags.Var(&_capabilityRules, "rules",
+		"comma-separated list of rules INTERFACE=PACKAGE, meaning the typed "+
+			"context interface INTERFACE (import/path.Name, or Name for any "+
+			"package) may only be requested in packages whose path matches "+
+			"PACKAGE (where ... matches anything)")
+}
+
+// _capabilityRule is a parsed rule.
+type _capabilityRule struct {
+	iface, pkg string
+	pkgRegexp  *regexp.Regexp
+}
+
+// _parseCapabilityRules parses rules of the form INTERFACE=PACKAGE.
+func _parseCapabilityRules(rules []string) ([]_capabilityRule, error) {
+	var parsed []_capabilityRule
+	for _, rule := range rules {
+		iface, pkg, ok := strings.Cut(rule, "=")
+		if !ok || iface == "" || pkg == "" {
+			return nil, fmt.Errorf("invalid capability rule %q: want INTERFACE=PACKAGE", rule)
+		}
+		parsed = append(parsed, _capabilityRule{iface, pkg, _packagePattern(pkg)})
+	}
+	return parsed, nil
+}
+
+// _restriction is a restricted interface a context includes, and the rules
+// restricting it.
+type _restriction struct {
+	iface *types.Named
+	rules []_capabilityRule
+}
+
+// allows returns true if one of the rules allows the given package to
+// request the interface.
+func (restriction *_restriction) allows(pkg *types.Package) bool {
+	for _, rule := range restriction.rules {
+		if rule.pkgRegexp.MatchString(pkg.Path()) {
+			return true
+		}
+	}
+	return false
+}
+
+// _restrictions returns the interfaces recursively embedded in typ
+// (including typ itself) which match some rule, in the order they're
+// embedded, each once.  seen holds the restrictions found so far, by
+// analysisengine.TypeKey.
+func _restrictions(typ types.Type, rules []_capabilityRule, seen map[string]bool) []*_restriction {
+	var restrictions []*_restriction
+	if named, ok := types.Unalias(typ).(*types.Named); ok && !seen[analysisengine.TypeKey(named)] {
+		restriction := &_restriction{iface: named}
+		for _, rule := range rules {
+			if matchesQualifiedName(named, rule.iface) {
+				restriction.rules = append(restriction.rules, rule)
+			}
+		}
+		if len(restriction.rules) > 0 {
+			seen[analysisengine.TypeKey(named)] = true
+			restrictions = append(restrictions, restriction)
+		}
+	}
+
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return restrictions
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		restrictions = append(restrictions, _restrictions(iface.EmbeddedType(i), rules, seen)...)
+	}
+	return restrictions
+}
+
+// _runCapability lints that restricted interfaces are only requested where
+// the rules allow.
+func _runCapability(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := _parseCapabilityRules(_capabilityRules)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	usage := pass.ResultOf[TypedContextUsageAnalyzer].(*InterfaceUsage)
+	for _, obj := range usage.Objects {
+		filename := pass.Fset.File(obj.Pos()).Name()
+		if strings.HasSuffix(filename, "_test.go") && !settings.checkTests {
+			continue
+		}
+		if usage.Usages[obj].DerivedFrom() != nil {
+			continue
+		}
+
+		for _, restriction := range _restrictions(obj.Type(), rules, map[string]bool{}) {
+			if restriction.allows(pass.Pkg) {
+				continue
+			}
+			var allowed []string
+			for _, rule := range restriction.rules {
+				allowed = append(allowed, rule.pkg)
+			}
+			reportf(pass, obj, CodeRestrictedInterface,
+				"%s requests %s, which only packages matching %s may request%s; "+
+					"narrow its context, or move this code to one of them",
+				_objName(obj), _shortTypeName(restriction.iface, pass.Pkg),
+				strings.Join(allowed, " or "),
+				_embedChainSuffix(obj.Type(), restriction.iface, pass.Pkg))
+		}
+	}
+	return nil, nil
+}
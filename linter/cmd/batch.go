@@ -0,0 +1,183 @@
+package main
+
+// This file implements the -batch mode, for linting trees too large to load
+// all at once.  multichecker type-checks every package matching its
+// patterns, with all their syntax, in a single packages.Load; on a large
+// monorepo that runs out of memory.  Instead, we first list the packages
+// (which needs no syntax or types), keep those matching -include and not
+// -exclude, and then load and analyze them in batches of -batchsize, on
+// -workers goroutines at a time.  Each batch's packages are dropped once
+// it's analyzed, so at most -workers batches are ever in memory.
+//
+// Dependencies shared between batches are type-checked once per batch, so
+// this does more work in total than a single load; smaller batches use less
+// memory but more time.  -fix and -json aren't supported in this mode.
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// batchArgs returns the remaining arguments, if the -batch flag was passed.
+func batchArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "-batch" || arg == "--batch" {
+			return append(append([]string{}, args[:i]...), args[i+1:]...), true
+		}
+	}
+	return nil, false
+}
+
+// globList is a flag.Value holding a comma-separated list of package-path
+// globs, as for packageGlob.
+type globList []*regexp.Regexp
+
+func (list *globList) String() string {
+	var globs []string
+	for _, re := range *list {
+		globs = append(globs, re.String())
+	}
+	return strings.Join(globs, ",")
+}
+
+func (list *globList) Set(value string) error {
+	*list = nil
+	for _, glob := range strings.Split(value, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			*list = append(*list, packageGlob(glob))
+		}
+	}
+	return nil
+}
+
+// matches returns true if path matches any of the globs.
+func (list globList) matches(path string) bool {
+	for _, re := range list {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageGlob compiles a package-path glob: "*" matches within one path
+// element, and "..." matches anything, as in go list's patterns (so
+// example.com/x/... matches example.com/x and everything under it).
+func packageGlob(glob string) *regexp.Regexp {
+	re := regexp.QuoteMeta(glob)
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+	re = strings.ReplaceAll(re, `\*`, `[^/]*`)
+	re = strings.ReplaceAll(re, `\?`, `[^/]`)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+	return regexp.MustCompile("^" + re + "$")
+}
+
+// basePath returns the import path of the package, without any test suffix;
+// test variants are loaded via (and batched with) the package they test.
+func basePath(pkg *packages.Package) string {
+	return strings.TrimSuffix(strings.TrimSuffix(pkg.PkgPath, ".test"), "_test")
+}
+
+// batched runs the analyzers over the packages matching the patterns in args
+// (which may also include analyzer flags and the flags above), a batch at a
+// time, prints the diagnostics, and returns the exit status under the given
+// -failon policy.
+func batched(args []string, policy string) int {
+	flags, tests := analyzerFlags("typedcontext -batch")
+	var include, exclude globList
+	flags.Var(&include, "include",
+		"comma-separated list of package-path globs to analyze (default all)")
+	flags.Var(&exclude, "exclude",
+		"comma-separated list of package-path globs not to analyze")
+	batchSize := flags.Int("batchsize", 50, "the number of packages to load at once")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "the number of batches to analyze at once")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	if *batchSize < 1 || *workers < 1 {
+		fmt.Fprintln(os.Stderr, "-batchsize and -workers must be positive")
+		return 2
+	}
+
+	// List the packages, without loading them.
+	config := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Tests: *tests}
+	roots, err := packages.Load(config, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if packages.PrintErrors(roots) > 0 {
+		return 1
+	}
+
+	var ids []string
+	var paths []string
+	idsByPath := map[string][]string{}
+	for _, pkg := range roots {
+		path := basePath(pkg)
+		if len(include) > 0 && !include.matches(path) || exclude.matches(path) {
+			continue
+		}
+		if _, ok := idsByPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		idsByPath[path] = append(idsByPath[path], pkg.ID)
+		ids = append(ids, pkg.ID)
+	}
+
+	batches := make(chan map[string]bool)
+	go func() {
+		for start := 0; start < len(paths); start += *batchSize {
+			batch := map[string]bool{}
+			for _, path := range paths[start:min(start+*batchSize, len(paths))] {
+				for _, id := range idsByPath[path] {
+					batch[id] = true
+				}
+			}
+			batches <- batch
+		}
+		close(batches)
+	}()
+
+	var mu sync.Mutex
+	results := map[string][]cachedDiagnostic{}
+	failed := false
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				analyzed, err := analyzePackages(batch, *tests)
+				mu.Lock()
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed = true
+				}
+				for id, diagnostics := range analyzed {
+					results[id] = diagnostics
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := printDiagnostics(ids, results, policy)
+	if failed {
+		return 1
+	}
+	return status
+}
@@ -0,0 +1,369 @@
+package main
+
+// This file implements the -cache mode, which caches each package's
+// diagnostics on disk, so that re-linting a large tree in which little has
+// changed only has to load and analyze the packages that did.
+//
+// Like go vet's cache, a package's entry is keyed by a hash of everything
+// that could affect its results: its source files, any configuration files
+// that apply to it, the hashes of its dependencies (so that changes to facts
+// or types upstream invalidate it), the linter's flags, and the linter
+// binary itself.  Packages with a cache hit aren't type-checked at all; the
+// rest are loaded and analyzed together, and their results stored.
+//
+// The cache lives in $TYPEDCONTEXT_CACHE, or typedcontext under the user's
+// cache directory.  -fix and -json aren't supported in this mode.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// cachedDiagnostic is a diagnostic as stored in the cache, with its
+// positions already formatted.
+type cachedDiagnostic struct {
+	Posn    string             `json:"posn"`
+	Message string             `json:"message"`
+	Related []cachedDiagnostic `json:"related,omitempty"`
+}
+
+// cacheArgs returns the remaining arguments, if the -cache flag was passed.
+func cacheArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "-cache" || arg == "--cache" {
+			return append(append([]string{}, args[:i]...), args[i+1:]...), true
+		}
+	}
+	return nil, false
+}
+
+// cacheDir returns the directory in which we store cached results.
+func cacheDir() (string, error) {
+	if dir := os.Getenv("TYPEDCONTEXT_CACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "typedcontext"), nil
+}
+
+// hashFiles writes the names and contents of the given files to h.
+func hashFiles(h io.Writer, filenames []string) error {
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file %s\n", filename)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageHasher computes the cache keys of packages.
+type packageHasher struct {
+	// prefix is hashed into every key; it identifies the linter binary and
+	// its flags.
+	prefix string
+	hashes map[*packages.Package]string
+}
+
+// hash returns the cache key of the given package.
+func (hasher *packageHasher) hash(pkg *packages.Package) (string, error) {
+	if hash, ok := hasher.hashes[pkg]; ok {
+		return hash, nil
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\npackage %s %s\n", hasher.prefix, pkg.ID, pkg.PkgPath)
+	if err := hashFiles(h, pkg.CompiledGoFiles); err != nil {
+		return "", err
+	}
+	if len(pkg.GoFiles) > 0 {
+		configs := contextLinter.ConfigFiles(filepath.Dir(pkg.GoFiles[0]))
+		if err := hashFiles(h, configs); err != nil {
+			return "", err
+		}
+	}
+
+	paths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		dep, err := hasher.hash(pkg.Imports[path])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "import %s %s\n", path, dep)
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	hasher.hashes[pkg] = hash
+	return hash, nil
+}
+
+// executableHash returns a hash of the running linter binary.
+func executableHash() (string, error) {
+	filename, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if err := hashFiles(h, []string{filename}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readCache returns the cached diagnostics with the given key, if any.
+func readCache(dir, key string) ([]cachedDiagnostic, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, key[:2], key))
+	if err != nil {
+		return nil, false
+	}
+	var diagnostics []cachedDiagnostic
+	if err := json.Unmarshal(data, &diagnostics); err != nil {
+		return nil, false // corrupt; we'll just recompute it
+	}
+	return diagnostics, true
+}
+
+// writeCache stores the given diagnostics with the given key.
+//
+// We write to a temporary file and rename it into place, so that concurrent
+// runs never see a partial entry.
+func writeCache(dir, key string, diagnostics []cachedDiagnostic) error {
+	data, err := json.Marshal(diagnostics)
+	if err != nil {
+		return err
+	}
+	subdir := filepath.Join(dir, key[:2])
+	if err := os.MkdirAll(subdir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(subdir, key+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(subdir, key))
+}
+
+// analyzePackages loads and analyzes the packages with the given IDs, and
+// returns their diagnostics, by package ID.
+func analyzePackages(ids map[string]bool, tests bool) (map[string][]cachedDiagnostic, error) {
+	var paths []string
+	seenPaths := map[string]bool{}
+	for id := range ids {
+		// Test variants have IDs like "p [p.test]", "p_test [p.test]" and
+		// "p.test"; we load them all via p.
+		path := strings.SplitN(id, " ", 2)[0]
+		path = strings.TrimSuffix(strings.TrimSuffix(path, ".test"), "_test")
+		if !seenPaths[path] {
+			seenPaths[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: tests}
+	loaded, err := packages.Load(config, paths...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(loaded) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+	var pkgs []*packages.Package
+	for _, pkg := range loaded {
+		if ids[pkg.ID] {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	graph, err := checker.Analyze(contextLinter.Analyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := map[string][]cachedDiagnostic{}
+	for _, pkg := range pkgs {
+		results[pkg.ID] = []cachedDiagnostic{}
+	}
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %v", act.Analyzer.Name, act.Err)
+		}
+		fset := act.Package.Fset
+		for _, diagnostic := range act.Diagnostics {
+			cached := cachedDiagnostic{
+				Posn:    fset.Position(diagnostic.Pos).String(),
+				Message: diagnostic.Message,
+			}
+			for _, related := range diagnostic.Related {
+				cached.Related = append(cached.Related, cachedDiagnostic{
+					Posn:    fset.Position(related.Pos).String(),
+					Message: related.Message,
+				})
+			}
+			results[act.Package.ID] = append(results[act.Package.ID], cached)
+		}
+	}
+	return results, nil
+}
+
+// cached runs the analyzers over the packages matching the patterns in args
+// (which may also include analyzer flags), reusing cached results where
+// possible, prints the diagnostics, and returns the exit status under the
+// given -failon policy.
+func cached(args []string, policy string) int {
+	flags, tests := analyzerFlags("typedcontext -cache")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	dir, err := cacheDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	exeHash, err := executableHash()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var settings []string
+	flags.VisitAll(func(f *flag.Flag) {
+		settings = append(settings, f.Name+"="+f.Value.String())
+	})
+	hasher := &packageHasher{
+		prefix: exeHash + "\n" + strings.Join(settings, "\n"),
+		hashes: map[*packages.Package]string{},
+	}
+
+	// Load just enough to compute the keys: no syntax or types.
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedImports | packages.NeedDeps,
+		Tests: *tests,
+	}
+	roots, err := packages.Load(config, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if packages.PrintErrors(roots) > 0 {
+		return 1
+	}
+
+	results := map[string][]cachedDiagnostic{}
+	keys := map[string]string{}
+	misses := map[string]bool{}
+	for _, pkg := range roots {
+		key, err := hasher.hash(pkg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		keys[pkg.ID] = key
+		if diagnostics, ok := readCache(dir, key); ok {
+			results[pkg.ID] = diagnostics
+		} else {
+			misses[pkg.ID] = true
+		}
+	}
+
+	if len(misses) > 0 {
+		analyzed, err := analyzePackages(misses, *tests)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for id, diagnostics := range analyzed {
+			results[id] = diagnostics
+			if err := writeCache(dir, keys[id], diagnostics); err != nil {
+				// We still have the results; we just can't save them.
+				fmt.Fprintf(os.Stderr, "warning: writing cache: %v\n", err)
+			}
+		}
+	}
+
+	ids := make([]string, len(roots))
+	for i, pkg := range roots {
+		ids[i] = pkg.ID
+	}
+	return printDiagnostics(ids, results, policy)
+}
+
+// analyzerFlags returns a flag set, named name, with the -test flag and the
+// flags of each analyzer (prefixed by its name, as multichecker does), for
+// the modes which run the analyzers themselves.
+func analyzerFlags(name string) (*flag.FlagSet, *bool) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	tests := flags.Bool("test", true, "indicates whether test files should be analyzed, too")
+	for _, analyzer := range contextLinter.Analyzers {
+		prefix := analyzer.Name + "."
+		analyzer.Flags.VisitAll(func(f *flag.Flag) {
+			flags.Var(f.Value, prefix+f.Name, f.Usage)
+		})
+	}
+	return flags, tests
+}
+
+// printDiagnostics prints the diagnostics of the packages with the given
+// IDs, in order, and returns the exit status under the given -failon
+// policy.
+//
+// We skip duplicates from files belonging to several packages (like p and
+// p [p.test]), as the checker does.
+func printDiagnostics(ids []string, results map[string][]cachedDiagnostic, policy string) int {
+	seen := map[string]bool{}
+	status := 0
+	for _, id := range ids {
+		for _, diagnostic := range results[id] {
+			line := diagnostic.Posn + ": " + diagnostic.Message
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			if fails(diagnostic, policy) {
+				status = 3 // like multichecker, when it reports diagnostics
+			}
+			fmt.Fprintln(os.Stderr, line)
+			for _, related := range diagnostic.Related {
+				fmt.Fprintf(os.Stderr, "%s: \t%s\n", related.Posn, related.Message)
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,90 @@
+package main
+
+// This file implements the -failon flag, which sets which diagnostics fail
+// the run (exit with status 3):
+//	advice	all of them (the default, as for any analysis driver)
+//	error	only those which aren't advice (see contextLinter.AdvicePrefix),
+//		so that new checks can be rolled out as warnings first
+// multichecker fails on every diagnostic, so with -failon=error we run the
+// analyzers ourselves, as -cache and -batch do, which also honor it; -fix
+// and -json aren't supported then.
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// The policies -failon accepts.
+const (
+	failOnAdvice = "advice"
+	failOnError  = "error"
+)
+
+// failOnArg returns the remaining arguments, and the policy given by the
+// -failon=POLICY flag, or failOnAdvice if it wasn't passed.
+func failOnArg(args []string) ([]string, string) {
+	policy := failOnAdvice
+	var rest []string
+	for _, arg := range args {
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if strings.HasPrefix(flag, "failon=") && arg != flag {
+			policy = flag[len("failon="):]
+			if policy != failOnAdvice && policy != failOnError {
+				fmt.Fprintf(os.Stderr, "invalid -failon policy %q: must be %s or %s\n",
+					policy, failOnAdvice, failOnError)
+				os.Exit(2)
+			}
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, policy
+}
+
+// fails returns true if the given diagnostic fails the run, under the given
+// policy.
+func fails(diagnostic cachedDiagnostic, policy string) bool {
+	return policy != failOnError || !strings.HasPrefix(diagnostic.Message, contextLinter.AdvicePrefix)
+}
+
+// direct runs the analyzers over the packages matching the patterns in args
+// (which may also include analyzer flags), all at once, prints the
+// diagnostics, and returns the exit status under the given policy.
+func direct(args []string, policy string) int {
+	flags, tests := analyzerFlags("typedcontext -failon=" + policy)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	config := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Tests: *tests}
+	roots, err := packages.Load(config, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if packages.PrintErrors(roots) > 0 {
+		return 1
+	}
+	ids := make([]string, len(roots))
+	wanted := map[string]bool{}
+	for i, pkg := range roots {
+		ids[i] = pkg.ID
+		wanted[pkg.ID] = true
+	}
+
+	results, err := analyzePackages(wanted, *tests)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return printDiagnostics(ids, results, policy)
+}
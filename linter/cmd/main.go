@@ -1,10 +1,356 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	contextLinter "github.com/khan/typed-context/linter"
-	"golang.org/x/tools/go/analysis/singlechecker"
+	"golang.org/x/tools/go/analysis/multichecker"
 )
 
 func main() {
-	singlechecker.Main(contextLinter.TypedContextInterfaceAnalyzer)
+	args, policy := failOnArg(os.Args[1:])
+	if code, ok := explainArg(args); ok {
+		os.Exit(explain(code))
+	}
+	if patterns, ok := deadInterfacesArgs(args); ok {
+		os.Exit(deadInterfaces(patterns))
+	}
+	if patterns, ok := unusedEmbedsArgs(args); ok {
+		os.Exit(unusedEmbeds(patterns))
+	}
+	if patterns, ok := wideFuncTypesArgs(args); ok {
+		os.Exit(wideFuncTypes(patterns))
+	}
+	if file, patterns, ok := metricsArgs(args); ok {
+		os.Exit(metrics(file, patterns))
+	}
+	if options, ok := ownersArgs(args); ok {
+		os.Exit(owners(options))
+	}
+	if file, patterns, ok := recordShapesArgs(args); ok {
+		os.Exit(recordShapes(file, patterns))
+	}
+	if threshold, write, patterns, ok := minimizeArgs(args); ok {
+		os.Exit(minimize(threshold, write, patterns))
+	}
+	if sets, args, ok := tagSetsArgs(args); ok {
+		os.Exit(tagSets(sets, args, policy))
+	}
+	if ref, args, ok := sinceArgs(args); ok {
+		os.Exit(since(ref, args, policy))
+	}
+	if args, ok := cacheArgs(args); ok {
+		os.Exit(cached(args, policy))
+	}
+	if args, ok := batchArgs(args); ok {
+		os.Exit(batched(args, policy))
+	}
+	if policy == failOnError {
+		os.Exit(direct(args, policy))
+	}
+	os.Args = append(os.Args[:1], args...) // without -failon
+	multichecker.Main(contextLinter.Analyzers...)
+}
+
+// explainArg returns the argument of the -explain flag, if it was passed.
+//
+// We handle -explain ourselves, rather than as a regular flag, because
+// multichecker insists on being given some packages to analyze.
+func explainArg(args []string) (string, bool) {
+	for i, arg := range args {
+		arg = strings.TrimPrefix(arg, "-")
+		switch {
+		case arg == "-explain" || arg == "explain":
+			if i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", true
+		case strings.HasPrefix(arg, "explain="), strings.HasPrefix(arg, "-explain="):
+			return arg[strings.Index(arg, "=")+1:], true
+		}
+	}
+	return "", false
+}
+
+// explain prints the documentation for the given diagnostic code, and returns
+// the exit status.
+func explain(code string) int {
+	explanation, ok := contextLinter.Explain(code)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown diagnostic code %q; known codes are:\n", code)
+		for _, code := range contextLinter.Codes() {
+			fmt.Fprintf(os.Stderr, "\t%s\n", code)
+		}
+		return 2
+	}
+	fmt.Println(explanation)
+	return 0
+}
+
+// deadInterfacesArgs returns the package patterns to check, if the
+// -deadinterfaces flag was passed.
+//
+// This is a separate mode, rather than a regular flag, since it's a
+// whole-program check which the analysis drivers can't do; see
+// contextLinter.FindDeadInterfaces.
+func deadInterfacesArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "-deadinterfaces" || arg == "--deadinterfaces" {
+			patterns := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return patterns, true
+		}
+	}
+	return nil, false
+}
+
+// deadInterfaces prints the typed context interfaces, in the packages
+// matching the given patterns, that nothing requests, and returns the exit
+// status.
+func deadInterfaces(patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	dead, err := contextLinter.FindDeadInterfaces(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, iface := range dead {
+		fmt.Printf("%s: typed context interface %s is never requested; "+
+			"delete it (%s)\n", iface.Position, iface.Name, contextLinter.CodeDeadInterface)
+	}
+	if len(dead) > 0 {
+		return 3 // like multichecker, when it reports diagnostics
+	}
+	return 0
+}
+
+// unusedEmbedsArgs returns the package patterns to check, if the
+// -unusedembeds flag was passed.  Like -deadinterfaces, this is a separate
+// mode; see contextLinter.FindUnusedEmbeds.
+func unusedEmbedsArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "-unusedembeds" || arg == "--unusedembeds" {
+			patterns := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return patterns, true
+		}
+	}
+	return nil, false
+}
+
+// unusedEmbeds prints the embeds of composite typed context interfaces, in
+// the packages matching the given patterns, that no function requesting the
+// interface uses, and returns the exit status.
+func unusedEmbeds(patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	unused, err := contextLinter.FindUnusedEmbeds(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, embed := range unused {
+		fmt.Printf("%s: no function requesting %s uses its embedded %s; "+
+			"remove it (%s)\n", embed.Position, embed.Interface, embed.Embed,
+			contextLinter.CodeUnusedEmbed)
+	}
+	if len(unused) > 0 {
+		return 3
+	}
+	return 0
+}
+
+// wideFuncTypesArgs returns the package patterns to check, if the
+// -widefunctypes flag was passed.  Like -deadinterfaces, this is a separate
+// mode; see contextLinter.FindWideFuncTypes.
+func wideFuncTypesArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "-widefunctypes" || arg == "--widefunctypes" {
+			patterns := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return patterns, true
+		}
+	}
+	return nil, false
+}
+
+// wideFuncTypes prints the context parameters of exported function types, in
+// the packages matching the given patterns, which request interfaces none of
+// their implementations use, and returns the exit status.
+func wideFuncTypes(patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	wide, err := contextLinter.FindWideFuncTypes(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, param := range wide {
+		name := param.Param
+		if name == "" || name == "_" {
+			name = "context"
+		}
+		fmt.Printf("%s: no function assigned to %s (of %d) uses %s of its %s; "+
+			"narrow it (%s)\n", param.Position, param.Declaration, param.Implementations,
+			strings.Join(param.Unused, ", "), name, contextLinter.CodeWideFuncType)
+	}
+	if len(wide) > 0 {
+		return 3
+	}
+	return 0
+}
+
+// metricsArgs returns the file to write the metrics to, and the package
+// patterns to compute them for, if the -metrics=FILE flag was passed.  Like
+// -deadinterfaces, this is a separate mode; see contextLinter.Metrics.
+func metricsArgs(args []string) (string, []string, bool) {
+	for i, arg := range args {
+		arg = strings.TrimPrefix(arg, "-")
+		if strings.HasPrefix(arg, "metrics=") || strings.HasPrefix(arg, "-metrics=") {
+			patterns := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return arg[strings.Index(arg, "=")+1:], patterns, true
+		}
+	}
+	return "", nil, false
+}
+
+// metrics writes the interface-size metrics of the packages matching the
+// given patterns to the given file, as JSON, and returns the exit status.
+func metrics(file string, patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	pkgs, err := contextLinter.Metrics(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"packages": pkgs}, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// recordShapesArgs returns the inventory file to write, and the package
+// patterns to record the shapes of, if the -recordshapes=FILE flag was
+// passed.  Like -metrics, this is a separate mode; see contextLinter.Shapes.
+func recordShapesArgs(args []string) (string, []string, bool) {
+	for i, arg := range args {
+		arg = strings.TrimPrefix(arg, "-")
+		if strings.HasPrefix(arg, "recordshapes=") || strings.HasPrefix(arg, "-recordshapes=") {
+			patterns := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return arg[strings.Index(arg, "=")+1:], patterns, true
+		}
+	}
+	return "", nil, false
+}
+
+// recordShapes writes the inventory of the shapes of inline typed context
+// interfaces in the packages matching the given patterns to the given file,
+// for -typedcontextshapes.inventory, and returns the exit status.
+func recordShapes(file string, patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	shapes, err := contextLinter.Shapes(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var buf bytes.Buffer
+	if err := contextLinter.WriteShapeInventory(&buf, shapes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// _defaultMinimizeThreshold is how many parameters of a package must need a
+// set of interfaces for -minimize to give it a name, if not set.
+const _defaultMinimizeThreshold = 3
+
+// minimizeArgs returns the threshold, whether to write the changes, and the
+// package patterns, if the -minimize or -minimize=THRESHOLD flag was passed.
+// -w writes the changes; otherwise they're only listed.  Like
+// -deadinterfaces, this is a separate mode; see contextLinter.Minimize.
+func minimizeArgs(args []string) (int, bool, []string, bool) {
+	threshold, write, found := _defaultMinimizeThreshold, false, false
+	var patterns []string
+	for _, arg := range args {
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		switch {
+		case flag == "minimize" && arg != flag:
+			found = true
+		case strings.HasPrefix(flag, "minimize=") && arg != flag:
+			n, err := strconv.Atoi(flag[len("minimize="):])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid -minimize threshold %q\n", flag[len("minimize="):])
+				os.Exit(2)
+			}
+			threshold, found = n, true
+		case flag == "w" && arg != flag:
+			write = true
+		default:
+			patterns = append(patterns, arg)
+		}
+	}
+	return threshold, write, patterns, found
+}
+
+// minimize prints the changes which would make the typed context interfaces
+// of the packages matching the given patterns minimal, and makes them if
+// write is set; it returns the exit status.
+func minimize(threshold int, write bool, patterns []string) int {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	plan, err := contextLinter.Minimize(threshold, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, merge := range plan.Merges {
+		fmt.Printf("%s: merge %s into %s, which has the same interfaces\n",
+			merge.Position, merge.Interface, merge.Into)
+	}
+	for _, iface := range plan.Interfaces {
+		fmt.Printf("%s: add %s, embedding %s, for %d parameters\n",
+			iface.File, iface.Name, strings.Join(iface.Leaves, ", "), iface.Params)
+	}
+	for _, rewrite := range plan.Rewrites {
+		fmt.Printf("%s: %s: change %s from %s to %s\n", rewrite.Position,
+			rewrite.Function, strings.Join(rewrite.Params, ", "), rewrite.Old, rewrite.New)
+	}
+	for _, skipped := range plan.Skipped {
+		fmt.Printf("%s: %s: not changing %s, since %s\n", skipped.Position,
+			skipped.Function, strings.Join(skipped.Params, ", "), skipped.Reason)
+	}
+	switch {
+	case plan.Empty():
+		return 0
+	case write:
+		if err := plan.Apply(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	default:
+		return 3 // like multichecker, when there's something to fix
+	}
 }
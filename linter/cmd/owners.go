@@ -0,0 +1,145 @@
+package main
+
+// This file implements the -owners=FILE mode, which runs the analyzers and,
+// rather than printing their diagnostics, writes a summary of them per owner,
+// as listed in a CODEOWNERS file, for routing findings to teams; see
+// contextLinter.SummarizeByOwner.  The summary is CSV if FILE ends in .csv,
+// and JSON otherwise.
+//
+// -codeowners=FILE gives the CODEOWNERS file; by default, we look for one in
+// the current directory, or its .github or docs subdirectory, and then in
+// its parents likewise.  -baseline=FILE compares the counts against those of
+// an earlier JSON summary.  Analyzer flags, like -typedcontextcancel.enable,
+// can be passed as usual, with their values.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// ownersOptions are the arguments of the -owners mode.
+type ownersOptions struct {
+	output     string
+	codeowners string
+	baseline   string
+	flags      map[string]string
+	patterns   []string
+}
+
+// ownersArgs returns the arguments of the -owners mode, if the -owners=FILE
+// flag was passed.
+func ownersArgs(args []string) (ownersOptions, bool) {
+	options := ownersOptions{flags: map[string]string{}}
+	found := false
+	for _, arg := range args {
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		name, value, hasValue := strings.Cut(flag, "=")
+		switch {
+		case arg == flag:
+			options.patterns = append(options.patterns, arg)
+		case name == "owners" && hasValue:
+			options.output, found = value, true
+		case name == "codeowners" && hasValue:
+			options.codeowners = value
+		case name == "baseline" && hasValue:
+			options.baseline = value
+		case hasValue:
+			options.flags[name] = value
+		default:
+			options.flags[name] = "true"
+		}
+	}
+	return options, found
+}
+
+// findCodeowners returns the CODEOWNERS file for the current directory; see
+// the top of the file.
+func findCodeowners() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, subdir := range []string{"", ".github", "docs"} {
+			filename := filepath.Join(dir, subdir, "CODEOWNERS")
+			if _, err := os.Stat(filename); err == nil {
+				return filename, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no CODEOWNERS file found; pass -codeowners=FILE")
+		}
+		dir = parent
+	}
+}
+
+// owners writes the summary of the diagnostics of the packages matching the
+// given patterns by owner, and returns the exit status.
+func owners(options ownersOptions) int {
+	if options.codeowners == "" {
+		filename, err := findCodeowners()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		options.codeowners = filename
+	}
+	codeowners, err := contextLinter.ReadCodeowners(options.codeowners)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var baseline *contextLinter.OwnerReport
+	if options.baseline != "" {
+		baseline, err = contextLinter.ReadOwnerReport(options.baseline)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	patterns := options.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax}, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return 1
+	}
+	findings, err := contextLinter.Run(context.Background(), pkgs,
+		contextLinter.Options{Flags: options.flags})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	report := contextLinter.SummarizeByOwner(findings, codeowners, baseline)
+	var buf bytes.Buffer
+	if strings.HasSuffix(options.output, ".csv") {
+		err = report.WriteCSV(&buf)
+	} else {
+		err = report.WriteJSON(&buf)
+	}
+	if err == nil {
+		err = os.WriteFile(options.output, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
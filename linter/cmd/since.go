@@ -0,0 +1,237 @@
+package main
+
+// This file implements the -since=REF mode, which lints only the packages
+// that changes since the git ref REF (committed or not) could affect: those
+// containing a changed file, or whose configuration files changed, and
+// those depending on them, transitively.  Everything else must lint as it
+// did at REF, so on a pull request, -since=origin/main reports whatever
+// linting the whole tree would, but analyzes a fraction of it.
+//
+// The analyzers still need facts about every dependency of the affected
+// packages, most of which haven't changed.  So rather than analyze them
+// ourselves, we run the affected packages through go vet, with this binary
+// as its -vettool: go vet keeps each package's facts in the build cache, keyed
+// by its sources and ours, so a previous full run (with go vet, or with
+// -since on another change) leaves the facts of unchanged packages ready to
+// reuse.  Note the build cache doesn't know about our configuration files;
+// a change to one affects the packages it applies to, but facts cached under
+// the old configuration may be reused for their dependencies.
+//
+// A change to go.mod or go.sum affects everything.  -fix, -json and
+// -test=false aren't supported in this mode.
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// sinceArgs returns the git ref, and the remaining arguments, if the
+// -since=REF flag was passed.
+func sinceArgs(args []string) (string, []string, bool) {
+	for i, arg := range args {
+		arg = strings.TrimPrefix(arg, "-")
+		if strings.HasPrefix(arg, "since=") || strings.HasPrefix(arg, "-since=") {
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return arg[strings.Index(arg, "=")+1:], rest, true
+		}
+	}
+	return "", nil, false
+}
+
+// gitLines runs git with the given arguments, and returns the lines of its
+// output.
+func gitLines(args ...string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// changedFiles returns the absolute paths of the files changed since the
+// given ref: those it differs from the working tree in, and untracked files.
+func changedFiles(ref string) ([]string, error) {
+	top, err := gitLines("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if len(top) != 1 {
+		return nil, fmt.Errorf("can't find the top of the git repository")
+	}
+	diff, err := gitLines("diff", "--name-only", "--no-renames", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitLines("ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, name := range append(diff, untracked...) {
+		changed = append(changed, filepath.Join(top[0], filepath.FromSlash(name)))
+	}
+	return changed, nil
+}
+
+// affectedPackages returns the import paths (without test suffixes) of the
+// packages among roots which the changed files could affect: see the top
+// of the file.  A package contains a changed file if it's in the package's
+// directory, so that deleted files count too.
+func affectedPackages(roots []*packages.Package, changed []string) []string {
+	changedDirs := map[string]bool{}
+	changedFiles := map[string]bool{}
+	all := false
+	for _, filename := range changed {
+		changedDirs[filepath.Dir(filename)] = true
+		changedFiles[filename] = true
+		if base := filepath.Base(filename); base == "go.mod" || base == "go.sum" {
+			all = true
+		}
+	}
+
+	memo := map[*packages.Package]bool{}
+	var affected func(pkg *packages.Package) bool
+	affected = func(pkg *packages.Package) bool {
+		if result, ok := memo[pkg]; ok {
+			return result
+		}
+		memo[pkg] = false // for import cycles, which can't really happen
+		result := all
+		if len(pkg.GoFiles) > 0 {
+			dir := filepath.Dir(pkg.GoFiles[0])
+			result = result || changedDirs[dir]
+			for _, filename := range append(contextLinter.ConfigFiles(dir), pkg.EmbedFiles...) {
+				result = result || changedFiles[filename]
+			}
+		}
+		for _, dep := range pkg.Imports {
+			result = result || affected(dep)
+		}
+		memo[pkg] = result
+		return result
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, pkg := range roots {
+		path := basePath(pkg)
+		if !seen[path] && affected(pkg) {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// _vetDiagnostic matches a line of go vet's output reporting a diagnostic,
+// as opposed to a related position (whose message starts with a tab) or a
+// package header.
+var _vetDiagnostic = regexp.MustCompile(`^(.+?:\d+:\d+): ([^\t].*)$`)
+
+// since runs the analyzers over the packages matching the patterns in args
+// (which may also include analyzer flags) which changes since ref could
+// affect, prints the diagnostics, and returns the exit status under the
+// given -failon policy.
+func since(ref string, args []string, policy string) int {
+	flags, tests := analyzerFlags("typedcontext -since=" + ref)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*tests {
+		fmt.Fprintln(os.Stderr, "-test=false isn't supported with -since")
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	changed, err := changedFiles(ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedEmbedFiles |
+			packages.NeedImports | packages.NeedDeps,
+		Tests: true,
+	}
+	roots, err := packages.Load(config, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if packages.PrintErrors(roots) > 0 {
+		return 1
+	}
+	paths := affectedPackages(roots, changed)
+	if len(paths) == 0 {
+		return 0
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	vetArgs := []string{"vet", "-vettool=" + executable}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name != "test" {
+			vetArgs = append(vetArgs, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	vetArgs = append(vetArgs, paths...)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", vetArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &stderr
+	vetErr := cmd.Run()
+
+	// go vet fails whenever there are diagnostics; we decide for ourselves
+	// whether they fail the run.
+	status := 0
+	found := false
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# ") {
+			continue // the package the following diagnostics are in
+		}
+		if match := _vetDiagnostic.FindStringSubmatch(line); match != nil {
+			found = true
+			if fails(cachedDiagnostic{Posn: match[1], Message: match[2]}, policy) {
+				status = 3 // like multichecker, when it reports diagnostics
+			}
+		}
+		fmt.Fprintln(os.Stderr, line)
+	}
+	if vetErr != nil && !found {
+		fmt.Fprintln(os.Stderr, vetErr)
+		return 1
+	}
+	return status
+}
@@ -0,0 +1,72 @@
+package main
+
+// This file implements the -tagset mode, which analyzes the packages under
+// the default build configuration and with each set of build tags given by
+// a -tagset=TAG[,TAG...] flag (which may be repeated), merging how each
+// context is used in all of them before reporting; see
+// contextLinter.RunConfigurations.  This keeps code built only with, say,
+// -tags=integration from making contexts look like they request interfaces
+// they don't use.  -fix, -json and -test=false aren't supported in this
+// mode.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	contextLinter "github.com/khan/typed-context/linter"
+)
+
+// tagSetsArgs returns the sets of build tags given by the -tagset flags, and
+// the remaining arguments, if any were passed.
+func tagSetsArgs(args []string) ([][]string, []string, bool) {
+	var tagSets [][]string
+	var rest []string
+	for _, arg := range args {
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if value, ok := strings.CutPrefix(flag, "tagset="); ok && arg != flag {
+			tagSets = append(tagSets, strings.Split(value, ","))
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return tagSets, rest, len(tagSets) > 0
+}
+
+// tagSets runs the analyzers over the packages matching the patterns in
+// args (which may also include analyzer flags) under each configuration,
+// prints the diagnostics, and returns the exit status under the given
+// -failon policy.
+func tagSets(sets [][]string, args []string, policy string) int {
+	flags, tests := analyzerFlags("typedcontext -tagset")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*tests {
+		fmt.Fprintln(os.Stderr, "-test=false isn't supported with -tagset")
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	findings, err := contextLinter.RunConfigurations(context.Background(), patterns, sets, contextLinter.Options{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var diagnostics []cachedDiagnostic
+	for _, finding := range findings {
+		diagnostic := cachedDiagnostic{Posn: finding.Position.String(), Message: finding.Message}
+		for _, related := range finding.Related {
+			diagnostic.Related = append(diagnostic.Related, cachedDiagnostic{
+				Posn:    related.Position.String(),
+				Message: related.Message,
+			})
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return printDiagnostics([]string{""}, map[string][]cachedDiagnostic{"": diagnostics}, policy)
+}
@@ -0,0 +1,1101 @@
+package linter
+
+// This file defines the stable, machine-readable codes we attach to each kind
+// of diagnostic, and their extended documentation.
+//
+// The code of each diagnostic is set as its analysis.Diagnostic.Category, so
+// it's available in the -json output; CI can use it to route different kinds
+// of report to different severities without matching on the message text.
+// Codes are never reused: if a kind of report is removed, so is its code.
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Code is the machine-readable identifier of a kind of diagnostic.
+type Code string
+
+const (
+	// CodeUnused is reported when a context requests, but does not use, some
+	// interfaces.
+	CodeUnused Code = "TC001"
+	// CodeUnrequested is reported when a context uses, but does not
+	// explicitly request, some interfaces.
+	CodeUnrequested Code = "TC002"
+	// CodeAllUnused is reported when none of the interfaces requested by a
+	// context are used.
+	CodeAllUnused Code = "TC003"
+	// CodeLowCohesion is reported when a context's interfaces are used only
+	// in disjoint branches.
+	CodeLowCohesion Code = "TC004"
+	// CodeLeakedRequestContext is reported when a request-scoped context is
+	// captured by a goroutine or stored somewhere long-lived.
+	CodeLeakedRequestContext Code = "TC005"
+	// CodeNonAccessorMethod is reported when a typed context interface
+	// declares a method which isn't an accessor.
+	CodeNonAccessorMethod Code = "TC006"
+	// CodeDeadInterface is reported (by FindDeadInterfaces) when an exported
+	// typed context interface is never requested anywhere in the program.
+	CodeDeadInterface Code = "TC007"
+	// CodeRedundantProvider is reported when a function takes both a typed
+	// context and a provider which that context provides.
+	CodeRedundantProvider Code = "TC008"
+	// CodeUnexportedContext is reported when an exported function requests
+	// an unexported interface in its context.
+	CodeUnexportedContext Code = "TC009"
+	// CodeDynamicType is reported when code type-switches on, or compares,
+	// typed contexts.
+	CodeDynamicType Code = "TC010"
+	// CodeRedundantEmbed is reported when a typed context interface embeds
+	// an interface already implied by its other embeds.
+	CodeRedundantEmbed Code = "TC011"
+	// CodeShadowedContext is reported when a context variable shadows
+	// another context variable of a different type.
+	CodeShadowedContext Code = "TC012"
+	// CodeMisplacedInterface is reported when an interface is declared
+	// outside the packages designated for it by -typedcontextlayout.rules.
+	CodeMisplacedInterface Code = "TC013"
+	// CodeContextValue is reported when code calls Value on a typed context.
+	CodeContextValue Code = "TC014"
+	// CodeWideContext is reported when a composite typed context interface
+	// includes more leaf interfaces than -typedcontextsize.max.
+	CodeWideContext Code = "TC015"
+	// CodeFreshContext is reported when a function which has a context
+	// calls context.Background or context.TODO.
+	CodeFreshContext Code = "TC016"
+	// CodeReturnedContext is reported when a function other than a
+	// constructor returns a context.
+	CodeReturnedContext Code = "TC017"
+	// CodeUnusedEmbed is reported (by FindUnusedEmbeds) when no function
+	// requesting a composite typed context interface uses one of its embeds.
+	CodeUnusedEmbed Code = "TC018"
+	// CodeContextAsAny is reported when a typed context is passed to a
+	// function as any.
+	CodeContextAsAny Code = "TC019"
+	// CodeWideWrapper is reported when a decorator's result takes a wider
+	// context than the function it wraps and the decorator itself need.
+	CodeWideWrapper Code = "TC020"
+	// CodeNewShape is reported when an inline typed context interface
+	// combines leaf interfaces in a way not recorded in the shape inventory.
+	CodeNewShape Code = "TC021"
+	// CodeImpureAccessor is reported when an accessor of a concrete typed
+	// context does I/O, takes a lock, or mutates state.
+	CodeImpureAccessor Code = "TC022"
+	// CodeWideFixture is reported when a test passes a context from a
+	// fixture which provides more than the function it's passed to requests.
+	CodeWideFixture Code = "TC023"
+	// CodePointerContext is reported when a parameter or field is a pointer
+	// to a context interface, or a parameter a pointer to a concrete context.
+	CodePointerContext Code = "TC024"
+	// CodeWideField is reported when a struct field's typed context
+	// interface includes interfaces none of the field's uses need.
+	CodeWideField Code = "TC025"
+	// CodeWideRegistration is reported when a handler registry's shared
+	// signature gives a handler a wider context than it needs.
+	CodeWideRegistration Code = "TC026"
+	// CodeUnusedContext is reported, with -typedcontextinterface.unusedroots,
+	// when a parameter requesting just context.Context is never used.
+	CodeUnusedContext Code = "TC027"
+	// CodeRestrictedInterface is reported when a context requests a typed
+	// context interface outside the packages allowed to request it.
+	CodeRestrictedInterface Code = "TC028"
+	// CodeUncancellableLoop is reported when a loop which blocks never
+	// checks its context for cancellation.
+	CodeUncancellableLoop Code = "TC029"
+	// CodeImplicitAccessor is reported when a struct asserted to implement a
+	// typed context gets an accessor only from an embedded composite
+	// interface.
+	CodeImplicitAccessor Code = "TC030"
+	// CodeStrayAccessor is reported when a struct asserted to implement
+	// typed contexts defines an accessor none of them include.
+	CodeStrayAccessor Code = "TC031"
+	// CodeUnusedByImplementations is reported, with
+	// -typedcontextimplementations.enable, when an interface method's
+	// context requests interfaces none of its implementations use.
+	CodeUnusedByImplementations Code = "TC032"
+	// CodeImplementationUses is reported, with
+	// -typedcontextimplementations.explain, to list which implementations of
+	// an interface method use each interface of its context.
+	CodeImplementationUses Code = "TC033"
+	// CodeMismatchedProvider is reported when a provider extracted from a
+	// typed context is stored in a struct field of some other type.
+	CodeMismatchedProvider Code = "TC034"
+	// CodeSeveredContext is reported when a call passes a fresh context
+	// alongside a typed context narrowed to a server interface.
+	CodeSeveredContext Code = "TC035"
+	// CodeCapturedContext is reported when a closure returned from a
+	// function, like a functional option, stores the function's typed
+	// context in a field which isn't a typed context.
+	CodeCapturedContext Code = "TC036"
+	// CodeRewrappedContext is reported when a composite typed context is
+	// passed to a function of package context, like WithTimeout, which
+	// returns a plain context.Context.
+	CodeRewrappedContext Code = "TC037"
+	// CodeWideFuncType is reported (by FindWideFuncTypes) when an exported
+	// function type's context requests interfaces none of the functions
+	// assigned to it use.
+	CodeWideFuncType Code = "TC038"
+	// CodeLeakedContext is reported when a value holding a sensitive typed
+	// context is passed to a function which formats, serializes, or
+	// reflects over it.
+	CodeLeakedContext Code = "TC039"
+	// CodeMockDrift is reported when a test double of a typed context has
+	// accessors the production context it stands in for doesn't, or vice
+	// versa.
+	CodeMockDrift Code = "TC040"
+	// CodeEmbedOrder is reported, with -typedcontextorder.enable, when the
+	// embeds of an inline typed context interface aren't in canonical order.
+	CodeEmbedOrder Code = "TC041"
+	// CodeRoundTrip is reported when a context is rebuilt from its own
+	// providers, where it could be forwarded instead.
+	CodeRoundTrip Code = "TC042"
+	// CodeRepeatedInterface is reported when a large inline typed context
+	// interface appears several times in a package.
+	CodeRepeatedInterface Code = "TC043"
+)
+
+var _explanations = map[Code]string{
+	CodeUnused: `TC001: context requests but does not use interface(s)
+
+A function (or other variable) asks for a typed context with some interfaces
+embedded, but nothing it does with the context needs them.  For example:
+
+	func F(ctx interface {
+		context.Context
+		LoggerContext
+		SecretsContext
+	}) {
+		ctx.Logger().Log("hi")
+	}
+
+requests SecretsContext but never uses it: F never calls ctx.Secrets(), and
+never passes ctx to a function that needs a SecretsContext.  Remove the
+interface to keep the context as small as possible; that way callers (and
+readers) know exactly what F depends on.`,
+
+	CodeUnrequested: `TC002: context uses but does not explicitly request interface(s)
+
+A function uses some interface of its context that it only requested
+indirectly, for example because it is embedded in another interface from a
+different package.  For example, if otherpkg defines
+
+	type I interface { LoggerContext; SecretsContext }
+
+then
+
+	func F(ctx otherpkg.I) {
+		ctx.Logger().Log("hi")
+	}
+
+uses LoggerContext without requesting it: F should say it needs
+LoggerContext itself, rather than relying on how otherpkg happens to define
+I.  Add the interface explicitly (see ADR-429).  Interfaces defined in the
+same package as the function, and exported, count as explicit requests for
+everything they embed.  context.Context never needs requesting explicitly:
+every context has its methods (but see TC014 for Value).
+
+If the context also requests interfaces it doesn't use (see TC001), they're
+listed in the same report: often the two go together, since F is using some
+other part of the context instead.  The report's related information points
+at each use and each unused request, and says which embeds an interface
+comes from, like "it's embedded via otherpkg.I -> LoggerContext".`,
+
+	CodeAllUnused: `TC003: no interfaces requested by a context are used
+
+A function asks for a typed context, but doesn't use any of its interfaces at
+all.  (The regular unused-variable checks don't complain about function
+parameters.)  Remove the interfaces, or rename the parameter to _ if it's
+needed only to match some signature.`,
+
+	CodeLowCohesion: `TC004: context has low cohesion
+
+A function requests several interfaces, but uses some of them only in one
+branch of an if/else or switch, and others only in another branch.  For
+example:
+
+	func Handle(ctx interface {
+		context.Context
+		DatabaseContext
+		CacheContext
+		HttpClientContext
+		SecretsContext
+	}, local bool) error {
+		if local {
+			// uses only ctx.Database() and ctx.Cache()
+		} else {
+			// uses only ctx.HttpClient() and ctx.Secrets()
+		}
+	}
+
+This suggests Handle is really two functions, and every caller has to
+provide the union of what they need.  Split it into two functions (each
+with a narrower context), or have it take narrower inputs.  This check is
+opt-in: enable it with -typedcontextcohesion.enable.`,
+
+	CodeLeakedRequestContext: `TC005: request-scoped context may outlive the request
+
+A context which embeds a request-scoped interface (by default, any interface
+named RequestContext; see -typedcontextdetach.requestscoped) is used by a
+goroutine, or stored in a package-level variable or a field of a method's
+receiver.  For example:
+
+	func Handle(ctx interface {
+		context.Context
+		RequestContext
+	}) {
+		go sendAnalytics(ctx)
+	}
+
+The goroutine may still be running after the request is done, at which
+point its providers (the request itself, a request-tagged logger, and so
+on) are no longer valid, and its cancellation fires.  Pass
+typedcontext.Detach(ctx) instead, and build a new typed context from it with
+providers that are safe to use in the background.`,
+
+	CodeNonAccessorMethod: `TC006: typed context interface declares a non-accessor method
+
+An interface which embeds context.Context declares a method which takes
+arguments, or doesn't return exactly one value.  For example:
+
+	type UserContext interface {
+		context.Context
+		LookupUser(id string) (*User, error)
+	}
+
+Typed context interfaces should be pure declarations of capabilities: each
+method is an accessor, which takes no arguments and returns a single
+provider.  Logic like LookupUser belongs on the provider:
+
+	type UserContext interface {
+		context.Context
+		Users() *UserStore
+	}
+
+and callers use ctx.Users().Lookup(id).  That way every implementation of
+the context (including mocks) just has to hold the providers.`,
+
+	CodeDeadInterface: `TC007: typed context interface is never requested
+
+An exported interface which embeds context.Context isn't mentioned in the
+signature of any function in the program, nor embedded in any interface
+that is.  Nothing can need it, so it can be deleted.
+
+This is a whole-program check, so it isn't run with the other analyzers.
+Run the linter in -deadinterfaces mode over all of your code, e.g.
+
+	go run github.com/khan/typed-context/linter/cmd -deadinterfaces ./...
+
+Interfaces requested only by packages outside the given patterns will be
+reported as dead, so make sure the patterns cover the whole program.`,
+
+	CodeRedundantProvider: `TC008: parameter duplicates a provider of the context
+
+A function takes a typed context, and also a separate parameter of the same
+type as one of the context's providers.  For example:
+
+	func F(ctx LoggerContext, logger *Logger) {
+		logger.Log("hi")
+	}
+
+Callers have to pass the logger twice, and may pass two different ones.
+Remove the parameter and use the accessor instead:
+
+	func F(ctx LoggerContext) {
+		ctx.Logger().Log("hi")
+	}
+
+Where it's safe, the diagnostic has a suggested fix which does this (apply
+it with -fix), and removes the argument from every call of the function.
+It's not offered for exported functions (outside package main), methods
+implementing an interface, or functions referred to other than by direct
+calls, whose callers may be out of reach or whose signature is fixed.`,
+
+	CodeUnexportedContext: `TC009: exported function requests an unexported context interface
+
+An exported function's context parameter mentions an unexported interface,
+typically embedded in an inline interface.  For example:
+
+	type loggerContext interface {
+		context.Context
+		Logger() *Logger
+	}
+
+	func F(ctx interface {
+		context.Context
+		loggerContext
+	}) { ... }
+
+Callers in other packages can pass a context with a Logger() method, but
+can't name the requirement: to request what F needs in their own context
+types, they have to spell out loggerContext's contents, and keep them in
+sync by hand.  Export the interface (LoggerContext), or declare an exported
+named interface for F's context.`,
+
+	CodeDynamicType: `TC010: branching on the dynamic type of a typed context
+
+Code type-switches on a typed context, or compares typed contexts with == or
+!=.  For example:
+
+	switch ctx.(type) {
+	case *prodContext:
+		...
+	case *mockContext:
+		...
+	}
+
+A function's context type says which capabilities it needs, and any
+implementation providing them should do.  Branching on the implementation
+defeats that: the function's real requirements are hidden, and it behaves
+differently in tests.  Request the capabilities you need instead, or add a
+provider whose behavior differs between implementations.
+
+Comparisons with nil, and type assertions to an interface (which is how a
+context is narrowed at a dynamic boundary), are fine.  This check is opt-in:
+enable it with -typedcontextdynamic.enable.  It's skipped in the packages
+that implement contexts; see -typedcontextdynamic.allowpkgs.`,
+
+	CodeRedundantEmbed: `TC011: interface embeds an interface implied by its other embeds
+
+A typed context interface embeds some interface which another of its embeds
+already includes.  For example:
+
+	func F(ctx interface {
+		RequestContext
+		DatabaseContext
+		context.Context
+	})
+
+where RequestContext already embeds context.Context.  The extra embed doesn't
+change the type, but it makes the declaration longer and obscures which
+capabilities are really being requested.  Remove it; the suggested fix does
+so.`,
+
+	CodeShadowedContext: `TC012: context shadows a context of a different type
+
+A context variable is declared in an inner scope with the same name as a
+context in an outer scope, but a different type.  For example:
+
+	func F(ctx interface {
+		LoggerContext
+		SecretsContext
+	}) {
+		if ctx, ok := ctx.(DatabaseContext); ok {
+			...
+		}
+	}
+
+Within the if, ctx is a DatabaseContext, and ctx.Logger() no longer compiles;
+worse, if the types overlap, code may silently use a different context than
+its author intended.  This also applies to function literals with their own
+ctx parameter.  Give the inner context a different name, like dbCtx.
+Shadowing a context with one of the identical type is fine.`,
+
+	CodeMisplacedInterface: `TC013: interface declared outside its designated packages
+
+A named interface is declared in a package not allowed by the layout rules
+(see -typedcontextlayout.rules, or layout in .typedcontext.yaml).  For
+example, with the rule
+
+	*Context=.../ctx
+
+every interface whose name ends in Context must be declared in a package
+whose path ends in /ctx.  Keeping context interfaces in designated packages
+makes them easy to find, and to reuse rather than redeclare.  Move the
+interface to such a package.`,
+
+	CodeContextValue: `TC014: Value called on a typed context
+
+Code calls Value on a typed context.  For example:
+
+	func F(ctx interface {
+		context.Context
+		LoggerContext
+	}) {
+		userID := ctx.Value(userIDKey).(string)
+		...
+	}
+
+F depends on a user ID, but its signature doesn't say so, and nothing checks
+that callers provide one; that's what typed contexts are for.  Add an
+accessor, in an interface like
+
+	type UserIDContext interface {
+		context.Context
+		UserID() string
+	}
+
+and request it instead.  Since every typed context has context.Context's
+methods, calling Value is never reported as an unrequested use (TC002); this
+is reported instead.  Calls on a plain context.Context aren't reported, nor
+are calls in the packages listed in -typedcontextvalue.allowpkgs (say,
+tracing libraries which look up their spans by key).`,
+
+	CodeWideContext: `TC015: composite typed context interface is too wide
+
+A composite typed context interface, named or inline, includes more distinct
+leaf interfaces (not counting context.Context) than the limit set by
+-typedcontextsize.max, or maxleaves in a configuration file (8 by default).
+For example:
+
+	type AppContext interface {
+		context.Context
+		RequestContext
+		DatabaseContext
+		HttpClientContext
+		... six more ...
+	}
+
+Even if every function requesting it uses all of it, those functions do a
+lot, and their signatures no longer say much about what they depend on.
+Split up the functions, so that each requests less; or group related
+interfaces into intermediate interfaces, like a StorageContext embedding
+DatabaseContext and CacheContext, which mean something on their own.`,
+
+	CodeFreshContext: `TC016: new context made where one is available
+
+A function which has a context parameter -- its own, or one of an enclosing
+function -- calls context.Background() or context.TODO().  For example:
+
+	func F(ctx AppContext) {
+		G(context.Background())
+	}
+
+G loses ctx's deadline, cancellation and values, and its typed interfaces:
+if G later needs, say, a LoggerContext, there's nothing to pass it but a
+context built from scratch.  Pass ctx instead.
+
+Calls in main and init functions and in _test.go files aren't reported.  A
+function which really does mean to start something independent of its
+caller can be listed in -typedcontextbackground.allow, or the
+allowbackground key of a configuration file; for work which must outlive a
+request, consider typedcontext.Detach(ctx) instead.`,
+
+	CodeReturnedContext: `TC017: context returned by a function which isn't a constructor
+
+A function returns a context, but isn't named like a constructor.  For
+example:
+
+	func lookUpUser(ctx AppContext) (AppContext, error)
+
+Contexts normally flow down the call chain; when functions also hand them
+back, it's hard to tell which context (and so which deadline, cancellation
+and providers) a given call sees.  Return just what the function computed,
+and keep passing the context you had.
+
+Functions whose names match -typedcontextreturn.constructors (by default
+With*, New*Context and Compose*), and any function in a package in
+-typedcontextreturn.allowpkgs, may return contexts.  This check is opt-in:
+enable it with -typedcontextreturn.enable.`,
+
+	CodeUnusedEmbed: `TC018: composite interface embeds an interface no function requesting it uses
+
+A named typed context interface which just combines others embeds one which
+none of the functions requesting the interface, anywhere in the program,
+use.  For example:
+
+	type HandlerContext interface {
+		context.Context
+		LoggerContext
+		SecretsContext
+	}
+
+where every function taking a HandlerContext logs, but none of them (or the
+functions they pass it to) look up secrets.  Remove SecretsContext from
+HandlerContext, so the shared interface shrinks along with the functions'
+own.  This is a whole-program check: run the linter with -unusedembeds over
+all your packages (e.g. ./...).`,
+
+	CodeContextAsAny: `TC019: typed context passed as any
+
+A typed context is passed to a function whose parameter has type any (or
+interface{}).  For example:
+
+	func Setup(ctx interface {
+		context.Context
+		LoggerContext
+	}) {
+		registry.Register(ctx)
+	}
+
+where Register takes an any.  Whatever uses the registered value has to
+type-assert it to get at its capabilities, so what it depends on appears in
+no signature, and the linter can't check that Setup provides it.  Have
+Register take the typed context interface it needs, or register the
+providers themselves.
+
+Functions in the packages listed in -typedcontextany.allowpkgs (by default
+fmt, log and errors, which only format their arguments) may take contexts
+as any.  Plain context.Contexts aren't reported.`,
+
+	CodeWideWrapper: `TC020: decorator widens the context of the function it wraps
+
+A function which wraps another, like a decorator or middleware, returns a
+function taking a wider context than the wrapped function and the wrapper
+itself need.  For example:
+
+	func logged(f func(ctx DBContext) error) func(ctx AppContext) error {
+		return func(ctx AppContext) error {
+			ctx.Logger().Log("calling f")
+			return f(ctx)
+		}
+	}
+
+The returned function needs DBContext, to call f, and LoggerContext, to log;
+if AppContext includes, say, SecretsContext too, every caller of the
+decorated function has to provide secrets for nothing.  Narrow the context
+type in logged's result (and in the function literal) to
+
+	interface {
+		DBContext
+		LoggerContext
+	}`,
+
+	CodeNewShape: `TC021: new shape of typed context interface
+
+An inline typed context interface combines leaf interfaces in a way that
+the package hasn't before, according to the shape inventory (see
+-typedcontextshapes.inventory).  For example, with an inventory listing
+only
+
+	example.com/billing	example.com/app.DBContext, example.com/app.LoggerContext
+
+a new function in example.com/billing taking
+
+	ctx interface {
+		context.Context
+		LoggerContext
+		SecretsContext
+	}
+
+is reported.  That's not necessarily wrong: it's a signal for review, since
+every new shape is one more combination callers have to provide.  Reuse an
+existing shape (or a named interface) if one fits; if not, run the linter
+with -recordshapes=FILE to update the inventory, and commit it with the
+change.  This analyzer is opt-in.`,
+
+	CodeImpureAccessor: `TC022: accessor does I/O, takes a lock, or mutates state
+
+An accessor method of a concrete typed context -- a type implementing
+context.Context -- does more than return its provider.  For example:
+
+	func (c *appContext) Database() DatabaseInterface {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.db == nil {
+			c.db = openDatabase()
+		}
+		return c.db
+	}
+
+Callers expect ctx.Database() to be a cheap lookup, which can't fail or
+block; and contexts which are expensive to use get hoarded rather than
+passed around.  Build the provider when the context is built, or, if it's
+expensive and often unused, lazily with sync.Once or typedcontext.Lazy
+(see typedcontext-gen's -lazy), whose first call is the only one that does
+any work.
+
+I/O means calls to functions in the packages in -typedcontextgetter.iopkgs
+(by default bufio, database/sql, io, net, os and syscall).  Calls to other
+helpers, and function literals like the one passed to sync.Once.Do, aren't
+looked into.`,
+
+	CodeWideFixture: `TC023: test fixture provides more than the function under test requests
+
+A test passes a context built by a fixture -- a function returning a typed
+context interface, like GetContextWithAllTheMocks -- to a function which
+requests only some of what it provides.  For example, if
+
+	func DoTheThing(ctx interface {
+		LoggerContext
+		SecretsContext
+	}, thing string) error
+
+then
+
+	func TestDoTheThing(t *testing.T) {
+		DoTheThing(GetContextWithAllTheMocks(), "thing")
+	}
+
+is reported: the test doesn't say what DoTheThing depends on, and if
+DoTheThing's signature grows, the test keeps passing with whatever the
+fixture happens to provide.  Use a fixture which provides just what's
+requested; if the package (or the fixture's) has one taking the same
+arguments, it's suggested, with a fix.  A context assigned to a variable is
+checked against everything the variable is passed to, unless it's used in
+any other way.
+
+Only _test.go files are checked.  This analyzer is opt-in.`,
+
+	CodePointerContext: `TC024: context passed or stored by pointer
+
+A parameter, or struct field, is a pointer to a typed context interface.
+For example:
+
+	func load(ctx *LoggerContext, id int) {
+		(*ctx).Logger().Log("loading")
+	}
+
+An interface value is already a reference to whatever implements it, so the
+pointer buys nothing but a dereference at each use, and callers have to
+take the address of a variable to call it.  Take (or store) the interface
+itself.  If the function is unexported, and its only callers, in the same
+package, pass &ctx, a fix drops the pointer from the parameter, the
+dereferences, and the callers' &.
+
+A parameter which is a pointer to a concrete context -- a type implementing
+context.Context, like ctx *appContext -- is reported too: the function can
+only be called with that one implementation, and its signature doesn't say
+which of the context's interfaces it uses.  Request those instead.`,
+
+	CodeWideField: `TC025: struct field requests but does not use interface(s)
+
+A struct field holding a typed context requests interfaces which none of
+its uses need.  For example:
+
+	type handler struct {
+		ctx interface {
+			LoggerContext
+			SecretsContext
+		}
+	}
+
+	func (h *handler) serve() {
+		h.ctx.Logger().Log("serving")
+	}
+
+Like a parameter's (see TC001), a field's type should request only what's
+used, so that what the struct depends on is visible, and it can be built
+from whatever context its creator has.  Each selector of the field, like
+h.ctx, is a use, anywhere in the package: calling a method on it, passing
+it to a function, assigning it to something of a narrower type, and so on.
+
+Only unexported, non-embedded fields are checked, and not those whose value
+escapes in ways the linter doesn't follow, like being copied to a new
+variable (ctx := h.ctx).`,
+
+	CodeWideRegistration: `TC026: handler registered with a wider context than it needs
+
+A function registered in a map, slice or array of handlers needs less of
+its context than the registry's shared signature provides.  For example:
+
+	var handlers = map[string]func(ctx AppContext, req *Request) error{
+		"users":  handleUsers,
+		"avatar": handleAvatar,
+	}
+
+where handleAvatar only uses ctx.Logger().  Each handler has to take the
+union of what all of them need, so none of their signatures say what they
+actually depend on, and they can't be called with anything less.  Narrow
+the handler's context, and register it via an adapter:
+
+	func handleAvatar(ctx LoggerContext, req *Request) error { ... }
+
+	"avatar": func(ctx AppContext, arg1 *Request) error { return handleAvatar(ctx, arg1) },
+
+For functions declared in the package, which are otherwise only called
+directly, a fix does just that.`,
+
+	CodeUnusedContext: `TC027: context.Context parameter is never used
+
+A function takes a context.Context (or another root, see
+-typedcontextinterface.contextroots), but never refers to it.  For example:
+
+	func resize(ctx context.Context, img *Image) *Image {
+		return img.Scale(0.5)
+	}
+
+There's nothing to narrow, so TC003 doesn't apply, and the regular
+unused-variable checks don't complain about parameters.  Remove it, or
+rename it to _ if it's needed to match some signature.
+
+This is only reported with -typedcontextinterface.unusedroots, and never for
+methods implementing an interface (declared in the package or any of its
+dependencies) with a method of that name, exported functions and methods
+outside package main, or functions referred to other than by direct calls,
+all of which may need the parameter to match some signature.`,
+
+	CodeRestrictedInterface: `TC028: restricted interface requested outside its allowlisted packages
+
+A context requests a typed context interface which, according to
+-typedcontextcapability.rules, only certain packages may request.  For
+example, with the rule
+
+	example.com/ctx.SecretsContext=example.com/secure/...
+
+a function in example.com/billing taking
+
+	func charge(ctx interface {
+		LoggerContext
+		SecretsContext
+	}, amount int) error
+
+is reported, as is one taking an AppContext which embeds SecretsContext,
+even if it only uses the secrets correctly: code outside the boundary
+shouldn't be handed the capability at all.  Narrow the context to what the
+code uses, or, if it really needs the interface, move the code into one of
+the allowed packages.
+
+The rules can only be set by flag, not in a .typedcontext.yaml, and
+directories exempted by configuration files are still checked, so that no
+directory can allowlist itself.`,
+
+	CodeUncancellableLoop: `TC029: loop blocks without checking its context for cancellation
+
+A loop in a function with a context does I/O, or calls a provider which
+blocks, each iteration, but never checks whether the context is cancelled.
+For example:
+
+	func sync(ctx DatabaseContext, ids []int) error {
+		for _, id := range ids {
+			if err := ctx.Database().Put(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+If the request is cancelled (the client went away, or a deadline passed),
+the loop still does every round trip.  Check for cancellation each
+iteration:
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		...
+	}
+
+or select on ctx.Done(), or pass ctx to the call, so it can give up itself.
+
+This is only reported with -typedcontextcancel.enable.  I/O is a call into
+one of the packages of -typedcontextgetter.iopkgs; providers which block are
+those returned by the accessors in -typedcontextcancel.providers.`,
+
+	CodeImplicitAccessor: `TC030: context struct gets an accessor only by embedding a composite interface
+
+A struct asserted to implement a typed context gets one of its accessors
+only because it embeds a composite interface, which may well be nil.  For
+example:
+
+	type MockContext struct {
+		AppContext
+		logger *Logger
+	}
+
+	func (c MockContext) Logger() *Logger { return c.logger }
+
+	var _ AppContext = MockContext{}
+
+Every accessor of AppContext but Logger comes from the embedded AppContext,
+so adding an accessor to AppContext doesn't break MockContext: its callers
+panic instead, far away.  Define the accessor on the struct, or, if
+delegating it is the point, embed the interface which declares it (like
+DatabaseContext for Database), which says so.`,
+
+	CodeStrayAccessor: `TC031: context struct defines an accessor none of its interfaces include
+
+A struct asserted to implement typed contexts defines an accessor of some
+other typed context interface, which none of its assertions include.  For
+example, after Billing is removed from AppContext:
+
+	var _ AppContext = MockContext{}
+
+	func (c MockContext) Billing() *Billing { return c.billing }
+
+Nothing calls it through AppContext any more; it's usually a leftover.
+Remove it, or, if the struct is meant to provide it, assert that too:
+
+	var _ BillingContext = MockContext{}`,
+
+	CodeUnusedByImplementations: `TC032: interface method requests interfaces none of its implementations use
+
+An interface method's context requests an interface which none of the
+method's implementations in the package use, judging each by itself.  For
+example:
+
+	type Store interface {
+		Get(ctx interface {
+			DatabaseContext
+			SecretsContext
+		}, key string) (string, error)
+	}
+
+	func (s *dbStore) Get(ctx interface {
+		DatabaseContext
+		SecretsContext
+	}, key string) (string, error) {
+		return ctx.Database().Get(key)
+	}
+
+Every caller of Store.Get must provide SecretsContext, but nothing needs it.
+Remove it from the method's context (and its implementations').  This is
+only reported with -typedcontextimplementations.enable.`,
+
+	CodeImplementationUses: `TC033: which implementations use each interface of a method's context
+
+This is informational: with -typedcontextimplementations.explain, each
+interface method with a typed context is reported with the interfaces of
+its context, and for each, which implementations in the package use it,
+like
+
+	implementations of Store.Get use DatabaseContext (dbStore.Get, memStore.Get), LoggerContext (dbStore.Get)
+
+An interface only one implementation uses is forced on every caller by that
+implementation; consider whether it should get it some other way.  To keep
+these from failing the run, set TC033=advice in -typedcontextinterface.severity
+and pass -failon=error.`,
+
+	CodeMismatchedProvider: `TC034: provider extracted from a context is stored in a field of another type
+
+A constructor extracts a provider from a typed context, and stores it in a
+struct field whose type isn't the one the accessor returns.  For example:
+
+	type Handler struct {
+		db any
+	}
+
+	func NewHandler(ctx DatabaseContext) *Handler {
+		return &Handler{db: ctx.Database()}
+	}
+
+Once the providers are in its fields, the struct's field types are what say
+what it depends on; "db any" says nothing, and readers (and tools) can't
+trace it back to DatabaseContext.  Declare the field with the accessor's
+result type:
+
+	type Handler struct {
+		db *Database
+	}
+
+A method value, like ctx.Database, should likewise be stored in a field of
+the accessor's signature, func() *Database.`,
+
+	CodeSeveredContext: `TC035: fresh context passed alongside a narrowed typed context
+
+A call passes context.Background() (or context.TODO()) alongside a typed
+context which it narrows to a server interface -- one with the providers,
+but not context.Context, as in example 07.  For example:
+
+	func G(ctx context.Context, server LoggerServer)
+
+	func F(ctx AppContext) {
+		G(context.Background(), ctx)
+	}
+
+G gets ctx's providers, but not its deadline or cancellation: if the
+request is cancelled, G carries on regardless.  Pass ctx as the context too:
+
+	G(ctx, ctx)
+
+This is reported wherever the typed context comes from, even in main, but
+not in _test.go files, or in the functions listed in
+-typedcontextbackground.allow.`,
+
+	CodeCapturedContext: `TC036: context captured by a returned closure is stored untyped
+
+A closure returned from a function, like a functional option, stores the
+function's typed context in a field of type context.Context (or any):
+
+	func WithContext(ctx AppContext) Option {
+		return func(o *options) {
+			o.ctx = ctx // o.ctx is a context.Context
+		}
+	}
+
+Whatever applies the option uses o.ctx later, presumably via a cast like
+o.ctx.(LoggerContext), which the linter can't trace back to ctx.  So it
+counts the store as a use of every interface ctx requests, and reports this
+instead, since it can't tell whether ctx requests more than it needs.
+
+Declare the field as the typed context its users need, and request just
+that:
+
+	type options struct {
+		ctx LoggerContext
+	}
+
+	func WithContext(ctx LoggerContext) Option
+
+If that's not possible, set the severity of TC036 to off (see
+-typedcontextinterface.severity).`,
+
+	CodeRewrappedContext: `TC037: typed context passed to a context.With function
+
+A composite typed context is passed to a function of package context which
+returns a plain context.Context, like:
+
+	func F(ctx AppContext) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		G(timeoutCtx.(AppContext)) // panics
+	}
+
+The result doesn't have ctx's providers, so code which needs them has to
+keep ctx around too, or assert the result back to AppContext, which fails.
+Package typedcontext has wrappers for context.WithCancel, WithDeadline,
+WithTimeout and WithValue, which return a context of the type they're
+given:
+
+	ctx, cancel := typedcontext.WithTimeout(ctx, time.Second)
+
+For the others, put the result back with typedcontext.WithContext:
+
+	inner, cancel := context.WithCancelCause(ctx)
+	ctx = typedcontext.WithContext(ctx, inner)
+
+Both need AppContext to have a constructor generated by typedcontext-gen.
+Calls whose result is passed to typedcontext.WithContext aren't reported.`,
+
+	CodeWideFuncType: `TC038: exported function type requests interfaces its implementations don't use
+
+An exported function type, or variable of function type, which other
+packages implement, like:
+
+	type HandlerFunc func(ctx AppContext, r Request) error
+
+gives its implementations a context requesting interfaces which none of the
+functions assigned or converted to it, anywhere in the program, use.  Narrow
+the declared context to the interfaces they use, so the API documents what
+its implementations depend on.  Declarations to which some function we can't
+see the body of is assigned aren't reported.  This is a whole-program check:
+run the linter with -widefunctypes over all your packages (e.g. ./...).`,
+
+	CodeLeakedContext: `TC039: sensitive context passed to formatting, serialization, or reflection
+
+A value holding a context which includes a sensitive interface (by default
+SecretsContext) is passed to a function which may dump its contents, like:
+
+	func Handle(ctx AppContext) {
+		log.Printf("handling %+v", ctx)
+	}
+
+where AppContext embeds SecretsContext.  Whatever implements the context
+holds the secrets, and printing it, marshaling it as JSON, or walking it
+with reflect may expose them.  The same goes for a struct with a field
+holding such a context, and for a local variable of type any assigned one.
+Pass only the fields you need.
+
+Configure the interfaces with -typedcontextleak.sensitive, and the functions
+with -typedcontextleak.sinks.`,
+
+	CodeMockDrift: `TC040: mock context's accessors differ from production's
+
+A test double of a typed context has an accessor the production context it
+stands in for doesn't, or lacks one it has.  For example:
+
+	type appContext struct { ... } // has Logger() and Database()
+
+	type MockAppContext struct { ... } // has Logger() and Secrets()
+
+Tests using the mock can't exercise code needing Database(), and pass for
+code needing Secrets(), which production can't provide.  Add the missing
+accessors, or remove the stray ones, so the two stay in step.
+
+A struct MockX is paired with the type X in its package, or, failing that,
+x (like appContext for MockAppContext).  To pair a mock with another type,
+name it in a directive in the mock's doc comment:
+
+	//typedcontext:mock-for appContext
+	type FakeContext struct { ... }
+
+Accessors a struct only gets by embedding an interface which doesn't
+declare them, which is usually nil, don't count.`,
+
+	CodeEmbedOrder: `TC041: embeds of inline interface are out of order
+
+The embeds of an inline typed context interface, in a parameter list,
+aren't in canonical order: context.Context first, then the rest
+alphabetized by package path and name.  For example:
+
+	func f(ctx interface {
+		RequestContext
+		context.Context
+		DatabaseContext
+	})
+
+should list context.Context, DatabaseContext, RequestContext.  Listing
+them in arbitrary order makes for noisy diffs, and makes it hard to see
+what two functions request in common.  Named interfaces aren't checked.
+
+This check is opt-in: enable it with -typedcontextorder.enable.`,
+
+	CodeRoundTrip: `TC042: context rebuilt from its own providers
+
+A context is passed to a function which builds a typed context, along with
+providers extracted from that same context, and nothing else.  For
+example:
+
+	logger := ctx.Logger()
+	ctx2 := WithLogger(ctx, logger)
+
+gives ctx back the logger it already has: ctx2 is ctx again, wrapped, and
+readers have to check that nothing changed.  The same goes for
+typedcontext.Override(ctx, typedcontext.With(ctx.Logger())), or a
+generated constructor called with ctx and all of its providers.  Forward
+ctx instead; if the result is meant to differ, pass the provider it should
+have instead.`,
+
+	CodeRepeatedInterface: `TC043: large inline interface repeated within a package
+
+An inline typed context interface including several leaf interfaces
+appears several times in the package.  For example:
+
+	func Charge(ctx interface {
+		context.Context
+		DatabaseContext
+		LoggerContext
+		RequestContext
+	}) { ... }
+
+	func Refund(ctx interface {
+		context.Context
+		DatabaseContext
+		LoggerContext
+		RequestContext
+	}) { ... }
+
+Repeated like this, it's a named type in all but name, and every change to
+it has to be made in each copy.  Declare it as a named interface, like
+DatabaseLoggerRequestContext, and use that; the fix does so, with a
+go:generate directive for typedcontext-gen, which generates its
+constructor.  Interfaces of fewer than 3 leaf interfaces, not counting
+context.Context, and those appearing only once, aren't reported; change
+the limits with -typedcontextrepeated.minleaves and
+-typedcontextrepeated.minuses.`,
+}
+
+// Explain returns the extended documentation for the given code (e.g.
+// "TC002"), or false if there is no such code.
+func Explain(code string) (string, bool) {
+	explanation, ok := _explanations[Code(strings.ToUpper(code))]
+	return explanation, ok
+}
+
+// Codes returns all the diagnostic codes, in order.
+func Codes() []Code {
+	codes := make([]Code, 0, len(_explanations))
+	for code := range _explanations {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// reportf is like pass.Reportf, but sets the diagnostic's code.
+func reportf(pass *analysis.Pass, node positioner, code Code, format string, args ...interface{}) {
+	pass.Report(analysis.Diagnostic{
+		Pos:      node.Pos(),
+		Category: string(code),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// positioner is anything with a position: an ast.Node, types.Object, etc.
+type positioner interface {
+	Pos() token.Pos
+}
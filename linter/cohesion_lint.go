@@ -0,0 +1,252 @@
+package linter
+
+// This file defines the linter that typed context parameters are "cohesive":
+// that a function doesn't request some interfaces it uses only in one branch,
+// and some others it uses only in another.  Such a function is really two
+// functions sharing a signature, and should probably be split, or take
+// narrower inputs.
+//
+// Specifically, for each context-parameter, and each branching statement
+// (if/else chain, switch, or select) in the function, we attribute each
+// leaf-interface of the parameter to the branches that use it.  If at least
+// two branches each use a group of interfaces that no other code in the
+// function uses, and those groups make up at least half of the interfaces
+// the parameter requests, we report the partition we found.
+//
+// This is opt-in, since it's only a heuristic: sometimes a function that
+// dispatches to one of several implementations is exactly what you want.
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextCohesionAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextcohesion",
+	Doc:  "reports typed context parameters whose interfaces are used only in disjoint branches",
+	Run:  _runCohesion,
+}
+
+var (
+	// _minCohesionLeaves is the number of interfaces a context must request
+	// before we check its cohesion.
+	_minCohesionLeaves int
+	// _minCohesionGroup is the number of interfaces a branch must use
+	// exclusively for us to count it as a separate group.
+	_minCohesionGroup int
+)
+
+func init() {
+	optIn(TypedContextCohesionAnalyzer)
+	TypedContextCohesionAnalyzer.Flags.IntVar(&_minCohesionLeaves, "minleaves",
+		4, "only check contexts requesting at least this many interfaces")
+	TypedContextCohesionAnalyzer.Flags.IntVar(&_minCohesionGroup, "mingroup",
+		2, "only count branches using at least this many interfaces exclusively")
+}
+
+// _branches returns the branches of the given statement, if it's a branching
+// statement, or nil otherwise.
+//
+// An if/else-if/else chain is treated as a single statement with a branch
+// for each block.  An if without an else has just one branch; the implicit
+// empty else-branch can't use anything.
+func _branches(stmt ast.Node) []ast.Node {
+	var branches []ast.Node
+	switch stmt := stmt.(type) {
+	case *ast.IfStmt:
+		branches = append(branches, stmt.Body)
+		for stmt.Else != nil {
+			elseIf, ok := stmt.Else.(*ast.IfStmt)
+			if !ok {
+				branches = append(branches, stmt.Else)
+				break
+			}
+			branches = append(branches, elseIf.Body)
+			stmt = elseIf
+		}
+	case *ast.SwitchStmt:
+		for _, clause := range stmt.Body.List {
+			branches = append(branches, clause)
+		}
+	case *ast.TypeSwitchStmt:
+		for _, clause := range stmt.Body.List {
+			branches = append(branches, clause)
+		}
+	case *ast.SelectStmt:
+		for _, clause := range stmt.Body.List {
+			branches = append(branches, clause)
+		}
+	}
+	return branches
+}
+
+// _cohesionChecker checks the cohesion of a single context-parameter.
+type _cohesionChecker struct {
+	obj    types.Object
+	leaves []types.Type
+	body   *ast.BlockStmt
+
+	typesInfo *types.Info
+	pkg       *types.Package
+	options   analysisengine.Options
+}
+
+// usedLeaves returns the leaf-interfaces of the parameter which are used
+// within the given nodes, excluding any code within the nodes in skip.
+func (checker *_cohesionChecker) usedLeaves(nodes []ast.Node, skip map[ast.Node]bool) map[types.Type]bool {
+	tracker := analysisengine.NewTracker(checker.typesInfo, checker.pkg, checker.options)
+	info := tracker.TrackObject(checker.obj)
+	for _, node := range nodes {
+		ast.Inspect(node, func(node ast.Node) bool {
+			if skip[node] {
+				return false
+			}
+			tracker.MarkNodeUses(node)
+			return true
+		})
+	}
+
+	used := map[types.Type]bool{}
+	for _, leaf := range checker.leaves {
+		if info.InterfaceWasUsed(leaf) {
+			used[leaf] = true
+		}
+	}
+	return used
+}
+
+// partition returns the groups of leaf-interfaces used exclusively by each
+// branch of the given statement, if they make the parameter non-cohesive,
+// or nil otherwise.
+func (checker *_cohesionChecker) partition(branches []ast.Node) [][]types.Type {
+	skip := make(map[ast.Node]bool, len(branches))
+	for _, branch := range branches {
+		skip[branch] = true
+	}
+	usedOutside := checker.usedLeaves([]ast.Node{checker.body}, skip)
+
+	usedByBranch := make([]map[types.Type]bool, len(branches))
+	for i, branch := range branches {
+		usedByBranch[i] = checker.usedLeaves([]ast.Node{branch}, nil)
+	}
+
+	var groups [][]types.Type
+	total := 0
+	for i := range branches {
+		var group []types.Type
+		for _, leaf := range checker.leaves {
+			if !usedByBranch[i][leaf] || usedOutside[leaf] {
+				continue
+			}
+			exclusive := true
+			for j := range branches {
+				if j != i && usedByBranch[j][leaf] {
+					exclusive = false
+					break
+				}
+			}
+			if exclusive {
+				group = append(group, leaf)
+			}
+		}
+		if len(group) >= _minCohesionGroup {
+			groups = append(groups, group)
+			total += len(group)
+		}
+	}
+
+	if len(groups) < 2 || 2*total < len(checker.leaves) {
+		return nil
+	}
+	return groups
+}
+
+// _checkCohesion reports the given parameter of a function with the given
+// body, if it's not cohesive.  Its uses are tracked with the given options,
+// so that, say, passing it to a configured sink counts as it does for
+// typedcontextinterface.
+func _checkCohesion(pass *analysis.Pass, options analysisengine.Options, ident *ast.Ident, body *ast.BlockStmt) {
+	obj := pass.TypesInfo.Defs[ident]
+	if obj == nil || obj.Name() == "_" || !isContextType(obj.Type()) ||
+		_skipFile(pass.Fset.File(obj.Pos()).Name(), pass.Pkg) {
+		return
+	}
+
+	// context.Context is needed by pretty much everything, so we don't count
+	// it toward either the size of the context or any group; nor do we count
+	// a leaf embedded via two paths twice.
+	leaves := _distinctLeaves(obj.Type())
+	if len(leaves) < _minCohesionLeaves {
+		return
+	}
+
+	checker := _cohesionChecker{obj, leaves, body, pass.TypesInfo, pass.Pkg, options}
+	reported := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		if reported {
+			return false
+		}
+		branches := _branches(node)
+		if len(branches) < 2 {
+			return true
+		}
+		groups := checker.partition(branches)
+		if groups == nil {
+			return true
+		}
+
+		groupNames := make([]string, len(groups))
+		for i, group := range groups {
+			groupNames[i] = "{" + _formatTypeList(group, pass.Pkg) + "}"
+		}
+		reportf(pass, obj, CodeLowCohesion,
+			"%s has low cohesion: the branches of the statement at line %d "+
+				"use disjoint interfaces %s; split the function or narrow "+
+				"its inputs",
+			obj.Name(), pass.Fset.Position(node.Pos()).Line,
+			strings.Join(groupNames, " and "))
+		reported = true
+		return false
+	})
+}
+
+// _runCohesion lints that typed context parameters are cohesive.
+func _runCohesion(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			var funcType *ast.FuncType
+			var body *ast.BlockStmt
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				funcType, body = node.Type, node.Body
+			case *ast.FuncLit:
+				funcType, body = node.Type, node.Body
+			default:
+				return true
+			}
+			if body == nil { // external (e.g. assembly) function
+				return true
+			}
+			for _, field := range funcType.Params.List {
+				for _, ident := range field.Names {
+					_checkCohesion(pass, options, ident, body)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,362 @@
+package linter
+
+// This file defines helpers for the configuration options accepted by the
+// analyzers in this package: flags, and per-directory configuration files.
+//
+// Options may be set in a file named .typedcontext.yaml, in the directory of
+// a package or any of its parents up to the module root, like
+//	# Files in these directories (relative to this file) aren't reported on.
+//	exempt: [generated, legacy/api]
+//	# Added to -typedcontextinterface.runners, .derivers, .sinks, and
+//	# .sameunit.
+//	runners: ["(*example.com/pool.Pool).Submit"]
+//	derivers: ["example.com/tracing.StartSpan"]
+//	sinks: ["example.com/log.Errorf"]
+//	sameunit: [example.com/services/users]
+//	# "strict" also reports contexts declared in _test.go files; "default"
+//	# uses the flags.
+//	strictness: strict
+//	# Added to -typedcontextlayout.rules.
+//	layout: ["*Context=.../ctx"]
+//	# Added to -typedcontextbackground.allow.
+//	allowbackground: ["example.com/jobs.Start"]
+//	# Overrides -typedcontextinterface.serverinterfaces and .functypes.
+//	serverinterfaces: true
+//	functypes: true
+//	# Overrides -typedcontextsize.max.
+//	maxleaves: 12
+//	# Overrides -typedcontextinterface.generated; see generated.go.
+//	generated: report
+//	# Added to -typedcontextinterface.generators.
+//	generators: [typedcontext-gen=lint]
+//	# Added to -typedcontextinterface.severity; see severity.go.
+//	severity: [TC002=advice, TC015=off]
+//	# Overrides -typedcontextshapes.inventory; relative to this file.
+//	shapeinventory: shapes.txt
+// For each package, the files are merged from the outermost to the
+// innermost: lists are appended to (flags first), and other values set in an
+// inner file override those set in an outer one.  That way a team can be
+// stricter (or more lenient) in its own directories than the repo as a
+// whole.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"gopkg.in/yaml.v3"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// stringList is a flag.Value holding a comma-separated list of strings.
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = nil
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			*list = append(*list, item)
+		}
+	}
+	return nil
+}
+
+// hasPathPrefix returns true if path is equal to, or is a subdirectory of, the
+// given prefix.  (Unlike strings.HasPrefix, "foo/bar" is not under "foo/b".)
+//
+// This works for both package paths and filesystem paths.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// hasAnyPathPrefix returns true if hasPathPrefix(path, prefix) for any of the
+// given prefixes.
+func hasAnyPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// optIn makes the given analyzer opt-in: it does nothing unless its -enable
+// flag is set.  (Drivers like multichecker run every analyzer by default.)
+//
+// This must be called from an init function, since it wraps analyzer.Run.
+// The analyzer must not have a result, since it may not run.
+func optIn(analyzer *analysis.Analyzer) {
+	enabled := new(bool)
+	analyzer.Flags.BoolVar(enabled, "enable", false,
+		"enable this analyzer, which is off by default")
+	run := analyzer.Run
+	analyzer.Run = func(pass *analysis.Pass) (interface{}, error) {
+		if !*enabled {
+			return nil, nil
+		}
+		return run(pass)
+	}
+}
+
+// _configFilename is the name of per-directory configuration files.
+const _configFilename = ".typedcontext.yaml"
+
+// _configFile is the contents of a configuration file.
+type _configFile struct {
+	Exempt          []string `yaml:"exempt"`
+	Runners         []string `yaml:"runners"`
+	Derivers        []string `yaml:"derivers"`
+	Sinks           []string `yaml:"sinks"`
+	SameUnit        []string `yaml:"sameunit"`
+	Layout          []string `yaml:"layout"`
+	AllowBackground []string `yaml:"allowbackground"`
+	Strictness      string   `yaml:"strictness"`
+	Generated       string   `yaml:"generated"`
+	Generators      []string `yaml:"generators"`
+	Severity        []string `yaml:"severity"`
+	ShapeInventory  string   `yaml:"shapeinventory"`
+	// ServerInterfaces and FuncTypes are pointers so an inner file can turn
+	// them off.
+	ServerInterfaces *bool `yaml:"serverinterfaces"`
+	FuncTypes        *bool `yaml:"functypes"`
+	// MaxLeaves is a pointer so an inner file can set it to 0 (no limit).
+	MaxLeaves *int `yaml:"maxleaves"`
+}
+
+// The strictness levels which may be set in a configuration file.
+const (
+	// _strictnessDefault uses the flags as given.
+	_strictnessDefault = "default"
+	// _strictnessStrict also reports contexts declared in _test.go files.
+	_strictnessStrict = "strict"
+)
+
+// settings are the options in effect for a particular package: the flags,
+// merged with any configuration files.
+type settings struct {
+	// exempt lists directories (absolute paths) in which we don't report.
+	exempt []string
+	// checkTests says whether to report contexts declared in _test.go
+	// files.
+	checkTests bool
+	// serverInterfaces says whether to track server interfaces; see
+	// analysisengine.Options.
+	serverInterfaces bool
+	// funcTypes says whether to check function-typed declarations; see
+	// analysisengine.Options.
+	funcTypes bool
+	// maxLeaves is the most leaf interfaces a composite interface may
+	// include, or 0 for no limit; see size_lint.go.
+	maxLeaves int
+	runners   []string
+	derivers  []string
+	sinks     []string
+	sameUnit  []string
+	layout    []string
+	// backgroundAllowed lists functions which may call context.Background
+	// even though they have a context; see background_lint.go.
+	backgroundAllowed []string
+	// generated is what to do with generated files, and generators
+	// overrides it for particular generators; see generated.go.
+	generated  string
+	generators []string
+	// severities sets the severity of particular codes, as CODE=LEVEL; see
+	// severity.go.
+	severities []string
+	// shapeInventory is the inventory file for shapes_lint.go, if any.
+	shapeInventory string
+}
+
+// _settingsByPackage caches the settings for each package we've analyzed, by
+// package path.
+var _settingsByPackage sync.Map
+
+// _flagSettings returns the settings given by the flags alone, for the given
+// package.
+func _flagSettings(pkg *types.Package) *settings {
+	return &settings{
+		// An external test package, like foo/bar_test, has the same tests
+		// as foo/bar.
+		checkTests: _checkTests ||
+			pkg != nil && hasAnyPathPrefix(strings.TrimSuffix(pkg.Path(), "_test"), _checkTestsPackages),
+		serverInterfaces:  _serverInterfaces,
+		funcTypes:         _funcTypes,
+		maxLeaves:         _maxLeaves,
+		runners:           append([]string(nil), _runners...),
+		derivers:          append([]string(nil), _derivers...),
+		sinks:             append([]string(nil), _sinks...),
+		sameUnit:          append([]string(nil), _sameUnitPrefixes...),
+		layout:            append([]string(nil), _layoutRules...),
+		backgroundAllowed: append([]string(nil), _backgroundAllowed...),
+		generated:         string(_generatedMode),
+		generators:        append([]string(nil), _generatorModes.stringList...),
+		severities:        append([]string(nil), _severities.stringList...),
+		shapeInventory:    _shapeInventory,
+	}
+}
+
+// ConfigFiles returns the configuration files which apply to packages in the
+// given directory, outermost first, followed by any other files the
+// configuration refers to (the shape inventory; see shapes_lint.go).  Tools
+// which cache the linter's results (see linter/cmd) need to know when these
+// change.
+func ConfigFiles(dir string) []string {
+	files := _configFiles(dir)
+	inventory := _shapeInventory
+	for _, filename := range files {
+		var config _configFile
+		if data, err := os.ReadFile(filename); err == nil &&
+			yaml.Unmarshal(data, &config) == nil && config.ShapeInventory != "" {
+			inventory = filepath.Join(filepath.Dir(filename), config.ShapeInventory)
+		}
+	}
+	if inventory != "" {
+		if _, err := os.Stat(inventory); err == nil {
+			files = append(files, inventory)
+		}
+	}
+	return files
+}
+
+// _configFiles returns the configuration files which apply to the given
+// directory, outermost first.  We look in the directory and its parents, up
+// to the module root (the directory containing go.mod).
+func _configFiles(dir string) []string {
+	var files []string
+	for {
+		filename := filepath.Join(dir, _configFilename)
+		if _, err := os.Stat(filename); err == nil {
+			files = append([]string{filename}, files...)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return files
+}
+
+// merge merges the given configuration file, whose path is filename, into
+// the settings.
+func (s *settings) merge(filename string, pkg *types.Package) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var config _configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for _, exempt := range config.Exempt {
+		s.exempt = append(s.exempt, filepath.Join(filepath.Dir(filename), exempt))
+	}
+	s.runners = append(s.runners, config.Runners...)
+	s.derivers = append(s.derivers, config.Derivers...)
+	s.sinks = append(s.sinks, config.Sinks...)
+	s.sameUnit = append(s.sameUnit, config.SameUnit...)
+	s.layout = append(s.layout, config.Layout...)
+	s.backgroundAllowed = append(s.backgroundAllowed, config.AllowBackground...)
+	if err := _checkGeneratorModes(config.Generators); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	s.generators = append(s.generators, config.Generators...)
+	if err := _checkSeverities(config.Severity); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	s.severities = append(s.severities, config.Severity...)
+	if config.Generated != "" {
+		if err := _checkGeneratedMode(config.Generated); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		s.generated = config.Generated
+	}
+	if config.ShapeInventory != "" {
+		s.shapeInventory = filepath.Join(filepath.Dir(filename), config.ShapeInventory)
+	}
+	if config.ServerInterfaces != nil {
+		s.serverInterfaces = *config.ServerInterfaces
+	}
+	if config.FuncTypes != nil {
+		s.funcTypes = *config.FuncTypes
+	}
+	if config.MaxLeaves != nil {
+		s.maxLeaves = *config.MaxLeaves
+	}
+	switch config.Strictness {
+	case "":
+	case _strictnessDefault:
+		s.checkTests = _flagSettings(pkg).checkTests
+	case _strictnessStrict:
+		s.checkTests = true
+	default:
+		return fmt.Errorf("%s: unknown strictness %q: must be %s or %s",
+			filename, config.Strictness, _strictnessDefault, _strictnessStrict)
+	}
+	return nil
+}
+
+// loadSettings computes the settings for the package being analyzed by the
+// given pass, and caches them for settingsFor.  Each analyzer should call it
+// before calling anything that uses settingsFor.
+func loadSettings(pass *analysis.Pass) (*settings, error) {
+	if cached, ok := _settingsByPackage.Load(pass.Pkg.Path()); ok {
+		return cached.(*settings), nil
+	}
+
+	s := _flagSettings(pass.Pkg)
+	if len(pass.Files) > 0 {
+		dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+		for _, filename := range _configFiles(dir) {
+			if err := s.merge(filename, pass.Pkg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cached, _ := _settingsByPackage.LoadOrStore(pass.Pkg.Path(), s)
+	return cached.(*settings), nil
+}
+
+// ResetSettings forgets the settings computed for each package, so that the
+// next pass over it sees any flags or configuration files changed since,
+// along with the type caches of analysisengine (see ResetCaches).  It's for
+// tests and long-running drivers which run the analyzers several times in one
+// process (see linter/lintertest and cmd/typedcontext-lsp); one-shot drivers
+// never need it.  It mustn't be called while analyzers are running.
+func ResetSettings() {
+	_settingsByPackage.Clear()
+	analysisengine.ResetCaches()
+}
+
+// settingsFor returns the settings for the given package, as computed by
+// loadSettings.  For packages we haven't analyzed (say, dependencies), it
+// returns the settings given by the flags.
+func settingsFor(pkg *types.Package) *settings {
+	if pkg != nil {
+		if cached, ok := _settingsByPackage.Load(pkg.Path()); ok {
+			return cached.(*settings)
+		}
+	}
+	return _flagSettings(pkg)
+}
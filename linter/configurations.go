@@ -0,0 +1,159 @@
+package linter
+
+// This file defines RunConfigurations, which analyzes packages under several
+// build configurations, merging how each context is used in all of them
+// before reporting.
+//
+// Files guarded by build tags, like
+//	//go:build integration
+// may use a context differently: say, an integration-only implementation of
+// an interface method uses a SecretsContext which the default one doesn't.
+// Analyzed only under the default configuration, the context looks like it
+// requests SecretsContext for nothing.  So we first load and analyze the
+// packages under every configuration, recording, for each context variable
+// (by position, which is the same in each), the leaf interfaces it uses.
+// Then we analyze each configuration again, counting the uses found in the
+// others (see analysisengine.Usage.UseElsewhere), and report the union of
+// their findings.
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var (
+	_configurationUsesMu sync.RWMutex
+	// _configurationUses are the leaf interfaces, by TypeKey, which each
+	// context variable, by position, uses in any build configuration; see
+	// RunConfigurations.  It's nil outside RunConfigurations.
+	_configurationUses map[string]map[string]bool
+)
+
+// _markConfigurationUses records, in the given usage, the uses its variables
+// have in other build configurations, if we're running under several.
+func _markConfigurationUses(fset *token.FileSet, usage *InterfaceUsage) {
+	_configurationUsesMu.RLock()
+	defer _configurationUsesMu.RUnlock()
+	if _configurationUses == nil {
+		return
+	}
+	for _, obj := range usage.Objects {
+		used := _configurationUses[fset.Position(obj.Pos()).String()]
+		if len(used) == 0 {
+			continue
+		}
+		for _, leaf := range analysisengine.LeafInterfaces(obj.Type()) {
+			if used[analysisengine.TypeKey(leaf)] {
+				usage.Usages[obj].UseElsewhere(leaf)
+			}
+		}
+	}
+}
+
+// _recordConfigurationUses adds the leaf interfaces used by each context
+// variable in the given analysis to uses.
+func _recordConfigurationUses(graph *checker.Graph, uses map[string]map[string]bool) error {
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return fmt.Errorf("%s: %s: %v", act.Package.PkgPath, act.Analyzer.Name, act.Err)
+		}
+		usage := act.Result.(*InterfaceUsage)
+		for _, obj := range usage.Objects {
+			info := usage.Usages[obj]
+			key := act.Package.Fset.Position(obj.Pos()).String()
+			for _, leaf := range analysisengine.LeafInterfaces(obj.Type()) {
+				if !info.InterfaceWasUsed(leaf) {
+					continue
+				}
+				if uses[key] == nil {
+					uses[key] = map[string]bool{}
+				}
+				uses[key][analysisengine.TypeKey(leaf)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// RunConfigurations is like Run, but loads the packages matching the given
+// patterns (and their tests) itself, under the default build configuration
+// and with each of the given sets of build tags, and reports the findings of
+// all of them, merging how each context is used in all of them first (see
+// the top of this file).  A finding reported under several configurations
+// is reported once.
+func RunConfigurations(ctx context.Context, patterns []string, tagSets [][]string, options Options) ([]Finding, error) {
+	analyzers := options.Analyzers
+	if len(analyzers) == 0 {
+		analyzers = Analyzers
+	}
+	configurations := append([][]string{nil}, tagSets...)
+	loaded := make([][]*packages.Package, len(configurations))
+	uses := map[string]map[string]bool{}
+	for i, tags := range configurations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+		if len(tags) > 0 {
+			config.BuildFlags = []string{"-tags=" + strings.Join(tags, ",")}
+		}
+		pkgs, err := packages.Load(config, patterns...)
+		if err != nil {
+			return nil, err
+		}
+		if packages.PrintErrors(pkgs) > 0 {
+			return nil, fmt.Errorf("errors loading packages with tags %q", strings.Join(tags, ","))
+		}
+		loaded[i] = pkgs
+
+		restore, err := _setFlags(analyzers, options.Flags)
+		if err != nil {
+			return nil, err
+		}
+		graph, err := checker.Analyze([]*analysis.Analyzer{TypedContextUsageAnalyzer}, pkgs, nil)
+		if err == nil {
+			err = _recordConfigurationUses(graph, uses)
+		}
+		restore()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_configurationUsesMu.Lock()
+	_configurationUses = uses
+	_configurationUsesMu.Unlock()
+	defer func() {
+		_configurationUsesMu.Lock()
+		_configurationUses = nil
+		_configurationUsesMu.Unlock()
+	}()
+
+	seen := map[string]bool{}
+	var findings []Finding
+	for _, pkgs := range loaded {
+		configurationFindings, err := Run(ctx, pkgs, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range configurationFindings {
+			key := finding.Position.String() + ": " + finding.Message
+			if !seen[key] {
+				seen[key] = true
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	_sortFindings(findings)
+	return findings, nil
+}
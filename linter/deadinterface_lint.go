@@ -0,0 +1,200 @@
+package linter
+
+// This file defines the whole-program check for exported typed context
+// interfaces which no function ever requests, and so can be deleted.
+//
+// A single analysis pass can't tell that an exported interface is dead: it
+// may be requested by some other package.  So the analyzer here doesn't
+// report anything itself.  Instead, it exports a fact for each package listing
+// the interfaces the package defines and those it requests, and
+// FindDeadInterfaces runs it over the whole program and aggregates the facts.
+// (That's the -deadinterfaces mode of the linter command.)
+//
+// An interface is "requested" if it's mentioned in a function signature (as a
+// parameter or result), or recursively embedded in one that is.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypedContextDeadInterfaceAnalyzer computes the facts used by
+// FindDeadInterfaces.  It reports nothing itself, so it isn't in Analyzers.
+var TypedContextDeadInterfaceAnalyzer = &analysis.Analyzer{
+	Name:      "typedcontextdeadinterface",
+	Doc:       "records which typed context interfaces each package defines and requests",
+	Run:       _runDeadInterface,
+	FactTypes: []analysis.Fact{new(_interfaceUsageFact)},
+}
+
+// _interfaceUsageFact is the package fact exported by
+// TypedContextDeadInterfaceAnalyzer.  Interfaces are named as
+// "import/path.Name".
+type _interfaceUsageFact struct {
+	// Defined maps the exported typed context interfaces defined in the
+	// package to their positions.
+	Defined map[string]token.Position
+	// Requested lists the named interfaces the package requests.
+	Requested []string
+}
+
+func (*_interfaceUsageFact) AFact() {}
+
+func (fact *_interfaceUsageFact) String() string {
+	return fmt.Sprintf("defines %d, requests %d typed context interfaces",
+		len(fact.Defined), len(fact.Requested))
+}
+
+// _qualifiedName returns the name of the given named type as
+// "import/path.Name".
+func _qualifiedName(named *types.Named) string {
+	obj := named.Obj()
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// _addRequested adds the qualified names of typ, if it's a named interface,
+// and of all the named interfaces it recursively embeds, to requested.
+func _addRequested(typ types.Type, requested map[string]bool) {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+	if named, ok := typ.(*types.Named); ok {
+		name := _qualifiedName(named)
+		if requested[name] {
+			return // already visited
+		}
+		requested[name] = true
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		_addRequested(iface.EmbeddedType(i), requested)
+	}
+}
+
+func _runDeadInterface(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	fact := &_interfaceUsageFact{Defined: map[string]token.Position{}}
+
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() || obj.IsAlias() {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || !types.IsInterface(named) || !isContextType(named) {
+			continue
+		}
+		if _skipFile(pass.Fset.File(obj.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		fact.Defined[_qualifiedName(named)] = pass.Fset.Position(obj.Pos())
+	}
+
+	requested := map[string]bool{}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			funcType, ok := node.(*ast.FuncType)
+			if !ok {
+				return true
+			}
+			for _, fields := range []*ast.FieldList{funcType.Params, funcType.Results} {
+				if fields == nil {
+					continue
+				}
+				for _, field := range fields.List {
+					if typ := pass.TypesInfo.TypeOf(field.Type); typ != nil {
+						_addRequested(typ, requested)
+					}
+				}
+			}
+			return true
+		})
+	}
+	for name := range requested {
+		fact.Requested = append(fact.Requested, name)
+	}
+	sort.Strings(fact.Requested)
+
+	pass.ExportPackageFact(fact)
+	return nil, nil
+}
+
+// DeadInterface is an exported typed context interface which no function in
+// the program requests.
+type DeadInterface struct {
+	// Name is the name of the interface, as "import/path.Name".
+	Name     string
+	Position token.Position
+}
+
+// FindDeadInterfaces returns the exported typed context interfaces defined in
+// the packages matching the given patterns which aren't requested anywhere in
+// those packages or their dependencies (including their tests), sorted by
+// position.
+//
+// Since it can only see the given packages, it should be run over the whole
+// program (e.g. ./...): an interface requested only by some package not
+// matched by the patterns will be reported as dead.
+func FindDeadInterfaces(patterns ...string) ([]DeadInterface, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(
+		[]*analysis.Analyzer{TypedContextDeadInterfaceAnalyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defined := map[string]token.Position{}
+	requested := map[string]bool{}
+	for act := range graph.All() {
+		if act.Err != nil {
+			return nil, act.Err
+		}
+		var fact _interfaceUsageFact
+		if !act.PackageFact(act.Package.Types, &fact) {
+			continue
+		}
+		if act.IsRoot {
+			for name, pos := range fact.Defined {
+				defined[name] = pos
+			}
+		}
+		for _, name := range fact.Requested {
+			requested[name] = true
+		}
+	}
+
+	var dead []DeadInterface
+	for name, pos := range defined {
+		if !requested[name] {
+			dead = append(dead, DeadInterface{name, pos})
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		if dead[i].Position.Filename != dead[j].Position.Filename {
+			return dead[i].Position.Filename < dead[j].Position.Filename
+		}
+		return dead[i].Position.Offset < dead[j].Position.Offset
+	})
+	return dead, nil
+}
@@ -0,0 +1,248 @@
+package linter
+
+// This file defines the linter that request-scoped typed contexts don't
+// outlive their request: that they aren't captured by a goroutine, or stored
+// somewhere long-lived (a package-level variable, or a field of a method's
+// receiver).
+//
+// Which contexts are request-scoped is configurable: by default, it's those
+// which embed an interface named RequestContext.  To pass a context to a
+// goroutine, use typedcontext.Detach, which returns a plain context that no
+// longer carries the request-scoped providers.
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextDetachAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextdetach",
+	Doc:  "enforces that request-scoped typed contexts aren't leaked to goroutines or long-lived storage",
+	Run:  _runDetach,
+}
+
+// _requestScopedInterfaces lists the interfaces which make a context
+// request-scoped, as "import/path.Name", or just "Name" to match an
+// interface of that name in any package.
+var _requestScopedInterfaces = stringList{"RequestContext"}
+
+func init() {
+	TypedContextDetachAnalyzer.Flags.Var(&_requestScopedInterfaces,
+		"requestscoped", "comma-separated list of interfaces (import/path.Name, "+
+			"or Name for any package) which make a context request-scoped")
+}
+
+// _detachFuncName is the function which makes a context safe to outlive its
+// request.
+const _detachFuncName = "github.com/khan/typed-context/typedcontext.Detach"
+
+// matchesQualifiedName returns true if the given named type matches name,
+// which is "import/path.Name", or just "Name" to match in any package.
+func matchesQualifiedName(named *types.Named, name string) bool {
+	obj := named.Obj()
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return obj.Name() == name
+	}
+	return i > 0 && lintutil.TypeIs(named, name[:i], name[i+1:])
+}
+
+// _requestScopedEmbed returns the request-scoped interface recursively
+// embedded in typ, if any.
+func _requestScopedEmbed(typ types.Type) types.Type {
+	if named, ok := typ.(*types.Named); ok {
+		for _, name := range _requestScopedInterfaces {
+			if matchesQualifiedName(named, name) {
+				return typ
+			}
+		}
+	}
+
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if embed := _requestScopedEmbed(iface.EmbeddedType(i)); embed != nil {
+			return embed
+		}
+	}
+	return nil
+}
+
+// _detachChecker finds leaks of request-scoped contexts in a single file.
+type _detachChecker struct {
+	pass *analysis.Pass
+	// receivers are the pointer-receivers of the methods in the file;
+	// storing a context in a field of one of them is storing it in a
+	// long-lived place.
+	receivers map[types.Object]bool
+}
+
+// requestScoped returns the request-scoped interface embedded in the type of
+// the given identifier, if it's a context variable.
+func (checker *_detachChecker) requestScoped(ident *ast.Ident) (types.Object, types.Type) {
+	obj, ok := checker.pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || obj.IsField() || !isContextType(obj.Type()) {
+		return nil, nil
+	}
+	embed := _requestScopedEmbed(obj.Type())
+	if embed == nil {
+		return nil, nil
+	}
+	return obj, embed
+}
+
+// checkGo reports any request-scoped contexts captured by the given go
+// statement: either passed as an argument, bound as a method receiver, or
+// referenced within a function literal.
+func (checker *_detachChecker) checkGo(stmt *ast.GoStmt) {
+	report := func(ident *ast.Ident) {
+		obj, embed := checker.requestScoped(ident)
+		if obj == nil {
+			return
+		}
+		// A context defined within the goroutine itself is fine.
+		if stmt.Pos() <= obj.Pos() && obj.Pos() < stmt.End() {
+			return
+		}
+		reportf(checker.pass, ident, CodeLeakedRequestContext,
+			"%s is request-scoped (it embeds %s) but is used by a goroutine "+
+				"which may outlive the request; pass typedcontext.Detach(%s) instead",
+			ident.Name, _shortTypeName(embed, checker.pass.Pkg), ident.Name)
+	}
+
+	// The function and arguments are evaluated before the goroutine starts,
+	// so ctx.Logger() is fine; but ctx itself, or a method value bound to it,
+	// is captured.
+	call := stmt.Call
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		if recv, ok := sel.X.(*ast.Ident); ok {
+			report(recv)
+		}
+	}
+	for _, arg := range call.Args {
+		if ident, ok := arg.(*ast.Ident); ok {
+			report(ident)
+		}
+	}
+
+	ast.Inspect(call, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpr:
+			funcName := lintutil.NameOf(lintutil.ObjectFor(node.Fun, checker.pass.TypesInfo))
+			return funcName != _detachFuncName
+		case *ast.FuncLit:
+			ast.Inspect(node.Body, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.CallExpr:
+					funcName := lintutil.NameOf(lintutil.ObjectFor(node.Fun, checker.pass.TypesInfo))
+					return funcName != _detachFuncName
+				case *ast.Ident:
+					report(node)
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+}
+
+// _rootIdent returns the identifier at the root of a chain of selectors and
+// index expressions, e.g. x for x.y[z].w.
+func _rootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// checkAssign reports any request-scoped contexts stored in a long-lived
+// place by the given assignment.
+func (checker *_detachChecker) checkAssign(stmt *ast.AssignStmt) {
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		return // x, y := f(), which can't be a context variable
+	}
+	for i, rhs := range stmt.Rhs {
+		ident, ok := rhs.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		obj, embed := checker.requestScoped(ident)
+		if obj == nil {
+			continue
+		}
+		root := _rootIdent(stmt.Lhs[i])
+		if root == nil {
+			continue
+		}
+		rootObj := checker.pass.TypesInfo.ObjectOf(root)
+		if rootObj == nil {
+			continue
+		}
+		var where string
+		switch {
+		case rootObj.Parent() == checker.pass.Pkg.Scope():
+			where = "a package-level variable"
+		case checker.receivers[rootObj] && root != stmt.Lhs[i]:
+			where = "a field of its receiver"
+		default:
+			continue
+		}
+		reportf(checker.pass, ident, CodeLeakedRequestContext,
+			"%s is request-scoped (it embeds %s) but is stored in %s, "+
+				"which may outlive the request; store typedcontext.Detach(%s) instead",
+			ident.Name, _shortTypeName(embed, checker.pass.Pkg), where, ident.Name)
+	}
+}
+
+// _runDetach lints that request-scoped contexts don't outlive the request.
+func _runDetach(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		checker := _detachChecker{pass, map[types.Object]bool{}}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if ok && funcDecl.Recv != nil && len(funcDecl.Recv.List[0].Names) > 0 {
+				recv := pass.TypesInfo.Defs[funcDecl.Recv.List[0].Names[0]]
+				if _, isPointer := recv.Type().(*types.Pointer); isPointer {
+					checker.receivers[recv] = true
+				}
+			}
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.GoStmt:
+				checker.checkGo(node)
+			case *ast.AssignStmt:
+				checker.checkAssign(node)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,102 @@
+package linter
+
+// This file defines the linter that code doesn't branch on the dynamic type
+// of a typed context: that it doesn't type-switch on a context, or compare
+// contexts for equality.  The point of typed contexts is that a function
+// declares the capabilities it needs, and works with any implementation;
+// code like
+//	switch ctx.(type) {
+//	case *prodContext: ...
+//	case *mockContext: ...
+//	}
+// defeats that, and hides requirements from the type system.  (Type
+// assertions to an interface, which is how a context is narrowed at a
+// dynamic boundary, are fine.)
+//
+// This is opt-in, and allowed within the packages that implement contexts
+// (by default, the typedcontext runtime package).
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextDynamicAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextdynamic",
+	Doc:  "reports type switches and equality comparisons on typed contexts",
+	Run:  _runDynamic,
+}
+
+// _dynamicAllowedPackages lists package-path prefixes in which we allow
+// inspecting contexts' dynamic types.
+var _dynamicAllowedPackages = stringList{"github.com/khan/typed-context/typedcontext"}
+
+func init() {
+	optIn(TypedContextDynamicAnalyzer)
+	TypedContextDynamicAnalyzer.Flags.Var(&_dynamicAllowedPackages, "allowpkgs",
+		"comma-separated list of package-path prefixes in which type switches "+
+			"and comparisons on contexts are allowed")
+}
+
+// _isContextExpr returns true if the given expression is a typed context
+// (other than a plain context.Context, whose dynamic type is none of our
+// business).
+func _isContextExpr(pass *analysis.Pass, expr ast.Expr) bool {
+	typ := pass.TypesInfo.TypeOf(expr)
+	return typ != nil && isContextType(typ) && !isContextRoot(typ)
+}
+
+// _isNil returns true if the given expression is the predeclared nil.
+func _isNil(pass *analysis.Pass, expr ast.Expr) bool {
+	tv, ok := pass.TypesInfo.Types[expr]
+	return ok && tv.IsNil()
+}
+
+// _runDynamic lints that code doesn't branch on contexts' dynamic types.
+func _runDynamic(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	if hasAnyPathPrefix(pass.Pkg.Path(), _dynamicAllowedPackages) {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.TypeSwitchStmt:
+				var assert *ast.TypeAssertExpr
+				switch stmt := node.Assign.(type) {
+				case *ast.ExprStmt: // switch ctx.(type)
+					assert, _ = stmt.X.(*ast.TypeAssertExpr)
+				case *ast.AssignStmt: // switch c := ctx.(type)
+					assert, _ = stmt.Rhs[0].(*ast.TypeAssertExpr)
+				}
+				if assert != nil && _isContextExpr(pass, assert.X) {
+					reportf(pass, assert, CodeDynamicType,
+						"type switch on typed context %s; request the "+
+							"capabilities you need instead of branching on "+
+							"the implementation", types.ExprString(assert.X))
+				}
+			case *ast.BinaryExpr:
+				if node.Op != token.EQL && node.Op != token.NEQ ||
+					_isNil(pass, node.X) || _isNil(pass, node.Y) {
+					return true
+				}
+				if _isContextExpr(pass, node.X) || _isContextExpr(pass, node.Y) {
+					reportf(pass, node, CodeDynamicType,
+						"comparison of typed contexts; request the "+
+							"capabilities you need instead of checking "+
+							"which implementation you have")
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
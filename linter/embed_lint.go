@@ -0,0 +1,145 @@
+package linter
+
+// This file defines the linter that typed context interfaces don't embed
+// interfaces which are already implied by their other embeds, like
+//	interface {
+//		RequestContext
+//		DatabaseContext
+//		context.Context
+//	}
+// where context.Context is redundant, since RequestContext already embeds it.
+// Listing it again does no harm to the type, but makes the declaration longer
+// and harder to read, and obscures which capabilities are really requested.
+//
+// An embed is redundant if some other embed of the same interface implements
+// it, the same test Usage.InterfaceWasUsed applies to uses.  If two embeds
+// imply each other (say, an interface and an alias of it), we report the
+// later one.  We suggest a fix which deletes the redundant embed.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextEmbedAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextembed",
+	Doc:  "reports embeds in typed context interfaces which are implied by other embeds",
+	Run:  _runEmbed,
+}
+
+// _embedFix returns a fix which deletes field i of the given interface's
+// method list.  If the embed is on a line of its own, we delete the whole
+// line, including any comments; otherwise we delete it along with the
+// separator on one side of it.
+func _embedFix(pass *analysis.Pass, ifaceType *ast.InterfaceType, i int) analysis.SuggestedFix {
+	fields := ifaceType.Methods.List
+	field := fields[i]
+	file := pass.Fset.File(field.Pos())
+
+	start, end := field.Pos(), field.End()
+	if field.Doc != nil {
+		start = field.Doc.Pos()
+	}
+	if field.Comment != nil {
+		end = field.Comment.End()
+	}
+	startLine, endLine := file.Line(start), file.Line(end)
+	ownLine := file.Line(ifaceType.Methods.Opening) < startLine &&
+		endLine < file.Line(ifaceType.Methods.Closing) &&
+		(i == 0 || file.Line(fields[i-1].End()) < startLine) &&
+		(i+1 == len(fields) || endLine < file.Line(fields[i+1].Pos()))
+
+	switch {
+	case ownLine:
+		start, end = file.LineStart(startLine), file.LineStart(endLine+1)
+	case i+1 < len(fields):
+		start, end = field.Pos(), fields[i+1].Pos()
+	case i > 0:
+		start, end = fields[i-1].End(), field.End()
+	default:
+		start, end = field.Pos(), field.End()
+	}
+
+	return analysis.SuggestedFix{
+		Message:   "Remove redundant embed " + types.ExprString(field.Type),
+		TextEdits: []analysis.TextEdit{{Pos: start, End: end}},
+	}
+}
+
+// _checkEmbeds reports any embeds of the given interface-type which are
+// implied by its other embeds.
+func _checkEmbeds(pass *analysis.Pass, ifaceType *ast.InterfaceType) {
+	typ := pass.TypesInfo.TypeOf(ifaceType)
+	if typ == nil || !isContextType(typ) {
+		return
+	}
+
+	type embed struct {
+		index int
+		typ   types.Type
+		iface *types.Interface
+	}
+	var embeds []embed
+	for i, field := range ifaceType.Methods.List {
+		if len(field.Names) > 0 {
+			continue // a method
+		}
+		embedTyp := pass.TypesInfo.TypeOf(field.Type)
+		if embedTyp == nil {
+			continue
+		}
+		iface, ok := embedTyp.Underlying().(*types.Interface)
+		if !ok || !iface.IsMethodSet() {
+			continue // a type constraint, not our business
+		}
+		embeds = append(embeds, embed{i, embedTyp, iface})
+	}
+
+	for j, redundant := range embeds {
+		for k, other := range embeds {
+			if k == j || !types.Implements(other.typ, redundant.iface) {
+				continue
+			}
+			// If they imply each other, keep the first.
+			if k > j && types.Implements(redundant.typ, other.iface) {
+				continue
+			}
+			field := ifaceType.Methods.List[redundant.index]
+			pass.Report(analysis.Diagnostic{
+				Pos:      field.Pos(),
+				Category: string(CodeRedundantEmbed),
+				Message: "embed of " + _shortTypeName(redundant.typ, pass.Pkg) +
+					" is redundant: it's implied by " +
+					_shortTypeName(other.typ, pass.Pkg) + "; remove it",
+				SuggestedFixes: []analysis.SuggestedFix{
+					_embedFix(pass, ifaceType, redundant.index),
+				},
+			})
+			break // one report per embed is plenty
+		}
+	}
+}
+
+// _runEmbed lints that typed context interfaces don't embed anything twice.
+//
+// Like the accessor check, this includes both named interfaces and inline
+// ones.
+func _runEmbed(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if ifaceType, ok := node.(*ast.InterfaceType); ok {
+				_checkEmbeds(pass, ifaceType)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
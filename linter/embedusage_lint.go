@@ -0,0 +1,300 @@
+package linter
+
+// This file defines the whole-program check for embeds of named composite
+// typed context interfaces which no function requesting the interface uses.
+// The interface linter shrinks each function's inline interface to what it
+// uses, but a shared interface like
+//	type HandlerContext interface {
+//		context.Context
+//		LoggerContext
+//		DatabaseContext
+//		SecretsContext
+//	}
+// is only reported at each function requesting it, if at all (a function
+// may use all of it by passing it along whole).  If none of them ever use
+// SecretsContext, it shouldn't be in HandlerContext at all.
+//
+// Like the dead interface check, a single pass can't tell: the functions
+// requesting an interface are mostly in other packages.  So the analyzer here
+// exports a fact for each package listing the named composite interfaces it
+// defines (those which declare no methods of their own, and embed two or
+// more interfaces), and, for each named interface its tracked contexts
+// request, which of the interface's embeds they use; FindUnusedEmbeds
+// aggregates the facts over the whole program.  (That's the -unusedembeds
+// mode of the linter command.)
+//
+// An embed is used by a context if any of its leaf interfaces (see
+// analysisengine.LeafInterfaces) is, as decided by the interface analyzer.
+// Interfaces nothing requests at all aren't reported here; -deadinterfaces
+// covers them.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// TypedContextEmbedUsageAnalyzer computes the facts used by
+// FindUnusedEmbeds.  It reports nothing itself, so it isn't in Analyzers.
+var TypedContextEmbedUsageAnalyzer = &analysis.Analyzer{
+	Name:      "typedcontextembedusage",
+	Doc:       "records which embeds of named typed context interfaces each package uses",
+	Run:       _runEmbedUsage,
+	FactTypes: []analysis.Fact{new(_embedUsageFact)},
+}
+
+// _embedDecl is an embed in the declaration of a composite interface.
+type _embedDecl struct {
+	// Name is the embedded type, as types.TypeString writes it with full
+	// package paths.
+	Name     string
+	Position token.Position
+}
+
+// _embedUsageFact is the package fact exported by
+// TypedContextEmbedUsageAnalyzer.  Interfaces are named as
+// "import/path.Name".
+type _embedUsageFact struct {
+	// Composites maps the named composite typed context interfaces defined
+	// in the package to their embeds, other than context roots.
+	Composites map[string][]_embedDecl
+	// Requested lists the named interfaces the package's contexts request,
+	// directly or via embeds.
+	Requested []string
+	// Used maps the named interfaces the package's contexts request to the
+	// names of those of their embeds which the contexts use.
+	Used map[string][]string
+}
+
+func (*_embedUsageFact) AFact() {}
+
+func (fact *_embedUsageFact) String() string {
+	return fmt.Sprintf("defines %d composite interfaces, requests %d",
+		len(fact.Composites), len(fact.Requested))
+}
+
+// _embedName returns the name of an embedded type as recorded in
+// _embedUsageFact.
+func _embedName(typ types.Type) string {
+	return types.TypeString(typ, nil)
+}
+
+// _compositeEmbeds returns the embeds, other than context roots, of the
+// given interface declaration, if it declares a named composite typed
+// context interface.
+func _compositeEmbeds(pass *analysis.Pass, spec *ast.TypeSpec) ([]_embedDecl, bool) {
+	ifaceType, ok := spec.Type.(*ast.InterfaceType)
+	if !ok || spec.Assign.IsValid() {
+		return nil, false
+	}
+	typ := pass.TypesInfo.TypeOf(spec.Name)
+	if typ == nil || !isContextType(typ) {
+		return nil, false
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok || iface.NumExplicitMethods() > 0 || iface.NumEmbeddeds() < 2 {
+		return nil, false
+	}
+
+	var embeds []_embedDecl
+	for _, field := range ifaceType.Methods.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		embed := pass.TypesInfo.TypeOf(field.Type)
+		if embed == nil || isContextRoot(embed) {
+			continue
+		}
+		embeds = append(embeds, _embedDecl{
+			Name:     _embedName(embed),
+			Position: pass.Fset.Position(field.Type.Pos()),
+		})
+	}
+	return embeds, true
+}
+
+// _addEmbedUsage records, for typ and each named interface it recursively
+// embeds, that it's requested, and which of its embeds info uses.
+func _addEmbedUsage(typ types.Type, info *analysisengine.Usage, requested map[string]bool, used map[string]map[string]bool) {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+	if named, ok := typ.(*types.Named); ok {
+		name := _qualifiedName(named)
+		requested[name] = true
+		for i := 0; i < iface.NumEmbeddeds(); i++ {
+			embed := iface.EmbeddedType(i)
+			for _, leaf := range analysisengine.LeafInterfaces(embed) {
+				if info.InterfaceWasUsed(leaf) {
+					if used[name] == nil {
+						used[name] = map[string]bool{}
+					}
+					used[name][_embedName(embed)] = true
+					break
+				}
+			}
+		}
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		_addEmbedUsage(iface.EmbeddedType(i), info, requested, used)
+	}
+}
+
+func _runEmbedUsage(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	fact := &_embedUsageFact{
+		Composites: map[string][]_embedDecl{},
+		Used:       map[string][]string{},
+	}
+
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				embeds, ok := _compositeEmbeds(pass, spec)
+				if !ok {
+					continue
+				}
+				named, ok := pass.TypesInfo.Defs[spec.Name].Type().(*types.Named)
+				if ok {
+					fact.Composites[_qualifiedName(named)] = embeds
+				}
+			}
+		}
+	}
+
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+	requested := map[string]bool{}
+	used := map[string]map[string]bool{}
+	for _, obj := range tracker.Objects() {
+		info := tracker.Usage(obj)
+		if tracker.IsAlias(obj) || info.DerivedFrom() != nil {
+			continue // counted toward the context it shares a Usage with
+		}
+		_addEmbedUsage(obj.Type(), info, requested, used)
+	}
+	for name := range requested {
+		fact.Requested = append(fact.Requested, name)
+	}
+	sort.Strings(fact.Requested)
+	for name, embeds := range used {
+		for embed := range embeds {
+			fact.Used[name] = append(fact.Used[name], embed)
+		}
+		sort.Strings(fact.Used[name])
+	}
+
+	pass.ExportPackageFact(fact)
+	return nil, nil
+}
+
+// UnusedEmbed is an embed of a named composite typed context interface which
+// no function requesting the interface uses.
+type UnusedEmbed struct {
+	// Interface is the name of the composite interface, as
+	// "import/path.Name".
+	Interface string
+	// Embed is the name of the embedded interface.
+	Embed string
+	// Position is the position of the embed in the interface's declaration.
+	Position token.Position
+}
+
+// FindUnusedEmbeds returns the embeds of the named composite typed context
+// interfaces defined in the packages matching the given patterns which no
+// context requesting the interface, in those packages or their dependencies
+// (including their tests), uses, sorted by position.
+//
+// Like FindDeadInterfaces, it should be run over the whole program: an embed
+// used only by some package not matched by the patterns will be reported.
+func FindUnusedEmbeds(patterns ...string) ([]UnusedEmbed, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(
+		[]*analysis.Analyzer{TypedContextEmbedUsageAnalyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	composites := map[string][]_embedDecl{}
+	requested := map[string]bool{}
+	used := map[string]map[string]bool{}
+	for act := range graph.All() {
+		if act.Err != nil {
+			return nil, act.Err
+		}
+		var fact _embedUsageFact
+		if !act.PackageFact(act.Package.Types, &fact) {
+			continue
+		}
+		if act.IsRoot {
+			for name, embeds := range fact.Composites {
+				composites[name] = embeds
+			}
+		}
+		for _, name := range fact.Requested {
+			requested[name] = true
+		}
+		for name, embeds := range fact.Used {
+			if used[name] == nil {
+				used[name] = map[string]bool{}
+			}
+			for _, embed := range embeds {
+				used[name][embed] = true
+			}
+		}
+	}
+
+	var unused []UnusedEmbed
+	for name, embeds := range composites {
+		if !requested[name] {
+			continue // dead; see FindDeadInterfaces
+		}
+		for _, embed := range embeds {
+			if !used[name][embed.Name] {
+				unused = append(unused, UnusedEmbed{name, embed.Name, embed.Position})
+			}
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		if unused[i].Position.Filename != unused[j].Position.Filename {
+			return unused[i].Position.Filename < unused[j].Position.Filename
+		}
+		return unused[i].Position.Offset < unused[j].Position.Offset
+	})
+	return unused, nil
+}
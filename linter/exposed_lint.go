@@ -0,0 +1,109 @@
+package linter
+
+// This file defines the linter that exported functions don't request
+// unexported interfaces in their contexts.  If an exported function takes
+//	ctx interface { context.Context; loggerContext }
+// callers in other packages can satisfy the requirement, but can't name it:
+// they can't declare that they need whatever F needs, so they have to spell
+// out loggerContext's embeds themselves, and keep them in sync by hand.
+//
+// Specifically, we report exported functions (and exported methods of
+// exported types) any of whose context parameters, as seen from another
+// package (see _expandUnexportedNames), mention an unexported named
+// interface.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextExposedAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextexposed",
+	Doc:  "enforces that exported functions don't request unexported context interfaces",
+	Run:  _runExposed,
+}
+
+// _unexportedMentions returns the unexported named interfaces which callers
+// in other packages would have to provide, but can't name, to pass a context
+// of the given type.
+func _unexportedMentions(typ types.Type, pkg *types.Package) []types.Type {
+	var unexported []types.Type
+	for _, mention := range _expandUnexportedNames(typ, pkg) {
+		if named, ok := mention.(*types.Named); ok && !named.Obj().Exported() {
+			unexported = append(unexported, mention)
+		}
+	}
+	return unexported
+}
+
+// _isExportedFunc returns true if the given function can be called by name
+// from another package: it's exported, and if it's a method, so is its
+// receiver type.
+func _isExportedFunc(funcDecl *ast.FuncDecl) bool {
+	if !funcDecl.Name.IsExported() {
+		return false
+	}
+	return funcDecl.Recv == nil || ast.IsExported(_receiverTypeName(funcDecl))
+}
+
+// _receiverTypeName returns the name of the receiver type of the given
+// method, without any pointer or type parameters, or "" if it's not a method.
+func _receiverTypeName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+	typ := funcDecl.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.IndexExpr: // generic receiver, T[P]
+			typ = t.X
+		case *ast.IndexListExpr: // generic receiver, T[P, Q]
+			typ = t.X
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// _runExposed lints that exported functions' contexts can be named by their
+// callers.
+func _runExposed(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || !_isExportedFunc(funcDecl) {
+				continue
+			}
+			for _, field := range funcDecl.Type.Params.List {
+				typ := pass.TypesInfo.TypeOf(field.Type)
+				if typ == nil || !isContextType(typ) {
+					continue
+				}
+				unexported := _unexportedMentions(typ, pass.Pkg)
+				if len(unexported) == 0 {
+					continue
+				}
+				reportf(pass, field.Type, CodeUnexportedContext,
+					"exported function %s requests unexported interface(s) %s, "+
+						"which callers in other packages can't name; "+
+						"export a named interface instead",
+					funcDecl.Name.Name, _formatTypeList(unexported, pass.Pkg))
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,152 @@
+package linter
+
+// This file defines the linter that providers extracted from typed contexts
+// keep their types.  A dependency-injection constructor, like
+//	func NewHandler(ctx interface {
+//		LoggerContext
+//		DatabaseContext
+//	}) *Handler {
+//		return &Handler{logger: ctx.Logger(), db: ctx.Database()}
+//	}
+// is a context factory: it extracts the providers the struct needs from the
+// context, and stores them in its fields, so that the struct's methods needn't
+// take a context at all.  Calling ctx.Logger() is a use of LoggerContext, as
+// any accessor call is, so the interface analyzer checks such constructors
+// like any other function.  But the struct's fields are now what document its
+// dependencies, and they only do so if each has the type the accessor
+// returns: a field `db any`, or of some interface the provider happens to
+// implement, hides what's in it, and can't be traced back to DatabaseContext.
+//
+// So we look at each provider extraction -- an accessor of a typed context
+// (a method, other than those of context.Context, taking no arguments and
+// returning one value), called or as a method value, stored in a struct
+// field by assignment or in a composite literal -- and report it if the
+// field's type isn't that of the accessor's result (or, for a method value,
+// the accessor's signature).
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextExtractAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextextract",
+	Doc:  "reports providers extracted from typed contexts into struct fields of other types",
+	Run:  _runExtract,
+}
+
+// _extractedProvider returns the accessor called (or taken as a method value)
+// by expr, if it extracts a provider from a typed context, and the type a
+// field storing it should have.
+func _extractedProvider(expr ast.Expr, info *types.Info) (*types.Func, types.Type) {
+	expr = ast.Unparen(expr)
+	call, isCall := expr.(*ast.CallExpr)
+	if isCall {
+		if len(call.Args) != 0 {
+			return nil, nil
+		}
+		expr = ast.Unparen(call.Fun)
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	selection := info.Selections[selector]
+	if selection == nil || selection.Kind() != types.MethodVal ||
+		!isContextType(selection.Recv()) {
+		return nil, nil
+	}
+	method := selection.Obj().(*types.Func)
+	sig := method.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 ||
+		analysisengine.IsContextMethod(method) {
+		return nil, nil
+	}
+	if isCall {
+		return method, sig.Results().At(0).Type()
+	}
+	return method, selection.Type()
+}
+
+// _storedFields calls store for each value stored in a struct field in the
+// given file, by assignment or in a composite literal, with the field.
+func _storedFields(file *ast.File, info *types.Info, store func(field *types.Var, value ast.Expr)) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				selector, ok := ast.Unparen(lhs).(*ast.SelectorExpr)
+				if !ok {
+					continue
+				}
+				selection := info.Selections[selector]
+				if selection == nil || selection.Kind() != types.FieldVal {
+					continue
+				}
+				store(selection.Obj().(*types.Var), node.Rhs[i])
+			}
+		case *ast.CompositeLit:
+			typ := info.TypeOf(node)
+			if typ == nil {
+				return true
+			}
+			if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+				typ = ptr.Elem() // &T{...} elided in a slice literal
+			}
+			st, ok := typ.Underlying().(*types.Struct)
+			if !ok {
+				return true
+			}
+			for i, elt := range node.Elts {
+				keyValue, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					if i < st.NumFields() {
+						store(st.Field(i), elt)
+					}
+					continue
+				}
+				key, ok := keyValue.Key.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if field, ok := info.Uses[key].(*types.Var); ok {
+					store(field, keyValue.Value)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// _runExtract lints that providers extracted from typed contexts are stored
+// in fields of their own types.
+func _runExtract(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	qualifier := types.RelativeTo(pass.Pkg)
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		_storedFields(file, pass.TypesInfo, func(field *types.Var, value ast.Expr) {
+			accessor, want := _extractedProvider(value, pass.TypesInfo)
+			if accessor == nil || types.Identical(field.Type(), want) {
+				return
+			}
+			reportf(pass, value, CodeMismatchedProvider,
+				"field %s stores the provider from %s as %s, not %s; declare it "+
+					"as %s, so that it says what the struct depends on",
+				field.Name(), accessor.Name(), types.TypeString(field.Type(), qualifier),
+				types.TypeString(want, qualifier), types.TypeString(want, qualifier))
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,218 @@
+package linter
+
+// This file defines the linter that struct fields holding typed contexts
+// request only what's used.  Storing a context in a struct is usually a
+// mistake (see detach_lint.go), but it's the right thing for, say, a
+// request-scoped handler whose methods all run in that request:
+//	type handler struct {
+//		ctx interface {
+//			LoggerContext
+//			DatabaseContext
+//		}
+//	}
+// Like a parameter, such a field should request only the interfaces its
+// uses need, so that what the struct depends on is visible, and it can be
+// built from whatever context its creator has.  The interface analyzer
+// checks parameters; here we check fields, the same way: each selector of
+// the field, like h.ctx, is a use of it, as an identifier of a parameter
+// would be (see analysisengine.Tracker.TrackObject).
+//
+// We only check unexported fields (other packages may use exported ones),
+// and not embedded ones, whose methods are promoted to the struct's own.
+// And we skip fields whose value escapes in ways the tracker doesn't follow:
+// copied to a new variable (`ctx := h.ctx`), or its address taken, say.
+// Writes to the field aren't uses; a field that's only written is reported
+// by unused-field linters, not us.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextFieldAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextfield",
+	Doc:  "reports struct fields whose typed context interfaces are wider than their uses need",
+	Run:  _runField,
+}
+
+// _contextField is a struct field we check.
+type _contextField struct {
+	field *types.Var
+	// structName is the name of the struct type declaring the field.
+	structName string
+	// read is set if the field is ever read, and escapes if it's used in a
+	// way we don't follow (see the top of this file).
+	read, escapes bool
+}
+
+// _contextFields returns the fields of the struct types declared in the
+// given files which we check: unexported, not embedded, and of a typed
+// context type.
+func _contextFields(pass *analysis.Pass, files []*ast.File) map[*types.Var]*_contextField {
+	fields := map[*types.Var]*_contextField{}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			structType, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range structType.Fields.List {
+				for _, name := range field.Names {
+					obj, ok := pass.TypesInfo.Defs[name].(*types.Var)
+					if !ok || obj.Exported() || obj.Name() == "_" || !isContextType(obj.Type()) {
+						continue
+					}
+					if _, ok := obj.Type().(*types.TypeParam); ok {
+						continue
+					}
+					if len(_distinctLeaves(obj.Type())) == 0 {
+						continue // just context.Context
+					}
+					fields[obj] = &_contextField{field: obj, structName: spec.Name.Name}
+				}
+			}
+			return true
+		})
+	}
+	return fields
+}
+
+// _followedUse returns whether the tracker follows the use of a field via
+// the given selector, whose parent node is parent, and whether it's a read.
+func _followedUse(selector ast.Expr, parent ast.Node) (followed, read bool) {
+	switch parent := parent.(type) {
+	case *ast.CallExpr:
+		for _, arg := range parent.Args {
+			if arg == selector {
+				return true, true
+			}
+		}
+	case *ast.SelectorExpr: // h.ctx.Logger(), or a method value
+		return parent.X == selector, true
+	case *ast.TypeAssertExpr:
+		return true, true
+	case *ast.AssignStmt:
+		for _, lhs := range parent.Lhs {
+			if lhs == selector {
+				return parent.Tok == token.ASSIGN, false
+			}
+		}
+		return parent.Tok == token.ASSIGN, true
+	case *ast.ValueSpec:
+		return parent.Type != nil, true
+	case *ast.ReturnStmt, *ast.CompositeLit:
+		return true, true
+	case *ast.KeyValueExpr:
+		return parent.Value == selector, true
+	}
+	return false, true
+}
+
+// _findFieldUses marks the given fields read, or escaping, according to
+// their uses in the given files.
+func _findFieldUses(pass *analysis.Pass, files []*ast.File, fields map[*types.Var]*_contextField) {
+	for _, file := range files {
+		var stack []ast.Node
+		ast.Inspect(file, func(node ast.Node) bool {
+			if node == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			stack = append(stack, node)
+			selector, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			selection := pass.TypesInfo.Selections[selector]
+			if selection == nil || selection.Kind() != types.FieldVal {
+				return true
+			}
+			field := fields[selection.Obj().(*types.Var)]
+			if field == nil {
+				return true
+			}
+			// Find the parent, looking through parentheses.
+			var expr ast.Expr = selector
+			parent := ast.Node(nil)
+			for i := len(stack) - 2; i >= 0; i-- {
+				if paren, ok := stack[i].(*ast.ParenExpr); ok {
+					expr = paren
+					continue
+				}
+				parent = stack[i]
+				break
+			}
+			followed, read := _followedUse(expr, parent)
+			field.escapes = field.escapes || !followed
+			field.read = field.read || read
+			return true
+		})
+	}
+}
+
+// _runField lints that struct fields request only what's used.
+func _runField(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, file := range pass.Files {
+		if !_skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			files = append(files, file)
+		}
+	}
+	fields := _contextFields(pass, files)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	_findFieldUses(pass, pass.Files, fields)
+
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	for obj, field := range fields {
+		if field.read && !field.escapes {
+			tracker.TrackObject(obj)
+		}
+	}
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+
+	for _, obj := range tracker.Objects() {
+		field := fields[obj.(*types.Var)]
+		_, unused, _ := tracker.Usage(obj).Problems()
+		var leaves []types.Type
+		for _, leaf := range unused {
+			if !isContextRoot(leaf) && !_containsLeaf(leaves, leaf) {
+				leaves = append(leaves, leaf)
+			}
+		}
+		switch {
+		case len(leaves) == 0:
+		case len(leaves) == len(_distinctLeaves(obj.Type())):
+			reportf(pass, obj, CodeWideField,
+				"no interfaces requested by field %s of %s are used; make it a context.Context",
+				obj.Name(), field.structName)
+		default:
+			reportf(pass, obj, CodeWideField,
+				"field %s of %s requests but does not use interface(s) %s; "+
+					"remove to use the smallest possible interface",
+				obj.Name(), field.structName, _formatTypeList(leaves, pass.Pkg))
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,349 @@
+package linter
+
+// This file defines the linter that tests don't hand the functions they test
+// bigger contexts than they need.  The other analyzers leave tests alone by
+// default (see _skipFile): a test helper may well take a big context, so it
+// can be used with many functions under test.  But a test like
+//	func TestDoTheThing(t *testing.T) {
+//		DoTheThing(GetContextWithAllTheMocks(), "thing")
+//	}
+// where DoTheThing only requests interface{ LoggerContext; SecretsContext },
+// hides what the function depends on: if it later starts using the
+// database, the test still passes, with whatever the all-the-mocks fixture
+// happens to provide.
+//
+// So, in _test.go files only, we look at calls of fixture builders --
+// functions returning a typed context interface -- whose result is passed
+// directly to functions requesting typed contexts, or assigned to a variable
+// which is only ever passed to them, and report the fixture if it provides
+// leaf interfaces (see analysisengine.LeafInterfaces) none of those
+// functions request.  If the package, or the fixture's, has a narrower
+// fixture which takes the same arguments and provides everything needed, we
+// suggest the narrowest.
+//
+// This analyzer is opt-in.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextFixtureAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextfixture",
+	Doc:  "reports tests passing contexts from fixtures which provide more than the function under test requests",
+	Run:  _runFixture,
+}
+
+func init() {
+	optIn(TypedContextFixtureAnalyzer)
+}
+
+// _fixtureResult returns the typed context interface fn returns, if it's a
+// fixture builder: a non-generic function (not a method) returning just that.
+func _fixtureResult(fn *types.Func) (types.Type, bool) {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil || sig.TypeParams().Len() > 0 || sig.Results().Len() != 1 {
+		return nil, false
+	}
+	result := sig.Results().At(0).Type()
+	if _, ok := result.Underlying().(*types.Interface); !ok || !isContextType(result) {
+		return nil, false
+	}
+	return result, true
+}
+
+// _fixtureCall returns the fixture builder expr calls, if any.
+func _fixtureCall(expr ast.Expr, typesInfo *types.Info) (*ast.CallExpr, *types.Func) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return nil, nil
+	}
+	fn, ok := lintutil.ObjectFor(call.Fun, typesInfo).(*types.Func)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := _fixtureResult(fn); !ok {
+		return nil, nil
+	}
+	return call, fn
+}
+
+// _fixtureUse is a call of a fixture builder whose result goes only to
+// functions requesting typed contexts.
+type _fixtureUse struct {
+	call    *ast.CallExpr
+	fixture *types.Func
+	// needs are the types of the parameters the result is passed as.
+	needs []types.Type
+	// unneeded says who doesn't need the rest, like "which DoTheThing
+	// doesn't need".
+	unneeded string
+	// escapes is set if the result (via the variable) is used other than
+	// as such an argument, in which case we don't know what it needs.
+	escapes bool
+}
+
+// _contextParamType returns the type of the i'th parameter of the given
+// call, if it's a typed context interface (not a type parameter).
+func _contextParamType(call *ast.CallExpr, i int, typesInfo *types.Info) types.Type {
+	sig, ok := typesInfo.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		return nil
+	}
+	paramType := analysisengine.ParamTypeAt(call, sig, i)
+	if paramType == nil {
+		return nil
+	}
+	if _, ok := paramType.(*types.TypeParam); ok {
+		return nil
+	}
+	if _, ok := paramType.Underlying().(*types.Interface); !ok || !isContextType(paramType) {
+		return nil
+	}
+	return paramType
+}
+
+// _fixtureUses returns the uses of fixtures in the given file, in order of
+// position.
+func _fixtureUses(file *ast.File, typesInfo *types.Info) []*_fixtureUse {
+	var uses []*_fixtureUse
+	byVar := map[types.Object]*_fixtureUse{}
+	// accounted are the uses of those variables as arguments.
+	accounted := map[*ast.Ident]bool{}
+
+	assigned := func(lhs *ast.Ident, rhs ast.Expr) {
+		if call, fixture := _fixtureCall(rhs, typesInfo); call != nil {
+			if obj := typesInfo.Defs[lhs]; obj != nil {
+				use := &_fixtureUse{
+					call: call, fixture: fixture,
+					unneeded: "which the uses of " + lhs.Name + " don't need",
+				}
+				byVar[obj] = use
+				uses = append(uses, use)
+			}
+		}
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE && len(node.Lhs) == 1 && len(node.Rhs) == 1 {
+				if lhs, ok := node.Lhs[0].(*ast.Ident); ok {
+					assigned(lhs, node.Rhs[0])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(node.Names) == 1 && len(node.Values) == 1 {
+				assigned(node.Names[0], node.Values[0])
+			}
+		case *ast.CallExpr:
+			for i, arg := range node.Args {
+				paramType := _contextParamType(node, i, typesInfo)
+				if paramType == nil {
+					continue
+				}
+				if call, fixture := _fixtureCall(arg, typesInfo); call != nil {
+					calleeName := types.ExprString(node.Fun)
+					if callee := lintutil.ObjectFor(node.Fun, typesInfo); callee != nil {
+						calleeName = callee.Name()
+					}
+					uses = append(uses, &_fixtureUse{
+						call: call, fixture: fixture,
+						needs:    []types.Type{paramType},
+						unneeded: "which " + calleeName + " doesn't need",
+					})
+				} else if ident, ok := ast.Unparen(arg).(*ast.Ident); ok {
+					if use := byVar[typesInfo.Uses[ident]]; use != nil {
+						use.needs = append(use.needs, paramType)
+						accounted[ident] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	// Any other use of the variables -- a method call, reassignment, being
+	// returned or captured -- means we don't know what they need.
+	ast.Inspect(file, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && !accounted[ident] {
+			if use := byVar[typesInfo.Uses[ident]]; use != nil {
+				use.escapes = true
+			}
+		}
+		return true
+	})
+	return uses
+}
+
+// _containsLeaf returns true if leaves contains leaf (see
+// analysisengine.SameType).
+func _containsLeaf(leaves []types.Type, leaf types.Type) bool {
+	for _, other := range leaves {
+		if analysisengine.SameType(leaf, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// _sameParams returns true if the two signatures take the same parameters.
+func _sameParams(sig, other *types.Signature) bool {
+	if sig.Variadic() != other.Variadic() || sig.Params().Len() != other.Params().Len() {
+		return false
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		if !types.Identical(sig.Params().At(i).Type(), other.Params().At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// _narrowerFixture returns the fixture, in pkg or the package of the given
+// fixture, which takes the same arguments as it and provides all of needed,
+// with the fewest leaves, if it's fewer than provided.
+func _narrowerFixture(fixture *types.Func, pkg *types.Package, needed []types.Type, provided int) *types.Func {
+	var best *types.Func
+	bestLeaves := provided
+	scopes := []*types.Package{pkg}
+	if fixture.Pkg() != nil && fixture.Pkg() != pkg {
+		scopes = append(scopes, fixture.Pkg())
+	}
+	for _, scopePkg := range scopes {
+		for _, name := range scopePkg.Scope().Names() {
+			candidate, ok := scopePkg.Scope().Lookup(name).(*types.Func)
+			if !ok || candidate == fixture || (scopePkg != pkg && !candidate.Exported()) {
+				continue
+			}
+			result, ok := _fixtureResult(candidate)
+			if !ok || !_sameParams(candidate.Type().(*types.Signature), fixture.Type().(*types.Signature)) {
+				continue
+			}
+			leaves := _distinctLeaves(result)
+			if len(leaves) >= bestLeaves {
+				continue
+			}
+			covers := true
+			for _, leaf := range needed {
+				covers = covers && _containsLeaf(leaves, leaf)
+			}
+			if covers {
+				best, bestLeaves = candidate, len(leaves)
+			}
+		}
+	}
+	return best
+}
+
+// _leafNames returns the short names of the given leaves, sorted and joined.
+func _leafNames(leaves []types.Type, pkg *types.Package) string {
+	names := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		names[i] = _shortTypeName(leaf, pkg)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// _funcName returns the name of fn as referred to from pkg.
+func _funcName(fn *types.Func, pkg *types.Package) string {
+	if fn.Pkg() == nil || fn.Pkg() == pkg {
+		return fn.Name()
+	}
+	return fn.Pkg().Name() + "." + fn.Name()
+}
+
+// _checkFixtureUse reports the given use of a fixture, if it provides more
+// than is needed.
+func _checkFixtureUse(pass *analysis.Pass, use *_fixtureUse) {
+	if use.escapes || len(use.needs) == 0 {
+		return
+	}
+	result, _ := _fixtureResult(use.fixture)
+	provided := _distinctLeaves(result)
+	var needed []types.Type
+	for _, need := range use.needs {
+		for _, leaf := range _distinctLeaves(need) {
+			if !_containsLeaf(provided, leaf) {
+				return // satisfied some other way; we can't tell what's extra
+			}
+			if !_containsLeaf(needed, leaf) {
+				needed = append(needed, leaf)
+			}
+		}
+	}
+	var extra []types.Type
+	for _, leaf := range provided {
+		if !_containsLeaf(needed, leaf) {
+			extra = append(extra, leaf)
+		}
+	}
+	if len(extra) == 0 {
+		return
+	}
+
+	diagnostic := analysis.Diagnostic{
+		Pos:      use.call.Pos(),
+		End:      use.call.End(),
+		Category: string(CodeWideFixture),
+	}
+	suggestion := "use a fixture providing just " + _leafNames(needed, pass.Pkg)
+	if len(needed) == 0 {
+		suggestion = "a plain context.Context will do"
+	}
+	if narrower := _narrowerFixture(use.fixture, pass.Pkg, needed, len(provided)); narrower != nil {
+		name := _funcName(narrower, pass.Pkg)
+		suggestion = "use " + name + "() instead"
+		// We can swap in the narrower fixture if it's referred to the
+		// same way: both unqualified, or both from the same package.
+		var target ast.Expr
+		switch fun := ast.Unparen(use.call.Fun).(type) {
+		case *ast.Ident:
+			if narrower.Pkg() == pass.Pkg {
+				target = fun
+			}
+		case *ast.SelectorExpr:
+			if narrower.Pkg() == use.fixture.Pkg() && narrower.Pkg() != pass.Pkg {
+				target = fun.Sel
+			}
+		}
+		if target != nil {
+			diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: "Use " + name + "()",
+				TextEdits: []analysis.TextEdit{{
+					Pos: target.Pos(), End: target.End(), NewText: []byte(narrower.Name()),
+				}},
+			}}
+		}
+	}
+	diagnostic.Message = _funcName(use.fixture, pass.Pkg) + "() provides " +
+		_leafNames(extra, pass.Pkg) + ", " + use.unneeded + "; " + suggestion
+	pass.Report(diagnostic)
+}
+
+// _runFixture lints that tests use fixtures no bigger than needed.
+func _runFixture(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		filename := pass.Fset.File(file.Pos()).Name()
+		// Unlike the others, this one is only for tests.
+		if !strings.HasSuffix(filename, "_test.go") || hasAnyPathPrefix(filename, settings.exempt) {
+			continue
+		}
+		for _, use := range _fixtureUses(file, pass.TypesInfo) {
+			_checkFixtureUse(pass, use)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,120 @@
+package linter
+
+// This file defines helpers for deciding whether a function's parameters can
+// be changed along with its callers: they can't if it's referred to other
+// than by direct calls, since it's probably passed somewhere expecting some
+// signature, nor if it's a method implementing an interface, since the
+// interface fixes its signature.  -unusedroots (see unusedroots.go), the
+// redundant analyzer's fix and -minimize use them.
+
+import (
+	"go/ast"
+	"go/types"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// _funcValues returns the functions and methods referred to in the given
+// files other than by direct calls, like `handler := f`, `var h func(C) =
+// f`, or the method value `impl.Do`.
+func _funcValues(info *types.Info, files []*ast.File) map[types.Object]bool {
+	called := map[*ast.Ident]bool{}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				switch fun := ast.Unparen(call.Fun).(type) {
+				case *ast.Ident:
+					called[fun] = true
+				case *ast.SelectorExpr:
+					// A method expression, T.M(t), is called with the
+					// receiver as an extra argument: treat it as a value.
+					if selection := info.Selections[fun]; selection == nil || selection.Kind() != types.MethodExpr {
+						called[fun.Sel] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	values := map[types.Object]bool{}
+	for ident, obj := range info.Uses {
+		if _, ok := obj.(*types.Func); ok && !called[ident] {
+			values[obj] = true
+		}
+	}
+	return values
+}
+
+// _interfaceTypes returns the interfaces declared at the top level of the
+// given packages and of the packages they (transitively) import, along with
+// those written anywhere in the code described by the given infos, like
+// `var _ interface{ Do(LoggerContext) } = impl{}`.
+func _interfaceTypes(pkgs []*types.Package, infos []*types.Info) []*types.Interface {
+	var interfaces []*types.Interface
+	seen := map[*types.Interface]bool{}
+	add := func(typ types.Type) {
+		iface, ok := typ.Underlying().(*types.Interface)
+		if ok && iface.NumMethods() > 0 && !seen[iface] {
+			seen[iface] = true
+			interfaces = append(interfaces, iface)
+		}
+	}
+	visited := map[*types.Package]bool{}
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		if visited[pkg] {
+			return
+		}
+		visited[pkg] = true
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if named, ok := types.Unalias(obj.Type()).(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue // only its instantiations can be implemented
+			}
+			add(obj.Type())
+		}
+		for _, imported := range pkg.Imports() {
+			visit(imported)
+		}
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+	for _, info := range infos {
+		for _, tv := range info.Types {
+			if tv.Type != nil && tv.IsType() {
+				add(tv.Type)
+			}
+		}
+	}
+	return interfaces
+}
+
+// _implementsAny returns true if the given method (or its pointer-receiver
+// counterpart) implements a method of one of the given interfaces, or might:
+// for methods of generic types, we don't try to tell.
+func _implementsAny(method *types.Func, interfaces []*types.Interface) bool {
+	recv := method.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	recvType := recv.Type()
+	if named, ok := types.Unalias(lintutil.UnwrapMaybePointer(recvType)).(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return true
+	}
+	if _, ok := recvType.(*types.Pointer); !ok {
+		recvType = types.NewPointer(recvType)
+	}
+	for _, iface := range interfaces {
+		for i := 0; i < iface.NumMethods(); i++ {
+			if iface.Method(i).Name() == method.Name() && types.Implements(recvType, iface) {
+				return true
+			}
+		}
+	}
+	return false
+}
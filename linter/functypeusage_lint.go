@@ -0,0 +1,328 @@
+package linter
+
+// This file defines the whole-program check for the context parameters of
+// exported function-typed declarations, like
+//	type HandlerFunc func(ctx AppContext, r Request) error
+//	var OnShutdown func(ctx ShutdownContext)
+// which form a plugin API: other packages write functions to register as
+// HandlerFuncs, or to assign to OnShutdown.  The context such a declaration
+// gives its implementations should include just the interfaces they use,
+// between them; an interface none of them use is a dependency the API
+// claims, but doesn't have.
+//
+// With -typedcontextinterface.functypes, the interface linter checks these
+// declarations against the functions assigned to them in the same package,
+// but the implementations of an exported one are mostly in other packages.
+// So, like the unused embed check, the analyzer here exports a fact for each
+// package listing the exported function-typed declarations it defines, with
+// the leaf interfaces of their context parameters, and, for each such
+// declaration (in any package) which functions in the package are assigned
+// or converted to, which of those leaves the functions' parameters use;
+// FindWideFuncTypes aggregates the facts over the whole program.  (That's
+// the -widefunctypes mode of the linter command.)
+//
+// Declarations with no implementations, or to which some function we can't
+// see is assigned (say, one passed in from elsewhere), aren't reported.
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// TypedContextFuncTypeUsageAnalyzer computes the facts used by
+// FindWideFuncTypes.  It reports nothing itself, so it isn't in Analyzers.
+var TypedContextFuncTypeUsageAnalyzer = &analysis.Analyzer{
+	Name:      "typedcontextfunctypeusage",
+	Doc:       "records which interfaces the implementations of exported function types use",
+	Run:       _runFuncTypeUsage,
+	FactTypes: []analysis.Fact{new(_funcTypeUsageFact)},
+}
+
+// _funcTypeLeaf is a leaf interface of a context parameter of a
+// function-typed declaration.
+type _funcTypeLeaf struct {
+	// Name is the leaf, as types.TypeString writes it with full package
+	// paths.
+	Name string
+	// Short is the leaf as it's written in the declaring package.
+	Short string
+}
+
+// _funcTypeParam is a context parameter of a function-typed declaration.
+type _funcTypeParam struct {
+	// Index is the index of the parameter.
+	Index int
+	// Name is the name of the parameter, if it has one.
+	Name string
+	// Leaves are its leaf interfaces, other than context roots.
+	Leaves   []_funcTypeLeaf
+	Position token.Position
+}
+
+// _funcTypeUsageFact is the package fact exported by
+// TypedContextFuncTypeUsageAnalyzer.  Declarations are named as
+// "import/path.Name".
+type _funcTypeUsageFact struct {
+	// Declared maps the exported function-typed declarations of the package
+	// to their context parameters, other than those requesting just a
+	// context root.
+	Declared map[string][]_funcTypeParam
+	// Implementations maps the exported function-typed declarations which
+	// the package assigns functions to to how many of those functions it
+	// has the bodies of.
+	Implementations map[string]int
+	// Used maps the same declarations, and the indexes of their parameters,
+	// to the names of the leaf interfaces those functions use.
+	Used map[string]map[int][]string
+	// Opaque lists the declarations to which the package assigns some
+	// function whose body it doesn't have.
+	Opaque []string
+}
+
+func (*_funcTypeUsageFact) AFact() {}
+
+func (fact *_funcTypeUsageFact) String() string {
+	return fmt.Sprintf("declares %d function types, implements %d",
+		len(fact.Declared), len(fact.Implementations))
+}
+
+// _funcDeclName returns the name of a package-level declaration as
+// recorded in _funcTypeUsageFact.
+func _funcDeclName(obj types.Object) string {
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// _exportedFuncSignature returns the signature of obj, a package-level
+// declaration, if it's an exported named function type, or an exported
+// variable of an unnamed one, and nil otherwise.
+func _exportedFuncSignature(obj types.Object) *types.Signature {
+	if !obj.Exported() {
+		return nil
+	}
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams() != nil {
+			return nil
+		}
+		sig, _ := named.Underlying().(*types.Signature)
+		return sig
+	case *types.Var:
+		sig, _ := obj.Type().(*types.Signature)
+		return sig
+	}
+	return nil
+}
+
+// _funcTypeParams returns the context parameters of sig, other than those
+// requesting just a context root.
+func _funcTypeParams(pass *analysis.Pass, sig *types.Signature) []_funcTypeParam {
+	var params []_funcTypeParam
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		if !isContextType(param.Type()) || _isRootOnly(param.Type()) {
+			continue
+		}
+		var leaves []_funcTypeLeaf
+		for _, leaf := range _distinctLeaves(param.Type()) {
+			leaves = append(leaves, _funcTypeLeaf{
+				Name:  _embedName(leaf),
+				Short: _shortTypeName(leaf, pass.Pkg),
+			})
+		}
+		if len(leaves) == 0 {
+			continue
+		}
+		params = append(params, _funcTypeParam{
+			Index:    i,
+			Name:     param.Name(),
+			Leaves:   leaves,
+			Position: pass.Fset.Position(param.Pos()),
+		})
+	}
+	return params
+}
+
+func _runFuncTypeUsage(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	options.FunctionTypes = false // so implementations keep their own Usage
+	fact := &_funcTypeUsageFact{
+		Declared:        map[string][]_funcTypeParam{},
+		Implementations: map[string]int{},
+		Used:            map[string]map[int][]string{},
+	}
+
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		sig := _exportedFuncSignature(obj)
+		if sig == nil || _skipFile(pass.Fset.Position(obj.Pos()).Filename, pass.Pkg) {
+			continue
+		}
+		if params := _funcTypeParams(pass, sig); len(params) > 0 {
+			fact.Declared[_funcDeclName(obj)] = params
+		}
+	}
+
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+	for obj, impls := range tracker.ExportedFuncTypes(pass.Files) {
+		name := _funcDeclName(obj)
+		if impls.Opaque {
+			fact.Opaque = append(fact.Opaque, name)
+		}
+		fact.Implementations[name] = len(impls.Implementations)
+		used := map[int][]string{}
+		for i := 0; i < impls.Signature.Params().Len(); i++ {
+			typ := impls.Signature.Params().At(i).Type()
+			if !isContextType(typ) {
+				continue
+			}
+			for _, leaf := range _distinctLeaves(typ) {
+				for _, impl := range impls.Implementations {
+					info := tracker.Usage(impl.Params().At(i))
+					if info != nil && info.InterfaceWasUsed(leaf) {
+						used[i] = append(used[i], _embedName(leaf))
+						break
+					}
+				}
+			}
+		}
+		fact.Used[name] = used
+	}
+	sort.Strings(fact.Opaque)
+
+	pass.ExportPackageFact(fact)
+	return nil, nil
+}
+
+// WideFuncType is a context parameter of an exported function-typed
+// declaration which requests interfaces none of the functions assigned to it
+// use.
+type WideFuncType struct {
+	// Declaration is the name of the function type or variable, as
+	// "import/path.Name".
+	Declaration string
+	// Param is the name of the parameter, or "" if it has none.
+	Param string
+	// Unused are the leaf interfaces no implementation uses, as they're
+	// written in the declaring package.
+	Unused []string
+	// Implementations is how many functions are assigned to it.
+	Implementations int
+	// Position is the position of the parameter.
+	Position token.Position
+}
+
+// FindWideFuncTypes returns the context parameters of the exported
+// function-typed declarations in the packages matching the given patterns
+// which request interfaces none of the functions assigned or converted to
+// them, in those packages or their dependencies (including their tests),
+// use, sorted by position.
+//
+// Like FindUnusedEmbeds, it should be run over the whole program: an
+// interface used only by an implementation in some package not matched by
+// the patterns will be reported.
+func FindWideFuncTypes(patterns ...string) ([]WideFuncType, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(
+		[]*analysis.Analyzer{TypedContextFuncTypeUsageAnalyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := map[string][]_funcTypeParam{}
+	implementations := map[string]int{}
+	opaque := map[string]bool{}
+	used := map[string]map[int]map[string]bool{}
+	for act := range graph.All() {
+		if act.Err != nil {
+			return nil, act.Err
+		}
+		var fact _funcTypeUsageFact
+		if !act.PackageFact(act.Package.Types, &fact) {
+			continue
+		}
+		if act.IsRoot {
+			for name, params := range fact.Declared {
+				declared[name] = params
+			}
+		}
+		for name, count := range fact.Implementations {
+			implementations[name] += count
+		}
+		for _, name := range fact.Opaque {
+			opaque[name] = true
+		}
+		for name, params := range fact.Used {
+			if used[name] == nil {
+				used[name] = map[int]map[string]bool{}
+			}
+			for i, leaves := range params {
+				if used[name][i] == nil {
+					used[name][i] = map[string]bool{}
+				}
+				for _, leaf := range leaves {
+					used[name][i][leaf] = true
+				}
+			}
+		}
+	}
+
+	var wide []WideFuncType
+	for name, params := range declared {
+		if implementations[name] == 0 || opaque[name] {
+			continue
+		}
+		for _, param := range params {
+			var unused []string
+			for _, leaf := range param.Leaves {
+				if !used[name][param.Index][leaf.Name] {
+					unused = append(unused, leaf.Short)
+				}
+			}
+			if len(unused) > 0 {
+				wide = append(wide, WideFuncType{
+					Declaration:     name,
+					Param:           param.Name,
+					Unused:          unused,
+					Implementations: implementations[name],
+					Position:        param.Position,
+				})
+			}
+		}
+	}
+	sort.Slice(wide, func(i, j int) bool {
+		if wide[i].Position.Filename != wide[j].Position.Filename {
+			return wide[i].Position.Filename < wide[j].Position.Filename
+		}
+		return wide[i].Position.Offset < wide[j].Position.Offset
+	})
+	return wide, nil
+}
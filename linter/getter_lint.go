@@ -0,0 +1,116 @@
+package linter
+
+// This file defines the linter that the accessors of concrete typed contexts
+// are pure getters.  An implementation like
+//	func (c *appContext) Database() DatabaseInterface {
+//		c.mu.Lock()
+//		defer c.mu.Unlock()
+//		if c.db == nil {
+//			c.db = sql.Open(...)
+//		}
+//		return c.db
+//	}
+// makes every call of ctx.Database() slow, and possibly failing, where the
+// signature promises a cheap lookup; and contexts which are expensive to use
+// get hoarded rather than passed around.  Providers should be built up front,
+// or lazily with sync.Once or typedcontext.Lazy (as the constructors
+// typedcontext-gen generates with -lazy do).
+//
+// So we look at the methods of concrete types which implement
+// context.Context (see analysisengine.IsContextMethod) -- other than its own
+// methods -- which take no arguments and return a single value, as accessors
+// do, and report any I/O, locking, or mutation of state in them, as found by
+// lintutil.Effects.  Calls to I/O functions count only if they're in the
+// packages listed in -typedcontextgetter.iopkgs; the analysis doesn't follow
+// calls to helpers, nor look inside function literals (like the one passed to
+// sync.Once.Do).
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextGetterAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextgetter",
+	Doc:  "reports accessors of concrete typed contexts which do I/O, take locks, or mutate state",
+	Run:  _runGetter,
+}
+
+// _ioPackages lists package-path prefixes whose functions do I/O.
+var _ioPackages = stringList{"bufio", "database/sql", "io", "net", "os", "syscall"}
+
+func init() {
+	TypedContextGetterAnalyzer.Flags.Var(&_ioPackages, "iopkgs",
+		"comma-separated list of package-path prefixes whose functions and "+
+			"methods do I/O, which accessors of typed contexts may not call")
+}
+
+// _isConcreteContext returns true if typ is a concrete (non-interface) type
+// which, or a pointer to which, has all the methods of context.Context.
+func _isConcreteContext(typ types.Type) bool {
+	if _, ok := typ.Underlying().(*types.Interface); ok {
+		return false
+	}
+	methods := types.NewMethodSet(types.NewPointer(typ))
+	count := 0
+	for i := 0; i < methods.Len(); i++ {
+		if method, ok := methods.At(i).Obj().(*types.Func); ok && analysisengine.IsContextMethod(method) {
+			count++
+		}
+	}
+	return count == 4
+}
+
+// _contextAccessor returns the method declared by funcDecl, if it's an
+// accessor of a concrete typed context.
+func _contextAccessor(pass *analysis.Pass, funcDecl *ast.FuncDecl) (*types.Func, bool) {
+	if funcDecl.Recv == nil || funcDecl.Body == nil {
+		return nil, false
+	}
+	method, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+	if !ok || analysisengine.IsContextMethod(method) {
+		return nil, false
+	}
+	sig := method.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return nil, false
+	}
+	recv := lintutil.UnwrapMaybePointer(sig.Recv().Type())
+	return method, _isConcreteContext(recv)
+}
+
+// _runGetter lints that accessors of concrete typed contexts are pure
+// getters.
+func _runGetter(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			method, ok := _contextAccessor(pass, funcDecl)
+			if !ok {
+				continue
+			}
+			for _, effect := range lintutil.Effects(funcDecl.Body, pass.TypesInfo, _ioPackages) {
+				reportf(pass, effect.Node, CodeImpureAccessor,
+					"accessor %s %s (%s); accessors should be plain getters, "+
+						"so build the provider up front, or lazily with sync.Once "+
+						"or typedcontext.Lazy",
+					method.Name(), effect.Description, effect.Kind)
+			}
+		}
+	}
+	return nil, nil
+}
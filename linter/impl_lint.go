@@ -0,0 +1,233 @@
+package linter
+
+// This file defines the linter that structs implementing typed contexts
+// provide each accessor on purpose.  A struct like
+//	type MockContext struct {
+//		AppContext
+//		logger *Logger
+//	}
+//	func (c MockContext) Logger() *Logger { return c.logger }
+//	var _ AppContext = MockContext{}
+// compiles however many accessors AppContext has: all but Logger are
+// promoted from the embedded AppContext, which is often nil.  When someone
+// adds an accessor to AppContext, nothing fails until a caller of the new
+// accessor panics, far from the struct.
+//
+// So, for each struct asserted (by a package-level var _ I = ..., the usual
+// idiom) to implement a typed context interface I, we report
+//   - accessors of I which the struct only gets from an embedded interface
+//     other than the leaf interface declaring them (see
+//     analysisengine.LeafInterfaces): defining them on the struct, or
+//     embedding the leaf (like LoggerContext), says that the struct provides
+//     them deliberately.  Accessors promoted from embedded concrete types,
+//     which define them, are fine too.
+//   - accessors the struct defines which belong to a typed context interface
+//     (in this package, or one it imports) that none of its assertions
+//     include: often the leftovers of an accessor removed from I.
+// Methods of context.Context (and other roots) aren't accessors.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextImplAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextimpl",
+	Doc:  "reports structs implementing typed contexts which get accessors by accident, or define stray ones",
+	Run:  _runImpl,
+}
+
+// _accessor is an accessor method, and the leaf interface declaring it.
+type _accessor struct {
+	method *types.Func
+	leaf   types.Type
+}
+
+// _accessors returns the accessors of the given typed context interface, in
+// the order of its leaves.
+func _accessors(typ types.Type) []_accessor {
+	var accessors []_accessor
+	for _, leaf := range analysisengine.LeafInterfaces(typ) {
+		if isContextRoot(leaf) {
+			continue
+		}
+		iface := leaf.Underlying().(*types.Interface)
+		for i := 0; i < iface.NumExplicitMethods(); i++ {
+			accessors = append(accessors, _accessor{iface.ExplicitMethod(i), leaf})
+		}
+	}
+	return accessors
+}
+
+// _knownAccessors returns the accessors of the typed context interfaces
+// declared in pkg and the packages it imports, by name.  If several
+// interfaces declare an accessor of the same name, we keep the first.
+func _knownAccessors(pkg *types.Package) map[string]_accessor {
+	known := map[string]_accessor{}
+	for _, p := range append([]*types.Package{pkg}, pkg.Imports()...) {
+		scope := p.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !isContextType(obj.Type()) {
+				continue
+			}
+			if _, ok := obj.Type().Underlying().(*types.Interface); !ok {
+				continue
+			}
+			for _, accessor := range _accessors(obj.Type()) {
+				if _, ok := known[accessor.method.Name()]; !ok {
+					known[accessor.method.Name()] = accessor
+				}
+			}
+		}
+	}
+	return known
+}
+
+// _implAssertions returns the typed context interfaces each struct declared
+// in the package is asserted to implement, by its type name, and those type
+// names in order of position.
+func _implAssertions(pass *analysis.Pass) (map[*types.TypeName][]types.Type, []*types.TypeName) {
+	assertions := map[*types.TypeName][]types.Type{}
+	var structs []*types.TypeName
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				if valueSpec.Type == nil {
+					continue
+				}
+				ifaceType := pass.TypesInfo.TypeOf(valueSpec.Type)
+				if ifaceType == nil || !isContextType(ifaceType) {
+					continue
+				}
+				for i, value := range valueSpec.Values {
+					if i >= len(valueSpec.Names) || valueSpec.Names[i].Name != "_" {
+						continue
+					}
+					typ := pass.TypesInfo.TypeOf(value)
+					if ptr, ok := typ.(*types.Pointer); ok {
+						typ = ptr.Elem()
+					}
+					named, ok := types.Unalias(typ).(*types.Named)
+					if !ok || named.Obj().Pkg() != pass.Pkg {
+						continue
+					}
+					if _, ok := named.Underlying().(*types.Struct); !ok {
+						continue
+					}
+					if _, ok := assertions[named.Obj()]; !ok {
+						structs = append(structs, named.Obj())
+					}
+					assertions[named.Obj()] = append(assertions[named.Obj()], ifaceType)
+				}
+			}
+		}
+	}
+	return assertions, structs
+}
+
+// _promotedFrom returns the embedded field of the struct named by obj
+// through which it gets the given accessor, and the type declaring it, or
+// nil if the struct (or its pointer) defines it itself.
+func _promotedFrom(obj *types.TypeName, method *types.Func) (*types.Var, types.Type) {
+	found, index, _ := types.LookupFieldOrMethod(types.NewPointer(obj.Type()), false, method.Pkg(), method.Name())
+	if found == nil || len(index) < 2 {
+		return nil, nil
+	}
+	var field *types.Var
+	typ := obj.Type()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		st := typ.Underlying().(*types.Struct)
+		if field == nil {
+			field = st.Field(i)
+		}
+		typ = st.Field(i).Type()
+	}
+	return field, typ
+}
+
+// _declaresMethod returns true if the given interface declares the named
+// method itself, rather than by embedding.
+func _declaresMethod(typ types.Type, name string) bool {
+	iface := typ.Underlying().(*types.Interface)
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// _runImpl lints that structs asserted to implement typed contexts provide
+// their accessors deliberately, and no others.
+func _runImpl(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	assertions, structs := _implAssertions(pass)
+	if len(structs) == 0 {
+		return nil, nil
+	}
+	known := _knownAccessors(pass.Pkg)
+
+	for _, obj := range structs {
+		if _skipFile(pass.Fset.File(obj.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		asserted := map[string]bool{}
+		for _, iface := range assertions[obj] {
+			for _, accessor := range _accessors(iface) {
+				if asserted[accessor.method.Name()] {
+					continue
+				}
+				asserted[accessor.method.Name()] = true
+
+				field, declaring := _promotedFrom(obj, accessor.method)
+				if field == nil {
+					continue
+				}
+				if _, ok := declaring.Underlying().(*types.Interface); !ok ||
+					_declaresMethod(declaring, accessor.method.Name()) {
+					continue
+				}
+				reportf(pass, obj, CodeImplicitAccessor,
+					"%s gets accessor %s of %s only by embedding %s, which may not "+
+						"provide it; define it, or embed %s to delegate it deliberately",
+					obj.Name(), accessor.method.Name(), _shortTypeName(iface, pass.Pkg),
+					_shortTypeName(field.Type(), pass.Pkg),
+					_shortTypeName(accessor.leaf, pass.Pkg))
+			}
+		}
+
+		named := obj.Type().(*types.Named)
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+			accessor, ok := known[method.Name()]
+			if !ok || asserted[method.Name()] ||
+				!types.Identical(method.Type().(*types.Signature).Results(),
+					accessor.method.Type().(*types.Signature).Results()) ||
+				method.Type().(*types.Signature).Params().Len() != 0 {
+				continue
+			}
+			reportf(pass, obj, CodeStrayAccessor,
+				"%s defines %s, the accessor of %s, which none of its assertions "+
+					"include; remove it, or assert that %s implements %s",
+				obj.Name(), method.Name(), _shortTypeName(accessor.leaf, pass.Pkg),
+				obj.Name(), _shortTypeName(accessor.leaf, pass.Pkg))
+		}
+	}
+	return nil, nil
+}
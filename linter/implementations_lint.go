@@ -0,0 +1,138 @@
+package linter
+
+// This file defines the opt-in linter that interface methods request only
+// what their implementations use.  The interface analyzer judges the
+// implementations of an interface method together (see
+// analysisengine.Tracker's identifyInterfaceMethods): in
+//	type Store interface {
+//		Get(ctx interface {
+//			DatabaseContext
+//			LoggerContext
+//			SecretsContext
+//		}, key string) (string, error)
+//	}
+// the context must be the same type in every implementation, so it's fine
+// for dbStore.Get to use just DatabaseContext and memStore.Get just
+// LoggerContext, as long as between them they use everything.  But that's
+// only reported on the implementations, if at all (not if they all name
+// their context _), and it doesn't tell the owner of Store which
+// implementation forces each interface on every caller.
+//
+// So, judging each implementation in the package by itself, we report
+// interface methods whose context requests leaf interfaces (see
+// analysisengine.LeafInterfaces) which none of their implementations use.
+// With -typedcontextimplementations.explain, we also report, for each
+// interface method with a typed context, which implementations use each of
+// its leaves: an interface only one implementation uses is one to look at.
+// We report at the method, on interfaces declared in the package which have
+// implementations there; methods an interface gets by embedding are
+// reported where they're declared.
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextImplementationsAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextimplementations",
+	Doc:  "reports interface methods whose contexts request interfaces none of their implementations use",
+	Run:  _runImplementations,
+}
+
+// _explainImplementations says to report which implementations use each
+// leaf of each interface method's context.
+var _explainImplementations bool
+
+func init() {
+	TypedContextImplementationsAnalyzer.Flags.BoolVar(&_explainImplementations, "explain", false,
+		"also report, for each interface method, which of its implementations "+
+			"use each interface of its context")
+	optIn(TypedContextImplementationsAnalyzer)
+}
+
+// _implementationName returns the name of the given method, for a report,
+// like "dbStore.Get".
+func _implementationName(impl *ast.FuncDecl) string {
+	return _receiverTypeName(impl) + "." + impl.Name.Name
+}
+
+// _runImplementations lints that interface methods' contexts request only
+// what their implementations use.
+func _runImplementations(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	methods := analysisengine.InterfaceMethods(pass.Files, pass.TypesInfo)
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	// We need each implementation's own uses, which the usage analyzer's
+	// tracker merges.
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	options.SeparateImplementations = true
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+
+	for _, method := range methods {
+		if method.Method.Pkg() != pass.Pkg ||
+			!_declaresMethod(method.Interface.Type(), method.Method.Name()) ||
+			_skipFile(pass.Fset.File(method.Method.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		params := method.Method.Type().(*types.Signature).Params()
+		if params.Len() == 0 || !isContextType(params.At(0).Type()) {
+			continue
+		}
+		leaves := _distinctLeaves(params.At(0).Type())
+		if len(leaves) == 0 {
+			continue
+		}
+
+		var unused []types.Type
+		explanations := make([]string, len(leaves))
+		for i, leaf := range leaves {
+			var users []string
+			for _, impl := range method.Implementations {
+				usage := tracker.Usage(analysisengine.ImplementationParam(impl, pass.TypesInfo))
+				if usage != nil && usage.InterfaceWasUsed(leaf) {
+					users = append(users, _implementationName(impl))
+				}
+			}
+			if len(users) == 0 {
+				unused = append(unused, leaf)
+				users = []string{"none"}
+			}
+			explanations[i] = _shortTypeName(leaf, pass.Pkg) + " (" + strings.Join(users, ", ") + ")"
+		}
+
+		name := method.Interface.Name() + "." + method.Method.Name()
+		if len(unused) > 0 {
+			implNames := make([]string, len(method.Implementations))
+			for i, impl := range method.Implementations {
+				implNames[i] = _implementationName(impl)
+			}
+			reportf(pass, method.Method, CodeUnusedByImplementations,
+				"%s requests interface(s) %s, which none of its implementations "+
+					"(%s) use; remove them from its context",
+				name, _formatTypeList(unused, pass.Pkg), strings.Join(implNames, ", "))
+		}
+		if _explainImplementations {
+			reportf(pass, method.Method, CodeImplementationUses,
+				"implementations of %s use %s", name, strings.Join(explanations, ", "))
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package linter
+
+// This file defines the linter that typed context interfaces are declared in
+// designated packages.  For example, with
+//	-typedcontextlayout.rules='*Context=.../ctx'
+// every named interface whose name matches *Context must be declared in a
+// package whose path ends in /ctx, so that there's one obvious place to look
+// for (and reuse) them, rather than an ad hoc one in every package.
+//
+// Each rule is NAME=PACKAGE, where NAME is a glob (as for path.Match)
+// matching interface names and PACKAGE is a package-path pattern, where ...
+// matches any string (as for the go command).  An interface matching several
+// rules' names may be declared in a package matching any of them.  With no
+// rules, this reports nothing.
+
+import (
+	"fmt"
+	"go/types"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextLayoutAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextlayout",
+	Doc:  "enforces that typed context interfaces are declared in designated packages",
+	Run:  _runLayout,
+}
+
+// _layoutRules are the rules, as NAME=PACKAGE; see the top of the file.
+var _layoutRules stringList
+
+func init() {
+	TypedContextLayoutAnalyzer.Flags.Var(&_layoutRules, "rules",
+		"comma-separated list of rules NAME=PACKAGE, meaning interfaces whose "+
+			"name matches the glob NAME must be declared in a package whose "+
+			"path matches PACKAGE (where ... matches anything)")
+}
+
+// _layoutRule is a parsed rule.
+type _layoutRule struct {
+	name, pkg string
+	pkgRegexp *regexp.Regexp
+}
+
+// _packagePattern compiles the given package-path pattern, where ... matches
+// any string (as for the go command).
+func _packagePattern(pattern string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\.\.\.`, `.*`)
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// _parseLayoutRules parses rules of the form NAME=PACKAGE.
+func _parseLayoutRules(rules []string) ([]_layoutRule, error) {
+	var parsed []_layoutRule
+	for _, rule := range rules {
+		name, pkg, ok := strings.Cut(rule, "=")
+		if !ok || name == "" || pkg == "" {
+			return nil, fmt.Errorf("invalid layout rule %q: want NAME=PACKAGE", rule)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("invalid layout rule %q: %w", rule, err)
+		}
+		parsed = append(parsed, _layoutRule{name, pkg, _packagePattern(pkg)})
+	}
+	return parsed, nil
+}
+
+// _runLayout lints that interfaces are declared where the rules say.
+func _runLayout(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := _parseLayoutRules(settings.layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || !types.IsInterface(obj.Type()) {
+			continue
+		}
+		if _skipFile(pass.Fset.File(obj.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+
+		var allowed []string
+		placed := false
+		for _, rule := range rules {
+			if matched, _ := path.Match(rule.name, name); !matched {
+				continue
+			}
+			allowed = append(allowed, rule.pkg)
+			if rule.pkgRegexp.MatchString(pass.Pkg.Path()) {
+				placed = true
+			}
+		}
+		if len(allowed) > 0 && !placed {
+			reportf(pass, obj, CodeMisplacedInterface,
+				"interface %s must be declared in a package matching %s, "+
+					"not %s; move it there", name,
+				strings.Join(allowed, " or "), pass.Pkg.Path())
+		}
+	}
+	return nil, nil
+}
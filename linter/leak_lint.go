@@ -0,0 +1,259 @@
+package linter
+
+// This file defines the linter that contexts carrying sensitive interfaces
+// aren't passed to functions which format, serialize, or reflect over their
+// arguments, like
+//	func Handle(ctx AppContext) {
+//		log.Printf("handling %+v", ctx) // dumps AppContext's secrets
+//	}
+// where AppContext embeds SecretsContext.  The any analyzer lets typed
+// contexts be passed to fmt and log, since formatting a context is usually
+// harmless, but formatting (or marshaling, or walking with reflect) one
+// which holds secrets may print them.
+//
+// A value is sensitive if its type is an interface which is, or recursively
+// embeds, one of the interfaces in -typedcontextleak.sensitive (by default
+// SecretsContext, in any package), or a struct, pointer, slice, array or map
+// which holds one, like a handler with a ctx field.  Values lose their type
+// when they're converted to an interface like any, so we also follow local
+// variables assigned a sensitive value, or a composite literal holding one,
+// like
+//	args := []any{ctx}
+//	fmt.Println(args...)
+// We report sensitive values passed as arguments to the functions in
+// -typedcontextleak.sinks: by default those of fmt, log and log/slog,
+// json.Marshal and the like, and reflect.ValueOf.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextLeakAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextleak",
+	Doc:  "reports contexts carrying sensitive interfaces passed to formatting, serialization or reflection",
+	Run:  _runLeak,
+}
+
+var (
+	// _sensitiveInterfaces lists the sensitive interfaces, as
+	// "import/path.Name", or just "Name" to match in any package.
+	_sensitiveInterfaces = stringList{"SecretsContext"}
+	// _leakSinks lists the functions which may dump their arguments, as
+	// types.Func.FullName returns them, or "import/path.*" for all the
+	// functions and methods of a package.
+	_leakSinks = stringList{
+		"fmt.*",
+		"log.*",
+		"log/slog.*",
+		"encoding/json.Marshal",
+		"encoding/json.MarshalIndent",
+		"(*encoding/json.Encoder).Encode",
+		"encoding/xml.Marshal",
+		"encoding/xml.MarshalIndent",
+		"(*encoding/xml.Encoder).Encode",
+		"(*encoding/gob.Encoder).Encode",
+		"reflect.ValueOf",
+	}
+)
+
+func init() {
+	TypedContextLeakAnalyzer.Flags.Var(&_sensitiveInterfaces, "sensitive",
+		"comma-separated list of sensitive typed context interfaces, as "+
+			"import/path.Name, or Name for any package")
+	TypedContextLeakAnalyzer.Flags.Var(&_leakSinks, "sinks",
+		"comma-separated list of functions which may dump their arguments, "+
+			"like encoding/json.Marshal or (*encoding/json.Encoder).Encode, "+
+			"or import/path.* for all the functions of a package")
+}
+
+// _isLeakSink returns true if callee is one of _leakSinks.
+func _isLeakSink(callee *types.Func) bool {
+	name := callee.FullName()
+	for _, sink := range _leakSinks {
+		if path, ok := strings.CutSuffix(sink, ".*"); ok {
+			if callee.Pkg() != nil && callee.Pkg().Path() == path {
+				return true
+			}
+		} else if name == sink {
+			return true
+		}
+	}
+	return false
+}
+
+// _sensitiveIn returns the sensitive interface held by a value of type typ
+// (see the top of the file), or nil if there's none.  seen holds the named
+// types already visited.
+func _sensitiveIn(typ types.Type, seen map[*types.Named]bool) *types.Named {
+	typ = types.Unalias(typ)
+	if named, ok := typ.(*types.Named); ok {
+		if seen[named] {
+			return nil
+		}
+		seen[named] = true
+		if types.IsInterface(named) {
+			for _, name := range _sensitiveInterfaces {
+				if matchesQualifiedName(named, name) {
+					return named
+				}
+			}
+		}
+	}
+
+	switch under := typ.Underlying().(type) {
+	case *types.Interface:
+		for i := 0; i < under.NumEmbeddeds(); i++ {
+			if found := _sensitiveIn(under.EmbeddedType(i), seen); found != nil {
+				return found
+			}
+		}
+	case *types.Pointer:
+		return _sensitiveIn(under.Elem(), seen)
+	case *types.Slice:
+		return _sensitiveIn(under.Elem(), seen)
+	case *types.Array:
+		return _sensitiveIn(under.Elem(), seen)
+	case *types.Map:
+		return _sensitiveIn(under.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < under.NumFields(); i++ {
+			if found := _sensitiveIn(under.Field(i).Type(), seen); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// _leakChecker finds the sensitive values in a file.
+type _leakChecker struct {
+	pass *analysis.Pass
+	// tainted maps the local variables which have been assigned a
+	// sensitive value, though their type doesn't say so, to the sensitive
+	// interface.
+	tainted map[types.Object]*types.Named
+}
+
+// sensitive returns the sensitive interface held by the value of expr, or
+// nil if there's none.
+func (checker *_leakChecker) sensitive(expr ast.Expr) *types.Named {
+	expr = ast.Unparen(expr)
+	if typ := checker.pass.TypesInfo.TypeOf(expr); typ != nil {
+		if found := _sensitiveIn(typ, map[*types.Named]bool{}); found != nil {
+			return found
+		}
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return checker.tainted[checker.pass.TypesInfo.ObjectOf(expr)]
+	case *ast.UnaryExpr:
+		if expr.Op == token.AND {
+			return checker.sensitive(expr.X)
+		}
+	case *ast.CallExpr:
+		// A conversion, like any(ctx).
+		if tv, ok := checker.pass.TypesInfo.Types[expr.Fun]; ok && tv.IsType() && len(expr.Args) == 1 {
+			return checker.sensitive(expr.Args[0])
+		}
+	case *ast.CompositeLit:
+		for _, elt := range expr.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if found := checker.sensitive(elt); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// taint records that the variable ident, if it's a local one, is assigned
+// value.  It returns true if that newly taints it.
+func (checker *_leakChecker) taint(ident *ast.Ident, value ast.Expr) bool {
+	obj, ok := checker.pass.TypesInfo.ObjectOf(ident).(*types.Var)
+	if !ok || obj.IsField() || obj.Parent() == nil ||
+		obj.Parent() == checker.pass.Pkg.Scope() || checker.tainted[obj] != nil {
+		return false
+	}
+	if found := checker.sensitive(value); found != nil {
+		checker.tainted[obj] = found
+		return true
+	}
+	return false
+}
+
+// taintFile finds the tainted variables in file, following assignments
+// until there are no more.
+func (checker *_leakChecker) taintFile(file *ast.File) {
+	for changed := true; changed; {
+		changed = false
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) != len(node.Rhs) {
+					return true
+				}
+				for i, lhs := range node.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && checker.taint(ident, node.Rhs[i]) {
+						changed = true
+					}
+				}
+			case *ast.ValueSpec:
+				if len(node.Names) != len(node.Values) {
+					return true
+				}
+				for i, name := range node.Names {
+					if checker.taint(name, node.Values[i]) {
+						changed = true
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// _runLeak lints that sensitive contexts aren't formatted, serialized, or
+// reflected over.
+func _runLeak(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		checker := &_leakChecker{pass: pass, tainted: map[types.Object]*types.Named{}}
+		checker.taintFile(file)
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			callee, ok := lintutil.ObjectFor(call.Fun, pass.TypesInfo).(*types.Func)
+			if !ok || !_isLeakSink(callee) {
+				return true
+			}
+			for _, arg := range call.Args {
+				iface := checker.sensitive(arg)
+				if iface == nil {
+					continue
+				}
+				reportf(pass, arg, CodeLeakedContext,
+					"value holding a %s passed to %s, which may dump its "+
+						"contents; pass only the fields you need",
+					_shortTypeName(iface, pass.Pkg), callee.Name())
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,363 @@
+// Package lintertest helps test the typed-context analyzers, and forks of
+// them, with analysistest.
+//
+// It publishes the corpus of annotated examples we test the analyzers
+// against: one package per analyzer (and a few for behavior common to all of
+// them), in analysistest's testdata layout, with
+// `// want` comments on each line where a diagnostic is expected, and .golden
+// files for the suggested fixes.  It covers every diagnostic code except
+// those reported only by the whole-program modes (TC007, TC018 and TC038);
+// those modes, and -minimize, are tested against the corpus's wholeprogram
+// and minimize packages by this package's own tests instead.  A fork which
+// adds its own special cases can run the corpus to check it hasn't changed
+// the analyzers' behavior elsewhere:
+//
+//	func TestCorpus(t *testing.T) { lintertest.RunCorpus(t) }
+//
+// and test its own cases, in its own testdata directory, with Run or
+// RunWithSuggestedFixes.
+package lintertest
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	contextLinter "github.com/khan/typed-context/linter"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+//go:embed all:testdata
+var _corpus embed.FS
+
+// Case is a package of the corpus.
+type Case struct {
+	// Package is the package's path in the corpus, like
+	// "typedcontextinterface".
+	Package string
+	// Analyzer is the analyzer the package exercises.
+	Analyzer *analysis.Analyzer
+	// Flags are the analyzer flags, by name (like "enable"), to run it with.
+	Flags map[string]string
+	// Codes are the diagnostic codes the package's annotations cover.
+	Codes []contextLinter.Code
+}
+
+// Cases are the packages of the corpus, in the order of
+// contextLinter.Analyzers, followed by those for behavior common to all of
+// them.
+var Cases = []Case{
+	{
+		Package:  "typedcontextinterface",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeUnused, contextLinter.CodeUnrequested, contextLinter.CodeAllUnused, contextLinter.CodeCapturedContext},
+	},
+	{
+		Package:  "typedcontextcohesion",
+		Analyzer: contextLinter.TypedContextCohesionAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeLowCohesion},
+	},
+	{
+		Package:  "typedcontextdetach",
+		Analyzer: contextLinter.TypedContextDetachAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeLeakedRequestContext},
+	},
+	{
+		Package:  "typedcontextaccessor",
+		Analyzer: contextLinter.TypedContextAccessorAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeNonAccessorMethod},
+	},
+	{
+		Package:  "typedcontextredundant",
+		Analyzer: contextLinter.TypedContextRedundantAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeRedundantProvider},
+	},
+	{
+		Package:  "typedcontextexposed",
+		Analyzer: contextLinter.TypedContextExposedAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeUnexportedContext},
+	},
+	{
+		Package:  "typedcontextdynamic",
+		Analyzer: contextLinter.TypedContextDynamicAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeDynamicType},
+	},
+	{
+		Package:  "typedcontextembed",
+		Analyzer: contextLinter.TypedContextEmbedAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeRedundantEmbed},
+	},
+	{
+		Package:  "typedcontextshadow",
+		Analyzer: contextLinter.TypedContextShadowAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeShadowedContext},
+	},
+	{
+		// The layout rules are in the package's .typedcontext.yaml.
+		Package:  "typedcontextlayout",
+		Analyzer: contextLinter.TypedContextLayoutAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeMisplacedInterface},
+	},
+	{
+		Package:  "typedcontextvalue",
+		Analyzer: contextLinter.TypedContextValueAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeContextValue},
+	},
+	{
+		// A tracing library, allowed to call Value.
+		Package:  "typedcontextvalue/tracing",
+		Analyzer: contextLinter.TypedContextValueAnalyzer,
+		Flags:    map[string]string{"allowpkgs": "typedcontextvalue/tracing"},
+	},
+	{
+		Package:  "typedcontextsize",
+		Analyzer: contextLinter.TypedContextSizeAnalyzer,
+		Flags:    map[string]string{"max": "3"},
+		Codes:    []contextLinter.Code{contextLinter.CodeWideContext},
+	},
+	{
+		Package:  "typedcontextbackground",
+		Analyzer: contextLinter.TypedContextBackgroundAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeFreshContext, contextLinter.CodeSeveredContext},
+	},
+	{
+		Package:  "typedcontextreturn",
+		Analyzer: contextLinter.TypedContextReturnAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeReturnedContext},
+	},
+	{
+		Package:  "typedcontextany",
+		Analyzer: contextLinter.TypedContextAnyAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeContextAsAny},
+	},
+	{
+		Package:  "typedcontextwrapper",
+		Analyzer: contextLinter.TypedContextWrapperAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeWideWrapper},
+	},
+	{
+		// The inventory is the package's shapes.txt, as configured in its
+		// .typedcontext.yaml.
+		Package:  "typedcontextshapes",
+		Analyzer: contextLinter.TypedContextShapesAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeNewShape},
+	},
+	{
+		Package:  "typedcontextgetter",
+		Analyzer: contextLinter.TypedContextGetterAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeImpureAccessor},
+	},
+	{
+		Package:  "typedcontextfixture",
+		Analyzer: contextLinter.TypedContextFixtureAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeWideFixture},
+	},
+	{
+		Package:  "typedcontextpointer",
+		Analyzer: contextLinter.TypedContextPointerAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodePointerContext},
+	},
+	{
+		Package:  "typedcontextfield",
+		Analyzer: contextLinter.TypedContextFieldAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeWideField},
+	},
+	{
+		Package:  "typedcontextregistry",
+		Analyzer: contextLinter.TypedContextRegistryAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeWideRegistration},
+	},
+	{
+		Package:  "typedcontextcapability",
+		Analyzer: contextLinter.TypedContextCapabilityAnalyzer,
+		Flags: map[string]string{"rules": "typedcontextcapability.SecretsContext=example.com/secure/...," +
+			"AdminDatabaseContext=example.com/admin,AdminDatabaseContext=typedcontextcapability"},
+		Codes: []contextLinter.Code{contextLinter.CodeRestrictedInterface},
+	},
+	{
+		Package:  "typedcontextcancel",
+		Analyzer: contextLinter.TypedContextCancelAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeUncancellableLoop},
+	},
+	{
+		Package:  "typedcontextimpl",
+		Analyzer: contextLinter.TypedContextImplAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeImplicitAccessor, contextLinter.CodeStrayAccessor},
+	},
+	{
+		Package:  "typedcontextimplementations",
+		Analyzer: contextLinter.TypedContextImplementationsAnalyzer,
+		Flags:    map[string]string{"enable": "true", "explain": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeUnusedByImplementations, contextLinter.CodeImplementationUses},
+	},
+	{
+		Package:  "typedcontextextract",
+		Analyzer: contextLinter.TypedContextExtractAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeMismatchedProvider},
+	},
+	{
+		Package:  "typedcontextrewrap",
+		Analyzer: contextLinter.TypedContextRewrapAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeRewrappedContext},
+	},
+	{
+		Package:  "typedcontextleak",
+		Analyzer: contextLinter.TypedContextLeakAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeLeakedContext},
+	},
+	{
+		Package:  "typedcontextmocks",
+		Analyzer: contextLinter.TypedContextMocksAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeMockDrift},
+	},
+	{
+		Package:  "typedcontextorder",
+		Analyzer: contextLinter.TypedContextOrderAnalyzer,
+		Flags:    map[string]string{"enable": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeEmbedOrder},
+	},
+	{
+		Package:  "typedcontextroundtrip",
+		Analyzer: contextLinter.TypedContextRoundtripAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeRoundTrip},
+	},
+	{
+		Package:  "typedcontextrepeated",
+		Analyzer: contextLinter.TypedContextRepeatedAnalyzer,
+		Codes:    []contextLinter.Code{contextLinter.CodeRepeatedInterface},
+	},
+	{
+		// The interface analyzer's opt-in check of plain context.Context
+		// parameters.
+		Package:  "unusedroots",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+		Flags:    map[string]string{"unusedroots": "true"},
+		Codes:    []contextLinter.Code{contextLinter.CodeUnusedContext},
+	},
+	{
+		// Which _test.go files the interface analyzer reports on: those of
+		// the packages under -checktestspkgs, including their external
+		// test packages.
+		Package:  "checktests",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+		Flags:    map[string]string{"checktestspkgs": "checktests"},
+	},
+	{
+		// How the interface analyzer treats contexts returned by derivers,
+		// with the settings in the package's .typedcontext.yaml.
+		Package:  "derivers",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+	},
+	{
+		// Not one of contextLinter.Analyzers, but a helper they may
+		// require: the purities it finds for each function, as facts.
+		Package:  "purity",
+		Analyzer: lintutil.PurityAnalyzer,
+	},
+	{
+		// Not an analyzer of its own: how all of them apply the severities
+		// in the package's .typedcontext.yaml.
+		Package:  "severity",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+	},
+	{
+		// Not an analyzer of its own: how all of them treat generated
+		// files, with the settings in the package's .typedcontext.yaml.
+		Package:  "generated",
+		Analyzer: contextLinter.TypedContextInterfaceAnalyzer,
+	},
+}
+
+// Corpus copies the corpus to a temporary directory, removed when the test
+// ends, and returns the directory; package p of the corpus is in
+// dir/src/p, as analysistest expects.
+func Corpus(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := fs.WalkDir(_corpus, "testdata", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel("testdata", filepath.FromSlash(path))
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), 0o755)
+		}
+		data, err := _corpus.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0o644)
+	})
+	if err != nil {
+		t.Fatalf("copying corpus: %v", err)
+	}
+	return dir
+}
+
+// SetFlag sets the named flag of analyzer to value until the test ends, when
+// it's restored.
+func SetFlag(t testing.TB, analyzer *analysis.Analyzer, name, value string) {
+	t.Helper()
+	flag := analyzer.Flags.Lookup(name)
+	if flag == nil {
+		t.Fatalf("%s has no flag %q", analyzer.Name, name)
+	}
+	old := flag.Value.String()
+	if err := flag.Value.Set(value); err != nil {
+		t.Fatalf("setting -%s.%s: %v", analyzer.Name, name, err)
+	}
+	t.Cleanup(func() {
+		flag.Value.Set(old)
+		contextLinter.ResetSettings()
+	})
+}
+
+// Run runs analyzer over the given packages in dir (as for
+// analysistest.Run), and checks its diagnostics against the packages'
+// `// want` comments.
+//
+// The analyzers cache their settings for each package; Run discards any
+// cached settings first, so that flags set since the last run (see SetFlag)
+// take effect.
+func Run(t testing.TB, dir string, analyzer *analysis.Analyzer, pkgs ...string) []*analysistest.Result {
+	t.Helper()
+	contextLinter.ResetSettings()
+	return analysistest.Run(t, dir, analyzer, pkgs...)
+}
+
+// RunWithSuggestedFixes is like Run, but also checks that applying the
+// suggested fixes to each file gives the contents of the corresponding
+// .golden file.
+func RunWithSuggestedFixes(t testing.TB, dir string, analyzer *analysis.Analyzer, pkgs ...string) []*analysistest.Result {
+	t.Helper()
+	contextLinter.ResetSettings()
+	return analysistest.RunWithSuggestedFixes(t, dir, analyzer, pkgs...)
+}
+
+// RunCorpus checks each package of the corpus, in a subtest named after it,
+// with its analyzer and flags.
+func RunCorpus(t *testing.T) {
+	dir := Corpus(t)
+	for _, c := range Cases {
+		t.Run(c.Package, func(t *testing.T) {
+			for name, value := range c.Flags {
+				SetFlag(t, c.Analyzer, name, value)
+			}
+			RunWithSuggestedFixes(t, dir, c.Analyzer, c.Package)
+		})
+	}
+}
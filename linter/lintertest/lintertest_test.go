@@ -0,0 +1,11 @@
+package lintertest_test
+
+import (
+	"testing"
+
+	"github.com/khan/typed-context/linter/lintertest"
+)
+
+func TestCorpus(t *testing.T) {
+	lintertest.RunCorpus(t)
+}
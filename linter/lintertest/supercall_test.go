@@ -0,0 +1,41 @@
+package lintertest_test
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/lintertest"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// _superCallAnalyzer reports each method's super-call, as found by
+// lintutil.SuperCall, with the path of embedded fields it goes through.
+var _superCallAnalyzer = &analysis.Analyzer{
+	Name: "supercall",
+	Doc:  "reports super-calls, for testing lintutil.SuperCall",
+	Run: func(pass *analysis.Pass) (any, error) {
+		funcDecls := lintutil.FilterFuncs(pass.Files, func(decl *ast.FuncDecl) bool {
+			return decl.Recv != nil && len(decl.Recv.List[0].Names) > 0
+		})
+		for _, funcDecl := range funcDecls {
+			path := lintutil.SuperCall(funcDecl, pass.TypesInfo)
+			if path == nil {
+				continue
+			}
+			names := make([]string, len(path))
+			for i, field := range path {
+				names[i] = field.Name()
+			}
+			pass.Reportf(funcDecl.Name.Pos(), "%s calls super through %s",
+				funcDecl.Name.Name, strings.Join(names, "."))
+		}
+		return nil, nil
+	},
+}
+
+func TestSuperCall(t *testing.T) {
+	lintertest.Run(t, lintertest.Corpus(t), _superCallAnalyzer, "supercall")
+}
@@ -0,0 +1,20 @@
+// Package checktests exercises -typedcontextinterface.checktestspkgs, which
+// is set to this package: contexts declared in its _test.go files are
+// reported like any others, in both its internal and external test packages.
+package checktests
+
+import "context"
+
+type Logger struct{}
+
+type DB struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
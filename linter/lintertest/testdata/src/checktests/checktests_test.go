@@ -0,0 +1,8 @@
+package checktests
+
+func internalHelper(ctx interface { // want `ctx requests but does not use interface\(s\) DBContext`
+	LoggerContext
+	DBContext
+}) {
+	ctx.Logger()
+}
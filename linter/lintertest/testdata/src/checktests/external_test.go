@@ -0,0 +1,10 @@
+package checktests_test
+
+import "checktests"
+
+func externalHelper(ctx interface { // want `ctx requests but does not use interface\(s\) checktests.DBContext`
+	checktests.LoggerContext
+	checktests.DBContext
+}) {
+	ctx.Logger()
+}
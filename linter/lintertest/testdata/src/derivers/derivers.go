@@ -0,0 +1,135 @@
+// Package derivers exercises how the interface analyzer treats contexts
+// returned by derivers: OpenTelemetry's Tracer.Start and errgroup's
+// WithContext, by default, and tracing.Start, in the package's
+// .typedcontext.yaml.
+package derivers
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+
+	"tracing"
+)
+
+type Logger struct{}
+
+type DB struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+
+func load(ctx DBContext) {
+	_ = ctx.DB()
+}
+
+// The derived context's uses count toward ctx: fine.
+func Derived(ctx interface {
+	tracing.TraceContext
+	DBContext
+}) {
+	spanCtx, span := tracing.Start(ctx, "load")
+	defer span.End()
+	load(spanCtx)
+}
+
+// Likewise when it replaces ctx.
+func Replaced(ctx interface {
+	tracing.TraceContext
+	DBContext
+}) {
+	ctx, span := tracing.Start(ctx, "load")
+	defer span.End()
+	load(ctx)
+}
+
+// Passing ctx to tracing.Start uses only TraceContext, not all of ctx's type.
+func Unused(ctx interface { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	tracing.TraceContext
+	DBContext
+	LoggerContext
+}) {
+	spanCtx, span := tracing.Start(ctx, "load")
+	defer span.End()
+	load(spanCtx)
+}
+
+// Assigning the result to a variable of the caller's choosing uses ctx as
+// that type.
+func Assigned(ctx interface { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	tracing.TraceContext
+	DBContext
+	LoggerContext
+}) {
+	var small DBContext
+	small, span := tracing.Start(ctx, "load")
+	defer span.End()
+	load(small)
+}
+
+// The plain context.Context Tracer.Start returns is ctx too, as far as it
+// goes.
+func Raw(ctx interface { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	DBContext
+	LoggerContext
+}, tracer trace.Tracer) {
+	spanCtx, span := tracer.Start(ctx, "load")
+	defer span.End()
+	_ = spanCtx.Err()
+	load(ctx)
+}
+
+func loadAll(ctx context.Context, ids []int) error {
+	return ctx.Err()
+}
+
+// The group's context is ctx too, and the closures' uses of ctx count.
+func Grouped(ctx interface {
+	DBContext
+	LoggerContext
+}) error {
+	g, groupCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		load(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		_ = ctx.Logger()
+		return loadAll(groupCtx, nil)
+	})
+	return g.Wait()
+}
+
+// Likewise when it shadows ctx; but then the closures can only use what the
+// group's context provides, which is none of ctx's typed interfaces, only
+// context.Context.
+func GroupedShadowed(ctx interface { // want `ctx requests but does not use interface\(s\) DBContext, LoggerContext`
+	DBContext
+	LoggerContext
+}) error {
+	if true {
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error { return loadAll(ctx, nil) })
+		return g.Wait()
+	}
+	return nil
+}
+
+// Closures passed to a WaitGroup just capture ctx.
+func Waited(ctx interface { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	DBContext
+	LoggerContext
+}) {
+	var wg sync.WaitGroup
+	wg.Go(func() { load(ctx) })
+	wg.Wait()
+}
@@ -0,0 +1,13 @@
+// Package generated exercises the handling of generated files: by default
+// their diagnostics are dropped, but its .typedcontext.yaml asks for those
+// of lintme-gen to be reported.
+package generated
+
+import "context"
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
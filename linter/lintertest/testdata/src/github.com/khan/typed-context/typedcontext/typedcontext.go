@@ -0,0 +1,21 @@
+// Package typedcontext stubs the parts of
+// github.com/khan/typed-context/typedcontext which the typedcontextrewrap
+// and typedcontextroundtrip packages use.
+package typedcontext
+
+import (
+	"context"
+	"time"
+)
+
+func WithContext[T any](ctx T, inner context.Context) T { return ctx }
+
+func WithTimeout[T context.Context](ctx T, timeout time.Duration) (T, context.CancelFunc) {
+	return ctx, func() {}
+}
+
+type Option struct{}
+
+func With[P any](provider P) Option { return Option{} }
+
+func Override[T any](ctx T, opts ...Option) T { return ctx }
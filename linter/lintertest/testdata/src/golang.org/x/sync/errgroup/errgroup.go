@@ -0,0 +1,15 @@
+// Package errgroup stubs the parts of golang.org/x/sync/errgroup which the
+// derivers package uses.
+package errgroup
+
+import "context"
+
+type Group struct{}
+
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	return &Group{}, ctx
+}
+
+func (g *Group) Go(f func() error) {}
+
+func (g *Group) Wait() error { return nil }
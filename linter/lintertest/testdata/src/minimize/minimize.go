@@ -0,0 +1,89 @@
+// Package minimize exercises the -minimize mode: see wholeprogram_test.go,
+// which runs it with a threshold of 2, and checks the result against
+// minimize.go.golden.
+package minimize
+
+import "context"
+
+type Logger struct{}
+
+type Database struct{}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// AppContext and appContext are duplicates: appContext is merged into
+// AppContext.
+type AppContext interface {
+	LoggerContext
+	DatabaseContext
+	SecretsContext
+}
+
+type appContext interface {
+	LoggerContext
+	DatabaseContext
+	SecretsContext
+}
+
+// Narrowed to LoggerContext.
+func Log(ctx AppContext) {
+	_ = ctx.Logger()
+}
+
+// Uses all of appContext, which becomes AppContext.
+func all(ctx appContext) {
+	_ = ctx.Logger()
+	_ = ctx.Database()
+	_ = ctx.Secrets()
+}
+
+// Query and store both need LoggerContext and DatabaseContext, which get a
+// name.
+func Query(ctx AppContext) {
+	_ = ctx.Logger()
+	_ = ctx.Database()
+}
+
+func store(ctx interface {
+	LoggerContext
+	DatabaseContext
+	SecretsContext
+}) {
+	_ = ctx.Logger()
+	_ = ctx.Database()
+}
+
+type Doer interface {
+	Do(ctx AppContext)
+}
+
+type impl struct{}
+
+// Skipped: Do implements Doer.
+func (impl) Do(ctx AppContext) {
+	_ = ctx.Logger()
+}
+
+var _ Doer = impl{}
+
+// Skipped: Fn is assigned to a func type.
+func Fn(ctx AppContext) {
+	_ = ctx.Logger()
+}
+
+var Hook func(AppContext) = Fn
@@ -0,0 +1,102 @@
+// Package purity exercises lintutil.PurityAnalyzer, whose facts are the
+// purities of the package's functions.
+package purity
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	mu    sync.Mutex
+	name  string
+	count int
+}
+
+var hits int
+
+func Name(first, last string) string { // want Name:"pure"
+	return strings.TrimSpace(first + " " + last)
+}
+
+func Greeting(name string) string { // want Greeting:"pure"
+	return fmt.Sprintf("hello, %s", Name(name, ""))
+}
+
+func Describe(ctx context.Context) string { // want Describe:"pure"
+	if ctx.Err() != nil {
+		return "canceled"
+	}
+	return []string{"a", "b"}[len(Greeting("x"))%2]
+}
+
+func Load(path string) ([]byte, error) { // want Load:"I/O"
+	return os.ReadFile(path)
+}
+
+func Log(msg string) { // want Log:"I/O"
+	fmt.Println(msg)
+}
+
+func Wait(done chan struct{}) { // want Wait:"blocking"
+	<-done
+}
+
+func Pause() { // want Pause:"blocking"
+	time.Sleep(time.Millisecond)
+}
+
+func (c *Config) Name() string { // want Name:"blocking"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.name
+}
+
+func Hit() { // want Hit:"unknown"
+	hits++
+}
+
+func Call(f func() int) int { // want Call:"unknown"
+	return f()
+}
+
+// I/O outranks what we can't tell.
+func Both(f func() int) { // want Both:"I/O"
+	Log(fmt.Sprint(f()))
+}
+
+// Recursion settles on the purity of what the functions do besides calling
+// each other.
+func Even(n int) bool { // want Even:"pure"
+	return n == 0 || Odd(n-1)
+}
+
+func Odd(n int) bool { // want Odd:"pure"
+	return n != 0 && Even(n-1)
+}
+
+func Ping(n int) { // want Ping:"I/O"
+	if n > 0 {
+		Pong(n - 1)
+	}
+}
+
+func Pong(n int) { // want Pong:"I/O"
+	Log("pong")
+	Ping(n)
+}
+
+// Function-literals count only if they're called, and goroutines not at
+// all.
+func Later() func() { // want Later:"pure"
+	go Log(Name("a", "b"))
+	return func() { Log("later") }
+}
+
+func Now() int { // want Now:"I/O"
+	return func() int { Log("now"); return 1 }()
+}
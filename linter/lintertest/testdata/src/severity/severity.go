@@ -0,0 +1,29 @@
+// Package severity exercises the severities of diagnostic codes: its
+// .typedcontext.yaml makes TC003 advice, and turns TC001 off.
+package severity
+
+import "context"
+
+type Logger struct{}
+
+type Database struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+func Advised(ctx LoggerContext) {} // want `^advice: no interfaces requested by ctx are used`
+
+// Would be TC001, but it's off.
+func Quiet(ctx interface {
+	LoggerContext
+	DatabaseContext
+}) {
+	ctx.Logger()
+}
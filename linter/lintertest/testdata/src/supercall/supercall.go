@@ -0,0 +1,49 @@
+// Package supercall exercises lintutil.SuperCall: which calls of a method
+// of the same name count as calls to the receiver's "super", and through
+// which embedded fields.
+package supercall
+
+type Base struct{}
+
+func (Base) M() {}
+
+type Middle struct{ Base }
+
+// Through an explicit field, with the method promoted from Base.
+type Implicit struct{ Middle }
+
+func (t Implicit) M() { t.Middle.M() } // want `M calls super through Middle.Base`
+
+// Through explicit fields all the way.
+type Explicit struct{ Middle }
+
+func (t Explicit) M() { t.Middle.Base.M() } // want `M calls super through Middle.Base`
+
+// Through a field which is itself promoted: Base is promoted through Middle.
+type Promoted struct{ Middle }
+
+func (t Promoted) M() { (t.Base).M() } // want `M calls super through Middle.Base`
+
+// Through a pointer receiver and an embedded pointer.
+type Pointer struct{ *Middle }
+
+func (t *Pointer) M() { t.Base.M() } // want `M calls super through Middle.Base`
+
+// Not through a field which isn't embedded: that's some other object.
+type Named struct{ other Base }
+
+func (t Named) M() { t.other.M() }
+
+// Nor through an embedded field reached via one which isn't.
+type Wrapper struct{ middle Middle }
+
+type Indirect struct{ Wrapper }
+
+func (t Indirect) M() { t.middle.Base.M() }
+
+// Nor a call to the receiver's own method.
+type Own struct{ Base }
+
+func (t Own) N() {}
+
+func (t Own) M() { t.N() }
@@ -0,0 +1,19 @@
+// Package tracing is a stand-in for typedcontextotel, for the derivers
+// package.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TraceContext interface {
+	context.Context
+	Tracer() trace.Tracer
+}
+
+func Start[T TraceContext](ctx T, name string) (T, trace.Span) {
+	_, span := ctx.Tracer().Start(ctx, name)
+	return ctx, span
+}
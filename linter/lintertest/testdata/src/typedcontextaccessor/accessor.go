@@ -0,0 +1,18 @@
+// Package typedcontextaccessor exercises TC006.
+package typedcontextaccessor
+
+import "context"
+
+type User struct{}
+type UserStore struct{}
+
+type UserContext interface {
+	context.Context
+	Users() *UserStore
+	LookupUser(id string) (*User, error) // want `typed context interface declares non-accessor method LookupUser`
+}
+
+// Not a typed context: fine.
+type Lookup interface {
+	LookupUser(id string) (*User, error)
+}
@@ -0,0 +1,34 @@
+// Package typedcontextany exercises TC019.
+package typedcontextany
+
+import (
+	"context"
+	"fmt"
+)
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+func Register(value any)                   {}
+func RegisterAll(values ...interface{})    {}
+func Generic[T any](value T)               {}
+func Logging(ctx LoggerContext)            { _ = ctx.Logger() }
+func Plain(ctx context.Context, value any) {}
+
+func F(ctx LoggerContext) {
+	Register(ctx)       // want `typed context passed to Register as any`
+	RegisterAll(1, ctx) // want `typed context passed to RegisterAll as any`
+	fmt.Println(ctx)    // allowed: fmt only formats it
+	Generic(ctx)        // a type parameter: fine
+	Logging(ctx)        // a typed parameter: fine
+	Plain(context.TODO(), 1)
+}
+
+// A plain context.Context: fine.
+func G(ctx context.Context) {
+	Register(ctx)
+}
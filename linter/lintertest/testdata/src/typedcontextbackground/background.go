@@ -0,0 +1,54 @@
+// Package typedcontextbackground exercises TC016 and TC035.
+package typedcontextbackground
+
+import "context"
+
+func g(ctx context.Context) {}
+
+// TC016: ctx is available.
+func F(ctx context.Context) {
+	g(context.Background()) // want `context.Background\(\) called where ctx is available`
+}
+
+// TC016: in a function literal, ctx is still available.
+func H(ctx context.Context) {
+	func() {
+		g(context.TODO()) // want `context.TODO\(\) called where ctx is available`
+	}()
+}
+
+// No context to pass: fine.
+func Start() {
+	g(context.Background())
+}
+
+type Logger struct{}
+
+type LoggerServer interface {
+	Logger() *Logger
+}
+
+type AppContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+func serve(ctx context.Context, server LoggerServer) {}
+
+func newAppContext() AppContext { return nil }
+
+// TC035: appCtx, though not a parameter, has a deadline that's dropped.
+func Handle() {
+	appCtx := newAppContext()
+	serve(context.Background(), appCtx) // want `context.Background\(\) passed alongside appCtx, narrowed to LoggerServer`
+}
+
+// TC035, not TC016: through a conversion, and where ctx is a parameter.
+func Forward(ctx AppContext) {
+	serve(context.TODO(), LoggerServer(ctx)) // want `context.TODO\(\) passed alongside ctx, narrowed to LoggerServer`
+}
+
+// Passing the typed context as both: fine.
+func Both(ctx AppContext) {
+	serve(ctx, ctx)
+}
@@ -0,0 +1,119 @@
+// Package typedcontextcancel exercises TC029.
+package typedcontextcancel
+
+import (
+	"context"
+	"os"
+)
+
+type Database struct{}
+
+func (*Database) Put(id int) error                             { return nil }
+func (*Database) PutContext(ctx context.Context, id int) error { return nil }
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+	Logger() *Logger
+}
+
+func sync(ctx DatabaseContext, ids []int) error {
+	for _, id := range ids { // want `loop calls ctx.Database\(\).Put, but never checks whether ctx is cancelled; check ctx.Err\(\) \(or select on ctx.Done\(\)\) in the loop, or pass it to the call`
+		if err := ctx.Database().Put(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncVia(ctx DatabaseContext, ids []int) error {
+	db := ctx.Database()
+	for i := 0; i < len(ids); i++ { // want `loop calls db.Put, but never checks whether ctx is cancelled`
+		if err := db.Put(ids[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeAll(ctx context.Context, paths []string) {
+	for _, path := range paths { // want `loop calls os.Remove, but never checks whether ctx is cancelled`
+		os.Remove(path)
+	}
+}
+
+func checked(ctx DatabaseContext, ids []int) error {
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ctx.Database().Put(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func selected(ctx DatabaseContext, ids []int) error {
+	for _, id := range ids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		ctx.Database().Put(id)
+	}
+	return nil
+}
+
+func passed(ctx DatabaseContext, ids []int) {
+	for _, id := range ids {
+		ctx.Database().PutContext(ctx, id)
+	}
+}
+
+// Logging doesn't block.
+func logged(ctx DatabaseContext, ids []int) {
+	for range ids {
+		ctx.Logger().Log("hi")
+	}
+}
+
+// The inner loop is reported, not the outer one.
+func nested(ctx DatabaseContext, batches [][]int) {
+	for _, batch := range batches {
+		ctx.Logger().Log("batch")
+		for _, id := range batch { // want `loop calls ctx.Database\(\).Put`
+			ctx.Database().Put(id)
+		}
+	}
+}
+
+// Goroutines started in the loop don't block it.
+func spawned(ctx DatabaseContext, ids []int) {
+	for _, id := range ids {
+		go func() {
+			ctx.Database().Put(id)
+		}()
+	}
+}
+
+// Without a context, there's nothing to check.
+func noContext(db *Database, ids []int) {
+	for _, id := range ids {
+		db.Put(id)
+	}
+}
+
+// A closure inherits its enclosing function's context.
+func closure(ctx DatabaseContext, ids []int) func() {
+	return func() {
+		for _, id := range ids { // want `loop calls ctx.Database\(\).Put, but never checks whether ctx is cancelled`
+			ctx.Database().Put(id)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package typedcontextcapability exercises TC028, with the rules
+//
+//	typedcontextcapability.SecretsContext=example.com/secure/...
+//	AdminDatabaseContext=example.com/admin
+//	AdminDatabaseContext=typedcontextcapability
+//
+// so this package may request an AdminDatabaseContext, but not a
+// SecretsContext.
+package typedcontextcapability
+
+import "context"
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type Secrets struct{}
+
+func (*Secrets) Get(string) string { return "" }
+
+type DB struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type AdminDatabaseContext interface {
+	context.Context
+	AdminDatabase() *DB
+}
+
+type AppContext interface {
+	LoggerContext
+	SecretsContext
+	AdminDatabaseContext
+}
+
+func logIt(ctx LoggerContext) {
+	ctx.Logger().Log("fine")
+}
+
+func charge(ctx interface { // want `ctx requests SecretsContext, which only packages matching example.com/secure/... may request; narrow its context, or move this code to one of them`
+	LoggerContext
+	SecretsContext
+}, amount int) {
+	ctx.Logger().Log(ctx.Secrets().Get("stripe"))
+}
+
+func migrate(ctx AdminDatabaseContext) {
+	_ = ctx.AdminDatabase()
+}
+
+func Serve(ctx AppContext) { // want `ctx requests SecretsContext, which only packages matching example.com/secure/... may request; it's embedded via AppContext -> SecretsContext; narrow its context`
+	logIt(ctx)
+	charge(ctx, 3)
+	migrate(ctx)
+}
+
+func run() {
+	var ctx AppContext // want `ctx requests SecretsContext`
+	Serve(ctx)
+}
@@ -0,0 +1,81 @@
+// Package typedcontextcohesion exercises TC004.
+package typedcontextcohesion
+
+import "context"
+
+type DB struct{}
+type Cache struct{}
+type HTTPClient struct{}
+type Secrets struct{}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+type CacheContext interface {
+	context.Context
+	Cache() *Cache
+}
+type HTTPClientContext interface {
+	context.Context
+	HTTPClient() *HTTPClient
+}
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// TC004: the branches use disjoint halves of the context.
+func Handle(ctx interface { // want `ctx has low cohesion: the branches of the statement at line 36 use disjoint interfaces \{CacheContext, DBContext\} and \{HTTPClientContext, SecretsContext\}`
+	context.Context
+	DBContext
+	CacheContext
+	HTTPClientContext
+	SecretsContext
+}, local bool) {
+	if local {
+		_ = ctx.DB()
+		_ = ctx.Cache()
+	} else {
+		_ = ctx.HTTPClient()
+		_ = ctx.Secrets()
+	}
+}
+
+// Uses everything outside the branch: fine.
+func Cohesive(ctx interface {
+	context.Context
+	DBContext
+	CacheContext
+	HTTPClientContext
+	SecretsContext
+}, local bool) {
+	_ = ctx.DB()
+	_ = ctx.Cache()
+	if local {
+		_ = ctx.HTTPClient()
+	} else {
+		_ = ctx.Secrets()
+	}
+}
+
+// audit is configured as a sink using all of its context, in .typedcontext.yaml.
+func audit(ctx context.Context) {}
+
+// Uses everything, via the sink, outside the branch: fine.
+func Audited(ctx interface {
+	context.Context
+	DBContext
+	CacheContext
+	HTTPClientContext
+	SecretsContext
+}, local bool) {
+	audit(ctx)
+	if local {
+		_ = ctx.DB()
+		_ = ctx.Cache()
+	} else {
+		_ = ctx.HTTPClient()
+		_ = ctx.Secrets()
+	}
+}
@@ -0,0 +1,34 @@
+// Package typedcontextdetach exercises TC005.
+package typedcontextdetach
+
+import "context"
+
+type Request struct{}
+
+type RequestContext interface {
+	context.Context
+	Request() *Request
+}
+
+func sendAnalytics(ctx context.Context) {}
+
+var saved context.Context
+
+// TC005: the goroutine may outlive the request.
+func Handle(ctx interface {
+	context.Context
+	RequestContext
+}) {
+	_ = ctx.Request()
+	go sendAnalytics(ctx) // want `ctx is request-scoped \(it embeds RequestContext\) but is used by a goroutine`
+}
+
+// TC005: stored in a package-level variable.
+func Save(ctx RequestContext) {
+	saved = ctx // want `ctx is request-scoped \(it embeds RequestContext\) but is stored in a package-level variable`
+}
+
+// Not request-scoped: fine.
+func Background(ctx context.Context) {
+	go sendAnalytics(ctx)
+}
@@ -0,0 +1,32 @@
+// Package typedcontextdynamic exercises TC010.
+package typedcontextdynamic
+
+import "context"
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type prodContext struct{ LoggerContext }
+type mockContext struct{ LoggerContext }
+
+// TC010: type-switches on the implementation.
+func F(ctx LoggerContext) {
+	switch ctx.(type) { // want `type switch on typed context ctx`
+	case *prodContext:
+	case *mockContext:
+	}
+}
+
+// TC010: compares contexts.
+func G(ctx, other LoggerContext) bool {
+	return ctx == other // want `comparison of typed contexts`
+}
+
+// Comparing with nil is fine.
+func H(ctx LoggerContext) bool {
+	return ctx == nil
+}
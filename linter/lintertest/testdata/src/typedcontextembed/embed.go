@@ -0,0 +1,27 @@
+// Package typedcontextembed exercises TC011.
+package typedcontextembed
+
+import "context"
+
+type Request struct{}
+type DB struct{}
+
+type RequestContext interface {
+	context.Context
+	Request() *Request
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+
+// TC011: RequestContext already embeds context.Context.
+func F(ctx interface {
+	RequestContext
+	DBContext
+	context.Context // want `embed of context.Context is redundant: it's implied by RequestContext`
+}) {
+	_ = ctx.Request()
+	_ = ctx.DB()
+}
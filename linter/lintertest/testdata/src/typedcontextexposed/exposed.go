@@ -0,0 +1,24 @@
+// Package typedcontextexposed exercises TC009.
+package typedcontextexposed
+
+import "context"
+
+type Logger struct{}
+
+type loggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+// TC009: callers elsewhere can't name loggerContext.
+func F(ctx interface { // want `exported function F requests unexported interface\(s\) loggerContext`
+	context.Context
+	loggerContext
+}) {
+	_ = ctx.Logger()
+}
+
+// Unexported: fine.
+func g(ctx loggerContext) {
+	_ = ctx.Logger()
+}
@@ -0,0 +1,88 @@
+// Package typedcontextextract exercises TC034.
+package typedcontextextract
+
+import "context"
+
+type Logger struct{}
+
+type Database struct{}
+
+type Getter interface {
+	Get(key string) string
+}
+
+func (*Database) Get(key string) string { return key }
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type Handler struct {
+	logger *Logger
+	db     *Database
+}
+
+// Stores each provider in a field of its own type: fine.
+func NewHandler(ctx interface {
+	LoggerContext
+	DatabaseContext
+}) *Handler {
+	return &Handler{logger: ctx.Logger(), db: ctx.Database()}
+}
+
+type looseHandler struct {
+	logger any
+	db     Getter
+	lookup func() *Logger
+}
+
+// TC034: the fields hide what they hold.
+func newLooseHandler(ctx interface {
+	LoggerContext
+	DatabaseContext
+}) *looseHandler {
+	h := &looseHandler{
+		logger: ctx.Logger(), // want `field logger stores the provider from Logger as any, not \*Logger`
+		lookup: ctx.Logger,
+	}
+	h.db = ctx.Database() // want `field db stores the provider from Database as Getter, not \*Database`
+	return h
+}
+
+type lookups struct {
+	logger any
+	lookup func() any
+}
+
+// TC034: a method value, stored as something other than a func() *Logger.
+func newLookups(ctx LoggerContext) lookups {
+	return lookups{
+		logger: ctx.Logger, // want `field logger stores the provider from Logger as any, not func\(\) \*Logger`
+		// A function literal isn't an extraction: fine.
+		lookup: func() any { return ctx.Logger() },
+	}
+}
+
+type positional struct {
+	db any
+}
+
+func newPositional(ctx DatabaseContext) positional {
+	return positional{ctx.Database()} // want `field db stores the provider from Database as any, not \*Database`
+}
+
+// Storing other values, or the context itself, isn't an extraction: fine.
+type holder struct {
+	ctx context.Context
+	err any
+}
+
+func newHolder(ctx DatabaseContext) holder {
+	return holder{ctx: ctx, err: ctx.Err()}
+}
@@ -0,0 +1,126 @@
+// Package typedcontextfield exercises TC025.
+package typedcontextfield
+
+import (
+	"context"
+	"fmt"
+)
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+func log(ctx LoggerContext) {
+	ctx.Logger().Log("hi")
+}
+
+// Uses everything it requests: fine.
+type exact struct {
+	ctx interface {
+		LoggerContext
+		SecretsContext
+	}
+}
+
+func (e *exact) serve() {
+	log(e.ctx)
+	_ = e.ctx.Secrets()
+}
+
+// Only ever uses the logger.
+type wide struct {
+	ctx interface { // want `field ctx of wide requests but does not use interface\(s\) SecretsContext; remove to use the smallest possible interface`
+		LoggerContext
+		SecretsContext
+	}
+	name string
+}
+
+func newWide(ctx interface {
+	LoggerContext
+	SecretsContext
+}) *wide {
+	return &wide{ctx: ctx}
+}
+
+func (w *wide) serve() {
+	(w.ctx).Logger().Log(w.name)
+}
+
+// Uses only context.Context.
+type cancelled struct {
+	ctx LoggerContext // want `no interfaces requested by field ctx of cancelled are used; make it a context.Context`
+}
+
+func (c cancelled) done() bool {
+	return c.ctx.Err() != nil
+}
+
+// Uses count wherever they are, not just in methods.
+type used struct {
+	ctx interface {
+		LoggerContext
+		SecretsContext
+	}
+}
+
+func useIt(u used) {
+	var small SecretsContext = u.ctx
+	_ = small
+	log(u.ctx)
+}
+
+// Copied to a variable, which we don't follow: skipped.
+type copied struct {
+	ctx interface {
+		LoggerContext
+		SecretsContext
+	}
+}
+
+func (c *copied) serve() {
+	ctx := c.ctx
+	log(ctx)
+}
+
+// Only written: skipped.
+type written struct {
+	ctx LoggerContext
+}
+
+func (w *written) set(ctx LoggerContext) {
+	w.ctx = ctx
+}
+
+// Exported: other packages may use it.
+type Exported struct {
+	Ctx interface {
+		LoggerContext
+		SecretsContext
+	}
+}
+
+func (e Exported) Serve() {
+	log(e.Ctx)
+}
+
+// Embedded: its methods are the struct's.
+type embedded struct {
+	LoggerContext
+}
+
+func (e embedded) serve() {
+	fmt.Println(e)
+}
@@ -0,0 +1,69 @@
+// Package typedcontextfixture exercises TC023.
+package typedcontextfixture
+
+import "context"
+
+type Logger struct{}
+
+type Secrets struct{}
+
+type Database struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type MockContext interface {
+	LoggerContext
+	SecretsContext
+	DatabaseContext
+}
+
+type LoggerSecretsContext interface {
+	LoggerContext
+	SecretsContext
+}
+
+// Fixtures.
+
+func AllTheMocks() MockContext { return nil }
+
+func LoggerSecretsMocks() LoggerSecretsContext { return nil }
+
+func LoggerMocks() LoggerContext { return nil }
+
+func NamedMocks(name string) MockContext { return nil }
+
+// Functions under test.
+
+func DoTheThing(ctx interface {
+	LoggerContext
+	SecretsContext
+}, thing string) {
+	ctx.Logger()
+	ctx.Secrets()
+}
+
+func Log(ctx LoggerContext) { ctx.Logger() }
+
+func UseAll(ctx MockContext) {
+	ctx.Logger()
+	ctx.Secrets()
+	ctx.Database()
+}
+
+// Outside tests, big contexts are the other analyzers' business.
+func notATest() {
+	DoTheThing(AllTheMocks(), "thing")
+}
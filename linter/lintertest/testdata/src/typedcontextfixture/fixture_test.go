@@ -0,0 +1,35 @@
+package typedcontextfixture
+
+import "testing"
+
+func TestDoTheThing(t *testing.T) {
+	DoTheThing(AllTheMocks(), "thing") // want `AllTheMocks\(\) provides DatabaseContext, which DoTheThing doesn't need; use LoggerSecretsMocks\(\) instead`
+}
+
+func TestLog(t *testing.T) {
+	// The narrowest fixture is suggested.
+	Log(AllTheMocks()) // want `AllTheMocks\(\) provides DatabaseContext, SecretsContext, which Log doesn't need; use LoggerMocks\(\) instead`
+}
+
+// Fine: it needs everything.
+func TestUseAll(t *testing.T) {
+	UseAll(AllTheMocks())
+}
+
+func TestBoth(t *testing.T) {
+	ctx := AllTheMocks() // want `AllTheMocks\(\) provides DatabaseContext, which the uses of ctx don't need; use LoggerSecretsMocks\(\) instead`
+	Log(ctx)
+	DoTheThing(ctx, "thing")
+}
+
+// Fine: ctx is used directly, so we don't know what it needs.
+func TestDirectUse(t *testing.T) {
+	ctx := AllTheMocks()
+	ctx.Database()
+	Log(ctx)
+}
+
+func TestNoNarrower(t *testing.T) {
+	// No fixture takes a name and provides less.
+	Log(NamedMocks("x")) // want `NamedMocks\(\) provides DatabaseContext, SecretsContext, which Log doesn't need; use a fixture providing just LoggerContext`
+}
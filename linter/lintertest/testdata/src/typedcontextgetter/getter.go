@@ -0,0 +1,82 @@
+// Package typedcontextgetter exercises TC022.
+package typedcontextgetter
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+type Logger struct{}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type appContext struct {
+	context.Context
+	logger *Logger
+
+	mu      sync.Mutex
+	once    sync.Once
+	secrets *Secrets
+	calls   map[string]int
+}
+
+var _ LoggerContext = (*appContext)(nil)
+
+// A plain getter: fine.
+func (c *appContext) Logger() *Logger {
+	return c.logger
+}
+
+// Built lazily with sync.Once: fine.
+func (c *appContext) Secrets() *Secrets {
+	c.once.Do(func() {
+		data, _ := os.ReadFile("secrets")
+		c.secrets = &Secrets{}
+		_ = data
+	})
+	return c.secrets
+}
+
+// TC022: I/O.
+func (c *appContext) Hostname() string {
+	name, _ := os.Hostname() // want `accessor Hostname calls os.Hostname \(I/O\)`
+	return name
+}
+
+// TC022: locks, and mutates state.
+func (c *appContext) CachedSecrets() *Secrets {
+	c.mu.Lock() // want `accessor CachedSecrets calls \(\*sync.Mutex\).Lock \(lock\)`
+	defer c.mu.Unlock()
+	c.calls["secrets"]++ // want `accessor CachedSecrets modifies c.calls\["secrets"\] \(mutation\)`
+	return c.secrets
+}
+
+// Modifying local state is fine.
+func (c *appContext) Names() []string {
+	names := []string{"a", "b"}
+	names[0] = "c"
+	seen := map[string]bool{}
+	seen["c"] = true
+	return names
+}
+
+// Not a context: fine.
+type notContext struct {
+	calls int
+}
+
+func (n *notContext) Count() int {
+	n.calls++
+	return n.calls
+}
@@ -0,0 +1,91 @@
+package typedcontextimpl
+
+import "context"
+
+type Logger struct{}
+
+type Database struct{}
+
+type Billing struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type BillingContext interface {
+	context.Context
+	Billing() *Billing
+}
+
+type AppContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+// Database is promoted from the embedded AppContext, which is usually nil.
+type PartialContext struct { // want `PartialContext gets accessor Database of AppContext only by embedding AppContext, which may not provide it; define it, or embed DatabaseContext to delegate it deliberately`
+	AppContext
+	logger *Logger
+}
+
+func (c PartialContext) Logger() *Logger { return c.logger }
+
+var _ AppContext = PartialContext{}
+
+// Embedding the leaf interface delegates Database deliberately.
+type DelegatingContext struct {
+	DatabaseContext
+	logger *Logger
+}
+
+func (c *DelegatingContext) Logger() *Logger { return c.logger }
+
+var _ AppContext = (*DelegatingContext)(nil)
+
+// Accessors promoted from concrete types are fine.
+type loggerProvider struct{ logger *Logger }
+
+func (p loggerProvider) Logger() *Logger { return p.logger }
+
+type ComposedContext struct {
+	context.Context
+	loggerProvider
+	database *Database
+}
+
+func (c ComposedContext) Database() *Database { return c.database }
+
+var _ AppContext = ComposedContext{}
+
+// Billing isn't part of AppContext any more.
+type StaleContext struct { // want `StaleContext defines Billing, the accessor of BillingContext, which none of its assertions include; remove it, or assert that StaleContext implements BillingContext`
+	context.Context
+	logger   *Logger
+	database *Database
+	billing  *Billing
+}
+
+func (c StaleContext) Logger() *Logger     { return c.logger }
+func (c StaleContext) Database() *Database { return c.database }
+func (c StaleContext) Billing() *Billing   { return c.billing }
+
+// Other methods aren't accessors.
+func (c StaleContext) Name() string { return "stale" }
+
+var _ AppContext = StaleContext{}
+
+// With both assertions, Billing is expected.
+type FullContext struct {
+	StaleContext
+}
+
+var (
+	_ AppContext     = FullContext{}
+	_ BillingContext = FullContext{}
+)
@@ -0,0 +1,94 @@
+// Package typedcontextimplementations exercises TC032 and TC033.
+package typedcontextimplementations
+
+import "context"
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type Database struct{}
+
+func (*Database) Get(key string) string { return key }
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// Between them, the implementations use all of Get's context: only TC033.
+type Store interface {
+	Get(ctx interface { // want `implementations of Store.Get use DatabaseContext \(dbStore.Get\), LoggerContext \(dbStore.Get, memStore.Get\)`
+		DatabaseContext
+		LoggerContext
+	}, key string) string
+}
+
+type dbStore struct{}
+
+func (*dbStore) Get(ctx interface {
+	DatabaseContext
+	LoggerContext
+}, key string) string {
+	ctx.Logger().Log(key)
+	return ctx.Database().Get(key)
+}
+
+type memStore struct{ values map[string]string }
+
+func (s memStore) Get(ctx interface {
+	DatabaseContext
+	LoggerContext
+}, key string) string {
+	ctx.Logger().Log(key)
+	return s.values[key]
+}
+
+// TC032: neither implementation uses SecretsContext, nor, since they don't
+// even name it, anything else.
+type Cache interface {
+	Put(ctx interface { // want `Cache.Put requests interface\(s\) LoggerContext, SecretsContext, which none of its implementations \(memCache.Put, nullCache.Put\) use` `implementations of Cache.Put use LoggerContext \(none\), SecretsContext \(none\)`
+		LoggerContext
+		SecretsContext
+	}, key, value string)
+}
+
+type memCache struct{ values map[string]string }
+
+func (c memCache) Put(_ interface {
+	LoggerContext
+	SecretsContext
+}, key, value string) {
+	c.values[key] = value
+}
+
+type nullCache struct{}
+
+func (nullCache) Put(interface {
+	LoggerContext
+	SecretsContext
+}, string, string) {
+}
+
+// Methods without contexts, or without implementations, aren't reported.
+type Sizer interface {
+	Size() int
+}
+
+func (c memCache) Size() int { return len(c.values) }
+
+type Unimplemented interface {
+	Do(ctx LoggerContext)
+}
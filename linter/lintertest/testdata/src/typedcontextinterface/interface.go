@@ -0,0 +1,295 @@
+// Package typedcontextinterface exercises TC001, TC002, TC003 and TC036.
+package typedcontextinterface
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type BothContext interface {
+	LoggerContext
+	SecretsContext
+}
+
+func log(ctx LoggerContext) {
+	ctx.Logger().Log("hi")
+}
+
+// Requests exactly what it uses: fine.
+func Exact(ctx LoggerContext) {
+	ctx.Logger().Log("hi")
+}
+
+// TC001: requests SecretsContext, but doesn't use it.
+func Unused(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) {
+	ctx.Logger().Log("hi")
+}
+
+// TC002: uses LoggerContext, which it only requests via BothContext.
+func Unrequested(ctx interface {
+	BothContext
+}) {
+	log(ctx)
+	_ = ctx.Secrets()
+}
+
+// TC003: uses nothing it requests.
+func AllUnused(ctx LoggerContext) {} // want `no interfaces requested by ctx are used`
+
+// Unused, but named _: fine.
+func Blank(_ LoggerContext) {}
+
+// Reached via two embeds, the same interface is still one interface: fine.
+func Diamond(ctx interface {
+	BothContext
+	LoggerContext
+}) {
+	log(ctx)
+	_ = ctx.Secrets()
+}
+
+type Provider[T any] interface {
+	context.Context
+	Get() T
+}
+
+func provideLogger(ctx Provider[*Logger]) {
+	_ = ctx.Get()
+}
+
+type AliasContext = LoggerContext
+
+// The instantiation, and the interface behind the alias, are the interfaces
+// requested, even if not the same types.Type: fine.
+func Instantiated(ctx interface {
+	Provider[*Logger]
+	AliasContext
+}) {
+	provideLogger(ctx)
+	log(ctx)
+}
+
+type Flags struct{}
+
+type FlagsContext interface {
+	context.Context
+	Flags() *Flags
+}
+
+func flags(ctx FlagsContext) {
+	_ = ctx.Flags()
+}
+
+func secretsAndTrace(ctx interface {
+	SecretsContext
+	TraceID() string
+}) {
+	_ = ctx.Secrets()
+	_ = ctx.TraceID()
+}
+
+// Between its callees, uses all of FlagsContext, SecretsContext, and
+// TraceID, though neither wants the whole interface: fine.
+func Mixed(ctx interface {
+	FlagsContext
+	SecretsContext
+	TraceID() string
+}) {
+	flags(ctx)
+	secretsAndTrace(ctx)
+}
+
+// TC001: requests RequestID, as well as TraceID, but doesn't use it.
+func MixedUnused(ctx interface { // want `ctx requests but does not use interface\(s\) interface\{RequestID\(\) string\}`
+	LoggerContext
+	TraceID() string
+	RequestID() string
+}) {
+	ctx.Logger().Log(ctx.TraceID())
+}
+
+// Declares its own methods on top of context.Context, and uses them: fine.
+func Inline(ctx interface {
+	context.Context
+	TraceID() string
+}) {
+	_ = ctx.TraceID()
+}
+
+type Handler struct {
+	logger  *Logger
+	secrets *Secrets
+}
+
+// A constructor extracting providers into fields uses their interfaces, as
+// any accessor call does: fine.
+func NewHandler(ctx interface {
+	LoggerContext
+	SecretsContext
+}) *Handler {
+	return &Handler{logger: ctx.Logger(), secrets: ctx.Secrets()}
+}
+
+// Likewise, by assignment: fine.
+func NewHandlerByFields(ctx interface {
+	LoggerContext
+	SecretsContext
+}) *Handler {
+	h := &Handler{}
+	h.logger = ctx.Logger()
+	h.secrets = ctx.Secrets()
+	return h
+}
+
+type options struct {
+	logger *Logger
+	ctx    context.Context
+	typed  LoggerContext
+}
+
+type Option func(*options)
+
+// A functional option's closure uses ctx like any other code, whenever it's
+// applied: it uses LoggerContext, but not SecretsContext.
+func WithLogger(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) Option {
+	return func(o *options) {
+		o.logger = ctx.Logger()
+	}
+}
+
+// Storing ctx as a typed context uses that type: fine.
+func WithTyped(ctx LoggerContext) Option {
+	return Option(func(o *options) {
+		o.typed = ctx
+	})
+}
+
+// TC036: stored as a plain context.Context, ctx's uses happen later, out of
+// sight; it counts as using everything it requests.
+func WithContext(ctx interface {
+	LoggerContext
+	SecretsContext
+}) Option {
+	return func(o *options) {
+		o.ctx = ctx // want `ctx is captured by a returned closure and stored as context.Context`
+	}
+}
+
+// Converting ctx to an interface uses that interface, as a cast does: fine.
+func Converted(ctx BothContext) {
+	log(LoggerContext(ctx))
+	_ = SecretsContext(ctx).Secrets()
+}
+
+// TC001: the conversion only uses LoggerContext.
+func ConvertedUnused(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) {
+	logger := LoggerContext(ctx)
+	log(logger)
+}
+
+// Constant calls of builtins, whose signatures go/types doesn't record,
+// don't use anything: fine.
+func Constant(ctx LoggerContext) int {
+	ctx.Logger().Log("hi")
+	return len("abc")
+}
+
+// Passing ctx to a sink taking a context.Context, like slog.InfoContext, uses
+// context.Context, but none of ctx's typed interfaces: TC001.
+func SinkRoot(ctx LoggerContext) { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	slog.InfoContext(ctx, "hi")
+}
+
+// So requesting context.Context for it is fine.
+func SinkRootExplicit(ctx interface {
+	context.Context
+	LoggerContext
+}) {
+	ctx.Logger().Log("hi")
+	slog.InfoContext(ctx, "hi")
+}
+
+// TC003: passing ctx to a sink taking any, like fmt.Println, uses nothing.
+func SinkAny(ctx LoggerContext) { // want `no interfaces requested by ctx are used`
+	fmt.Println(ctx)
+}
+
+func logAll(ctxs ...LoggerContext) {
+	for _, ctx := range ctxs {
+		log(ctx)
+	}
+}
+
+func printAll(args ...any) {}
+
+func cancelAll(ctxs ...context.Context) {}
+
+// TC001: ctx is passed as an element of the variadic parameter, so it's used
+// as a LoggerContext, not a []LoggerContext.
+func VariadicElem(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) {
+	logAll(ctx)
+}
+
+// TC003: as an element of ...any, it uses nothing.
+func VariadicAny(ctx LoggerContext) { // want `no interfaces requested by ctx are used`
+	printAll(ctx)
+}
+
+// TC001: as an element of ...context.Context, it uses only context.Context.
+func VariadicRoot(ctx LoggerContext) { // want `ctx requests but does not use interface\(s\) LoggerContext`
+	cancelAll(ctx)
+}
+
+// TC001: spread, the slice is the variadic parameter, and ctx is used as its
+// element type.
+func VariadicSpread(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) {
+	logAll([]LoggerContext{ctx}...)
+}
+
+// TC001: append's variadic parameter is of the slice's element type.
+func VariadicAppend(ctx interface { // want `ctx requests but does not use interface\(s\) SecretsContext`
+	LoggerContext
+	SecretsContext
+}) []LoggerContext {
+	var ctxs []LoggerContext
+	return append(ctxs, ctx)
+}
+
+// append([]byte, string...), whose variadic parameter isn't a slice, doesn't
+// use anything: fine.
+func VariadicAppendBytes(ctx LoggerContext, b []byte) []byte {
+	ctx.Logger().Log("hi")
+	return append(b, "abc"...)
+}
@@ -0,0 +1,18 @@
+// Package typedcontextlayout exercises TC013, with the layout rules in its
+// .typedcontext.yaml.
+package typedcontextlayout
+
+import "context"
+
+type Logger struct{}
+
+// TC013: *Context interfaces belong in a .../ctx package.
+type LoggerContext interface { // want `interface LoggerContext must be declared in a package matching .../ctx`
+	context.Context
+	Logger() *Logger
+}
+
+// Not matched by any rule: fine.
+type Loggers interface {
+	Logger() *Logger
+}
@@ -0,0 +1,80 @@
+// Package typedcontextleak exercises TC039.
+package typedcontextleak
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+)
+
+type Logger struct{}
+
+type Secrets struct{ key string }
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type AppContext interface {
+	LoggerContext
+	SecretsContext
+}
+
+type handler struct {
+	ctx  AppContext
+	name string
+}
+
+func Direct(ctx AppContext) {
+	fmt.Printf("%+v\n", ctx)               // want `value holding a SecretsContext passed to Printf`
+	log.Println("handling", ctx)           // want `value holding a SecretsContext passed to Println`
+	data, _ := json.Marshal(ctx)           // want `value holding a SecretsContext passed to Marshal`
+	_ = reflect.ValueOf(ctx)               // want `value holding a SecretsContext passed to ValueOf`
+	json.NewEncoder(os.Stdout).Encode(ctx) // want `value holding a SecretsContext passed to Encode`
+	_ = data
+}
+
+func Secret(ctx SecretsContext) {
+	fmt.Println(ctx) // want `value holding a SecretsContext passed to Println`
+}
+
+// Structs, pointers and slices holding a sensitive context.
+func Holders(ctx AppContext) {
+	h := &handler{ctx: ctx, name: "h"}
+	fmt.Printf("%v\n", h)                  // want `value holding a SecretsContext passed to Printf`
+	_, _ = json.Marshal([]AppContext{ctx}) // want `value holding a SecretsContext passed to Marshal`
+	fmt.Println(h.name)                    // just a string: fine
+}
+
+// Values which have lost their type.
+func Untyped(ctx AppContext) {
+	var value any = ctx
+	fmt.Println(value) // want `value holding a SecretsContext passed to Println`
+	args := []any{"ctx", ctx}
+	fmt.Println(args...) // want `value holding a SecretsContext passed to Println`
+	plain := context.Context(ctx)
+	fmt.Println(plain) // want `value holding a SecretsContext passed to Println`
+	other := value
+	log.Print(other) // want `value holding a SecretsContext passed to Print`
+}
+
+// Contexts without secrets are fine, as are the providers of contexts with
+// them, and functions which aren't sinks.
+func Fine(ctx LoggerContext, app AppContext) {
+	fmt.Println(ctx)
+	fmt.Println(app.Logger())
+	var value any = ctx
+	fmt.Println(value)
+	Use(app)
+}
+
+func Use(ctx AppContext) { _ = ctx.Logger(); _ = ctx.Secrets() }
@@ -0,0 +1,97 @@
+// Package typedcontextmocks exercises TC040.
+package typedcontextmocks
+
+import "context"
+
+type Logger struct{}
+
+type Database struct{}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type AppContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+// appContext is the production context.
+type appContext struct {
+	context.Context
+	logger   *Logger
+	database *Database
+}
+
+func (c *appContext) Logger() *Logger     { return c.logger }
+func (c *appContext) Database() *Database { return c.database }
+
+// MockAppContext is paired with AppContext, the interface, by convention.
+type MockAppContext struct { // want `MockAppContext has no accessor Database, which AppContext` `MockAppContext has accessor Secrets, which AppContext`
+	context.Context
+	LoggerProvider  *Logger
+	SecretsProvider *Secrets
+}
+
+func (m *MockAppContext) Logger() *Logger   { return m.LoggerProvider }
+func (m *MockAppContext) Secrets() *Secrets { return m.SecretsProvider }
+
+// MockServer is paired with server, since there's no Server.
+type MockServer struct { // want `MockServer has no accessor Database, which server`
+	context.Context
+	logger *Logger
+}
+
+func (m MockServer) Logger() *Logger { return m.logger }
+
+type server struct {
+	context.Context
+	logger   *Logger
+	database *Database
+}
+
+func (s server) Logger() *Logger     { return s.logger }
+func (s server) Database() *Database { return s.database }
+
+// An accessor promoted from an embedded composite doesn't count.
+//
+//typedcontext:mock-for appContext
+type FakeContext struct { // want `FakeContext has no accessor Database, which appContext`
+	AppContext
+}
+
+func (f FakeContext) Logger() *Logger { return nil }
+
+// In sync: accessors promoted from the leaf declaring them, or from a
+// concrete type, count.
+//
+//typedcontext:mock-for appContext
+type GoodFake struct {
+	LoggerContext
+	databases
+}
+
+type databases struct{ database *Database }
+
+func (d databases) Database() *Database { return d.database }
+
+//typedcontext:mock-for nosuchContext // want `typedcontext:mock-for names nosuchContext, which isn't a type`
+type Broken struct{}
+
+// Not contexts: ignored.
+type MockClock struct{}
+
+type Clock struct{}
@@ -0,0 +1,6 @@
+package typedcontextmocks
+
+// Mocks in tests are checked too.
+type MockDatabaseContext struct { // want `MockDatabaseContext has no accessor Database, which DatabaseContext`
+	Database *Database
+}
@@ -0,0 +1,10 @@
+package logging
+
+import "context"
+
+type Logger struct{}
+
+type Context interface {
+	context.Context
+	Logger() *Logger
+}
@@ -0,0 +1,75 @@
+// Package typedcontextorder exercises TC041.
+package typedcontextorder
+
+import (
+	"context"
+
+	"typedcontextorder/logging"
+)
+
+type Request struct{}
+type DB struct{}
+
+type RequestContext interface {
+	context.Context
+	Request() *Request
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+
+func Unordered(ctx interface { // want `embeds are out of order; list them as context.Context, DBContext, RequestContext, logging.Context \(context.Context first, then by package and name\)`
+	RequestContext
+	logging.Context
+	context.Context
+	DBContext
+}) {
+}
+
+func ContextLast(ctx interface { // want `embeds are out of order; list them as context.Context, DBContext`
+	DBContext
+	context.Context
+}) {
+}
+
+// Explicit methods may go anywhere.
+func WithMethod(ctx interface { // want `embeds are out of order; list them as DBContext, RequestContext`
+	Flags() map[string]bool
+	RequestContext
+	DBContext
+}) {
+}
+
+// Comments wouldn't move with the embeds, so we don't suggest a fix.
+func Commented(ctx interface { // want `embeds are out of order; list them as DBContext, RequestContext`
+	RequestContext
+	DBContext // for the user's settings
+}) {
+}
+
+func Literal() {
+	_ = func(ctx interface { // want `embeds are out of order; list them as DBContext, RequestContext`
+		RequestContext
+		DBContext
+	}) {
+	}
+}
+
+// Already in order: fine.
+func Ordered(ctx interface {
+	context.Context
+	DBContext
+	RequestContext
+	logging.Context
+}) {
+}
+
+// Named interfaces are left alone.
+type AppContext interface {
+	RequestContext
+	DBContext
+}
+
+func Named(ctx AppContext) {}
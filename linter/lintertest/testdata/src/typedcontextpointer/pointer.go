@@ -0,0 +1,81 @@
+// Package typedcontextpointer exercises TC024.
+package typedcontextpointer
+
+import "context"
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type appContext struct {
+	context.Context
+	logger *Logger
+}
+
+func (c *appContext) Logger() *Logger { return c.logger }
+
+// A method of the concrete context itself: fine.
+func (c *appContext) With(logger *Logger) *appContext {
+	return &appContext{c.Context, logger}
+}
+
+// Requests the interface itself: fine.
+func Plain(ctx LoggerContext) {
+	ctx.Logger().Log("hi")
+}
+
+// Only dereferenced, and only called with &ctx: fixed.
+func load(ctx *LoggerContext, id int) { // want `ctx is a pointer to the context interface LoggerContext; pass the interface itself, which is already a reference`
+	(*ctx).Logger().Log("loading")
+	Plain(*ctx)
+}
+
+func Caller(ctx LoggerContext) {
+	load(&ctx, 1)
+	load((&ctx), 2)
+}
+
+// Exported, so its callers may be anywhere: reported, but not fixed.
+func Exported(ctx *LoggerContext) { // want `ctx is a pointer to the context interface LoggerContext`
+	Plain(*ctx)
+}
+
+// Assigned through: not fixed.
+func replace(ctx *LoggerContext, other LoggerContext) { // want `ctx is a pointer to the context interface LoggerContext`
+	*ctx = other
+}
+
+// Used as a pointer: not fixed.
+func forward(ctx *LoggerContext) { // want `ctx is a pointer to the context interface LoggerContext`
+	replace(ctx, nil)
+}
+
+// Inline interfaces count too; uncalled, so fixed.
+func inline(ctx *interface { // want `ctx is a pointer to the context interface interface\{ \.\.\. \}`
+	context.Context
+	Logger() *Logger
+}) {
+	_ = (*ctx).Logger()
+}
+
+// Ties Concrete to the one implementation.
+func Concrete(ctx *appContext) { // want `ctx is a pointer to the concrete context appContext; request the typed context interfaces it uses instead`
+	ctx.Logger().Log("hi")
+}
+
+// A plain pointer to a non-context: fine.
+func NotContext(logger *Logger) {
+	logger.Log("hi")
+}
+
+type handler struct {
+	ctx  *LoggerContext // want `ctx is a pointer to the context interface LoggerContext; store the interface itself, which is already a reference`
+	base LoggerContext
+}
+
+var _ = func(ctx *LoggerContext) {} // want `ctx is a pointer to the context interface LoggerContext`
@@ -0,0 +1,88 @@
+// Package typedcontextredundant exercises TC008.
+package typedcontextredundant
+
+import "context"
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+func newLogger() *Logger { return &Logger{} }
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+// TC008: logger duplicates ctx.Logger().  But F is exported, so it may have
+// callers we can't see, and there's no fix.
+func F(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
+
+// TC008, and the fix updates its callers, including itself.
+func f(ctx LoggerContext, logger *Logger, n int) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+	if n > 0 {
+		f(ctx, logger, n-1)
+	}
+}
+
+func callsF(ctx LoggerContext) {
+	logger := ctx.Logger()
+	logger.Log("hi")
+	f(ctx, ctx.Logger(), 1)
+	f(ctx, logger, 2)
+}
+
+// TC008, but a caller passes something whose evaluation the fix would
+// remove: no fix.
+func g(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
+
+func callsG(ctx LoggerContext) {
+	g(ctx, newLogger())
+}
+
+// TC008, but a caller passes a variable it uses nowhere else, which the fix
+// would leave unused: no fix.
+func k(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
+
+func callsK(ctx LoggerContext) {
+	logger := ctx.Logger()
+	k(ctx, logger)
+}
+
+// TC008, but h is used as a value, so its signature is fixed: no fix.
+func h(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
+
+var handler func(LoggerContext, *Logger) = h
+
+type Doer interface {
+	Do(ctx LoggerContext, logger *Logger)
+}
+
+type impl struct{}
+
+// TC008, but Do implements Doer, so its signature is fixed: no fix.
+func (impl) Do(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
+
+// TC008, but ctx is shadowed where logger is used: no fix.
+func shadowed(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	for _, ctx := range []int{1} {
+		_ = ctx
+		logger.Log("hi")
+	}
+}
+
+// TC008, but a function literal is a value: no fix.
+var literal = func(ctx LoggerContext, logger *Logger) { // want `logger duplicates ctx.Logger\(\)`
+	logger.Log("hi")
+}
@@ -0,0 +1,93 @@
+// Package typedcontextregistry exercises TC026.
+package typedcontextregistry
+
+import "context"
+
+type Logger struct{}
+
+func (*Logger) Log(string) {}
+
+type Secrets struct{}
+
+type Request struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type AppContext interface {
+	LoggerContext
+	SecretsContext
+}
+
+type Handler func(ctx AppContext, req *Request) error
+
+// Uses everything: fine.
+func handleUsers(ctx AppContext, req *Request) error {
+	ctx.Logger().Log("users")
+	_ = ctx.Secrets()
+	return nil
+}
+
+// Only needs the logger: fixed.
+func handleAvatar(ctx AppContext, req *Request) error {
+	ctx.Logger().Log("avatar")
+	return nil
+}
+
+// Only needs context.Context: fixed.
+func handlePing(ctx AppContext, req *Request) error {
+	return ctx.Err()
+}
+
+// Also used as a value elsewhere: reported, but not fixed.
+func handleSecrets(ctx AppContext, req *Request) error {
+	_ = ctx.Secrets()
+	return nil
+}
+
+var fallback Handler = handleSecrets
+
+var handlers = map[string]Handler{
+	"users":   handleUsers,
+	"avatar":  handleAvatar,  // want `handleAvatar needs only LoggerContext of the AppContext handlers makes it take; narrow its context, and register it via an adapter`
+	"ping":    handlePing,    // want `handlePing needs only a context.Context of the AppContext handlers makes it take`
+	"secrets": handleSecrets, // want `handleSecrets needs only SecretsContext of the AppContext handlers makes it take`
+	"inline": func(ctx AppContext, req *Request) error { // want `this handler needs only LoggerContext of the AppContext handlers makes it take`
+		ctx.Logger().Log("inline")
+		return nil
+	},
+}
+
+func audit(ctx LoggerContext, event string) {
+	ctx.Logger().Log(event)
+}
+
+// Called directly too, which is fine; and in a slice.
+var tasks = []func(AppContext, ...string){
+	func(ctx AppContext, events ...string) {
+		audit(ctx, events[0])
+		_ = ctx.Secrets()
+	},
+	logAll, // want `logAll needs only LoggerContext of the AppContext tasks makes it take`
+}
+
+func logAll(ctx AppContext, events ...string) {
+	for _, event := range events {
+		audit(ctx, event)
+	}
+}
+
+func Run(ctx AppContext) {
+	logAll(ctx, "start")
+	for _, task := range tasks {
+		task(ctx)
+	}
+	_ = handlers["users"](ctx, &Request{})
+}
@@ -0,0 +1,95 @@
+// Package typedcontextrepeated exercises TC043.
+package typedcontextrepeated
+
+import "context"
+
+type DatabaseContext interface {
+	context.Context
+	Database() string
+}
+
+type LoggerContext interface {
+	context.Context
+	Logger() string
+}
+
+type RequestContext interface {
+	context.Context
+	Request() string
+}
+
+type UserContext interface {
+	context.Context
+	User() string
+}
+
+// TC043: the same shape appears here, below, and in reports.go.
+func Charge(ctx interface { // want `interface{ DatabaseContext; LoggerContext; RequestContext } appears 3 times in this package; declare it as a named interface, like DatabaseLoggerRequestContext`
+	context.Context
+	DatabaseContext
+	LoggerContext
+	RequestContext
+}) {
+}
+
+func Refund(ctx interface {
+	context.Context
+	DatabaseContext
+	LoggerContext
+	RequestContext
+}) {
+}
+
+// Only two leaf interfaces: fine.
+func Small(ctx interface {
+	DatabaseContext
+	LoggerContext
+}) {
+}
+
+func SmallAgain(ctx interface {
+	DatabaseContext
+	LoggerContext
+}) {
+}
+
+// Only once: fine.
+func Once(ctx interface {
+	DatabaseContext
+	LoggerContext
+	UserContext
+}) {
+}
+
+// TC043, but the name we'd suggest is taken, so there's no fix.
+type LoggerRequestUserContext struct{}
+
+func Taken(ctx interface { // want `appears 2 times in this package; declare it as a named interface$`
+	LoggerContext
+	RequestContext
+	UserContext
+}) {
+}
+
+func TakenAgain(ctx interface {
+	LoggerContext
+	RequestContext
+	UserContext
+}) {
+}
+
+// TC043, but an embed has a comment, which wouldn't survive, so there's no
+// fix.
+func Commented(ctx interface { // want `interface{ DatabaseContext; RequestContext; UserContext } appears 2 times in this package; declare it as a named interface, like DatabaseRequestUserContext`
+	DatabaseContext
+	RequestContext // the user's request
+	UserContext
+}) {
+}
+
+func CommentedAgain(ctx interface {
+	DatabaseContext
+	RequestContext
+	UserContext
+}) {
+}
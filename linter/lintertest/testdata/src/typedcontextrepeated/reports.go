@@ -0,0 +1,12 @@
+package typedcontextrepeated
+
+import "context"
+
+// The same shape, listed in another order.
+func Audit(ctx interface {
+	RequestContext
+	LoggerContext
+	DatabaseContext
+	context.Context
+}) {
+}
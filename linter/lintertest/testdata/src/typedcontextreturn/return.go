@@ -0,0 +1,23 @@
+// Package typedcontextreturn exercises TC017, with
+// -typedcontextreturn.enable.
+package typedcontextreturn
+
+import "context"
+
+type User struct{}
+
+type AppContext interface {
+	context.Context
+	User() *User
+}
+
+// TC017: not a constructor.
+func lookUpUser(ctx AppContext) (AppContext, error) { // want `lookUpUser returns a context, but only constructors`
+	_ = ctx.User()
+	return ctx, nil
+}
+
+// A constructor: fine.
+func WithUser(ctx context.Context, user *User) AppContext {
+	return nil
+}
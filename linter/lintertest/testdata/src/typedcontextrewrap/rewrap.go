@@ -0,0 +1,88 @@
+// Package typedcontextrewrap exercises TC037.
+package typedcontextrewrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/khan/typed-context/typedcontext"
+)
+
+type Logger struct{}
+
+type Database struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type AppContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+// ComposeAppContext stands in for the constructor typedcontext-gen
+// generates.
+func ComposeAppContext(ctx context.Context, logger *Logger, db *Database) AppContext {
+	return nil
+}
+
+type JobContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+func load(ctx AppContext) {}
+
+// TC037: the result has no providers; typedcontext.WithTimeout keeps them.
+func Timeout(ctx AppContext) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second) // want `context.WithTimeout returns a plain context.Context, without the providers of AppContext; call typedcontext.WithTimeout`
+	defer cancel()
+	load(timeoutCtx.(AppContext))
+}
+
+// Already using the wrapper: fine.
+func Wrapped(ctx AppContext) {
+	ctx, cancel := typedcontext.WithTimeout(ctx, time.Second)
+	defer cancel()
+	load(ctx)
+}
+
+// Putting the result back with WithContext, directly or via a variable:
+// fine.
+func Rewrapped(ctx AppContext) {
+	load(typedcontext.WithContext(ctx, context.WithValue(ctx, "k", "v")))
+	inner, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	load(typedcontext.WithContext(ctx, inner))
+}
+
+// TC037: there's no wrapper for WithCancelCause.
+func Cause(ctx AppContext) context.Context {
+	inner, _ := context.WithCancelCause(ctx) // want `put its result back with typedcontext.WithContext`
+	return inner
+}
+
+// TC037: JobContext has no constructor, so there's no fix.
+func NoConstructor(ctx JobContext) context.Context {
+	inner, _ := context.WithTimeout(ctx, time.Second) // want `generate a constructor for JobContext with typedcontext-gen`
+	return inner
+}
+
+// A single interface isn't a composite: fine.
+func Single(ctx LoggerContext) context.Context {
+	inner, _ := context.WithTimeout(ctx, time.Second)
+	return inner
+}
+
+// Dropping the providers with the cancellation is what WithoutCancel is
+// for: fine.
+func Detached(ctx AppContext) context.Context {
+	return context.WithoutCancel(ctx)
+}
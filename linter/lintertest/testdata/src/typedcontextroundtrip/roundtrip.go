@@ -0,0 +1,98 @@
+// Package typedcontextroundtrip exercises TC042.
+package typedcontextroundtrip
+
+import (
+	"context"
+
+	"github.com/khan/typed-context/typedcontext"
+)
+
+type Logger struct{}
+
+type Database struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+type AppContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+func WithLogger(ctx AppContext, logger *Logger) AppContext { return ctx }
+
+// ComposeAppContext stands in for the constructor typedcontext-gen
+// generates.
+func ComposeAppContext(ctx context.Context, logger *Logger, db *Database) AppContext {
+	return nil
+}
+
+func use(ctx AppContext) {}
+
+// TC042: the logger comes straight back from ctx.
+func ViaVariable(ctx AppContext) {
+	logger := ctx.Logger()
+	ctx2 := WithLogger(ctx, logger) // want `WithLogger only rebuilds ctx from its own Logger\(\); forward ctx instead`
+	use(ctx2)
+}
+
+// TC042, directly, and via typedcontext.Override.
+func Direct(ctx AppContext) {
+	use(WithLogger(ctx, ctx.Logger()))                                        // want `WithLogger only rebuilds ctx`
+	use(typedcontext.Override(ctx, typedcontext.With(ctx.Logger())))          // want `typedcontext.Override only rebuilds ctx from its own Logger\(\)`
+	use(ComposeAppContext(ctx, ctx.Logger(), ctx.Database()))                 // want `ComposeAppContext only rebuilds ctx from its own Logger\(\), Database\(\)`
+	use(typedcontext.Override(ctx, typedcontext.With[*Logger](ctx.Logger()))) // want `typedcontext.Override only rebuilds ctx`
+}
+
+// TC042, though the variable is used again, so it stays.
+func UsedAgain(ctx AppContext) *Logger {
+	logger := ctx.Logger()
+	use(WithLogger(ctx, logger)) // want `WithLogger only rebuilds ctx`
+	return logger
+}
+
+// A new logger: fine.
+func NewLogger(ctx AppContext) {
+	use(WithLogger(ctx, &Logger{}))
+	use(ComposeAppContext(ctx, ctx.Logger(), &Database{}))
+}
+
+// Another context's logger: fine.
+func OtherContext(ctx, other AppContext) {
+	use(WithLogger(ctx, other.Logger()))
+	logger := other.Logger()
+	use(WithLogger(ctx, logger))
+}
+
+// The variable or ctx changes in between: fine.
+func Reassigned(ctx, other AppContext) {
+	logger := ctx.Logger()
+	logger = &Logger{}
+	use(WithLogger(ctx, logger))
+
+	saved := ctx.Logger()
+	ctx = other
+	use(WithLogger(ctx, saved))
+}
+
+func WithDefaultDatabase(ctx context.Context, logger *Logger) AppContext { return nil }
+
+// Building a type ctx isn't: fine.
+func Widening(ctx LoggerContext) {
+	use(WithDefaultDatabase(ctx, ctx.Logger()))
+}
+
+// TC042, but ctx is of another type, so there's no fix.
+func Wider(ctx interface {
+	AppContext
+	Extra() int
+}) {
+	use(WithLogger(ctx, ctx.Logger())) // want `WithLogger only rebuilds ctx`
+}
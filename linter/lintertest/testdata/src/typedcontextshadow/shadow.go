@@ -0,0 +1,32 @@
+// Package typedcontextshadow exercises TC012.
+package typedcontextshadow
+
+import "context"
+
+type Logger struct{}
+type DB struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+
+// TC012: the inner ctx has a different type.
+func F(ctx LoggerContext) {
+	_ = ctx.Logger()
+	if ctx, ok := ctx.(DBContext); ok { // want `context ctx of type DBContext shadows one of type LoggerContext`
+		_ = ctx.DB()
+	}
+}
+
+// The same type: fine.
+func G(ctx LoggerContext) {
+	func(ctx LoggerContext) {
+		_ = ctx.Logger()
+	}(ctx)
+}
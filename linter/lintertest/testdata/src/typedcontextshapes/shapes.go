@@ -0,0 +1,74 @@
+// Package typedcontextshapes exercises TC021, with the inventory in
+// shapes.txt.
+package typedcontextshapes
+
+import "context"
+
+type Logger struct{}
+
+type DB struct{}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// In the inventory: fine.
+func Recorded(ctx interface {
+	LoggerContext
+	DBContext
+}) {
+	_ = ctx.Logger()
+	_ = ctx.DB()
+}
+
+// The same shape, in another order: fine.
+func Reordered(ctx interface {
+	DBContext
+	LoggerContext
+}) {
+	_ = ctx.Logger()
+	_ = ctx.DB()
+}
+
+// TC021: a new combination.
+func New(ctx interface { // want `new typed context shape interface\{ LoggerContext; SecretsContext \}`
+	LoggerContext
+	SecretsContext
+}) {
+	_ = ctx.Logger()
+	_ = ctx.Secrets()
+}
+
+// Reported only at its first use in the package.
+func NewAgain(ctx interface {
+	SecretsContext
+	LoggerContext
+}) {
+	_ = ctx.Logger()
+	_ = ctx.Secrets()
+}
+
+// A single leaf interface isn't a combination: fine.
+func Single(ctx interface{ SecretsContext }) {
+	_ = ctx.Secrets()
+}
+
+// Named composites are in the diff already: fine.
+type AllContext interface {
+	LoggerContext
+	DBContext
+	SecretsContext
+}
@@ -0,0 +1,38 @@
+// Package typedcontextsize exercises TC015, with -typedcontextsize.max=3.
+package typedcontextsize
+
+import "context"
+
+type A interface {
+	context.Context
+	A() int
+}
+type B interface {
+	context.Context
+	B() int
+}
+type C interface {
+	context.Context
+	C() int
+}
+type D interface {
+	context.Context
+	D() int
+}
+
+// TC015: four leaf interfaces.
+type AppContext interface { // want `typed context interface AppContext includes 4 leaf interfaces, more than the limit of 3`
+	context.Context
+	A
+	B
+	C
+	D
+}
+
+// Three: fine.
+type SmallContext interface {
+	context.Context
+	A
+	B
+	C
+}
@@ -0,0 +1,20 @@
+// Package tracing is a tracing library, which may look up its spans by key
+// (its case sets -typedcontextvalue.allowpkgs).
+package tracing
+
+import "context"
+
+type Span struct{}
+
+type TracerContext interface {
+	context.Context
+	Tracer() string
+}
+
+type spanKey struct{}
+
+// Allowed: this package is in -typedcontextvalue.allowpkgs.
+func SpanFromContext(ctx TracerContext) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,24 @@
+// Package typedcontextvalue exercises TC014.
+package typedcontextvalue
+
+import "context"
+
+type Logger struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type userIDKey struct{}
+
+// TC014: the user ID should be an accessor.
+func F(ctx LoggerContext) string {
+	_ = ctx.Logger()
+	return ctx.Value(userIDKey{}).(string) // want `ctx.Value bypasses the typed context`
+}
+
+// A plain context.Context: fine.
+func G(ctx context.Context) string {
+	return ctx.Value(userIDKey{}).(string)
+}
@@ -0,0 +1,43 @@
+// Package typedcontextwrapper exercises TC020.
+package typedcontextwrapper
+
+import "context"
+
+type Logger struct{}
+type DB struct{}
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+type DBContext interface {
+	context.Context
+	DB() *DB
+}
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type Wide interface {
+	LoggerContext
+	DBContext
+	SecretsContext
+}
+
+// TC020: the result adds SecretsContext, which neither f nor the wrapper uses.
+func logged(f func(ctx DBContext) error) func(ctx Wide) error { // want `logged widens the context of the function it wraps from DBContext to Wide, which needlessly adds SecretsContext`
+	return func(ctx Wide) error {
+		_ = ctx.Logger()
+		return f(ctx)
+	}
+}
+
+// The same context type as f: fine.
+func logged2(f func(ctx Wide) error) func(ctx Wide) error {
+	return func(ctx Wide) error {
+		_ = ctx.Logger()
+		return f(ctx)
+	}
+}
@@ -0,0 +1,9 @@
+// Package jobs declares an interface with a method taking a context, which
+// package unusedroots implements without importing this package.
+package jobs
+
+import "context"
+
+type Job interface {
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,12 @@
+// Package queue runs jobs.
+package queue
+
+import (
+	"context"
+
+	"unusedroots/jobs"
+)
+
+func Submit(ctx context.Context, job jobs.Job) error {
+	return job.Run(ctx)
+}
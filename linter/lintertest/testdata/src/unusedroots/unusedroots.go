@@ -0,0 +1,94 @@
+// Package unusedroots exercises -typedcontextinterface.unusedroots.
+package unusedroots
+
+import (
+	"context"
+	"fmt"
+
+	"unusedroots/queue"
+)
+
+type Image struct{ width int }
+
+func resize(ctx context.Context, img *Image) *Image { // want `ctx is never used; remove it, or rename it to _ if it's needed to match some signature`
+	return &Image{width: img.width / 2}
+}
+
+func crop(ctx context.Context, img *Image) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func describe(ctx context.Context, img *Image) string {
+	return fmt.Sprint(ctx, img.width)
+}
+
+func unnamed(_ context.Context, img *Image) *Image {
+	return img
+}
+
+// Exported: callers in other packages may pass one.
+func Resize(ctx context.Context, img *Image) *Image {
+	return resize(ctx, img)
+}
+
+type thumbnailer struct{}
+
+// Exported method of an unexported type: not API.
+func (thumbnailer) Thumbnail(ctx context.Context, img *Image) *Image { // want `ctx is never used`
+	return img
+}
+
+// Implements jobs.Job, which this package doesn't import.
+type cleanup struct{}
+
+func (cleanup) Run(ctx context.Context) error {
+	return nil
+}
+
+// Implements a local interface.
+type resizer interface {
+	resize(ctx context.Context, img *Image) *Image
+}
+
+type halver struct{}
+
+func (*halver) resize(ctx context.Context, img *Image) *Image {
+	return &Image{width: img.width / 2}
+}
+
+var _ resizer = &halver{}
+
+// Not an implementation: no interface has a method of this name.
+func (*halver) double(ctx context.Context, img *Image) *Image { // want `ctx is never used`
+	return &Image{width: img.width * 2}
+}
+
+// Used as a value, so it must match some signature.
+func onDone(ctx context.Context, err error) {
+	fmt.Println(err)
+}
+
+func notify(callback func(context.Context, error)) {
+	callback(context.Background(), nil)
+}
+
+// Typed contexts are the interface analyzer's usual business.
+type LoggerContext interface {
+	context.Context
+	Logger() string
+}
+
+func logNothing(ctx LoggerContext) {} // want `no interfaces requested by ctx are used`
+
+func Run(ctx LoggerContext, img *Image) string {
+	_ = Resize(ctx, resize(ctx, img))
+	_ = crop(ctx, unnamed(ctx, img))
+	_ = thumbnailer{}.Thumbnail(ctx, (&halver{}).double(ctx, img))
+	notify(onDone)
+	logNothing(ctx)
+	_ = queue.Submit(ctx, cleanup{})
+	return describe(ctx, img) + ctx.Logger()
+}
@@ -0,0 +1,30 @@
+// Package app uses the typed contexts of package contexts.
+package app
+
+import "wholeprogram/contexts"
+
+func Serve(ctx contexts.AppContext) {
+	_ = ctx.Logger()
+}
+
+func Query(ctx contexts.BothContext) {
+	_ = ctx.Logger()
+	_ = ctx.Database()
+}
+
+func handle(ctx interface {
+	contexts.LoggerContext
+	contexts.SecretsContext
+}) error {
+	_ = ctx.Logger()
+	return nil
+}
+
+func hook(ctx contexts.LoggerContext) {
+	_ = ctx.Logger()
+}
+
+var (
+	Handlers = []contexts.HandlerFunc{handle}
+	Hooks    = []contexts.HookFunc{hook}
+)
@@ -0,0 +1,54 @@
+// Package contexts declares the typed contexts, used by package app, which
+// exercise the whole-program modes: TC007, TC018 and TC038.  See
+// wholeprogram_test.go.
+package contexts
+
+import "context"
+
+type Logger struct{}
+
+type Secrets struct{}
+
+type Database struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+type DatabaseContext interface {
+	context.Context
+	Database() *Database
+}
+
+// TC007: nothing requests DeadContext.
+type DeadContext interface {
+	context.Context
+	Dead() int
+}
+
+// TC018: nothing requesting AppContext uses its SecretsContext.
+type AppContext interface {
+	LoggerContext
+	SecretsContext
+}
+
+// Everything requesting BothContext uses all of it: fine.
+type BothContext interface {
+	LoggerContext
+	DatabaseContext
+}
+
+// TC038: no HandlerFunc uses its context's SecretsContext.
+type HandlerFunc func(ctx interface {
+	LoggerContext
+	SecretsContext
+}) error
+
+// Every function assigned to HookFunc uses all of its context: fine.
+type HookFunc func(ctx LoggerContext)
@@ -0,0 +1,150 @@
+package lintertest_test
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	contextLinter "github.com/khan/typed-context/linter"
+	"github.com/khan/typed-context/linter/lintertest"
+)
+
+// The whole-program modes load packages with go/packages rather than
+// analysistest, so we point the go command at a copy of the corpus, in
+// GOPATH mode, and check what they return.  Package wholeprogram/contexts
+// declares the interfaces and package wholeprogram/app uses them; package
+// minimize is rewritten by Minimize.
+
+// wholeProgram copies the corpus, and sets up the environment to load its
+// packages by import path, until the test ends; it returns the copy's src
+// directory.
+func wholeProgram(t *testing.T) string {
+	dir := lintertest.Corpus(t)
+	t.Setenv("GOPATH", dir)
+	t.Setenv("GO111MODULE", "off")
+	t.Setenv("GOFLAGS", "")
+	t.Chdir(dir)
+	contextLinter.ResetSettings()
+	t.Cleanup(contextLinter.ResetSettings)
+	return filepath.Join(dir, "src")
+}
+
+// where returns the given position as path:line, relative to src.
+func where(t *testing.T, src string, position token.Position) string {
+	rel, err := filepath.Rel(src, position.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.ToSlash(rel) + ":" + strconv.Itoa(position.Line)
+}
+
+func TestDeadInterfaces(t *testing.T) {
+	src := wholeProgram(t)
+	dead, err := contextLinter.FindDeadInterfaces("wholeprogram/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, iface := range dead {
+		got = append(got, iface.Name+" at "+where(t, src, iface.Position))
+	}
+	want := []string{"wholeprogram/contexts.DeadContext at wholeprogram/contexts/contexts.go:30"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got dead interfaces %q, want %q", got, want)
+	}
+}
+
+func TestUnusedEmbeds(t *testing.T) {
+	src := wholeProgram(t)
+	unused, err := contextLinter.FindUnusedEmbeds("wholeprogram/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, embed := range unused {
+		got = append(got, embed.Interface+" embeds "+embed.Embed+" at "+where(t, src, embed.Position))
+	}
+	want := []string{
+		"wholeprogram/contexts.AppContext embeds wholeprogram/contexts.SecretsContext at wholeprogram/contexts/contexts.go:38",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got unused embeds %q, want %q", got, want)
+	}
+}
+
+func TestWideFuncTypes(t *testing.T) {
+	src := wholeProgram(t)
+	wide, err := contextLinter.FindWideFuncTypes("wholeprogram/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, decl := range wide {
+		got = append(got, decl.Declaration+"("+decl.Param+") doesn't use "+
+			strings.Join(decl.Unused, ", ")+" in "+strconv.Itoa(decl.Implementations)+
+			" implementations at "+where(t, src, decl.Position))
+	}
+	want := []string{
+		"wholeprogram/contexts.HandlerFunc(ctx) doesn't use SecretsContext in 1 implementations at wholeprogram/contexts/contexts.go:48",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got wide function types %q, want %q", got, want)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	src := wholeProgram(t)
+	plan, err := contextLinter.Minimize(2, "minimize")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, merge := range plan.Merges {
+		got = append(got, "merge "+merge.Interface+" into "+merge.Into+" at "+where(t, src, merge.Position))
+	}
+	for _, iface := range plan.Interfaces {
+		got = append(got, "add "+iface.Name+" embedding "+strings.Join(iface.Leaves, ", ")+
+			" for "+strconv.Itoa(iface.Params)+" parameters")
+	}
+	for _, rewrite := range plan.Rewrites {
+		got = append(got, "change "+rewrite.Function+" from "+rewrite.Old+" to "+rewrite.New+
+			" at "+where(t, src, rewrite.Position))
+	}
+	for _, skipped := range plan.Skipped {
+		got = append(got, "skip "+skipped.Function+" since "+skipped.Reason+
+			" at "+where(t, src, skipped.Position))
+	}
+	want := []string{
+		"merge minimize.appContext into AppContext at minimize/minimize.go:37",
+		"add minimize.LoggerDatabaseContext embedding LoggerContext, DatabaseContext for 2 parameters",
+		"change Log from AppContext to LoggerContext at minimize/minimize.go:44",
+		"change Query from AppContext to LoggerDatabaseContext at minimize/minimize.go:57",
+		"change store from interface{LoggerContext; DatabaseContext; SecretsContext} to LoggerDatabaseContext at minimize/minimize.go:62",
+		"skip impl.Do since it's a method implementing an interface, which fixes its signature at minimize/minimize.go:78",
+		"skip Fn since its function is used as a value, which must match some signature at minimize/minimize.go:85",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got plan\n\t%s\nwant\n\t%s", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(src, "minimize", "minimize.go")
+	rewritten, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filename + ".golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rewritten) != string(golden) {
+		t.Errorf("rewritten minimize.go:\n%s\nwant:\n%s", rewritten, golden)
+	}
+}
@@ -0,0 +1,187 @@
+package linter
+
+// This file computes metrics about the size of the typed context interfaces
+// in a program, for tracking on a dashboard whether contexts are bloating
+// over time.  (That's the -metrics mode of the linter command.)
+//
+// For each context parameter of each function, we record how many leaf
+// interfaces it requests and how many of those it uses (as computed by the
+// interface analyzer), and how deeply its type's embeddings nest; for each
+// package, how many composite interfaces it defines.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// FunctionMetrics describes one context parameter of a function.
+type FunctionMetrics struct {
+	// Function is the name of the function, as "Func" or "Type.Method".
+	Function string `json:"function"`
+	// Position is the position of the parameter, as "file:line:col".
+	Position string `json:"position"`
+	// Param is the name of the parameter.
+	Param string `json:"param"`
+	// Type is the parameter's declared type.
+	Type string `json:"type"`
+	// LeavesRequested is the number of leaf interfaces (see
+	// analysisengine.LeafInterfaces) the type includes, other than
+	// context.Context.
+	LeavesRequested int `json:"leavesRequested"`
+	// LeavesUsed is the number of those the function uses.
+	LeavesUsed int `json:"leavesUsed"`
+	// MaxEmbeddingDepth is how deeply the type's embedded interfaces nest:
+	// 0 for an interface which embeds nothing, like context.Context, 1 for
+	// one which only embeds such interfaces, and so on.
+	MaxEmbeddingDepth int `json:"maxEmbeddingDepth"`
+}
+
+// PackageMetrics describes the typed contexts of one package.
+type PackageMetrics struct {
+	// Path is the package's import path.
+	Path string `json:"path"`
+	// CompositeInterfaces is the number of composite interfaces the package
+	// defines: named typed context interfaces which declare no methods of
+	// their own, only embed two or more other interfaces.
+	CompositeInterfaces int `json:"compositeInterfaces"`
+	// Functions has an entry for each typed context parameter of each
+	// function declared in the package, in order of position.
+	Functions []FunctionMetrics `json:"functions"`
+}
+
+// _embeddingDepth returns how deeply the embedded interfaces of typ nest
+// (see FunctionMetrics.MaxEmbeddingDepth).
+func _embeddingDepth(typ types.Type) int {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return 0
+	}
+	depth := 0
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if embedDepth := 1 + _embeddingDepth(iface.EmbeddedType(i)); embedDepth > depth {
+			depth = embedDepth
+		}
+	}
+	return depth
+}
+
+// _countCompositeInterfaces returns the number of composite interfaces (see
+// PackageMetrics.CompositeInterfaces) declared in the given package.
+func _countCompositeInterfaces(pkg *packages.Package) int {
+	count := 0
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || _skipFile(pkg.Fset.File(obj.Pos()).Name(), pkg.Types) {
+			continue
+		}
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if ok && isContextType(obj.Type()) &&
+			iface.NumExplicitMethods() == 0 && iface.NumEmbeddeds() >= 2 {
+			count++
+		}
+	}
+	return count
+}
+
+// _packageMetrics computes the metrics for the given package.
+func _packageMetrics(pkg *packages.Package) (PackageMetrics, error) {
+	metrics := PackageMetrics{
+		Path:                pkg.PkgPath,
+		CompositeInterfaces: _countCompositeInterfaces(pkg),
+		Functions:           []FunctionMetrics{},
+	}
+
+	settings, err := loadSettings(&analysis.Pass{
+		Fset: pkg.Fset, Files: pkg.Syntax, Pkg: pkg.Types,
+	})
+	if err != nil {
+		return metrics, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return metrics, err
+	}
+	tracker := analysisengine.NewTracker(pkg.TypesInfo, pkg.Types, options)
+	tracker.Track(pkg.Syntax)
+	for _, file := range pkg.Syntax {
+		tracker.MarkUses(file)
+	}
+
+	for _, file := range pkg.Syntax {
+		if _skipFile(pkg.Fset.File(file.Pos()).Name(), pkg.Types) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			name := funcDecl.Name.Name
+			if recv := _receiverTypeName(funcDecl); recv != "" {
+				name = recv + "." + name
+			}
+			for _, field := range funcDecl.Type.Params.List {
+				for _, ident := range field.Names {
+					obj := pkg.TypesInfo.Defs[ident]
+					info := tracker.Usage(obj)
+					if info == nil {
+						continue // not a typed context, or not tracked
+					}
+					function := FunctionMetrics{
+						Function:          name,
+						Position:          pkg.Fset.Position(ident.Pos()).String(),
+						Param:             ident.Name,
+						Type:              types.TypeString(obj.Type(), types.RelativeTo(pkg.Types)),
+						MaxEmbeddingDepth: _embeddingDepth(obj.Type()),
+					}
+					for _, leaf := range analysisengine.LeafInterfaces(obj.Type()) {
+						if isContextRoot(leaf) {
+							continue
+						}
+						function.LeavesRequested++
+						if info.InterfaceWasUsed(leaf) {
+							function.LeavesUsed++
+						}
+					}
+					metrics.Functions = append(metrics.Functions, function)
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// Metrics returns the interface-size metrics of the packages matching the
+// given patterns (not including their tests), sorted by path.
+//
+// Settings are taken from the typedcontextinterface flags and any
+// configuration files, as for the analyzer.
+func Metrics(patterns ...string) ([]PackageMetrics, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	var result []PackageMetrics
+	for _, pkg := range pkgs {
+		metrics, err := _packageMetrics(pkg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, metrics)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
@@ -0,0 +1,802 @@
+package linter
+
+// This file computes a minimal hierarchy of typed context interfaces for a
+// whole program, and rewrites the program to use it.  (That's the -minimize
+// mode of the linter command.)  The analyzers report problems one context at
+// a time; this is the end state they point toward:
+//
+//   - Duplicate composite interfaces -- named interfaces in the same package
+//     with the same leaf interfaces (see analysisengine.LeafInterfaces) --
+//     are merged into the first, by position: references to the others are
+//     rewritten, and they're deleted, or, if they're exported, kept as
+//     aliases so that code outside the program still compiles.
+//   - Each context parameter which requests more than it needs (see
+//     analysisengine.Usage.Requirements) is rewritten to request just that,
+//     like the interface analyzer's TC001 asks.
+//   - A set of leaves which at least threshold parameters in a package need
+//     gets a name: a composite interface already declared in the package
+//     with just those leaves, or else a new one, declared at the end of the
+//     first file needing it; the parameters request it by name.  Other
+//     sets are written inline (and other parameters which already request
+//     just what they need are left alone).
+//
+// Parameters are only rewritten where the interface analyzer would look at
+// them (so not in tests, by default), only if the file already imports the
+// packages of the interfaces they need, and only if their function's
+// signature isn't fixed: by being used as a value, or by implementing an
+// interface method (see _fixedSignatures); the others are listed as
+// skipped.  And the changes are only written if the rewritten packages
+// type-check.  Requirements come from the callees' current signatures, so when
+// callees are narrowed their callers may be able to narrow further: run it
+// again until it has nothing more to do.
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// MinimizeMerge is a composite interface merged into an identical one.
+type MinimizeMerge struct {
+	// Interface is the merged interface, as "import/path.Name".
+	Interface string
+	// Into is the name of the interface it's merged into.
+	Into string
+	// Position is the position of the merged interface's declaration.
+	Position token.Position
+}
+
+// MinimizeInterface is a new composite interface, naming a set of leaves
+// many parameters need.
+type MinimizeInterface struct {
+	// Name is the new interface, as "import/path.Name".
+	Name string
+	// Leaves are its embeds, as written in its declaration.
+	Leaves []string
+	// Params is the number of parameters which will request it.
+	Params int
+	// File is the file it will be declared in, at the end.
+	File string
+}
+
+// MinimizeRewrite is a change to the type of a context parameter.
+type MinimizeRewrite struct {
+	// Position is the position of the parameter's type.
+	Position token.Position
+	// Function is the name of the function, as "Func" or "Type.Method".
+	Function string
+	// Params are the names of the parameters declared with the type.
+	Params []string
+	// Old and New are the old and new types, on one line.
+	Old, New string
+}
+
+// MinimizeSkip is a context parameter which requests more than it needs,
+// but which Minimize can't rewrite.
+type MinimizeSkip struct {
+	// Position is the position of the parameter's type.
+	Position token.Position
+	// Function and Params are as for MinimizeRewrite.
+	Function string
+	Params   []string
+	// Reason says why it's skipped.
+	Reason string
+}
+
+// MinimizePlan is the set of changes computed by Minimize.
+type MinimizePlan struct {
+	Merges     []MinimizeMerge
+	Interfaces []MinimizeInterface
+	Rewrites   []MinimizeRewrite
+	Skipped    []MinimizeSkip
+
+	// edits are the changes, by filename.
+	edits map[string][]_minimizeEdit
+	// patterns are the patterns the packages were loaded with, so Apply can
+	// check that they still type-check.
+	patterns []string
+}
+
+// _minimizeEdit replaces the bytes between two offsets of a file.
+type _minimizeEdit struct {
+	start, end int
+	text       string
+}
+
+// Empty returns true if the plan changes nothing.
+func (plan *MinimizePlan) Empty() bool {
+	return len(plan.edits) == 0
+}
+
+// _addEdit adds an edit to the plan, unless it overlaps one already added
+// (other than an identical one, which is the same change found twice, e.g.
+// via a package and its test variant); it returns whether it did.
+func (plan *MinimizePlan) _addEdit(position token.Position, end int, text string) bool {
+	edit := _minimizeEdit{position.Offset, end, text}
+	for _, other := range plan.edits[position.Filename] {
+		if other == edit {
+			return true
+		}
+		if edit.start < other.end && other.start < edit.end ||
+			edit.start == other.start {
+			return false
+		}
+	}
+	plan.edits[position.Filename] = append(plan.edits[position.Filename], edit)
+	return true
+}
+
+// Apply makes the changes in the plan, gofmt-ing the files it changes.  It
+// writes nothing unless every changed file can be formatted, and the
+// packages still type-check with the changes.
+func (plan *MinimizePlan) Apply() error {
+	contents := map[string][]byte{}
+	for filename, edits := range plan.edits {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+		var out bytes.Buffer
+		last := 0
+		for _, edit := range edits {
+			if edit.end > len(src) {
+				return fmt.Errorf("%s has changed since it was analyzed", filename)
+			}
+			out.Write(src[last:edit.start])
+			out.WriteString(edit.text)
+			last = edit.end
+		}
+		out.Write(src[last:])
+		formatted, err := format.Source(out.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s: rewritten file doesn't format, so not applying any changes: %v", filename, err)
+		}
+		contents[filename] = formatted
+	}
+	if err := plan._typeCheck(contents); err != nil {
+		return err
+	}
+	for filename, content := range contents {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, content, info.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// _typeCheck returns an error if the packages the plan was made for don't
+// type-check with the given contents of the changed files.
+func (plan *MinimizePlan) _typeCheck(contents map[string][]byte) error {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true, Overlay: contents}
+	pkgs, err := packages.Load(config, plan.patterns...)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err.Error())
+		}
+	})
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("rewritten packages don't type-check, so not applying any changes:\n\t%s",
+			strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// _leafKey returns the key identifying a set of leaves: their full names,
+// sorted.
+func _leafKey(leaves []types.Type) string {
+	names := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		names[i] = types.TypeString(leaf, nil)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// _minimizeFile is a file whose declarations Minimize looks at, along with
+// the package it was loaded as part of.
+type _minimizeFile struct {
+	pkg  *packages.Package
+	file *ast.File
+	name string
+}
+
+// _minimizeFiles returns the files of the given packages, each once: the
+// non-test files from the packages themselves, and the test files from
+// their test variants.
+func _minimizeFiles(pkgs []*packages.Package) []_minimizeFile {
+	var files []_minimizeFile
+	seen := map[string]bool{}
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue // the generated test main
+		}
+		variant := pkg.ID != pkg.PkgPath
+		for _, file := range pkg.Syntax {
+			name := pkg.Fset.File(file.Pos()).Name()
+			if seen[name] || variant != strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			seen[name] = true
+			files = append(files, _minimizeFile{pkg, file, name})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		// Non-test files first, so new interfaces are declared in them.
+		iTest := strings.HasSuffix(files[i].name, "_test.go")
+		jTest := strings.HasSuffix(files[j].name, "_test.go")
+		if iTest != jTest {
+			return jTest
+		}
+		return files[i].name < files[j].name
+	})
+	return files
+}
+
+// _composite is a named composite typed context interface.
+type _composite struct {
+	obj  *types.TypeName
+	key  string
+	spec *ast.TypeSpec
+	decl *ast.GenDecl
+	in   _minimizeFile
+}
+
+// _composites returns the named composite typed context interfaces declared
+// in the given file.
+func _composites(in _minimizeFile) []_composite {
+	var composites []_composite
+	for _, decl := range in.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			spec := spec.(*ast.TypeSpec)
+			obj, ok := in.pkg.TypesInfo.Defs[spec.Name].(*types.TypeName)
+			if !ok || spec.Assign.IsValid() || spec.TypeParams != nil || !isContextType(obj.Type()) {
+				continue
+			}
+			iface, ok := obj.Type().Underlying().(*types.Interface)
+			if !ok || iface.NumExplicitMethods() > 0 || iface.NumEmbeddeds() < 2 {
+				continue
+			}
+			composites = append(composites, _composite{
+				obj, _leafKey(_distinctLeaves(obj.Type())), spec, genDecl, in,
+			})
+		}
+	}
+	return composites
+}
+
+// _typeQualifier returns the name by which the given file refers to the
+// package with the given path, if it imports it.
+func _typeQualifier(file *ast.File, path string, defaultName string) (string, bool) {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || importPath != path {
+			continue
+		}
+		switch {
+		case spec.Name == nil:
+			return defaultName, true
+		case spec.Name.Name == "_":
+			continue
+		default:
+			return spec.Name.Name, true
+		}
+	}
+	return "", false
+}
+
+// _typeText returns how the given named type is written in the given file
+// of pkg, or an error saying why it can't be.
+func _typeText(typ types.Type, file *ast.File, pkg *types.Package) (string, error) {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return "", fmt.Errorf("%s isn't a named type", typ)
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg() == pkg {
+		return obj.Name(), nil
+	}
+	if !obj.Exported() {
+		return "", fmt.Errorf("%s isn't exported", _qualifiedName(named))
+	}
+	qualifier, ok := _typeQualifier(file, obj.Pkg().Path(), obj.Pkg().Name())
+	if !ok {
+		return "", fmt.Errorf("it would need to import %s", obj.Pkg().Path())
+	}
+	if qualifier == "." {
+		return obj.Name(), nil
+	}
+	return qualifier + "." + obj.Name(), nil
+}
+
+// _minimizeParam is a field of context parameters which request more than
+// they need, or which could request a named interface instead.
+type _minimizeParam struct {
+	in       _minimizeFile
+	function string
+	// declared is the position of the function's name, which identifies
+	// it across the variants of its package.
+	declared token.Position
+	field    *ast.Field
+	// current are the leaves the field's type has; needed are those the
+	// parameters need, in the same order.
+	current, needed []types.Type
+	// named is set if the field's type is named.
+	named bool
+}
+
+// _minimizeParams returns the context parameters declared in the given
+// file, with what they need.
+func _minimizeParams(in _minimizeFile, tracker *analysisengine.Tracker) []*_minimizeParam {
+	var params []*_minimizeParam
+	for _, decl := range in.file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		function := funcDecl.Name.Name
+		if recv := _receiverTypeName(funcDecl); recv != "" {
+			function = recv + "." + function
+		}
+		for _, field := range funcDecl.Type.Params.List {
+			if _, ok := field.Type.(*ast.Ellipsis); ok || len(field.Names) == 0 {
+				continue
+			}
+			param := _fieldNeeds(in, field, tracker)
+			if param != nil {
+				param.function = function
+				param.declared = in.pkg.Fset.Position(funcDecl.Name.Pos())
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+// _fieldNeeds returns what the parameters declared by the given field need,
+// if they're tracked contexts and it's all provided by their type.
+func _fieldNeeds(in _minimizeFile, field *ast.Field, tracker *analysisengine.Tracker) *_minimizeParam {
+	typ := in.pkg.TypesInfo.TypeOf(field.Type)
+	if typ == nil {
+		return nil
+	}
+	current := _distinctLeaves(typ)
+	var needed []types.Type
+	for _, name := range field.Names {
+		obj := in.pkg.TypesInfo.Defs[name]
+		info := tracker.Usage(obj)
+		if info == nil || tracker.IsAlias(obj) || info.DerivedFrom() != nil {
+			return nil
+		}
+		if allUnused, _, _ := info.Problems(); allUnused {
+			return nil // the interface analyzer says to rename it to _
+		}
+		for _, requirement := range info.Requirements() {
+			for _, leaf := range _distinctLeaves(requirement) {
+				if !_containsLeaf(current, leaf) {
+					return nil // provided some other way; leave it be
+				}
+				if !_containsLeaf(needed, leaf) {
+					needed = append(needed, leaf)
+				}
+			}
+		}
+	}
+	var ordered []types.Type
+	for _, leaf := range current {
+		if _containsLeaf(needed, leaf) {
+			ordered = append(ordered, leaf)
+		}
+	}
+	_, named := typ.(*types.Named)
+	return &_minimizeParam{in: in, field: field, current: current, needed: ordered, named: named}
+}
+
+// _fixedSignatures returns why each function or method of the given
+// packages, by the position of its name, can't have its parameters'
+// types changed, if it can't: because it's referred to other than by direct
+// calls, like `var h func(C) = f`, so it must match some signature, or
+// because it's a method which implements an interface.  A function
+// declared in a package with tests has an object in each variant of the
+// package, so we check them all.
+func _fixedSignatures(pkgs []*packages.Package) map[token.Position]string {
+	var typesPkgs []*types.Package
+	var infos []*types.Info
+	for _, pkg := range pkgs {
+		typesPkgs = append(typesPkgs, pkg.Types)
+		infos = append(infos, pkg.TypesInfo)
+	}
+	interfaces := _interfaceTypes(typesPkgs, infos)
+
+	reasons := map[token.Position]string{}
+	for _, pkg := range pkgs {
+		for obj := range _funcValues(pkg.TypesInfo, pkg.Syntax) {
+			if obj.Pkg() != nil && obj.Pos().IsValid() {
+				reasons[pkg.Fset.Position(obj.Pos())] = "its function is used as a value, which must match some signature"
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, obj := range pkg.TypesInfo.Defs {
+			fn, ok := obj.(*types.Func)
+			if !ok || fn.Type().(*types.Signature).Recv() == nil {
+				continue
+			}
+			position := pkg.Fset.Position(fn.Pos())
+			if reasons[position] == "" && _implementsAny(fn, interfaces) {
+				reasons[position] = "it's a method implementing an interface, which fixes its signature"
+			}
+		}
+	}
+	return reasons
+}
+
+// _newInterfaceName returns a name for a new interface embedding the given
+// leaves, like LoggerSecretsContext, which isn't taken in the given scope.
+func _newInterfaceName(leaves []types.Type, scope *types.Scope, taken map[string]bool) string {
+	var base strings.Builder
+	for _, leaf := range leaves {
+		name := leaf.String()
+		if named, ok := leaf.(*types.Named); ok {
+			name = named.Obj().Name()
+		}
+		name = strings.TrimSuffix(name, "Context")
+		base.WriteString(strings.ToUpper(name[:1]) + name[1:])
+	}
+	base.WriteString("Context")
+	name := base.String()
+	for i := 2; scope.Lookup(name) != nil || taken[name]; i++ {
+		name = base.String() + strconv.Itoa(i)
+	}
+	taken[name] = true
+	return name
+}
+
+// _positionLess orders positions by file, then offset.
+func _positionLess(position, other token.Position) bool {
+	if position.Filename != other.Filename {
+		return position.Filename < other.Filename
+	}
+	return position.Offset < other.Offset
+}
+
+// _oneLine returns the given type expression on one line.
+func _oneLine(expr string) string {
+	return strings.Join(strings.Fields(expr), " ")
+}
+
+// Minimize computes the changes to make the typed context interfaces of the
+// packages matching the given patterns (including their tests) minimal, as
+// described at the top of this file: merging duplicate composite
+// interfaces, naming the sets of leaves at least threshold parameters in a
+// package need, and rewriting parameters to request what they need.  Call
+// Apply on the result to make them.
+//
+// Like FindDeadInterfaces, it should be run over the whole program.
+// Settings are taken from the typedcontextinterface flags and any
+// configuration files, as for the analyzer.
+func Minimize(threshold int, patterns ...string) (*MinimizePlan, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+	plan := &MinimizePlan{edits: map[string][]_minimizeEdit{}, patterns: patterns}
+	files := _minimizeFiles(pkgs)
+
+	// First, the duplicate composites, grouped by package path and key.
+	groups := map[string]map[string][]_composite{}
+	var keys [][2]string
+	for _, in := range files {
+		if _skipFile(in.name, in.pkg.Types) {
+			continue
+		}
+		for _, composite := range _composites(in) {
+			pkgPath := in.pkg.PkgPath
+			if groups[pkgPath] == nil {
+				groups[pkgPath] = map[string][]_composite{}
+			}
+			if groups[pkgPath][composite.key] == nil {
+				keys = append(keys, [2]string{pkgPath, composite.key})
+			}
+			groups[pkgPath][composite.key] = append(groups[pkgPath][composite.key], composite)
+		}
+	}
+	// names maps the key of each set of leaves with a composite to its name,
+	// by package path; merged maps the position of each merged composite to
+	// the name of the one it's merged into.
+	names := map[string]map[string]string{}
+	merged := map[token.Position]string{}
+	for _, key := range keys {
+		group := groups[key[0]][key[1]]
+		// Merge into the first exported one, if any, since others may be
+		// referred to from other packages.
+		into := group[0]
+		for _, composite := range group {
+			if composite.obj.Exported() {
+				into = composite
+				break
+			}
+		}
+		if names[key[0]] == nil {
+			names[key[0]] = map[string]string{}
+		}
+		names[key[0]][key[1]] = into.obj.Name()
+		for _, composite := range group {
+			if composite.obj == into.obj {
+				continue
+			}
+			fset := composite.in.pkg.Fset
+			position := fset.Position(composite.obj.Pos())
+			merged[position] = into.obj.Name()
+			plan.Merges = append(plan.Merges, MinimizeMerge{
+				Interface: _qualifiedName(composite.obj.Type().(*types.Named)),
+				Into:      into.obj.Name(),
+				Position:  position,
+			})
+			if composite.obj.Exported() {
+				// Keep it as an alias, for code outside the program.
+				plan._addEdit(fset.Position(composite.spec.Name.End()),
+					fset.Position(composite.spec.Type.End()).Offset, " = "+into.obj.Name())
+				continue
+			}
+			start, end := composite.spec.Pos(), composite.spec.End()
+			if composite.spec.Doc != nil {
+				start = composite.spec.Doc.Pos()
+			}
+			if len(composite.decl.Specs) == 1 {
+				start, end = composite.decl.Pos(), composite.decl.End()
+				if composite.decl.Doc != nil {
+					start = composite.decl.Doc.Pos()
+				}
+			}
+			plan._addEdit(fset.Position(start), fset.Position(end).Offset, "")
+		}
+	}
+
+	// Next, what each parameter needs.
+	var params []*_minimizeParam
+	trackers := map[*packages.Package]*analysisengine.Tracker{}
+	for _, in := range files {
+		if _skipFile(in.name, in.pkg.Types) {
+			continue
+		}
+		tracker := trackers[in.pkg]
+		if tracker == nil {
+			settings, err := loadSettings(&analysis.Pass{
+				Fset: in.pkg.Fset, Files: in.pkg.Syntax, Pkg: in.pkg.Types,
+			})
+			if err != nil {
+				return nil, err
+			}
+			options, err := _engineOptions(settings)
+			if err != nil {
+				return nil, err
+			}
+			tracker = analysisengine.NewTracker(in.pkg.TypesInfo, in.pkg.Types, options)
+			tracker.Track(in.pkg.Syntax)
+			for _, file := range in.pkg.Syntax {
+				tracker.MarkUses(file)
+			}
+			trackers[in.pkg] = tracker
+		}
+		params = append(params, _minimizeParams(in, tracker)...)
+	}
+
+	// Parameters of functions whose signatures are fixed can't change; we
+	// list those which request more than they need as skipped.
+	fixed := _fixedSignatures(pkgs)
+	var changeable []*_minimizeParam
+	for _, param := range params {
+		reason := fixed[param.declared]
+		if reason == "" {
+			changeable = append(changeable, param)
+			continue
+		}
+		if _leafKey(param.needed) != _leafKey(param.current) {
+			var paramNames []string
+			for _, name := range param.field.Names {
+				paramNames = append(paramNames, name.Name)
+			}
+			plan.Skipped = append(plan.Skipped, MinimizeSkip{
+				param.in.pkg.Fset.Position(param.field.Type.Pos()), param.function, paramNames, reason,
+			})
+		}
+	}
+	params = changeable
+
+	// Then which sets of leaves get new names: those enough parameters
+	// need, which aren't already named in the package.
+	counts := map[string]map[string]int{}
+	for _, param := range params {
+		key := _leafKey(param.needed)
+		if len(param.needed) < 2 || (param.named && key == _leafKey(param.current)) {
+			continue
+		}
+		if counts[param.in.pkg.PkgPath] == nil {
+			counts[param.in.pkg.PkgPath] = map[string]int{}
+		}
+		counts[param.in.pkg.PkgPath][key]++
+	}
+	newNames := map[string]map[string]*MinimizeInterface{}
+	taken := map[string]bool{}
+	for _, param := range params {
+		pkgPath, key := param.in.pkg.PkgPath, _leafKey(param.needed)
+		if counts[pkgPath][key] < threshold || names[pkgPath][key] != "" {
+			continue
+		}
+		if newNames[pkgPath] == nil {
+			newNames[pkgPath] = map[string]*MinimizeInterface{}
+		}
+		if newNames[pkgPath][key] == nil {
+			name := _newInterfaceName(param.needed, param.in.pkg.Types.Scope(), taken)
+			newNames[pkgPath][key] = &MinimizeInterface{Name: pkgPath + "." + name}
+		}
+	}
+
+	// Finally, rewrite the parameters, and declare the new interfaces where
+	// they're first used.
+	for _, param := range params {
+		pkgPath, key := param.in.pkg.PkgPath, _leafKey(param.needed)
+		if key == _leafKey(param.current) &&
+			(param.named || len(param.needed) < 2 || counts[pkgPath][key] < threshold) {
+			continue // already minimal, and named if it should be
+		}
+		position := param.in.pkg.Fset.Position(param.field.Type.Pos())
+		var paramNames []string
+		for _, name := range param.field.Names {
+			paramNames = append(paramNames, name.Name)
+		}
+		skip := func(reason string) {
+			plan.Skipped = append(plan.Skipped, MinimizeSkip{position, param.function, paramNames, reason})
+		}
+
+		var text string
+		var err error
+		newInterface := newNames[pkgPath][key]
+		switch {
+		case names[pkgPath][key] != "":
+			text = names[pkgPath][key]
+		case newInterface != nil:
+			text = newInterface.Name[len(pkgPath)+1:]
+		case len(param.needed) == 0:
+			// Just the root, like context.Context.
+			for _, leaf := range analysisengine.LeafInterfaces(param.in.pkg.TypesInfo.TypeOf(param.field.Type)) {
+				if isContextRoot(leaf) {
+					text, err = _typeText(leaf, param.in.file, param.in.pkg.Types)
+					break
+				}
+			}
+		case len(param.needed) == 1:
+			text, err = _typeText(param.needed[0], param.in.file, param.in.pkg.Types)
+		default:
+			text = "interface {\n"
+			for _, leaf := range param.needed {
+				var leafText string
+				leafText, err = _typeText(leaf, param.in.file, param.in.pkg.Types)
+				if err != nil {
+					break
+				}
+				text += "\t" + leafText + "\n"
+			}
+			text += "}"
+		}
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		if text == "" {
+			skip("it has no context root to request")
+			continue
+		}
+		var leafTexts []string
+		if newInterface != nil && newInterface.File == "" {
+			// Declare it here: we know the leaves can be written in
+			// this file.
+			for _, leaf := range param.needed {
+				leafText, err := _typeText(leaf, param.in.file, param.in.pkg.Types)
+				if err != nil {
+					break
+				}
+				leafTexts = append(leafTexts, leafText)
+			}
+			if len(leafTexts) < len(param.needed) {
+				skip("the new interface " + text + " can't be declared in its file")
+				continue
+			}
+		}
+		oldText := types.ExprString(param.field.Type)
+		end := param.in.pkg.Fset.Position(param.field.Type.End()).Offset
+		if !plan._addEdit(position, end, text) {
+			skip("it overlaps another change")
+			continue
+		}
+		plan.Rewrites = append(plan.Rewrites, MinimizeRewrite{
+			Position: position,
+			Function: param.function,
+			Params:   paramNames,
+			Old:      _oneLine(oldText),
+			New:      _oneLine(strings.ReplaceAll(strings.ReplaceAll(text, "{\n", "{ "), "\n", "; ")),
+		})
+		if newInterface != nil {
+			newInterface.Params++
+			if newInterface.File == "" {
+				name := text
+				newInterface.File = param.in.name
+				newInterface.Leaves = leafTexts
+				tokFile := param.in.pkg.Fset.File(param.in.file.Pos())
+				declaration := fmt.Sprintf("\n// %s is the typed context requested by functions\n// which need %s.\n"+
+					"type %s interface {\n\t%s\n}\n",
+					name, strings.Join(leafTexts, " and "), name, strings.Join(leafTexts, "\n\t"))
+				plan._addEdit(tokFile.Position(tokFile.Pos(tokFile.Size())), tokFile.Size(), declaration)
+			}
+		}
+	}
+	for _, byKey := range newNames {
+		for _, newInterface := range byKey {
+			if newInterface.File != "" {
+				plan.Interfaces = append(plan.Interfaces, *newInterface)
+			}
+		}
+	}
+	sort.Slice(plan.Interfaces, func(i, j int) bool { return plan.Interfaces[i].Name < plan.Interfaces[j].Name })
+
+	// And point the references to merged composites at what they're merged
+	// into (unless they're in a parameter type we rewrote).
+	for _, in := range files {
+		ast.Inspect(in.file, func(node ast.Node) bool {
+			ident, ok := node.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj, ok := in.pkg.TypesInfo.Uses[ident].(*types.TypeName)
+			if !ok {
+				return true
+			}
+			if into, ok := merged[in.pkg.Fset.Position(obj.Pos())]; ok {
+				position := in.pkg.Fset.Position(ident.Pos())
+				// If it overlaps, it's in a rewritten parameter type.
+				plan._addEdit(position, position.Offset+len(ident.Name), into)
+			}
+			return true
+		})
+	}
+	sort.Slice(plan.Merges, func(i, j int) bool {
+		return _positionLess(plan.Merges[i].Position, plan.Merges[j].Position)
+	})
+	sort.Slice(plan.Rewrites, func(i, j int) bool {
+		return _positionLess(plan.Rewrites[i].Position, plan.Rewrites[j].Position)
+	})
+	sort.Slice(plan.Skipped, func(i, j int) bool {
+		return _positionLess(plan.Skipped[i].Position, plan.Skipped[j].Position)
+	})
+	return plan, nil
+}
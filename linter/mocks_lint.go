@@ -0,0 +1,234 @@
+package linter
+
+// This file defines the linter that test doubles of typed contexts stay in
+// sync with the production contexts they stand in for.  A hand-written
+//	type MockAppContext struct { ... }
+// and the production
+//	type appContext struct { ... }
+// each implement whatever interfaces their users assert, but nothing ties
+// them to each other: when an accessor is added to appContext, and to the
+// functions' interfaces, tests keep passing with a mock which lacks it (the
+// functions under test get a narrower interface, or the mock embeds one),
+// and when a mock gains an accessor production lacks, tests pass while
+// production can't build the context the code needs.
+//
+// We pair a mock with its production counterpart
+//   - by a //typedcontext:mock-for T directive in the mock's doc comment,
+//     where T is a type in the package, or pkg.T in an imported one; or
+//   - by convention: a struct MockX is paired with the type X in the same
+//     package, or, if there's none, with x (X with its first word
+//     lower-cased, like appContext for MockAppContext).
+// The counterpart may be a struct, or a typed context interface.  We report,
+// at the mock, the accessors (methods of the typed context interfaces of
+// the package and its imports; see _knownAccessors) which one of them has
+// and the other doesn't.  Accessors a struct only gets by embedding an
+// interface which doesn't declare them don't count: they're usually nil.
+// Pairs found by convention are only checked if one of them has an
+// accessor.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextMocksAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextmocks",
+	Doc:  "reports test doubles of typed contexts whose accessors differ from the production contexts'",
+	Run:  _runMockSync,
+}
+
+// _mockForDirective is the directive naming a mock's counterpart.
+const _mockForDirective = "//typedcontext:mock-for "
+
+// _mockFor returns the argument of the mock-for directive in the given doc
+// comment, and the directive, if there is one.
+func _mockFor(doc *ast.CommentGroup) (string, *ast.Comment) {
+	if doc == nil {
+		return "", nil
+	}
+	for _, comment := range doc.List {
+		if name, ok := strings.CutPrefix(comment.Text, _mockForDirective); ok {
+			return strings.TrimSpace(name), comment
+		}
+	}
+	return "", nil
+}
+
+// _lookupCounterpart returns the type named by name, as given in a mock-for
+// directive in file, or nil if there's none.
+func _lookupCounterpart(pass *analysis.Pass, file *ast.File, name string) *types.TypeName {
+	scope := pass.Pkg.Scope()
+	if pkgName, typeName, ok := strings.Cut(name, "."); ok {
+		pkg, ok := pass.TypesInfo.Scopes[file].Lookup(pkgName).(*types.PkgName)
+		if !ok {
+			return nil
+		}
+		scope, name = pkg.Imported().Scope(), typeName
+	}
+	obj, _ := scope.Lookup(name).(*types.TypeName)
+	return obj
+}
+
+// _conventionalCounterpart returns the type the struct named by mock is
+// paired with by convention, or nil if there's none.
+func _conventionalCounterpart(pass *analysis.Pass, mock *types.TypeName) *types.TypeName {
+	name, ok := strings.CutPrefix(mock.Name(), "Mock")
+	if !ok || name == "" || !unicode.IsUpper(rune(name[0])) {
+		return nil
+	}
+	if _, ok := mock.Type().Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	for _, candidate := range []string{name, _lowerFirstWord(name)} {
+		if obj, ok := pass.Pkg.Scope().Lookup(candidate).(*types.TypeName); ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// _lowerFirstWord lower-cases the first word of an identifier, treating an
+// initialism as a single word: "AppContext" becomes "appContext", and
+// "HTTPContext" becomes "httpContext".
+func _lowerFirstWord(name string) string {
+	runes := []rune(name)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	if i > 1 && i < len(runes) {
+		i-- // the last capital begins the next word
+	}
+	return strings.ToLower(string(runes[:i])) + string(runes[i:])
+}
+
+// _providedAccessors returns the names of the accessors in known which the
+// type named by obj provides: if it's an interface, those it includes, and
+// if it's another type, the methods of its pointer which are accessors,
+// other than those it only gets by embedding an interface which doesn't
+// declare them.
+func _providedAccessors(obj *types.TypeName, known map[string]_accessor) map[string]bool {
+	provided := map[string]bool{}
+	if types.IsInterface(obj.Type()) {
+		for _, accessor := range _accessors(obj.Type()) {
+			provided[accessor.method.Name()] = true
+		}
+		return provided
+	}
+	methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.At(i).Obj().(*types.Func)
+		accessor, ok := known[method.Name()]
+		sig := method.Type().(*types.Signature)
+		if !ok || sig.Params().Len() != 0 ||
+			!types.Identical(sig.Results(), accessor.method.Type().(*types.Signature).Results()) {
+			continue
+		}
+		field, declaring := _promotedFrom(obj, method)
+		if field != nil && types.IsInterface(declaring) && !_declaresMethod(declaring, method.Name()) {
+			continue
+		}
+		provided[method.Name()] = true
+	}
+	return provided
+}
+
+// _sortedNames returns the names in the given set, sorted.
+func _sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// _checkMockSync reports the accessors which one of mock and its
+// counterpart provides and the other doesn't.  If byConvention is set, the
+// pair isn't checked unless one of them provides an accessor.
+func _checkMockSync(pass *analysis.Pass, mock, counterpart *types.TypeName, known map[string]_accessor, byConvention bool) {
+	mockAccessors := _providedAccessors(mock, known)
+	prodAccessors := _providedAccessors(counterpart, known)
+	if byConvention && len(mockAccessors) == 0 && len(prodAccessors) == 0 {
+		return
+	}
+	prodName := _shortTypeName(counterpart.Type(), pass.Pkg)
+	for _, accessor := range _sortedNames(prodAccessors) {
+		if !mockAccessors[accessor] {
+			reportf(pass, mock, CodeMockDrift,
+				"%s has no accessor %s, which %s, the production context it "+
+					"stands in for, has; add it, so tests can build what "+
+					"production does", mock.Name(), accessor, prodName)
+		}
+	}
+	for _, accessor := range _sortedNames(mockAccessors) {
+		if !prodAccessors[accessor] {
+			reportf(pass, mock, CodeMockDrift,
+				"%s has accessor %s, which %s, the production context it "+
+					"stands in for, lacks; add it to %s, or remove it from "+
+					"the mock, so tests don't pass where production can't",
+				mock.Name(), accessor, prodName, prodName)
+		}
+	}
+}
+
+// _runMockSync lints that mocks of typed contexts have the same accessors
+// as the production contexts they stand in for.
+func _runMockSync(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	var known map[string]_accessor
+	for _, file := range pass.Files {
+		// Mocks are often in tests, so we check those regardless of
+		// -typedcontextinterface.checktests.
+		if hasAnyPathPrefix(pass.Fset.File(file.Pos()).Name(), settings.exempt) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				mock, ok := pass.TypesInfo.Defs[spec.Name].(*types.TypeName)
+				if !ok || mock.IsAlias() {
+					continue
+				}
+				doc := spec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				name, directive := _mockFor(doc)
+				var counterpart *types.TypeName
+				if name != "" {
+					counterpart = _lookupCounterpart(pass, file, name)
+					if counterpart == nil {
+						reportf(pass, directive, CodeMockDrift,
+							"typedcontext:mock-for names %s, which isn't a type; "+
+								"name a type in this package, or pkg.Type", name)
+						continue
+					}
+				} else if counterpart = _conventionalCounterpart(pass, mock); counterpart == nil {
+					continue
+				}
+				if counterpart == mock {
+					continue
+				}
+				if known == nil {
+					known = _knownAccessors(pass.Pkg)
+				}
+				_checkMockSync(pass, mock, counterpart, known, name == "")
+			}
+		}
+	}
+	return nil, nil
+}
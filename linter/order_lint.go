@@ -0,0 +1,158 @@
+package linter
+
+// This file defines the linter that the embeds of inline typed context
+// interfaces, in parameter lists, are listed in a canonical order:
+// context.Context (and any other context roots) first, then the rest
+// alphabetized by package path and name, like
+//	func f(ctx interface {
+//		context.Context
+//		DatabaseContext
+//		RequestContext
+//		logging.Context
+//	})
+// (where DatabaseContext and RequestContext are declared in the package
+// itself, and logging.Context in .../logging).  Large inline interfaces
+// listed in arbitrary order make for noisy diffs, as each change reshuffles
+// them, and make it hard to see what two functions have in common.
+//
+// Named interfaces are left alone: their order is often meaningful, like
+// grouping related capabilities.  Explicit methods may appear anywhere; we
+// only check the embeds relative to each other.  We suggest a fix which
+// reorders them, unless one has comments, which wouldn't move with it.
+//
+// This is opt-in, since it's purely a matter of style.
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextOrderAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextorder",
+	Doc:  "enforces a canonical order for the embeds of inline typed context interfaces",
+	Run:  _runOrder,
+}
+
+func init() {
+	optIn(TypedContextOrderAnalyzer)
+}
+
+// _orderKey returns the key by which we sort the given embed: context roots
+// sort first, then the rest by package path and name.
+func _orderKey(typ types.Type) string {
+	root := "1"
+	if analysisengine.IsContextRoot(typ) {
+		root = "0"
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return root + typ.String()
+	}
+	return root + named.Obj().Pkg().Path() + "\x00" + named.Obj().Name()
+}
+
+// _checkOrder reports the given inline interface if its embeds aren't in
+// canonical order.
+func _checkOrder(pass *analysis.Pass, ifaceType *ast.InterfaceType) {
+	typ := pass.TypesInfo.TypeOf(ifaceType)
+	if typ == nil || !isContextType(typ) {
+		return
+	}
+
+	type embed struct {
+		field *ast.Field
+		typ   types.Type
+		key   string
+	}
+	var embeds []embed
+	for _, field := range ifaceType.Methods.List {
+		if len(field.Names) > 0 {
+			continue // a method
+		}
+		embedTyp := pass.TypesInfo.TypeOf(field.Type)
+		if embedTyp == nil {
+			return
+		}
+		if iface, ok := embedTyp.Underlying().(*types.Interface); !ok || !iface.IsMethodSet() {
+			return // a type constraint, not our business
+		}
+		embeds = append(embeds, embed{field, embedTyp, _orderKey(embedTyp)})
+	}
+
+	sorted := append([]embed(nil), embeds...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+	inOrder := true
+	for i := range embeds {
+		inOrder = inOrder && embeds[i].field == sorted[i].field
+	}
+	if inOrder {
+		return
+	}
+
+	names := make([]string, len(sorted))
+	movable := true
+	var edits []analysis.TextEdit
+	for i, embed := range sorted {
+		names[i] = _shortTypeName(embed.typ, pass.Pkg)
+		if embed.field.Doc != nil || embed.field.Comment != nil {
+			movable = false
+		}
+		if slot := embeds[i].field; slot != embed.field {
+			edits = append(edits, analysis.TextEdit{
+				Pos:     slot.Type.Pos(),
+				End:     slot.Type.End(),
+				NewText: []byte(types.ExprString(embed.field.Type)),
+			})
+		}
+	}
+
+	diagnostic := analysis.Diagnostic{
+		Pos:      ifaceType.Pos(),
+		Category: string(CodeEmbedOrder),
+		Message: "embeds are out of order; list them as " + strings.Join(names, ", ") +
+			" (context.Context first, then by package and name)",
+	}
+	if movable {
+		diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Reorder embeds",
+			TextEdits: edits,
+		}}
+	}
+	pass.Report(diagnostic)
+}
+
+// _checkParamOrder checks the inline interfaces which are the types of the
+// parameters of the given function type.
+func _checkParamOrder(pass *analysis.Pass, funcType *ast.FuncType) {
+	for _, field := range funcType.Params.List {
+		if ifaceType, ok := field.Type.(*ast.InterfaceType); ok {
+			_checkOrder(pass, ifaceType)
+		}
+	}
+}
+
+// _runOrder lints that inline typed context interfaces list their embeds in
+// canonical order.
+func _runOrder(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if funcType, ok := node.(*ast.FuncType); ok {
+				_checkParamOrder(pass, funcType)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,276 @@
+package linter
+
+// This file attributes findings to their owners, as listed in a CODEOWNERS
+// file, and summarizes them per owner, for teams driving adoption across a
+// monorepo: each owner gets a count of its findings by code, compared to
+// those of an earlier report, if any.  (That's the -owners mode of the
+// linter command.)
+//
+// CODEOWNERS files are as GitHub and GitLab define them: each line is a
+// pattern, in the style of .gitignore, followed by its owners, and the last
+// line whose pattern matches a file gives its owners.  We ignore sections,
+// and other extensions.
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unowned is the owner to which findings in files no CODEOWNERS rule
+// matches, or matches without listing owners, are attributed.
+const Unowned = "(unowned)"
+
+// _ownerRule is a line of a CODEOWNERS file.
+type _ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// Codeowners are the rules of a CODEOWNERS file.  Create one with
+// ReadCodeowners.
+type Codeowners struct {
+	// root is the directory to which the patterns are relative.
+	root  string
+	rules []_ownerRule
+}
+
+// _codeownersPattern returns the regexp matching the paths, relative to the
+// root and slash-separated, which the given CODEOWNERS pattern matches: the
+// files it names, and those in the directories it names.
+func _codeownersPattern(pattern string) (*regexp.Regexp, error) {
+	// A pattern with a slash, other than at its end, is relative to the
+	// root; otherwise it matches at any depth.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// ReadCodeowners reads the given CODEOWNERS file.  Its patterns are relative
+// to the directory containing it, or, if that's .github or docs, as where
+// GitHub looks for it, to that directory's parent.
+func ReadCodeowners(filename string) (*Codeowners, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	if base := filepath.Base(root); base == ".github" || base == "docs" {
+		root = filepath.Dir(root)
+	}
+
+	codeowners := &Codeowners{root: root}
+	for i, line := range strings.Split(string(data), "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") {
+			continue // blank, a comment, or a section heading
+		}
+		pattern, err := _codeownersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", filename, i+1, fields[0], err)
+		}
+		codeowners.rules = append(codeowners.rules, _ownerRule{pattern, fields[1:]})
+	}
+	return codeowners, nil
+}
+
+// Owners returns the owners of the given file, or nil if it has none, or
+// isn't under the root.
+func (codeowners *Codeowners) Owners(filename string) []string {
+	rel, err := filepath.Rel(codeowners.root, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(codeowners.rules) - 1; i >= 0; i-- {
+		if codeowners.rules[i].pattern.MatchString(rel) {
+			return codeowners.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// OwnerCount is a count of findings in an OwnerReport.
+type OwnerCount struct {
+	// Findings is the number of findings.
+	Findings int `json:"findings"`
+	// Baseline is the number of findings in the report compared against, if
+	// any (see OwnerReport.Compared).
+	Baseline int `json:"baseline"`
+	// Change is Findings less Baseline, if the report was compared.
+	Change int `json:"change"`
+}
+
+// OwnerSummary is the summary of one owner's findings.
+type OwnerSummary struct {
+	// Owner is the owner, like "@org/team", or Unowned.
+	Owner string `json:"owner"`
+	// Total counts all its findings.
+	Total OwnerCount `json:"total"`
+	// Codes counts its findings by diagnostic code.
+	Codes map[Code]OwnerCount `json:"codes"`
+}
+
+// OwnerReport summarizes findings by owner.
+type OwnerReport struct {
+	// Compared says whether the counts were compared against an earlier
+	// report, so that their Baseline and Change are set.
+	Compared bool `json:"compared"`
+	// Owners are the summaries, by owner, sorted.  A finding in a file with
+	// several owners counts toward each.
+	Owners []OwnerSummary `json:"owners"`
+}
+
+// SummarizeByOwner returns the summary of the given findings by their owners
+// in codeowners.  If baseline is set, the counts are compared against its
+// counts, and owners and codes which had findings then, but have none now,
+// are included too.
+func SummarizeByOwner(findings []Finding, codeowners *Codeowners, baseline *OwnerReport) *OwnerReport {
+	counts := map[string]map[Code]int{}
+	count := func(owner string) map[Code]int {
+		if counts[owner] == nil {
+			counts[owner] = map[Code]int{}
+		}
+		return counts[owner]
+	}
+	for _, finding := range findings {
+		owners := codeowners.Owners(finding.Position.Filename)
+		if len(owners) == 0 {
+			owners = []string{Unowned}
+		}
+		for _, owner := range owners {
+			count(owner)[finding.Code]++
+		}
+	}
+	baselines := map[string]map[Code]int{}
+	if baseline != nil {
+		for _, summary := range baseline.Owners {
+			baselines[summary.Owner] = map[Code]int{}
+			count(summary.Owner)
+			for code, codeCount := range summary.Codes {
+				baselines[summary.Owner][code] = codeCount.Findings
+			}
+		}
+	}
+
+	report := &OwnerReport{Compared: baseline != nil}
+	for owner, codes := range counts {
+		summary := OwnerSummary{Owner: owner, Codes: map[Code]OwnerCount{}}
+		for code := range baselines[owner] {
+			if _, ok := codes[code]; !ok {
+				codes[code] = 0
+			}
+		}
+		for code, findings := range codes {
+			codeCount := OwnerCount{Findings: findings}
+			if report.Compared {
+				codeCount.Baseline = baselines[owner][code]
+				codeCount.Change = codeCount.Findings - codeCount.Baseline
+			}
+			summary.Codes[code] = codeCount
+			summary.Total.Findings += codeCount.Findings
+			summary.Total.Baseline += codeCount.Baseline
+			summary.Total.Change += codeCount.Change
+		}
+		report.Owners = append(report.Owners, summary)
+	}
+	sort.Slice(report.Owners, func(i, j int) bool { return report.Owners[i].Owner < report.Owners[j].Owner })
+	return report
+}
+
+// ReadOwnerReport reads a report written by WriteJSON, for use as a baseline.
+func ReadOwnerReport(filename string) (*OwnerReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report OwnerReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &report, nil
+}
+
+// WriteJSON writes the report as JSON.
+func (report *OwnerReport) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteCSV writes the report as CSV, with a row per owner and code, and one
+// with the code "total" per owner, under a header row:
+//
+//	owner,code,findings,baseline,change
+//
+// The baseline and change are empty unless the report was compared.
+func (report *OwnerReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"owner", "code", "findings", "baseline", "change"})
+	row := func(owner, code string, count OwnerCount) {
+		record := []string{owner, code, strconv.Itoa(count.Findings), "", ""}
+		if report.Compared {
+			record[3] = strconv.Itoa(count.Baseline)
+			record[4] = strconv.Itoa(count.Change)
+		}
+		writer.Write(record)
+	}
+	for _, summary := range report.Owners {
+		codes := make([]Code, 0, len(summary.Codes))
+		for code := range summary.Codes {
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		for _, code := range codes {
+			row(summary.Owner, string(code), summary.Codes[code])
+		}
+		row(summary.Owner, "total", summary.Total)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,264 @@
+package linter
+
+// This file defines the linter that contexts aren't passed by pointer.  A
+// typed context is an interface, which is already a reference to whatever
+// implements it, so a parameter like
+//	func load(ctx *LoggerContext, id int)
+// (or `ctx *interface{ ... }`) buys nothing but a dereference at each use,
+// and can't be passed the contexts callers actually have.  Likewise a
+// parameter like `ctx *appContext`, where appContext is a concrete context
+// (see _isConcreteContext), ties the function to that one implementation:
+// it should request the typed context interfaces it uses instead.  We also
+// report struct fields holding pointers to context interfaces, like
+//	type handler struct { ctx *LoggerContext }
+// which are the same mistake one step removed.
+//
+// For pointers to interfaces, we suggest a fix dropping the pointer, if it's
+// safe: the parameter is one of an unexported function (not a method, which
+// may implement some interface), and is only ever dereferenced (not assigned
+// through), and every reference to the function in the package is a call
+// passing &x, which becomes x.  (Callers in the package's tests are only
+// seen when the tests are analyzed.)  Receivers are never reported: the
+// methods of a concrete context are of course declared on it.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextPointerAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextpointer",
+	Doc:  "reports contexts passed or stored by pointer",
+	Run:  _runPointer,
+}
+
+// _pointerToContext returns the type typ points to, and whether that's a
+// context interface (as opposed to a concrete context), if typ is a pointer
+// to either.
+func _pointerToContext(typ types.Type) (elem types.Type, isInterface bool, ok bool) {
+	pointer, ok := types.Unalias(typ).(*types.Pointer)
+	if !ok {
+		return nil, false, false
+	}
+	elem = pointer.Elem()
+	if _, ok := elem.Underlying().(*types.Interface); ok {
+		return elem, true, isContextType(elem)
+	}
+	return elem, false, _isConcreteContext(elem)
+}
+
+// _derefOnly returns the dereferences of param in body, including any
+// parentheses around them, like (*ctx), if it's only ever used dereferenced,
+// and not assigned through.
+func _derefOnly(param types.Object, body *ast.BlockStmt, typesInfo *types.Info) ([]ast.Expr, bool) {
+	var derefs []ast.Expr
+	derefed := map[*ast.Ident]bool{}
+	// parens are the parentheses around dereferences.
+	parens := map[ast.Expr]*ast.ParenExpr{}
+	// assigned are dereferences which are assigned to, or whose address is
+	// taken, either of which would change what the pointer points to.
+	assigned := map[ast.Expr]bool{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ParenExpr:
+			parens[node.X] = node
+		case *ast.StarExpr:
+			if ident, ok := ast.Unparen(node.X).(*ast.Ident); ok && typesInfo.Uses[ident] == param {
+				var deref ast.Expr = node
+				for parens[deref] != nil {
+					deref = parens[deref]
+				}
+				derefs = append(derefs, deref)
+				derefed[ident] = true
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				assigned[ast.Unparen(lhs)] = true
+			}
+		case *ast.IncDecStmt:
+			assigned[ast.Unparen(node.X)] = true
+		case *ast.UnaryExpr:
+			if node.Op == token.AND {
+				assigned[ast.Unparen(node.X)] = true
+			}
+		}
+		return true
+	})
+	safe := true
+	ast.Inspect(body, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && typesInfo.Uses[ident] == param && !derefed[ident] {
+			safe = false
+		}
+		return safe
+	})
+	for _, deref := range derefs {
+		if assigned[ast.Unparen(deref)] {
+			return nil, false
+		}
+	}
+	return derefs, safe
+}
+
+// _addressArgs returns the &x arguments passed as the i'th parameter of fn,
+// if every reference to fn in files is a call passing one.
+func _addressArgs(fn *types.Func, i int, files []*ast.File, typesInfo *types.Info) ([]*ast.UnaryExpr, bool) {
+	var args []*ast.UnaryExpr
+	called := map[*ast.Ident]bool{}
+	safe := true
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := ast.Unparen(call.Fun).(*ast.Ident)
+			if !ok || typesInfo.Uses[ident] != fn {
+				return true
+			}
+			called[ident] = true
+			if call.Ellipsis.IsValid() || i >= len(call.Args) {
+				safe = false
+				return true
+			}
+			arg, ok := ast.Unparen(call.Args[i]).(*ast.UnaryExpr)
+			if !ok || arg.Op != token.AND {
+				safe = false
+				return true
+			}
+			args = append(args, arg)
+			return true
+		})
+	}
+	for ident, obj := range typesInfo.Uses {
+		if obj == fn && !called[ident] {
+			return nil, false // e.g. passed as a func value
+		}
+	}
+	return args, safe
+}
+
+// _pointerFix returns a fix dropping the pointer from the given parameter of
+// funcDecl, declared by field, if it's safe (see the top of this file).
+func _pointerFix(pass *analysis.Pass, funcDecl *ast.FuncDecl, field *ast.Field, param types.Object) []analysis.SuggestedFix {
+	star, ok := field.Type.(*ast.StarExpr)
+	if !ok || funcDecl.Recv != nil || funcDecl.Body == nil || len(field.Names) != 1 {
+		return nil
+	}
+	fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+	if !ok || fn.Exported() {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	index := -1
+	for i := 0; i < sig.Params().Len(); i++ {
+		if sig.Params().At(i) == param {
+			index = i
+		}
+	}
+	if index < 0 || (sig.Variadic() && index == sig.Params().Len()-1) {
+		return nil
+	}
+	derefs, ok := _derefOnly(param, funcDecl.Body, pass.TypesInfo)
+	if !ok {
+		return nil
+	}
+	args, ok := _addressArgs(fn, index, pass.Files, pass.TypesInfo)
+	if !ok {
+		return nil
+	}
+
+	edits := []analysis.TextEdit{{Pos: star.Pos(), End: star.X.Pos()}}
+	for _, deref := range derefs {
+		edits = append(edits, analysis.TextEdit{Pos: deref.Pos(), End: deref.End(), NewText: []byte(param.Name())})
+	}
+	for _, arg := range args {
+		edits = append(edits, analysis.TextEdit{Pos: arg.Pos(), End: arg.X.Pos()})
+	}
+	return []analysis.SuggestedFix{{
+		Message:   "Pass " + param.Name() + " by value",
+		TextEdits: edits,
+	}}
+}
+
+// _checkPointerParams reports the parameters of the given function which
+// are pointers to contexts.
+func _checkPointerParams(pass *analysis.Pass, funcType *ast.FuncType, funcDecl *ast.FuncDecl) {
+	for _, field := range funcType.Params.List {
+		elem, isInterface, ok := _pointerToContext(pass.TypesInfo.TypeOf(field.Type))
+		if !ok {
+			continue
+		}
+		name := "parameter"
+		var param types.Object
+		if len(field.Names) > 0 {
+			name = field.Names[0].Name
+			param = pass.TypesInfo.Defs[field.Names[0]]
+		}
+		typeName := _shortTypeName(elem, pass.Pkg)
+		if _, ok := elem.(*types.Interface); ok {
+			typeName = "interface{ ... }"
+		}
+		if !isInterface {
+			reportf(pass, field, CodePointerContext,
+				"%s is a pointer to the concrete context %s; request the typed "+
+					"context interfaces it uses instead", name, typeName)
+			continue
+		}
+		diagnostic := analysis.Diagnostic{
+			Pos:      field.Pos(),
+			Category: string(CodePointerContext),
+			Message: name + " is a pointer to the context interface " + typeName +
+				"; pass the interface itself, which is already a reference",
+		}
+		if funcDecl != nil && param != nil {
+			diagnostic.SuggestedFixes = _pointerFix(pass, funcDecl, field, param)
+		}
+		pass.Report(diagnostic)
+	}
+}
+
+// _checkPointerFields reports the fields of the given struct which are
+// pointers to context interfaces.
+func _checkPointerFields(pass *analysis.Pass, structType *ast.StructType) {
+	for _, field := range structType.Fields.List {
+		elem, isInterface, ok := _pointerToContext(pass.TypesInfo.TypeOf(field.Type))
+		if !ok || !isInterface {
+			continue
+		}
+		name := "field"
+		if len(field.Names) > 0 {
+			name = field.Names[0].Name
+		}
+		reportf(pass, field, CodePointerContext,
+			"%s is a pointer to the context interface %s; store the interface "+
+				"itself, which is already a reference",
+			name, _shortTypeName(elem, pass.Pkg))
+	}
+}
+
+// _runPointer lints that contexts aren't passed or stored by pointer.
+func _runPointer(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				_checkPointerParams(pass, node.Type, node)
+			case *ast.FuncLit:
+				_checkPointerParams(pass, node.Type, nil)
+			case *ast.StructType:
+				_checkPointerFields(pass, node)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,180 @@
+package linter
+
+// This file answers the question "what does this function need?": given a
+// function, it computes the smallest set of typed context interfaces each of
+// its context parameters could request, using the same usage data as the
+// interface analyzer (see analysisengine.Usage.Requirements).  It's used by
+// cmd/typedcontext-query.
+//
+// The callees' requirements come from their signatures: if we pass ctx to
+// some G(ctx LoggerContext), we need LoggerContext, whether or not G really
+// uses all of it.  (If it doesn't, the linter will complain about G.)
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// Requirement describes what a function needs of one of its context
+// parameters.
+type Requirement struct {
+	// Param is the name of the parameter.
+	Param string
+	// Declared is the parameter's declared type.
+	Declared string
+	// Needs lists the typed context interfaces the function uses, minimized
+	// so that none is implied by another, and sorted.  Types from packages
+	// other than the function's own are qualified by package name.
+	Needs []string
+}
+
+// _parseSymbol splits a function symbol, one of
+//
+//	import/path.Func
+//	import/path.Type.Method
+//	(*import/path.Type).Method
+//
+// into its package path, receiver type name (if any), and function name.
+// The import path may also be a relative directory like ./pkg.
+func _parseSymbol(symbol string) (pkgPath, recv, name string, err error) {
+	if strings.HasPrefix(symbol, "(") {
+		end := strings.Index(symbol, ").")
+		if end < 0 {
+			return "", "", "", fmt.Errorf("invalid method symbol %q", symbol)
+		}
+		typ := strings.TrimPrefix(symbol[1:end], "*")
+		dot := strings.LastIndex(typ, ".")
+		if dot < 0 {
+			return "", "", "", fmt.Errorf("invalid method symbol %q", symbol)
+		}
+		return typ[:dot], typ[dot+1:], symbol[end+2:], nil
+	}
+
+	slash := strings.LastIndex(symbol, "/")
+	parts := strings.Split(symbol[slash+1:], ".")
+	prefix := symbol[:slash+1]
+	switch len(parts) {
+	case 2:
+		return prefix + parts[0], "", parts[1], nil
+	case 3:
+		return prefix + parts[0], parts[1], parts[2], nil
+	default:
+		return "", "", "", fmt.Errorf(
+			"invalid symbol %q: want import/path.Func or import/path.Type.Method", symbol)
+	}
+}
+
+// _minimalInterfaces returns the given interfaces, without duplicates or any
+// which are implied by (i.e. implemented by) another, sorted by name.
+func _minimalInterfaces(ifaces []types.Type, pkg *types.Package) []string {
+	var names []string
+	for i, iface := range ifaces {
+		underlying, ok := iface.Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		implied := false
+		for j, other := range ifaces {
+			if i == j || !types.Implements(other, underlying) {
+				continue
+			}
+			// If they imply each other, keep the first.
+			if j < i || !types.Implements(iface, other.Underlying().(*types.Interface)) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			names = append(names, _shortTypeName(iface, pkg))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Requirements returns, for each context parameter of the function with the
+// given symbol (see _parseSymbol), the typed context interfaces the function
+// needs it to provide.
+//
+// Settings are taken from the typedcontextinterface flags and any
+// configuration files, as for the analyzer.
+func Requirements(symbol string) ([]Requirement, error) {
+	pkgPath, recv, name, err := _parseSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &packages.Config{Mode: packages.LoadAllSyntax}
+	pkgs, err := packages.Load(config, pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s matches %d packages, want 1", pkgPath, len(pkgs))
+	}
+	pkg := pkgs[0]
+
+	var funcDecl *ast.FuncDecl
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if ok && decl.Name.Name == name && _receiverTypeName(decl) == recv {
+				funcDecl = decl
+			}
+		}
+	}
+	if funcDecl == nil {
+		return nil, fmt.Errorf("%s not found in %s", symbol, pkg.PkgPath)
+	}
+
+	settings, err := loadSettings(&analysis.Pass{
+		Fset: pkg.Fset, Files: pkg.Syntax, Pkg: pkg.Types,
+	})
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	tracker := analysisengine.NewTracker(pkg.TypesInfo, pkg.Types, options)
+	tracker.Track(pkg.Syntax)
+	for _, file := range pkg.Syntax {
+		tracker.MarkUses(file)
+	}
+
+	var requirements []Requirement
+	for _, field := range funcDecl.Type.Params.List {
+		typ := pkg.TypesInfo.TypeOf(field.Type)
+		if typ == nil || !isContextType(typ) {
+			continue
+		}
+		for _, ident := range field.Names {
+			requirement := Requirement{
+				Param:    ident.Name,
+				Declared: types.TypeString(typ, types.RelativeTo(pkg.Types)),
+			}
+			obj := pkg.TypesInfo.Defs[ident]
+			if info := tracker.Usage(obj); info != nil {
+				requirement.Needs = _minimalInterfaces(info.Requirements(), pkg.Types)
+			} else if isContextRoot(typ) {
+				// We don't track plain contexts (see Tracker.Track); there's
+				// nothing smaller to ask for anyway.
+				requirement.Needs = []string{"context.Context"}
+			}
+			requirements = append(requirements, requirement)
+		}
+	}
+	return requirements, nil
+}
@@ -0,0 +1,402 @@
+package linter
+
+// This file defines the linter that functions don't take both a typed
+// context and a provider that context already provides, like
+//	func f(ctx LoggerContext, logger *Logger)
+// The logger parameter is redundant: f can (and should) use ctx.Logger(), so
+// that its callers don't have to pass the provider twice, and can't pass
+// inconsistent ones.
+//
+// We report a parameter as redundant if its type is identical to the result
+// type of an accessor (a method with no arguments and a single result) of
+// some context parameter of the same function.  Where it's safe to do so, we
+// suggest a fix which removes the parameter, and the corresponding argument
+// from every call, and replaces its uses with calls to the accessor; see
+// _redundantFixer.fix for when it is.
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextRedundantAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextredundant",
+	Doc:  "reports parameters which duplicate a provider of a typed context parameter",
+	Run:  _runRedundant,
+}
+
+// _contextMethods are the methods of context.Context, which are not
+// accessors even if they look like them.
+var _contextMethods = map[string]bool{
+	"Deadline": true, "Done": true, "Err": true, "Value": true,
+}
+
+// _accessorFor returns the name of the accessor of the given context type
+// whose result has the given type, or "" if there is none.
+//
+// We don't match basic types: a context with an accessor `UserID() string`
+// doesn't make every string parameter redundant.
+func _accessorFor(ctxType, typ types.Type) string {
+	if _, ok := typ.Underlying().(*types.Basic); ok {
+		return ""
+	}
+	iface, ok := ctxType.Underlying().(*types.Interface)
+	if !ok {
+		return ""
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		if _contextMethods[method.Name()] {
+			continue
+		}
+		sig := method.Type().(*types.Signature)
+		if sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+			types.Identical(sig.Results().At(0).Type(), typ) {
+			return method.Name()
+		}
+	}
+	return ""
+}
+
+// _redundantFixer suggests fixes for redundant parameters of the functions
+// of a package.
+type _redundantFixer struct {
+	pass *analysis.Pass
+	// calls are the calls of each function declared in the package;
+	// values and interfaces are as returned by _funcValues and
+	// _interfaceTypes.
+	calls      map[types.Object][]*ast.CallExpr
+	values     map[types.Object]bool
+	interfaces []*types.Interface
+	// unseenTests is set if the package has _test.go files we can't see,
+	// which may call its functions.
+	unseenTests bool
+	// uses are the uses of each object; params are the parameters of the
+	// package's functions.
+	uses   map[types.Object][]*ast.Ident
+	params map[types.Object]bool
+}
+
+func _newRedundantFixer(pass *analysis.Pass) *_redundantFixer {
+	fixer := &_redundantFixer{
+		pass:       pass,
+		calls:      map[types.Object][]*ast.CallExpr{},
+		values:     _funcValues(pass.TypesInfo, pass.Files),
+		interfaces: _interfaceTypes([]*types.Package{pass.Pkg}, []*types.Info{pass.TypesInfo}),
+		uses:       map[types.Object][]*ast.Ident{},
+		params:     map[types.Object]bool{},
+	}
+	for ident, obj := range pass.TypesInfo.Uses {
+		fixer.uses[obj] = append(fixer.uses[obj], ident)
+	}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CallExpr:
+				if fn, ok := lintutil.ObjectFor(node.Fun, pass.TypesInfo).(*types.Func); ok && fn.Pkg() == pass.Pkg {
+					fixer.calls[fn] = append(fixer.calls[fn], node)
+				}
+			case *ast.FuncType:
+				for _, field := range node.Params.List {
+					for _, name := range field.Names {
+						fixer.params[pass.TypesInfo.Defs[name]] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	fixer.unseenTests = _hasUnseenTests(pass)
+	return fixer
+}
+
+// _hasUnseenTests returns true if the package's directory has _test.go
+// files in the package itself, which aren't among those being analyzed:
+// when analyzing a package without its tests, we can't see their calls.
+func _hasUnseenTests(pass *analysis.Pass) bool {
+	if len(pass.Files) == 0 {
+		return false
+	}
+	for _, file := range pass.Files {
+		if strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go") {
+			return false
+		}
+	}
+	dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+	filenames, _ := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly)
+		if err != nil || file.Name.Name == pass.Pkg.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// _droppableArg returns true if the given argument can be removed from a
+// call without changing what else the call does: it calls nothing but
+// accessors of typed contexts, and conversions.
+func _droppableArg(arg ast.Expr, info *types.Info) bool {
+	droppable := true
+	ast.Inspect(arg, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpr:
+			if method, _ := _extractedProvider(node, info); method == nil && !info.Types[node.Fun].IsType() {
+				droppable = false
+			}
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW {
+				droppable = false
+			}
+		case *ast.FuncLit:
+			return false
+		}
+		return droppable
+	})
+	return droppable
+}
+
+// _removeListItem returns an edit removing the i'th of the given nodes,
+// along with the comma on one side of it.
+func _removeListItem(nodes []ast.Node, i int) analysis.TextEdit {
+	start, end := nodes[i].Pos(), nodes[i].End()
+	switch {
+	case i+1 < len(nodes):
+		end = nodes[i+1].Pos()
+	case i > 0:
+		start = nodes[i-1].End()
+	}
+	return analysis.TextEdit{Pos: start, End: end}
+}
+
+// fix returns a fix which removes the given parameter (field i of the
+// parameters of fn, declared by funcDecl, which must have a single name)
+// from fn and from every call of it, and replaces its uses in the body with
+// ctx.accessor(), or nil if that's not safe.
+//
+// It isn't if fn may be called from code we can't see: if it's exported, or
+// the package has tests we can't see; nor if fn is referred to other than by
+// direct calls, or is a method implementing an interface, since then its
+// signature is fixed.  Nor is it if some call passes something other than a
+// plain value for the parameter, which removing it would stop evaluating,
+// or a variable used nowhere else, nor if ctx is shadowed where the
+// parameter is used.
+func (fixer *_redundantFixer) fix(
+	funcDecl *ast.FuncDecl,
+	fn *types.Func,
+	i int,
+	ctx types.Object,
+	accessor string,
+) *analysis.SuggestedFix {
+	pass := fixer.pass
+	field := funcDecl.Type.Params.List[i]
+	obj := pass.TypesInfo.Defs[field.Names[0]]
+	if funcDecl.Body == nil || ctx.Name() == "_" || obj == nil || fixer.unseenTests ||
+		_isAPI(pass.Pkg, fn) || fixer.values[fn] ||
+		funcDecl.Recv != nil && _implementsAny(fn, fixer.interfaces) {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	index := 0 // the index of the parameter, counting each name
+	for _, before := range funcDecl.Type.Params.List[:i] {
+		index += max(len(before.Names), 1)
+	}
+	if sig.Variadic() && index == sig.Params().Len()-1 {
+		return nil
+	}
+
+	// Remove the argument from each call.
+	var edits []analysis.TextEdit
+	for _, call := range fixer.calls[fn] {
+		if len(call.Args) != sig.Params().Len() || !_droppableArg(call.Args[index], pass.TypesInfo) {
+			return nil
+		}
+		args := make([]ast.Node, len(call.Args))
+		for j, arg := range call.Args {
+			args[j] = arg
+		}
+		edits = append(edits, _removeListItem(args, index))
+	}
+	removed := func(node ast.Node) bool {
+		for _, edit := range edits {
+			if edit.Pos <= node.Pos() && node.End() <= edit.End {
+				return true
+			}
+		}
+		return false
+	}
+	// Nor may removing them leave a local variable unused, which wouldn't
+	// compile.
+	for _, call := range fixer.calls[fn] {
+		unused := false
+		ast.Inspect(call.Args[index], func(node ast.Node) bool {
+			ident, ok := node.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			local, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+			if !ok || local.IsField() || local.Parent() == pass.Pkg.Scope() || fixer.params[local] {
+				return true
+			}
+			unused = true
+			for _, use := range fixer.uses[local] {
+				if !removed(use) {
+					unused = false
+				}
+			}
+			return !unused
+		})
+		if unused {
+			return nil
+		}
+	}
+
+	var uses []*ast.Ident
+	safe := true
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			// If the parameter is assigned to, it's not just a copy of the
+			// provider.
+			for _, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == obj {
+					safe = false
+				}
+			}
+		case *ast.UnaryExpr:
+			// Likewise if we take its address.
+			if ident, ok := node.X.(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == obj {
+				safe = false
+			}
+		case *ast.Ident:
+			if pass.TypesInfo.Uses[node] != obj || removed(node) {
+				break // an argument of a recursive call, which goes anyway
+			}
+			// ctx must still be ctx there.
+			scope := pass.TypesInfo.Scopes[funcDecl.Type].Innermost(node.Pos())
+			if _, found := scope.LookupParent(ctx.Name(), node.Pos()); found != ctx {
+				safe = false
+			}
+			uses = append(uses, node)
+		}
+		return safe
+	})
+	if !safe {
+		return nil
+	}
+
+	params := make([]ast.Node, len(funcDecl.Type.Params.List))
+	for j, param := range funcDecl.Type.Params.List {
+		params[j] = param
+	}
+	edits = append(edits, _removeListItem(params, i))
+
+	var call bytes.Buffer
+	_ = format.Node(&call, pass.Fset, &ast.CallExpr{
+		Fun: &ast.SelectorExpr{X: ast.NewIdent(ctx.Name()), Sel: ast.NewIdent(accessor)},
+	})
+	for _, use := range uses {
+		edits = append(edits, analysis.TextEdit{
+			Pos: use.Pos(), End: use.End(), NewText: call.Bytes(),
+		})
+	}
+
+	return &analysis.SuggestedFix{
+		Message:   "Remove " + field.Names[0].Name + " and use " + call.String(),
+		TextEdits: edits,
+	}
+}
+
+// _checkRedundant reports any parameters of the given function which
+// duplicate a provider of one of its context parameters.  funcDecl is the
+// function's declaration, if it's not a function literal; only then do we
+// suggest fixes, since function literals are values.
+func _checkRedundant(fixer *_redundantFixer, funcType *ast.FuncType, funcDecl *ast.FuncDecl) {
+	pass := fixer.pass
+	type ctxParam struct {
+		obj types.Object
+		typ types.Type
+	}
+	var ctxParams []ctxParam
+	for _, field := range funcType.Params.List {
+		typ := pass.TypesInfo.TypeOf(field.Type)
+		if typ == nil || !isContextType(typ) {
+			continue
+		}
+		var obj types.Object
+		if len(field.Names) > 0 {
+			obj = pass.TypesInfo.Defs[field.Names[0]]
+		}
+		if obj == nil {
+			obj = types.NewParam(field.Pos(), pass.Pkg, "_", typ)
+		}
+		ctxParams = append(ctxParams, ctxParam{obj, typ})
+	}
+	if len(ctxParams) == 0 {
+		return
+	}
+
+	for i, field := range funcType.Params.List {
+		typ := pass.TypesInfo.TypeOf(field.Type)
+		if typ == nil || isContextType(typ) {
+			continue
+		}
+		for _, ctx := range ctxParams {
+			accessor := _accessorFor(ctx.typ, typ)
+			if accessor == "" {
+				continue
+			}
+			for _, name := range field.Names {
+				diagnostic := analysis.Diagnostic{
+					Pos:      name.Pos(),
+					Category: string(CodeRedundantProvider),
+					Message: name.Name + " duplicates " + ctx.obj.Name() + "." +
+						accessor + "(); remove it and use that instead",
+				}
+				if funcDecl != nil && len(field.Names) == 1 {
+					fn := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+					fix := fixer.fix(funcDecl, fn, i, ctx.obj, accessor)
+					if fix != nil {
+						diagnostic.SuggestedFixes = []analysis.SuggestedFix{*fix}
+					}
+				}
+				pass.Report(diagnostic)
+			}
+			break // one report per parameter is plenty
+		}
+	}
+}
+
+// _runRedundant lints that functions don't take providers their contexts
+// already provide.
+func _runRedundant(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	fixer := _newRedundantFixer(pass)
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				_checkRedundant(fixer, node.Type, node)
+			case *ast.FuncLit:
+				_checkRedundant(fixer, node.Type, nil)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,362 @@
+package linter
+
+// This file defines the linter that handler registries don't force handlers
+// to take bigger contexts than they need.  In
+//	var handlers = map[string]func(ctx AppContext, req *Request) error{
+//		"users":  handleUsers,
+//		"avatar": handleAvatar,
+//	}
+// every handler has to take an AppContext, even if, say, handleAvatar only
+// needs a LoggerContext: the registry's shared signature is the union of
+// what all of them need.  (The interface linter reports handleAvatar's ctx
+// as requesting more than it uses, but it can't be narrowed without breaking
+// the registration.)  Instead, handleAvatar can take just what it needs, and
+// be registered via an adapter:
+//	"avatar": func(ctx AppContext, req *Request) error { return handleAvatar(ctx, req) },
+//
+// So we look at map, slice and array literals whose element type is a
+// function with a typed context parameter, and report each element which is
+// a function needing less of that context than the signature provides.  For
+// functions declared in the package (not methods, nor function literals),
+// we suggest a fix narrowing the function's parameter and registering it via
+// an adapter, if the function isn't referred to other than by direct calls
+// and this registration.
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextRegistryAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextregistry",
+	Doc:  "reports handlers registered with a wider context than they need",
+	Run:  _runRegistry,
+}
+
+// _registry is what we know about a package's registrations.
+type _registry struct {
+	pass    *analysis.Pass
+	tracker *analysisengine.Tracker
+	// decls are the functions declared in the package, and the files
+	// declaring them.
+	decls map[*types.Func]*ast.FuncDecl
+	files map[*types.Func]*ast.File
+	// called are the identifiers which are the function of a call.
+	called map[*ast.Ident]bool
+	// names are the names of the variables registries are assigned to.
+	names map[*ast.CompositeLit]string
+}
+
+// _newRegistry indexes the given package's functions and registries.
+func _newRegistry(pass *analysis.Pass, tracker *analysisengine.Tracker) *_registry {
+	registry := &_registry{
+		pass:    pass,
+		tracker: tracker,
+		decls:   map[*types.Func]*ast.FuncDecl{},
+		files:   map[*types.Func]*ast.File{},
+		called:  map[*ast.Ident]bool{},
+		names:   map[*ast.CompositeLit]string{},
+	}
+	name := func(lhs, rhs ast.Expr) {
+		if lit, ok := ast.Unparen(rhs).(*ast.CompositeLit); ok {
+			registry.names[lit] = types.ExprString(lhs)
+		}
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func); ok {
+					registry.decls[fn] = funcDecl
+					registry.files[fn] = file
+				}
+			}
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CallExpr:
+				if ident, ok := ast.Unparen(node.Fun).(*ast.Ident); ok {
+					registry.called[ident] = true
+				}
+			case *ast.ValueSpec:
+				for i, value := range node.Values {
+					if i < len(node.Names) {
+						name(node.Names[i], value)
+					}
+				}
+			case *ast.AssignStmt:
+				if len(node.Lhs) == len(node.Rhs) {
+					for i, value := range node.Rhs {
+						name(node.Lhs[i], value)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return registry
+}
+
+// _registrySignature returns the function signature of the elements of the
+// given literal, and the index of its typed context parameter, if it's a
+// registry.
+func _registrySignature(typ types.Type) (*types.Signature, int) {
+	var elem types.Type
+	switch typ := typ.Underlying().(type) {
+	case *types.Map:
+		elem = typ.Elem()
+	case *types.Slice:
+		elem = typ.Elem()
+	case *types.Array:
+		elem = typ.Elem()
+	default:
+		return nil, -1
+	}
+	sig, ok := elem.Underlying().(*types.Signature)
+	if !ok {
+		return nil, -1
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		paramType := sig.Params().At(i).Type()
+		if isContextType(paramType) && len(_distinctLeaves(paramType)) > 0 {
+			return sig, i
+		}
+	}
+	return nil, -1
+}
+
+// needs returns the leaves of current which the given parameter needs, in
+// the same order, or false if we can't tell.
+func (registry *_registry) needs(param types.Object, current []types.Type) ([]types.Type, bool) {
+	info := registry.tracker.Usage(param)
+	if info == nil || registry.tracker.IsAlias(param) {
+		return nil, false
+	}
+	var needed []types.Type
+	for _, requirement := range info.Requirements() {
+		for _, leaf := range _distinctLeaves(requirement) {
+			if !_containsLeaf(current, leaf) {
+				return nil, false // provided some other way
+			}
+			needed = append(needed, leaf)
+		}
+	}
+	var ordered []types.Type
+	for _, leaf := range current {
+		if _containsLeaf(needed, leaf) {
+			ordered = append(ordered, leaf)
+		}
+	}
+	return ordered, true
+}
+
+// _qualifier returns a types.Qualifier writing package names as the given
+// file of pkg refers to them.  It sets *missing if the file doesn't import
+// some package it's asked about.
+func _qualifier(file *ast.File, pkg *types.Package, missing *bool) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		name, ok := _typeQualifier(file, other.Path(), other.Name())
+		if !ok {
+			*missing = true
+		}
+		if name == "." {
+			return ""
+		}
+		return name
+	}
+}
+
+// _adapterText returns a function literal of the given signature, written in
+// the given file, which calls the function with the given name.
+func _adapterText(sig *types.Signature, name string, ctxIndex int, file *ast.File, pkg *types.Package) (string, bool) {
+	missing := false
+	qualifier := _qualifier(file, pkg, &missing)
+	var params, args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		paramName := "arg" + strconv.Itoa(i)
+		if i == ctxIndex {
+			paramName = "ctx"
+		}
+		paramType := sig.Params().At(i).Type()
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			params = append(params, paramName+" ..."+types.TypeString(paramType.(*types.Slice).Elem(), qualifier))
+			args = append(args, paramName+"...")
+		} else {
+			params = append(params, paramName+" "+types.TypeString(paramType, qualifier))
+			args = append(args, paramName)
+		}
+	}
+	var results []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, types.TypeString(sig.Results().At(i).Type(), qualifier))
+	}
+	text := "func(" + strings.Join(params, ", ") + ")"
+	call := name + "(" + strings.Join(args, ", ") + ")"
+	switch len(results) {
+	case 0:
+		text += " { " + call + " }"
+	case 1:
+		text += " " + results[0] + " { return " + call + " }"
+	default:
+		text += " (" + strings.Join(results, ", ") + ") { return " + call + " }"
+	}
+	return text, !missing
+}
+
+// fix returns a fix narrowing the context parameter of the given function
+// to needed, and registering it via an adapter at value, if that's safe.
+func (registry *_registry) fix(value *ast.Ident, fn *types.Func, sig *types.Signature, ctxIndex int, needed []types.Type, registryFile *ast.File) []analysis.SuggestedFix {
+	pass := registry.pass
+	funcDecl, file := registry.decls[fn], registry.files[fn]
+	if funcDecl == nil || funcDecl.Recv != nil || funcDecl.Type.TypeParams != nil {
+		return nil
+	}
+	field := _flatFields(funcDecl.Type.Params, ctxIndex)
+	if field == nil || len(field.Names) != 1 {
+		return nil
+	}
+	for ident, obj := range pass.TypesInfo.Uses {
+		if obj == fn && ident != value && !registry.called[ident] {
+			return nil // say, registered somewhere else too
+		}
+	}
+
+	var text string
+	var err error
+	switch len(needed) {
+	case 0:
+		if name, ok := _typeQualifier(file, "context", "context"); ok && name != "." {
+			text = name + ".Context"
+		}
+	case 1:
+		text, err = _typeText(needed[0], file, pass.Pkg)
+	default:
+		var leafTexts []string
+		for _, leaf := range needed {
+			var leafText string
+			leafText, err = _typeText(leaf, file, pass.Pkg)
+			if err != nil {
+				break
+			}
+			leafTexts = append(leafTexts, leafText)
+		}
+		text = "interface {\n" + strings.Join(leafTexts, "\n") + "\n}"
+	}
+	if err != nil || text == "" {
+		return nil
+	}
+	adapter, ok := _adapterText(sig, value.Name, ctxIndex, registryFile, pass.Pkg)
+	if !ok {
+		return nil
+	}
+	return []analysis.SuggestedFix{{
+		Message: "Narrow " + fn.Name() + "'s context, and register it via an adapter",
+		TextEdits: []analysis.TextEdit{
+			{Pos: field.Type.Pos(), End: field.Type.End(), NewText: []byte(text)},
+			{Pos: value.Pos(), End: value.End(), NewText: []byte(adapter)},
+		},
+	}}
+}
+
+// check reports the elements of the given literal which are handlers
+// needing less than the registry's signature provides.
+func (registry *_registry) check(lit *ast.CompositeLit, file *ast.File) {
+	pass := registry.pass
+	sig, ctxIndex := _registrySignature(pass.TypesInfo.TypeOf(lit))
+	if sig == nil {
+		return
+	}
+	ctxType := sig.Params().At(ctxIndex).Type()
+	current := _distinctLeaves(ctxType)
+	registryName := registry.names[lit]
+	if registryName == "" {
+		registryName = "this registry"
+	}
+
+	for _, elt := range lit.Elts {
+		if keyValue, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = keyValue.Value
+		}
+		var param types.Object
+		var name string
+		// value and fn are set if the handler is a function we can narrow.
+		var value *ast.Ident
+		var fn *types.Func
+		switch elt := ast.Unparen(elt).(type) {
+		case *ast.Ident:
+			fn, _ = pass.TypesInfo.Uses[elt].(*types.Func)
+			if fn == nil || fn.Pkg() != pass.Pkg {
+				continue
+			}
+			value = elt
+			param, name = fn.Type().(*types.Signature).Params().At(ctxIndex), fn.Name()
+		case *ast.FuncLit:
+			litSig, ok := pass.TypesInfo.TypeOf(elt).(*types.Signature)
+			if !ok {
+				continue
+			}
+			param, name = litSig.Params().At(ctxIndex), "this handler"
+		default:
+			continue
+		}
+		needed, ok := registry.needs(param, current)
+		if !ok || len(needed) == len(current) {
+			continue
+		}
+
+		what := "only a context.Context"
+		if len(needed) > 0 {
+			what = "only " + _formatTypeList(needed, pass.Pkg)
+		}
+		diagnostic := analysis.Diagnostic{
+			Pos:      elt.Pos(),
+			End:      elt.End(),
+			Category: string(CodeWideRegistration),
+			Message: name + " needs " + what + " of the " + _shortTypeName(ctxType, pass.Pkg) +
+				" " + registryName + " makes it take; narrow its context, and register it via an adapter",
+		}
+		if value != nil {
+			diagnostic.SuggestedFixes = registry.fix(value, fn, sig, ctxIndex, needed, file)
+		}
+		pass.Report(diagnostic)
+	}
+}
+
+// _runRegistry lints that registries don't widen their handlers' contexts.
+func _runRegistry(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+
+	registry := _newRegistry(pass, tracker)
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if lit, ok := node.(*ast.CompositeLit); ok {
+				registry.check(lit, file)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
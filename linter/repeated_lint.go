@@ -0,0 +1,196 @@
+package linter
+
+// This file defines the linter that large inline typed context interfaces
+// aren't repeated.  An inline interface like
+//	func f(ctx interface {
+//		context.Context
+//		DatabaseContext
+//		LoggerContext
+//		RequestContext
+//	})
+// is fine once; but copied into ten functions, it's a named type in all but
+// name, and every change to it has to be made ten times.  So we report
+// inline interfaces of the same shape (the same leaf interfaces; see
+// shapes_lint.go) appearing at least -typedcontextrepeated.minuses times in
+// a package, if they include at least -typedcontextrepeated.minleaves leaf
+// interfaces, not counting context.Context.  Smaller ones are cheap to
+// repeat, and say what each function uses at a glance.
+//
+// We report each shape once, at its first appearance, with the others as
+// related positions, and suggest a fix which declares a named interface for
+// it, with a go:generate directive for typedcontext-gen so that it gets a
+// constructor, and uses that name everywhere instead.  The name joins the
+// names of its leaf interfaces, like DatabaseLoggerRequestContext for the
+// above; if that's taken, or an embed in any appearance has comments, which
+// wouldn't survive, there's no fix.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextRepeatedAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextrepeated",
+	Doc:  "reports large inline typed context interfaces repeated within a package, which should be named",
+	Run:  _runRepeated,
+}
+
+// The defaults for -typedcontextrepeated.minleaves and minuses.
+const (
+	_defaultMinRepeatedLeaves = 3
+	_defaultMinRepeatedUses   = 2
+)
+
+var (
+	// _minRepeatedLeaves is the fewest leaf interfaces an inline interface
+	// must include for us to report it.
+	_minRepeatedLeaves = _defaultMinRepeatedLeaves
+	// _minRepeatedUses is the fewest times an inline interface must appear
+	// in a package for us to report it.
+	_minRepeatedUses = _defaultMinRepeatedUses
+)
+
+func init() {
+	TypedContextRepeatedAnalyzer.Flags.IntVar(&_minRepeatedLeaves, "minleaves", _defaultMinRepeatedLeaves,
+		"the fewest leaf interfaces, other than context.Context, an inline "+
+			"typed context interface must include to be reported when repeated")
+	TypedContextRepeatedAnalyzer.Flags.IntVar(&_minRepeatedUses, "minuses", _defaultMinRepeatedUses,
+		"the fewest times an inline typed context interface must appear in "+
+			"a package to be reported (0 turns this off)")
+}
+
+// _repeatedName returns the name we suggest for the given shape, or "" if
+// we can't name it.
+func _repeatedName(shape _shape) string {
+	var name strings.Builder
+	for _, leaf := range shape.types {
+		named, ok := types.Unalias(leaf).(*types.Named)
+		if !ok || named.TypeArgs().Len() > 0 {
+			return ""
+		}
+		name.WriteString(strings.TrimSuffix(named.Obj().Name(), "Context"))
+	}
+	return name.String() + "Context"
+}
+
+// _hasEmbedComments returns true if an embed in any of the given shapes has
+// comments.
+func _hasEmbedComments(shapes []_shape) bool {
+	for _, shape := range shapes {
+		for _, field := range shape.node.Methods.List {
+			if field.Doc != nil || field.Comment != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// _namedInterfaceFix returns a fix which declares the given shape, as
+// written at its first appearance, in the given file, as an interface of
+// the given name, and replaces all its appearances with the name.
+func _namedInterfaceFix(file *ast.File, name string, shapes []_shape) analysis.SuggestedFix {
+	first := shapes[0].node
+	var decl strings.Builder
+	fmt.Fprintf(&decl, "//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=%s\n", name)
+	fmt.Fprintf(&decl, "type %s interface {\n", name)
+	for _, field := range first.Methods.List {
+		fmt.Fprintf(&decl, "\t%s\n", types.ExprString(field.Type))
+	}
+	decl.WriteString("}\n\n")
+
+	// Declare it before the declaration containing its first appearance.
+	var before ast.Node
+	for _, topLevel := range file.Decls {
+		if topLevel.Pos() <= first.Pos() && first.End() <= topLevel.End() {
+			before = topLevel
+			if funcDecl, ok := topLevel.(*ast.FuncDecl); ok && funcDecl.Doc != nil {
+				before = funcDecl.Doc
+			} else if genDecl, ok := topLevel.(*ast.GenDecl); ok && genDecl.Doc != nil {
+				before = genDecl.Doc
+			}
+			break
+		}
+	}
+
+	edits := []analysis.TextEdit{{Pos: before.Pos(), End: before.Pos(), NewText: []byte(decl.String())}}
+	for _, shape := range shapes {
+		edits = append(edits, analysis.TextEdit{
+			Pos: shape.node.Pos(), End: shape.node.End(), NewText: []byte(name),
+		})
+	}
+	return analysis.SuggestedFix{
+		Message:   "Declare " + name + " and use it",
+		TextEdits: edits,
+	}
+}
+
+// _runRepeated lints that large inline typed context interfaces aren't
+// repeated within the package.
+func _runRepeated(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	if _minRepeatedUses <= 0 {
+		return nil, nil
+	}
+
+	var keys []string
+	byKey := map[string][]_shape{}
+	files := map[string]*ast.File{}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, shape := range _inlineShapes(file, pass.TypesInfo) {
+			if len(shape.leaves) < _minRepeatedLeaves {
+				continue
+			}
+			key := shape.key()
+			if byKey[key] == nil {
+				keys = append(keys, key)
+				files[key] = file
+			}
+			byKey[key] = append(byKey[key], shape)
+		}
+	}
+
+	for _, key := range keys {
+		shapes := byKey[key]
+		if len(shapes) < _minRepeatedUses {
+			continue
+		}
+		name := _repeatedName(shapes[0])
+		if name != "" && pass.Pkg.Scope().Lookup(name) != nil {
+			name = ""
+		}
+
+		message := fmt.Sprintf("%s appears %d times in this package; declare it as a named interface",
+			_shapeString(shapes[0], pass.Pkg), len(shapes))
+		if name != "" {
+			message += ", like " + name + ", with a constructor generated by typedcontext-gen"
+		}
+		diagnostic := analysis.Diagnostic{
+			Pos:      shapes[0].node.Pos(),
+			Category: string(CodeRepeatedInterface),
+			Message:  message,
+		}
+		for _, shape := range shapes[1:] {
+			diagnostic.Related = append(diagnostic.Related, analysis.RelatedInformation{
+				Pos:     shape.node.Pos(),
+				Message: "also here",
+			})
+		}
+		if name != "" && !_hasEmbedComments(shapes) {
+			diagnostic.SuggestedFixes = []analysis.SuggestedFix{
+				_namedInterfaceFix(files[key], name, shapes),
+			}
+		}
+		pass.Report(diagnostic)
+	}
+	return nil, nil
+}
@@ -0,0 +1,94 @@
+package linter
+
+// This file defines the linter that only constructors return contexts.  A
+// context normally flows down the call chain, from a request's handler to
+// everything it calls; a function like
+//	func lookUpUser(ctx AppContext) (AppContext, error)
+// which hands back a (perhaps different) context makes it hard to tell which
+// context -- and which deadline, cancellation and providers -- any given
+// call sees.  Functions whose job is to build contexts are the exception:
+// by default, those named like With*, New*Context, or Compose* (as generated
+// by typedcontext-gen), and any function in the packages listed in
+// -typedcontextreturn.allowpkgs (middleware, say, and the typedcontext
+// runtime package).
+//
+// This is opt-in.  Only declared functions and methods are checked, not
+// function literals, which are typically middleware's own wrappers.
+
+import (
+	"go/ast"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextReturnAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextreturn",
+	Doc:  "reports functions other than constructors which return contexts",
+	Run:  _runReturn,
+}
+
+var (
+	// _contextConstructors lists globs (as for path.Match) matching the
+	// names of functions and methods which may return contexts.
+	_contextConstructors = stringList{"With*", "New*Context", "Compose*"}
+	// _returnAllowedPackages lists package-path prefixes in which any
+	// function may return a context.
+	_returnAllowedPackages = stringList{"github.com/khan/typed-context/typedcontext"}
+)
+
+func init() {
+	optIn(TypedContextReturnAnalyzer)
+	TypedContextReturnAnalyzer.Flags.Var(&_contextConstructors, "constructors",
+		"comma-separated list of globs matching the names of functions and "+
+			"methods which may return contexts")
+	TypedContextReturnAnalyzer.Flags.Var(&_returnAllowedPackages, "allowpkgs",
+		"comma-separated list of package-path prefixes (e.g. middleware) in "+
+			"which any function may return a context")
+}
+
+// _isContextConstructor returns true if the given function name matches one
+// of the constructor globs.
+func _isContextConstructor(name string) bool {
+	for _, pattern := range _contextConstructors {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// _runReturn lints that only constructors return contexts.
+func _runReturn(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	if hasAnyPathPrefix(pass.Pkg.Path(), _returnAllowedPackages) {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Type.Results == nil || _isContextConstructor(funcDecl.Name.Name) {
+				continue
+			}
+			for _, field := range funcDecl.Type.Results.List {
+				typ := pass.TypesInfo.TypeOf(field.Type)
+				if typ == nil || !isContextType(typ) {
+					continue
+				}
+				reportf(pass, field.Type, CodeReturnedContext,
+					"%s returns a context, but only constructors (functions "+
+						"named like %s) should; pass contexts down to the "+
+						"functions that need them instead",
+					funcDecl.Name.Name, strings.Join(_contextConstructors, ", "))
+				break // one report per function is plenty
+			}
+		}
+	}
+	return nil, nil
+}
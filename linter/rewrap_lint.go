@@ -0,0 +1,251 @@
+package linter
+
+// This file defines the linter that composite typed contexts aren't passed
+// through package context's With functions, like
+//	func F(ctx AppContext) {
+//		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+//		defer cancel()
+//		G(timeoutCtx.(AppContext)) // panics
+//	}
+// The result is a plain context.Context, without ctx's providers, so code
+// which needs them has to keep ctx around as well, or assert the result back
+// to a typed context, which fails.  Package typedcontext has wrappers,
+// typedcontext.WithTimeout and so on, which return a context of the same type
+// as the one they're given; for the rest, typedcontext.WithContext puts the
+// result back into a copy of the typed context.  Either needs the context's
+// type to have a constructor generated by typedcontext-gen.
+//
+// We report calls to context.WithCancel, WithDeadline, WithTimeout,
+// WithValue, and their Cause variants, whose argument is a named composite
+// typed context (one with at least two interfaces besides context.Context),
+// unless their result is put back with typedcontext.WithContext, directly or
+// via a variable.  Where there's a wrapper, and the type has a generated
+// constructor (ComposeX, for X), we suggest calling the wrapper instead.
+// We don't report context.WithoutCancel: dropping the providers along with
+// the cancellation is what typedcontext.Detach is for.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextRewrapAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextrewrap",
+	Doc:  "reports composite typed contexts passed to context.WithTimeout and the like, which return plain contexts",
+	Run:  _runRewrap,
+}
+
+// _typedcontextPath is the import path of package typedcontext.
+const _typedcontextPath = "github.com/khan/typed-context/typedcontext"
+
+// _rewrappers maps the functions of package context we report to whether
+// package typedcontext has a wrapper of the same name.
+var _rewrappers = map[string]bool{
+	"context.WithCancel":        true,
+	"context.WithDeadline":      true,
+	"context.WithTimeout":       true,
+	"context.WithValue":         true,
+	"context.WithCancelCause":   false,
+	"context.WithDeadlineCause": false,
+	"context.WithTimeoutCause":  false,
+}
+
+// _rewrappedCalls returns the calls in file whose results are put back into
+// a typed context with typedcontext.WithContext: passed to it directly, or
+// assigned to a variable which is.
+func _rewrappedCalls(pass *analysis.Pass, file *ast.File) map[*ast.CallExpr]bool {
+	rewrapped := map[*ast.CallExpr]bool{}
+	vars := map[types.Object]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 ||
+			lintutil.NameOf(lintutil.ObjectFor(call.Fun, pass.TypesInfo)) != _typedcontextPath+".WithContext" {
+			return true
+		}
+		switch inner := ast.Unparen(call.Args[1]).(type) {
+		case *ast.CallExpr:
+			rewrapped[inner] = true
+		case *ast.Ident:
+			if obj := pass.TypesInfo.ObjectOf(inner); obj != nil {
+				vars[obj] = true
+			}
+		}
+		return true
+	})
+	if len(vars) == 0 {
+		return rewrapped
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		var lhs []ast.Expr
+		var rhs []ast.Expr
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = node.Lhs, node.Rhs
+		case *ast.ValueSpec:
+			for _, name := range node.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = node.Values
+		default:
+			return true
+		}
+		if len(rhs) != 1 || len(lhs) == 0 {
+			return true
+		}
+		call, ok := ast.Unparen(rhs[0]).(*ast.CallExpr)
+		ident, isIdent := lhs[0].(*ast.Ident)
+		if ok && isIdent && vars[pass.TypesInfo.ObjectOf(ident)] {
+			rewrapped[call] = true
+		}
+		return true
+	})
+	return rewrapped
+}
+
+// _hasConstructor returns true if typ's package declares the constructor
+// typedcontext-gen generates for it, which the wrappers in package
+// typedcontext need.
+func _hasConstructor(typ *types.Named) bool {
+	obj := typ.Obj()
+	if obj.Pkg() == nil {
+		return false
+	}
+	_, ok := obj.Pkg().Scope().Lookup("Compose" + obj.Name()).(*types.Func)
+	return ok
+}
+
+// _rewrapFix returns a fix replacing the function of the given call, to
+// package context, with the wrapper of the same name in package typedcontext,
+// importing the latter if need be, or nil if it's shadowed.
+func _rewrapFix(pass *analysis.Pass, file *ast.File, call *ast.CallExpr, funcName string) []analysis.SuggestedFix {
+	name := "typedcontext"
+	imported := false
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == _typedcontextPath {
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			imported = true
+			break
+		}
+	}
+	if name == "_" || name == "." {
+		return nil
+	}
+	scope := pass.Pkg.Scope().Innermost(call.Pos())
+	if scope == nil {
+		return nil
+	}
+	_, obj := scope.LookupParent(name, call.Pos())
+	switch obj := obj.(type) {
+	case nil:
+		if imported {
+			return nil
+		}
+	case *types.PkgName:
+		if !imported || obj.Imported().Path() != _typedcontextPath {
+			return nil // another package of that name
+		}
+	default:
+		return nil // shadowed
+	}
+
+	edits := []analysis.TextEdit{{
+		Pos: call.Fun.Pos(), End: call.Fun.End(), NewText: []byte(name + "." + funcName),
+	}}
+	if !imported {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.IMPORT {
+				continue
+			}
+			if genDecl.Lparen.IsValid() {
+				edits = append(edits, analysis.TextEdit{
+					Pos: genDecl.Rparen, End: genDecl.Rparen,
+					NewText: []byte("\t" + strconv.Quote(_typedcontextPath) + "\n"),
+				})
+			} else {
+				edits = append(edits, analysis.TextEdit{
+					Pos: genDecl.End(), End: genDecl.End(),
+					NewText: []byte("\nimport " + strconv.Quote(_typedcontextPath)),
+				})
+			}
+			break
+		}
+	}
+	return []analysis.SuggestedFix{{
+		Message:   "Call typedcontext." + funcName + " instead",
+		TextEdits: edits,
+	}}
+}
+
+// _runRewrap lints that composite typed contexts aren't passed to functions
+// of package context which return plain contexts.
+func _runRewrap(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		var rewrapped map[*ast.CallExpr]bool
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			fullName := lintutil.NameOf(lintutil.ObjectFor(call.Fun, pass.TypesInfo))
+			hasWrapper, ok := _rewrappers[fullName]
+			if !ok {
+				return true
+			}
+			named, ok := types.Unalias(pass.TypesInfo.TypeOf(call.Args[0])).(*types.Named)
+			if !ok || !isContextType(named) || len(_distinctLeaves(named)) < 2 {
+				return true
+			}
+			if rewrapped == nil {
+				rewrapped = _rewrappedCalls(pass, file)
+			}
+			if rewrapped[call] {
+				return true
+			}
+
+			funcName := strings.TrimPrefix(fullName, "context.")
+			typeName := _shortTypeName(named, pass.Pkg)
+			message := fullName + " returns a plain context.Context, without the " +
+				"providers of " + typeName
+			var fixes []analysis.SuggestedFix
+			switch {
+			case hasWrapper && _hasConstructor(named):
+				message += "; call typedcontext." + funcName + ", which returns a " + typeName
+				fixes = _rewrapFix(pass, file, call, funcName)
+			case hasWrapper:
+				message += "; generate a constructor for " + typeName + " with " +
+					"typedcontext-gen, and call typedcontext." + funcName + ", which returns a " + typeName
+			case _hasConstructor(named):
+				message += "; put its result back with typedcontext.WithContext"
+			default:
+				message += "; generate a constructor for " + typeName + " with " +
+					"typedcontext-gen, and put its result back with typedcontext.WithContext"
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:            call.Pos(),
+				End:            call.End(),
+				Category:       string(CodeRewrappedContext),
+				Message:        message,
+				SuggestedFixes: fixes,
+			})
+			return true
+		})
+	}
+	return nil, nil
+}
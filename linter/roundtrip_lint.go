@@ -0,0 +1,297 @@
+package linter
+
+// This file defines the linter that contexts aren't rebuilt from their own
+// providers, like
+//	func F(ctx AppContext) {
+//		logger := ctx.Logger()
+//		ctx2 := WithLogger(ctx, logger)
+//		G(ctx2)
+//	}
+// WithLogger here gives ctx back the logger it already has: ctx2 is just ctx
+// again, at the cost of a wrapper, and readers have to check that nothing
+// changed.  Likewise typedcontext.Override(ctx,
+// typedcontext.With(ctx.Logger())), or a generated constructor called with
+// ctx and all of its providers.
+//
+// We report calls returning a typed context which are passed a context
+// variable, and otherwise only providers extracted from it by accessor calls
+// (see _extractedProvider): directly, via typedcontext.With, or via a
+// variable holding the result, which is never assigned again, if ctx isn't
+// assigned in between either.  The variable's type must be assignable to the
+// call's result type, so that it can be forwarded instead.  If it's the same
+// type, we suggest a fix which does so, removing the declarations of any
+// variables holding the providers if that was their only use.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextRoundtripAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextroundtrip",
+	Doc:  "reports contexts rebuilt from their own providers, which could be forwarded instead",
+	Run:  _runRoundTrip,
+}
+
+// _roundTrip is a provider extracted from a context variable by an accessor
+// call.
+type _roundTrip struct {
+	ctx  types.Object
+	call *ast.CallExpr
+	// holder is the variable the provider was stored in, if any, and decl
+	// the statement declaring it, if it declares nothing else.
+	holder types.Object
+	decl   ast.Node
+}
+
+// _accessorCallOn returns the context variable the given expression calls
+// an accessor of, and the call, or nil if it doesn't.
+func _accessorCallOn(expr ast.Expr, info *types.Info) (types.Object, *ast.CallExpr) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return nil, nil
+	}
+	if method, _ := _extractedProvider(call, info); method == nil {
+		return nil, nil
+	}
+	recv, ok := ast.Unparen(call.Fun.(*ast.SelectorExpr).X).(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	ctx, ok := info.Uses[recv].(*types.Var)
+	if !ok {
+		return nil, nil
+	}
+	return ctx, call
+}
+
+// _roundTripVars returns the variables in file declared to hold a provider
+// extracted from a context variable, like logger := ctx.Logger(), and
+// never assigned again nor addressed, and the ends of the statements
+// assigning each variable in file, other than those declaring them.
+func _roundTripVars(file *ast.File, info *types.Info) (map[types.Object]_roundTrip, map[types.Object][]token.Pos) {
+	holders := map[types.Object]_roundTrip{}
+	assigned := map[types.Object][]token.Pos{}
+	declare := func(node ast.Node, lhs []*ast.Ident, rhs []ast.Expr) {
+		for i, ident := range lhs {
+			obj := info.Defs[ident]
+			if obj == nil {
+				if obj = info.Uses[ident]; obj != nil { // redeclared with :=
+					assigned[obj] = append(assigned[obj], node.End())
+				}
+				continue
+			}
+			if len(lhs) != len(rhs) {
+				continue
+			}
+			if ctx, call := _accessorCallOn(rhs[i], info); ctx != nil {
+				holder := _roundTrip{ctx: ctx, call: call, holder: obj}
+				if len(lhs) == 1 {
+					holder.decl = node
+				}
+				holders[obj] = holder
+			}
+		}
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				var lhs []*ast.Ident
+				for _, expr := range node.Lhs {
+					if ident, ok := expr.(*ast.Ident); ok {
+						lhs = append(lhs, ident)
+					}
+				}
+				if len(lhs) == len(node.Lhs) {
+					declare(node, lhs, node.Rhs)
+				}
+				return true
+			}
+			for _, expr := range node.Lhs {
+				if ident, ok := ast.Unparen(expr).(*ast.Ident); ok {
+					if obj := info.Uses[ident]; obj != nil {
+						assigned[obj] = append(assigned[obj], node.End())
+					}
+				}
+			}
+		case *ast.GenDecl:
+			if node.Tok != token.VAR {
+				return true
+			}
+			for _, spec := range node.Specs {
+				spec := spec.(*ast.ValueSpec)
+				var decl ast.Node = spec
+				if len(node.Specs) == 1 {
+					decl = node
+				}
+				declare(decl, spec.Names, spec.Values)
+			}
+		case *ast.RangeStmt:
+			for _, expr := range []ast.Expr{node.Key, node.Value} {
+				if ident, ok := expr.(*ast.Ident); ok && info.Uses[ident] != nil {
+					assigned[info.Uses[ident]] = append(assigned[info.Uses[ident]], node.End())
+				}
+			}
+		case *ast.IncDecStmt:
+			if ident, ok := ast.Unparen(node.X).(*ast.Ident); ok && info.Uses[ident] != nil {
+				assigned[info.Uses[ident]] = append(assigned[info.Uses[ident]], node.End())
+			}
+		case *ast.UnaryExpr:
+			if node.Op != token.AND {
+				return true
+			}
+			if ident, ok := ast.Unparen(node.X).(*ast.Ident); ok && info.Uses[ident] != nil {
+				assigned[info.Uses[ident]] = append(assigned[info.Uses[ident]], node.End())
+			}
+		}
+		return true
+	})
+	for obj := range holders {
+		if len(assigned[obj]) > 0 {
+			delete(holders, obj)
+		}
+	}
+	return holders, assigned
+}
+
+// _providerArg returns the round trip which the given argument is, or false
+// if it isn't one.
+func _providerArg(arg ast.Expr, info *types.Info, holders map[types.Object]_roundTrip) (_roundTrip, bool) {
+	arg = ast.Unparen(arg)
+	if call, ok := arg.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		fun := ast.Unparen(call.Fun)
+		if index, ok := fun.(*ast.IndexExpr); ok {
+			fun = index.X // typedcontext.With[P](provider)
+		}
+		if lintutil.NameOf(lintutil.ObjectFor(fun, info)) == _typedcontextPath+".With" {
+			arg = ast.Unparen(call.Args[0])
+		}
+	}
+	if ctx, call := _accessorCallOn(arg, info); ctx != nil {
+		return _roundTrip{ctx: ctx, call: call}, true
+	}
+	if ident, ok := arg.(*ast.Ident); ok {
+		holder, ok := holders[info.Uses[ident]]
+		return holder, ok
+	}
+	return _roundTrip{}, false
+}
+
+// _deleteLines returns an edit deleting the lines the given node spans.
+func _deleteLines(fset *token.FileSet, node ast.Node) analysis.TextEdit {
+	file := fset.File(node.Pos())
+	end := node.End()
+	if line := file.Line(end); line < file.LineCount() {
+		end = file.LineStart(line + 1)
+	}
+	return analysis.TextEdit{Pos: file.LineStart(file.Line(node.Pos())), End: end}
+}
+
+// _checkRoundTrip reports the given call if it rebuilds a context from its
+// own providers.
+func _checkRoundTrip(
+	pass *analysis.Pass,
+	call *ast.CallExpr,
+	holders map[types.Object]_roundTrip,
+	assigned map[types.Object][]token.Pos,
+	uses map[types.Object]int,
+) {
+	resultType := pass.TypesInfo.TypeOf(call)
+	if call.Ellipsis.IsValid() || resultType == nil || !isContextType(resultType) {
+		return
+	}
+	if tv := pass.TypesInfo.Types[call.Fun]; tv.IsType() {
+		return // a conversion
+	}
+
+	var ctxIdent *ast.Ident
+	var ctx types.Object
+	var trips []_roundTrip
+	for _, arg := range call.Args {
+		if ident, ok := ast.Unparen(arg).(*ast.Ident); ok {
+			if obj, ok := pass.TypesInfo.Uses[ident].(*types.Var); ok && isContextType(obj.Type()) {
+				if ctx != nil && obj != ctx {
+					return
+				}
+				ctxIdent, ctx = ident, obj
+				continue
+			}
+		}
+		trip, ok := _providerArg(arg, pass.TypesInfo, holders)
+		if !ok {
+			return
+		}
+		trips = append(trips, trip)
+	}
+	if ctx == nil || len(trips) == 0 || !types.AssignableTo(ctx.Type(), resultType) {
+		return
+	}
+
+	var accessors []string
+	for _, trip := range trips {
+		if trip.ctx != ctx {
+			return
+		}
+		for _, end := range assigned[ctx] {
+			if trip.call.End() <= end && end <= call.Pos() {
+				return // ctx was reassigned since
+			}
+		}
+		accessors = append(accessors, types.ExprString(trip.call.Fun.(*ast.SelectorExpr).Sel)+"()")
+	}
+
+	diagnostic := analysis.Diagnostic{
+		Pos:      call.Pos(),
+		End:      call.End(),
+		Category: string(CodeRoundTrip),
+		Message: types.ExprString(call.Fun) + " only rebuilds " + ctxIdent.Name +
+			" from its own " + strings.Join(accessors, ", ") + "; forward " + ctxIdent.Name + " instead",
+	}
+	if types.Identical(ctx.Type(), resultType) {
+		edits := []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(ctxIdent.Name)}}
+		deleted := map[types.Object]bool{}
+		for _, trip := range trips {
+			if trip.holder != nil && trip.decl != nil && uses[trip.holder] == 1 && !deleted[trip.holder] {
+				deleted[trip.holder] = true
+				edits = append(edits, _deleteLines(pass.Fset, trip.decl))
+			}
+		}
+		diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Forward " + ctxIdent.Name,
+			TextEdits: edits,
+		}}
+	}
+	pass.Report(diagnostic)
+}
+
+// _runRoundTrip lints that contexts aren't rebuilt from their own
+// providers.
+func _runRoundTrip(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	uses := map[types.Object]int{}
+	for _, obj := range pass.TypesInfo.Uses {
+		uses[obj]++
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		holders, assigned := _roundTripVars(file, pass.TypesInfo)
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				_checkRoundTrip(pass, call, holders, assigned, uses)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,224 @@
+package linter
+
+// This file defines Run, which runs the analyzers in-process and returns
+// structured findings, for tools like dashboards and review bots which would
+// otherwise have to run the linter command and parse its output.
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Options configures Run.
+type Options struct {
+	// Analyzers are the analyzers to run; if empty, all of Analyzers.
+	Analyzers []*analysis.Analyzer
+	// Flags are analyzer flags to set for the run, by their name as given
+	// on the command line, without the dash: like
+	// "typedcontextinterface.checktests" or "typedcontextcancel.enable".
+	// They're restored afterwards.
+	Flags map[string]string
+}
+
+// Related is a position related to a Finding, like the declaration of an
+// interface it's about.
+type Related struct {
+	Position token.Position `json:"position"`
+	Message  string         `json:"message"`
+}
+
+// Finding is a diagnostic reported by Run.
+type Finding struct {
+	// Analyzer is the name of the analyzer which reported it.
+	Analyzer string `json:"analyzer"`
+	// Code is its diagnostic code, like "TC001" (see Explain).
+	Code Code `json:"code"`
+	// Position is where it was reported.
+	Position token.Position `json:"position"`
+	// Message is the diagnostic's message.
+	Message string `json:"message"`
+	// Function is the name of the function declaration containing it, as
+	// "Func" or "Type.Method", or "" if it's outside any function.
+	Function string `json:"function,omitempty"`
+	// Interfaces are the leaf interfaces (see
+	// analysisengine.LeafInterfaces), other than context.Context, of the
+	// typed context it's reported on -- the innermost expression at its
+	// position whose type is a typed context -- as full names like
+	// "example.com/app.LoggerContext", sorted; or nil if there's none.
+	Interfaces []string `json:"interfaces,omitempty"`
+	// Related are the diagnostic's related positions, if any.
+	Related []Related `json:"related,omitempty"`
+}
+
+// _setFlags sets the given analyzer flags (see Options.Flags), and returns a
+// function which restores them.
+func _setFlags(analyzers []*analysis.Analyzer, values map[string]string) (func(), error) {
+	var restores []func()
+	restore := func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		ResetSettings()
+	}
+	for name, value := range values {
+		var found bool
+		for _, analyzer := range analyzers {
+			flagName, ok := strings.CutPrefix(name, analyzer.Name+".")
+			if !ok {
+				continue
+			}
+			flag := analyzer.Flags.Lookup(flagName)
+			if flag == nil {
+				break
+			}
+			old := flag.Value.String()
+			if err := flag.Value.Set(value); err != nil {
+				restore()
+				return nil, fmt.Errorf("invalid value %q for flag -%s: %w", value, name, err)
+			}
+			restores = append(restores, func() { flag.Value.Set(old) })
+			found = true
+			break
+		}
+		if !found {
+			restore()
+			return nil, fmt.Errorf("unknown flag -%s", name)
+		}
+	}
+	ResetSettings()
+	return restore, nil
+}
+
+// _enclosingFunction returns the name of the function declaration in file
+// containing pos, as for Finding.Function, and the path of nodes enclosing
+// pos, innermost first.
+func _enclosingFunction(file *ast.File, pos token.Pos) (string, []ast.Node) {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	for _, node := range path {
+		if funcDecl, ok := node.(*ast.FuncDecl); ok {
+			name := funcDecl.Name.Name
+			if recv := _receiverTypeName(funcDecl); recv != "" {
+				name = recv + "." + name
+			}
+			return name, path
+		}
+	}
+	return "", path
+}
+
+// _findingInterfaces returns the Interfaces of a finding at the given path
+// of nodes (see Finding.Interfaces).
+func _findingInterfaces(path []ast.Node, info *types.Info) []string {
+	for _, node := range path {
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			continue
+		}
+		typ := info.TypeOf(expr)
+		if typ == nil || !isContextType(typ) || isContextRoot(typ) {
+			continue
+		}
+		var names []string
+		for _, leaf := range _distinctLeaves(typ) {
+			names = append(names, types.TypeString(leaf, nil))
+		}
+		sort.Strings(names)
+		return names
+	}
+	return nil
+}
+
+// Run runs the analyzers over the given packages, which must have been
+// loaded with their syntax and types (packages.LoadAllSyntax), and returns
+// their findings, sorted by position.  Findings in files shared by several
+// packages (like p and its test variant) are reported once.
+//
+// Settings come from the analyzer flags, as changed by options.Flags, and
+// any configuration files, as for the linter command.  Since the flags are
+// global, Run mustn't be called concurrently with itself, or anything else
+// using the analyzers.  The analysis can't be interrupted; ctx is only
+// checked before and after it.
+func Run(ctx context.Context, pkgs []*packages.Package, options Options) ([]Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	analyzers := options.Analyzers
+	if len(analyzers) == 0 {
+		analyzers = Analyzers
+	}
+	restore, err := _setFlags(analyzers, options.Flags)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var findings []Finding
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", act.Package.PkgPath, act.Analyzer.Name, act.Err)
+		}
+		fset := act.Package.Fset
+		for _, diagnostic := range act.Diagnostics {
+			finding := Finding{
+				Analyzer: act.Analyzer.Name,
+				Code:     Code(diagnostic.Category),
+				Position: fset.Position(diagnostic.Pos),
+				Message:  diagnostic.Message,
+			}
+			key := finding.Position.String() + ": " + finding.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			for _, file := range act.Package.Syntax {
+				if file.FileStart <= diagnostic.Pos && diagnostic.Pos <= file.FileEnd {
+					var path []ast.Node
+					finding.Function, path = _enclosingFunction(file, diagnostic.Pos)
+					finding.Interfaces = _findingInterfaces(path, act.Package.TypesInfo)
+					break
+				}
+			}
+			for _, related := range diagnostic.Related {
+				finding.Related = append(finding.Related, Related{fset.Position(related.Pos), related.Message})
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	_sortFindings(findings)
+	return findings, nil
+}
+
+// _sortFindings sorts the given findings by position.
+func _sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Position, findings[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
@@ -0,0 +1,113 @@
+package linter
+
+// This file defines the severity of each diagnostic code, so that new checks
+// can be rolled out gradually: first as advice, which is reported but (with
+// linter/cmd's -failon=error) doesn't fail the run, then as errors once the
+// existing reports are fixed.
+//
+// -typedcontextinterface.severity (or severity in a configuration file) sets
+// the severity of particular codes, as CODE=LEVEL, like TC002=advice:
+//	error	report it as usual (the default)
+//	advice	report it, with its message prefixed by AdvicePrefix
+//	off	don't report it at all
+// Like -generated, this applies to all the analyzers in Analyzers, by way of
+// _applySeverity.
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// The severities of diagnostic codes; see the top of the file.
+const (
+	_severityError  = "error"
+	_severityAdvice = "advice"
+	_severityOff    = "off"
+)
+
+// AdvicePrefix starts the message of each diagnostic whose code is set to
+// advice, so that drivers (and readers) can tell it from an error.
+const AdvicePrefix = "advice: "
+
+// _checkSeverities returns an error if any of the given CODE=LEVEL settings
+// is malformed, or names a code which doesn't exist.
+func _checkSeverities(severities []string) error {
+	for _, item := range severities {
+		code, level, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid severity %q: want CODE=LEVEL", item)
+		}
+		if _, ok := Explain(code); !ok {
+			return fmt.Errorf("invalid severity %q: unknown diagnostic code %q", item, code)
+		}
+		switch level {
+		case _severityError, _severityAdvice, _severityOff:
+		default:
+			return fmt.Errorf("invalid severity %q: level must be %s, %s or %s",
+				item, _severityError, _severityAdvice, _severityOff)
+		}
+	}
+	return nil
+}
+
+// _severitiesFlag is the flag.Value of -severity: a list of CODE=LEVEL.
+type _severitiesFlag struct{ stringList }
+
+func (severities *_severitiesFlag) Set(value string) error {
+	if err := severities.stringList.Set(value); err != nil {
+		return err
+	}
+	return _checkSeverities(severities.stringList)
+}
+
+// _severities sets the severity of particular codes, as CODE=LEVEL.
+var _severities _severitiesFlag
+
+func init() {
+	TypedContextInterfaceAnalyzer.Flags.Var(&_severities, "severity",
+		"comma-separated list of CODE=LEVEL, like TC002=advice, setting the "+
+			"severity of diagnostics with that code in all analyzers: error "+
+			"(the default), advice, or off")
+}
+
+// severity returns the severity of diagnostics with the given code.
+func (s *settings) severity(code string) string {
+	level := _severityError
+	for _, item := range s.severities {
+		if name, override, _ := strings.Cut(item, "="); strings.EqualFold(name, code) {
+			level = override // later settings win
+		}
+	}
+	return level
+}
+
+// _applySeverity wraps the given analyzer's Run so that its diagnostics are
+// dropped, or marked as advice, as configured.
+//
+// This must be called from an init function, since it wraps analyzer.Run.
+func _applySeverity(analyzer *analysis.Analyzer) {
+	run := analyzer.Run
+	analyzer.Run = func(pass *analysis.Pass) (interface{}, error) {
+		settings, err := loadSettings(pass)
+		if err != nil {
+			return nil, err
+		}
+		if len(settings.severities) == 0 {
+			return run(pass)
+		}
+
+		filtered := *pass
+		filtered.Report = func(diagnostic analysis.Diagnostic) {
+			switch settings.severity(diagnostic.Category) {
+			case _severityOff:
+				return
+			case _severityAdvice:
+				diagnostic.Message = AdvicePrefix + diagnostic.Message
+			}
+			pass.Report(diagnostic)
+		}
+		return run(&filtered)
+	}
+}
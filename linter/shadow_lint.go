@@ -0,0 +1,120 @@
+package linter
+
+// This file defines the linter that code doesn't shadow a context with one
+// of a different type, like
+//	func F(ctx interface{ context.Context; LoggerContext }) {
+//		...
+//		if ctx, ok := ctx.(SecretsContext); ok {
+//			...
+//		}
+//	}
+// or a function literal with its own ctx parameter.  Within the shadow's
+// scope, "ctx" means something with a different set of capabilities, and
+// it's easy for later code (or a later edit) to use the wrong one without
+// noticing.
+//
+// Shadowing with a context of the identical type, which is common and
+// harmless (e.g. `ctx := ctx` to capture it), is fine; so are the parameters
+// of function literals passed to runners (see -typedcontextinterface.runners),
+// which are the same context passed through.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+var TypedContextShadowAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextshadow",
+	Doc:  "reports contexts shadowed by contexts of a different type",
+	Run:  _runShadow,
+}
+
+// _shadowedContext returns the context variable which the given one shadows,
+// or nil if it doesn't shadow one.  We only consider local variables: a
+// package-level variable named ctx would be strange enough that it's not
+// worth worrying about.
+func _shadowedContext(pass *analysis.Pass, obj *types.Var) *types.Var {
+	scope := obj.Parent()
+	if scope == nil || scope.Parent() == nil {
+		return nil
+	}
+	_, shadowed := scope.Parent().LookupParent(obj.Name(), obj.Pos())
+	shadowedVar, ok := shadowed.(*types.Var)
+	if !ok || shadowedVar.Parent() == pass.Pkg.Scope() ||
+		!isContextType(shadowedVar.Type()) {
+		return nil
+	}
+	return shadowedVar
+}
+
+// _runnerParams returns the parameters of function literals passed to the
+// given runners.
+func _runnerParams(pass *analysis.Pass, runners []string) map[types.Object]bool {
+	isRunner := map[string]bool{}
+	for _, runner := range runners {
+		isRunner[runner] = true
+	}
+	params := map[types.Object]bool{}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok ||
+				!isRunner[lintutil.NameOf(lintutil.ObjectFor(call.Fun, pass.TypesInfo))] {
+				return true
+			}
+			for _, arg := range call.Args {
+				funcLit, ok := arg.(*ast.FuncLit)
+				if !ok {
+					continue
+				}
+				for _, field := range funcLit.Type.Params.List {
+					for _, name := range field.Names {
+						params[pass.TypesInfo.Defs[name]] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return params
+}
+
+// _runShadow lints that contexts aren't shadowed by contexts with different
+// interfaces.
+func _runShadow(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	runnerParams := _runnerParams(pass, settings.runners)
+	for ident, obj := range pass.TypesInfo.Defs {
+		obj, ok := obj.(*types.Var)
+		if !ok || obj.Name() == "_" || obj.IsField() || runnerParams[obj] ||
+			!isContextType(obj.Type()) {
+			continue
+		}
+		if _skipFile(pass.Fset.File(ident.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		shadowed := _shadowedContext(pass, obj)
+		if shadowed == nil || types.Identical(obj.Type(), shadowed.Type()) {
+			continue
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:      ident.Pos(),
+			Category: string(CodeShadowedContext),
+			Message: "context " + obj.Name() + " of type " +
+				_shortTypeName(obj.Type(), pass.Pkg) + " shadows one of type " +
+				_shortTypeName(shadowed.Type(), pass.Pkg) + "; rename one of them",
+			Related: []analysis.RelatedInformation{{
+				Pos:     shadowed.Pos(),
+				Message: "shadowed " + shadowed.Name() + " declared here",
+			}},
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,291 @@
+package linter
+
+// This file defines the linter that keeps new combinations of typed context
+// interfaces visible in code review.  Calling f(ctx), where f requests
+// interface{ A; B } and ctx is interface{ A; B; C }, is fine; but each new
+// inline interface like
+//	func f(ctx interface {
+//		context.Context
+//		LoggerContext
+//		BillingContext
+//	})
+// is a new "shape" of context -- a combination of leaf interfaces (see
+// analysisengine.LeafInterfaces) -- which callers have to be able to provide,
+// and nothing else in the diff says so.  Over time they sprawl.
+//
+// So we keep an inventory of the shapes each package uses: a file, given by
+// -typedcontextshapes.inventory (or shapeinventory in a configuration file),
+// with a line per package and shape, like
+//	example.com/billing	example.com/app.BillingContext, example.com/app.LoggerContext
+// (context.Context isn't listed), and report inline interfaces whose shape
+// isn't listed for their package.  The linter command's -recordshapes=FILE
+// mode writes the inventory; committing it alongside the code puts each new
+// shape in the diff.  Named composite interfaces aren't checked: their
+// declarations are already in the diff.
+//
+// This analyzer is opt-in, since it needs the inventory.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+var TypedContextShapesAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextshapes",
+	Doc:  "reports inline typed context interfaces combining leaf interfaces in a way not recorded in the shape inventory",
+	Run:  _runShapes,
+}
+
+// _shapeInventory is the inventory file; if it's empty, we don't check.
+var _shapeInventory string
+
+func init() {
+	TypedContextShapesAnalyzer.Flags.StringVar(&_shapeInventory, "inventory", "",
+		"the file listing the known shapes of inline typed context interfaces, "+
+			"as written by the -recordshapes mode")
+	optIn(TypedContextShapesAnalyzer)
+}
+
+// _shape is the shape of an inline interface.
+type _shape struct {
+	// leaves are the full names of its leaf interfaces, sorted, like
+	// "example.com/app.LoggerContext".
+	leaves []string
+	// types are the leaf interfaces themselves, in the same order.
+	types []types.Type
+	// node is the interface itself.
+	node *ast.InterfaceType
+}
+
+// key returns the shape as listed in the inventory.
+func (shape _shape) key() string {
+	return strings.Join(shape.leaves, ", ")
+}
+
+// _inlineShapes returns the shapes of the inline composite typed context
+// interfaces in the given file, in order of position.
+func _inlineShapes(file *ast.File, typesInfo *types.Info) []_shape {
+	var shapes []_shape
+	ast.Inspect(file, func(node ast.Node) bool {
+		ifaceType, ok := node.(*ast.InterfaceType)
+		if !ok {
+			if spec, ok := node.(*ast.TypeSpec); ok {
+				if ifaceType, ok := spec.Type.(*ast.InterfaceType); ok {
+					// Named: skip it, but not any inline interfaces
+					// within it.
+					ast.Inspect(ifaceType.Methods, func(node ast.Node) bool {
+						if inline, ok := node.(*ast.InterfaceType); ok {
+							shapes = append(shapes, _inlineShape(inline, typesInfo)...)
+						}
+						return true
+					})
+					return false
+				}
+			}
+			return true
+		}
+		shapes = append(shapes, _inlineShape(ifaceType, typesInfo)...)
+		return true
+	})
+	return shapes
+}
+
+// _inlineShape returns the shape of the given inline interface, if it's a
+// composite typed context interface, as a slice of zero or one shapes.
+func _inlineShape(ifaceType *ast.InterfaceType, typesInfo *types.Info) []_shape {
+	typ := typesInfo.TypeOf(ifaceType)
+	if typ == nil || !isContextType(typ) {
+		return nil
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok || iface.NumExplicitMethods() > 0 {
+		return nil
+	}
+	leaves := _distinctLeaves(typ)
+	if len(leaves) < 2 {
+		return nil // not a combination of anything
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return types.TypeString(leaves[i], nil) < types.TypeString(leaves[j], nil)
+	})
+	shape := _shape{types: leaves, node: ifaceType}
+	for _, leaf := range leaves {
+		shape.leaves = append(shape.leaves, types.TypeString(leaf, nil))
+	}
+	return []_shape{shape}
+}
+
+// _inventory is a parsed inventory file: the set of shapes (see
+// _shape.key) by package path.
+type _inventory map[string]map[string]bool
+
+// _inventories caches the inventory files we've read, by path.
+var _inventories sync.Map
+
+// _parseInventory parses the inventory in data, read from the given file.
+func _parseInventory(filename string, data []byte) (_inventory, error) {
+	inventory := _inventory{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pkg, rest, ok := strings.Cut(text, "\t")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want PACKAGE<tab>SHAPE", filename, line)
+		}
+		var leaves []string
+		for _, leaf := range strings.Split(rest, ",") {
+			leaves = append(leaves, strings.TrimSpace(leaf))
+		}
+		sort.Strings(leaves)
+		if inventory[pkg] == nil {
+			inventory[pkg] = map[string]bool{}
+		}
+		inventory[pkg][strings.Join(leaves, ", ")] = true
+	}
+	return inventory, scanner.Err()
+}
+
+// _loadInventory returns the inventory in the given file.
+func _loadInventory(filename string) (_inventory, error) {
+	if cached, ok := _inventories.Load(filename); ok {
+		return cached.(_inventory), nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading shape inventory: %w", err)
+	}
+	inventory, err := _parseInventory(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := _inventories.LoadOrStore(filename, inventory)
+	return cached.(_inventory), nil
+}
+
+// _shapeString returns the given shape as an interface literal, with names
+// relative to pkg, like "interface{ BillingContext; LoggerContext }".
+func _shapeString(shape _shape, pkg *types.Package) string {
+	names := make([]string, len(shape.types))
+	for i, leaf := range shape.types {
+		names[i] = _shortTypeName(leaf, pkg)
+	}
+	return "interface{ " + strings.Join(names, "; ") + " }"
+}
+
+// _runShapes lints that inline interfaces have shapes in the inventory.
+func _runShapes(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	if settings.shapeInventory == "" {
+		return nil, nil
+	}
+	inventory, err := _loadInventory(settings.shapeInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	reported := map[string]bool{}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, shape := range _inlineShapes(file, pass.TypesInfo) {
+			key := shape.key()
+			if inventory[pass.Pkg.Path()][key] || reported[key] {
+				continue
+			}
+			reported[key] = true // just the first use in the package
+			reportf(pass, shape.node, CodeNewShape,
+				"new typed context shape %s, not in the inventory %s for this "+
+					"package; if it's needed, record it with -recordshapes",
+				_shapeString(shape, pass.Pkg), filepath.Base(settings.shapeInventory))
+		}
+	}
+	return nil, nil
+}
+
+// Shape is a shape of inline typed context interface used in a package, as
+// listed in the inventory; see Shapes.
+type Shape struct {
+	// Package is the path of the package.
+	Package string
+	// Leaves are the full names of the leaf interfaces of the shape,
+	// sorted, like "example.com/app.LoggerContext".
+	Leaves []string
+	// Types are the leaf interfaces themselves, in the same order.
+	Types []types.Type
+}
+
+// Shapes returns the shapes of the inline typed context interfaces used in
+// the packages matching the given patterns, including their tests, sorted
+// by package and shape, for the inventory checked by
+// TypedContextShapesAnalyzer.  (That's the -recordshapes mode of the linter
+// command.)
+func Shapes(patterns ...string) ([]Shape, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+	return ShapesIn(pkgs), nil
+}
+
+// ShapesIn is like Shapes, for packages the caller has already loaded (with
+// syntax and types), say to generate code using the same types.
+func ShapesIn(pkgs []*packages.Package) []Shape {
+	seen := map[string]bool{}
+	var shapes []Shape
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, shape := range _inlineShapes(file, pkg.TypesInfo) {
+				line := pkg.PkgPath + "\t" + shape.key()
+				if !seen[line] { // e.g. in a package and its test variant
+					seen[line] = true
+					shapes = append(shapes, Shape{pkg.PkgPath, shape.leaves, shape.types})
+				}
+			}
+		}
+	}
+	sort.Slice(shapes, func(i, j int) bool {
+		if shapes[i].Package != shapes[j].Package {
+			return shapes[i].Package < shapes[j].Package
+		}
+		return strings.Join(shapes[i].Leaves, ", ") < strings.Join(shapes[j].Leaves, ", ")
+	})
+	return shapes
+}
+
+// WriteShapeInventory writes the given shapes to w in the format of the
+// inventory file.
+func WriteShapeInventory(w io.Writer, shapes []Shape) error {
+	if _, err := fmt.Fprintln(w, "# Shapes of inline typed context interfaces, by package;"+
+		" see typedcontextshapes."); err != nil {
+		return err
+	}
+	for _, shape := range shapes {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", shape.Package, strings.Join(shape.Leaves, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package linter
+
+// This file defines the linter that composite typed context interfaces don't
+// grow too wide.  A "god context" like
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		DatabaseContext
+//		... a dozen more ...
+//	}
+// satisfies the interface analyzer as long as every function requesting it
+// uses all of it, but such functions do too much, and their signatures no
+// longer say anything useful about what they depend on.  So we report any
+// composite interface, named or inline, which includes more than
+// -typedcontextsize.max distinct leaf interfaces (see
+// analysisengine.LeafInterfaces), not counting context.Context.  The fix is
+// to split up the functions requesting it, or to group related interfaces
+// into intermediate ones with some meaning of their own.
+//
+// An interface which just wraps a single other interface, like
+// `interface{ AppContext }`, isn't reported: we report the interface it
+// wraps, where that's declared.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextSizeAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextsize",
+	Doc:  "reports composite typed context interfaces which include too many leaf interfaces",
+	Run:  _runSize,
+}
+
+// _defaultMaxLeaves is the default for -typedcontextsize.max.
+const _defaultMaxLeaves = 8
+
+// _maxLeaves is the most leaf interfaces a composite interface may include;
+// if it's zero, we don't check.
+var _maxLeaves = _defaultMaxLeaves
+
+func init() {
+	TypedContextSizeAnalyzer.Flags.IntVar(&_maxLeaves, "max", _defaultMaxLeaves,
+		"the most leaf interfaces, other than context.Context, that a "+
+			"composite typed context interface may include (0 for no limit)")
+}
+
+// _distinctLeaves returns the leaf interfaces of typ, other than
+// context.Context, without duplicates (say, from two embeds which both embed
+// LoggerContext).
+func _distinctLeaves(typ types.Type) []types.Type {
+	var leaves []types.Type
+	for _, leaf := range analysisengine.LeafInterfaces(typ) {
+		if isContextRoot(leaf) {
+			continue
+		}
+		duplicate := false
+		for _, other := range leaves {
+			if analysisengine.SameType(leaf, other) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			leaves = append(leaves, leaf)
+		}
+	}
+	return leaves
+}
+
+// _checkSize reports the given interface-type if it's a composite typed
+// context interface with more than maxLeaves leaves.  name is the name it's
+// declared with, or nil if it's inline.
+func _checkSize(pass *analysis.Pass, ifaceType *ast.InterfaceType, name *ast.Ident, maxLeaves int) {
+	typ := pass.TypesInfo.TypeOf(ifaceType)
+	if typ == nil || !isContextType(typ) {
+		return
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok || iface.NumExplicitMethods() > 0 || iface.NumEmbeddeds() < 2 {
+		return // a leaf itself, or a wrapper of a single interface
+	}
+	leaves := _distinctLeaves(typ)
+	if len(leaves) <= maxLeaves {
+		return
+	}
+
+	var node positioner = ifaceType
+	description := "this typed context interface"
+	if name != nil {
+		node = name
+		description = "typed context interface " + name.Name
+	}
+	reportf(pass, node, CodeWideContext,
+		"%s includes %d leaf interfaces, more than the limit of %d; split up "+
+			"the functions requesting it, or group related interfaces into "+
+			"intermediate interfaces",
+		description, len(leaves), maxLeaves)
+}
+
+// _runSize lints that composite typed context interfaces aren't too wide.
+func _runSize(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	if settings.maxLeaves <= 0 {
+		return nil, nil
+	}
+
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.TypeSpec:
+				if ifaceType, ok := node.Type.(*ast.InterfaceType); ok {
+					_checkSize(pass, ifaceType, node.Name, settings.maxLeaves)
+					// Don't visit ifaceType again as an inline interface,
+					// but do visit any inline interfaces within it.
+					ast.Inspect(ifaceType.Methods, func(node ast.Node) bool {
+						if inline, ok := node.(*ast.InterfaceType); ok {
+							_checkSize(pass, inline, nil, settings.maxLeaves)
+						}
+						return true
+					})
+					return false
+				}
+			case *ast.InterfaceType:
+				_checkSize(pass, node, nil, settings.maxLeaves)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,265 @@
+package linter
+
+// This file computes, for each named typed context interface in a program,
+// how many functions request it, how many of those use it, and which
+// packages they're in, to guide which interfaces to split or merge: an
+// interface many functions request but few use is a candidate for
+// splitting, and one few request, for merging into another.
+// (That's cmd/typedcontext-stats.)
+//
+// As with the unused embed check, the functions requesting an interface are
+// mostly in other packages than the one defining it, so the analyzer here
+// exports a fact for each package counting the functions which request, and
+// use, each named interface, and InterfaceStats aggregates the facts over
+// the whole program.
+//
+// A function requests an interface if one of its typed context parameters
+// does, directly or via embeds, and uses it if the parameter uses any of its
+// leaf interfaces (see analysisengine.LeafInterfaces), as decided by the
+// interface analyzer.  Function literals count as functions too.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// TypedContextStatsAnalyzer computes the facts used by InterfaceStats.  It
+// reports nothing itself, so it isn't in Analyzers.
+var TypedContextStatsAnalyzer = &analysis.Analyzer{
+	Name:      "typedcontextstats",
+	Doc:       "counts the functions which request and use each named typed context interface",
+	Run:       _runStats,
+	FactTypes: []analysis.Fact{new(_interfaceStatsFact)},
+}
+
+// _interfaceStatsFact is the package fact exported by
+// TypedContextStatsAnalyzer.  Interfaces are named as "import/path.Name".
+type _interfaceStatsFact struct {
+	// Defined maps the named typed context interfaces defined in the
+	// package, other than context roots, to their positions.
+	Defined map[string]token.Position
+	// Requesters maps the named interfaces the package's functions request
+	// to the positions of those functions.  (We record positions rather
+	// than counts since a package's test variant has the functions of its
+	// other files too.)
+	Requesters map[string][]string
+	// Users maps the same interfaces to the positions of those functions
+	// which use them.
+	Users map[string][]string
+}
+
+func (*_interfaceStatsFact) AFact() {}
+
+func (fact *_interfaceStatsFact) String() string {
+	return fmt.Sprintf("defines %d typed context interfaces, requests %d",
+		len(fact.Defined), len(fact.Requesters))
+}
+
+// _addInterfaceStats records in requested, for typ and each named
+// interface it recursively embeds, other than context roots, whether info
+// uses it.
+func _addInterfaceStats(typ types.Type, info *analysisengine.Usage, requested map[string]bool) {
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok || isContextRoot(typ) {
+		return
+	}
+	if named, ok := typ.(*types.Named); ok {
+		name := _qualifiedName(named)
+		for _, leaf := range analysisengine.LeafInterfaces(typ) {
+			if !isContextRoot(leaf) && info.InterfaceWasUsed(leaf) {
+				requested[name] = true
+				break
+			}
+		}
+		if _, ok := requested[name]; !ok {
+			requested[name] = false
+		}
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		_addInterfaceStats(iface.EmbeddedType(i), info, requested)
+	}
+}
+
+func _runStats(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	fact := &_interfaceStatsFact{
+		Defined:    map[string]token.Position{},
+		Requesters: map[string][]string{},
+		Users:      map[string][]string{},
+	}
+
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || !types.IsInterface(named) || !isContextType(named) || isContextRoot(named) {
+			continue
+		}
+		if _skipFile(pass.Fset.File(obj.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		fact.Defined[_qualifiedName(named)] = pass.Fset.Position(obj.Pos())
+	}
+
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			var funcType *ast.FuncType
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				funcType = node.Type
+			case *ast.FuncLit:
+				funcType = node.Type
+			default:
+				return true
+			}
+			// requested maps the interfaces the function requests to
+			// whether it uses them.
+			requested := map[string]bool{}
+			for _, field := range funcType.Params.List {
+				for _, ident := range field.Names {
+					obj := pass.TypesInfo.Defs[ident]
+					if info := tracker.Usage(obj); info != nil {
+						_addInterfaceStats(obj.Type(), info, requested)
+					}
+				}
+			}
+			position := pass.Fset.Position(funcType.Pos()).String()
+			for name, used := range requested {
+				fact.Requesters[name] = append(fact.Requesters[name], position)
+				if used {
+					fact.Users[name] = append(fact.Users[name], position)
+				}
+			}
+			return true
+		})
+	}
+
+	pass.ExportPackageFact(fact)
+	return nil, nil
+}
+
+// InterfaceStat describes how a named typed context interface is used.
+type InterfaceStat struct {
+	// Interface is the name of the interface, as "import/path.Name".
+	Interface string `json:"interface"`
+	// Position is the position of its declaration, as "file:line:col".
+	Position string `json:"position"`
+	// Requesters is the number of functions which request it.
+	Requesters int `json:"requesters"`
+	// Users is the number of those which use it.
+	Users int `json:"users"`
+	// Packages are the import paths of the packages declaring those
+	// functions, sorted.
+	Packages []string `json:"packages"`
+}
+
+// InterfaceStats returns the stats of the named typed context interfaces
+// defined in the packages matching the given patterns, counting the
+// functions in those packages and their dependencies (including their
+// tests).  They're ranked by how many functions request them, then by how
+// many use them, most first.
+//
+// Like FindUnusedEmbeds, it should be run over the whole program, or the
+// counts will be missing the functions in packages not matched by the
+// patterns.
+func InterfaceStats(patterns ...string) ([]InterfaceStat, error) {
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Tests: true}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading packages")
+	}
+
+	graph, err := checker.Analyze(
+		[]*analysis.Analyzer{TypedContextStatsAnalyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defined := map[string]token.Position{}
+	requesters := map[string]map[string]bool{}
+	users := map[string]map[string]bool{}
+	packagesOf := map[string]map[string]bool{}
+	add := func(sets map[string]map[string]bool, name string, positions []string) {
+		if sets[name] == nil {
+			sets[name] = map[string]bool{}
+		}
+		for _, position := range positions {
+			sets[name][position] = true
+		}
+	}
+	for act := range graph.All() {
+		if act.Err != nil {
+			return nil, act.Err
+		}
+		var fact _interfaceStatsFact
+		if !act.PackageFact(act.Package.Types, &fact) {
+			continue
+		}
+		if act.IsRoot {
+			for name, position := range fact.Defined {
+				defined[name] = position
+			}
+		}
+		for name, positions := range fact.Requesters {
+			add(requesters, name, positions)
+			add(users, name, fact.Users[name])
+			add(packagesOf, name, []string{act.Package.PkgPath})
+		}
+	}
+
+	result := make([]InterfaceStat, 0, len(defined))
+	for name, position := range defined {
+		stat := InterfaceStat{
+			Interface:  name,
+			Position:   position.String(),
+			Requesters: len(requesters[name]),
+			Users:      len(users[name]),
+			Packages:   []string{},
+		}
+		for path := range packagesOf[name] {
+			stat.Packages = append(stat.Packages, path)
+		}
+		sort.Strings(stat.Packages)
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Requesters != result[j].Requesters {
+			return result[i].Requesters > result[j].Requesters
+		}
+		if result[i].Users != result[j].Users {
+			return result[i].Users > result[j].Users
+		}
+		return result[i].Interface < result[j].Interface
+	})
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package linter
+
+// This file defines the check, enabled by -typedcontextinterface.unusedroots,
+// that parameters requesting just context.Context (or another root) are
+// used.  The tracker doesn't track those (there's nothing to narrow), so an
+// unused one, like ctx in
+//	func resize(ctx context.Context, img *Image) *Image
+// was reported by nobody: the regular unused-variable checks don't complain
+// about parameters.  Often that's fine: the parameter is there to match some
+// signature.  So we don't report
+//   - methods which implement an interface having a method of that name, in
+//     this package or any package it (transitively) depends on.  The latter
+//     we learn from facts, exported by _rootMethodsAnalyzer for each
+//     interface with a method taking a root, since the interface may be
+//     declared in a package this one doesn't import directly.
+//   - exported functions, and exported methods of exported types (outside
+//     package main): other packages may call them, so dropping a parameter
+//     is a breaking change, and renaming it to _ changes their docs.
+//   - functions referred to other than by direct calls: they're probably
+//     passed somewhere expecting some signature.
+//   - function literals, which are almost always callbacks.
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+	lintutil "github.com/khan/typed-context/linter/util"
+)
+
+// _rootMethodsAnalyzer computes the facts used by
+// -typedcontextinterface.unusedroots.  It reports nothing itself, so it isn't
+// in Analyzers; TypedContextInterfaceAnalyzer requires it.
+var _rootMethodsAnalyzer = &analysis.Analyzer{
+	Name:       "typedcontextrootmethods",
+	Doc:        "records the interfaces with methods taking a context root",
+	Run:        _runRootMethods,
+	ResultType: reflect.TypeOf(_rootMethods(nil)),
+	FactTypes:  []analysis.Fact{new(_rootMethodsFact)},
+}
+
+// _rootMethodsFact is the object fact exported by _rootMethodsAnalyzer, for
+// named interfaces with methods taking a context root.
+type _rootMethodsFact struct {
+	// Methods are the names of those methods, sorted.
+	Methods []string
+}
+
+func (*_rootMethodsFact) AFact() {}
+
+func (fact *_rootMethodsFact) String() string {
+	return "methods taking a context root: " + strings.Join(fact.Methods, ", ")
+}
+
+// _rootMethods is the result of _rootMethodsAnalyzer: the interfaces, in the
+// package and its dependencies, with methods taking a context root, and the
+// names of those methods.
+type _rootMethods map[*types.TypeName][]string
+
+// _isRootOnly returns true if typ requests just a context root, like
+// context.Context.
+func _isRootOnly(typ types.Type) bool {
+	leaves := analysisengine.LeafInterfaces(typ)
+	return len(leaves) == 1 && isContextRoot(leaves[0])
+}
+
+// _rootMethodNames returns the names of the methods of iface with a
+// parameter requesting just a context root, sorted.
+func _rootMethodNames(iface *types.Interface) []string {
+	var names []string
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		params := method.Type().(*types.Signature).Params()
+		for j := 0; j < params.Len(); j++ {
+			if _isRootOnly(params.At(j).Type()) {
+				names = append(names, method.Name())
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func _runRootMethods(pass *analysis.Pass) (interface{}, error) {
+	result := _rootMethods{}
+	if !_unusedRoots {
+		return result, nil
+	}
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		if methods := _rootMethodNames(iface); len(methods) > 0 {
+			pass.ExportObjectFact(obj, &_rootMethodsFact{Methods: methods})
+		}
+	}
+	for _, objectFact := range pass.AllObjectFacts() {
+		obj, ok := objectFact.Object.(*types.TypeName)
+		if ok {
+			result[obj] = objectFact.Fact.(*_rootMethodsFact).Methods
+		}
+	}
+	return result, nil
+}
+
+// implements returns true if the given method implements a method of one
+// of the interfaces.
+func (interfaces _rootMethods) implements(method *types.Func) bool {
+	recv := method.Type().(*types.Signature).Recv().Type()
+	if named, ok := types.Unalias(recv).(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return true // we can't easily tell; assume it might
+	}
+	for obj, methods := range interfaces {
+		i := sort.SearchStrings(methods, method.Name())
+		if i == len(methods) || methods[i] != method.Name() {
+			continue
+		}
+		iface := obj.Type().Underlying().(*types.Interface)
+		if types.Implements(recv, iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// _isAPI returns true if other packages may call the given function.
+func _isAPI(pkg *types.Package, fn *types.Func) bool {
+	if pkg.Name() == "main" || !fn.Exported() {
+		return false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return true
+	}
+	named, ok := types.Unalias(lintutil.UnwrapMaybePointer(recv.Type())).(*types.Named)
+	return !ok || named.Obj().Exported()
+}
+
+// _reportUnusedRoots reports the unused parameters requesting just a context
+// root (see the top of this file).
+func _reportUnusedRoots(pass *analysis.Pass, interfaces _rootMethods) {
+	used := map[types.Object]bool{}
+	for _, obj := range pass.TypesInfo.Uses {
+		used[obj] = true
+	}
+	// asValue are the functions referred to other than by direct calls.
+	asValue := _funcValues(pass.TypesInfo, pass.Files)
+
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+			fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+			if !ok || asValue[fn] || _isAPI(pass.Pkg, fn) ||
+				funcDecl.Recv != nil && interfaces.implements(fn) {
+				continue
+			}
+			for _, field := range funcDecl.Type.Params.List {
+				for _, name := range field.Names {
+					obj := pass.TypesInfo.Defs[name]
+					if obj == nil || obj.Name() == "_" || used[obj] || !_isRootOnly(obj.Type()) {
+						continue
+					}
+					reportf(pass, name, CodeUnusedContext,
+						"%s is never used; remove it, or rename it to _ if it's "+
+							"needed to match some signature", name.Name)
+				}
+			}
+		}
+	}
+}
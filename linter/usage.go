@@ -0,0 +1,95 @@
+package linter
+
+// This file defines TypedContextUsageAnalyzer, which computes how each typed
+// context in a package is used, for TypedContextInterfaceAnalyzer to report
+// on, and for other analyzers to build on.  For example, a team which wants
+// only code under pkg/secure to use SecretsContext could write
+//	var SecureAnalyzer = &analysis.Analyzer{
+//		Name:     "secure",
+//		Doc:      "reports uses of SecretsContext outside pkg/secure",
+//		Requires: []*analysis.Analyzer{contextLinter.TypedContextUsageAnalyzer},
+//		Run: func(pass *analysis.Pass) (any, error) {
+//			usage := pass.ResultOf[contextLinter.TypedContextUsageAnalyzer].(*contextLinter.InterfaceUsage)
+//			for _, obj := range usage.Objects {
+//				for _, typ := range usage.Usages[obj].Requirements() {
+//					// ... report typ if it's SecretsContext ...
+//				}
+//			}
+//			return nil, nil
+//		},
+//	}
+// without copying (or forking) the tracker.  The usage is computed with the
+// settings of the package (see config.go), including the
+// -typedcontextinterface flags.
+
+import (
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+// TypedContextUsageAnalyzer computes the InterfaceUsage of each package.  It
+// reports nothing itself, so it isn't in Analyzers.
+var TypedContextUsageAnalyzer = &analysis.Analyzer{
+	Name:       "typedcontextusage",
+	Doc:        "computes which interfaces and methods of each typed context are used",
+	Run:        _runUsage,
+	ResultType: reflect.TypeOf((*InterfaceUsage)(nil)),
+}
+
+// InterfaceUsage is the result of TypedContextUsageAnalyzer: how each typed
+// context variable (parameter, local variable, or tracked struct field) in
+// the package is used.  It must not be modified.
+type InterfaceUsage struct {
+	// Objects are the variables, in order of position.
+	Objects []types.Object
+	// Usages maps each variable to its uses.  Aliases share the Usage of
+	// the variable they alias.
+	Usages map[types.Object]*analysisengine.Usage
+	// Aliases are the variables which share the Usage of another (see
+	// analysisengine.Tracker.IsAlias); analyzers reporting problems should
+	// report them on the other.
+	Aliases map[types.Object]bool
+	// Captures are the variables stored by closures in fields which aren't
+	// typed contexts, which count as uses of all their interfaces (see
+	// analysisengine.Capture), in order of position.
+	Captures []analysisengine.Capture
+}
+
+// _runUsage computes the InterfaceUsage of the package.
+func _runUsage(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// First, find the identifiers we want to look at; second, see where
+	// they're used.
+	tracker := analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+	tracker.Track(pass.Files)
+	for _, file := range pass.Files {
+		tracker.MarkUses(file)
+	}
+
+	usage := &InterfaceUsage{
+		Objects:  tracker.Objects(),
+		Usages:   map[types.Object]*analysisengine.Usage{},
+		Aliases:  map[types.Object]bool{},
+		Captures: tracker.Captures(),
+	}
+	for _, obj := range usage.Objects {
+		usage.Usages[obj] = tracker.Usage(obj)
+		if tracker.IsAlias(obj) {
+			usage.Aliases[obj] = true
+		}
+	}
+	_markConfigurationUses(pass.Fset, usage)
+	return usage, nil
+}
@@ -0,0 +1,176 @@
+package lintutil
+
+// This file defines a simple intra-function effect analysis: what a
+// function's body does, directly, beyond computing a value.  It's syntactic,
+// and doesn't follow calls: a call to a helper which does I/O isn't I/O,
+// unless the helper is in one of the I/O packages.
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// EffectKind is a kind of side effect.
+type EffectKind string
+
+// The kinds of side effect Effects finds.
+const (
+	// EffectIO is a call to a function or method of an I/O package, like
+	// os.Open or (*sql.DB).Query.
+	EffectIO EffectKind = "I/O"
+	// EffectLock is taking a lock, like (*sync.Mutex).Lock, or otherwise
+	// waiting on another goroutine: waiting on a sync.WaitGroup or
+	// sync.Cond, or sending or receiving on a channel.  (sync.Once.Do isn't
+	// counted: it only waits the first time.)
+	EffectLock EffectKind = "lock"
+	// EffectMutation is an assignment (or ++, --, delete or clear) to
+	// something which outlives the call: a package variable, or anything
+	// reached through a pointer, map or slice that wasn't created in the
+	// function itself.
+	EffectMutation EffectKind = "mutation"
+)
+
+// Effect is a side effect found by Effects.
+type Effect struct {
+	Kind EffectKind
+	// Node is the statement or expression with the effect.
+	Node ast.Node
+	// Description says what it does, like "calls os.Open".
+	Description string
+}
+
+// _lockMethods are the methods, by lintutil.NameOf, which take locks or wait
+// on other goroutines.
+var _lockMethods = map[string]bool{
+	"(*sync.Mutex).Lock":       true,
+	"(*sync.Mutex).TryLock":    true,
+	"(*sync.RWMutex).Lock":     true,
+	"(*sync.RWMutex).RLock":    true,
+	"(*sync.RWMutex).TryLock":  true,
+	"(*sync.RWMutex).TryRLock": true,
+	"(*sync.WaitGroup).Wait":   true,
+	"(*sync.Cond).Wait":        true,
+}
+
+// Effects returns the side effects of the given function body, in order of
+// position.  Calls to functions and methods in the packages whose paths are
+// or start with one of ioPackages (like "os", which covers "os/exec") are
+// I/O.
+//
+// Function literals in the body aren't included: they don't run when the
+// function does (or if they do, as with sync.Once.Do, that's up to the
+// function they're passed to).
+func Effects(body *ast.BlockStmt, typesInfo *types.Info, ioPackages []string) []Effect {
+	var effects []Effect
+	add := func(kind EffectKind, node ast.Node, description string) {
+		effects = append(effects, Effect{kind, node, description})
+	}
+	mutation := func(target ast.Expr) {
+		if _mutates(target, body, typesInfo) {
+			add(EffectMutation, target, "modifies "+types.ExprString(target))
+		}
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			callee := ObjectFor(node.Fun, typesInfo)
+			switch callee := callee.(type) {
+			case *types.Builtin:
+				if (callee.Name() == "delete" || callee.Name() == "clear") && len(node.Args) > 0 {
+					mutation(node.Args[0])
+				}
+			case *types.Func:
+				name := NameOf(callee)
+				switch {
+				case _lockMethods[name]:
+					add(EffectLock, node, "calls "+name)
+				case callee.Pkg() != nil && _hasAnyPathPrefix(callee.Pkg().Path(), ioPackages):
+					add(EffectIO, node, "calls "+name)
+				}
+			}
+		case *ast.SendStmt:
+			add(EffectLock, node, "sends on a channel")
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW {
+				add(EffectLock, node, "receives from a channel")
+			}
+		case *ast.SelectStmt:
+			add(EffectLock, node, "waits in a select")
+			return false // don't also report each case
+		case *ast.RangeStmt:
+			if typ := typesInfo.TypeOf(node.X); typ != nil {
+				if _, ok := typ.Underlying().(*types.Chan); ok {
+					add(EffectLock, node, "receives from a channel")
+				}
+			}
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				for _, lhs := range node.Lhs {
+					mutation(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			mutation(node.X)
+		}
+		return true
+	})
+	return effects
+}
+
+// _mutates returns true if assigning to target, in the given function body,
+// modifies something which outlives the call.
+func _mutates(target ast.Expr, body *ast.BlockStmt, typesInfo *types.Info) bool {
+	switch target := target.(type) {
+	case *ast.ParenExpr:
+		return _mutates(target.X, body, typesInfo)
+	case *ast.Ident:
+		// A package variable; params, results and locals are the call's
+		// own.
+		v, ok := typesInfo.ObjectOf(target).(*types.Var)
+		return ok && v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+	case *ast.StarExpr:
+		return true
+	case *ast.SelectorExpr:
+		selection := typesInfo.Selections[target]
+		if selection == nil {
+			return true // a qualified identifier: another package's variable
+		}
+		if selection.Indirect() {
+			return true // through a pointer
+		}
+		return _mutates(target.X, body, typesInfo)
+	case *ast.IndexExpr:
+		typ := typesInfo.TypeOf(target.X)
+		if typ == nil {
+			return false
+		}
+		if _, ok := typ.Underlying().(*types.Array); ok {
+			return _mutates(target.X, body, typesInfo)
+		}
+		// A map, a slice, or a pointer to an array: shared, unless it's
+		// a local variable made in the function.
+		root, ok := ast.Unparen(target.X).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj := typesInfo.ObjectOf(root)
+		return obj == nil || obj.Pos() < body.Pos() || obj.Pos() >= body.End()
+	}
+	return false
+}
+
+// _hasAnyPathPrefix returns true if path is equal to, or is a subpackage of,
+// any of the given prefixes.
+func _hasAnyPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package lintutil
+
+// This file defines an index from positions to the functions containing
+// them, for rules which need to know what function some node is in.
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// FuncIndex maps positions to the functions (declarations and literals)
+// containing them.  Build one with EnclosingFuncs, or get one for the whole
+// package from EnclosingFuncsAnalyzer.
+type FuncIndex struct {
+	// funcs are the *ast.FuncDecls and *ast.FuncLits, in order of position,
+	// so each function comes after those containing it.
+	funcs []ast.Node
+	// parents holds, for each function in funcs, the index of the function
+	// immediately containing it, or -1.
+	parents []int
+}
+
+// EnclosingFuncs indexes the functions in the given files.
+func EnclosingFuncs(files ...*ast.File) *FuncIndex {
+	sorted := append([]*ast.File(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Pos() < sorted[j].Pos() })
+
+	index := &FuncIndex{}
+	for _, file := range sorted {
+		var nodes []ast.Node // the path from the root to the current node
+		funcStack := []int{-1}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if node == nil {
+				switch nodes[len(nodes)-1].(type) {
+				case *ast.FuncDecl, *ast.FuncLit:
+					funcStack = funcStack[:len(funcStack)-1]
+				}
+				nodes = nodes[:len(nodes)-1]
+				return true
+			}
+			nodes = append(nodes, node)
+
+			switch node.(type) {
+			case *ast.FuncDecl, *ast.FuncLit:
+				index.funcs = append(index.funcs, node)
+				index.parents = append(index.parents, funcStack[len(funcStack)-1])
+				funcStack = append(funcStack, len(index.funcs)-1)
+			}
+			return true
+		})
+	}
+	return index
+}
+
+// innermost returns the index in index.funcs of the innermost function
+// containing pos, or -1.
+func (index *FuncIndex) innermost(pos token.Pos) int {
+	// Start from the last function starting at or before pos; if it doesn't
+	// contain pos, whichever does must contain it too.
+	i := sort.Search(len(index.funcs), func(i int) bool { return index.funcs[i].Pos() > pos }) - 1
+	for i >= 0 && index.funcs[i].End() <= pos {
+		i = index.parents[i]
+	}
+	return i
+}
+
+// Innermost returns the innermost *ast.FuncDecl or *ast.FuncLit containing
+// pos, or nil if there is none.
+func (index *FuncIndex) Innermost(pos token.Pos) ast.Node {
+	if i := index.innermost(pos); i >= 0 {
+		return index.funcs[i]
+	}
+	return nil
+}
+
+// Enclosing returns all the *ast.FuncDecls and *ast.FuncLits containing pos,
+// innermost first.
+func (index *FuncIndex) Enclosing(pos token.Pos) []ast.Node {
+	var enclosing []ast.Node
+	for i := index.innermost(pos); i >= 0; i = index.parents[i] {
+		enclosing = append(enclosing, index.funcs[i])
+	}
+	return enclosing
+}
+
+// EnclosingFuncsAnalyzer computes a FuncIndex of the files of each package,
+// for other analyzers to require, so that they share one index rather than
+// each building their own.
+var EnclosingFuncsAnalyzer = &analysis.Analyzer{
+	Name:       "enclosingfuncs",
+	Doc:        "indexes the functions enclosing each position, for other analyzers",
+	Run:        func(pass *analysis.Pass) (interface{}, error) { return EnclosingFuncs(pass.Files...), nil },
+	ResultType: reflect.TypeOf((*FuncIndex)(nil)),
+}
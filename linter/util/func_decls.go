@@ -42,32 +42,118 @@ func ReceiversByType(files []*ast.File, typesInfo *types.Info) map[types.Type][]
 // receiver) calls its "super" -- that is,
 // <receiver-var>.<superclass-name>.<receiver-name>().
 //
-// This doesn't validate what the superclass name is;
-// in the general case it's not clear what the correct rule would be as it's
-// not necessarily the name of any field of the receiver-type (it may be a
-// field of an intermediate embedded type).  We just validate that you call
-// some method of a field of your receiver, which has the same name; in
-// practice you probably only have one option.
+// See SuperCall for exactly what counts as a super-call.
 func CallsSuper(funcDecl *ast.FuncDecl, typesInfo *types.Info) bool {
-	foundSuper := false
+	return SuperCall(funcDecl, typesInfo) != nil
+}
+
+// SuperCall returns the path of embedded fields through which the given
+// function body (which must be a receiver) calls its "super", or nil if it
+// doesn't.  The last field of the path is the "superclass" being called.
+//
+// For example, given
+//	type Base struct{}
+//	func (Base) M() {}
+//	type Middle struct{ Base }
+//	type T struct{ Middle }
+// then in
+//	func (t T) M() { t.Middle.M() }
+// the path is [Middle, Base]: t.Middle.M is Base.M, promoted through Middle.
+// Likewise for t.Middle.Base.M(), and t.Base.M(), with Base promoted through
+// Middle.  But t.Other.M(), where Other isn't an embedded field, doesn't
+// count: it's a call to some other object, not to a superclass.
+//
+// If there are several super-calls, the path for the first one is returned.
+func SuperCall(funcDecl *ast.FuncDecl, typesInfo *types.Info) []*types.Var {
+	var path []*types.Var
 	receiver := typesInfo.Defs[funcDecl.Recv.List[0].Names[0]]
 	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		if path != nil {
+			return false // already found one
+		}
 		expr, ok := node.(*ast.SelectorExpr)
 		if !ok || expr.Sel.Name != funcDecl.Name.Name {
 			return true // recurse
 		}
-		subExpr, ok := expr.X.(*ast.SelectorExpr)
+		path = _superPath(expr, receiver, typesInfo)
+		return path == nil // no need to recurse if we found it
+	})
+	return path
+}
+
+// _superPath returns the path of embedded fields from the given receiver
+// through which the given method-selector selects the method, if expr is of
+// the form <receiver-var>.<field>...<field>.<method> and each field,
+// including those implicitly traversed to find the method, is embedded.
+// Otherwise, it returns nil.
+func _superPath(expr *ast.SelectorExpr, receiver types.Object, typesInfo *types.Info) []*types.Var {
+	methodSel, ok := typesInfo.Selections[expr]
+	if !ok || methodSel.Kind() != types.MethodVal {
+		return nil
+	}
+
+	// Walk the explicit fields, from the method back to the receiver.
+	var explicit []*types.Var
+	x := expr.X
+	for {
+		if paren, ok := x.(*ast.ParenExpr); ok {
+			x = paren.X
+			continue
+		}
+		if ident, ok := x.(*ast.Ident); ok {
+			if typesInfo.Uses[ident] != receiver {
+				return nil
+			}
+			break
+		}
+		fieldExpr, ok := x.(*ast.SelectorExpr)
 		if !ok {
-			return true
+			return nil
 		}
-		ident, ok := subExpr.X.(*ast.Ident)
-		if !ok || typesInfo.Uses[ident] != receiver {
-			return true
+		fieldSel, ok := typesInfo.Selections[fieldExpr]
+		if !ok || fieldSel.Kind() != types.FieldVal {
+			return nil
 		}
-		foundSuper = true
-		return false // no need to recurse here
-	})
-	return foundSuper
+		// The field may itself be promoted, like t.Base where Base is
+		// embedded in an embedded Middle; then each field along the way
+		// must be embedded too.
+		fields := _embeddedFieldPath(typesInfo.TypeOf(fieldExpr.X), fieldSel.Index())
+		if fields == nil {
+			return nil
+		}
+		explicit = append(fields, explicit...)
+		x = fieldExpr.X
+	}
+	if len(explicit) == 0 {
+		return nil // a call to the receiver's own method, not its super
+	}
+
+	// Then add any fields implicitly traversed to find the method.
+	index := methodSel.Index()
+	implicit := _embeddedFieldPath(explicit[len(explicit)-1].Type(), index[:len(index)-1])
+	if implicit == nil && len(index) > 1 { // should never happen
+		return nil
+	}
+	return append(explicit, implicit...)
+}
+
+// _embeddedFieldPath returns the fields which the given selection index
+// path traverses from typ, or nil if any of them isn't embedded.
+func _embeddedFieldPath(typ types.Type, index []int) []*types.Var {
+	var path []*types.Var
+	for _, i := range index {
+		st, ok := UnwrapMaybePointer(typ).Underlying().(*types.Struct)
+		if !ok || i >= st.NumFields() {
+			return nil
+		}
+		field := st.Field(i)
+		if !field.Embedded() {
+			return nil
+		}
+		path = append(path, field)
+		typ = field.Type()
+	}
+	return path
 }
 
 // Says whether the given function is a graphql resolver.  A
@@ -80,6 +166,10 @@ func CallsSuper(funcDecl *ast.FuncDecl, typesInfo *types.Info) bool {
 // 2) is exported
 // 3a) either has a `context.Context` as the first argument (for resolvers)
 // 3b) or returns an object whose name ends with Resolver (for federation)
+//
+// Resolvers rewritten by gqlgenplugin take a typed context rather than a
+// context.Context, so they don't match (and are linted like any other
+// function); only the generated shims which narrow their context do.
 func IsResolverFunc(funcDecl *ast.FuncDecl, typesInfo *types.Info) bool {
 	if funcDecl.Recv == nil {
 		return false
@@ -83,9 +83,11 @@ func NameOf(obj types.Object) string {
 // Returns false if pkgPath.name is not a type, or if it is not this type.
 // Note that this includes cases where this type wraps pkgPath.name, or where
 // they share an underlying type: this will only return true if the types are
-// the same.  Predeclared types will match the empty path.
+// the same.  Predeclared types will match the empty path.  Aliases match the
+// type they stand for, and instantiations of a generic type, like
+// pkg.Foo[Bar], match it (see also TypeIsInstantiationOf).
 func TypeIs(typ types.Type, pkgPath string, name string) bool {
-	named, ok := typ.(*types.Named)
+	named, ok := types.Unalias(typ).(*types.Named)
 	if !ok {
 		return false
 	}
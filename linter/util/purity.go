@@ -0,0 +1,291 @@
+package lintutil
+
+// This file defines a classifier of calls by what they may do: compute a
+// value, wait on another goroutine, or do I/O.  Unlike Effects, it follows
+// calls, to functions of the package and (via facts) of its dependencies,
+// and knows about the parts of the standard library listed below.
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Purity is what a call may do, as classified by PurityAnalyzer.
+type Purity string
+
+// The purities, in increasing order: a function calling functions of
+// several purities has the greatest of them.
+const (
+	// PurityPure means the call just computes a value: it doesn't modify
+	// anything which outlives it, wait on another goroutine, or do I/O.
+	PurityPure Purity = "pure"
+	// PurityUnknown means we can't tell: the call is dynamic (a call of an
+	// interface method or function value), or to a function we know
+	// nothing about, or which modifies something which outlives it.
+	PurityUnknown Purity = "unknown"
+	// PurityBlocking means the call may wait on another goroutine: take a
+	// lock, sleep, or send or receive on a channel (see EffectLock).
+	PurityBlocking Purity = "blocking"
+	// PurityIO means the call may do I/O.
+	PurityIO Purity = "I/O"
+)
+
+// _purityRank orders the purities; see Join.
+var _purityRank = map[Purity]int{
+	PurityPure:     0,
+	PurityUnknown:  1,
+	PurityBlocking: 2,
+	PurityIO:       3,
+}
+
+// Join returns the greater of purity and other: the purity of doing both.
+// A call known to do I/O does I/O whatever else it calls, so the definite
+// purities outrank PurityUnknown.
+func (purity Purity) Join(other Purity) Purity {
+	if _purityRank[other] > _purityRank[purity] {
+		return other
+	}
+	return purity
+}
+
+// PurityIOPackages are the package-path prefixes whose functions and methods
+// do I/O, as for Effects.
+var PurityIOPackages = []string{"bufio", "database/sql", "io", "log", "net", "os", "syscall"}
+
+// PurityPurePackages are the package paths whose functions (but not methods,
+// which may modify their receivers) are pure.
+var PurityPurePackages = []string{
+	"errors", "math", "math/bits", "path", "strconv", "strings",
+	"unicode", "unicode/utf16", "unicode/utf8",
+}
+
+// PuritySeeds are the purities of other functions and methods of the
+// standard library, by lintutil.NameOf.  Those of PurityIOPackages and
+// PurityPurePackages needn't be listed, nor the methods which take locks
+// (see EffectLock).
+var PuritySeeds = map[string]Purity{
+	"context.Background":              PurityPure,
+	"context.TODO":                    PurityPure,
+	"context.WithValue":               PurityPure,
+	"(context.Context).Deadline":      PurityPure,
+	"(context.Context).Done":          PurityPure,
+	"(context.Context).Err":           PurityPure,
+	"(context.Context).Value":         PurityPure,
+	"fmt.Errorf":                      PurityPure,
+	"fmt.Sprint":                      PurityPure,
+	"fmt.Sprintf":                     PurityPure,
+	"fmt.Sprintln":                    PurityPure,
+	"fmt.Fprint":                      PurityIO,
+	"fmt.Fprintf":                     PurityIO,
+	"fmt.Fprintln":                    PurityIO,
+	"fmt.Print":                       PurityIO,
+	"fmt.Printf":                      PurityIO,
+	"fmt.Println":                     PurityIO,
+	"time.Sleep":                      PurityBlocking,
+	"time.After":                      PurityPure,
+	"time.Since":                      PurityPure,
+	"time.Now":                        PurityPure,
+	"(*sync.Once).Do":                 PurityUnknown, // only waits the first time
+	"(*sync.Mutex).Unlock":            PurityPure,
+	"(*sync.RWMutex).Unlock":          PurityPure,
+	"(*sync.RWMutex).RUnlock":         PurityPure,
+	"(*sync.WaitGroup).Add":           PurityPure,
+	"(*sync.WaitGroup).Done":          PurityPure,
+	"(*sync/atomic.Int64).Load":       PurityPure,
+	"(*sync/atomic.Value).Load":       PurityPure,
+	"(*strings.Builder).String":       PurityPure,
+	"(*bytes.Buffer).String":          PurityPure,
+	"(*encoding/json.Decoder).Decode": PurityIO,
+	"(*encoding/json.Encoder).Encode": PurityIO,
+}
+
+// _pureBuiltins are the builtins which are pure; the others (like delete,
+// clear and copy, which modify their arguments, and print) aren't.
+var _pureBuiltins = map[string]bool{
+	"append": true, "cap": true, "complex": true, "imag": true,
+	"len": true, "make": true, "max": true, "min": true, "new": true,
+	"panic": true, "real": true, "recover": true,
+}
+
+// PurityFact is the purity of a function declared in the package; see
+// PurityAnalyzer.
+type PurityFact struct {
+	Purity Purity
+}
+
+func (*PurityFact) AFact() {}
+
+func (fact *PurityFact) String() string { return string(fact.Purity) }
+
+// Purities classifies calls, for analyzers which require PurityAnalyzer
+// (whose result it is).
+type Purities struct {
+	typesInfo *types.Info
+	// funcs are the purities of the functions declared in the package.
+	funcs map[*types.Func]Purity
+	// importFact imports a PurityFact of a function of another package.
+	importFact func(obj types.Object, fact analysis.Fact) bool
+}
+
+// Func returns the purity of calling the given function or method
+// statically.  (Calling an interface method is dynamic, and so unknown,
+// except for the methods of context.Context.)
+func (purities *Purities) Func(fn *types.Func) Purity {
+	fn = fn.Origin()
+	name := NameOf(fn)
+	if purity, ok := PuritySeeds[name]; ok {
+		return purity
+	}
+	if _lockMethods[name] {
+		return PurityBlocking
+	}
+	if purity, ok := purities.funcs[fn]; ok {
+		return purity
+	}
+
+	pkg := fn.Pkg()
+	if pkg == nil { // e.g. error.Error
+		return PurityUnknown
+	}
+	if _hasAnyPathPrefix(pkg.Path(), PurityIOPackages) {
+		return PurityIO
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		for _, path := range PurityPurePackages {
+			if pkg.Path() == path {
+				return PurityPure
+			}
+		}
+	}
+	if sig.Recv() != nil && types.IsInterface(sig.Recv().Type()) {
+		return PurityUnknown
+	}
+	var fact PurityFact
+	if purities.importFact != nil && purities.importFact(fn, &fact) {
+		return fact.Purity
+	}
+	return PurityUnknown
+}
+
+// Call returns the purity of the given call, not counting the evaluation of
+// its arguments.  Conversions are pure; calls of function-literals have the
+// purity of their bodies.
+func (purities *Purities) Call(call *ast.CallExpr) Purity {
+	if tv, ok := purities.typesInfo.Types[call.Fun]; ok && tv.IsType() {
+		return PurityPure
+	}
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		return purities.Body(lit.Body)
+	}
+	switch callee := ObjectFor(call.Fun, purities.typesInfo).(type) {
+	case *types.Builtin:
+		if _pureBuiltins[callee.Name()] {
+			return PurityPure
+		}
+		if callee.Name() == "print" || callee.Name() == "println" {
+			return PurityIO
+		}
+		return PurityUnknown
+	case *types.Func:
+		return purities.Func(callee)
+	}
+	return PurityUnknown
+}
+
+// Body returns the purity of running the given function body: the greatest
+// of those of its calls and its side effects (see Effects), where modifying
+// something which outlives the call is unknown.  Function-literals in the
+// body count only if they're called there, and goroutines it starts not at
+// all.
+func (purities *Purities) Body(body *ast.BlockStmt) Purity {
+	if body == nil { // declared in assembly, say
+		return PurityUnknown
+	}
+	purity := PurityPure
+	for _, effect := range Effects(body, purities.typesInfo, PurityIOPackages) {
+		switch effect.Kind {
+		case EffectIO:
+			purity = purity.Join(PurityIO)
+		case EffectLock:
+			purity = purity.Join(PurityBlocking)
+		default:
+			purity = purity.Join(PurityUnknown)
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.GoStmt:
+			for _, arg := range node.Call.Args {
+				ast.Inspect(arg, func(node ast.Node) bool {
+					if call, ok := node.(*ast.CallExpr); ok {
+						purity = purity.Join(purities.Call(call))
+					}
+					_, isLit := node.(*ast.FuncLit)
+					return !isLit
+				})
+			}
+			return false
+		case *ast.CallExpr:
+			purity = purity.Join(purities.Call(node))
+		}
+		return true
+	})
+	return purity
+}
+
+// _runPurity classifies the functions declared in the package, exporting a
+// PurityFact for each.  Since they may call each other, recursively, it
+// starts them all pure, and raises them until nothing changes.
+func _runPurity(pass *analysis.Pass) (interface{}, error) {
+	purities := &Purities{
+		typesInfo:  pass.TypesInfo,
+		funcs:      map[*types.Func]Purity{},
+		importFact: pass.ImportObjectFact,
+	}
+	var decls []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func); ok {
+				purities.funcs[fn] = PurityPure
+				decls = append(decls, funcDecl)
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, funcDecl := range decls {
+			fn := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+			if purity := purities.Body(funcDecl.Body); purity != purities.funcs[fn] {
+				purities.funcs[fn] = purity
+				changed = true
+			}
+		}
+	}
+
+	for fn, purity := range purities.funcs {
+		pass.ExportObjectFact(fn, &PurityFact{purity})
+	}
+	return purities, nil
+}
+
+// PurityAnalyzer classifies the functions of each package by purity, and
+// exports a PurityFact for each, so that it can classify calls to them from
+// other packages.  Analyzers which require it get a *Purities.
+var PurityAnalyzer = &analysis.Analyzer{
+	Name:       "purity",
+	Doc:        "classifies functions as pure, blocking, I/O, or unknown, for other analyzers",
+	Run:        _runPurity,
+	FactTypes:  []analysis.Fact{new(PurityFact)},
+	ResultType: reflect.TypeOf((*Purities)(nil)),
+}
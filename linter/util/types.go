@@ -14,3 +14,32 @@ func UnwrapMaybePointer(typ types.Type) types.Type {
 		typ = pointer.Elem()
 	}
 }
+
+// TypeIsMaybePointer returns true if typ is the given named type, as with
+// TypeIs, or a pointer to it (or a pointer to a pointer to it, etc.).
+func TypeIsMaybePointer(typ types.Type, pkgPath string, name string) bool {
+	for {
+		pointer, ok := types.Unalias(typ).(*types.Pointer)
+		if !ok {
+			return TypeIs(typ, pkgPath, name)
+		}
+		typ = pointer.Elem()
+	}
+}
+
+// TypeIsInstantiationOf returns the type arguments of typ, if it's an
+// instantiation of the given generic type: for pkg.Foo[Bar], it returns
+// [Bar] if asked about pkg.Foo.  The generic type itself, un-instantiated
+// (as it appears in its own methods' receivers), isn't an instantiation of
+// it.  The names are as with TypeIs.
+func TypeIsInstantiationOf(typ types.Type, pkgPath string, name string) ([]types.Type, bool) {
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.TypeArgs().Len() == 0 || !TypeIs(named, pkgPath, name) {
+		return nil, false
+	}
+	args := make([]types.Type, named.TypeArgs().Len())
+	for i := range args {
+		args[i] = named.TypeArgs().At(i)
+	}
+	return args, true
+}
@@ -0,0 +1,88 @@
+package linter
+
+// This file defines the linter that code doesn't call Value on a typed
+// context, like
+//	func F(ctx interface{ context.Context; LoggerContext }) {
+//		userID := ctx.Value(userIDKey).(string)
+//	}
+// Value is an untyped, undeclared dependency: F needs a user ID, but nothing
+// in its signature says so, and nothing checks that its callers provide one.
+// That's exactly what typed contexts are meant to replace; add an accessor
+// (UserIDContext, say) instead.
+//
+// This is separate from the interface linter's reports: Value, like the other
+// methods of context.Context, is always available on a typed context, so
+// using it is never an "unrequested" use (see analysisengine.Usage), but
+// it's a problem in its own right.  Calls on a plain context.Context are left
+// alone; that's ordinary Go.
+//
+// Some packages do need Value: typically in-house tracing or logging
+// libraries, which find their spans and loggers under their own keys, even
+// in a typed context.  Calls in the packages listed in
+// -typedcontextvalue.allowpkgs (by default our own typedcontext packages)
+// aren't reported.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var TypedContextValueAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextvalue",
+	Doc:  "reports calls to Value on typed contexts",
+	Run:  _runValue,
+}
+
+// _valueAllowedPackages lists package-path prefixes in which we allow
+// calling Value on typed contexts.
+var _valueAllowedPackages = stringList{"github.com/khan/typed-context/typedcontext"}
+
+func init() {
+	TypedContextValueAnalyzer.Flags.Var(&_valueAllowedPackages, "allowpkgs",
+		"comma-separated list of package-path prefixes (e.g. tracing libraries) "+
+			"in which calling Value on typed contexts is allowed")
+}
+
+// _isContextValueCall returns true if the given call is a call to the Value
+// method of context.Context (or another context root) on a typed context.
+func _isContextValueCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "Value" {
+		return false
+	}
+	selection := pass.TypesInfo.Selections[selector]
+	if selection == nil || selection.Kind() != types.MethodVal {
+		return false
+	}
+	sig, ok := selection.Obj().Type().(*types.Signature)
+	return ok && sig.Recv() != nil && isContextRoot(sig.Recv().Type()) &&
+		_isContextExpr(pass, selector.X)
+}
+
+// _runValue lints that code doesn't use Value on typed contexts.
+func _runValue(pass *analysis.Pass) (interface{}, error) {
+	if _, err := loadSettings(pass); err != nil {
+		return nil, err
+	}
+	if hasAnyPathPrefix(pass.Pkg.Path(), _valueAllowedPackages) {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if ok && _isContextValueCall(pass, call) {
+				reportf(pass, call, CodeContextValue,
+					"%s.Value bypasses the typed context; add an accessor "+
+						"for this value and request it instead",
+					types.ExprString(call.Fun.(*ast.SelectorExpr).X))
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
@@ -0,0 +1,201 @@
+package linter
+
+// This file defines the linter that decorators don't widen the context of
+// the functions they wrap more than they need to.  In
+//	func logged(f func(ctx DBContext) error) func(ctx AppContext) error {
+//		return func(ctx AppContext) error {
+//			ctx.Logger().Log("calling f")
+//			return f(ctx)
+//		}
+//	}
+// the returned function needs DBContext, for f, and LoggerContext, for
+// itself; if AppContext includes anything else, every caller of the wrapped
+// function has to provide it for nothing.  The interface linter sees the
+// function literal's ctx, but can't say where to fix it: the literal's type
+// is dictated by logged's result type.  So we report that.
+//
+// Specifically, we look at functions which return a function literal whose
+// context parameter it forwards (at the same position) to one of the
+// function's own function-typed parameters.  Each leaf interface of the
+// literal's context type (see analysisengine.LeafInterfaces) must either be
+// provided by the wrapped function's context type, or be used by the literal
+// itself; we report the rest at the result type.
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/khan/typed-context/linter/analysisengine"
+)
+
+var TypedContextWrapperAnalyzer = &analysis.Analyzer{
+	Name: "typedcontextwrapper",
+	Doc:  "reports decorators whose result widens the context of the function they wrap more than needed",
+	Run:  _runWrapper,
+}
+
+// _flatFields returns the i'th entry, counting each name separately, of the
+// given field list, or nil if there is none.
+func _flatFields(fields *ast.FieldList, i int) *ast.Field {
+	if fields == nil {
+		return nil
+	}
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		if i < n {
+			return field
+		}
+		i -= n
+	}
+	return nil
+}
+
+// _firstContextParam returns the index and object of the first
+// context-typed parameter of the given function literal, if any.
+func _firstContextParam(pass *analysis.Pass, lit *ast.FuncLit) (int, *types.Var) {
+	i := 0
+	for _, field := range lit.Type.Params.List {
+		if len(field.Names) == 0 {
+			i++
+			continue
+		}
+		for _, name := range field.Names {
+			if param, ok := pass.TypesInfo.Defs[name].(*types.Var); ok && isContextType(param.Type()) {
+				return i, param
+			}
+			i++
+		}
+	}
+	return -1, nil
+}
+
+// _wrappedContextType returns the type of the context parameter of the
+// function, among params, to which the given literal forwards its context
+// parameter, ctx, at index i; or nil if it doesn't.
+func _wrappedContextType(pass *analysis.Pass, lit *ast.FuncLit, params *types.Tuple, ctx *types.Var, i int) types.Type {
+	isParam := map[types.Object]bool{}
+	for j := 0; j < params.Len(); j++ {
+		isParam[params.At(j)] = true
+	}
+	var wrapped types.Type
+	ast.Inspect(lit.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || wrapped != nil || len(call.Args) <= i {
+			return wrapped == nil
+		}
+		fun, ok := call.Fun.(*ast.Ident)
+		if !ok || !isParam[pass.TypesInfo.Uses[fun]] {
+			return true
+		}
+		arg, ok := call.Args[i].(*ast.Ident)
+		if !ok || pass.TypesInfo.Uses[arg] != ctx {
+			return true
+		}
+		if sig, ok := pass.TypesInfo.TypeOf(fun).Underlying().(*types.Signature); ok {
+			wrapped = analysisengine.ParamTypeAt(call, sig, i)
+		}
+		return wrapped == nil
+	})
+	return wrapped
+}
+
+// _checkWrapper reports the given function if the function literal it
+// returns, as its k'th result, needlessly widens the context of the function
+// it wraps.
+func _checkWrapper(pass *analysis.Pass, tracker *analysisengine.Tracker, funcDecl *ast.FuncDecl, lit *ast.FuncLit, k int) {
+	sig, ok := pass.TypesInfo.Defs[funcDecl.Name].Type().(*types.Signature)
+	if !ok {
+		return
+	}
+	i, ctx := _firstContextParam(pass, lit)
+	if ctx == nil {
+		return
+	}
+	wrapped := _wrappedContextType(pass, lit, sig.Params(), ctx, i)
+	if wrapped == nil {
+		return
+	}
+	info := tracker.Usage(ctx)
+	if info == nil {
+		return
+	}
+
+	var unneeded []types.Type
+	for _, leaf := range _distinctLeaves(ctx.Type()) {
+		iface, ok := leaf.Underlying().(*types.Interface)
+		if !ok || types.Implements(wrapped, iface) || info.InterfaceWasUsed(leaf) {
+			continue
+		}
+		unneeded = append(unneeded, leaf)
+	}
+	if len(unneeded) == 0 {
+		return
+	}
+
+	var node positioner = lit.Type
+	if result := _flatFields(funcDecl.Type.Results, k); result != nil {
+		if funcType, ok := result.Type.(*ast.FuncType); ok {
+			if param := _flatFields(funcType.Params, i); param != nil {
+				node = param.Type
+			}
+		}
+	}
+	reportf(pass, node, CodeWideWrapper,
+		"%s widens the context of the function it wraps from %s to %s, "+
+			"which needlessly adds %s; narrow its result's context type",
+		funcDecl.Name.Name, _shortTypeName(wrapped, pass.Pkg),
+		_shortTypeName(ctx.Type(), pass.Pkg), _formatTypeList(unneeded, pass.Pkg))
+}
+
+// _runWrapper lints that decorators don't widen contexts needlessly.
+func _runWrapper(pass *analysis.Pass) (interface{}, error) {
+	settings, err := loadSettings(pass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := _engineOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	var tracker *analysisengine.Tracker // built when first needed
+
+	for _, file := range pass.Files {
+		if _skipFile(pass.Fset.File(file.Pos()).Name(), pass.Pkg) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil || funcDecl.Type.Results == nil {
+				continue
+			}
+			ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.FuncLit:
+					return false // its returns aren't funcDecl's
+				case *ast.ReturnStmt:
+					for k, result := range node.Results {
+						lit, ok := result.(*ast.FuncLit)
+						if !ok {
+							continue
+						}
+						if tracker == nil {
+							tracker = analysisengine.NewTracker(pass.TypesInfo, pass.Pkg, options)
+							tracker.Track(pass.Files)
+							for _, file := range pass.Files {
+								tracker.MarkUses(file)
+							}
+						}
+						_checkWrapper(pass, tracker, funcDecl, lit, k)
+					}
+				}
+				return true
+			})
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+package typedcontext
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Capability is a typed context interface known to the registry (see
+// Register), like LoggerContext.
+type Capability struct {
+	// Name is the name of the interface, e.g. "app.LoggerContext".
+	Name string
+	// Type is the interface type itself.
+	Type reflect.Type
+}
+
+var (
+	_capabilitiesMu sync.RWMutex
+	// _capabilities are the registered capabilities, sorted by name.
+	_capabilities []Capability
+)
+
+// Register adds the interface T to the capabilities known to Describe and
+// Missing.  Typically each package defining a provider-interface registers
+// it in an init function:
+//
+//	func init() { typedcontext.Register[LoggerContext]() }
+//
+// Register panics if T is not an interface.  Registering the same interface
+// twice has no effect.
+func Register[T any]() {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("typedcontext.Register: %v is not an interface", typ))
+	}
+
+	_capabilitiesMu.Lock()
+	defer _capabilitiesMu.Unlock()
+	for _, capability := range _capabilities {
+		if capability.Type == typ {
+			return
+		}
+	}
+	_capabilities = append(_capabilities, Capability{typ.String(), typ})
+	sort.Slice(_capabilities, func(i, j int) bool {
+		return _capabilities[i].Name < _capabilities[j].Name
+	})
+}
+
+// Describe returns the registered capabilities that ctx satisfies, sorted by
+// name.  It's meant for debugging and error messages: for example, to log
+// what a context received at some dynamic boundary actually provides.
+func Describe(ctx any) []Capability {
+	if ctx == nil {
+		return nil
+	}
+	typ := reflect.TypeOf(ctx)
+
+	_capabilitiesMu.RLock()
+	defer _capabilitiesMu.RUnlock()
+	var satisfied []Capability
+	for _, capability := range _capabilities {
+		if typ.Implements(capability.Type) {
+			satisfied = append(satisfied, capability)
+		}
+	}
+	return satisfied
+}
+
+// _interfaceType returns the interface type described by want, which is
+// either a reflect.Type or a nil pointer to the interface, like
+// (*AppContext)(nil).
+func _interfaceType(want any) (reflect.Type, error) {
+	typ, ok := want.(reflect.Type)
+	if !ok {
+		typ = reflect.TypeOf(want)
+		if typ == nil || typ.Kind() != reflect.Pointer {
+			return nil, fmt.Errorf("typedcontext: want must be a reflect.Type "+
+				"or a pointer to an interface, like (*AppContext)(nil); got %T", want)
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("typedcontext: %v is not an interface", typ)
+	}
+	return typ, nil
+}
+
+// Missing returns a description of what ctx lacks to satisfy the interface
+// want, or nil if it satisfies it.  want is a nil pointer to the interface,
+// like (*AppContext)(nil), or its reflect.Type.
+//
+// Where the missing methods make up registered capabilities which want
+// embeds, Missing lists those capabilities (e.g. "app.LoggerContext");
+// otherwise it lists the missing methods themselves (e.g.
+// "Logger() *app.Logger").  This is meant for producing actionable errors at
+// dynamic boundaries, instead of bare type-assertion panics:
+//
+//	typed, ok := ctx.(AppContext)
+//	if !ok {
+//		return fmt.Errorf("context is missing %s",
+//			strings.Join(typedcontext.Missing(ctx, (*AppContext)(nil)), ", "))
+//	}
+//
+// Missing panics if want isn't a pointer to an interface, or its type.
+func Missing(ctx any, want any) []string {
+	wantType, err := _interfaceType(want)
+	if err != nil {
+		panic(err)
+	}
+
+	var ctxType reflect.Type
+	if ctx != nil {
+		ctxType = reflect.TypeOf(ctx)
+		if ctxType.Implements(wantType) {
+			return nil
+		}
+	}
+
+	// Find the missing methods.
+	missing := map[string]reflect.Method{}
+	for i := 0; i < wantType.NumMethod(); i++ {
+		method := wantType.Method(i)
+		if ctxType == nil {
+			missing[method.Name] = method
+			continue
+		}
+		ctxMethod, ok := ctxType.MethodByName(method.Name)
+		// (ctxMethod.Type has the receiver as its first argument, so we
+		// compare against the method of the interface it satisfies.)
+		if !ok || !_sameSignature(ctxMethod.Type, method.Type) {
+			missing[method.Name] = method
+		}
+	}
+
+	// Group them by registered capability, where we can.
+	var descriptions []string
+	_capabilitiesMu.RLock()
+	for _, capability := range _capabilities {
+		if !wantType.Implements(capability.Type) ||
+			ctxType != nil && ctxType.Implements(capability.Type) {
+			continue
+		}
+		covered := false
+		for i := 0; i < capability.Type.NumMethod(); i++ {
+			name := capability.Type.Method(i).Name
+			if _, ok := missing[name]; ok {
+				delete(missing, name)
+				covered = true
+			}
+		}
+		if covered {
+			descriptions = append(descriptions, capability.Name)
+		}
+	}
+	_capabilitiesMu.RUnlock()
+
+	var methods []string
+	for name, method := range missing {
+		methods = append(methods, name+strings.TrimPrefix(method.Type.String(), "func"))
+	}
+	sort.Strings(methods)
+	return append(descriptions, methods...)
+}
+
+// _sameSignature returns true if the given method of a concrete type (whose
+// first argument is the receiver) has the same signature as the given
+// interface method.
+func _sameSignature(concrete, iface reflect.Type) bool {
+	if concrete.NumIn() != iface.NumIn()+1 || concrete.NumOut() != iface.NumOut() ||
+		concrete.IsVariadic() != iface.IsVariadic() {
+		return false
+	}
+	for i := 0; i < iface.NumIn(); i++ {
+		if concrete.In(i+1) != iface.In(i) {
+			return false
+		}
+	}
+	for i := 0; i < iface.NumOut(); i++ {
+		if concrete.Out(i) != iface.Out(i) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package typedcontext
+
+import (
+	"context"
+	"time"
+)
+
+// WithCancel is like context.WithCancel, but returns a context of the same
+// type as ctx, rather than a plain context.Context, so that its providers
+// aren't lost:
+//
+//	ctx, cancel := typedcontext.WithCancel(ctx)
+//	defer cancel()
+//
+// Like WithContext, on which it's built, it requires T to be a composite
+// interface embedding context.Context, with a constructor generated by
+// typedcontext-gen.
+func WithCancel[T context.Context](ctx T) (T, context.CancelFunc) {
+	inner, cancel := context.WithCancel(ctx)
+	return WithContext(ctx, inner), cancel
+}
+
+// WithDeadline is like context.WithDeadline, but returns a context of the
+// same type as ctx; see WithCancel.
+func WithDeadline[T context.Context](ctx T, deadline time.Time) (T, context.CancelFunc) {
+	inner, cancel := context.WithDeadline(ctx, deadline)
+	return WithContext(ctx, inner), cancel
+}
+
+// WithTimeout is like context.WithTimeout, but returns a context of the same
+// type as ctx; see WithCancel.
+func WithTimeout[T context.Context](ctx T, timeout time.Duration) (T, context.CancelFunc) {
+	inner, cancel := context.WithTimeout(ctx, timeout)
+	return WithContext(ctx, inner), cancel
+}
+
+// WithValue is like context.WithValue, but returns a context of the same type
+// as ctx; see WithCancel.  Prefer a provider to a value where you can: its
+// accessor documents the dependency.
+func WithValue[T context.Context](ctx T, key, val any) T {
+	return WithContext(ctx, context.WithValue(ctx, key, val))
+}
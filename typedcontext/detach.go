@@ -0,0 +1,20 @@
+package typedcontext
+
+import "context"
+
+// Detach returns a context for use beyond the lifetime of ctx's request, for
+// example in a background goroutine.
+//
+// The result keeps ctx's values, but is not canceled when ctx is, and has no
+// deadline.  It also does not implement any of ctx's typed interfaces: those
+// often include request-scoped providers (the request itself, a logger
+// tagged with the request ID, and so on) which must not outlive the request.
+// To use typed accessors in the background, build a new typed context from
+// the result with providers that are safe to use there.
+//
+// The typedcontextdetach linter allows passing the result of Detach to a
+// goroutine, but not a request-scoped typed context itself.
+func Detach(ctx context.Context) context.Context {
+	// (WithoutCancel returns a new type, which hides ctx's typed methods.)
+	return context.WithoutCancel(ctx)
+}
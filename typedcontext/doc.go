@@ -0,0 +1,128 @@
+// Package typedcontext contains runtime support for statically typed contexts
+// (see 05-strongly-typed-context).
+//
+// The linter in ../linter polices the interfaces that functions request; this
+// package and the typedcontext-gen command are the production-side
+// counterpart, for building values that satisfy those interfaces.
+//
+// # Composing contexts
+//
+// Given a composite interface, such as
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=AppContext
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		LoggerContext
+//	}
+//
+// typedcontext-gen generates a constructor
+//
+//	func ComposeAppContext(ctx context.Context, request *Request, logger *Logger) AppContext
+//
+// which takes one argument per accessor of the interface.  Since every
+// provider is a required argument, forgetting one -- for example, after
+// adding a new interface to AppContext -- is a compile error at every call
+// site, rather than a nil-pointer panic at runtime.  This replaces
+// hand-rolled structs like the MockContext in the examples.
+//
+// Interfaces which don't embed context.Context (the "server interface" of
+// 07-server-interface) are supported too; their constructors just don't take
+// a ctx.
+//
+// # Lazy providers
+//
+// With -lazy, typedcontext-gen also generates a constructor taking a function
+// per provider, for providers which are expensive to build and often unused:
+//
+//	func ComposeLazyAppContext(ctx context.Context, request func() *Request, logger func() *Logger) AppContext
+//
+// Each function is called on the first call of its accessor, at most once
+// even with concurrent callers (see Lazy).  Override and WithContext keep
+// such a context lazy: the providers they don't replace are built, if ever,
+// on the first call of their accessors, once for the original context and
+// every copy.
+//
+// # Overriding providers
+//
+// To replace just one provider of an existing context -- say, to log with
+// extra fields for part of a request -- use Override, rather than calling
+// the constructor again with every provider:
+//
+//	ctx = typedcontext.Override(ctx, typedcontext.With(ctx.Logger().With("job", id)))
+//
+// This works for any composite interface with a generated constructor: the
+// generated code registers a wrapper for Override to use.
+//
+// # Wiring providers
+//
+// Rather than calling every provider's constructor by hand, in the right
+// order, and passing the results to ComposeAppContext, the typedcontextdi
+// subpackage does it from registered constructors, keyed by accessor
+// interface; each may take a narrower typed context, of the providers it
+// depends on:
+//
+//	container := typedcontextdi.New[AppContext]()
+//	container.Provide((*LoggerContext)(nil), NewLogger) // func(ctx ConfigContext) *Logger
+//	container.Compose(ComposeAppContext, ComposeConfigContext)
+//	ctx, err := container.Build(context.Background())
+//
+// Its Check method reports missing providers and dependency cycles at
+// startup, without building anything.
+//
+// # Migrating between styles
+//
+// For code moving between typed contexts and server interfaces, the
+// typedcontext-adaptgen command generates, from a struct holding providers in
+// its fields, both styles of interface and the conversions between them:
+//
+//	//go:generate go run github.com/khan/typed-context/cmd/typedcontext-adaptgen -type=Server
+//	type Server struct {
+//		Request *Request
+//		Logger  *Logger
+//	}
+//
+// generates NewServerContext(ctx, server), ServerContextFromInterface(ctx,
+// server), and ServerInterfaceFromContext(ctx), among others.
+//
+// # Describing contexts
+//
+// Where a typed context crosses a dynamic boundary (an RPC handler, a task
+// queue), it's often recovered with a type assertion, which fails with an
+// unhelpful panic.  Provider-interfaces registered with Register can be
+// listed with Describe, to see what a context provides, and Missing, to
+// explain what it lacks:
+//
+//	Missing(ctx, (*AppContext)(nil)) // => ["app.LoggerContext"]
+//
+// # Testing implementations
+//
+// For contexts implemented by hand, rather than by a generated constructor,
+// the typedcontexttest subpackage checks that every accessor returns a
+// non-nil provider, and that the methods of context.Context delegate to the
+// context the implementation was built from.  The typedcontext-testgen
+// command generates such a test, listing the accessors, so that
+// regenerating it after an interface gains one checks that too.
+//
+// # Crossing process boundaries
+//
+// Value-like providers, such as a request ID, can be carried to other
+// services in HTTP headers or gRPC metadata, and used to build the typed
+// context there, with the propagation subpackage.
+//
+// # Deriving contexts
+//
+// Functions like OpenTelemetry's tracer.Start derive a new context.Context
+// from a typed context, losing its typed interface.  WithContext puts the
+// derived context.Context back into a copy of the typed context; the
+// typedcontextotel subpackage does this for spans.  WithCancel, WithDeadline,
+// WithTimeout and WithValue do it for their namesakes in package context:
+//
+//	ctx, cancel := typedcontext.WithTimeout(ctx, time.Second)
+//	defer cancel()
+//
+// For other functions, wrap the result yourself:
+//
+//	inner, cancel := context.WithCancelCause(ctx)
+//	ctx = typedcontext.WithContext(ctx, inner)
+package typedcontext
@@ -0,0 +1,103 @@
+package gen
+
+// This file generates adapters between the two styles of passing providers:
+// typed contexts (05-strongly-typed-context) and server interfaces
+// (07-server-interface).
+
+// Adapt generates, for the provider struct P with fields F1, F2, ...:
+//
+//   - 07-style interfaces F1Server { F1() T1 }, ..., and their composite
+//     PInterface;
+//   - 05-style interfaces F1Context { context.Context; F1() T1 }, ..., and
+//     their composite PContext;
+//   - NewPInterface(*P) and NewPContext(ctx, *P), which wrap a P;
+//   - PContextFromInterface(ctx, PInterface), and its inverse
+//     PInterfaceFromContext(PContext), which convert between the two.
+//
+// The context wrappers embed the ctx they're given, so deadlines,
+// cancellation and values are preserved.
+func (g *Generator) Adapt(provider *Provider) {
+	name := provider.Name
+	contextPkg := g.importPackage("context", "context")
+	ifaceName := name + "Interface"
+	ctxName := name + "Context"
+
+	for _, field := range provider.Fields {
+		typ := g.typeString(field.Type)
+		g.printf("// %sServer is the server interface for the %s provider of %s.\n",
+			field.Name, field.Name, name)
+		g.printf("type %sServer interface {\n", field.Name)
+		g.printf("\t%s() %s\n", field.Name, typ)
+		g.printf("}\n\n")
+
+		g.printf("// %sContext is the typed context for the %s provider of %s.\n",
+			field.Name, field.Name, name)
+		g.printf("type %sContext interface {\n", field.Name)
+		g.printf("\t%s.Context\n", contextPkg)
+		g.printf("\t%s() %s\n", field.Name, typ)
+		g.printf("}\n\n")
+	}
+
+	g.printf("// %s is the server interface for all the providers of %s.\n", ifaceName, name)
+	g.printf("type %s interface {\n", ifaceName)
+	for _, field := range provider.Fields {
+		g.printf("\t%sServer\n", field.Name)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %s is the typed context for all the providers of %s.\n", ctxName, name)
+	g.printf("type %s interface {\n", ctxName)
+	for _, field := range provider.Fields {
+		g.printf("\t%sContext\n", field.Name)
+	}
+	g.printf("}\n\n")
+
+	adapterName := "adapted" + ifaceName
+	g.printf("// New%s returns a %s whose accessors return the fields\n", ifaceName, ifaceName)
+	g.printf("// of server.\n")
+	g.printf("func New%s(server *%s) %s {\n", ifaceName, name, ifaceName)
+	g.printf("\treturn %s{server}\n", adapterName)
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", adapterName)
+	g.printf("\tserver *%s\n", name)
+	g.printf("}\n\n")
+
+	for _, field := range provider.Fields {
+		g.printf("func (a %s) %s() %s {\n", adapterName, field.Name, g.typeString(field.Type))
+		g.printf("\treturn a.server.%s\n", field.Name)
+		g.printf("}\n\n")
+	}
+
+	g.printf("// New%s returns a %s wrapping ctx, whose accessors return\n", ctxName, ctxName)
+	g.printf("// the fields of server.\n")
+	g.printf("func New%s(ctx %s.Context, server *%s) %s {\n", ctxName, contextPkg, name, ctxName)
+	g.printf("\treturn %sFromInterface(ctx, New%s(server))\n", ctxName, ifaceName)
+	g.printf("}\n\n")
+
+	contextAdapterName := "adapted" + ctxName
+	g.printf("// %sFromInterface returns a %s wrapping ctx, whose\n", ctxName, ctxName)
+	g.printf("// accessors delegate to server.\n")
+	g.printf("func %sFromInterface(ctx %s.Context, server %s) %s {\n",
+		ctxName, contextPkg, ifaceName, ctxName)
+	g.printf("\treturn %s{ctx, server}\n", contextAdapterName)
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", contextAdapterName)
+	g.printf("\t%s.Context\n", contextPkg)
+	g.printf("\t%s\n", ifaceName)
+	g.printf("}\n\n")
+
+	g.printf("var _ %s = %s{}\n\n", ctxName, contextAdapterName)
+
+	g.printf("// %sFromContext splits ctx into the context and server\n", ifaceName)
+	g.printf("// arguments expected by code in the server-interface style.\n")
+	g.printf("//\n")
+	g.printf("// Since every %s has the accessors of a %s, this is\n", ctxName, ifaceName)
+	g.printf("// just ctx, twice; it's here for symmetry, and to document the\n")
+	g.printf("// conversion.\n")
+	g.printf("func %sFromContext(ctx %s) (%s.Context, %s) {\n",
+		ifaceName, ctxName, contextPkg, ifaceName)
+	g.printf("\treturn ctx, ctx\n")
+	g.printf("}\n\n")
+}
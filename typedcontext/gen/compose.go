@@ -0,0 +1,84 @@
+package gen
+
+// This file generates the Compose constructors for composite interfaces.
+
+// composedName returns the name of the (unexported) struct implementing the
+// composite returned by its Compose constructor.
+func composedName(composite *Composite) string {
+	return "composed" + composite.Name
+}
+
+// Compose generates a constructor ComposeX for the composite interface X,
+// which takes one argument per accessor (plus a ctx, if X embeds
+// context.Context), and returns an X.
+//
+// Because each provider is a required argument, a caller that's missing a
+// provider fails to compile.
+//
+// It also registers a function with typedcontext.RegisterOverride which
+// builds the same struct from an existing X, so that typedcontext.Override
+// can replace some of its providers, and typedcontext.WithContext its
+// context.Context.
+func (g *Generator) Compose(composite *Composite) {
+	name := composite.Name
+	structName := composedName(composite)
+	contextType := ""
+	if composite.HasContext {
+		contextType = g.importPackage("context", "context") + ".Context"
+	}
+
+	g.printf("// Compose%s returns a %s built from the given providers.\n", name, name)
+	g.printf("//\n")
+	g.printf("// Every provider is required, so adding an accessor to %s makes\n", name)
+	g.printf("// callers which don't provide it fail to compile.\n")
+	g.printf("func Compose%s(\n", name)
+	if composite.HasContext {
+		g.printf("\tctx %s,\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s %s,\n", accessor.VarName(), g.typeString(accessor.Type))
+	}
+	g.printf(") %s {\n", name)
+	g.printf("\treturn %s{\n", structName)
+	if composite.HasContext {
+		g.printf("\t\tContext: ctx,\n")
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t%s: %s,\n", accessor.VarName(), accessor.VarName())
+	}
+	g.printf("\t}\n")
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", structName)
+	if composite.HasContext {
+		g.printf("\t%s\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s %s\n", accessor.VarName(), g.typeString(accessor.Type))
+	}
+	g.printf("}\n\n")
+
+	g.printf("var _ %s = %s{}\n\n", name, structName)
+
+	for _, accessor := range composite.Accessors {
+		g.printf("func (c %s) %s() %s {\n", structName, accessor.Name, g.typeString(accessor.Type))
+		g.printf("\treturn c.%s\n", accessor.VarName())
+		g.printf("}\n\n")
+	}
+
+	typedcontextPkg := g.importPackage("github.com/khan/typed-context/typedcontext", "typedcontext")
+	g.printf("func init() {\n")
+	g.printf("\t%s.RegisterOverride(func(ctx %s, overrides *%s.Overrides) %s {\n",
+		typedcontextPkg, name, typedcontextPkg, name)
+	g.printf("\t\treturn %s{\n", structName)
+	if composite.HasContext {
+		g.printf("\t\t\tContext: %s.OverriddenContext(overrides, ctx),\n", typedcontextPkg)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t\t%s: %s.Overridden(overrides, ctx.%s()),\n",
+			accessor.VarName(), typedcontextPkg, accessor.Name)
+	}
+	g.printf("\t\t}\n")
+	g.printf("\t})\n")
+	g.printf("}\n\n")
+}
@@ -0,0 +1,175 @@
+// Package gen generates code for typed contexts: constructors, wrappers, and
+// so on for composite context interfaces.  It's the library behind the
+// typedcontext-gen command.
+package gen
+
+// This file defines the model of a composite interface that the generators
+// work from.
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// Accessor is a provider-accessor method of a typed-context interface, like
+// `Logger() *Logger`.
+type Accessor struct {
+	// Name is the name of the method, e.g. "Logger".
+	Name string
+	// Type is the type of the provider it returns, e.g. *Logger.
+	Type types.Type
+	// Interface is the interface which declares the method explicitly,
+	// e.g. LoggerContext.
+	Interface types.Type
+}
+
+// VarName returns a name for a variable (or field) holding the provider
+// returned by this accessor, e.g. "logger" for Logger().
+func (accessor Accessor) VarName() string {
+	name := lowerInitial(accessor.Name)
+	if token.IsKeyword(name) || name == "ctx" {
+		name += "_"
+	}
+	return name
+}
+
+// lowerInitial lower-cases the first word of an identifier, treating an
+// initialism as a single word: "HttpClient" becomes "httpClient", and
+// "URLFetcher" becomes "urlFetcher".
+func lowerInitial(name string) string {
+	runes := []rune(name)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	if i > 1 && i < len(runes) {
+		i-- // the last capital begins the next word
+	}
+	return strings.ToLower(string(runes[:i])) + string(runes[i:])
+}
+
+// Composite describes a composite context interface, like
+//
+//	type AppContext interface {
+//		context.Context
+//		RequestContext
+//		LoggerContext
+//	}
+type Composite struct {
+	// Name is the name of the interface, e.g. "AppContext".
+	Name string
+	// Type is the interface type itself, or nil for a combination (see
+	// NewCombination).
+	Type *types.Named
+	// Embeds are the interfaces a combination combines, or nil for a named
+	// interface.
+	Embeds []types.Type
+	// HasContext is set if the interface embeds context.Context (the
+	// typed-context pattern), rather than being just a set of accessors (the
+	// server-interface pattern).
+	HasContext bool
+	// Accessors are the accessor-methods of the interface, other than those
+	// of context.Context, in the order they are embedded.
+	Accessors []Accessor
+}
+
+// isStdContext returns true if typ is context.Context.
+func isStdContext(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
+
+// NewComposite returns the Composite for the given named interface type.
+//
+// It returns an error if the type isn't an interface, or if it has methods
+// other than accessors (methods with no arguments and a single result) and
+// those of context.Context.
+func NewComposite(named *types.Named) (*Composite, error) {
+	if _, ok := named.Underlying().(*types.Interface); !ok {
+		return nil, fmt.Errorf("%s is not an interface", named.Obj().Name())
+	}
+
+	composite := &Composite{Name: named.Obj().Name(), Type: named}
+	if err := composite.visit(named, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	return composite, nil
+}
+
+// NewCombination returns a Composite, with the given name, for the
+// unnamed combination of the given interfaces, like
+//
+//	interface {
+//		DatabaseContext
+//		LoggerContext
+//	}
+//
+// Its Type is nil, and its Embeds are the interfaces.  It returns an error
+// if any of them isn't an interface, or has methods other than accessors
+// and those of context.Context.
+func NewCombination(name string, embeds []types.Type) (*Composite, error) {
+	composite := &Composite{Name: name, Embeds: embeds}
+	seen := map[string]bool{}
+	for _, embed := range embeds {
+		if _, ok := embed.Underlying().(*types.Interface); !ok {
+			return nil, fmt.Errorf("%s is not an interface", types.TypeString(embed, nil))
+		}
+		if err := composite.visit(embed, seen); err != nil {
+			return nil, err
+		}
+	}
+	return composite, nil
+}
+
+// visit adds the accessors of the interface typ, and those it embeds, to
+// the composite, skipping those in seen (by name), which it updates.
+func (composite *Composite) visit(typ types.Type, seen map[string]bool) error {
+	if isStdContext(typ) {
+		composite.HasContext = true
+		return nil
+	}
+	iface := typ.Underlying().(*types.Interface)
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if err := composite.visit(iface.EmbeddedType(i), seen); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		method := iface.ExplicitMethod(i)
+		if seen[method.Name()] {
+			continue // diamond embed
+		}
+		seen[method.Name()] = true
+
+		sig := method.Type().(*types.Signature)
+		if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+			return fmt.Errorf("method %s of %s is not an accessor: "+
+				"accessors take no arguments and return a single provider",
+				method.Name(), types.TypeString(typ, nil))
+		}
+		composite.Accessors = append(composite.Accessors, Accessor{
+			Name:      method.Name(),
+			Type:      sig.Results().At(0).Type(),
+			Interface: typ,
+		})
+	}
+	return nil
+}
+
+// LookupComposite returns the Composite for the interface with the given
+// name in the given package.
+func LookupComposite(pkg *types.Package, name string) (*Composite, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in package %s", name, pkg.Path())
+	}
+	named, ok := types.Unalias(obj.Type()).(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a named type", name)
+	}
+	return NewComposite(named)
+}
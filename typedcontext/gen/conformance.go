@@ -0,0 +1,72 @@
+package gen
+
+// This file generates conformance tests for implementations of composite
+// interfaces; see package typedcontexttest.
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// _typedcontexttestPath is the import path of package typedcontexttest.
+const _typedcontexttestPath = "github.com/khan/typed-context/typedcontext/typedcontexttest"
+
+// LookupBuilder returns the function with the given name in the given
+// package, checking that it can build an implementation of the composite
+// for a conformance test: that it takes a context.Context, or, if the
+// composite doesn't embed context.Context, optionally nothing, and returns a
+// single value assignable to the composite.
+func LookupBuilder(pkg *types.Package, name string, composite *Composite) (*types.Func, error) {
+	build, ok := pkg.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return nil, fmt.Errorf("no function %s in package %s", name, pkg.Path())
+	}
+	sig := build.Type().(*types.Signature)
+	if sig.TypeParams() != nil {
+		return nil, fmt.Errorf("%s is generic", name)
+	}
+	switch {
+	case sig.Params().Len() == 1 && isStdContext(sig.Params().At(0).Type()):
+	case sig.Params().Len() == 0 && !composite.HasContext:
+	default:
+		return nil, fmt.Errorf("%s must take just a context.Context", name)
+	}
+	if sig.Results().Len() != 1 ||
+		composite.Type != nil && !types.AssignableTo(sig.Results().At(0).Type(), composite.Type) {
+		return nil, fmt.Errorf("%s must return a single %s", name, composite.Name)
+	}
+	return build, nil
+}
+
+// Conformance generates a test, TestNameConformance for the composite Name,
+// checking the implementation of the composite which build (see
+// LookupBuilder) returns with typedcontexttest.Conformance: that its
+// accessors return non-nil providers, and that it delegates the methods of
+// context.Context to the context it's built from.
+//
+// The test lists the accessors, so regenerating it after the composite gains
+// one adds a check for it.  It should be generated into a _test.go file of
+// the package; build must be declared in its other files.
+func (g *Generator) Conformance(composite *Composite, build *types.Func) {
+	name := composite.Name
+	testingPkg := g.importPackage("testing", "testing")
+	contextPkg := g.importPackage("context", "context")
+	testPkg := g.importPackage(_typedcontexttestPath, "typedcontexttest")
+
+	args := ""
+	if build.Type().(*types.Signature).Params().Len() == 1 {
+		args = "ctx"
+	}
+	g.printf("// Test%sConformance checks that %s's accessors return non-nil\n", name, build.Name())
+	g.printf("// providers, and that it delegates to the context it's built from.\n")
+	g.printf("func Test%sConformance(t *%s.T) {\n", name, testingPkg)
+	g.printf("\t%s.Conformance(t, func(ctx %s.Context) %s {\n", testPkg, contextPkg, name)
+	g.printf("\t\treturn %s(%s)\n", build.Name(), args)
+	g.printf("\t},\n")
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t%s.Accessor[%s]{Name: %q, Call: func(ctx %s) any { return ctx.%s() }},\n",
+			testPkg, name, accessor.Name, name, accessor.Name)
+	}
+	g.printf("\t)\n")
+	g.printf("}\n\n")
+}
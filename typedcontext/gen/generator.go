@@ -0,0 +1,124 @@
+package gen
+
+// This file defines the Generator, which accumulates the code for a single
+// generated file.
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generator accumulates generated code for a single output file, in the given
+// package.
+type Generator struct {
+	pkg *types.Package
+	// tool is the name of the command generating the file.
+	tool string
+	body bytes.Buffer
+	// imports maps the path of each imported package to the name we refer to
+	// it by.
+	imports map[string]string
+}
+
+// NewGenerator returns a Generator for a file in the given package, generated
+// by the given command.
+func NewGenerator(pkg *types.Package, tool string) *Generator {
+	return &Generator{pkg: pkg, tool: tool, imports: map[string]string{}}
+}
+
+// printf appends to the body of the generated file.
+func (g *Generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// qualifier is a types.Qualifier which adds imports as needed.
+func (g *Generator) qualifier(pkg *types.Package) string {
+	if pkg.Path() == g.pkg.Path() {
+		return "" // even if it's another copy, like a test variant's
+	}
+	if name, ok := g.imports[pkg.Path()]; ok {
+		return name
+	}
+
+	// Make sure the name is unique among our imports.
+	name := pkg.Name()
+	for i := 2; g.importNameUsed(name); i++ {
+		name = pkg.Name() + strconv.Itoa(i)
+	}
+	g.imports[pkg.Path()] = name
+	return name
+}
+
+// importNameUsed returns true if we already import some package as name.
+func (g *Generator) importNameUsed(name string) bool {
+	for _, used := range g.imports {
+		if used == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typeString returns the name of typ, as it should be written in the
+// generated file.
+func (g *Generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, g.qualifier)
+}
+
+// importPackage imports the package with the given path, and returns the name
+// by which to refer to it.
+func (g *Generator) importPackage(path, name string) string {
+	return g.qualifier(types.NewPackage(path, name))
+}
+
+// Source returns the generated file, formatted.
+func (g *Generator) Source() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by %s; DO NOT EDIT.\n\n", g.tool)
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg.Name())
+
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		specs := make([]string, len(paths))
+		for i, path := range paths {
+			specs[i] = strconv.Quote(path)
+			if name := g.imports[path]; name != defaultName(path) {
+				specs[i] = name + " " + specs[i]
+			}
+		}
+		if len(specs) == 1 {
+			fmt.Fprintf(&buf, "import %s\n\n", specs[0])
+		} else {
+			fmt.Fprintf(&buf, "import (\n\t%s\n)\n\n", strings.Join(specs, "\n\t"))
+		}
+	}
+
+	buf.Write(g.body.Bytes())
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		// Return the unformatted source, to help debug the generator.
+		return buf.Bytes(), fmt.Errorf("generated invalid code: %w", err)
+	}
+	return source, nil
+}
+
+// defaultName returns the name a package with the given path has by
+// convention: the last element of its path.
+func defaultName(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,87 @@
+package gen
+
+// This file generates the lazy constructors for composite interfaces.
+
+// lazyName returns the name of the (unexported) struct implementing the
+// composite returned by its ComposeLazy constructor.
+func lazyName(composite *Composite) string {
+	return "lazy" + composite.Name
+}
+
+// ComposeLazy generates a constructor ComposeLazyX for the composite
+// interface X, which takes one function per accessor (plus a ctx, if X
+// embeds context.Context), and returns an X each of whose accessors calls
+// the corresponding function on its first call, and returns the same
+// provider thereafter (see typedcontext.Lazy).
+//
+// As with Compose, every function is a required argument.
+//
+// It also registers a function with typedcontext.RegisterOverrideFor which
+// builds another lazy struct from an existing one, so that
+// typedcontext.Override and typedcontext.WithContext don't build the
+// providers they keep (see typedcontext.OverriddenLazy).
+func (g *Generator) ComposeLazy(composite *Composite) {
+	name := composite.Name
+	structName := lazyName(composite)
+	typedcontextPkg := g.importPackage("github.com/khan/typed-context/typedcontext", "typedcontext")
+	contextType := ""
+	if composite.HasContext {
+		contextType = g.importPackage("context", "context") + ".Context"
+	}
+
+	g.printf("// ComposeLazy%s returns a %s whose providers are built by the given\n", name, name)
+	g.printf("// functions, each on the first call of its accessor.  It's safe for\n")
+	g.printf("// concurrent use: each function is called at most once.\n")
+	g.printf("//\n")
+	g.printf("// Every function is required, so adding an accessor to %s makes\n", name)
+	g.printf("// callers which don't provide it fail to compile.\n")
+	g.printf("func ComposeLazy%s(\n", name)
+	if composite.HasContext {
+		g.printf("\tctx %s,\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s func() %s,\n", accessor.VarName(), g.typeString(accessor.Type))
+	}
+	g.printf(") %s {\n", name)
+	g.printf("\treturn %s{\n", structName)
+	if composite.HasContext {
+		g.printf("\t\tContext: ctx,\n")
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t%s: %s.NewLazy(%s),\n", accessor.VarName(), typedcontextPkg, accessor.VarName())
+	}
+	g.printf("\t}\n")
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", structName)
+	if composite.HasContext {
+		g.printf("\t%s\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s *%s.Lazy[%s]\n", accessor.VarName(), typedcontextPkg, g.typeString(accessor.Type))
+	}
+	g.printf("}\n\n")
+
+	g.printf("var _ %s = %s{}\n\n", name, structName)
+
+	for _, accessor := range composite.Accessors {
+		g.printf("func (c %s) %s() %s {\n", structName, accessor.Name, g.typeString(accessor.Type))
+		g.printf("\treturn c.%s.Get()\n", accessor.VarName())
+		g.printf("}\n\n")
+	}
+
+	g.printf("func init() {\n")
+	g.printf("\t%s.RegisterOverrideFor(func(ctx %s, overrides *%s.Overrides) %s {\n",
+		typedcontextPkg, structName, typedcontextPkg, name)
+	g.printf("\t\treturn %s{\n", structName)
+	if composite.HasContext {
+		g.printf("\t\t\tContext: %s.OverriddenContext(overrides, ctx.Context),\n", typedcontextPkg)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t\t%s: %s.OverriddenLazy(overrides, ctx.%s),\n",
+			accessor.VarName(), typedcontextPkg, accessor.VarName())
+	}
+	g.printf("\t\t}\n")
+	g.printf("\t})\n")
+	g.printf("}\n\n")
+}
@@ -0,0 +1,109 @@
+package gen
+
+// This file defines how generators load the package they generate code for.
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadPackage loads the package in dir, for generating code into the file
+// outputName (in that package).
+//
+// We ignore the contents of that file, which may be out of date.  We also
+// ignore type errors: the package may well use the code we're about to
+// generate.  If the interfaces themselves are broken, LookupComposite will
+// tell us.
+func LoadPackage(dir, outputName string) (*types.Package, error) {
+	overlay, err := outputOverlay(outputName)
+	if err != nil {
+		return nil, err
+	}
+	return loadPackage(dir, overlay)
+}
+
+// LoadPackageWith is like LoadPackage, but loads the package as if the file
+// outputName had the given contents.  This is for generators which generate
+// declarations (see Generator.Declare), and then more code from them.
+func LoadPackageWith(dir, outputName string, contents []byte) (*types.Package, error) {
+	absOutput, err := filepath.Abs(outputName)
+	if err != nil {
+		return nil, err
+	}
+	return loadPackage(dir, map[string][]byte{absOutput: contents})
+}
+
+// loadPackage loads the package in dir, with the given overlay.
+func loadPackage(dir string, overlay map[string][]byte) (*types.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes,
+		Dir:     dir,
+		Overlay: overlay,
+	}, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+	if pkgs[0].Types == nil {
+		return nil, fmt.Errorf("could not load package in %s", dir)
+	}
+	return pkgs[0].Types, nil
+}
+
+// outputOverlay returns an overlay replacing the file outputName with just
+// its package clause, so that loading ignores its contents.
+func outputOverlay(outputName string) (map[string][]byte, error) {
+	absOutput, err := filepath.Abs(outputName)
+	if err != nil {
+		return nil, err
+	}
+	overlay := map[string][]byte{}
+	if file, err := parser.ParseFile(token.NewFileSet(), absOutput, nil, parser.PackageClauseOnly); err == nil {
+		overlay[absOutput] = []byte("package " + file.Name.Name + "\n")
+	}
+	return overlay, nil
+}
+
+// LoadPackages is like LoadPackage, but also loads the packages matching
+// the given patterns (relative to dir), including their tests, with syntax
+// and types, for generators which look at how code uses the package.  It
+// returns the package in dir, and all the packages loaded, which share
+// their types.
+//
+// As in LoadPackage, we ignore type errors: code elsewhere may well use the
+// code we're about to generate.
+func LoadPackages(dir, outputName string, patterns []string) (*packages.Package, []*packages.Package, error) {
+	overlay, err := outputOverlay(outputName)
+	if err != nil {
+		return nil, nil, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.LoadAllSyntax,
+		Dir:     dir,
+		Overlay: overlay,
+		Tests:   true,
+	}, append([]string{"."}, patterns...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pkg := range pkgs {
+		// The package itself, not its test variants.
+		if pkg.ID == pkg.PkgPath && len(pkg.GoFiles) > 0 &&
+			filepath.Dir(pkg.GoFiles[0]) == absDir && pkg.Types != nil {
+			return pkg, pkgs, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("could not load package in %s", dir)
+}
@@ -0,0 +1,84 @@
+package gen
+
+// This file generates test doubles for composite interfaces.
+
+// Mock generates a test double named mockName for the composite interface.
+//
+// The double has a settable field for each accessor (e.g. LoggerProvider for
+// Logger()), and records the accessors called on it.  Since it's generated
+// from the composite, which may embed several interfaces, a single struct
+// implements all of them.
+func (g *Generator) Mock(composite *Composite, mockName string) {
+	name := composite.Name
+	syncPkg := g.importPackage("sync", "sync")
+	contextPkg := ""
+	if composite.HasContext {
+		contextPkg = g.importPackage("context", "context")
+	}
+
+	g.printf("// %s is a test double for %s.\n", mockName, name)
+	g.printf("//\n")
+	g.printf("// Set the provider fields to whatever the code under test needs; the\n")
+	g.printf("// accessors return them, and record that they were called.\n")
+	g.printf("type %s struct {\n", mockName)
+	if composite.HasContext {
+		g.printf("\t%s.Context\n\n", contextPkg)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t// %sProvider is returned by %s() (from %s).\n",
+			accessor.Name, accessor.Name, g.typeString(accessor.Interface))
+		g.printf("\t%sProvider %s\n", accessor.Name, g.typeString(accessor.Type))
+	}
+	g.printf("\n\tmu    %s.Mutex\n", syncPkg)
+	g.printf("\tcalls []string\n")
+	g.printf("}\n\n")
+
+	g.printf("var _ %s = (*%s)(nil)\n\n", name, mockName)
+
+	g.printf("// New%s returns a %s with no providers set", mockName, mockName)
+	if composite.HasContext {
+		g.printf(",\n// wrapping context.Background().\n")
+	} else {
+		g.printf(".\n")
+	}
+	g.printf("func New%s() *%s {\n", mockName, mockName)
+	if composite.HasContext {
+		g.printf("\treturn &%s{Context: %s.Background()}\n", mockName, contextPkg)
+	} else {
+		g.printf("\treturn &%s{}\n", mockName)
+	}
+	g.printf("}\n\n")
+
+	for _, accessor := range composite.Accessors {
+		g.printf("func (m *%s) %s() %s {\n", mockName, accessor.Name, g.typeString(accessor.Type))
+		g.printf("\tm.record(%q)\n", accessor.Name)
+		g.printf("\treturn m.%sProvider\n", accessor.Name)
+		g.printf("}\n\n")
+	}
+
+	g.printf("func (m *%s) record(accessor string) {\n", mockName)
+	g.printf("\tm.mu.Lock()\n")
+	g.printf("\tdefer m.mu.Unlock()\n")
+	g.printf("\tm.calls = append(m.calls, accessor)\n")
+	g.printf("}\n\n")
+
+	g.printf("// Calls returns the names of the accessors called, in order.\n")
+	g.printf("func (m *%s) Calls() []string {\n", mockName)
+	g.printf("\tm.mu.Lock()\n")
+	g.printf("\tdefer m.mu.Unlock()\n")
+	g.printf("\treturn append([]string(nil), m.calls...)\n")
+	g.printf("}\n\n")
+
+	g.printf("// CallCount returns the number of times the given accessor was called.\n")
+	g.printf("func (m *%s) CallCount(accessor string) int {\n", mockName)
+	g.printf("\tm.mu.Lock()\n")
+	g.printf("\tdefer m.mu.Unlock()\n")
+	g.printf("\tcount := 0\n")
+	g.printf("\tfor _, call := range m.calls {\n")
+	g.printf("\t\tif call == accessor {\n")
+	g.printf("\t\t\tcount++\n")
+	g.printf("\t\t}\n")
+	g.printf("\t}\n")
+	g.printf("\treturn count\n")
+	g.printf("}\n\n")
+}
@@ -0,0 +1,63 @@
+package gen
+
+// This file defines the model of a provider struct, which the adapter
+// generator works from.
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// Provider describes a struct holding providers in its exported fields, like
+//
+//	type Server struct {
+//		Request *Request
+//		Logger  *Logger
+//	}
+//
+// which is how code in the server-interface style (see 07-server-interface)
+// often keeps them.
+type Provider struct {
+	// Name is the name of the struct, e.g. "Server".
+	Name string
+	// Type is the struct type itself.
+	Type *types.Named
+	// Fields are the accessors the providers become, one per exported
+	// field, in order.  Their Interface is unset.
+	Fields []Accessor
+}
+
+// LookupProvider returns the Provider for the struct with the given name in
+// the given package.
+//
+// It returns an error if the type isn't a struct, or has no exported fields.
+func LookupProvider(pkg *types.Package, name string) (*Provider, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in package %s", name, pkg.Path())
+	}
+	named, ok := types.Unalias(obj.Type()).(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a named type", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", name)
+	}
+
+	provider := &Provider{Name: name, Type: named}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Exported() || field.Embedded() {
+			continue
+		}
+		provider.Fields = append(provider.Fields, Accessor{
+			Name: field.Name(),
+			Type: field.Type(),
+		})
+	}
+	if len(provider.Fields) == 0 {
+		return nil, fmt.Errorf("%s has no exported (non-embedded) fields", name)
+	}
+	return provider, nil
+}
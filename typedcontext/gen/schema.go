@@ -0,0 +1,283 @@
+package gen
+
+// This file defines schemas: declarative descriptions of a package's
+// providers and the capability groups built from them, from which
+// typedcontext-gen generates the interfaces themselves, along with
+// everything it generates from interfaces written by hand.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema describes the typed contexts of a package, like
+//
+//	imports:
+//	  log: example.com/log
+//	providers:
+//	  - name: Logger
+//	    type: "*log.Logger"
+//	  - name: RequestID
+//	    type: RequestID
+//	    propagate:
+//	      header: x-request-id
+//	      codec: string
+//	capabilities:
+//	  - name: AppContext
+//	    include: [Logger, RequestID]
+//	    mock: true
+//
+// Each provider becomes a capability interface with a single accessor, like
+//
+//	type LoggerContext interface {
+//		context.Context
+//		Logger() *log.Logger
+//	}
+//
+// and each capability a composite interface embedding those it includes.
+// Capabilities may include only those declared before them, so the
+// interfaces can't embed themselves.
+type Schema struct {
+	// Style is "context" (the default), for interfaces which embed
+	// context.Context, or "server", for server interfaces (see
+	// 07-server-interface), which don't.
+	Style string `yaml:"style"`
+	// Imports maps the names by which the types in the schema refer to
+	// packages to the packages' paths.
+	Imports map[string]string `yaml:"imports"`
+	// Providers are the providers, each of which gets its own interface.
+	Providers []SchemaProvider `yaml:"providers"`
+	// Capabilities are the composite interfaces.
+	Capabilities []SchemaCapability `yaml:"capabilities"`
+}
+
+// SchemaProvider is a provider in a Schema.
+type SchemaProvider struct {
+	// Name is the name of its accessor, like "Logger".
+	Name string `yaml:"name"`
+	// Type is the type of the provider, as written in the package, like
+	// "*log.Logger".
+	Type string `yaml:"type"`
+	// Interface is the name of its interface; by default, Name followed by
+	// Context (or Server, for server interfaces).
+	Interface string `yaml:"interface"`
+	// Doc documents its interface.
+	Doc string `yaml:"doc"`
+	// Propagate, if set, registers the provider with package propagation.
+	Propagate *SchemaPropagation `yaml:"propagate"`
+}
+
+// SchemaPropagation says how a provider is propagated across process
+// boundaries; see package propagation.
+type SchemaPropagation struct {
+	// Header is the header (or metadata key) carrying it.
+	Header string `yaml:"header"`
+	// Codec is "string" or "int", for propagation.StringCodec or IntCodec,
+	// or else a Go expression for a propagation.Codec of the provider's
+	// type, like "localeCodec()".
+	Codec string `yaml:"codec"`
+}
+
+// SchemaCapability is a capability group in a Schema.
+type SchemaCapability struct {
+	// Name is the name of its interface, like "AppContext".
+	Name string `yaml:"name"`
+	// Doc documents its interface.
+	Doc string `yaml:"doc"`
+	// Include are the providers, by name, and capabilities declared before
+	// it, by interface name, that it includes.
+	Include []string `yaml:"include"`
+	// Lazy says to also generate its ComposeLazy constructor.
+	Lazy bool `yaml:"lazy"`
+	// Mock says to also generate its test double, MockX.
+	Mock bool `yaml:"mock"`
+}
+
+// ReadSchema reads and checks the schema in the given file.
+func ReadSchema(filename string) (*Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&schema); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := schema.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &schema, nil
+}
+
+// interfaceName returns the name of the interface of the given provider.
+func (schema *Schema) interfaceName(provider SchemaProvider) string {
+	if provider.Interface != "" {
+		return provider.Interface
+	}
+	if schema.Style == "server" {
+		return provider.Name + "Server"
+	}
+	return provider.Name + "Context"
+}
+
+// check returns an error if the schema is malformed: it names something
+// which doesn't exist, or something twice, or a type isn't a type.
+func (schema *Schema) check() error {
+	switch schema.Style {
+	case "", "context", "server":
+	default:
+		return fmt.Errorf("invalid style %q: must be context or server", schema.Style)
+	}
+	for name, path := range schema.Imports {
+		if !token.IsIdentifier(name) || path == "" {
+			return fmt.Errorf("invalid import %s: %q", name, path)
+		}
+	}
+
+	// declared are the interfaces declared so far, by name.
+	declared := map[string]bool{}
+	accessors := map[string]bool{}
+	for _, provider := range schema.Providers {
+		if !token.IsExported(provider.Name) {
+			return fmt.Errorf("invalid provider name %q: must be an exported identifier", provider.Name)
+		}
+		if accessors[provider.Name] {
+			return fmt.Errorf("provider %s is declared twice", provider.Name)
+		}
+		accessors[provider.Name] = true
+		if _, err := parser.ParseExpr(provider.Type); err != nil {
+			return fmt.Errorf("provider %s: invalid type %q: %w", provider.Name, provider.Type, err)
+		}
+		name := schema.interfaceName(provider)
+		if !token.IsIdentifier(name) {
+			return fmt.Errorf("provider %s: invalid interface name %q", provider.Name, name)
+		}
+		if declared[name] {
+			return fmt.Errorf("interface %s is declared twice", name)
+		}
+		declared[name] = true
+		if provider.Propagate != nil &&
+			(provider.Propagate.Header == "" || provider.Propagate.Codec == "") {
+			return fmt.Errorf("provider %s: propagate needs a header and a codec", provider.Name)
+		}
+	}
+	for _, capability := range schema.Capabilities {
+		if !token.IsIdentifier(capability.Name) {
+			return fmt.Errorf("invalid capability name %q", capability.Name)
+		}
+		if declared[capability.Name] {
+			return fmt.Errorf("interface %s is declared twice", capability.Name)
+		}
+		if len(capability.Include) == 0 {
+			return fmt.Errorf("capability %s includes nothing", capability.Name)
+		}
+		for _, include := range capability.Include {
+			if !declared[include] && !accessors[include] {
+				return fmt.Errorf("capability %s includes %s, which is not a provider "+
+					"or a capability declared before it", capability.Name, include)
+			}
+		}
+		declared[capability.Name] = true
+	}
+	return nil
+}
+
+// Declare generates the interfaces the schema describes, and registers its
+// propagated providers.  Generate the rest (see Compose, ComposeLazy and
+// Mock) from the package as type-checked with these declarations; see
+// LoadPackageWith.
+func (g *Generator) Declare(schema *Schema) {
+	names := make([]string, 0, len(schema.Imports))
+	for name := range schema.Imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.imports[schema.Imports[name]] = name
+	}
+	contextType := ""
+	if schema.Style != "server" {
+		contextType = g.importPackage("context", "context") + ".Context"
+	}
+
+	interfaces := map[string]string{}
+	for _, provider := range schema.Providers {
+		name := schema.interfaceName(provider)
+		interfaces[provider.Name] = name
+		g.printDoc(provider.Doc, name+" provides a "+provider.Name+".")
+		g.printf("type %s interface {\n", name)
+		if contextType != "" {
+			g.printf("\t%s\n", contextType)
+		}
+		g.printf("\t%s() %s\n", provider.Name, provider.Type)
+		g.printf("}\n\n")
+	}
+
+	// The capabilities get context.Context from the interfaces they
+	// include; embedding it again would be redundant.
+	for _, capability := range schema.Capabilities {
+		includes := make([]string, len(capability.Include))
+		for i, include := range capability.Include {
+			if name, ok := interfaces[include]; ok {
+				include = name
+			}
+			includes[i] = include
+		}
+		g.printDoc(capability.Doc, capability.Name+" combines "+strings.Join(includes, ", ")+".")
+		g.printf("type %s interface {\n", capability.Name)
+		for _, include := range includes {
+			g.printf("\t%s\n", include)
+		}
+		g.printf("}\n\n")
+	}
+
+	var propagated []SchemaProvider
+	for _, provider := range schema.Providers {
+		if provider.Propagate != nil {
+			propagated = append(propagated, provider)
+		}
+	}
+	if len(propagated) == 0 {
+		return
+	}
+	propagationPkg := g.importPackage("github.com/khan/typed-context/typedcontext/propagation", "propagation")
+	g.printf("func init() {\n")
+	for _, provider := range propagated {
+		codec := provider.Propagate.Codec
+		switch codec {
+		case "string":
+			codec = fmt.Sprintf("%s.StringCodec[%s]()", propagationPkg, provider.Type)
+		case "int":
+			codec = fmt.Sprintf("%s.IntCodec[%s]()", propagationPkg, provider.Type)
+		}
+		g.printf("\t%s.Register(%q, %s.%s, %s)\n", propagationPkg,
+			provider.Propagate.Header, interfaces[provider.Name], provider.Name, codec)
+	}
+	g.printf("}\n\n")
+}
+
+// printDoc prints the given doc comment, or the default if it's empty.
+func (g *Generator) printDoc(doc, defaultDoc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		doc = defaultDoc
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			g.printf("//\n")
+		} else {
+			g.printf("// %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package gen
+
+// This file generates wrappers for combinations of interfaces: the inline
+// composites like interface{ DatabaseContext; LoggerContext } that code
+// requests without naming them.
+
+import "strings"
+
+// wrappedName returns the name of the struct Wrap generates for the
+// combination.
+func wrappedName(composite *Composite) string {
+	return "Wrapped" + composite.Name
+}
+
+// CombinationName returns a name for the combination of interfaces with the
+// given names, like "DatabaseLogger" for DatabaseContext and LoggerContext:
+// their names, in order, each capitalized and without any Context suffix.
+func CombinationName(names []string) string {
+	var name strings.Builder
+	for _, embed := range names {
+		if trimmed := strings.TrimSuffix(embed, "Context"); trimmed != "" {
+			embed = trimmed
+		}
+		name.WriteString(strings.ToUpper(embed[:1]) + embed[1:])
+	}
+	return name.String()
+}
+
+// Wrap generates, for the combination X (see NewCombination), a struct
+// WrappedX implementing all of its interfaces, a constructor WrapX, which
+// takes one argument per accessor (plus a ctx, if they embed
+// context.Context), and a method WithA per accessor A, returning a copy with
+// that provider replaced.
+//
+// WrappedX is a plain value, so building one (or a modified copy, as
+// middleware does when it adds a provider) allocates nothing; only
+// converting it to an interface may, as for any struct.  As with Compose,
+// every provider is a required argument.
+func (g *Generator) Wrap(composite *Composite) {
+	name := composite.Name
+	structName := wrappedName(composite)
+	contextType := ""
+	if composite.HasContext {
+		contextType = g.importPackage("context", "context") + ".Context"
+	}
+	embeds := make([]string, len(composite.Embeds))
+	for i, embed := range composite.Embeds {
+		embeds[i] = g.typeString(embed)
+	}
+	literal := "interface {\n\t" + strings.Join(embeds, "\n\t") + "\n}"
+
+	g.printf("// Wrap%s returns a %s built from the given providers.\n", name, structName)
+	g.printf("func Wrap%s(\n", name)
+	if composite.HasContext {
+		g.printf("\tctx %s,\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s %s,\n", accessor.VarName(), g.typeString(accessor.Type))
+	}
+	g.printf(") %s {\n", structName)
+	g.printf("\treturn %s{\n", structName)
+	if composite.HasContext {
+		g.printf("\t\tContext: ctx,\n")
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t\t%s: %s,\n", accessor.VarName(), accessor.VarName())
+	}
+	g.printf("\t}\n")
+	g.printf("}\n\n")
+
+	g.printf("// %s implements %s\n", structName, strings.Join(embeds, ", "))
+	g.printf("// at once, for code requesting them together.\n")
+	g.printf("type %s struct {\n", structName)
+	if composite.HasContext {
+		g.printf("\t%s\n", contextType)
+	}
+	for _, accessor := range composite.Accessors {
+		g.printf("\t%s %s\n", accessor.VarName(), g.typeString(accessor.Type))
+	}
+	g.printf("}\n\n")
+
+	g.printf("var _ %s = %s{}\n\n", literal, structName)
+
+	for _, accessor := range composite.Accessors {
+		typ := g.typeString(accessor.Type)
+		g.printf("func (c %s) %s() %s {\n", structName, accessor.Name, typ)
+		g.printf("\treturn c.%s\n", accessor.VarName())
+		g.printf("}\n\n")
+
+		g.printf("// With%s returns a copy of c whose %s accessor returns %s.\n",
+			accessor.Name, accessor.Name, accessor.VarName())
+		g.printf("func (c %s) With%s(%s %s) %s {\n",
+			structName, accessor.Name, accessor.VarName(), typ, structName)
+		g.printf("\tc.%s = %s\n", accessor.VarName(), accessor.VarName())
+		g.printf("\treturn c\n")
+		g.printf("}\n\n")
+	}
+}
@@ -0,0 +1,24 @@
+// Package fixture declares a composite context, with constructors generated
+// by typedcontext-gen, for package typedcontext's tests.
+package fixture
+
+import "context"
+
+type Secrets struct{ Name string }
+
+type Database struct{ Name string }
+
+type SecretsContext interface {
+	Secrets() *Secrets
+}
+
+type DatabaseContext interface {
+	Database() *Database
+}
+
+//go:generate go run github.com/khan/typed-context/cmd/typedcontext-gen -type=AppContext -lazy
+type AppContext interface {
+	context.Context
+	SecretsContext
+	DatabaseContext
+}
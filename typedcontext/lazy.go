@@ -0,0 +1,25 @@
+package typedcontext
+
+import "sync"
+
+// Lazy is a provider which is built on first use, for providers which are
+// expensive to build (a secrets store, a database pool) and which many
+// requests never need.  It's safe to use from several goroutines: the
+// provider is built at most once, and every caller sees the same one.
+//
+// Create one with NewLazy; the zero value isn't usable.  The constructors
+// typedcontext-gen generates with -lazy use a Lazy for each provider.
+type Lazy[T any] struct {
+	get func() T
+}
+
+// NewLazy returns a Lazy whose provider is built by init, on the first call
+// to Get.  If init panics, so does that call, and every later one.
+func NewLazy[T any](init func() T) *Lazy[T] {
+	return &Lazy[T]{get: sync.OnceValue(init)}
+}
+
+// Get returns the provider, building it if this is the first call.
+func (lazy *Lazy[T]) Get() T {
+	return lazy.get()
+}
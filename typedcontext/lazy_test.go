@@ -0,0 +1,139 @@
+package typedcontext_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/khan/typed-context/typedcontext"
+	"github.com/khan/typed-context/typedcontext/internal/fixture"
+)
+
+// counted returns a constructor of *P which counts its calls in calls.
+func counted[P any](calls *atomic.Int32) func() *P {
+	return func() *P {
+		calls.Add(1)
+		return new(P)
+	}
+}
+
+// concurrently calls f from several goroutines at once, and waits for them.
+func concurrently(f func()) {
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for range 20 {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			start.Wait()
+			f()
+		}()
+	}
+	start.Done()
+	done.Wait()
+}
+
+func TestLazy(t *testing.T) {
+	var calls atomic.Int32
+	lazy := typedcontext.NewLazy(counted[fixture.Secrets](&calls))
+	if calls.Load() != 0 {
+		t.Fatalf("NewLazy built the provider")
+	}
+
+	var mu sync.Mutex
+	got := map[*fixture.Secrets]bool{}
+	concurrently(func() {
+		secrets := lazy.Get()
+		mu.Lock()
+		got[secrets] = true
+		mu.Unlock()
+	})
+	if calls.Load() != 1 {
+		t.Errorf("built the provider %d times, want once", calls.Load())
+	}
+	if len(got) != 1 {
+		t.Errorf("got %d different providers, want one", len(got))
+	}
+}
+
+// newLazyContext returns a lazy fixture.AppContext, and the counts of calls
+// to its constructors.
+func newLazyContext(ctx context.Context) (appCtx fixture.AppContext, secretsCalls, databaseCalls *atomic.Int32) {
+	secretsCalls, databaseCalls = new(atomic.Int32), new(atomic.Int32)
+	appCtx = fixture.ComposeLazyAppContext(ctx,
+		counted[fixture.Secrets](secretsCalls), counted[fixture.Database](databaseCalls))
+	return appCtx, secretsCalls, databaseCalls
+}
+
+func TestComposeLazy(t *testing.T) {
+	ctx, secretsCalls, databaseCalls := newLazyContext(context.Background())
+	if secretsCalls.Load() != 0 || databaseCalls.Load() != 0 {
+		t.Fatalf("ComposeLazyAppContext built providers")
+	}
+
+	concurrently(func() { ctx.Secrets() })
+	if secretsCalls.Load() != 1 {
+		t.Errorf("built Secrets %d times, want once", secretsCalls.Load())
+	}
+	if databaseCalls.Load() != 0 {
+		t.Errorf("built Database, which nothing used")
+	}
+	if ctx.Secrets() != ctx.Secrets() {
+		t.Errorf("Secrets returned different providers")
+	}
+}
+
+func TestOverrideLazy(t *testing.T) {
+	ctx, secretsCalls, databaseCalls := newLazyContext(context.Background())
+	database := &fixture.Database{Name: "override"}
+	overridden := typedcontext.Override(ctx, typedcontext.With(database))
+	if secretsCalls.Load() != 0 || databaseCalls.Load() != 0 {
+		t.Fatalf("Override built providers: %d Secrets, %d Database",
+			secretsCalls.Load(), databaseCalls.Load())
+	}
+
+	if overridden.Database() != database {
+		t.Errorf("got Database %v, want the override", overridden.Database())
+	}
+	// The kept provider is shared with the original, and built once between
+	// them, however they're called.
+	concurrently(func() {
+		if overridden.Secrets() != ctx.Secrets() {
+			t.Errorf("Override's Secrets isn't the original's")
+		}
+	})
+	if secretsCalls.Load() != 1 {
+		t.Errorf("built Secrets %d times, want once", secretsCalls.Load())
+	}
+	if databaseCalls.Load() != 0 {
+		t.Errorf("built the overridden Database")
+	}
+}
+
+type key struct{}
+
+func TestWithContextLazy(t *testing.T) {
+	ctx, secretsCalls, databaseCalls := newLazyContext(context.Background())
+	inner := context.WithValue(ctx, key{}, "inner")
+	derived := typedcontext.WithContext(ctx, inner)
+	if secretsCalls.Load() != 0 || databaseCalls.Load() != 0 {
+		t.Fatalf("WithContext built providers: %d Secrets, %d Database",
+			secretsCalls.Load(), databaseCalls.Load())
+	}
+
+	if derived.Value(key{}) != "inner" {
+		t.Errorf("WithContext didn't replace the context.Context")
+	}
+	concurrently(func() {
+		if derived.Database() != ctx.Database() {
+			t.Errorf("WithContext's Database isn't the original's")
+		}
+	})
+	if databaseCalls.Load() != 1 {
+		t.Errorf("built Database %d times, want once", databaseCalls.Load())
+	}
+	if secretsCalls.Load() != 0 {
+		t.Errorf("built Secrets, which nothing used")
+	}
+}
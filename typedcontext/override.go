@@ -0,0 +1,207 @@
+package typedcontext
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Option is an override of a single provider, as passed to Override.  Create
+// one with With.
+type Option struct {
+	typ      reflect.Type
+	provider any
+}
+
+// With returns an Option overriding the provider of type P.  P is usually
+// inferred from provider, but must be given explicitly when the accessor
+// returns an interface, like With[DatabaseInterface](db).
+func With[P any](provider P) Option {
+	return Option{reflect.TypeFor[P](), provider}
+}
+
+// Overrides are the options passed to Override, as seen by the override
+// functions registered with RegisterOverride.
+type Overrides struct {
+	providers map[reflect.Type]any
+	// used records the types of the providers which some accessor returned.
+	used map[reflect.Type]bool
+	// context replaces the context.Context, if set; see WithContext.
+	context     context.Context
+	contextUsed bool
+}
+
+// Overridden returns the provider of type P in overrides, if there is one,
+// or current otherwise.  It's called by the code typedcontext-gen generates,
+// once per accessor.
+func Overridden[P any](overrides *Overrides, current P) P {
+	typ := reflect.TypeFor[P]()
+	provider, ok := overrides.providers[typ]
+	if !ok {
+		return current
+	}
+	overrides.used[typ] = true
+	return provider.(P)
+}
+
+// OverriddenLazy is like Overridden, for lazy providers (see Lazy): it
+// returns a Lazy for the provider of type P in overrides, if there is one,
+// or current otherwise, without building either.  It's called by the code
+// typedcontext-gen generates for its -lazy constructors, so that Override
+// and WithContext keep the providers they don't replace lazy.
+func OverriddenLazy[P any](overrides *Overrides, current *Lazy[P]) *Lazy[P] {
+	typ := reflect.TypeFor[P]()
+	provider, ok := overrides.providers[typ]
+	if !ok {
+		return current
+	}
+	overrides.used[typ] = true
+	return &Lazy[P]{get: func() P { return provider.(P) }}
+}
+
+// OverriddenContext returns the context.Context set by WithContext, if any,
+// or current otherwise.  It's called by the code typedcontext-gen generates
+// for composite interfaces which embed context.Context.
+func OverriddenContext(overrides *Overrides, current context.Context) context.Context {
+	overrides.contextUsed = true
+	if overrides.context == nil {
+		return current
+	}
+	return overrides.context
+}
+
+var (
+	_overridesMu sync.RWMutex
+	// _overrides maps each interface type T to its registered override
+	// function, of type func(T, *Overrides) T, and each implementation
+	// registered with RegisterOverrideFor to a function of the same type,
+	// for the interface it was registered for.
+	_overrides = map[reflect.Type]any{}
+)
+
+// RegisterOverride registers the function which Override uses for contexts
+// of the interface type T.  It returns a T with the same context as ctx (if
+// T embeds context.Context), and each provider replaced by the one in
+// overrides, if any, or otherwise the one ctx provides.
+//
+// The constructors generated by typedcontext-gen register one for each
+// composite interface they're generated for, so it's rarely called
+// directly.
+func RegisterOverride[T any](override func(ctx T, overrides *Overrides) T) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("typedcontext.RegisterOverride: %v is not an interface", typ))
+	}
+
+	_overridesMu.Lock()
+	defer _overridesMu.Unlock()
+	_overrides[typ] = override
+}
+
+// RegisterOverrideFor registers the function which Override and WithContext
+// use for contexts of the interface type T whose dynamic type is C, in
+// preference to the one registered for T with RegisterOverride.  It's for
+// implementations which must be overridden differently: the constructors
+// typedcontext-gen generates with -lazy register one for their lazy
+// implementation, which keeps the providers it doesn't replace lazy rather
+// than building them all.
+func RegisterOverrideFor[T, C any](override func(ctx C, overrides *Overrides) T) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("typedcontext.RegisterOverrideFor: %v is not an interface", typ))
+	}
+	impl := reflect.TypeFor[C]()
+	if impl.Kind() == reflect.Interface || !impl.Implements(typ) {
+		panic(fmt.Sprintf("typedcontext.RegisterOverrideFor: %v is not an implementation of %v", impl, typ))
+	}
+
+	_overridesMu.Lock()
+	defer _overridesMu.Unlock()
+	_overrides[impl] = func(ctx T, overrides *Overrides) T {
+		return override(any(ctx).(C), overrides)
+	}
+}
+
+// Override returns a copy of ctx with some of its providers replaced, and the
+// rest (and its context.Context, if T embeds one) kept.  For example, to log
+// with extra fields in part of a request:
+//
+//	ctx = typedcontext.Override(ctx, typedcontext.With(ctx.Logger().With("job", id)))
+//
+// The other providers are those ctx returns when Override is called.
+//
+// T must be a composite interface for which typedcontext-gen generated a
+// constructor, which registers the wrapper Override uses (see
+// RegisterOverride).  Override panics if it didn't, or if some option's
+// provider type isn't returned by any accessor of T.
+func Override[T any](ctx T, opts ...Option) T {
+	override := _override("Override", ctx)
+	overrides := &Overrides{
+		providers: map[reflect.Type]any{},
+		used:      map[reflect.Type]bool{},
+	}
+	for _, opt := range opts {
+		overrides.providers[opt.typ] = opt.provider
+	}
+	result := override(ctx, overrides)
+
+	var unused []string
+	for providerType := range overrides.providers {
+		if !overrides.used[providerType] {
+			unused = append(unused, providerType.String())
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		panic(fmt.Sprintf("typedcontext.Override: %v has no accessor returning %s",
+			reflect.TypeFor[T](), strings.Join(unused, ", ")))
+	}
+	return result
+}
+
+// WithContext returns a copy of ctx whose context.Context is inner, and whose
+// providers are those of ctx.  It's for functions like tracer.Start, which
+// derive a new context.Context from the one they're given: wrapping them with
+// WithContext gives back a context of the same typed interface, rather than
+// a plain context.Context (see typedcontextotel, which does this for
+// OpenTelemetry).  inner should be derived from ctx, or its values, deadline
+// and so on are lost.
+//
+// T must be a composite interface embedding context.Context, for which
+// typedcontext-gen generated a constructor; WithContext panics if it isn't,
+// or if the constructor was generated before WithContext existed.
+func WithContext[T any](ctx T, inner context.Context) T {
+	override := _override("WithContext", ctx)
+	overrides := &Overrides{
+		providers: map[reflect.Type]any{},
+		used:      map[reflect.Type]bool{},
+		context:   inner,
+	}
+	result := override(ctx, overrides)
+	if !overrides.contextUsed {
+		panic(fmt.Sprintf("typedcontext.WithContext: %v doesn't embed context.Context, "+
+			"or its constructor needs regenerating with typedcontext-gen", reflect.TypeFor[T]()))
+	}
+	return result
+}
+
+// _override returns the function registered for ctx's dynamic type with
+// RegisterOverrideFor, if any, or else for T with RegisterOverride, or
+// panics, on behalf of the named function, if there is none.
+func _override[T any](caller string, ctx T) func(T, *Overrides) T {
+	typ := reflect.TypeFor[T]()
+	_overridesMu.RLock()
+	override, ok := _overrides[reflect.TypeOf(ctx)].(func(T, *Overrides) T)
+	if !ok {
+		override, ok = _overrides[typ].(func(T, *Overrides) T)
+	}
+	_overridesMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("typedcontext.%s: no override registered for %v; "+
+			"generate its constructor with typedcontext-gen", caller, typ))
+	}
+	return override
+}
@@ -0,0 +1,121 @@
+package typedcontext_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/khan/typed-context/typedcontext"
+	"github.com/khan/typed-context/typedcontext/internal/fixture"
+)
+
+// handWritten is an implementation of fixture.AppContext which
+// typedcontext-gen didn't generate, and which registers no override of its
+// own.
+type handWritten struct {
+	context.Context
+	secrets  *fixture.Secrets
+	database *fixture.Database
+}
+
+func (ctx handWritten) Secrets() *fixture.Secrets   { return ctx.secrets }
+func (ctx handWritten) Database() *fixture.Database { return ctx.database }
+
+// registered is likewise, but registers its own override.
+type registered struct{ handWritten }
+
+func init() {
+	typedcontext.RegisterOverrideFor(func(ctx registered, overrides *typedcontext.Overrides) fixture.AppContext {
+		return registered{handWritten{
+			Context:  typedcontext.OverriddenContext(overrides, ctx.Context),
+			secrets:  typedcontext.Overridden(overrides, ctx.secrets),
+			database: typedcontext.Overridden(overrides, ctx.database),
+		}}
+	})
+}
+
+// wantPanic fails the test unless f panics with a message containing want.
+func wantPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		got, _ := recover().(string)
+		if !strings.Contains(got, want) {
+			t.Errorf("got panic %q, want one containing %q", got, want)
+		}
+	}()
+	f()
+}
+
+func TestOverrideDynamicType(t *testing.T) {
+	secrets := &fixture.Secrets{Name: "original"}
+	database := &fixture.Database{Name: "override"}
+	var ctx fixture.AppContext = registered{handWritten{context.Background(), secrets, nil}}
+
+	overridden := typedcontext.Override(ctx, typedcontext.With(database))
+	got, ok := overridden.(registered)
+	if !ok {
+		t.Fatalf("got a %T, want the registered type's override to make a registered", overridden)
+	}
+	if got.secrets != secrets || got.database != database {
+		t.Errorf("got providers %v, %v; want %v, %v", got.secrets, got.database, secrets, database)
+	}
+}
+
+func TestOverrideFallback(t *testing.T) {
+	secrets := &fixture.Secrets{Name: "original"}
+	database := &fixture.Database{Name: "override"}
+	var ctx fixture.AppContext = handWritten{context.Background(), secrets, nil}
+
+	// With no override registered for handWritten, Override uses the one
+	// typedcontext-gen generated for AppContext.
+	overridden := typedcontext.Override(ctx, typedcontext.With(database))
+	if typ := reflect.TypeOf(overridden); typ == reflect.TypeFor[handWritten]() {
+		t.Errorf("got a %v, want the generated implementation", typ)
+	}
+	if overridden.Secrets() != secrets || overridden.Database() != database {
+		t.Errorf("got providers %v, %v; want %v, %v",
+			overridden.Secrets(), overridden.Database(), secrets, database)
+	}
+
+	inner := context.WithValue(ctx, key{}, "inner")
+	if derived := typedcontext.WithContext(ctx, inner); derived.Value(key{}) != "inner" {
+		t.Errorf("WithContext didn't replace the context.Context")
+	}
+}
+
+// Unrelated is a provider type no accessor of fixture.AppContext returns.
+type Unrelated struct{}
+
+// UnregisteredContext is a composite with no generated constructor.
+type UnregisteredContext interface {
+	context.Context
+	fixture.SecretsContext
+}
+
+func TestOverridePanics(t *testing.T) {
+	ctx := fixture.ComposeAppContext(context.Background(), &fixture.Secrets{}, &fixture.Database{})
+	wantPanic(t, "typedcontext.Override: fixture.AppContext has no accessor returning *typedcontext_test.Unrelated", func() {
+		typedcontext.Override(ctx, typedcontext.With(&Unrelated{}), typedcontext.With(&fixture.Secrets{}))
+	})
+
+	var unregistered UnregisteredContext = ctx
+	wantPanic(t, "typedcontext.Override: no override registered for typedcontext_test.UnregisteredContext", func() {
+		typedcontext.Override(unregistered, typedcontext.With(&fixture.Secrets{}))
+	})
+	wantPanic(t, "typedcontext.WithContext: no override registered for typedcontext_test.UnregisteredContext", func() {
+		typedcontext.WithContext(unregistered, context.Background())
+	})
+
+	wantPanic(t, "typedcontext.RegisterOverride: *fixture.Secrets is not an interface", func() {
+		typedcontext.RegisterOverride(func(ctx *fixture.Secrets, overrides *typedcontext.Overrides) *fixture.Secrets {
+			return ctx
+		})
+	})
+	wantPanic(t, "typedcontext.RegisterOverrideFor: typedcontext_test.Unrelated is not an implementation of fixture.AppContext", func() {
+		typedcontext.RegisterOverrideFor(func(ctx Unrelated, overrides *typedcontext.Overrides) fixture.AppContext {
+			return nil
+		})
+	})
+}
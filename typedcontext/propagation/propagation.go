@@ -0,0 +1,228 @@
+// Package propagation carries selected providers of a typed context across
+// process boundaries, in HTTP headers or gRPC metadata.
+//
+// Value-like providers -- a request ID, the acting user's key, the locale --
+// are registered once, with the capability interface that provides them and
+// a codec:
+//
+//	func init() {
+//		propagation.Register("x-request-id", RequestIDContext.RequestID,
+//			propagation.StringCodec[RequestID]())
+//	}
+//
+// The calling service injects whichever of them its context provides into
+// the outgoing request:
+//
+//	err := propagation.Inject(ctx, propagation.HTTPHeader(req.Header))
+//
+// and the receiving service extracts them, and builds its own typed context
+// from them, either by passing them to its constructor:
+//
+//	values, err := propagation.Extract(propagation.HTTPHeader(r.Header))
+//	requestID, ok := propagation.Get[RequestID](values)
+//
+// or by overriding the providers of a default context (see
+// typedcontext.Override):
+//
+//	ctx = typedcontext.Override(ctx, values.Options()...)
+//
+// Only register providers which are plain values: the point of a typed
+// context is that its providers are built by the service that uses them, so
+// clients, secrets and the like should never be propagated.
+package propagation
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/khan/typed-context/typedcontext"
+)
+
+// Carrier is where propagated providers are written and read: the headers of
+// a request, say.  See HTTPHeader and GRPCMetadata.
+type Carrier interface {
+	// Get returns the value of the given key, or "" if there is none.
+	Get(key string) string
+	// Set sets the value of the given key.
+	Set(key, value string)
+}
+
+// HTTPHeader returns a Carrier reading and writing the given HTTP headers.
+func HTTPHeader(header http.Header) Carrier {
+	return httpCarrier(header)
+}
+
+type httpCarrier http.Header
+
+func (carrier httpCarrier) Get(key string) string { return http.Header(carrier).Get(key) }
+func (carrier httpCarrier) Set(key, value string) { http.Header(carrier).Set(key, value) }
+
+// GRPCMetadata returns a Carrier reading and writing the given gRPC
+// metadata; use metadata.FromIncomingContext on the server, and
+// metadata.NewOutgoingContext with a fresh metadata.MD on the client.
+func GRPCMetadata(md metadata.MD) Carrier {
+	return grpcCarrier(md)
+}
+
+type grpcCarrier metadata.MD
+
+func (carrier grpcCarrier) Get(key string) string {
+	if values := metadata.MD(carrier).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (carrier grpcCarrier) Set(key, value string) { metadata.MD(carrier).Set(key, value) }
+
+// Codec encodes and decodes providers of type P as header values.
+type Codec[P any] struct {
+	Encode func(P) (string, error)
+	Decode func(string) (P, error)
+}
+
+// StringCodec returns a Codec for providers whose type is a string, like
+// `type RequestID string`.
+func StringCodec[P ~string]() Codec[P] {
+	return Codec[P]{
+		Encode: func(provider P) (string, error) { return string(provider), nil },
+		Decode: func(value string) (P, error) { return P(value), nil },
+	}
+}
+
+// IntCodec returns a Codec for providers whose type is an integer, like
+// `type UserKey int64`.  Decoding a value out of the range of the type is an
+// error, rather than wrapping around.
+func IntCodec[P ~int | ~int32 | ~int64]() Codec[P] {
+	bits := reflect.TypeFor[P]().Bits()
+	return Codec[P]{
+		Encode: func(provider P) (string, error) { return strconv.FormatInt(int64(provider), 10), nil },
+		Decode: func(value string) (P, error) {
+			n, err := strconv.ParseInt(value, 10, bits)
+			if err != nil {
+				return 0, err
+			}
+			return P(n), nil
+		},
+	}
+}
+
+// _field is a registered provider.
+type _field struct {
+	header string
+	// typ is the type of the provider.
+	typ reflect.Type
+	// inject encodes the provider of ctx, if ctx has the capability.
+	inject func(ctx any) (string, bool, error)
+	// extract decodes the provider.
+	extract func(value string) (any, typedcontext.Option, error)
+}
+
+var (
+	_fieldsMu sync.RWMutex
+	// _fields are the registered providers, sorted by header.
+	_fields []_field
+)
+
+// Register adds the provider of type P, which contexts with the capability C
+// provide via accessor (usually a method expression, like
+// RequestIDContext.RequestID), to those propagated in the given header.
+//
+// Register panics if C isn't an interface, or if the header or provider type
+// is already registered.
+func Register[C, P any](header string, accessor func(C) P, codec Codec[P]) {
+	capability := reflect.TypeFor[C]()
+	if capability.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("propagation.Register: %v is not an interface", capability))
+	}
+	field := _field{
+		header: strings.ToLower(header),
+		typ:    reflect.TypeFor[P](),
+		inject: func(ctx any) (string, bool, error) {
+			typed, ok := ctx.(C)
+			if !ok {
+				return "", false, nil
+			}
+			value, err := codec.Encode(accessor(typed))
+			return value, true, err
+		},
+		extract: func(value string) (any, typedcontext.Option, error) {
+			provider, err := codec.Decode(value)
+			return provider, typedcontext.With(provider), err
+		},
+	}
+
+	_fieldsMu.Lock()
+	defer _fieldsMu.Unlock()
+	for _, other := range _fields {
+		if other.header == field.header || other.typ == field.typ {
+			panic(fmt.Sprintf("propagation.Register: %s (%v) is already registered as %s (%v)",
+				header, field.typ, other.header, other.typ))
+		}
+	}
+	_fields = append(_fields, field)
+	sort.Slice(_fields, func(i, j int) bool { return _fields[i].header < _fields[j].header })
+}
+
+// Inject writes to carrier each registered provider that ctx has the
+// capability for.
+func Inject(ctx any, carrier Carrier) error {
+	_fieldsMu.RLock()
+	defer _fieldsMu.RUnlock()
+	for _, field := range _fields {
+		value, ok, err := field.inject(ctx)
+		if err != nil {
+			return fmt.Errorf("propagation: encoding %s: %w", field.header, err)
+		}
+		if ok {
+			carrier.Set(field.header, value)
+		}
+	}
+	return nil
+}
+
+// Values are the providers read from a carrier by Extract.
+type Values struct {
+	providers map[reflect.Type]any
+	options   []typedcontext.Option
+}
+
+// Extract reads from carrier each registered provider it holds.
+func Extract(carrier Carrier) (*Values, error) {
+	values := &Values{providers: map[reflect.Type]any{}}
+	_fieldsMu.RLock()
+	defer _fieldsMu.RUnlock()
+	for _, field := range _fields {
+		value := carrier.Get(field.header)
+		if value == "" {
+			continue
+		}
+		provider, option, err := field.extract(value)
+		if err != nil {
+			return nil, fmt.Errorf("propagation: decoding %s: %w", field.header, err)
+		}
+		values.providers[field.typ] = provider
+		values.options = append(values.options, option)
+	}
+	return values, nil
+}
+
+// Get returns the provider of type P in values, if there was one.
+func Get[P any](values *Values) (P, bool) {
+	provider, ok := values.providers[reflect.TypeFor[P]()].(P)
+	return provider, ok
+}
+
+// Options returns the providers in values as options for
+// typedcontext.Override.  Each provider type must be returned by some
+// accessor of the context being overridden.
+func (values *Values) Options() []typedcontext.Option {
+	return values.options
+}
@@ -0,0 +1,161 @@
+package propagation_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/khan/typed-context/typedcontext/propagation"
+)
+
+type RequestID string
+
+type UserKey int32
+
+type RequestIDContext interface {
+	RequestID() RequestID
+}
+
+type UserKeyContext interface {
+	UserKey() UserKey
+}
+
+func init() {
+	propagation.Register("X-Request-ID", RequestIDContext.RequestID, propagation.StringCodec[RequestID]())
+	propagation.Register("x-user-key", UserKeyContext.UserKey, propagation.IntCodec[UserKey]())
+}
+
+// requestContext provides both registered providers.
+type requestContext struct {
+	context.Context
+	requestID RequestID
+	userKey   UserKey
+}
+
+func (ctx requestContext) RequestID() RequestID { return ctx.requestID }
+func (ctx requestContext) UserKey() UserKey     { return ctx.userKey }
+
+// anonymousContext provides just a request ID.
+type anonymousContext struct {
+	context.Context
+	requestID RequestID
+}
+
+func (ctx anonymousContext) RequestID() RequestID { return ctx.requestID }
+
+// roundTrip injects ctx into carrier, and extracts it back out.
+func roundTrip(t *testing.T, ctx any, carrier propagation.Carrier) *propagation.Values {
+	t.Helper()
+	if err := propagation.Inject(ctx, carrier); err != nil {
+		t.Fatal(err)
+	}
+	values, err := propagation.Extract(carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// checkValues checks that values holds the given request ID, and the given
+// user key if it's nonzero, and no user key otherwise.
+func checkValues(t *testing.T, values *propagation.Values, requestID RequestID, userKey UserKey) {
+	t.Helper()
+	if got, ok := propagation.Get[RequestID](values); !ok || got != requestID {
+		t.Errorf("got request ID %q, %v; want %q", got, ok, requestID)
+	}
+	got, ok := propagation.Get[UserKey](values)
+	if userKey == 0 && ok || userKey != 0 && (!ok || got != userKey) {
+		t.Errorf("got user key %d, %v; want %d", got, ok, userKey)
+	}
+	want := 1
+	if userKey != 0 {
+		want = 2
+	}
+	if len(values.Options()) != want {
+		t.Errorf("got %d options, want %d", len(values.Options()), want)
+	}
+}
+
+func TestHTTPHeader(t *testing.T) {
+	header := http.Header{}
+	values := roundTrip(t, requestContext{context.Background(), "req-1", 42}, propagation.HTTPHeader(header))
+	checkValues(t, values, "req-1", 42)
+	if header.Get("X-Request-Id") != "req-1" || header.Get("X-User-Key") != "42" {
+		t.Errorf("got headers %v", header)
+	}
+
+	// Providers the context lacks aren't sent.
+	header = http.Header{}
+	values = roundTrip(t, anonymousContext{context.Background(), "req-2"}, propagation.HTTPHeader(header))
+	checkValues(t, values, "req-2", 0)
+	if _, ok := header["X-User-Key"]; ok {
+		t.Errorf("got a user key header for a context without one: %v", header)
+	}
+}
+
+func TestGRPCMetadata(t *testing.T) {
+	md := metadata.MD{}
+	values := roundTrip(t, requestContext{context.Background(), "req-1", -7}, propagation.GRPCMetadata(md))
+	checkValues(t, values, "req-1", -7)
+	if got := md.Get("x-request-id"); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("got metadata %v", md)
+	}
+
+	md = metadata.MD{}
+	values = roundTrip(t, anonymousContext{context.Background(), "req-2"}, propagation.GRPCMetadata(md))
+	checkValues(t, values, "req-2", 0)
+}
+
+func TestIntCodec(t *testing.T) {
+	codec := propagation.IntCodec[UserKey]()
+	if got, err := codec.Decode("2147483647"); err != nil || got != 2147483647 {
+		t.Errorf("Decode(max int32) = %d, %v", got, err)
+	}
+	for _, value := range []string{"2147483648", "-2147483649", "1e3", "key"} {
+		if got, err := codec.Decode(value); err == nil {
+			t.Errorf("Decode(%q) = %d, want an error", value, got)
+		}
+	}
+
+	// Extract reports it.
+	header := http.Header{"X-User-Key": {"4294967296"}}
+	_, err := propagation.Extract(propagation.HTTPHeader(header))
+	if err == nil || !strings.Contains(err.Error(), "propagation: decoding x-user-key: ") {
+		t.Errorf("got error %v, want one decoding x-user-key", err)
+	}
+}
+
+// wantPanic fails the test unless f panics with a message containing want.
+func wantPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		got, _ := recover().(string)
+		if !strings.Contains(got, want) {
+			t.Errorf("got panic %q, want one containing %q", got, want)
+		}
+	}()
+	f()
+}
+
+type Locale string
+
+type LocaleContext interface {
+	Locale() Locale
+}
+
+func TestRegisterPanics(t *testing.T) {
+	wantPanic(t, "propagation.Register: x-request-id (propagation_test.Locale) is already registered as x-request-id (propagation_test.RequestID)", func() {
+		// Headers are case-insensitive.
+		propagation.Register("x-request-id", LocaleContext.Locale, propagation.StringCodec[Locale]())
+	})
+	wantPanic(t, "propagation.Register: x-correlation-id (propagation_test.RequestID) is already registered as x-request-id (propagation_test.RequestID)", func() {
+		propagation.Register("x-correlation-id", RequestIDContext.RequestID, propagation.StringCodec[RequestID]())
+	})
+	wantPanic(t, "propagation.Register: propagation_test.requestContext is not an interface", func() {
+		propagation.Register("x-locale", requestContext.RequestID, propagation.StringCodec[RequestID]())
+	})
+}
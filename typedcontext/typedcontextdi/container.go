@@ -0,0 +1,346 @@
+// Package typedcontextdi builds typed contexts from registered providers,
+// rather than hand-wired functions like the examples' GetProdServer, which
+// call each provider's constructor in the right order and pass the results
+// to the context's constructor.
+//
+// Each provider is registered under its accessor interface, with a
+// constructor, which may itself take a typed context, narrower than the
+// one being built, of the providers it depends on.  The contexts are built
+// by the constructors typedcontext-gen generates, registered with Compose:
+//
+//	container := typedcontextdi.New[AppContext]()
+//	container.Provide((*ConfigContext)(nil), LoadConfig)     // func() (*Config, error)
+//	container.Provide((*LoggerContext)(nil), NewLogger)      // func(ctx ConfigContext) *Logger
+//	container.Provide((*DatabaseContext)(nil), OpenDatabase) // func(ctx ConfigLoggerContext) (*Database, error)
+//	container.Compose(ComposeAppContext, ComposeConfigLoggerContext, ComposeConfigContext)
+//	if err := container.Check(); err != nil {
+//		log.Fatal(err) // a provider is missing, say
+//	}
+//	...
+//	ctx, err := container.Build(context.Background())
+//
+// Build resolves the providers in dependency order, building each at most
+// once per call, and the contexts they take along the way.  Check reports,
+// without calling any constructors, what Build would be missing: providers
+// not registered, contexts without constructors, and dependency cycles.
+package typedcontextdi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// _contextType and _errorType are the types of context.Context and error.
+var (
+	_contextType = reflect.TypeFor[context.Context]()
+	_errorType   = reflect.TypeFor[error]()
+)
+
+// _contextMethods are the methods of context.Context, which aren't
+// accessors.
+var _contextMethods = map[string]bool{
+	"Deadline": true,
+	"Done":     true,
+	"Err":      true,
+	"Value":    true,
+}
+
+// _provider is a provider registered with Provide.
+type _provider struct {
+	// accessor is the accessor interface it was registered under, like
+	// LoggerContext.
+	accessor reflect.Type
+	// constructor builds it; it takes no arguments, or a context, and
+	// returns the provider, and maybe an error.
+	constructor reflect.Value
+}
+
+// Container holds the providers, and the constructors of contexts, from
+// which it builds contexts of the interface T.  Create one with New; the
+// zero value isn't usable.  It's safe to use from several goroutines.
+type Container[T any] struct {
+	mu sync.RWMutex
+	// providers are the registered providers, by the type their accessor
+	// returns.
+	providers map[reflect.Type]_provider
+	// composers are the registered constructors of contexts, by the
+	// interface they return.
+	composers map[reflect.Type]reflect.Value
+}
+
+// New returns an empty container for building contexts of the interface T.
+// It panics if T isn't an interface.
+func New[T any]() *Container[T] {
+	if typ := reflect.TypeFor[T](); typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("typedcontextdi.New: %v is not an interface", typ))
+	}
+	return &Container[T]{
+		providers: map[reflect.Type]_provider{},
+		composers: map[reflect.Type]reflect.Value{},
+	}
+}
+
+// _interfaceType returns the interface type described by want, which is
+// either a reflect.Type or a nil pointer to the interface, like
+// (*LoggerContext)(nil).
+func _interfaceType(want any) (reflect.Type, error) {
+	typ, ok := want.(reflect.Type)
+	if !ok {
+		typ = reflect.TypeOf(want)
+		if typ == nil || typ.Kind() != reflect.Pointer {
+			return nil, fmt.Errorf("want a reflect.Type or a pointer to an "+
+				"interface, like (*LoggerContext)(nil); got %T", want)
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("%v is not an interface", typ)
+	}
+	return typ, nil
+}
+
+// Provide registers constructor as the way to build the provider returned
+// by the accessor interface given, which is a nil pointer to the interface,
+// like (*LoggerContext)(nil), or its reflect.Type.  The interface must have
+// exactly one accessor (a method, other than those of context.Context,
+// taking no arguments and returning one value).
+//
+// constructor is a function taking either nothing, or a context (a
+// context.Context, given the base context passed to Build, or a typed
+// context, which the container builds first), and returning the provider
+// (or something assignable to its type), and optionally an error.
+//
+// Provide panics if any of these doesn't hold, or if the accessor's type
+// already has a provider.
+func (container *Container[T]) Provide(accessor any, constructor any) {
+	accessorType, err := _interfaceType(accessor)
+	if err != nil {
+		panic("typedcontextdi.Provide: " + err.Error())
+	}
+	var accessors []reflect.Method
+	for i := 0; i < accessorType.NumMethod(); i++ {
+		method := accessorType.Method(i)
+		if !_contextMethods[method.Name] && method.Type.NumIn() == 0 && method.Type.NumOut() == 1 {
+			accessors = append(accessors, method)
+		}
+	}
+	if len(accessors) != 1 {
+		panic(fmt.Sprintf("typedcontextdi.Provide: %v has %d accessors; want exactly one",
+			accessorType, len(accessors)))
+	}
+	providerType := accessors[0].Type.Out(0)
+
+	value := reflect.ValueOf(constructor)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		panic(fmt.Sprintf("typedcontextdi.Provide: constructor for %v is a %T, not a function",
+			accessorType, constructor))
+	}
+	funcType := value.Type()
+	if funcType.NumIn() > 1 || funcType.IsVariadic() ||
+		funcType.NumIn() == 1 && funcType.In(0).Kind() != reflect.Interface {
+		panic(fmt.Sprintf("typedcontextdi.Provide: constructor for %v must take "+
+			"nothing or a context; it's a %v", accessorType, funcType))
+	}
+	if funcType.NumOut() == 0 || funcType.NumOut() > 2 ||
+		!funcType.Out(0).AssignableTo(providerType) ||
+		funcType.NumOut() == 2 && funcType.Out(1) != _errorType {
+		panic(fmt.Sprintf("typedcontextdi.Provide: constructor for %v must return "+
+			"a %v, and optionally an error; it's a %v", accessorType, providerType, funcType))
+	}
+
+	container.mu.Lock()
+	defer container.mu.Unlock()
+	if existing, ok := container.providers[providerType]; ok {
+		panic(fmt.Sprintf("typedcontextdi.Provide: %v already has a provider, registered for %v",
+			providerType, existing.accessor))
+	}
+	container.providers[providerType] = _provider{accessorType, value}
+}
+
+// Compose registers the given constructors of contexts, typically those
+// typedcontext-gen generates, like ComposeAppContext.  Each must return
+// an interface, and take, optionally, a context.Context, and then providers
+// (as registered with Provide).  Every context the container builds, of T or
+// taken by a provider's constructor, needs one.
+//
+// Compose panics if a constructor isn't of that form, or if its interface
+// already has one.
+func (container *Container[T]) Compose(composers ...any) {
+	container.mu.Lock()
+	defer container.mu.Unlock()
+	for _, composer := range composers {
+		value := reflect.ValueOf(composer)
+		if value.Kind() != reflect.Func || value.IsNil() {
+			panic(fmt.Sprintf("typedcontextdi.Compose: %T is not a function", composer))
+		}
+		funcType := value.Type()
+		if funcType.NumOut() != 1 || funcType.Out(0).Kind() != reflect.Interface || funcType.IsVariadic() {
+			panic(fmt.Sprintf("typedcontextdi.Compose: %v doesn't return just an interface", funcType))
+		}
+		result := funcType.Out(0)
+		if _, ok := container.composers[result]; ok {
+			panic(fmt.Sprintf("typedcontextdi.Compose: %v already has a constructor", result))
+		}
+		container.composers[result] = value
+	}
+}
+
+// _build is the state of a call to Build or Check.
+type _build struct {
+	providers map[reflect.Type]_provider
+	composers map[reflect.Type]reflect.Value
+	// base is the context passed to Build; it's nil for Check, in which
+	// case we call no constructors.
+	base context.Context
+	// built are the providers built so far, by type.
+	built map[reflect.Type]reflect.Value
+	// resolving are the providers we're in the middle of building,
+	// outermost first, for detecting cycles.  (Only providers can form
+	// them: a context depends on nothing but providers.)
+	resolving []_provider
+}
+
+// _enter records that we're building the given provider, or returns an
+// error if that's a cycle.
+func (b *_build) _enter(provider _provider) error {
+	for i, outer := range b.resolving {
+		if outer.accessor == provider.accessor {
+			var names []string
+			for _, outer := range append(b.resolving[i:], provider) {
+				names = append(names, outer.accessor.String())
+			}
+			return fmt.Errorf("dependency cycle: %s", strings.Join(names, " -> "))
+		}
+	}
+	b.resolving = append(b.resolving, provider)
+	return nil
+}
+
+// _leave undoes _enter.
+func (b *_build) _leave() {
+	b.resolving = b.resolving[:len(b.resolving)-1]
+}
+
+// _context builds a context of the given interface with its registered
+// constructor, building the providers it takes first.
+func (b *_build) _context(typ reflect.Type) (reflect.Value, error) {
+	if typ == _contextType {
+		return reflect.ValueOf(&b.base).Elem(), nil
+	}
+	composer, ok := b.composers[typ]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no constructor for %v; "+
+			"generate one with typedcontext-gen, and register it with Compose", typ)
+	}
+
+	funcType := composer.Type()
+	args := make([]reflect.Value, funcType.NumIn())
+	for i := range args {
+		var err error
+		if funcType.In(i) == _contextType {
+			args[i], err = b._context(_contextType)
+		} else {
+			args[i], err = b._provider(funcType.In(i))
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	if b.base == nil {
+		return reflect.Zero(typ), nil
+	}
+	return composer.Call(args)[0], nil
+}
+
+// _provider builds the provider of the given type with its registered
+// constructor, building the context it takes first, or returns the one
+// already built.
+func (b *_build) _provider(typ reflect.Type) (reflect.Value, error) {
+	if value, ok := b.built[typ]; ok {
+		return value, nil
+	}
+	provider, ok := b.providers[typ]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no provider of %v; register one with Provide", typ)
+	}
+	if err := b._enter(provider); err != nil {
+		return reflect.Value{}, err
+	}
+	defer b._leave()
+
+	funcType := provider.constructor.Type()
+	var args []reflect.Value
+	if funcType.NumIn() == 1 {
+		ctx, err := b._context(funcType.In(0))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("providing %v: %w", provider.accessor, err)
+		}
+		args = append(args, ctx)
+	}
+	if b.base == nil {
+		b.built[typ] = reflect.Zero(typ)
+		return b.built[typ], nil
+	}
+
+	results := provider.constructor.Call(args)
+	if len(results) == 2 && !results[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("providing %v: %w",
+			provider.accessor, results[1].Interface().(error))
+	}
+	value := reflect.New(typ).Elem()
+	value.Set(results[0])
+	b.built[typ] = value
+	return value, nil
+}
+
+// _start returns the state for a call to Build or Check, with the given
+// base context.
+func (container *Container[T]) _start(base context.Context) *_build {
+	container.mu.RLock()
+	defer container.mu.RUnlock()
+	b := &_build{
+		providers: make(map[reflect.Type]_provider, len(container.providers)),
+		composers: make(map[reflect.Type]reflect.Value, len(container.composers)),
+		base:      base,
+		built:     map[reflect.Type]reflect.Value{},
+	}
+	for typ, provider := range container.providers {
+		b.providers[typ] = provider
+	}
+	for typ, composer := range container.composers {
+		b.composers[typ] = composer
+	}
+	return b
+}
+
+// Check returns an error if Build would fail for lack of a provider or a
+// constructor of a context, or because of a dependency cycle, without
+// calling any constructors.  Call it at startup, to report mistakes in the
+// wiring before the first request.
+func (container *Container[T]) Check() error {
+	if _, err := container._start(nil)._context(reflect.TypeFor[T]()); err != nil {
+		return fmt.Errorf("typedcontextdi: %w", err)
+	}
+	return nil
+}
+
+// Build returns a context of the interface T, whose context.Context (if it
+// has one) is base, building its providers, and the contexts they take,
+// with the registered constructors.  Each provider is built at most once
+// per call, even if several others depend on it.  It returns an error if
+// anything is missing (see Check), or if a provider's constructor does.
+func (container *Container[T]) Build(base context.Context) (T, error) {
+	if base == nil {
+		panic("typedcontextdi.Build: nil base context")
+	}
+	value, err := container._start(base)._context(reflect.TypeFor[T]())
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("typedcontextdi: %w", err)
+	}
+	ctx, _ := value.Interface().(T) // nil if the constructor returned nil
+	return ctx, nil
+}
@@ -0,0 +1,307 @@
+package typedcontextdi_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/khan/typed-context/typedcontext/typedcontextdi"
+)
+
+type Config struct{ dsn string }
+type Logger struct{ config *Config }
+type Database struct {
+	dsn    string
+	logger *Logger
+}
+
+type ConfigContext interface{ Config() *Config }
+type LoggerContext interface{ Logger() *Logger }
+type DatabaseContext interface{ Database() *Database }
+
+type ConfigLoggerContext interface {
+	context.Context
+	ConfigContext
+	LoggerContext
+}
+
+type AppContext interface {
+	context.Context
+	ConfigContext
+	LoggerContext
+	DatabaseContext
+}
+
+// The constructors of the contexts, as typedcontext-gen would generate them.
+
+type configLoggerContext struct {
+	context.Context
+	config *Config
+	logger *Logger
+}
+
+func (ctx configLoggerContext) Config() *Config { return ctx.config }
+func (ctx configLoggerContext) Logger() *Logger { return ctx.logger }
+
+func ComposeConfigLoggerContext(ctx context.Context, config *Config, logger *Logger) ConfigLoggerContext {
+	return configLoggerContext{ctx, config, logger}
+}
+
+type appContext struct {
+	context.Context
+	config   *Config
+	logger   *Logger
+	database *Database
+}
+
+func (ctx appContext) Config() *Config     { return ctx.config }
+func (ctx appContext) Logger() *Logger     { return ctx.logger }
+func (ctx appContext) Database() *Database { return ctx.database }
+
+func ComposeAppContext(ctx context.Context, config *Config, logger *Logger, database *Database) AppContext {
+	return appContext{ctx, config, logger, database}
+}
+
+type configContext struct{ config *Config }
+
+func (ctx configContext) Config() *Config { return ctx.config }
+
+func ComposeConfigContext(config *Config) ConfigContext {
+	return configContext{config}
+}
+
+// calls counts the calls to each constructor.
+type calls map[string]int
+
+func (c calls) loadConfig() (*Config, error) {
+	c["loadConfig"]++
+	return &Config{dsn: "db://test"}, nil
+}
+
+func (c calls) newLogger(ctx ConfigContext) *Logger {
+	c["newLogger"]++
+	return &Logger{ctx.Config()}
+}
+
+func (c calls) openDatabase(ctx ConfigLoggerContext) (*Database, error) {
+	c["openDatabase"]++
+	return &Database{ctx.Config().dsn, ctx.Logger()}, nil
+}
+
+// newContainer returns a container building AppContext, with all its
+// providers and constructors registered.
+func newContainer(c calls) *typedcontextdi.Container[AppContext] {
+	container := typedcontextdi.New[AppContext]()
+	container.Provide((*ConfigContext)(nil), c.loadConfig)
+	container.Provide((*LoggerContext)(nil), c.newLogger)
+	container.Provide((*DatabaseContext)(nil), c.openDatabase)
+	container.Compose(ComposeAppContext, ComposeConfigLoggerContext, ComposeConfigContext)
+	return container
+}
+
+// wantError fails the test unless err is non-nil and contains want.
+func wantError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want one containing %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("got error %q, want one containing %q", err, want)
+	}
+}
+
+type key struct{}
+
+func TestBuild(t *testing.T) {
+	c := calls{}
+	container := newContainer(c)
+	if err := container.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 0 {
+		t.Errorf("Check called constructors %v", c)
+	}
+
+	base := context.WithValue(context.Background(), key{}, "base")
+	ctx, err := container.Build(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Value(key{}) != "base" {
+		t.Errorf("built context doesn't wrap the base context")
+	}
+	if ctx.Database().logger != ctx.Logger() || ctx.Logger().config != ctx.Config() {
+		t.Errorf("providers were built more than once: got %+v", ctx)
+	}
+	if ctx.Database().dsn != "db://test" {
+		t.Errorf("got dsn %q, want db://test", ctx.Database().dsn)
+	}
+	want := calls{"loadConfig": 1, "newLogger": 1, "openDatabase": 1}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got constructor calls %v, want %v", c, want)
+	}
+
+	// Each Build starts afresh.
+	if _, err := container.Build(base); err != nil {
+		t.Fatal(err)
+	}
+	if c["loadConfig"] != 2 {
+		t.Errorf("got %d calls to loadConfig after two builds, want 2", c["loadConfig"])
+	}
+}
+
+func TestMissingProvider(t *testing.T) {
+	c := calls{}
+	container := typedcontextdi.New[AppContext]()
+	container.Provide((*ConfigContext)(nil), c.loadConfig)
+	container.Provide((*LoggerContext)(nil), c.newLogger)
+	container.Compose(ComposeAppContext, ComposeConfigLoggerContext, ComposeConfigContext)
+
+	want := "typedcontextdi: no provider of *typedcontextdi_test.Database; register one with Provide"
+	wantError(t, container.Check(), want)
+	_, err := container.Build(context.Background())
+	wantError(t, err, want)
+}
+
+func TestMissingComposer(t *testing.T) {
+	c := calls{}
+	container := typedcontextdi.New[AppContext]()
+	container.Provide((*ConfigContext)(nil), c.loadConfig)
+	container.Provide((*LoggerContext)(nil), c.newLogger)
+	container.Provide((*DatabaseContext)(nil), c.openDatabase)
+	container.Compose(ComposeAppContext, ComposeConfigContext)
+
+	want := "typedcontextdi: providing typedcontextdi_test.DatabaseContext: " +
+		"no constructor for typedcontextdi_test.ConfigLoggerContext"
+	wantError(t, container.Check(), want)
+	_, err := container.Build(context.Background())
+	wantError(t, err, want)
+}
+
+// LoggerOnlyContext and ConfigOnlyContext let Config and Logger depend on
+// each other.
+type LoggerOnlyContext interface {
+	context.Context
+	LoggerContext
+}
+
+type ConfigOnlyContext interface {
+	context.Context
+	ConfigContext
+}
+
+type loggerOnlyContext struct {
+	context.Context
+	logger *Logger
+}
+
+func (ctx loggerOnlyContext) Logger() *Logger { return ctx.logger }
+
+type configOnlyContext struct {
+	context.Context
+	config *Config
+}
+
+func (ctx configOnlyContext) Config() *Config { return ctx.config }
+
+func TestCycle(t *testing.T) {
+	c := calls{}
+	container := typedcontextdi.New[AppContext]()
+	container.Provide((*ConfigContext)(nil), func(ctx LoggerOnlyContext) *Config { return &Config{} })
+	container.Provide((*LoggerContext)(nil), func(ctx ConfigOnlyContext) *Logger { return &Logger{} })
+	container.Provide((*DatabaseContext)(nil), c.openDatabase)
+	container.Compose(ComposeAppContext, ComposeConfigLoggerContext,
+		func(ctx context.Context, logger *Logger) LoggerOnlyContext {
+			return loggerOnlyContext{ctx, logger}
+		},
+		func(ctx context.Context, config *Config) ConfigOnlyContext {
+			return configOnlyContext{ctx, config}
+		})
+
+	// Each step of the way wraps the error.
+	want := "typedcontextdi: providing typedcontextdi_test.ConfigContext: " +
+		"providing typedcontextdi_test.LoggerContext: " +
+		"dependency cycle: typedcontextdi_test.ConfigContext -> " +
+		"typedcontextdi_test.LoggerContext -> typedcontextdi_test.ConfigContext"
+	wantError(t, container.Check(), want)
+	_, err := container.Build(context.Background())
+	wantError(t, err, want)
+	if len(c) != 0 {
+		t.Errorf("Build called constructors %v despite the cycle", c)
+	}
+}
+
+func TestConstructorError(t *testing.T) {
+	c := calls{}
+	dbErr := errors.New("connection refused")
+	container := typedcontextdi.New[AppContext]()
+	container.Provide((*ConfigContext)(nil), c.loadConfig)
+	container.Provide((*LoggerContext)(nil), c.newLogger)
+	container.Provide((*DatabaseContext)(nil), func(ConfigLoggerContext) (*Database, error) {
+		return nil, dbErr
+	})
+	container.Compose(ComposeAppContext, ComposeConfigLoggerContext, ComposeConfigContext)
+
+	// Check doesn't call the constructors, so can't know.
+	if err := container.Check(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := container.Build(context.Background())
+	wantError(t, err, "typedcontextdi: providing typedcontextdi_test.DatabaseContext: connection refused")
+	if !errors.Is(err, dbErr) {
+		t.Errorf("got error %v, which doesn't wrap %v", err, dbErr)
+	}
+	if ctx != nil {
+		t.Errorf("got context %v along with the error", ctx)
+	}
+}
+
+// wantPanic fails the test unless f panics with a message containing want.
+func wantPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		got, _ := recover().(string)
+		if !strings.Contains(got, want) {
+			t.Errorf("got panic %q, want one containing %q", got, want)
+		}
+	}()
+	f()
+}
+
+func TestRegistrationPanics(t *testing.T) {
+	c := calls{}
+	container := typedcontextdi.New[AppContext]()
+	wantPanic(t, "want a reflect.Type or a pointer to an interface", func() {
+		container.Provide(ConfigContext(nil), c.loadConfig)
+	})
+	wantPanic(t, "has 0 accessors; want exactly one", func() {
+		container.Provide((*context.Context)(nil), c.loadConfig)
+	})
+	wantPanic(t, "is a string, not a function", func() {
+		container.Provide((*ConfigContext)(nil), "loadConfig")
+	})
+	wantPanic(t, "must take nothing or a context", func() {
+		container.Provide((*ConfigContext)(nil), func(string) *Config { return nil })
+	})
+	wantPanic(t, "must return a *typedcontextdi_test.Config, and optionally an error", func() {
+		container.Provide((*ConfigContext)(nil), func() (*Config, bool) { return nil, false })
+	})
+	container.Provide((*ConfigContext)(nil), c.loadConfig)
+	wantPanic(t, "*typedcontextdi_test.Config already has a provider", func() {
+		container.Provide(reflect.TypeFor[ConfigContext](), c.loadConfig)
+	})
+
+	wantPanic(t, "doesn't return just an interface", func() {
+		container.Compose(func() *Config { return nil })
+	})
+	container.Compose(ComposeConfigContext)
+	wantPanic(t, "typedcontextdi_test.ConfigContext already has a constructor", func() {
+		container.Compose(ComposeConfigContext)
+	})
+	wantPanic(t, "typedcontextdi_test.Config is not an interface", func() {
+		typedcontextdi.New[Config]()
+	})
+}
@@ -0,0 +1,148 @@
+// Package typedcontextgrpc adapts typed contexts to gRPC.
+//
+// The server interceptors build a typed context for each incoming call, from
+// the plain context.Context gRPC provides.  Handlers, whose signatures are
+// generated and must take a context.Context, recover it with FromContext:
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(typedcontextgrpc.UnaryServerInterceptor(
+//			func(ctx context.Context) AppContext {
+//				md, _ := metadata.FromIncomingContext(ctx)
+//				return ComposeAppContext(ctx, secrets, NewLogger(md))
+//			})))
+//
+//	func (s *thingServer) DoThing(ctx context.Context, req *pb.Req) (*pb.Resp, error) {
+//		typedCtx, err := typedcontextgrpc.FromContext[interface {
+//			context.Context
+//			LoggerContext
+//		}](ctx)
+//		if err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+//
+// Calls to other services should be made with Outgoing(ctx), which degrades
+// the typed context back to a plain context.Context.
+package typedcontextgrpc
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// contextKey is the key under which we store the typed context.
+type contextKey struct{}
+
+// typedHolder holds the typed context.  We store a pointer to it in the
+// context before building the typed context, and set its value afterwards,
+// so that the typed context can itself find the value.
+type typedHolder struct {
+	value any
+}
+
+// attach builds a typed context from ctx, and returns a context from which
+// FromContext can recover it.
+//
+// If the typed context is itself a context.Context (as in the typed-context
+// pattern), we return it, so handlers can also just type-assert their ctx.
+func attach[T any](ctx context.Context, build func(context.Context) T) context.Context {
+	holder := &typedHolder{}
+	ctx = context.WithValue(ctx, contextKey{}, holder)
+	typed := build(ctx)
+	holder.value = typed
+	if typedCtx, ok := any(typed).(context.Context); ok {
+		return typedCtx
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor returns an interceptor which calls build to
+// construct a typed context for each incoming unary call, for handlers to
+// recover with FromContext.
+func UnaryServerInterceptor[T any](build func(context.Context) T) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(attach(ctx, build), req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor which calls build to
+// construct a typed context for each incoming streaming call, for handlers
+// to recover with FromContext(stream.Context()).
+func StreamServerInterceptor[T any](build func(context.Context) T) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, typedStream{stream, attach(stream.Context(), build)})
+	}
+}
+
+// typedStream is a grpc.ServerStream whose context is the typed context.
+type typedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream typedStream) Context() context.Context {
+	return stream.ctx
+}
+
+// FromContext returns the typed context built by one of the server
+// interceptors, as a T.
+//
+// It returns an Internal error if there's no such context, or it does not
+// implement T: that means the server was misconfigured.
+func FromContext[T any](ctx context.Context) (T, error) {
+	if typed, ok := ctx.(T); ok {
+		return typed, nil
+	}
+	holder, ok := ctx.Value(contextKey{}).(*typedHolder)
+	if ok {
+		if typed, ok := holder.value.(T); ok {
+			return typed, nil
+		}
+	}
+	var zero T
+	return zero, status.Errorf(codes.Internal,
+		"typedcontextgrpc: no typed context implementing %v; "+
+			"is the typedcontextgrpc interceptor installed?",
+		reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Outgoing returns ctx as a plain context.Context, for use in outgoing gRPC
+// calls.
+//
+// The result has the same deadline, cancellation, and values as ctx, but it
+// does not implement any of ctx's typed interfaces, and FromContext won't
+// find the typed context in it.  That way, client interceptors and the like
+// can't reach the providers (secrets and so on) of the calling service.  It
+// still wraps ctx, though, to delegate to it, so whatever holds on to the
+// result keeps the typed context alive too.
+func Outgoing(ctx context.Context) context.Context {
+	return plainContext{ctx}
+}
+
+// plainContext hides everything about a context but its context.Context
+// methods.
+type plainContext struct {
+	context.Context
+}
+
+func (ctx plainContext) Value(key any) any {
+	if key == (contextKey{}) {
+		return nil
+	}
+	return ctx.Context.Value(key)
+}
@@ -0,0 +1,166 @@
+package typedcontextgrpc_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/khan/typed-context/typedcontext/typedcontextgrpc"
+)
+
+type Logger struct{}
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// appContext is the typed context the interceptors build: it provides a
+// Logger, but no Secrets.
+type appContext struct {
+	context.Context
+	logger *Logger
+}
+
+func (ctx appContext) Logger() *Logger { return ctx.logger }
+
+func build(logger *Logger) func(context.Context) appContext {
+	return func(ctx context.Context) appContext { return appContext{ctx, logger} }
+}
+
+// wantInternal fails the test unless err is a gRPC Internal error.
+func wantInternal(t *testing.T, err error) {
+	t.Helper()
+	if status.Code(err) != codes.Internal {
+		t.Errorf("got error %v, want an Internal one", err)
+	}
+}
+
+// unary runs handler behind the unary interceptor for the given builder.
+func unary[T any](t *testing.T, build func(context.Context) T, handler grpc.UnaryHandler) {
+	t.Helper()
+	interceptor := typedcontextgrpc.UnaryServerInterceptor(build)
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger := &Logger{}
+	called := false
+	unary(t, build(logger), func(ctx context.Context, req any) (any, error) {
+		called = true
+		typed, err := typedcontextgrpc.FromContext[LoggerContext](ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typed.Logger() != logger {
+			t.Errorf("got logger %p, want %p", typed.Logger(), logger)
+		}
+
+		// Contexts derived from it, which don't implement T, still find it.
+		derived, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if _, err := typedcontextgrpc.FromContext[LoggerContext](derived); err != nil {
+			t.Errorf("FromContext(derived): %v", err)
+		}
+
+		// But it's an Internal error to ask for more than the server built.
+		_, err = typedcontextgrpc.FromContext[SecretsContext](ctx)
+		wantInternal(t, err)
+		return nil, nil
+	})
+	if !called {
+		t.Error("handler wasn't called")
+	}
+}
+
+// plainLogger is a typed context which isn't itself a context.Context.
+type plainLogger struct{ logger *Logger }
+
+func (ctx plainLogger) Logger() *Logger { return ctx.logger }
+
+func TestUnaryServerInterceptorNonContext(t *testing.T) {
+	logger := &Logger{}
+	unary(t, func(context.Context) plainLogger { return plainLogger{logger} },
+		func(ctx context.Context, req any) (any, error) {
+			typed, err := typedcontextgrpc.FromContext[interface{ Logger() *Logger }](ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typed.Logger() != logger {
+				t.Errorf("got logger %p, want %p", typed.Logger(), logger)
+			}
+			return nil, nil
+		})
+}
+
+func TestFromContextWithoutInterceptor(t *testing.T) {
+	_, err := typedcontextgrpc.FromContext[LoggerContext](context.Background())
+	wantInternal(t, err)
+}
+
+// serverStream is a grpc.ServerStream with just a context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream serverStream) Context() context.Context { return stream.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	logger := &Logger{}
+	interceptor := typedcontextgrpc.StreamServerInterceptor(build(logger))
+	called := false
+	err := interceptor("srv", serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{},
+		func(srv any, stream grpc.ServerStream) error {
+			called = true
+			if srv != "srv" {
+				t.Errorf("got srv %v, want srv", srv)
+			}
+			typed, err := typedcontextgrpc.FromContext[LoggerContext](stream.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typed.Logger() != logger {
+				t.Errorf("got logger %p, want %p", typed.Logger(), logger)
+			}
+			_, err = typedcontextgrpc.FromContext[SecretsContext](stream.Context())
+			wantInternal(t, err)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("handler wasn't called")
+	}
+}
+
+type key struct{}
+
+func TestOutgoing(t *testing.T) {
+	unary(t, build(&Logger{}), func(ctx context.Context, req any) (any, error) {
+		ctx = context.WithValue(ctx, key{}, "value")
+		outgoing := typedcontextgrpc.Outgoing(ctx)
+		if _, ok := outgoing.(LoggerContext); ok {
+			t.Error("Outgoing's result implements LoggerContext")
+		}
+		_, err := typedcontextgrpc.FromContext[LoggerContext](outgoing)
+		wantInternal(t, err)
+		if outgoing.Value(key{}) != "value" {
+			t.Error("Outgoing's result lost the context's values")
+		}
+		return nil, nil
+	})
+}
@@ -0,0 +1,79 @@
+// Package typedcontexthttp adapts typed contexts to net/http.
+//
+// Middleware builds a typed context for each request and attaches it to the
+// request; Handler recovers it, so handlers can have a typed signature:
+//
+//	mux.Handle("/thing", typedcontexthttp.Handler(
+//		func(ctx interface {
+//			context.Context
+//			RequestContext
+//			LoggerContext
+//		}, w http.ResponseWriter, r *http.Request) {
+//			...
+//		}))
+//	server := typedcontexthttp.Middleware(func(r *http.Request) AppContext {
+//		return ComposeAppContext(r.Context(), NewRequest(r), logger)
+//	})(mux)
+//
+// The handler may request any interface the built context implements; it
+// need not be the same type as the middleware builds.  In particular, it
+// should request only what it uses, as the linter enforces.
+package typedcontexthttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// contextKey is the key under which Middleware stores the typed context.
+type contextKey struct{}
+
+// Middleware returns middleware which calls build to construct a typed
+// context for each request, and attaches it to the request for handlers to
+// recover with FromRequest or Handler.
+//
+// build will typically wrap r.Context(), and is called before any of the
+// handlers wrapped by the middleware.
+func Middleware[T any](build func(*http.Request) T) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), contextKey{}, build(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromRequest returns the typed context attached to r by Middleware, as a T.
+//
+// It returns false if there is no typed context attached to r, or if the
+// attached context does not implement T.
+func FromRequest[T any](r *http.Request) (T, bool) {
+	ctx, ok := r.Context().Value(contextKey{}).(T)
+	return ctx, ok
+}
+
+// HandlerFunc is an HTTP handler which takes a typed context.
+type HandlerFunc[T any] func(ctx T, w http.ResponseWriter, r *http.Request)
+
+// Handler adapts a HandlerFunc to an http.Handler, which passes it the typed
+// context attached to the request by Middleware.
+//
+// If there is no such context, or it does not implement T, the handler
+// responds with an internal server error: that means the server was
+// misconfigured.
+func Handler[T any](handler HandlerFunc[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := FromRequest[T](r)
+		if !ok {
+			http.Error(w, fmt.Sprintf(
+				"typedcontexthttp: request has no typed context implementing %v; "+
+					"is typedcontexthttp.Middleware installed?",
+				reflect.TypeOf((*T)(nil)).Elem()),
+				http.StatusInternalServerError)
+			return
+		}
+		handler(ctx, w, r)
+	})
+}
@@ -0,0 +1,111 @@
+package typedcontexthttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/khan/typed-context/typedcontext/typedcontexthttp"
+)
+
+type Logger struct{ lines []string }
+
+type Secrets struct{}
+
+type LoggerContext interface {
+	context.Context
+	Logger() *Logger
+}
+
+type SecretsContext interface {
+	context.Context
+	Secrets() *Secrets
+}
+
+// appContext is the typed context the middleware builds: it provides a
+// Logger, but no Secrets.
+type appContext struct {
+	context.Context
+	logger *Logger
+}
+
+func (ctx appContext) Logger() *Logger { return ctx.logger }
+
+func middleware(logger *Logger) func(http.Handler) http.Handler {
+	return typedcontexthttp.Middleware(func(r *http.Request) appContext {
+		return appContext{r.Context(), logger}
+	})
+}
+
+// serve serves a GET of / with handler, and returns the response.
+func serve(handler http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	logger := &Logger{}
+	handler := typedcontexthttp.Handler(func(ctx LoggerContext, w http.ResponseWriter, r *http.Request) {
+		ctx.Logger().lines = append(ctx.Logger().lines, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	w := serve(middleware(logger)(handler))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("got status %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	if len(logger.lines) != 1 || logger.lines[0] != "/" {
+		t.Errorf("got logged lines %q, want [/]", logger.lines)
+	}
+}
+
+// wantMisconfigured fails the test unless w is the internal server error
+// Handler responds with when it can't find a T.
+func wantMisconfigured(t *testing.T, w *httptest.ResponseRecorder, typeName string) {
+	t.Helper()
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	want := "request has no typed context implementing " + typeName
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("got body %q, want one containing %q", w.Body, want)
+	}
+}
+
+func TestHandlerMisconfigured(t *testing.T) {
+	called := false
+	handler := typedcontexthttp.Handler(func(SecretsContext, http.ResponseWriter, *http.Request) {
+		called = true
+	})
+	// No middleware.
+	wantMisconfigured(t, serve(handler), "typedcontexthttp_test.SecretsContext")
+	// Middleware building a context which doesn't implement T.
+	wantMisconfigured(t, serve(middleware(&Logger{})(handler)), "typedcontexthttp_test.SecretsContext")
+	if called {
+		t.Error("handler was called without its context")
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	logger := &Logger{}
+	called := false
+	serve(middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		ctx, ok := typedcontexthttp.FromRequest[LoggerContext](r)
+		if !ok || ctx.Logger() != logger {
+			t.Errorf("FromRequest[LoggerContext] = %v, %v; want the built context", ctx, ok)
+		}
+		if _, ok := typedcontexthttp.FromRequest[SecretsContext](r); ok {
+			t.Error("FromRequest[SecretsContext] found a context which doesn't implement it")
+		}
+	})))
+	if !called {
+		t.Error("handler wasn't called")
+	}
+
+	if _, ok := typedcontexthttp.FromRequest[LoggerContext](httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Error("FromRequest found a context without the middleware")
+	}
+}
@@ -0,0 +1,53 @@
+// Package typedcontextotel adapts typed contexts to OpenTelemetry tracing.
+//
+// tracer.Start takes a context.Context and returns a new one, carrying the
+// span; called with a typed context, it loses the typed interface, so the
+// caller has to keep both around:
+//
+//	spanCtx, span := tracer.Start(ctx, "load")
+//	defer span.End()
+//	load(spanCtx, ctx.Database()) // which to pass?
+//
+// Start instead returns a context of the same type as the one it's given,
+// whose context.Context carries the span:
+//
+//	ctx, span := typedcontextotel.Start(ctx, "load")
+//	defer span.End()
+//	load(ctx)
+//
+// The tracer comes from the context itself, which must provide TraceContext;
+// StartWith takes one explicitly.  Either way, the context's type must be a
+// composite interface with a constructor generated by typedcontext-gen (see
+// typedcontext.WithContext).
+//
+// The linter treats both, and tracer.Start itself, as deriving functions (see
+// -typedcontextinterface.derivers): the context they return is checked as
+// part of the one passed in, rather than on its own.
+package typedcontextotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/khan/typed-context/typedcontext"
+)
+
+// TraceContext is the capability to create spans.
+type TraceContext interface {
+	context.Context
+	Tracer() trace.Tracer
+}
+
+// Start starts a span with the tracer ctx provides, and returns it, along
+// with a copy of ctx, of the same type, which carries it.
+func Start[T TraceContext](ctx T, name string, opts ...trace.SpanStartOption) (T, trace.Span) {
+	return StartWith(ctx, ctx.Tracer(), name, opts...)
+}
+
+// StartWith is like Start, but uses the given tracer, for contexts which
+// don't provide one.
+func StartWith[T context.Context](ctx T, tracer trace.Tracer, name string, opts ...trace.SpanStartOption) (T, trace.Span) {
+	spanCtx, span := tracer.Start(ctx, name, opts...)
+	return typedcontext.WithContext(ctx, spanCtx), span
+}
@@ -0,0 +1,247 @@
+// Package typedcontexttask adapts typed contexts to background task queues.
+//
+// A task declares, via its type parameter, the typed context its handler
+// needs.  The worker declares, via its Dispatcher, the typed context it
+// builds for each task.  Registering a handler whose context the worker
+// can't build is an error at worker startup, and enqueueing a task to
+// workers which can't build its context is an error at enqueue time -- rather
+// than a type-assertion panic when the task finally runs:
+//
+//	var SendEmail = typedcontexttask.NewTask[interface {
+//		context.Context
+//		LoggerContext
+//		EmailContext
+//	}]("send-email")
+//
+//	// worker
+//	dispatcher := typedcontexttask.NewDispatcher(func(ctx context.Context) WorkerContext {
+//		return ComposeWorkerContext(ctx, logger, emailClient)
+//	})
+//	if err := typedcontexttask.Handle(dispatcher, SendEmail, sendEmail); err != nil {
+//		log.Fatal(err) // WorkerContext doesn't provide EmailContext
+//	}
+//
+//	// enqueuer
+//	queue := typedcontexttask.NewQueue(send, dispatcher.Provides())
+//	err := SendEmail.Enqueue(ctx, queue, payload)
+//
+// Each message carries the task's requirements (the method signatures of its
+// context type), so workers can also check them when they receive it.  The
+// workers' Provides() is likewise serializable, so enqueuers in another
+// binary can check against it (e.g. as part of deploy configuration).
+package typedcontexttask
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Message is a task as sent to a queue.  It's meant to be serialized (e.g.
+// as JSON) by the transport.
+type Message struct {
+	// Task is the name of the task.
+	Task string `json:"task"`
+	// Requires lists the method signatures of the typed context the task's
+	// handler needs, like "Logger() *app.Logger".
+	Requires []string `json:"requires"`
+	// Payload is the task's argument, in whatever encoding it likes.
+	Payload []byte `json:"payload"`
+}
+
+// _methodSet returns the method signatures of the given type, sorted, in the
+// form used by Message.Requires.
+func _methodSet(typ reflect.Type) []string {
+	methods := make([]string, 0, typ.NumMethod())
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		sig := method.Type
+		if typ.Kind() != reflect.Interface {
+			// For concrete types, the first argument is the receiver; we
+			// want the signature as it would be in an interface.
+			in := make([]reflect.Type, 0, sig.NumIn()-1)
+			for j := 1; j < sig.NumIn(); j++ {
+				in = append(in, sig.In(j))
+			}
+			out := make([]reflect.Type, 0, sig.NumOut())
+			for j := 0; j < sig.NumOut(); j++ {
+				out = append(out, sig.Out(j))
+			}
+			sig = reflect.FuncOf(in, out, sig.IsVariadic())
+		}
+		methods = append(methods, method.Name+strings.TrimPrefix(sig.String(), "func"))
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// _missing returns the elements of required not in provided.
+func _missing(required []string, provided map[string]bool) []string {
+	var missing []string
+	for _, method := range required {
+		if !provided[method] {
+			missing = append(missing, method)
+		}
+	}
+	return missing
+}
+
+// Task is a kind of background task, whose handler needs a typed context of
+// type T.
+type Task[T any] struct {
+	name     string
+	requires []string
+}
+
+// NewTask declares a task with the given name, whose handler needs a typed
+// context of type T (typically an interface).
+func NewTask[T any](name string) *Task[T] {
+	return &Task[T]{name, _methodSet(reflect.TypeFor[T]())}
+}
+
+// Name returns the name of the task.
+func (task *Task[T]) Name() string {
+	return task.name
+}
+
+// Requires returns the method signatures of the typed context the task's
+// handler needs, as sent in each Message.
+func (task *Task[T]) Requires() []string {
+	return append([]string(nil), task.requires...)
+}
+
+// Enqueue sends a message for this task to the given queue.
+//
+// It returns an error, without sending anything, if the queue's workers
+// can't build the context the task needs.
+func (task *Task[T]) Enqueue(ctx context.Context, queue *Queue, payload []byte) error {
+	if queue.provides != nil {
+		if missing := _missing(task.requires, queue.provides); len(missing) > 0 {
+			return fmt.Errorf("typedcontexttask: workers can't run task %s: "+
+				"their contexts are missing %s", task.name, strings.Join(missing, ", "))
+		}
+	}
+	return queue.send(ctx, Message{task.name, task.requires, payload})
+}
+
+// Queue is where tasks are enqueued: a transport, plus what its workers
+// provide.
+type Queue struct {
+	send     func(context.Context, Message) error
+	provides map[string]bool
+}
+
+// NewQueue returns a queue which sends messages with send, to workers whose
+// contexts provide the given methods (as returned by Dispatcher.Provides).
+//
+// If provides is nil, Enqueue doesn't check tasks' requirements; the workers
+// still do, when they receive them.
+func NewQueue(send func(context.Context, Message) error, provides []string) *Queue {
+	queue := &Queue{send: send}
+	if provides != nil {
+		queue.provides = map[string]bool{}
+		for _, method := range provides {
+			queue.provides[method] = true
+		}
+	}
+	return queue
+}
+
+// Dispatcher runs tasks on the worker side: it builds the worker's typed
+// context for each message, and passes it to the task's handler.
+type Dispatcher struct {
+	contextType reflect.Type
+	provides    []string
+	build       func(context.Context) any
+
+	mu       sync.RWMutex
+	handlers map[string]func(ctx any, payload []byte) error
+}
+
+// NewDispatcher returns a dispatcher which calls build to construct a typed
+// context for each task it runs.
+func NewDispatcher[W any](build func(context.Context) W) *Dispatcher {
+	contextType := reflect.TypeFor[W]()
+	return &Dispatcher{
+		contextType: contextType,
+		provides:    _methodSet(contextType),
+		build:       func(ctx context.Context) any { return build(ctx) },
+		handlers:    map[string]func(any, []byte) error{},
+	}
+}
+
+// Provides returns the method signatures of the typed context the
+// dispatcher builds.  Pass it to NewQueue so enqueuers can check tasks'
+// requirements.
+func (d *Dispatcher) Provides() []string {
+	return append([]string(nil), d.provides...)
+}
+
+// Handle registers the handler for the given task.
+//
+// It returns an error if the dispatcher's context type doesn't implement
+// the context the task needs, or if the task already has a handler.  Call it
+// at worker startup, so that misconfiguration fails the deploy.
+func Handle[T any](d *Dispatcher, task *Task[T], handler func(ctx T, payload []byte) error) error {
+	want := reflect.TypeFor[T]()
+	// Implements panics unless want is an interface; a concrete T (say, the
+	// worker's own context struct) must be the worker's type exactly.
+	implements := want.Kind() == reflect.Interface && d.contextType.Implements(want)
+	if !implements && !d.contextType.AssignableTo(want) {
+		provided := map[string]bool{}
+		for _, method := range d.provides {
+			provided[method] = true
+		}
+		return fmt.Errorf("typedcontexttask: can't handle task %s: "+
+			"worker context %v is missing %s", task.name, d.contextType,
+			strings.Join(_missing(task.requires, provided), ", "))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.handlers[task.name]; ok {
+		return fmt.Errorf("typedcontexttask: task %s already has a handler", task.name)
+	}
+	d.handlers[task.name] = func(ctx any, payload []byte) error {
+		// The check above guarantees this unless build returned a nil
+		// interface.
+		typed, ok := ctx.(T)
+		if !ok {
+			return fmt.Errorf("typedcontexttask: can't run task %s: "+
+				"worker built a nil %v", task.name, d.contextType)
+		}
+		return handler(typed, payload)
+	}
+	return nil
+}
+
+// Dispatch runs the handler for the given message, with a typed context
+// built from ctx.
+//
+// It returns an error if there's no handler for the task, if the message
+// requires methods the dispatcher's context doesn't provide (for example,
+// because it was enqueued by a newer version of the task), or if the
+// dispatcher's build function returns nil (for an interface context type).
+func (d *Dispatcher) Dispatch(ctx context.Context, msg Message) error {
+	d.mu.RLock()
+	handler, ok := d.handlers[msg.Task]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("typedcontexttask: no handler for task %s", msg.Task)
+	}
+
+	provided := map[string]bool{}
+	for _, method := range d.provides {
+		provided[method] = true
+	}
+	if missing := _missing(msg.Requires, provided); len(missing) > 0 {
+		return fmt.Errorf("typedcontexttask: can't run task %s: "+
+			"worker context %v is missing %s", msg.Task, d.contextType,
+			strings.Join(missing, ", "))
+	}
+
+	return handler(d.build(ctx), msg.Payload)
+}
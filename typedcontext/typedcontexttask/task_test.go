@@ -0,0 +1,206 @@
+package typedcontexttask_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/khan/typed-context/typedcontext/typedcontexttask"
+)
+
+type Logger struct{ lines []string }
+
+type Mailer struct{}
+
+type LoggerContext interface{ Logger() *Logger }
+
+type MailerContext interface{ Mailer() *Mailer }
+
+type EmailContext interface {
+	context.Context
+	LoggerContext
+	MailerContext
+}
+
+type LogContext interface {
+	context.Context
+	LoggerContext
+}
+
+// workerContext is the worker's typed context: it provides a Logger but no
+// Mailer.
+type workerContext struct {
+	context.Context
+	logger *Logger
+}
+
+func (ctx *workerContext) Logger() *Logger { return ctx.logger }
+
+func newDispatcher(logger *Logger) *typedcontexttask.Dispatcher {
+	return typedcontexttask.NewDispatcher(func(ctx context.Context) *workerContext {
+		return &workerContext{ctx, logger}
+	})
+}
+
+// wantError fails the test unless err is non-nil and contains want.
+func wantError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want one containing %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("got error %q, want one containing %q", err, want)
+	}
+}
+
+func TestHandleAndDispatch(t *testing.T) {
+	logger := &Logger{}
+	dispatcher := newDispatcher(logger)
+	task := typedcontexttask.NewTask[LogContext]("log")
+	err := typedcontexttask.Handle(dispatcher, task, func(ctx LogContext, payload []byte) error {
+		ctx.Logger().lines = append(ctx.Logger().lines, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []typedcontexttask.Message
+	queue := typedcontexttask.NewQueue(func(ctx context.Context, msg typedcontexttask.Message) error {
+		sent = append(sent, msg)
+		return nil
+	}, dispatcher.Provides())
+	if err := task.Enqueue(context.Background(), queue, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("got %d messages, want 1", len(sent))
+	}
+	want := typedcontexttask.Message{Task: "log", Requires: task.Requires(), Payload: []byte("hello")}
+	if !reflect.DeepEqual(sent[0], want) {
+		t.Errorf("got message %+v, want %+v", sent[0], want)
+	}
+
+	if err := dispatcher.Dispatch(context.Background(), sent[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(logger.lines, []string{"hello"}) {
+		t.Errorf("got logged lines %q, want [hello]", logger.lines)
+	}
+}
+
+func TestHandleConcrete(t *testing.T) {
+	dispatcher := newDispatcher(&Logger{})
+	var got *workerContext
+	own := typedcontexttask.NewTask[*workerContext]("own")
+	err := typedcontexttask.Handle(dispatcher, own, func(ctx *workerContext, payload []byte) error {
+		got = ctx
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := typedcontexttask.Message{Task: "own", Requires: own.Requires()}
+	if err := dispatcher.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Error("handler wasn't called")
+	}
+
+	// A different concrete type is an error, not a panic.
+	other := typedcontexttask.NewTask[*Logger]("other")
+	err = typedcontexttask.Handle(dispatcher, other, func(*Logger, []byte) error { return nil })
+	wantError(t, err, "can't handle task other")
+}
+
+func TestHandleMismatch(t *testing.T) {
+	dispatcher := newDispatcher(&Logger{})
+	task := typedcontexttask.NewTask[EmailContext]("send-email")
+	err := typedcontexttask.Handle(dispatcher, task, func(EmailContext, []byte) error { return nil })
+	wantError(t, err, "is missing Mailer() *typedcontexttask_test.Mailer")
+
+	// Nor does the failed registration leave a handler behind.
+	msg := typedcontexttask.Message{Task: "send-email"}
+	wantError(t, dispatcher.Dispatch(context.Background(), msg), "no handler for task send-email")
+}
+
+func TestHandleTwice(t *testing.T) {
+	dispatcher := newDispatcher(&Logger{})
+	task := typedcontexttask.NewTask[LogContext]("log")
+	handler := func(LogContext, []byte) error { return nil }
+	if err := typedcontexttask.Handle(dispatcher, task, handler); err != nil {
+		t.Fatal(err)
+	}
+	wantError(t, typedcontexttask.Handle(dispatcher, task, handler), "already has a handler")
+}
+
+func TestEnqueueMismatch(t *testing.T) {
+	dispatcher := newDispatcher(&Logger{})
+	sent := 0
+	send := func(context.Context, typedcontexttask.Message) error {
+		sent++
+		return nil
+	}
+	task := typedcontexttask.NewTask[EmailContext]("send-email")
+
+	err := task.Enqueue(context.Background(), typedcontexttask.NewQueue(send, dispatcher.Provides()), nil)
+	wantError(t, err, "workers can't run task send-email")
+	if sent != 0 {
+		t.Errorf("sent %d messages, want none", sent)
+	}
+
+	// Without the workers' provides, Enqueue can't check.
+	if err := task.Enqueue(context.Background(), typedcontexttask.NewQueue(send, nil), nil); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 {
+		t.Errorf("sent %d messages, want 1", sent)
+	}
+
+	sendErr := errors.New("queue is down")
+	queue := typedcontexttask.NewQueue(func(context.Context, typedcontexttask.Message) error {
+		return sendErr
+	}, nil)
+	if err := task.Enqueue(context.Background(), queue, nil); !errors.Is(err, sendErr) {
+		t.Errorf("got error %v, want %v", err, sendErr)
+	}
+}
+
+func TestDispatchMismatch(t *testing.T) {
+	dispatcher := newDispatcher(&Logger{})
+	task := typedcontexttask.NewTask[LogContext]("log")
+	if err := typedcontexttask.Handle(dispatcher, task, func(LogContext, []byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// A newer version of the task, which needs a Mailer too.
+	newer := typedcontexttask.NewTask[EmailContext]("log")
+	msg := typedcontexttask.Message{Task: "log", Requires: newer.Requires()}
+	wantError(t, dispatcher.Dispatch(context.Background(), msg), "can't run task log")
+
+	msg = typedcontexttask.Message{Task: "unknown"}
+	wantError(t, dispatcher.Dispatch(context.Background(), msg), "no handler for task unknown")
+}
+
+func TestDispatchNilContext(t *testing.T) {
+	dispatcher := typedcontexttask.NewDispatcher(func(ctx context.Context) LogContext {
+		return nil
+	})
+	task := typedcontexttask.NewTask[LoggerContext]("log")
+	called := false
+	err := typedcontexttask.Handle(dispatcher, task, func(LoggerContext, []byte) error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := typedcontexttask.Message{Task: "log", Requires: task.Requires()}
+	wantError(t, dispatcher.Dispatch(context.Background(), msg), "worker built a nil")
+	if called {
+		t.Error("handler was called with a nil context")
+	}
+}
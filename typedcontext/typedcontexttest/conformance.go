@@ -0,0 +1,173 @@
+// Package typedcontexttest helps test implementations of typed context
+// interfaces.
+//
+// Production contexts are often hand-written structs, and they regress when
+// an interface gains an accessor: the struct gets the method (or the code
+// wouldn't compile), but nothing sets the provider behind it, so it returns
+// nil until some request happens to need it.  Conformance checks, for a
+// composite interface and a function building its implementation, that
+// every accessor returns a non-nil provider, and that the methods of
+// context.Context delegate to the context the implementation was built
+// from:
+//
+//	func TestAppContext(t *testing.T) {
+//		typedcontexttest.Conformance(t, func(ctx context.Context) AppContext {
+//			return newAppContext(ctx, config)
+//		})
+//	}
+//
+// The typedcontext-testgen command generates such tests, with a table of the
+// interface's accessors, from go:generate.
+package typedcontexttest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Accessor is an accessor of the typed context interface T, for Conformance:
+// Call calls it on a context, and returns the provider.
+type Accessor[T any] struct {
+	Name string
+	Call func(ctx T) any
+}
+
+// _contextMethods are the methods of context.Context, which aren't
+// accessors.
+var _contextMethods = map[string]bool{
+	"Deadline": true,
+	"Done":     true,
+	"Err":      true,
+	"Value":    true,
+}
+
+// Accessors returns the accessors of the interface T: its methods, other
+// than those of context.Context, with no arguments and a single result, in
+// order of name.  It panics if T isn't an interface type.
+func Accessors[T any]() []Accessor[T] {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Interface {
+		panic("typedcontexttest: " + typ.String() + " is not an interface type")
+	}
+	var accessors []Accessor[T]
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if !method.IsExported() || _contextMethods[method.Name] ||
+			method.Type.NumIn() != 0 || method.Type.NumOut() != 1 {
+			continue
+		}
+		name := method.Name
+		accessors = append(accessors, Accessor[T]{
+			Name: name,
+			Call: func(ctx T) any {
+				return reflect.ValueOf(ctx).MethodByName(name).Call(nil)[0].Interface()
+			},
+		})
+	}
+	return accessors
+}
+
+// _isNil returns true if value is nil, or holds a nil pointer, map, slice,
+// channel, function, or interface.
+func _isNil(value any) bool {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Chan,
+		reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// _conformanceKey is the key of the value Conformance puts in the parent
+// context.
+type _conformanceKey struct{}
+
+// Conformance checks the implementation of the typed context interface T
+// which build returns, given a parent context: in a subtest per accessor,
+// that it returns a non-nil provider, and, if T includes context.Context, in
+// a subtest per method of context.Context, that it delegates to parent.
+// (Implementations which don't include context.Context may ignore parent.)
+//
+// The accessors are those given, or, if there are none, those Accessors
+// finds.  Each subtest builds a fresh context.
+func Conformance[T any](t *testing.T, build func(parent context.Context) T, accessors ...Accessor[T]) {
+	t.Helper()
+	if len(accessors) == 0 {
+		accessors = Accessors[T]()
+	}
+	for _, accessor := range accessors {
+		t.Run(accessor.Name, func(t *testing.T) {
+			ctx := build(context.Background())
+			if _isNil(ctx) {
+				t.Fatalf("build returned nil")
+			}
+			if provider := accessor.Call(ctx); _isNil(provider) {
+				t.Errorf("%s() returned nil; is its provider set?", accessor.Name)
+			}
+		})
+	}
+	if !reflect.TypeFor[T]().Implements(reflect.TypeFor[context.Context]()) {
+		return
+	}
+
+	// newParent returns a parent context with a deadline and a value, to
+	// check that the implementation passes them through.
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	newParent := func() (context.Context, context.CancelFunc) {
+		parent, cancel := context.WithDeadline(context.Background(), deadline)
+		return context.WithValue(parent, _conformanceKey{}, "conformance"), cancel
+	}
+	// contextOf builds the implementation from parent, as a context.Context.
+	contextOf := func(parent context.Context) context.Context {
+		return any(build(parent)).(context.Context)
+	}
+
+	t.Run("Deadline", func(t *testing.T) {
+		parent, cancel := newParent()
+		defer cancel()
+		if got, ok := contextOf(parent).Deadline(); !ok || !got.Equal(deadline) {
+			t.Errorf("Deadline() = %v, %v; want the parent's, %v, true", got, ok, deadline)
+		}
+	})
+	t.Run("Value", func(t *testing.T) {
+		parent, cancel := newParent()
+		defer cancel()
+		if got := contextOf(parent).Value(_conformanceKey{}); got != "conformance" {
+			t.Errorf("Value() = %v; want the parent's value, %q", got, "conformance")
+		}
+	})
+	t.Run("Done", func(t *testing.T) {
+		parent, cancel := newParent()
+		defer cancel()
+		ctx := contextOf(parent)
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Done() is closed before the parent is canceled")
+		default:
+		}
+		cancel()
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Errorf("Done() isn't closed when the parent is canceled")
+		}
+	})
+	t.Run("Err", func(t *testing.T) {
+		parent, cancel := newParent()
+		defer cancel()
+		ctx := contextOf(parent)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("Err() = %v before the parent is canceled; want nil", err)
+		}
+		cancel()
+		if err := ctx.Err(); err != context.Canceled {
+			t.Errorf("Err() = %v after the parent is canceled; want %v", err, context.Canceled)
+		}
+	})
+}